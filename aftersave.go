@@ -0,0 +1,138 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sync"
+)
+
+// ErrAfterSave when an after save hook fails, the events are saved in the event store
+var ErrAfterSave = errors.New("after save hook failed")
+
+// ErrNoWorkers when an OrderedDispatcher or SnapshotWorker is constructed without workers
+var ErrNoWorkers = errors.New("dispatcher needs at least one worker")
+
+// ErrDispatcherClosed when events are dispatched after the OrderedDispatcher is closed
+var ErrDispatcherClosed = errors.New("dispatcher is closed")
+
+// AfterSaver receives the events after they are saved in the event store, with their global version set.
+// Used to publish the events to message brokers like Kafka, NATS or SNS.
+type AfterSaver[T any] interface {
+	AfterSave(events []Event[T]) error
+}
+
+// AfterSaveFunc makes a function an AfterSaver
+type AfterSaveFunc[T any] func(events []Event[T]) error
+
+// AfterSave calls the function
+func (f AfterSaveFunc[T]) AfterSave(events []Event[T]) error {
+	return f(events)
+}
+
+// AddAfterSave adds a hook called with the saved events in Save. The hooks are called in the order they are added,
+// an error from a hook is returned from Save wrapped in ErrAfterSave.
+func (r *Repository[T]) AddAfterSave(hook AfterSaver[T]) {
+	r.afterSave = append(r.afterSave, hook)
+}
+
+// afterSaveHooks calls the after save hooks and stops on the first failing hook
+func (r *Repository[T]) afterSaveHooks(events []Event[T]) error {
+	for _, hook := range r.afterSave {
+		err := hook.AfterSave(events)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrAfterSave, err)
+		}
+	}
+	return nil
+}
+
+// OrderedDispatcher is an AfterSaver passing the events to another AfterSaver in the background.
+// The events of an aggregate are always handled by the same worker, keeping the order per aggregate while
+// events from different aggregates are handled in parallel.
+type OrderedDispatcher[T any] struct {
+	hook    AfterSaver[T]
+	queues  []chan []Event[T]
+	wg      sync.WaitGroup
+	lock    sync.Mutex
+	onError func(events []Event[T], err error)
+	// closeLock is held for reading while sending on the queues so Close can't close a queue during a send
+	closeLock sync.RWMutex
+	closed    bool
+}
+
+// NewOrderedDispatcher starts workers number of goroutines calling the hook. queueSize is the number of saves
+// each worker can have waiting, AfterSave blocks when the queue of the worker is full. Returns ErrNoWorkers if
+// workers is below one.
+func NewOrderedDispatcher[T any](hook AfterSaver[T], workers, queueSize int) (*OrderedDispatcher[T], error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("%w: %d workers", ErrNoWorkers, workers)
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+	d := &OrderedDispatcher[T]{
+		hook:   hook,
+		queues: make([]chan []Event[T], workers),
+	}
+	d.wg.Add(workers)
+	for i := range d.queues {
+		d.queues[i] = make(chan []Event[T], queueSize)
+		go d.work(d.queues[i])
+	}
+	return d, nil
+}
+
+// OnError sets a function that is called when the hook fails
+func (d *OrderedDispatcher[T]) OnError(f func(events []Event[T], err error)) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.onError = f
+}
+
+// AfterSave queue the events on the worker of the aggregate, returns ErrDispatcherClosed after Close
+func (d *OrderedDispatcher[T]) AfterSave(events []Event[T]) error {
+	if len(events) == 0 {
+		return nil
+	}
+	d.closeLock.RLock()
+	defer d.closeLock.RUnlock()
+	if d.closed {
+		return ErrDispatcherClosed
+	}
+	h := fnv.New32a()
+	h.Write([]byte(events[0].AggregateType + "_" + events[0].AggregateID))
+	d.queues[h.Sum32()%uint32(len(d.queues))] <- events
+	return nil
+}
+
+// Close waits until the queued events are handled. No events can be dispatched after Close.
+func (d *OrderedDispatcher[T]) Close() {
+	d.closeLock.Lock()
+	if d.closed {
+		d.closeLock.Unlock()
+		return
+	}
+	d.closed = true
+	d.closeLock.Unlock()
+	for _, q := range d.queues {
+		close(q)
+	}
+	d.wg.Wait()
+}
+
+func (d *OrderedDispatcher[T]) work(queue chan []Event[T]) {
+	defer d.wg.Done()
+	for events := range queue {
+		err := d.hook.AfterSave(events)
+		if err == nil {
+			continue
+		}
+		d.lock.Lock()
+		onError := d.onError
+		d.lock.Unlock()
+		if onError != nil {
+			onError(events, err)
+		}
+	}
+}
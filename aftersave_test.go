@@ -0,0 +1,123 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestAfterSave(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	var saved []eventsourcing.Event[PersonEvent]
+	repo.AddAfterSave(eventsourcing.AfterSaveFunc[PersonEvent](func(events []eventsourcing.Event[PersonEvent]) error {
+		saved = append(saved, events...)
+		return nil
+	}))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("expected 2 events got %d", len(saved))
+	}
+	if saved[0].GlobalVersion != 1 || saved[1].GlobalVersion != 2 {
+		t.Fatal("expected the events to have the global version set")
+	}
+
+	// no hook call without events
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved) != 2 {
+		t.Fatalf("expected no new events got %d", len(saved))
+	}
+}
+
+func TestAfterSaveError(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	repo.AddAfterSave(eventsourcing.AfterSaveFunc[PersonEvent](func(events []eventsourcing.Event[PersonEvent]) error {
+		return errors.New("broker unavailable")
+	}))
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if !errors.Is(err, eventsourcing.ErrAfterSave) {
+		t.Fatalf("expected ErrAfterSave got %v", err)
+	}
+	// the events are saved
+	if person.UnsavedEvents() {
+		t.Fatal("expected the events to be saved")
+	}
+}
+
+func TestOrderedDispatcher(t *testing.T) {
+	var lock sync.Mutex
+	versions := make(map[string][]eventsourcing.Version)
+	dispatcher, err := eventsourcing.NewOrderedDispatcher[PersonEvent](eventsourcing.AfterSaveFunc[PersonEvent](func(events []eventsourcing.Event[PersonEvent]) error {
+		lock.Lock()
+		defer lock.Unlock()
+		for _, e := range events {
+			versions[e.AggregateID] = append(versions[e.AggregateID], e.Version)
+		}
+		return nil
+	}), 4, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for v := 1; v <= 50; v++ {
+		for a := 0; a < 5; a++ {
+			dispatcher.AfterSave([]eventsourcing.Event[PersonEvent]{{AggregateID: fmt.Sprint(a), AggregateType: "Person", Version: eventsourcing.Version(v)}})
+		}
+	}
+	dispatcher.Close()
+
+	for id, vs := range versions {
+		if len(vs) != 50 {
+			t.Fatalf("expected 50 events for %s got %d", id, len(vs))
+		}
+		for i, v := range vs {
+			if v != eventsourcing.Version(i+1) {
+				t.Fatalf("events out of order for %s", id)
+			}
+		}
+	}
+}
+
+func TestOrderedDispatcherWithoutWorkers(t *testing.T) {
+	_, err := eventsourcing.NewOrderedDispatcher[PersonEvent](eventsourcing.AfterSaveFunc[PersonEvent](func(events []eventsourcing.Event[PersonEvent]) error {
+		return nil
+	}), 0, 10)
+	if !errors.Is(err, eventsourcing.ErrNoWorkers) {
+		t.Fatalf("expected ErrNoWorkers got %v", err)
+	}
+}
+
+func TestOrderedDispatcherAfterSaveAfterClose(t *testing.T) {
+	dispatcher, err := eventsourcing.NewOrderedDispatcher[PersonEvent](eventsourcing.AfterSaveFunc[PersonEvent](func(events []eventsourcing.Event[PersonEvent]) error {
+		return nil
+	}), 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dispatcher.Close()
+	// closing twice is a no-op
+	dispatcher.Close()
+	err = dispatcher.AfterSave([]eventsourcing.Event[PersonEvent]{{AggregateID: "1", AggregateType: "Person", Version: 1}})
+	if !errors.Is(err, eventsourcing.ErrDispatcherClosed) {
+		t.Fatalf("expected ErrDispatcherClosed got %v", err)
+	}
+}
@@ -2,6 +2,8 @@ package eventsourcing
 
 import (
 	"errors"
+	"fmt"
+	"math"
 	"reflect"
 	"time"
 )
@@ -9,11 +11,27 @@ import (
 // Version is the event version used in event.Version, event.GlobalVersion and aggregateRoot
 type Version uint64
 
+// MaxVersion is the highest aggregate version an event store accepts. Some event stores persist the version
+// as a signed 64 bit integer.
+const MaxVersion Version = math.MaxInt64
+
+// ErrVersionOverflow when a version is negative or above MaxVersion
+var ErrVersionOverflow = errors.New("version overflow")
+
+// VersionFromInt64 converts a signed integer, as returned from database drivers, to a Version
+func VersionFromInt64(v int64) (Version, error) {
+	if v < 0 {
+		return 0, fmt.Errorf("%w: %d is negative", ErrVersionOverflow, v)
+	}
+	return Version(v), nil
+}
+
 // AggregateRoot to be included into aggregates
 type AggregateRoot[T any] struct {
 	aggregateID            string
 	aggregateVersion       Version
 	aggregateGlobalVersion Version
+	aggregateTimestamp     time.Time
 	aggregateEvents        []Event[T]
 }
 
@@ -26,48 +44,81 @@ var ErrAggregateAlreadyExists = errors.New("its not possible to set ID on alread
 
 // TrackChange is used internally by behaviour methods to apply a state change to
 // the current instance and also track it in order that it can be persisted later.
-func (ar *AggregateRoot[T]) TrackChange(a Aggregate[T], data T) {
-	ar.TrackChangeWithMetadata(a, data, nil)
+// Event data implementing EventValidator is validated first, an invalid event is not tracked nor applied.
+func (ar *AggregateRoot[T]) TrackChange(a Aggregate[T], data T) error {
+	return ar.TrackChangeWithMetadata(a, data, nil)
 }
 
 // TrackChangeWithMetadata is used internally by behaviour methods to apply a state change to
 // the current instance and also track it in order that it can be persisted later.
 // meta data is handled by this func to store none related application state
-func (ar *AggregateRoot[T]) TrackChangeWithMetadata(a Aggregate[T], data T, metadata map[string]interface{}) {
+func (ar *AggregateRoot[T]) TrackChangeWithMetadata(a Aggregate[T], data T, metadata map[string]interface{}) error {
+	return ar.trackChange(a, data, trackOptions{metadata: metadata})
+}
+
+// TrackChangeWithValidTime tracks a state change that took effect at another time than it's recorded, like a
+// backdated correction. The valid time is stored on the event beside the timestamp.
+func (ar *AggregateRoot[T]) TrackChangeWithValidTime(a Aggregate[T], data T, validTime time.Time, metadata map[string]interface{}) error {
+	return ar.trackChange(a, data, trackOptions{metadata: metadata, validTime: validTime.UTC()})
+}
+
+// TrackChangeWithOptions tracks a state change with the metadata, timestamp and valid time set by the options
+func (ar *AggregateRoot[T]) TrackChangeWithOptions(a Aggregate[T], data T, opts ...TrackOption) error {
+	o := trackOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return ar.trackChange(a, data, o)
+}
+
+func (ar *AggregateRoot[T]) trackChange(a Aggregate[T], data T, o trackOptions) error {
+	err := ValidateEventData(data)
+	if err != nil {
+		return err
+	}
 	// This can be overwritten in the constructor of the aggregate
 	if ar.aggregateID == emptyAggregateID {
 		ar.aggregateID = idFunc()
 	}
 
+	timestamp := o.timestamp
+	if timestamp.IsZero() {
+		timestamp = ar.nextTimestamp()
+	}
 	name := reflect.TypeOf(a).Elem().Name()
 	event := Event[T]{
+		EventID:       eventIDFunc(),
 		AggregateID:   ar.aggregateID,
 		Version:       ar.nextVersion(),
 		AggregateType: name,
-		Timestamp:     time.Now().UTC(),
+		Timestamp:     timestamp,
+		ValidTime:     o.validTime,
 		Data:          data,
-		Metadata:      metadata,
+		Metadata:      o.metadata,
 	}
 	ar.aggregateEvents = append(ar.aggregateEvents, event)
-	a.Transition(event)
+	apply(a, event)
+	return nil
 }
 
 // BuildFromHistory builds the aggregate state from events
 func (ar *AggregateRoot[T]) BuildFromHistory(a Aggregate[T], events []Event[T]) {
 	for _, event := range events {
-		a.Transition(event)
+		apply(a, event)
 		//Set the aggregate ID
 		ar.aggregateID = event.AggregateID
 		// Make sure the aggregate is in the correct version (the last event)
 		ar.aggregateVersion = event.Version
 		ar.aggregateGlobalVersion = event.GlobalVersion
+		ar.aggregateTimestamp = event.Timestamp
 	}
 }
 
-func (ar *AggregateRoot[T]) setInternals(id string, version, globalVersion Version) {
+func (ar *AggregateRoot[T]) setInternals(id string, version, globalVersion Version, timestamp time.Time) {
 	ar.aggregateID = id
 	ar.aggregateVersion = version
 	ar.aggregateGlobalVersion = globalVersion
+	ar.aggregateTimestamp = timestamp
 	ar.aggregateEvents = []Event[T]{}
 }
 
@@ -75,6 +126,20 @@ func (ar *AggregateRoot[T]) nextVersion() Version {
 	return ar.Version() + 1
 }
 
+// nextTimestamp returns the current time but never a time before the last event on the aggregate.
+// This makes sure the timestamps within an aggregate never goes backwards even if the clock is skewed.
+func (ar *AggregateRoot[T]) nextTimestamp() time.Time {
+	now := time.Now().UTC()
+	last := ar.aggregateTimestamp
+	if len(ar.aggregateEvents) > 0 {
+		last = ar.aggregateEvents[len(ar.aggregateEvents)-1].Timestamp
+	}
+	if now.Before(last) {
+		return last
+	}
+	return now
+}
+
 // update sets the AggregateVersion and AggregateGlobalVersion to the values in the last event
 // This function is called after the aggregate is saved in the repository
 func (ar *AggregateRoot[T]) update() {
@@ -82,6 +147,7 @@ func (ar *AggregateRoot[T]) update() {
 		lastEvent := ar.aggregateEvents[len(ar.aggregateEvents)-1]
 		ar.aggregateVersion = lastEvent.Version
 		ar.aggregateGlobalVersion = lastEvent.GlobalVersion
+		ar.aggregateTimestamp = lastEvent.Timestamp
 		ar.aggregateEvents = []Event[T]{}
 	}
 }
@@ -124,7 +190,7 @@ func (ar *AggregateRoot[T]) GlobalVersion() Version {
 	return ar.aggregateGlobalVersion
 }
 
-// Events return the aggregate events from the aggregate
+// Events return the unsaved events from the aggregate
 // make a copy of the slice preventing outsiders modifying events.
 func (ar *AggregateRoot[T]) Events() []Event[T] {
 	e := make([]Event[T], len(ar.aggregateEvents))
@@ -136,3 +202,16 @@ func (ar *AggregateRoot[T]) Events() []Event[T] {
 func (ar *AggregateRoot[T]) UnsavedEvents() bool {
 	return len(ar.aggregateEvents) > 0
 }
+
+// UnsavedEventCount returns the number of unsaved events on the aggregate
+func (ar *AggregateRoot[T]) UnsavedEventCount() int {
+	return len(ar.aggregateEvents)
+}
+
+// ClearUnsavedEvents drops the unsaved events and returns them, the version goes back to the last saved event.
+// The state changes made by the events are not reverted, get the aggregate from the repository to continue using it.
+func (ar *AggregateRoot[T]) ClearUnsavedEvents() []Event[T] {
+	events := ar.aggregateEvents
+	ar.aggregateEvents = []Event[T]{}
+	return events
+}
@@ -212,6 +212,32 @@ func TestIDFuncGeneratingRandomIDs(t *testing.T) {
 	}
 }
 
+func TestEventID(t *testing.T) {
+	person, _ := CreatePerson("kalle")
+	person.GrowOlder()
+	events := person.Events()
+	first, second := events[0].EventID, events[1].EventID
+	if len(first) != 36 || first[14] != '7' {
+		t.Fatalf("expected a UUIDv7 got %q", first)
+	}
+	if first == second {
+		t.Fatalf("expected unique event ids got %q twice", first)
+	}
+	// the time prefix sorts the ids in creation order
+	if first[:8] > second[:8] {
+		t.Fatalf("expected %q before %q", first, second)
+	}
+}
+
+func TestSetEventIDFunc(t *testing.T) {
+	defer eventsourcing.SetEventIDFunc(eventsourcing.NewUUIDv7)
+	eventsourcing.SetEventIDFunc(func() string { return "event-1" })
+	person, _ := CreatePerson("kalle")
+	if person.Events()[0].EventID != "event-1" {
+		t.Fatalf("event id not set via the new SetEventIDFunc got %q", person.Events()[0].EventID)
+	}
+}
+
 func TestMutateEvents(t *testing.T) {
 	var m = "mutated from the outside"
 	person, _ := CreatePerson("kalle")
@@ -222,3 +248,64 @@ func TestMutateEvents(t *testing.T) {
 		t.Fatal("events should not be mutated from the outside")
 	}
 }
+
+func TestUnsavedEvents(t *testing.T) {
+	person := Person{}
+	person.BuildFromHistory(&person, []eventsourcing.Event[PersonEvent]{
+		{AggregateID: "123", Version: 1, AggregateType: "Person", Data: &Born{Name: "kalle"}},
+	})
+	if person.UnsavedEvents() || person.UnsavedEventCount() != 0 {
+		t.Fatal("expected no unsaved events on a built aggregate")
+	}
+	person.GrowOlder()
+	person.GrowOlder()
+	if !person.UnsavedEvents() || person.UnsavedEventCount() != 2 {
+		t.Fatalf("expected two unsaved events got %d", person.UnsavedEventCount())
+	}
+	if person.Version() != 3 {
+		t.Fatalf("expected version 3 got %d", person.Version())
+	}
+
+	events := person.ClearUnsavedEvents()
+	if len(events) != 2 || events[1].Version != 3 {
+		t.Fatalf("expected the two cleared events got %v", events)
+	}
+	if person.UnsavedEvents() || len(person.Events()) != 0 {
+		t.Fatal("expected no unsaved events after clear")
+	}
+	if person.Version() != 1 {
+		t.Fatalf("expected the saved version 1 got %d", person.Version())
+	}
+}
+
+func TestTimestampNeverGoesBackwards(t *testing.T) {
+	future := time.Now().UTC().Add(time.Hour)
+	person := Person{}
+	person.BuildFromHistory(&person, []eventsourcing.Event[PersonEvent]{
+		{AggregateID: "123", Version: 1, AggregateType: "Person", Timestamp: future, Data: &Born{Name: "kalle"}},
+	})
+	person.GrowOlder()
+	person.GrowOlder()
+
+	events := person.Events()
+	if events[0].Timestamp.Before(future) {
+		t.Fatalf("event timestamp %v before last event timestamp %v", events[0].Timestamp, future)
+	}
+	if events[1].Timestamp.Before(events[0].Timestamp) {
+		t.Fatalf("event timestamp %v before previous event timestamp %v", events[1].Timestamp, events[0].Timestamp)
+	}
+}
+
+func TestVersionFromInt64(t *testing.T) {
+	v, err := eventsourcing.VersionFromInt64(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != 10 {
+		t.Fatalf("expected version 10 got %d", v)
+	}
+	_, err = eventsourcing.VersionFromInt64(-1)
+	if !errors.Is(err, eventsourcing.ErrVersionOverflow) {
+		t.Fatalf("expected ErrVersionOverflow got %v", err)
+	}
+}
@@ -0,0 +1,12 @@
+package eventsourcing
+
+import "context"
+
+// AggregateSubscriber is implemented by event stores that can deliver the events of a single aggregate as they are
+// saved, used to live update the view of one entity
+type AggregateSubscriber[T any] interface {
+	// SubscribeAggregate delivers the events of the aggregate after fromVersion in version order, the already saved
+	// events first followed by the events saved after the call. It blocks until the context is done or the handler
+	// fails.
+	SubscribeAggregate(ctx context.Context, aggregateType, id string, fromVersion Version, handler func(event Event[T]) error) error
+}
@@ -0,0 +1,84 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// MetadataSuccessorID is the metadata key on the terminal event of a closed stream holding the successor stream id
+const MetadataSuccessorID = "successor_id"
+
+// maxAliasDepth is the max number of aliases followed when resolving an aggregate id
+const maxAliasDepth = 100
+
+// ErrAliasCycle when the aliases of an aggregate id point back to an id already followed
+var ErrAliasCycle = errors.New("alias cycle")
+
+// AliasStore maps aggregate ids to the id of the stream holding the aggregate. Aliases are followed in a chain,
+// a ledger cut over every year has the aliases 2022 -> 2023 -> 2024.
+type AliasStore interface {
+	// Alias returns the id the aggregate id points to, ok is false if the id has no alias
+	Alias(ctx context.Context, aggregateType, id string) (target string, ok bool, err error)
+	// SetAlias points the aggregate id to the target id
+	SetAlias(ctx context.Context, aggregateType, id, target string) error
+}
+
+// SetAliasStore makes the repository resolve aggregate ids via the alias store before the aggregate is loaded
+func (r *Repository[T]) SetAliasStore(aliases AliasStore) {
+	r.aliases = aliases
+}
+
+// Resolve returns the id of the stream currently holding the aggregate
+func (r *Repository[T]) Resolve(ctx context.Context, aggregateType, id string) (string, error) {
+	if r.aliases == nil {
+		return id, nil
+	}
+	followed := map[string]struct{}{id: {}}
+	for i := 0; i < maxAliasDepth; i++ {
+		target, ok, err := r.aliases.Alias(ctx, aggregateType, id)
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			return id, nil
+		}
+		if _, ok := followed[target]; ok {
+			return "", fmt.Errorf("%w: %s %s", ErrAliasCycle, aggregateType, target)
+		}
+		followed[target] = struct{}{}
+		id = target
+	}
+	return "", fmt.Errorf("%w: more than %d aliases from %s %s", ErrAliasCycle, maxAliasDepth, aggregateType, id)
+}
+
+// Cutover closes the stream of the aggregate with the terminal event and continues the aggregate in the successor
+// stream, seeded with the seed event built from the state of the closed aggregate. The closed stream is kept as is
+// and its id is pointed to the successor so Get on any earlier id loads the successor.
+//
+// The terminal event is saved first, a concurrent writer to the closed stream makes the cutover fail on a
+// concurrency error. The alias is set last, until then the closed stream is returned from Get.
+func (r *Repository[T]) Cutover(ctx context.Context, aggregate Aggregate[T], terminal T, successor Aggregate[T], seed T) error {
+	if r.aliases == nil {
+		return errors.New("no alias store has been set")
+	}
+	err := successor.Root().TrackChange(successor, seed)
+	if err != nil {
+		return err
+	}
+	successorID := successor.Root().ID()
+	err = aggregate.Root().TrackChangeWithMetadata(aggregate, terminal, map[string]interface{}{MetadataSuccessorID: successorID})
+	if err != nil {
+		return err
+	}
+	err = r.Save(aggregate)
+	if err != nil {
+		return err
+	}
+	err = r.Save(successor)
+	if err != nil {
+		return err
+	}
+	return r.aliases.SetAlias(ctx, reflect.TypeOf(aggregate).Elem().Name(), aggregate.Root().ID(), successorID)
+}
@@ -0,0 +1,33 @@
+package memory
+
+import (
+	"context"
+	"sync"
+)
+
+// Memory is an alias store keeping the aliases in memory
+type Memory struct {
+	lock    sync.RWMutex
+	aliases map[string]string
+}
+
+// New constructs a memory alias store
+func New() *Memory {
+	return &Memory{aliases: make(map[string]string)}
+}
+
+// Alias returns the id the aggregate id points to
+func (m *Memory) Alias(ctx context.Context, aggregateType, id string) (string, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	target, ok := m.aliases[aggregateType+"_"+id]
+	return target, ok, nil
+}
+
+// SetAlias points the aggregate id to the target id
+func (m *Memory) SetAlias(ctx context.Context, aggregateType, id, target string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.aliases[aggregateType+"_"+id] = target
+	return nil
+}
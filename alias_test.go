@@ -0,0 +1,105 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	aliasmem "github.com/hallgren/eventsourcing/alias/memory"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Ledger struct {
+	eventsourcing.AggregateRoot[any]
+	Balance int
+	Closed  bool
+}
+
+type Opened struct{ Balance int }
+type Deposited struct{ Amount int }
+type YearClosed struct{}
+
+func (l *Ledger) Transition(event eventsourcing.Event[any]) {
+	switch e := event.Data.(type) {
+	case *Opened:
+		l.Balance = e.Balance
+	case *Deposited:
+		l.Balance += e.Amount
+	case *YearClosed:
+		l.Closed = true
+	}
+}
+
+func TestCutover(t *testing.T) {
+	es := memory.Create[any]()
+	repo := eventsourcing.NewRepository[any](es, nil)
+	repo.SetAliasStore(aliasmem.New())
+
+	ledger := &Ledger{}
+	ledger.SetID("2022")
+	ledger.TrackChange(ledger, &Opened{Balance: 10})
+	ledger.TrackChange(ledger, &Deposited{Amount: 5})
+	err := repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	successor := &Ledger{}
+	successor.SetID("2023")
+	err = repo.Cutover(context.Background(), ledger, &YearClosed{}, successor, &Opened{Balance: ledger.Balance})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the closed stream is kept with the terminal event pointing to the successor
+	iter, err := es.Get(context.Background(), "2022", "Ledger", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	terminal, err := iter.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if terminal.Metadata[eventsourcing.MetadataSuccessorID] != "2023" {
+		t.Fatalf("expected the successor id on the terminal event got %v", terminal.Metadata)
+	}
+
+	// the old id resolves to the successor
+	l := Ledger{}
+	err = repo.Get("2022", &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ID() != "2023" || l.Balance != 15 || l.Closed {
+		t.Fatalf("expected the successor ledger got id %s balance %d closed %v", l.ID(), l.Balance, l.Closed)
+	}
+
+	// a second cutover is followed in a chain
+	successor = &Ledger{}
+	successor.SetID("2024")
+	err = repo.Cutover(context.Background(), &l, &YearClosed{}, successor, &Opened{Balance: l.Balance})
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := repo.Resolve(context.Background(), "Ledger", "2022")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "2024" {
+		t.Fatalf("expected 2024 got %s", id)
+	}
+}
+
+func TestResolveAliasCycle(t *testing.T) {
+	aliases := aliasmem.New()
+	aliases.SetAlias(context.Background(), "Ledger", "a", "b")
+	aliases.SetAlias(context.Background(), "Ledger", "b", "a")
+	repo := eventsourcing.NewRepository[any](memory.Create[any](), nil)
+	repo.SetAliasStore(aliases)
+
+	err := repo.Get("a", &Ledger{})
+	if !errors.Is(err, eventsourcing.ErrAliasCycle) {
+		t.Fatalf("expected ErrAliasCycle got %v", err)
+	}
+}
@@ -0,0 +1,97 @@
+// Package archive reads and writes event streams in a compressed file format used for archival and backups.
+//
+// An archive file is laid out as
+//
+//	magic "ESARCHV1"
+//	chunk 1 .. chunk n
+//	manifest
+//	manifest length, 8 bytes big endian
+//	magic "ESARCHV1"
+//
+// Each chunk is a gzip stream of newline separated JSON records, one per event, in global version order. The event
+// data is serialized with the serializer of the event store and stored base64 encoded in the record. The manifest is
+// JSON and holds the byte offset, length, sha256 checksum and global version range of each chunk, making it possible
+// to read the events from a global version without decompressing the chunks before it. Snapshots are kept in chunks
+// of their own, listed separately in the manifest.
+//
+// The manifest is written last so an archive can be streamed to writers that can't seek, the reader needs random
+// access to find it.
+package archive
+
+import (
+	"errors"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// FormatVersion is the version of the archive format written by the Writer
+const FormatVersion = 1
+
+const (
+	magic         = "ESARCHV1"
+	trailerLength = 8 + len(magic)
+)
+
+var (
+	// ErrInvalidArchive when the file is not an archive or its manifest is corrupt
+	ErrInvalidArchive = errors.New("invalid archive")
+	// ErrChecksumMismatch when the content of a chunk does not match its checksum in the manifest
+	ErrChecksumMismatch = errors.New("chunk checksum mismatch")
+	// ErrOutOfOrder when events are not written in global version order
+	ErrOutOfOrder = errors.New("events out of global version order")
+	// ErrUnregisteredEvent when the archive holds an event not registered in the serializer
+	ErrUnregisteredEvent = errors.New("event not registered in the serializer")
+	// ErrWriterClosed when writing to a closed Writer
+	ErrWriterClosed = errors.New("archive writer closed")
+)
+
+// Manifest describes the content of an archive
+type Manifest struct {
+	FormatVersion      int                   `json:"format_version"`
+	Created            time.Time             `json:"created"`
+	Events             uint64                `json:"events"`
+	FirstGlobalVersion eventsourcing.Version `json:"first_global_version"`
+	LastGlobalVersion  eventsourcing.Version `json:"last_global_version"`
+	Chunks             []Chunk               `json:"chunks"`
+	Snapshots          uint64                `json:"snapshots,omitempty"`
+	SnapshotChunks     []Chunk               `json:"snapshot_chunks,omitempty"`
+}
+
+// Chunk is the index entry of a compressed chunk of events or snapshots
+type Chunk struct {
+	// Offset is the position of the chunk from the start of the file
+	Offset int64 `json:"offset"`
+	// Length is the compressed length of the chunk
+	Length int64 `json:"length"`
+	// Records is the number of events or snapshots in the chunk
+	Records            int                   `json:"records"`
+	FirstGlobalVersion eventsourcing.Version `json:"first_global_version"`
+	LastGlobalVersion  eventsourcing.Version `json:"last_global_version"`
+	// Checksum is the hex encoded sha256 of the compressed chunk
+	Checksum string `json:"checksum"`
+}
+
+// record is the archived form of an event
+type record struct {
+	EventID       string                 `json:"event_id,omitempty"`
+	AggregateID   string                 `json:"aggregate_id"`
+	AggregateType string                 `json:"aggregate_type"`
+	Version       eventsourcing.Version  `json:"version"`
+	GlobalVersion eventsourcing.Version  `json:"global_version"`
+	Reason        string                 `json:"reason"`
+	Timestamp     time.Time              `json:"timestamp"`
+	ValidTime     time.Time              `json:"valid_time"`
+	Data          []byte                 `json:"data"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// snapshotRecord is the archived form of a snapshot
+type snapshotRecord struct {
+	ID            string                `json:"id"`
+	Type          string                `json:"type"`
+	Version       eventsourcing.Version `json:"version"`
+	GlobalVersion eventsourcing.Version `json:"global_version"`
+	Timestamp     time.Time             `json:"timestamp"`
+	State         []byte                `json:"state"`
+}
@@ -0,0 +1,168 @@
+package archive_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/archive"
+)
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {}
+
+type Opened struct {
+	Owner string
+}
+
+type Deposited struct {
+	Amount int
+}
+
+func serializer(t *testing.T) *eventsourcing.Serializer[any] {
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Account{}, ser.Events(&Opened{}, &Deposited{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ser
+}
+
+// events returns count events spread over ten accounts
+func events(count int) []eventsourcing.Event[any] {
+	var events []eventsourcing.Event[any]
+	for i := 1; i <= count; i++ {
+		id := fmt.Sprint(i % 10)
+		version := eventsourcing.Version((i-1)/10 + 1)
+		var data any = &Deposited{Amount: i}
+		if version == 1 {
+			data = &Opened{Owner: id}
+		}
+		events = append(events, eventsourcing.Event[any]{
+			AggregateID:   id,
+			AggregateType: "Account",
+			Version:       version,
+			GlobalVersion: eventsourcing.Version(i),
+			Timestamp:     time.Date(2024, 1, 1, 0, 0, i, 0, time.UTC),
+			Data:          data,
+			Metadata:      map[string]interface{}{"n": float64(i)},
+		})
+	}
+	return events
+}
+
+func write(t *testing.T, ser *eventsourcing.Serializer[any], events []eventsourcing.Event[any]) *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	w, err := archive.NewWriter(buf, *ser, archive.WithChunkSize(7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Write(events...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = w.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestWriteAndRead(t *testing.T) {
+	ser := serializer(t)
+	expected := events(50)
+	buf := write(t, ser, expected)
+
+	r, err := archive.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()), *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := r.Manifest()
+	if m.Events != 50 || len(m.Chunks) != 8 || m.FirstGlobalVersion != 1 || m.LastGlobalVersion != 50 {
+		t.Fatalf("unexpected manifest %+v", m)
+	}
+
+	it := r.Events(0)
+	defer it.Close()
+	for _, e := range expected {
+		event, err := it.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.GlobalVersion != e.GlobalVersion || event.AggregateID != e.AggregateID || event.Version != e.Version ||
+			!event.Timestamp.Equal(e.Timestamp) || event.Reason() != e.Reason() || event.Metadata["n"] != e.Metadata["n"] {
+			t.Fatalf("expected %+v got %+v", e, event)
+		}
+	}
+	_, err = it.Next()
+	if !errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+		t.Fatalf("expected no more events got %v", err)
+	}
+}
+
+func TestReadFromGlobalVersion(t *testing.T) {
+	ser := serializer(t)
+	buf := write(t, ser, events(50))
+	r, err := archive.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()), *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	it := r.Events(31)
+	event, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.GlobalVersion != 31 {
+		t.Fatalf("expected global version 31 got %d", event.GlobalVersion)
+	}
+	if d, ok := event.Data.(*Deposited); !ok || d.Amount != 31 {
+		t.Fatalf("expected deposit of 31 got %#v", event.Data)
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	ser := serializer(t)
+	buf := write(t, ser, events(20))
+	b := buf.Bytes()
+	r, err := archive.Open(bytes.NewReader(b), int64(len(b)), *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corrupt the second chunk
+	chunk := r.Manifest().Chunks[1]
+	b[chunk.Offset+chunk.Length/2] ^= 0xff
+
+	it := r.Events(chunk.FirstGlobalVersion)
+	_, err = it.Next()
+	if !errors.Is(err, archive.ErrChecksumMismatch) {
+		t.Fatalf("expected checksum mismatch got %v", err)
+	}
+}
+
+func TestWriteOutOfOrder(t *testing.T) {
+	ser := serializer(t)
+	w, err := archive.NewWriter(&bytes.Buffer{}, *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := events(2)
+	err = w.Write(e[1], e[0])
+	if !errors.Is(err, archive.ErrOutOfOrder) {
+		t.Fatalf("expected out of order got %v", err)
+	}
+}
+
+func TestOpenInvalidArchive(t *testing.T) {
+	b := []byte("not an archive, just some bytes")
+	_, err := archive.Open(bytes.NewReader(b), int64(len(b)), *serializer(t))
+	if !errors.Is(err, archive.ErrInvalidArchive) {
+		t.Fatalf("expected invalid archive got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package archive
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const backupBatchSize = 1000
+
+// ErrIncompleteArchive when the events restored from an archive don't match its manifest
+var ErrIncompleteArchive = errors.New("incomplete archive")
+
+type aggregateKey struct {
+	aggregateType string
+	id            string
+}
+
+// Backup writes all events in the event store, in global version order, to the archive and closes the writer. The
+// backup holds the events up to the last global version in the manifest, events saved during the backup are included
+// until the last read. If snapshots is not nil the latest snapshot of each aggregate in the backup is added, snapshots
+// newer than the backed up events of their aggregate are left out.
+func Backup[T any](ctx context.Context, store eventsourcing.GlobalEventStore[T], snapshots eventsourcing.SnapshotStore, w *Writer[T]) error {
+	versions := make(map[aggregateKey]eventsourcing.Version)
+	start := uint64(1)
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		events, err := store.GlobalEvents(start, backupBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			break
+		}
+		err = w.Write(events...)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			versions[aggregateKey{event.AggregateType, event.AggregateID}] = event.Version
+		}
+		start = uint64(events[len(events)-1].GlobalVersion) + 1
+	}
+	if snapshots != nil {
+		for key, version := range versions {
+			s, err := snapshots.Get(ctx, key.id, key.aggregateType)
+			if errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+				continue
+			} else if err != nil {
+				return err
+			}
+			if s.Version > version {
+				// the snapshot holds events saved after the backup
+				continue
+			}
+			err = w.WriteSnapshots(s)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return w.Close()
+}
+
+// Restore saves the events in the archive to the event store, and the snapshots to the snapshot store if not nil.
+// The stores are expected to be empty. The aggregate versions are verified to be continuous from version 1 and the
+// number of restored events to match the manifest, the global versions are set by the event store.
+func Restore[T any](ctx context.Context, r *Reader[T], store eventsourcing.EventStore[T], snapshots eventsourcing.SnapshotStore) error {
+	versions := make(map[aggregateKey]eventsourcing.Version)
+	restored := uint64(0)
+	var batch []eventsourcing.Event[T]
+	save := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := store.Save(batch)
+		if err != nil {
+			return fmt.Errorf("restore %s %s: %w", batch[0].AggregateType, batch[0].AggregateID, err)
+		}
+		restored += uint64(len(batch))
+		batch = nil
+		return nil
+	}
+
+	iterator := r.Events(0)
+	defer iterator.Close()
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			break
+		} else if err != nil {
+			return err
+		}
+		key := aggregateKey{event.AggregateType, event.AggregateID}
+		if event.Version != versions[key]+1 {
+			return fmt.Errorf("%w: %s %s expected version %d got %d", eventsourcing.ErrEventVersionGap, event.AggregateType, event.AggregateID, versions[key]+1, event.Version)
+		}
+		versions[key] = event.Version
+		// keep the global order by saving the consecutive events of an aggregate together
+		if len(batch) > 0 && (batch[0].AggregateType != event.AggregateType || batch[0].AggregateID != event.AggregateID) {
+			err = save()
+			if err != nil {
+				return err
+			}
+		}
+		event.GlobalVersion = 0
+		batch = append(batch, event)
+	}
+	err := save()
+	if err != nil {
+		return err
+	}
+	if restored != r.Manifest().Events {
+		return fmt.Errorf("%w: restored %d events of %d", ErrIncompleteArchive, restored, r.Manifest().Events)
+	}
+	if snapshots == nil {
+		return nil
+	}
+
+	snapshotIterator := r.Snapshots()
+	defer snapshotIterator.Close()
+	for {
+		s, err := snapshotIterator.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if s.Version > versions[aggregateKey{s.Type, s.ID}] {
+			return fmt.Errorf("%w: snapshot of %s %s at version %d is ahead of its events", ErrIncompleteArchive, s.Type, s.ID, s.Version)
+		}
+		err = snapshots.Save(s)
+		if err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,90 @@
+package archive_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/archive"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	snapshots "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestBackupAndRestore(t *testing.T) {
+	ctx := context.Background()
+	ser := serializer(t)
+	es := memory.Create[any]()
+	for _, e := range events(2500) {
+		err := es.Save([]eventsourcing.Event[any]{e})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	ss := snapshots.New()
+	ss.Save(eventsourcing.Snapshot{ID: "1", Type: "Account", Version: 100, GlobalVersion: 991, State: []byte("state")})
+	// a snapshot ahead of the events is left out
+	ss.Save(eventsourcing.Snapshot{ID: "2", Type: "Account", Version: 300, State: []byte("ahead")})
+
+	buf := &bytes.Buffer{}
+	w, err := archive.NewWriter(buf, *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = archive.Backup[any](ctx, es, ss, w)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := archive.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()), *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.Manifest().Events != 2500 || r.Manifest().Snapshots != 1 {
+		t.Fatalf("expected 2500 events and 1 snapshot got %d %d", r.Manifest().Events, r.Manifest().Snapshots)
+	}
+
+	restored := memory.Create[any]()
+	restoredSnapshots := snapshots.New()
+	err = archive.Restore[any](ctx, r, restored, restoredSnapshots)
+	if err != nil {
+		t.Fatal(err)
+	}
+	original, _ := es.GlobalEvents(1, 3000)
+	copied, _ := restored.GlobalEvents(1, 3000)
+	if len(copied) != len(original) {
+		t.Fatalf("expected %d events got %d", len(original), len(copied))
+	}
+	for i := range original {
+		if original[i].GlobalVersion != copied[i].GlobalVersion || original[i].AggregateID != copied[i].AggregateID || original[i].Version != copied[i].Version {
+			t.Fatalf("expected %+v got %+v", original[i], copied[i])
+		}
+	}
+	s, err := restoredSnapshots.Get(ctx, "1", "Account")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(s.State) != "state" || s.Version != 100 {
+		t.Fatalf("unexpected snapshot %+v", s)
+	}
+	_, err = restoredSnapshots.Get(ctx, "2", "Account")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected the snapshot ahead of the events to be left out got %v", err)
+	}
+}
+
+func TestRestoreVersionGap(t *testing.T) {
+	ser := serializer(t)
+	e := events(30)
+	// drop version 2 of aggregate 1
+	e = append(e[:10], e[11:]...)
+	buf := write(t, ser, e)
+	r, err := archive.Open(bytes.NewReader(buf.Bytes()), int64(buf.Len()), *ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = archive.Restore[any](context.Background(), r, memory.Create[any](), nil)
+	if !errors.Is(err, eventsourcing.ErrEventVersionGap) {
+		t.Fatalf("expected version gap got %v", err)
+	}
+}
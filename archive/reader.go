@@ -0,0 +1,214 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Reader reads events from an archive
+type Reader[T any] struct {
+	r          io.ReaderAt
+	serializer eventsourcing.Serializer[T]
+	manifest   Manifest
+}
+
+// Open reads the manifest of the archive in r of size bytes
+func Open[T any](r io.ReaderAt, size int64, serializer eventsourcing.Serializer[T]) (*Reader[T], error) {
+	if size < int64(len(magic)+trailerLength) {
+		return nil, fmt.Errorf("%w: too short", ErrInvalidArchive)
+	}
+	header := make([]byte, len(magic))
+	_, err := r.ReadAt(header, 0)
+	if err != nil {
+		return nil, err
+	}
+	trailer := make([]byte, trailerLength)
+	_, err = r.ReadAt(trailer, size-int64(trailerLength))
+	if err != nil {
+		return nil, err
+	}
+	if string(header) != magic || string(trailer[8:]) != magic {
+		return nil, fmt.Errorf("%w: magic missing", ErrInvalidArchive)
+	}
+	length := binary.BigEndian.Uint64(trailer)
+	if length > uint64(size-int64(len(magic)+trailerLength)) {
+		return nil, fmt.Errorf("%w: manifest length %d", ErrInvalidArchive, length)
+	}
+	b := make([]byte, length)
+	_, err = r.ReadAt(b, size-int64(trailerLength)-int64(length))
+	if err != nil {
+		return nil, err
+	}
+	manifest := Manifest{}
+	err = json.Unmarshal(b, &manifest)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidArchive, err)
+	}
+	if manifest.FormatVersion != FormatVersion {
+		return nil, fmt.Errorf("%w: format version %d", ErrInvalidArchive, manifest.FormatVersion)
+	}
+	return &Reader[T]{r: r, serializer: serializer, manifest: manifest}, nil
+}
+
+// Manifest returns the manifest of the archive
+func (r *Reader[T]) Manifest() Manifest {
+	return r.manifest
+}
+
+// Events returns an iterator over the events from the global version. Chunks holding only earlier events are not read.
+func (r *Reader[T]) Events(from eventsourcing.Version) *Iterator[T] {
+	first := sort.Search(len(r.manifest.Chunks), func(i int) bool {
+		return r.manifest.Chunks[i].LastGlobalVersion >= from
+	})
+	return &Iterator[T]{reader: r, chunks: chunkReader{r: r.r, chunks: r.manifest.Chunks[first:]}, from: from}
+}
+
+// Snapshots returns an iterator over the snapshots in the archive
+func (r *Reader[T]) Snapshots() *SnapshotIterator {
+	return &SnapshotIterator{chunks: chunkReader{r: r.r, chunks: r.manifest.SnapshotChunks}}
+}
+
+// Iterator over the events in an archive, implements eventsourcing.EventIterator
+type Iterator[T any] struct {
+	reader *Reader[T]
+	chunks chunkReader
+	from   eventsourcing.Version
+}
+
+// Next returns the next event, ErrNoMoreEvents when all events are read
+func (i *Iterator[T]) Next() (eventsourcing.Event[T], error) {
+	for {
+		rec := record{}
+		err := i.chunks.next(&rec)
+		if errors.Is(err, io.EOF) {
+			return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
+		} else if err != nil {
+			return eventsourcing.Event[T]{}, fmt.Errorf("could not deserialize event, %w", err)
+		}
+		if rec.GlobalVersion < i.from {
+			continue
+		}
+		return i.reader.toEvent(rec)
+	}
+}
+
+// Close the iterator
+func (i *Iterator[T]) Close() {
+	i.chunks.close()
+}
+
+// SnapshotIterator over the snapshots in an archive
+type SnapshotIterator struct {
+	chunks chunkReader
+}
+
+// Next returns the next snapshot, io.EOF when all snapshots are read
+func (i *SnapshotIterator) Next() (eventsourcing.Snapshot, error) {
+	rec := snapshotRecord{}
+	err := i.chunks.next(&rec)
+	if errors.Is(err, io.EOF) {
+		return eventsourcing.Snapshot{}, io.EOF
+	} else if err != nil {
+		return eventsourcing.Snapshot{}, fmt.Errorf("could not deserialize snapshot, %w", err)
+	}
+	return eventsourcing.Snapshot{
+		ID:            rec.ID,
+		Type:          rec.Type,
+		Version:       rec.Version,
+		GlobalVersion: rec.GlobalVersion,
+		Timestamp:     rec.Timestamp,
+		State:         rec.State,
+	}, nil
+}
+
+// Close the iterator
+func (i *SnapshotIterator) Close() {
+	i.chunks.close()
+}
+
+// chunkReader decodes the records of the chunks in order
+type chunkReader struct {
+	r      io.ReaderAt
+	chunks []Chunk
+	dec    *json.Decoder
+}
+
+// next decodes the next record into v, io.EOF when all chunks are read
+func (c *chunkReader) next(v any) error {
+	for {
+		if c.dec == nil {
+			if len(c.chunks) == 0 {
+				return io.EOF
+			}
+			err := c.open(c.chunks[0])
+			if err != nil {
+				return err
+			}
+			c.chunks = c.chunks[1:]
+		}
+		err := c.dec.Decode(v)
+		if errors.Is(err, io.EOF) {
+			c.dec = nil
+			continue
+		}
+		return err
+	}
+}
+
+func (c *chunkReader) close() {
+	c.chunks = nil
+	c.dec = nil
+}
+
+// open verifies the checksum of the chunk and prepares it for decoding
+func (c *chunkReader) open(chunk Chunk) error {
+	b := make([]byte, chunk.Length)
+	_, err := c.r.ReadAt(b, chunk.Offset)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b)
+	if hex.EncodeToString(sum[:]) != chunk.Checksum {
+		return fmt.Errorf("%w: chunk at offset %d", ErrChecksumMismatch, chunk.Offset)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	c.dec = json.NewDecoder(gz)
+	return nil
+}
+
+func (r *Reader[T]) toEvent(rec record) (eventsourcing.Event[T], error) {
+	f, ok := r.serializer.Type(rec.AggregateType, rec.Reason)
+	if !ok {
+		// unlike the event stores the event is not skipped, a restore would lose it
+		return eventsourcing.Event[T]{}, fmt.Errorf("%w: %s %s", ErrUnregisteredEvent, rec.AggregateType, rec.Reason)
+	}
+	data := f()
+	err := r.serializer.Unmarshal(rec.Data, &data)
+	if err != nil {
+		return eventsourcing.Event[T]{}, fmt.Errorf("could not deserialize event data, %w", err)
+	}
+	return eventsourcing.Event[T]{
+		EventID:       rec.EventID,
+		AggregateID:   rec.AggregateID,
+		AggregateType: rec.AggregateType,
+		Version:       rec.Version,
+		GlobalVersion: rec.GlobalVersion,
+		Timestamp:     rec.Timestamp,
+		ValidTime:     rec.ValidTime,
+		Data:          data,
+		Metadata:      rec.Metadata,
+	}, nil
+}
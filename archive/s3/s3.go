@@ -0,0 +1,234 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/archive"
+)
+
+// ErrArchiveExists when an archive of the same global version range is already stored
+var ErrArchiveExists = errors.New("archive already exists")
+
+// API is the part of the s3 client used by the archive store, it's implemented by *s3.Client
+type API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// Option configures the s3 archive store
+type Option func(*options)
+
+type options struct {
+	prefix        string
+	writerOptions []archive.Option
+}
+
+// WithPrefix sets a prefix on the object keys
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithWriterOptions sets the options of the archive writer, like the chunk size
+func WithWriterOptions(opts ...archive.Option) Option {
+	return func(o *options) {
+		o.writerOptions = opts
+	}
+}
+
+// S3 is the archival store writing events as archive files to objects keyed by prefix and the global version range
+// of the events. Reads fetch the manifest and the chunks from the global version with ranged gets, the chunks before
+// it are not downloaded.
+type S3[T any] struct {
+	client     API
+	bucket     string
+	serializer eventsourcing.Serializer[T]
+	options    options
+}
+
+// New returns a S3 archive store
+func New[T any](client API, bucket string, serializer eventsourcing.Serializer[T], opts ...Option) *S3[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &S3[T]{
+		client:     client,
+		bucket:     bucket,
+		serializer: serializer,
+		options:    o,
+	}
+}
+
+// Archive writes the events to a new archive object. The events have to be saved events in global version order,
+// each call is expected to archive the events after the ones archived before.
+func (s *S3[T]) Archive(ctx context.Context, events ...eventsourcing.Event[T]) error {
+	if len(events) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	w, err := archive.NewWriter(&buf, s.serializer, s.options.writerOptions...)
+	if err != nil {
+		return err
+	}
+	err = w.Write(events...)
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(s.key(events[0].GlobalVersion, events[len(events)-1].GlobalVersion)),
+		Body:        bytes.NewReader(buf.Bytes()),
+		IfNoneMatch: aws.String("*"),
+	})
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed" {
+		return ErrArchiveExists
+	}
+	return err
+}
+
+// Events returns an iterator over the archived events from the global version
+func (s *S3[T]) Events(ctx context.Context, from eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	objects, err := s.objects(ctx)
+	if err != nil {
+		return nil, err
+	}
+	first := sort.Search(len(objects), func(i int) bool {
+		return objects[i].last >= from
+	})
+	return &iterator[T]{ctx: ctx, store: s, objects: objects[first:], from: from}, nil
+}
+
+// object is an archive object with the global version range parsed from the key
+type object struct {
+	key         string
+	size        int64
+	first, last eventsourcing.Version
+}
+
+// objects lists the archive objects in global version order
+func (s *S3[T]) objects(ctx context.Context) ([]object, error) {
+	var objects []object
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(s.options.prefix),
+	}
+	for {
+		out, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range out.Contents {
+			key := aws.ToString(o.Key)
+			var first, last uint64
+			_, err = fmt.Sscanf(strings.TrimPrefix(key, s.options.prefix), "%d-%d", &first, &last)
+			if err != nil || key != s.key(eventsourcing.Version(first), eventsourcing.Version(last)) {
+				// not an archive object
+				continue
+			}
+			objects = append(objects, object{key: key, size: aws.ToInt64(o.Size), first: eventsourcing.Version(first), last: eventsourcing.Version(last)})
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+	sort.Slice(objects, func(i, j int) bool {
+		return objects[i].first < objects[j].first
+	})
+	return objects, nil
+}
+
+// key builds the object key from the global version range, zero padded to list the objects in order
+func (s *S3[T]) key(first, last eventsourcing.Version) string {
+	return fmt.Sprintf("%s%020d-%020d", s.options.prefix, uint64(first), uint64(last))
+}
+
+// iterator reads the events of the archive objects one object at a time
+type iterator[T any] struct {
+	ctx     context.Context
+	store   *S3[T]
+	objects []object
+	from    eventsourcing.Version
+	current *archive.Iterator[T]
+}
+
+// Next returns the next event, ErrNoMoreEvents when all objects are read
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	for {
+		if i.current == nil {
+			if len(i.objects) == 0 {
+				return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
+			}
+			o := i.objects[0]
+			i.objects = i.objects[1:]
+			r, err := archive.Open(&objectReader{ctx: i.ctx, client: i.store.client, bucket: i.store.bucket, key: o.key}, o.size, i.store.serializer)
+			if err != nil {
+				return eventsourcing.Event[T]{}, fmt.Errorf("could not open archive %s, %w", o.key, err)
+			}
+			i.current = r.Events(i.from)
+		}
+		event, err := i.current.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			i.current.Close()
+			i.current = nil
+			continue
+		}
+		return event, err
+	}
+}
+
+// Close the iterator
+func (i *iterator[T]) Close() {
+	if i.current != nil {
+		i.current.Close()
+		i.current = nil
+	}
+	i.objects = nil
+}
+
+// objectReader reads byte ranges of an object
+type objectReader struct {
+	ctx    context.Context
+	client API
+	bucket string
+	key    string
+}
+
+// ReadAt gets the byte range of p from the offset
+func (r *objectReader) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	out, err := r.client.GetObject(r.ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(r.key),
+		Range:  aws.String(fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	defer out.Body.Close()
+	n, err := io.ReadFull(out.Body, p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
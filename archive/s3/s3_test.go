@@ -0,0 +1,161 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/archive"
+	"github.com/hallgren/eventsourcing/archive/s3"
+)
+
+// fakeS3 is an in memory bucket supporting the ranged gets and conditional puts used by the store
+type fakeS3 struct {
+	lock    sync.Mutex
+	objects map[string][]byte
+	gets    int
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string][]byte)}
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.gets++
+	body, ok := f.objects[*params.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	if params.Range != nil {
+		var start, end int
+		_, err := fmt.Sscanf(*params.Range, "bytes=%d-%d", &start, &end)
+		if err != nil {
+			return nil, err
+		}
+		if end >= len(body) {
+			end = len(body) - 1
+		}
+		body = body[start : end+1]
+	}
+	return &awss3.GetObjectOutput{Body: io.NopCloser(bytes.NewReader(body))}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *awss3.PutObjectInput, optFns ...func(*awss3.Options)) (*awss3.PutObjectOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if _, exists := f.objects[*params.Key]; exists && params.IfNoneMatch != nil {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.objects[*params.Key] = body
+	return &awss3.PutObjectOutput{}, nil
+}
+
+// ListObjectsV2 lists one object per page to exercise the pagination
+func (f *fakeS3) ListObjectsV2(ctx context.Context, params *awss3.ListObjectsV2Input, optFns ...func(*awss3.Options)) (*awss3.ListObjectsV2Output, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	var keys []string
+	for key := range f.objects {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) && key > aws.ToString(params.ContinuationToken) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	if len(keys) == 0 {
+		return &awss3.ListObjectsV2Output{IsTruncated: aws.Bool(false)}, nil
+	}
+	return &awss3.ListObjectsV2Output{
+		Contents:              []types.Object{{Key: aws.String(keys[0]), Size: aws.Int64(int64(len(f.objects[keys[0]])))}},
+		IsTruncated:           aws.Bool(len(keys) > 1),
+		NextContinuationToken: aws.String(keys[0]),
+	}, nil
+}
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {}
+
+type Deposited struct {
+	Amount int
+}
+
+func events(from, to int) []eventsourcing.Event[any] {
+	var events []eventsourcing.Event[any]
+	for i := from; i <= to; i++ {
+		events = append(events, eventsourcing.Event[any]{
+			AggregateID:   "1",
+			AggregateType: "Account",
+			Version:       eventsourcing.Version(i),
+			GlobalVersion: eventsourcing.Version(i),
+			Data:          &Deposited{Amount: i},
+		})
+	}
+	return events
+}
+
+func TestArchive(t *testing.T) {
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Account{}, ser.Events(&Deposited{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := newFakeS3()
+	client.objects["events/other"] = []byte("not an archive")
+	store := s3.New[any](client, "bucket", *ser, s3.WithPrefix("events/"), s3.WithWriterOptions(archive.WithChunkSize(3)))
+
+	ctx := context.Background()
+	for _, batch := range [][]eventsourcing.Event[any]{events(1, 10), events(11, 20)} {
+		err = store.Archive(ctx, batch...)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = store.Archive(ctx, events(11, 20)...)
+	if !errors.Is(err, s3.ErrArchiveExists) {
+		t.Fatalf("expected ErrArchiveExists got %v", err)
+	}
+
+	iterator, err := store.Events(ctx, 15)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	client.gets = 0
+	var amounts []int
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		amounts = append(amounts, event.Data.(*Deposited).Amount)
+	}
+	if fmt.Sprint(amounts) != "[15 16 17 18 19 20]" {
+		t.Fatalf("expected the events from global version 15 got %v", amounts)
+	}
+	// header, trailer and manifest of the second archive and its three chunks from version 15
+	if client.gets != 6 {
+		t.Fatalf("expected only the second archive to be read got %d gets", client.gets)
+	}
+}
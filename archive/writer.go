@@ -0,0 +1,222 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const defaultChunkSize = 1000
+
+// Option to configure the Writer
+type Option func(*options)
+
+type options struct {
+	chunkSize int
+}
+
+// WithChunkSize sets the number of events per chunk, default 1000. Smaller chunks make seeking cheaper at the cost of
+// compression.
+func WithChunkSize(events int) Option {
+	return func(o *options) {
+		if events > 0 {
+			o.chunkSize = events
+		}
+	}
+}
+
+// Writer writes events and snapshots to an archive
+type Writer[T any] struct {
+	out        io.Writer
+	serializer eventsourcing.Serializer[T]
+	chunkSize  int
+	offset     int64
+	manifest   Manifest
+	events     chunkBuilder
+	snapshots  chunkBuilder
+	closed     bool
+}
+
+// chunkBuilder compresses the records of the chunk being built
+type chunkBuilder struct {
+	buf   bytes.Buffer
+	gz    *gzip.Writer
+	enc   *json.Encoder
+	chunk Chunk
+}
+
+// add encodes the record to the chunk, starting a new chunk if there is none
+func (b *chunkBuilder) add(v any, globalVersion eventsourcing.Version) error {
+	if b.gz == nil {
+		b.buf.Reset()
+		b.gz = gzip.NewWriter(&b.buf)
+		b.enc = json.NewEncoder(b.gz)
+		b.chunk = Chunk{FirstGlobalVersion: globalVersion}
+	}
+	err := b.enc.Encode(v)
+	if err != nil {
+		return err
+	}
+	b.chunk.Records++
+	if globalVersion < b.chunk.FirstGlobalVersion {
+		b.chunk.FirstGlobalVersion = globalVersion
+	}
+	if globalVersion > b.chunk.LastGlobalVersion {
+		b.chunk.LastGlobalVersion = globalVersion
+	}
+	return nil
+}
+
+// NewWriter writes the archive header to w and returns a Writer. The archive is not complete until Close is called.
+func NewWriter[T any](w io.Writer, serializer eventsourcing.Serializer[T], opts ...Option) (*Writer[T], error) {
+	o := options{chunkSize: defaultChunkSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	n, err := io.WriteString(w, magic)
+	if err != nil {
+		return nil, err
+	}
+	return &Writer[T]{
+		out:        w,
+		serializer: serializer,
+		chunkSize:  o.chunkSize,
+		offset:     int64(n),
+		manifest:   Manifest{FormatVersion: FormatVersion, Created: time.Now().UTC(), Chunks: []Chunk{}},
+	}, nil
+}
+
+// Write adds the events to the archive. The events have to be saved events written in global version order.
+func (w *Writer[T]) Write(events ...eventsourcing.Event[T]) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	for _, event := range events {
+		if event.GlobalVersion == 0 || event.GlobalVersion <= w.manifest.LastGlobalVersion {
+			return fmt.Errorf("%w: %d after %d", ErrOutOfOrder, event.GlobalVersion, w.manifest.LastGlobalVersion)
+		}
+		data, err := w.serializer.Marshal(event.Data)
+		if err != nil {
+			return fmt.Errorf("could not serialize event data, %w", err)
+		}
+		err = w.events.add(record{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       event.Version,
+			GlobalVersion: event.GlobalVersion,
+			Reason:        event.Reason(),
+			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
+			Data:          data,
+			Metadata:      event.Metadata,
+		}, event.GlobalVersion)
+		if err != nil {
+			return err
+		}
+		if w.manifest.FirstGlobalVersion == 0 {
+			w.manifest.FirstGlobalVersion = event.GlobalVersion
+		}
+		w.manifest.LastGlobalVersion = event.GlobalVersion
+		w.manifest.Events++
+		if w.events.chunk.Records >= w.chunkSize {
+			err = w.flush(&w.events, &w.manifest.Chunks)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// WriteSnapshots adds the snapshots to the archive, they can be written in any order
+func (w *Writer[T]) WriteSnapshots(snapshots ...eventsourcing.Snapshot) error {
+	if w.closed {
+		return ErrWriterClosed
+	}
+	for _, s := range snapshots {
+		err := w.snapshots.add(snapshotRecord{
+			ID:            s.ID,
+			Type:          s.Type,
+			Version:       s.Version,
+			GlobalVersion: s.GlobalVersion,
+			Timestamp:     s.Timestamp,
+			State:         s.State,
+		}, s.GlobalVersion)
+		if err != nil {
+			return err
+		}
+		w.manifest.Snapshots++
+		if w.snapshots.chunk.Records >= w.chunkSize {
+			err = w.flush(&w.snapshots, &w.manifest.SnapshotChunks)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Manifest returns the manifest of the chunks written so far
+func (w *Writer[T]) Manifest() Manifest {
+	m := w.manifest
+	m.Chunks = append([]Chunk{}, w.manifest.Chunks...)
+	m.SnapshotChunks = append([]Chunk(nil), w.manifest.SnapshotChunks...)
+	return m
+}
+
+// Close writes the last chunks and the manifest. It does not close the underlying writer.
+func (w *Writer[T]) Close() error {
+	if w.closed {
+		return nil
+	}
+	err := w.flush(&w.events, &w.manifest.Chunks)
+	if err != nil {
+		return err
+	}
+	err = w.flush(&w.snapshots, &w.manifest.SnapshotChunks)
+	if err != nil {
+		return err
+	}
+	w.closed = true
+	manifest, err := json.Marshal(w.manifest)
+	if err != nil {
+		return err
+	}
+	trailer := make([]byte, trailerLength)
+	binary.BigEndian.PutUint64(trailer, uint64(len(manifest)))
+	copy(trailer[8:], magic)
+	_, err = w.out.Write(append(manifest, trailer...))
+	return err
+}
+
+// flush writes the chunk being built and adds it to the chunks
+func (w *Writer[T]) flush(b *chunkBuilder, chunks *[]Chunk) error {
+	if b.gz == nil {
+		return nil
+	}
+	err := b.gz.Close()
+	b.gz = nil
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(b.buf.Bytes())
+	b.chunk.Checksum = hex.EncodeToString(sum[:])
+	b.chunk.Offset = w.offset
+	n, err := w.out.Write(b.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	b.chunk.Length = int64(n)
+	w.offset += int64(n)
+	*chunks = append(*chunks, b.chunk)
+	return nil
+}
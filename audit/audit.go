@@ -0,0 +1,291 @@
+// Package audit builds human readable audit trails from the events of an aggregate or of a business transaction
+// traced by its correlation id. Each entry tells who made the change, when and which fields it changed, and the trail
+// can be exported as JSON or CSV for compliance reports.
+package audit
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrCorrelationNotSupported when the event store can't look up events on their correlation id
+var ErrCorrelationNotSupported = errors.New("event store does not implement eventsourcing.CorrelationEventStore")
+
+// Change is a field that changed value, nested fields are joined with a dot
+type Change = eventsourcing.FieldChange
+
+// Entry is the audit record of one event
+type Entry struct {
+	GlobalVersion eventsourcing.Version `json:"global_version"`
+	AggregateType string                `json:"aggregate_type"`
+	AggregateID   string                `json:"aggregate_id"`
+	Version       eventsourcing.Version `json:"version"`
+	Reason        string                `json:"reason"`
+	Timestamp     time.Time             `json:"timestamp"`
+	UserID        string                `json:"user_id,omitempty"`
+	CorrelationID string                `json:"correlation_id,omitempty"`
+	Changes       []Change              `json:"changes"`
+}
+
+// Trail is the audit entries in the order the events were stored
+type Trail []Entry
+
+// Option configures the reporter
+type Option func(*options)
+
+type options struct {
+	maskMode eventsourcing.MaskMode
+}
+
+// WithMaskMode sets how the fields tagged es:"sensitive" are masked in the changes, redacted by default
+func WithMaskMode(mode eventsourcing.MaskMode) Option {
+	return func(o *options) {
+		o.maskMode = mode
+	}
+}
+
+// Reporter builds audit trails from the events in the event store
+type Reporter[T any] struct {
+	store      eventsourcing.EventStore[T]
+	aggregates map[string]func() eventsourcing.Aggregate[T]
+	options    options
+}
+
+// New constructs a reporter reading events from the event store. The values of the fields tagged es:"sensitive" are
+// masked in the changes, see eventsourcing.MarshalMasked.
+func New[T any](store eventsourcing.EventStore[T], opts ...Option) *Reporter[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Reporter[T]{
+		store:      store,
+		aggregates: make(map[string]func() eventsourcing.Aggregate[T]),
+		options:    o,
+	}
+}
+
+// Register makes the changes of the aggregate type the difference in aggregate state before and after each event,
+// built by replaying the events on aggregates from newAggregate. The changes of aggregate types not registered are the
+// fields of the event payload.
+func (r *Reporter[T]) Register(aggregateType string, newAggregate func() eventsourcing.Aggregate[T]) {
+	r.aggregates[aggregateType] = newAggregate
+}
+
+// Aggregate returns the audit trail of the aggregate
+func (r *Reporter[T]) Aggregate(ctx context.Context, aggregateType, id string) (Trail, error) {
+	events, err := r.stream(ctx, aggregateType, id)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := r.changes(aggregateType, events)
+	if err != nil {
+		return nil, err
+	}
+	trail := make(Trail, 0, len(events))
+	for i, event := range events {
+		trail = append(trail, entry(event, changes[i]))
+	}
+	return trail, nil
+}
+
+// Correlation returns the audit trail of the events with the correlation id across all aggregates. The event store
+// has to implement eventsourcing.CorrelationEventStore.
+func (r *Reporter[T]) Correlation(ctx context.Context, correlationID string) (Trail, error) {
+	store, ok := r.store.(eventsourcing.CorrelationEventStore[T])
+	if !ok {
+		return nil, ErrCorrelationNotSupported
+	}
+	events, err := store.EventsByCorrelationID(ctx, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	// the state changes need the whole stream of the aggregate, it's read once per aggregate in the trail
+	type key struct{ aggregateType, id string }
+	streams := make(map[key]map[eventsourcing.Version][]Change)
+	trail := make(Trail, 0, len(events))
+	for _, event := range events {
+		if _, ok := r.aggregates[event.AggregateType]; !ok {
+			c, err := r.payloadChanges(event)
+			if err != nil {
+				return nil, err
+			}
+			trail = append(trail, entry(event, c))
+			continue
+		}
+		k := key{event.AggregateType, event.AggregateID}
+		versions, ok := streams[k]
+		if !ok {
+			stream, err := r.stream(ctx, event.AggregateType, event.AggregateID)
+			if err != nil {
+				return nil, err
+			}
+			changes, err := r.changes(event.AggregateType, stream)
+			if err != nil {
+				return nil, err
+			}
+			versions = make(map[eventsourcing.Version][]Change, len(stream))
+			for i, e := range stream {
+				versions[e.Version] = changes[i]
+			}
+			streams[k] = versions
+		}
+		trail = append(trail, entry(event, versions[event.Version]))
+	}
+	return trail, nil
+}
+
+func (r *Reporter[T]) stream(ctx context.Context, aggregateType, id string) ([]eventsourcing.Event[T], error) {
+	iterator, err := r.store.Get(ctx, id, aggregateType, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	var events []eventsourcing.Event[T]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+// changes returns the changes of each event in the stream of an aggregate
+func (r *Reporter[T]) changes(aggregateType string, events []eventsourcing.Event[T]) ([][]Change, error) {
+	changes := make([][]Change, len(events))
+	newAggregate, ok := r.aggregates[aggregateType]
+	if !ok {
+		for i, event := range events {
+			c, err := r.payloadChanges(event)
+			if err != nil {
+				return nil, err
+			}
+			changes[i] = c
+		}
+		return changes, nil
+	}
+	aggregate := newAggregate()
+	// the state before each event is kept in a copy as the aggregate is changed in place, the copy is decoded from the
+	// JSON of the state as the changes are made from it
+	before := newAggregate()
+	for i, event := range events {
+		aggregate.Root().BuildFromHistory(aggregate, []eventsourcing.Event[T]{event})
+		var err error
+		changes[i], err = eventsourcing.DiffMasked(before, aggregate, r.options.maskMode)
+		if err != nil {
+			return nil, err
+		}
+		state, err := json.Marshal(aggregate)
+		if err != nil {
+			return nil, err
+		}
+		before = newAggregate()
+		err = json.Unmarshal(state, before)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+func (r *Reporter[T]) payloadChanges(event eventsourcing.Event[T]) ([]Change, error) {
+	return eventsourcing.DiffMasked(nil, event.Data, r.options.maskMode)
+}
+
+func entry[T any](event eventsourcing.Event[T], changes []Change) Entry {
+	carrier := eventsourcing.MetadataCarrierFrom(event)
+	if changes == nil {
+		changes = []Change{}
+	}
+	return Entry{
+		GlobalVersion: event.GlobalVersion,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Version:       event.Version,
+		Reason:        event.Reason(),
+		Timestamp:     event.Timestamp,
+		UserID:        carrier.UserID,
+		CorrelationID: carrier.CorrelationID,
+		Changes:       changes,
+	}
+}
+
+// JSON writes the trail as a JSON array
+func (t Trail) JSON(w io.Writer) error {
+	if t == nil {
+		t = Trail{}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// CSV writes the trail with a row per changed field. Events without changes get a row with empty change columns.
+func (t Trail) CSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	err := cw.Write([]string{"global_version", "timestamp", "aggregate_type", "aggregate_id", "version", "reason", "user_id", "correlation_id", "field", "from", "to"})
+	if err != nil {
+		return err
+	}
+	for _, e := range t {
+		row := []string{
+			strconv.FormatUint(uint64(e.GlobalVersion), 10),
+			e.Timestamp.UTC().Format(time.RFC3339Nano),
+			e.AggregateType,
+			e.AggregateID,
+			strconv.FormatUint(uint64(e.Version), 10),
+			e.Reason,
+			e.UserID,
+			e.CorrelationID,
+		}
+		if len(e.Changes) == 0 {
+			err = cw.Write(append(row, "", "", ""))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		for _, c := range e.Changes {
+			from, err := csvValue(c.From)
+			if err != nil {
+				return err
+			}
+			to, err := csvValue(c.To)
+			if err != nil {
+				return err
+			}
+			err = cw.Write(append(row[:len(row):len(row)], c.Field, from, to))
+			if err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvValue returns strings as is and other values as JSON, a missing value is empty
+func csvValue(v interface{}) (string, error) {
+	switch value := v.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("audit value: %w", err)
+	}
+	return string(b), nil
+}
@@ -0,0 +1,223 @@
+package audit_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/audit"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Opened struct {
+	Owner string
+}
+
+type Deposited struct {
+	Amount int
+}
+
+type Address struct {
+	City string
+}
+
+type Moved struct {
+	City string
+}
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+	Owner   string
+	Balance int
+	Address Address
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {
+	switch e := event.Data.(type) {
+	case *Opened:
+		a.Owner = e.Owner
+	case *Deposited:
+		a.Balance += e.Amount
+	case *Moved:
+		a.Address.City = e.City
+	}
+}
+
+var timestamp = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func store(t *testing.T) *memory.Memory[any] {
+	es := memory.Create[any]()
+	metadata := eventsourcing.MetadataCarrier{UserID: "jane", CorrelationID: "c1"}.Metadata()
+	err := es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Account", Version: 1, Timestamp: timestamp, Data: &Opened{Owner: "Jane"}, Metadata: metadata},
+		{AggregateID: "1", AggregateType: "Account", Version: 2, Timestamp: timestamp.Add(time.Second), Data: &Deposited{Amount: 10}},
+		{AggregateID: "1", AggregateType: "Account", Version: 3, Timestamp: timestamp.Add(2 * time.Second), Data: &Moved{City: "Lund"}, Metadata: metadata},
+		{AggregateID: "1", AggregateType: "Account", Version: 4, Timestamp: timestamp.Add(3 * time.Second), Data: &Deposited{Amount: 0}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "2", AggregateType: "Ledger", Version: 1, Timestamp: timestamp.Add(4 * time.Second), Data: &Deposited{Amount: 10}, Metadata: metadata},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return es
+}
+
+func TestAggregate(t *testing.T) {
+	reporter := audit.New[any](store(t))
+	reporter.Register("Account", func() eventsourcing.Aggregate[any] { return &Account{} })
+	trail, err := reporter.Aggregate(context.Background(), "Account", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 4 {
+		t.Fatalf("expected 4 entries got %d", len(trail))
+	}
+	first := trail[0]
+	if first.UserID != "jane" || first.CorrelationID != "c1" || first.Reason != "Opened" || first.Version != 1 {
+		t.Fatalf("unexpected entry %+v", first)
+	}
+	if len(first.Changes) != 1 || first.Changes[0] != (audit.Change{Field: "Owner", From: "", To: "Jane"}) {
+		t.Fatalf("unexpected changes %+v", first.Changes)
+	}
+	if trail[1].Changes[0] != (audit.Change{Field: "Balance", From: float64(0), To: float64(10)}) {
+		t.Fatalf("unexpected changes %+v", trail[1].Changes)
+	}
+	if trail[2].Changes[0].Field != "Address.City" {
+		t.Fatalf("expected nested field got %+v", trail[2].Changes)
+	}
+	// the deposit of zero did not change the state
+	if len(trail[3].Changes) != 0 {
+		t.Fatalf("expected no changes got %+v", trail[3].Changes)
+	}
+}
+
+func TestCorrelation(t *testing.T) {
+	reporter := audit.New[any](store(t))
+	reporter.Register("Account", func() eventsourcing.Aggregate[any] { return &Account{} })
+	trail, err := reporter.Correlation(context.Background(), "c1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trail) != 3 {
+		t.Fatalf("expected 3 entries got %d", len(trail))
+	}
+	if trail[1].Version != 3 || trail[1].Changes[0] != (audit.Change{Field: "Address.City", From: "", To: "Lund"}) {
+		t.Fatalf("unexpected entry %+v", trail[1])
+	}
+	// the ledger is not registered, the changes are the event payload
+	if trail[2].AggregateType != "Ledger" || trail[2].Changes[0] != (audit.Change{Field: "Amount", To: float64(10)}) {
+		t.Fatalf("unexpected entry %+v", trail[2])
+	}
+}
+
+type noCorrelation struct {
+	eventsourcing.EventStore[any]
+}
+
+func TestCorrelationNotSupported(t *testing.T) {
+	reporter := audit.New[any](noCorrelation{store(t)})
+	_, err := reporter.Correlation(context.Background(), "c1")
+	if !errors.Is(err, audit.ErrCorrelationNotSupported) {
+		t.Fatalf("expected ErrCorrelationNotSupported got %v", err)
+	}
+}
+
+func TestExport(t *testing.T) {
+	reporter := audit.New[any](store(t))
+	trail, err := reporter.Aggregate(context.Background(), "Account", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := bytes.Buffer{}
+	err = trail[:2].CSV(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `global_version,timestamp,aggregate_type,aggregate_id,version,reason,user_id,correlation_id,field,from,to
+1,2024-03-01T12:00:00Z,Account,1,1,Opened,jane,c1,Owner,,Jane
+2,2024-03-01T12:00:01Z,Account,1,2,Deposited,,,Amount,,10
+`
+	if b.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+
+	b.Reset()
+	err = trail.JSON(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded audit.Trail
+	err = json.Unmarshal(b.Bytes(), &decoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded) != 4 || decoded[0].UserID != "jane" || decoded[0].Changes[0].To != "Jane" {
+		t.Fatalf("unexpected trail %+v", decoded)
+	}
+}
+
+type Registered struct {
+	Name  string
+	Email string `es:"sensitive"`
+}
+
+type Customer struct {
+	eventsourcing.AggregateRoot[any]
+	Name  string
+	Email string `es:"sensitive"`
+}
+
+func (c *Customer) Transition(event eventsourcing.Event[any]) {
+	if e, ok := event.Data.(*Registered); ok {
+		c.Name = e.Name
+		c.Email = e.Email
+	}
+}
+
+func TestMasked(t *testing.T) {
+	es := memory.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Customer", Version: 1, Timestamp: timestamp, Data: &Registered{Name: "jane", Email: "jane@example.com"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		title      string
+		registered bool
+		expected   []audit.Change
+	}{
+		{title: "payload", expected: []audit.Change{{Field: "Email", From: nil, To: eventsourcing.Redacted}, {Field: "Name", From: nil, To: "jane"}}},
+		// the change of the email is kept with the values redacted
+		{title: "state", registered: true, expected: []audit.Change{{Field: "Email", From: eventsourcing.Redacted, To: eventsourcing.Redacted}, {Field: "Name", From: "", To: "jane"}}},
+	}
+	for _, test := range tests {
+		reporter := audit.New[any](es)
+		if test.registered {
+			reporter.Register("Customer", func() eventsourcing.Aggregate[any] { return &Customer{} })
+		}
+		trail, err := reporter.Aggregate(context.Background(), "Customer", "1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(trail[0].Changes, test.expected) {
+			t.Fatalf("expected the email to be redacted got %+v", trail[0].Changes)
+		}
+	}
+
+	reporter := audit.New[any](es, audit.WithMaskMode(eventsourcing.MaskDrop))
+	trail, err := reporter.Aggregate(context.Background(), "Customer", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(trail[0].Changes) != 1 || trail[0].Changes[0].Field != "Name" {
+		t.Fatalf("expected the email to be dropped got %+v", trail[0].Changes)
+	}
+}
@@ -0,0 +1,82 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+)
+
+// BatchEventStore is implemented by event stores that can read the events of many aggregates in one round trip
+type BatchEventStore[T any] interface {
+	// GetMany returns an iterator per aggregate id over its events after the version in afterVersions, zero for the
+	// ids not in it. Ids without events are left out of the returned map.
+	GetMany(ctx context.Context, aggregateType string, ids []string, afterVersions map[string]Version) (map[string]EventIterator[T], error)
+}
+
+// GetMany returns an iterator per aggregate id over its events after the version in afterVersions. Event stores not
+// implementing BatchEventStore are read one aggregate at a time, the events are read into memory to not hold a
+// connection per aggregate.
+func GetMany[T any](ctx context.Context, store EventStore[T], aggregateType string, ids []string, afterVersions map[string]Version) (map[string]EventIterator[T], error) {
+	if s, ok := store.(BatchEventStore[T]); ok {
+		return s.GetMany(ctx, aggregateType, ids, afterVersions)
+	}
+	iterators := make(map[string]EventIterator[T], len(ids))
+	for _, id := range ids {
+		if _, ok := iterators[id]; ok {
+			continue
+		}
+		iterator, err := store.Get(ctx, id, aggregateType, afterVersions[id])
+		if errors.Is(err, ErrNoEvents) {
+			continue
+		} else if err != nil {
+			return nil, err
+		}
+		events, err := drain(iterator)
+		if err != nil {
+			return nil, err
+		}
+		if len(events) > 0 {
+			iterators[id] = NewSliceIterator(events)
+		}
+	}
+	return iterators, nil
+}
+
+// drain reads the events of the iterator and closes it
+func drain[T any](iterator EventIterator[T]) ([]Event[T], error) {
+	defer iterator.Close()
+	var events []Event[T]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+// SliceIterator iterates over events held in memory
+type SliceIterator[T any] struct {
+	events []Event[T]
+}
+
+// NewSliceIterator returns an iterator over the events
+func NewSliceIterator[T any](events []Event[T]) *SliceIterator[T] {
+	return &SliceIterator[T]{events: events}
+}
+
+// Next returns the next event, ErrNoMoreEvents when all events are returned
+func (i *SliceIterator[T]) Next() (Event[T], error) {
+	if len(i.events) == 0 {
+		return Event[T]{}, ErrNoMoreEvents
+	}
+	event := i.events[0]
+	i.events = i.events[1:]
+	return event, nil
+}
+
+// Close releases the events
+func (i *SliceIterator[T]) Close() {
+	i.events = nil
+}
@@ -0,0 +1,549 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+)
+
+var (
+	// ErrBinaryUnsupportedType is returned when a value of a type the binary format can't hold is marshaled
+	ErrBinaryUnsupportedType = errors.New("type not supported by the binary format")
+
+	// ErrBinaryCorrupt is returned when the data to unmarshal is not in the binary format of the type
+	ErrBinaryCorrupt = errors.New("corrupt binary data")
+)
+
+// binaryFormat is the first byte of the data, to be able to change the format
+const binaryFormat = 1
+
+// Binary returns marshal functions for a compact binary format, for systems only sharing events between Go programs
+// that want the smallest payloads. Numbers are stored as varints and strings with their length, the struct fields are
+// stored in the order they are declared without their names. The event types can't change once events are stored,
+// changed events are better registered under a new reason.
+//
+// Types implementing encoding.BinaryMarshaler, like time.Time, are stored with it. Values held in interface fields,
+// like the metadata values, can be of the basic types, json.Number and the slices and maps of metadata.
+func Binary() (MarshalSnapshotFunc, UnmarshalSnapshotFunc) {
+	marshal := func(v any) ([]byte, error) {
+		rv := reflect.ValueOf(v)
+		for rv.Kind() == reflect.Ptr && !rv.IsNil() {
+			rv = rv.Elem()
+		}
+		if rv.Kind() == reflect.Ptr {
+			return nil, fmt.Errorf("%w: nil pointer", ErrBinaryUnsupportedType)
+		}
+		e := binaryEncoder{}
+		e.buf.WriteByte(binaryFormat)
+		err := e.encode(rv)
+		if err != nil {
+			return nil, err
+		}
+		return e.buf.Bytes(), nil
+	}
+	unmarshal := func(data []byte, v any) error {
+		target, err := decodeTarget(v)
+		if err != nil {
+			return err
+		}
+		if len(data) == 0 || data[0] != binaryFormat {
+			return fmt.Errorf("%w: unknown format", ErrBinaryCorrupt)
+		}
+		d := binaryDecoder{data: data[1:]}
+		err = d.decode(target.Elem())
+		if err != nil {
+			return err
+		}
+		if len(d.data) > 0 {
+			return fmt.Errorf("%w: %d bytes left", ErrBinaryCorrupt, len(d.data))
+		}
+		return nil
+	}
+	return marshal, unmarshal
+}
+
+// NewBinarySerializer returns a serializer using the marshal functions from Binary
+func NewBinarySerializer[T any]() *Serializer[T] {
+	marshal, unmarshal := Binary()
+	return NewSerializer[T](marshal, unmarshal)
+}
+
+// decodeTarget returns the pointer to the value to unmarshal into, following pointers to pointers. The serializer
+// unmarshals into a pointer to the event interface holding a pointer to the registered type, it's the pointer held by
+// the interface.
+func decodeTarget(v any) (reflect.Value, error) {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return reflect.Value{}, fmt.Errorf("unmarshal into non pointer %T", v)
+	}
+	for {
+		e := rv.Elem()
+		switch {
+		case e.Kind() == reflect.Ptr:
+			if e.IsNil() {
+				e.Set(reflect.New(e.Type().Elem()))
+			}
+		case e.Kind() == reflect.Interface && !e.IsNil() && e.Elem().Kind() == reflect.Ptr && !e.Elem().IsNil():
+			e = e.Elem()
+		default:
+			return rv, nil
+		}
+		rv = e
+	}
+}
+
+var (
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+	jsonNumberType        = reflect.TypeOf(json.Number(""))
+)
+
+// binaryMarshals returns true if values of the type are marshaled with MarshalBinary and unmarshaled with
+// UnmarshalBinary
+func binaryMarshals(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Interface {
+		return false
+	}
+	return t.Implements(binaryMarshalerType) && reflect.PtrTo(t).Implements(binaryUnmarshalerType)
+}
+
+// the tags of the types held by interface values
+const (
+	tagNil byte = iota
+	tagString
+	tagBool
+	tagInt
+	tagInt64
+	tagInt32
+	tagUint
+	tagUint64
+	tagUint32
+	tagFloat64
+	tagFloat32
+	tagNumber
+	tagBytes
+	tagSlice
+	tagMap
+)
+
+var interfaceTypes = map[reflect.Type]byte{
+	reflect.TypeOf(""):               tagString,
+	reflect.TypeOf(false):            tagBool,
+	reflect.TypeOf(0):                tagInt,
+	reflect.TypeOf(int64(0)):         tagInt64,
+	reflect.TypeOf(int32(0)):         tagInt32,
+	reflect.TypeOf(uint(0)):          tagUint,
+	reflect.TypeOf(uint64(0)):        tagUint64,
+	reflect.TypeOf(uint32(0)):        tagUint32,
+	reflect.TypeOf(float64(0)):       tagFloat64,
+	reflect.TypeOf(float32(0)):       tagFloat32,
+	jsonNumberType:                   tagNumber,
+	reflect.TypeOf([]byte{}):         tagBytes,
+	reflect.TypeOf([]any{}):          tagSlice,
+	reflect.TypeOf(map[string]any{}): tagMap,
+}
+
+// interfaceType returns the type of the tag
+func interfaceType(tag byte) (reflect.Type, bool) {
+	for t, tg := range interfaceTypes {
+		if tg == tag {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+type binaryEncoder struct {
+	buf     bytes.Buffer
+	scratch [binary.MaxVarintLen64]byte
+}
+
+func (e *binaryEncoder) uvarint(x uint64) {
+	n := binary.PutUvarint(e.scratch[:], x)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *binaryEncoder) varint(x int64) {
+	n := binary.PutVarint(e.scratch[:], x)
+	e.buf.Write(e.scratch[:n])
+}
+
+func (e *binaryEncoder) bytes(b []byte) {
+	e.uvarint(uint64(len(b)))
+	e.buf.Write(b)
+}
+
+// length writes the length of a slice or map, zero is a nil slice or map
+func (e *binaryEncoder) length(v reflect.Value) {
+	if v.IsNil() {
+		e.uvarint(0)
+		return
+	}
+	e.uvarint(uint64(v.Len()) + 1)
+}
+
+func (e *binaryEncoder) encode(v reflect.Value) error {
+	if !v.IsValid() {
+		return fmt.Errorf("%w: nil", ErrBinaryUnsupportedType)
+	}
+	t := v.Type()
+	if binaryMarshals(t) {
+		data, err := v.Interface().(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			return err
+		}
+		e.bytes(data)
+		return nil
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			e.buf.WriteByte(1)
+		} else {
+			e.buf.WriteByte(0)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		e.varint(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		e.uvarint(v.Uint())
+	case reflect.Float32:
+		var b [4]byte
+		binary.LittleEndian.PutUint32(b[:], math.Float32bits(float32(v.Float())))
+		e.buf.Write(b[:])
+	case reflect.Float64:
+		var b [8]byte
+		binary.LittleEndian.PutUint64(b[:], math.Float64bits(v.Float()))
+		e.buf.Write(b[:])
+	case reflect.String:
+		e.uvarint(uint64(v.Len()))
+		e.buf.WriteString(v.String())
+	case reflect.Slice:
+		e.length(v)
+		if t.Elem().Kind() == reflect.Uint8 {
+			e.buf.Write(v.Bytes())
+			return nil
+		}
+		return e.elements(v)
+	case reflect.Array:
+		return e.elements(v)
+	case reflect.Map:
+		return e.encodeMap(v)
+	case reflect.Struct:
+		for _, i := range binaryFields(t) {
+			if err := e.encode(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		if v.IsNil() {
+			e.buf.WriteByte(0)
+			return nil
+		}
+		e.buf.WriteByte(1)
+		return e.encode(v.Elem())
+	case reflect.Interface:
+		if v.IsNil() {
+			e.buf.WriteByte(tagNil)
+			return nil
+		}
+		tag, ok := interfaceTypes[v.Elem().Type()]
+		if !ok {
+			return fmt.Errorf("%w: %s in interface", ErrBinaryUnsupportedType, v.Elem().Type())
+		}
+		e.buf.WriteByte(tag)
+		return e.encode(v.Elem())
+	default:
+		return fmt.Errorf("%w: %s", ErrBinaryUnsupportedType, t)
+	}
+	return nil
+}
+
+func (e *binaryEncoder) elements(v reflect.Value) error {
+	for i := 0; i < v.Len(); i++ {
+		if err := e.encode(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeMap writes the entries sorted on the encoded keys, the same map is always marshaled to the same bytes
+func (e *binaryEncoder) encodeMap(v reflect.Value) error {
+	e.length(v)
+	type entry struct {
+		key   []byte
+		value reflect.Value
+	}
+	entries := make([]entry, 0, v.Len())
+	iter := v.MapRange()
+	for iter.Next() {
+		k := binaryEncoder{}
+		if err := k.encode(iter.Key()); err != nil {
+			return err
+		}
+		entries = append(entries, entry{key: k.buf.Bytes(), value: iter.Value()})
+	}
+	sort.Slice(entries, func(i, j int) bool { return bytes.Compare(entries[i].key, entries[j].key) < 0 })
+	for _, en := range entries {
+		e.buf.Write(en.key)
+		if err := e.encode(en.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// binaryFields returns the indexes of the stored fields, the exported fields and the embedded structs
+func binaryFields(t reflect.Type) []int {
+	var fields []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.IsExported() || f.Anonymous && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, i)
+		}
+	}
+	return fields
+}
+
+type binaryDecoder struct {
+	data []byte
+}
+
+func (d *binaryDecoder) corrupt(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrBinaryCorrupt, fmt.Sprintf(format, args...))
+}
+
+func (d *binaryDecoder) next(n int) ([]byte, error) {
+	if n < 0 || n > len(d.data) {
+		return nil, d.corrupt("unexpected end of data")
+	}
+	b := d.data[:n]
+	d.data = d.data[n:]
+	return b, nil
+}
+
+func (d *binaryDecoder) byte() (byte, error) {
+	b, err := d.next(1)
+	if err != nil {
+		return 0, err
+	}
+	return b[0], nil
+}
+
+func (d *binaryDecoder) uvarint() (uint64, error) {
+	x, n := binary.Uvarint(d.data)
+	if n <= 0 {
+		return 0, d.corrupt("invalid varint")
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+func (d *binaryDecoder) varint() (int64, error) {
+	x, n := binary.Varint(d.data)
+	if n <= 0 {
+		return 0, d.corrupt("invalid varint")
+	}
+	d.data = d.data[n:]
+	return x, nil
+}
+
+// size reads a length, it can't be larger than the data left as each element takes at least a byte
+func (d *binaryDecoder) size() (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n > uint64(len(d.data)) {
+		return 0, d.corrupt("length %d larger than the data", n)
+	}
+	return int(n), nil
+}
+
+// length reads the length of a slice or map, nil is returned as -1
+func (d *binaryDecoder) length() (int, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return -1, nil
+	}
+	if n-1 > uint64(len(d.data)) {
+		return 0, d.corrupt("length %d larger than the data", n-1)
+	}
+	return int(n - 1), nil
+}
+
+func (d *binaryDecoder) decode(v reflect.Value) error {
+	t := v.Type()
+	if binaryMarshals(t) {
+		n, err := d.size()
+		if err != nil {
+			return err
+		}
+		data, _ := d.next(n)
+		return v.Addr().Interface().(encoding.BinaryUnmarshaler).UnmarshalBinary(data)
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		if b > 1 {
+			return d.corrupt("invalid bool %d", b)
+		}
+		v.SetBool(b == 1)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		x, err := d.varint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowInt(x) {
+			return d.corrupt("%d overflows %s", x, t)
+		}
+		v.SetInt(x)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		x, err := d.uvarint()
+		if err != nil {
+			return err
+		}
+		if v.OverflowUint(x) {
+			return d.corrupt("%d overflows %s", x, t)
+		}
+		v.SetUint(x)
+	case reflect.Float32:
+		b, err := d.next(4)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(float64(math.Float32frombits(binary.LittleEndian.Uint32(b))))
+	case reflect.Float64:
+		b, err := d.next(8)
+		if err != nil {
+			return err
+		}
+		v.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(b)))
+	case reflect.String:
+		n, err := d.size()
+		if err != nil {
+			return err
+		}
+		b, _ := d.next(n)
+		v.SetString(string(b))
+	case reflect.Slice:
+		n, err := d.length()
+		if err != nil {
+			return err
+		}
+		if n < 0 {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, _ := d.next(n)
+			v.SetBytes(append([]byte{}, b...))
+			return nil
+		}
+		s := reflect.MakeSlice(t, n, n)
+		for i := 0; i < n; i++ {
+			if err := d.decode(s.Index(i)); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if err := d.decode(v.Index(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		return d.decodeMap(v)
+	case reflect.Struct:
+		for _, i := range binaryFields(t) {
+			if err := d.decode(v.Field(i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Ptr:
+		b, err := d.byte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case 0:
+			v.Set(reflect.Zero(t))
+		case 1:
+			p := reflect.New(t.Elem())
+			if err := d.decode(p.Elem()); err != nil {
+				return err
+			}
+			v.Set(p)
+		default:
+			return d.corrupt("invalid pointer %d", b)
+		}
+	case reflect.Interface:
+		return d.decodeInterface(v)
+	default:
+		return fmt.Errorf("%w: %s", ErrBinaryUnsupportedType, t)
+	}
+	return nil
+}
+
+func (d *binaryDecoder) decodeMap(v reflect.Value) error {
+	t := v.Type()
+	n, err := d.length()
+	if err != nil {
+		return err
+	}
+	if n < 0 {
+		v.Set(reflect.Zero(t))
+		return nil
+	}
+	m := reflect.MakeMapWithSize(t, n)
+	for i := 0; i < n; i++ {
+		key := reflect.New(t.Key()).Elem()
+		if err := d.decode(key); err != nil {
+			return err
+		}
+		value := reflect.New(t.Elem()).Elem()
+		if err := d.decode(value); err != nil {
+			return err
+		}
+		m.SetMapIndex(key, value)
+	}
+	v.Set(m)
+	return nil
+}
+
+func (d *binaryDecoder) decodeInterface(v reflect.Value) error {
+	tag, err := d.byte()
+	if err != nil {
+		return err
+	}
+	if tag == tagNil {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	it, ok := interfaceType(tag)
+	if !ok {
+		return d.corrupt("unknown type tag %d", tag)
+	}
+	if !it.AssignableTo(v.Type()) {
+		return d.corrupt("%s not assignable to %s", it, v.Type())
+	}
+	value := reflect.New(it).Elem()
+	if err := d.decode(value); err != nil {
+		return err
+	}
+	v.Set(value)
+	return nil
+}
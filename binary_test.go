@@ -0,0 +1,196 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type Reading struct {
+	Sample
+	Sensor  string
+	Value   float64
+	Ratio   float32
+	Count   int64
+	Total   uint64
+	Valid   bool
+	Raw     []byte
+	At      time.Time
+	Labels  map[string]string
+	Samples []Sample
+	Next    *Reading
+	Extra   any
+}
+
+type Sample struct {
+	Offset int8
+}
+
+// readingFrom builds a reading from the fuzzed values
+func readingFrom(sensor string, value float64, count int64, total uint64, valid bool, raw []byte) Reading {
+	return Reading{
+		Sample:  Sample{Offset: int8(count)},
+		Sensor:  sensor,
+		Value:   value,
+		Ratio:   float32(value),
+		Count:   count,
+		Total:   total,
+		Valid:   valid,
+		Raw:     raw,
+		At:      time.Unix(0, count).UTC(),
+		Labels:  map[string]string{sensor: sensor + "!", "b": ""},
+		Samples: []Sample{{Offset: 1}, {Offset: int8(total)}},
+		Next:    &Reading{Sensor: sensor, Count: -count},
+		Extra:   map[string]any{"count": count, "sensor": sensor, "list": []any{total, valid}},
+	}
+}
+
+// sameReading compares the readings, NaN values are compared on their bits
+func sameReading(a, b Reading) bool {
+	if math.Float64bits(a.Value) != math.Float64bits(b.Value) || math.Float32bits(a.Ratio) != math.Float32bits(b.Ratio) || !a.At.Equal(b.At) {
+		return false
+	}
+	a.Value, a.Ratio, a.At = 0, 0, time.Time{}
+	b.Value, b.Ratio, b.At = 0, 0, time.Time{}
+	return reflect.DeepEqual(a, b)
+}
+
+func TestBinaryRoundTrip(t *testing.T) {
+	marshal, unmarshal := eventsourcing.Binary()
+	tests := []struct {
+		title string
+		value any
+		into  func() any
+	}{
+		{title: "struct", value: readingFrom("s1", 1.5, -300, 1<<40, true, []byte{1, 2}), into: func() any { return &Reading{} }},
+		{title: "empty struct", value: Reading{}, into: func() any { return &Reading{} }},
+		{title: "metadata", value: map[string]any{"user": "jane", "tenant": int64(9007199254740993), "amount": 1.25, "number": json.Number("12"), "nested": map[string]any{"ok": true}, "nil": nil}, into: func() any { return &map[string]any{} }},
+		{title: "nil map", value: map[string]any(nil), into: func() any { return &map[string]any{} }},
+		{title: "string", value: "hello", into: func() any { return new(string) }},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			data, err := marshal(test.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			into := test.into()
+			err = unmarshal(data, into)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := reflect.ValueOf(into).Elem().Interface()
+			if r, ok := got.(Reading); ok {
+				if !sameReading(r, test.value.(Reading)) {
+					t.Fatalf("expected %+v got %+v", test.value, r)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, test.value) {
+				t.Fatalf("expected %#v got %#v", test.value, got)
+			}
+		})
+	}
+}
+
+func TestBinaryDeterministic(t *testing.T) {
+	marshal, _ := eventsourcing.Binary()
+	reading := readingFrom("s1", 2, 3, 4, false, nil)
+	first, err := marshal(reading)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 20; i++ {
+		data, err := marshal(reading)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != string(first) {
+			t.Fatal("expected the same bytes for the same value")
+		}
+	}
+}
+
+func TestBinaryErrors(t *testing.T) {
+	marshal, unmarshal := eventsourcing.Binary()
+	_, err := marshal(map[string]any{"reading": Reading{}})
+	if !errors.Is(err, eventsourcing.ErrBinaryUnsupportedType) {
+		t.Fatalf("expected unsupported type got %v", err)
+	}
+	data, err := marshal(Reading{Sensor: "s1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reading Reading
+	err = unmarshal(data[:len(data)-1], &reading)
+	if !errors.Is(err, eventsourcing.ErrBinaryCorrupt) {
+		t.Fatalf("expected corrupt data got %v", err)
+	}
+	err = unmarshal(append(data, 0), &reading)
+	if !errors.Is(err, eventsourcing.ErrBinaryCorrupt) {
+		t.Fatalf("expected corrupt data got %v", err)
+	}
+}
+
+func TestBinarySerializer(t *testing.T) {
+	s := eventsourcing.NewBinarySerializer[Data]()
+	err := s.Register(&SomeAggregate{}, s.Events(&SomeData{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Marshal(&SomeData{A: 1, B: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := eventsourcing.Event[Data]{AggregateType: "SomeAggregate"}
+	_, err = s.UnmarshalEvent(&event, "SomeData", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Data.(*SomeData) != (SomeData{A: 1, B: "b"}) {
+		t.Fatalf("expected the event back got %+v", event.Data)
+	}
+}
+
+func FuzzBinaryRoundTrip(f *testing.F) {
+	f.Add("s1", 1.5, int64(-300), uint64(1<<40), true, []byte{1, 2})
+	f.Add("", math.NaN(), int64(math.MinInt64), uint64(math.MaxUint64), false, []byte(nil))
+	marshal, unmarshal := eventsourcing.Binary()
+	f.Fuzz(func(t *testing.T, sensor string, value float64, count int64, total uint64, valid bool, raw []byte) {
+		reading := readingFrom(sensor, value, count, total, valid, raw)
+		data, err := marshal(reading)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Reading
+		err = unmarshal(data, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameReading(reading, got) {
+			t.Fatalf("expected %+v got %+v", reading, got)
+		}
+	})
+}
+
+func FuzzBinaryUnmarshal(f *testing.F) {
+	marshal, unmarshal := eventsourcing.Binary()
+	data, err := marshal(readingFrom("s1", 1.5, -300, 1<<40, true, []byte{1, 2}))
+	if err != nil {
+		f.Fatal(err)
+	}
+	f.Add(data)
+	f.Add([]byte{1, 255, 255, 255, 255, 255, 255, 255, 255, 255, 1})
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// corrupt data returns an error and never panics
+		var reading Reading
+		_ = unmarshal(data, &reading)
+		metadata := map[string]any{}
+		_ = unmarshal(data, &metadata)
+	})
+}
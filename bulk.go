@@ -0,0 +1,46 @@
+package eventsourcing
+
+import "errors"
+
+// ErrBulkSaveNotSupported is returned from SaveAll when the event store can't save several aggregates atomically
+var ErrBulkSaveNotSupported = errors.New("event store does not support saving several aggregates atomically")
+
+// BulkSaver is implemented by event stores that can save the events of several aggregates in one atomic operation.
+// Each slice holds the events of one aggregate and is validated against its current version, if one of them fails
+// no events are saved.
+type BulkSaver[T any] interface {
+	SaveAll(events [][]Event[T]) error
+}
+
+// SaveAll saves the events of the aggregates in one atomic operation, used when one command updates several
+// aggregates. Either all aggregates are saved or none of them. The event store has to implement BulkSaver.
+func (r *Repository[T]) SaveAll(aggregates ...Aggregate[T]) error {
+	saver, ok := r.eventStore.(BulkSaver[T])
+	if !ok {
+		return ErrBulkSaveNotSupported
+	}
+	events := make([][]Event[T], 0, len(aggregates))
+	for _, aggregate := range aggregates {
+		err := beforeSave(aggregate)
+		if err != nil {
+			return err
+		}
+		// use under laying event slice to set GlobalVersion
+		events = append(events, aggregate.Root().aggregateEvents)
+	}
+	err := saver.SaveAll(events)
+	if err != nil {
+		for _, aggregate := range aggregates {
+			r.invalidateCache(aggregate)
+		}
+		return err
+	}
+	var firstErr error
+	for _, aggregate := range aggregates {
+		err := r.saved(aggregate)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,88 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/resilience"
+)
+
+func TestSaveAll(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](memory.Create[any](), nil)
+
+	from := &Ledger{}
+	from.SetID("from")
+	from.TrackChange(from, &Opened{Balance: 10})
+	err := repo.Save(from)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// transfer between the ledgers in one command
+	to := &Ledger{}
+	to.SetID("to")
+	to.TrackChange(to, &Opened{Balance: 5})
+	from.TrackChange(from, &Deposited{Amount: -5})
+	err = repo.SaveAll(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if from.UnsavedEvents() || to.UnsavedEvents() {
+		t.Fatal("expected the events to be saved")
+	}
+	if to.Version() != 1 || to.GlobalVersion() == 0 {
+		t.Fatalf("expected version 1 with global version got %d %d", to.Version(), to.GlobalVersion())
+	}
+
+	l := Ledger{}
+	err = repo.Get("from", &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.Balance != 5 {
+		t.Fatalf("expected balance 5 got %d", l.Balance)
+	}
+}
+
+func TestSaveAllConcurrencyError(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](memory.Create[any](), nil)
+
+	stale := &Ledger{}
+	stale.SetID("stale")
+	stale.TrackChange(stale, &Opened{Balance: 10})
+	other := &Ledger{}
+	other.SetID("stale")
+	other.TrackChange(other, &Opened{Balance: 20})
+	err := repo.Save(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fresh := &Ledger{}
+	fresh.SetID("fresh")
+	fresh.TrackChange(fresh, &Opened{Balance: 5})
+	err = repo.SaveAll(fresh, stale)
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+	if !fresh.UnsavedEvents() {
+		t.Fatal("expected the events of fresh to stay unsaved")
+	}
+	err = repo.Get("fresh", &Ledger{})
+	if !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected fresh not to be saved got %v", err)
+	}
+}
+
+func TestSaveAllNotSupported(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](resilience.New[any](memory.Create[any]()), nil)
+	l := &Ledger{}
+	l.TrackChange(l, &Opened{Balance: 10})
+	err := repo.SaveAll(l)
+	if !errors.Is(err, eventsourcing.ErrBulkSaveNotSupported) {
+		t.Fatalf("expected bulk save not supported got %v", err)
+	}
+}
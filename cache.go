@@ -0,0 +1,225 @@
+package eventsourcing
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+)
+
+// AggregateCache is an in-process least recently used cache of serialized aggregates. It implements the
+// Cache interface and is set on the repository with SetCache.
+// The cache is bounded by the number of entries and by the total size of the serialized aggregates,
+// when one of the limits is passed the least recently used aggregates are evicted.
+type AggregateCache struct {
+	lock       sync.Mutex
+	maxEntries int
+	maxBytes   int
+	bytes      int
+	entries    map[string]*list.Element
+	order      *list.List // front is the most recently used
+}
+
+// NewAggregateCache constructs a cache holding at most maxEntries aggregates and maxBytes bytes of serialized state.
+// A limit of zero or less is not enforced.
+func NewAggregateCache(maxEntries, maxBytes int) *AggregateCache {
+	return &AggregateCache{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		entries:    make(map[string]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// Save adds or replaces the aggregate in the cache
+func (c *AggregateCache) Save(s Snapshot) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	key := cacheKey(s.ID, s.Type)
+	if e, ok := c.entries[key]; ok {
+		c.bytes -= len(e.Value.(Snapshot).State)
+		c.order.Remove(e)
+		delete(c.entries, key)
+	}
+	if c.maxBytes > 0 && len(s.State) > c.maxBytes {
+		// the aggregate would evict every other aggregate and still not fit
+		return nil
+	}
+	c.entries[key] = c.order.PushFront(s)
+	c.bytes += len(s.State)
+	for (c.maxEntries > 0 && c.order.Len() > c.maxEntries) || (c.maxBytes > 0 && c.bytes > c.maxBytes) {
+		c.evict(c.order.Back())
+	}
+	return nil
+}
+
+// Get returns the cached aggregate
+func (c *AggregateCache) Get(ctx context.Context, id, typ string) (Snapshot, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[cacheKey(id, typ)]
+	if !ok {
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	c.order.MoveToFront(e)
+	return e.Value.(Snapshot), nil
+}
+
+// Invalidate removes the aggregate from the cache
+func (c *AggregateCache) Invalidate(id, typ string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[cacheKey(id, typ)]; ok {
+		c.evict(e)
+	}
+}
+
+// Len returns the number of cached aggregates
+func (c *AggregateCache) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// Size returns the total size in bytes of the cached aggregates
+func (c *AggregateCache) Size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.bytes
+}
+
+func (c *AggregateCache) evict(e *list.Element) {
+	s := c.order.Remove(e).(Snapshot)
+	delete(c.entries, cacheKey(s.ID, s.Type))
+	c.bytes -= len(s.State)
+}
+
+func cacheKey(id, typ string) string {
+	return typ + "_" + id
+}
+
+// ErrCacheClosed when saving to a closed TieredCache
+var ErrCacheClosed = errors.New("cache is closed")
+
+// Cache is an aggregate cache the repository can use, implemented by AggregateCache and TieredCache
+type Cache interface {
+	SnapshotStore
+	Invalidate(id, typ string)
+}
+
+// TieredCache is a two tier aggregate cache for services running in multiple instances. Misses in the local cache
+// are read from a shared snapshot store, like redis, before the aggregate is rebuilt from its events. Saved aggregates
+// are written to the local cache directly and to the shared store in the background.
+type TieredCache struct {
+	local   *AggregateCache
+	shared  SnapshotStore
+	queue   chan Snapshot
+	lock    sync.Mutex
+	onError func(snap Snapshot, err error)
+	dropped uint64
+	closed  bool
+	done    chan struct{}
+}
+
+// NewTieredCache constructs a two tier cache, queueSize is the number of writes to the shared store that can wait
+// before new writes are dropped.
+func NewTieredCache(local *AggregateCache, shared SnapshotStore, queueSize int) *TieredCache {
+	c := &TieredCache{
+		local:  local,
+		shared: shared,
+		queue:  make(chan Snapshot, queueSize),
+		done:   make(chan struct{}),
+	}
+	go c.write()
+	return c
+}
+
+// OnError sets a function that is called when a write to the shared store fails
+func (c *TieredCache) OnError(f func(snap Snapshot, err error)) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.onError = f
+}
+
+// Get returns the aggregate from the local cache or the shared store. A failing shared store is reported to the
+// OnError function and treated as a miss.
+func (c *TieredCache) Get(ctx context.Context, id, typ string) (Snapshot, error) {
+	snap, err := c.local.Get(ctx, id, typ)
+	if err == nil || !errors.Is(err, ErrSnapshotNotFound) {
+		return snap, err
+	}
+	snap, err = c.shared.Get(ctx, id, typ)
+	if errors.Is(err, ErrSnapshotNotFound) {
+		return Snapshot{}, err
+	} else if err != nil {
+		c.reportError(Snapshot{ID: id, Type: typ}, err)
+		return Snapshot{}, ErrSnapshotNotFound
+	}
+	c.local.Save(snap)
+	return snap, nil
+}
+
+// Save writes the aggregate to the local cache and queue the write to the shared store. The write to the
+// shared store is dropped if the queue is full, the aggregate can still be rebuilt from its events. Returns
+// ErrCacheClosed after the cache is closed.
+func (c *TieredCache) Save(snap Snapshot) error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.closed {
+		return ErrCacheClosed
+	}
+	err := c.local.Save(snap)
+	if err != nil {
+		return err
+	}
+	select {
+	case c.queue <- snap:
+	default:
+		c.dropped++
+	}
+	return nil
+}
+
+// Invalidate removes the aggregate from the local cache. The shared store is left as is, the aggregates read from
+// it are brought up to date with the events after its version.
+func (c *TieredCache) Invalidate(id, typ string) {
+	c.local.Invalidate(id, typ)
+}
+
+// Dropped returns the number of writes to the shared store dropped due to a full queue
+func (c *TieredCache) Dropped() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.dropped
+}
+
+// Close waits for the queued writes to the shared store. Saves after the cache is closed return ErrCacheClosed.
+func (c *TieredCache) Close() {
+	c.lock.Lock()
+	if !c.closed {
+		c.closed = true
+		close(c.queue)
+	}
+	c.lock.Unlock()
+	<-c.done
+}
+
+// write saves the queued snapshots to the shared store in the order they were saved
+func (c *TieredCache) write() {
+	defer close(c.done)
+	for snap := range c.queue {
+		err := c.shared.Save(snap)
+		if err != nil {
+			c.reportError(snap, err)
+		}
+	}
+}
+
+func (c *TieredCache) reportError(snap Snapshot, err error) {
+	c.lock.Lock()
+	onError := c.onError
+	c.lock.Unlock()
+	if onError != nil {
+		onError(snap, err)
+	}
+}
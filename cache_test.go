@@ -0,0 +1,186 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestAggregateCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	cache := eventsourcing.NewAggregateCache(2, 0)
+	cache.Save(eventsourcing.Snapshot{ID: "1", Type: "Person"})
+	cache.Save(eventsourcing.Snapshot{ID: "2", Type: "Person"})
+	// use 1 to make 2 the least recently used
+	_, err := cache.Get(context.Background(), "1", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Save(eventsourcing.Snapshot{ID: "3", Type: "Person"})
+
+	if cache.Len() != 2 {
+		t.Fatalf("expected 2 cached aggregates got %d", cache.Len())
+	}
+	_, err = cache.Get(context.Background(), "2", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected 2 to be evicted got %v", err)
+	}
+}
+
+func TestAggregateCacheMaxBytes(t *testing.T) {
+	cache := eventsourcing.NewAggregateCache(0, 10)
+	cache.Save(eventsourcing.Snapshot{ID: "1", Type: "Person", State: make([]byte, 6)})
+	cache.Save(eventsourcing.Snapshot{ID: "2", Type: "Person", State: make([]byte, 6)})
+	if cache.Len() != 1 || cache.Size() != 6 {
+		t.Fatalf("expected one cached aggregate of 6 bytes got %d of %d bytes", cache.Len(), cache.Size())
+	}
+	// replacing an aggregate updates the size
+	cache.Save(eventsourcing.Snapshot{ID: "2", Type: "Person", State: make([]byte, 2)})
+	if cache.Size() != 2 {
+		t.Fatalf("expected 2 bytes got %d", cache.Size())
+	}
+	// too large to ever fit
+	cache.Save(eventsourcing.Snapshot{ID: "3", Type: "Person", State: make([]byte, 11)})
+	if cache.Len() != 1 {
+		t.Fatalf("expected the large aggregate to not be cached got %d entries", cache.Len())
+	}
+}
+
+// afterVersionStore records the version events are fetched after
+type afterVersionStore struct {
+	eventsourcing.EventStore[PersonEvent]
+	afterVersions []eventsourcing.Version
+}
+
+func (s *afterVersionStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[PersonEvent], error) {
+	s.afterVersions = append(s.afterVersions, afterVersion)
+	return s.EventStore.Get(ctx, id, aggregateType, afterVersion)
+}
+
+func TestRepositoryCache(t *testing.T) {
+	eventStore := &afterVersionStore{EventStore: memory.Create[PersonEvent]()}
+	repo := eventsourcing.NewRepository[PersonEvent](eventStore, nil)
+	cache := eventsourcing.NewAggregateCache(10, 0)
+	repo.SetCache(cache, *eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cache.Len() != 1 {
+		t.Fatal("expected the saved aggregate to be cached")
+	}
+
+	twin := Person{}
+	err = repo.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.Age != person.Age || twin.Version() != person.Version() {
+		t.Fatalf("wrong aggregate from cache age %d version %d", twin.Age, twin.Version())
+	}
+	if eventStore.afterVersions[0] != person.Version() {
+		t.Fatalf("expected events after the cached version %d got %d", person.Version(), eventStore.afterVersions[0])
+	}
+
+	// a concurrent save makes the stale aggregate fail and invalidates the cache
+	twin.GrowOlder()
+	err = repo.Save(&twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err == nil {
+		t.Fatal("expected concurrency error")
+	}
+	if cache.Len() != 0 {
+		t.Fatal("expected the cache to be invalidated")
+	}
+}
+
+type failingSnapshotStore struct{}
+
+func (failingSnapshotStore) Save(s eventsourcing.Snapshot) error { return errors.New("unavailable") }
+
+func (failingSnapshotStore) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot, error) {
+	return eventsourcing.Snapshot{}, errors.New("unavailable")
+}
+
+func TestTieredCache(t *testing.T) {
+	ser := *eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	eventStore := &afterVersionStore{EventStore: memory.Create[PersonEvent]()}
+	shared := memsnap.New()
+
+	// two instances of the service sharing the event store and snapshot store
+	cache1 := eventsourcing.NewTieredCache(eventsourcing.NewAggregateCache(10, 0), shared, 10)
+	repo1 := eventsourcing.NewRepository[PersonEvent](eventStore, nil)
+	repo1.SetCache(cache1, ser)
+	cache2 := eventsourcing.NewTieredCache(eventsourcing.NewAggregateCache(10, 0), shared, 10)
+	repo2 := eventsourcing.NewRepository[PersonEvent](eventStore, nil)
+	repo2.SetCache(cache2, ser)
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo1.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// wait for the write to the shared store
+	cache1.Close()
+
+	twin := Person{}
+	err = repo2.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.Age != person.Age {
+		t.Fatalf("wrong age %d expected %d", twin.Age, person.Age)
+	}
+	if eventStore.afterVersions[0] != person.Version() {
+		t.Fatalf("expected events after the shared version %d got %d", person.Version(), eventStore.afterVersions[0])
+	}
+	cache2.Close()
+}
+
+func TestTieredCacheSaveAfterClose(t *testing.T) {
+	cache := eventsourcing.NewTieredCache(eventsourcing.NewAggregateCache(10, 0), memsnap.New(), 10)
+	cache.Close()
+	err := cache.Save(eventsourcing.Snapshot{ID: "1", Type: "Person"})
+	if !errors.Is(err, eventsourcing.ErrCacheClosed) {
+		t.Fatalf("expected ErrCacheClosed got %v", err)
+	}
+	// closing twice is a no op
+	cache.Close()
+}
+
+func TestTieredCacheSharedStoreFailure(t *testing.T) {
+	cache := eventsourcing.NewTieredCache(eventsourcing.NewAggregateCache(10, 0), failingSnapshotStore{}, 10)
+	errs := make(chan error, 2)
+	cache.OnError(func(snap eventsourcing.Snapshot, err error) { errs <- err })
+
+	_, err := cache.Get(context.Background(), "1", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected a failing shared store to be a miss got %v", err)
+	}
+	err = cache.Save(eventsourcing.Snapshot{ID: "1", Type: "Person"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache.Close()
+	if len(errs) != 2 {
+		t.Fatalf("expected 2 reported errors got %d", len(errs))
+	}
+}
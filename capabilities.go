@@ -0,0 +1,60 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCapabilityMissing when an event store lacks a capability required by a component
+var ErrCapabilityMissing = errors.New("event store capability missing")
+
+// Capabilities describes the optional features of an event store
+type Capabilities struct {
+	// GlobalOrder is true when the events can be read in the global order via GlobalEventStore
+	GlobalOrder bool
+	// Subscriptions is true when the event store can push saved events to subscribers
+	Subscriptions bool
+	// MultiAggregateTx is true when the events of several aggregates can be saved atomically via BulkSaver
+	MultiAggregateTx bool
+	// Delete is true when the events of an aggregate can be deleted
+	Delete bool
+}
+
+// CapabilitiesDescriber is implemented by event stores describing their capabilities
+type CapabilitiesDescriber interface {
+	Capabilities() Capabilities
+}
+
+// CapabilitiesOf returns the capabilities of the event store. For event stores not implementing CapabilitiesDescriber,
+// like decorators wrapping another event store, they are derived from the interfaces the event store implements.
+func CapabilitiesOf[T any](eventStore EventStore[T]) Capabilities {
+	if d, ok := eventStore.(CapabilitiesDescriber); ok {
+		return d.Capabilities()
+	}
+	_, globalOrder := eventStore.(GlobalEventStore[T])
+	_, subscriptions := eventStore.(AggregateSubscriber[T])
+	_, bulk := eventStore.(BulkSaver[T])
+	return Capabilities{GlobalOrder: globalOrder, Subscriptions: subscriptions, MultiAggregateTx: bulk}
+}
+
+// Require returns ErrCapabilityMissing naming the required capabilities that are missing
+func (c Capabilities) Require(required Capabilities) error {
+	var missing []string
+	if required.GlobalOrder && !c.GlobalOrder {
+		missing = append(missing, "global order")
+	}
+	if required.Subscriptions && !c.Subscriptions {
+		missing = append(missing, "subscriptions")
+	}
+	if required.MultiAggregateTx && !c.MultiAggregateTx {
+		missing = append(missing, "multi aggregate transactions")
+	}
+	if required.Delete && !c.Delete {
+		missing = append(missing, "delete")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: %s", ErrCapabilityMissing, strings.Join(missing, ", "))
+	}
+	return nil
+}
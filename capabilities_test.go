@@ -0,0 +1,52 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/resilience"
+)
+
+// globalStore reads the global order but does not describe its capabilities
+type globalStore struct {
+	eventsourcing.EventStore[PersonEvent]
+}
+
+func (globalStore) GlobalEvents(start, count uint64) ([]eventsourcing.Event[PersonEvent], error) {
+	return nil, nil
+}
+
+func TestCapabilitiesOf(t *testing.T) {
+	c := eventsourcing.CapabilitiesOf[PersonEvent](memory.Create[PersonEvent]())
+	if !c.GlobalOrder || !c.Subscriptions || !c.MultiAggregateTx || c.Delete {
+		t.Fatalf("unexpected memory capabilities %+v", c)
+	}
+
+	// derived from the implemented interfaces
+	c = eventsourcing.CapabilitiesOf[PersonEvent](globalStore{memory.Create[PersonEvent]()})
+	if !c.GlobalOrder || c.Subscriptions || c.MultiAggregateTx {
+		t.Fatalf("expected only global order got %+v", c)
+	}
+	c = eventsourcing.CapabilitiesOf[PersonEvent](resilience.New[PersonEvent](memory.Create[PersonEvent]()))
+	if c != (eventsourcing.Capabilities{}) {
+		t.Fatalf("expected no capabilities got %+v", c)
+	}
+}
+
+func TestCapabilitiesRequire(t *testing.T) {
+	c := eventsourcing.Capabilities{GlobalOrder: true}
+	err := c.Require(eventsourcing.Capabilities{GlobalOrder: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = c.Require(eventsourcing.Capabilities{GlobalOrder: true, MultiAggregateTx: true, Delete: true})
+	if !errors.Is(err, eventsourcing.ErrCapabilityMissing) {
+		t.Fatalf("expected capability missing got %v", err)
+	}
+	if !strings.Contains(err.Error(), "multi aggregate transactions, delete") {
+		t.Fatalf("expected the missing capabilities to be named got %v", err)
+	}
+}
@@ -0,0 +1,148 @@
+// Package cdc ingests row changes from change data capture feeds, like Debezium, as events. It makes it possible to
+// move a legacy CRUD system to event sourcing one table at a time while the legacy system keeps writing the rows.
+package cdc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/projection"
+)
+
+// MetadataPosition is the metadata key holding the feed position of the change an event was ingested from
+const MetadataPosition = "cdc_position"
+
+// ErrIDMissing when the row of a change holds no aggregate id
+var ErrIDMissing = errors.New("aggregate id missing in row")
+
+// Mapping turns the changes of a table into events on an aggregate type
+type Mapping[T any] struct {
+	AggregateType string
+	// IDColumn is the column holding the aggregate id, default "id"
+	IDColumn string
+	// Event returns the event data for the change, false to skip the change
+	Event func(change Change) (T, bool)
+}
+
+// Ingester appends the changes of the mapped tables as events to the aggregates in the event store
+type Ingester[T any] struct {
+	name        string
+	store       eventsourcing.EventStore[T]
+	checkpoints projection.CheckpointStore
+	mappings    map[string]Mapping[T]
+}
+
+// New constructs an ingester. The position of the feed is saved under name in the checkpoint store.
+func New[T any](name string, store eventsourcing.EventStore[T], checkpoints projection.CheckpointStore) *Ingester[T] {
+	return &Ingester[T]{
+		name:        name,
+		store:       store,
+		checkpoints: checkpoints,
+		mappings:    make(map[string]Mapping[T]),
+	}
+}
+
+// Map sets the mapping of the table, changes of tables without a mapping are skipped
+func (i *Ingester[T]) Map(table string, mapping Mapping[T]) {
+	if mapping.IDColumn == "" {
+		mapping.IDColumn = "id"
+	}
+	i.mappings[table] = mapping
+}
+
+// Ingest parses the Debezium message read at position in the feed and applies it. Tombstones are skipped.
+func (i *Ingester[T]) Ingest(ctx context.Context, position eventsourcing.Version, message []byte) error {
+	change, err := ParseDebezium(message)
+	if errors.Is(err, ErrTombstone) {
+		return i.checkpoints.SaveCheckpoint(ctx, i.name, position)
+	} else if err != nil {
+		return err
+	}
+	return i.Apply(ctx, position, change)
+}
+
+// Apply appends the event of the change to its aggregate and saves the position. The positions have to increase
+// through the feed, like a Kafka offset or a database log sequence number.
+//
+// Changes at or before the saved position are skipped. The position is also stored in the metadata of the event,
+// a change redelivered after the event is saved but before the position is, is skipped when it's not newer than the
+// last event of the aggregate. Checking it reads the aggregate's events, keep the streams of ingested aggregates short.
+func (i *Ingester[T]) Apply(ctx context.Context, position eventsourcing.Version, change Change) error {
+	checkpoint, err := i.checkpoints.Checkpoint(ctx, i.name)
+	if err != nil {
+		return err
+	}
+	if checkpoint > 0 && position <= checkpoint {
+		return nil
+	}
+	mapping, ok := i.mappings[change.Table]
+	if ok {
+		err = i.append(ctx, mapping, position, change)
+		if err != nil {
+			return err
+		}
+	}
+	return i.checkpoints.SaveCheckpoint(ctx, i.name, position)
+}
+
+func (i *Ingester[T]) append(ctx context.Context, mapping Mapping[T], position eventsourcing.Version, change Change) error {
+	data, ok := mapping.Event(change)
+	if !ok {
+		return nil
+	}
+	value, ok := change.Row()[mapping.IDColumn]
+	if !ok || value == nil {
+		return fmt.Errorf("%w: %s.%s", ErrIDMissing, change.Table, mapping.IDColumn)
+	}
+	id := fmt.Sprint(value)
+
+	last, err := i.last(ctx, mapping.AggregateType, id)
+	if err != nil {
+		return err
+	}
+	if p, err := eventsourcing.MetadataAs[string](last, MetadataPosition); err == nil {
+		ingested, err := strconv.ParseUint(p, 10, 64)
+		if err == nil && eventsourcing.Version(ingested) >= position {
+			// already ingested
+			return nil
+		}
+	}
+	timestamp := change.Timestamp
+	if timestamp.IsZero() {
+		timestamp = time.Now().UTC()
+	}
+	return i.store.Save([]eventsourcing.Event[T]{{
+		EventID:       eventsourcing.NewEventID(),
+		AggregateID:   id,
+		AggregateType: mapping.AggregateType,
+		Version:       last.Version + 1,
+		Timestamp:     timestamp,
+		Data:          data,
+		Metadata:      map[string]interface{}{MetadataPosition: strconv.FormatUint(uint64(position), 10)},
+	}})
+}
+
+// last returns the last event of the aggregate, the zero event if it has none
+func (i *Ingester[T]) last(ctx context.Context, aggregateType, id string) (eventsourcing.Event[T], error) {
+	last := eventsourcing.Event[T]{}
+	iterator, err := i.store.Get(ctx, id, aggregateType, 0)
+	if errors.Is(err, eventsourcing.ErrNoEvents) {
+		return last, nil
+	} else if err != nil {
+		return last, err
+	}
+	defer iterator.Close()
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return last, nil
+		} else if err != nil {
+			return last, err
+		}
+		last = event
+	}
+}
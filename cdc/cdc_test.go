@@ -0,0 +1,136 @@
+package cdc_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/cdc"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	checkpoints "github.com/hallgren/eventsourcing/projection/memory"
+)
+
+type CustomerImported struct {
+	Name string
+}
+
+type CustomerRenamed struct {
+	Name string
+}
+
+type CustomerRemoved struct{}
+
+const (
+	created = `{"schema":{},"payload":{"before":null,"after":{"id":12345678901234567,"name":"kalle"},"source":{"table":"customers"},"op":"c","ts_ms":1700000000000}}`
+	renamed = `{"before":{"id":12345678901234567,"name":"kalle"},"after":{"id":12345678901234567,"name":"anka"},"source":{"table":"customers"},"op":"u","ts_ms":1700000001000}`
+	removed = `{"before":{"id":12345678901234567,"name":"anka"},"after":null,"source":{"table":"customers"},"op":"d","ts_ms":1700000002000}`
+	order   = `{"before":null,"after":{"id":1},"source":{"table":"orders"},"op":"c"}`
+)
+
+func customers(change cdc.Change) (any, bool) {
+	switch change.Op {
+	case cdc.OpCreate, cdc.OpRead:
+		return &CustomerImported{Name: change.After["name"].(string)}, true
+	case cdc.OpUpdate:
+		return &CustomerRenamed{Name: change.After["name"].(string)}, true
+	case cdc.OpDelete:
+		return &CustomerRemoved{}, true
+	}
+	return nil, false
+}
+
+func events(t *testing.T, es *memory.Memory[any]) []eventsourcing.Event[any] {
+	events, err := es.GlobalEvents(1, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestIngest(t *testing.T) {
+	ctx := context.Background()
+	es := memory.Create[any]()
+	cp := checkpoints.New()
+	i := cdc.New[any]("legacy", es, cp)
+	i.Map("customers", cdc.Mapping[any]{AggregateType: "Customer", Event: customers})
+
+	messages := []string{created, order, renamed, "null", removed}
+	for p, m := range messages {
+		err := i.Ingest(ctx, eventsourcing.Version(p+1), []byte(m))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	e := events(t, es)
+	if len(e) != 3 {
+		t.Fatalf("expected three events got %d", len(e))
+	}
+	if e[0].AggregateID != "12345678901234567" || e[0].AggregateType != "Customer" || e[2].Version != 3 {
+		t.Fatalf("unexpected events %+v", e)
+	}
+	if e[1].Data.(*CustomerRenamed).Name != "anka" || e[1].Timestamp.UnixMilli() != 1700000001000 {
+		t.Fatalf("unexpected rename %+v", e[1])
+	}
+	position, _ := cp.Checkpoint(ctx, "legacy")
+	if position != 5 {
+		t.Fatalf("expected position 5 got %d", position)
+	}
+
+	// redelivered changes are skipped
+	err := i.Ingest(ctx, 3, []byte(renamed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events(t, es)) != 3 {
+		t.Fatal("expected the redelivered change to be skipped")
+	}
+}
+
+func TestIngestSavedBeforePosition(t *testing.T) {
+	ctx := context.Background()
+	es := memory.Create[any]()
+	cp := checkpoints.New()
+	i := cdc.New[any]("legacy", es, cp)
+	i.Map("customers", cdc.Mapping[any]{AggregateType: "Customer", Event: customers})
+
+	err := i.Ingest(ctx, 1, []byte(created))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = i.Ingest(ctx, 2, []byte(renamed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the position was lost after the event was saved
+	cp.SaveCheckpoint(ctx, "legacy", 1)
+	err = i.Ingest(ctx, 2, []byte(renamed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events(t, es)) != 2 {
+		t.Fatal("expected the change to be ingested once")
+	}
+}
+
+func TestIngestIDMissing(t *testing.T) {
+	i := cdc.New[any]("legacy", memory.Create[any](), checkpoints.New())
+	i.Map("orders", cdc.Mapping[any]{AggregateType: "Order", IDColumn: "order_id", Event: func(change cdc.Change) (any, bool) {
+		return &CustomerImported{}, true
+	}})
+	err := i.Ingest(context.Background(), 1, []byte(order))
+	if !errors.Is(err, cdc.ErrIDMissing) {
+		t.Fatalf("expected id missing got %v", err)
+	}
+}
+
+func TestParseDebeziumInvalid(t *testing.T) {
+	_, err := cdc.ParseDebezium([]byte(`{"op":"x","after":{}}`))
+	if !errors.Is(err, cdc.ErrInvalidMessage) {
+		t.Fatalf("expected invalid message got %v", err)
+	}
+	_, err = cdc.ParseDebezium([]byte(`{"schema":{},"payload":null}`))
+	if !errors.Is(err, cdc.ErrTombstone) {
+		t.Fatalf("expected tombstone got %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+package cdc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Op is the kind of row change
+type Op string
+
+// The row changes in a Debezium feed
+const (
+	OpCreate Op = "c"
+	OpUpdate Op = "u"
+	OpDelete Op = "d"
+	// OpRead is a row read while the connector takes its initial snapshot of the table
+	OpRead Op = "r"
+)
+
+var (
+	// ErrTombstone when the message is the empty tombstone following a delete
+	ErrTombstone = errors.New("tombstone message")
+	// ErrInvalidMessage when the message is not a Debezium change event
+	ErrInvalidMessage = errors.New("invalid change message")
+)
+
+// Change is a row change read from a change data capture feed
+type Change struct {
+	Op        Op
+	Table     string
+	Before    map[string]interface{}
+	After     map[string]interface{}
+	Timestamp time.Time
+}
+
+// Row returns the row after the change, or the row before it for deletes
+func (c Change) Row() map[string]interface{} {
+	if c.Op == OpDelete {
+		return c.Before
+	}
+	return c.After
+}
+
+type debeziumSource struct {
+	Table string `json:"table"`
+}
+
+type debeziumPayload struct {
+	Op     Op                     `json:"op"`
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source debeziumSource         `json:"source"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+// ParseDebezium parses a Debezium JSON change event, with or without the schema envelope. Numbers in the rows are
+// kept as json.Number to not lose the precision of large keys.
+func ParseDebezium(message []byte) (Change, error) {
+	message = bytes.TrimSpace(message)
+	if len(message) == 0 || bytes.Equal(message, []byte("null")) {
+		return Change{}, ErrTombstone
+	}
+	envelope := struct {
+		Payload json.RawMessage `json:"payload"`
+	}{}
+	err := json.Unmarshal(message, &envelope)
+	if err != nil {
+		return Change{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	if len(envelope.Payload) > 0 {
+		if bytes.Equal(envelope.Payload, []byte("null")) {
+			return Change{}, ErrTombstone
+		}
+		message = envelope.Payload
+	}
+	payload := debeziumPayload{}
+	dec := json.NewDecoder(bytes.NewReader(message))
+	dec.UseNumber()
+	err = dec.Decode(&payload)
+	if err != nil {
+		return Change{}, fmt.Errorf("%w: %v", ErrInvalidMessage, err)
+	}
+	switch payload.Op {
+	case OpCreate, OpUpdate, OpRead:
+		if payload.After == nil {
+			return Change{}, fmt.Errorf("%w: %s without after", ErrInvalidMessage, payload.Op)
+		}
+	case OpDelete:
+		if payload.Before == nil {
+			return Change{}, fmt.Errorf("%w: delete without before", ErrInvalidMessage)
+		}
+	default:
+		return Change{}, fmt.Errorf("%w: unknown op %q", ErrInvalidMessage, payload.Op)
+	}
+	change := Change{
+		Op:     payload.Op,
+		Table:  payload.Source.Table,
+		Before: payload.Before,
+		After:  payload.After,
+	}
+	if payload.TsMs > 0 {
+		change.Timestamp = time.UnixMilli(payload.TsMs).UTC()
+	}
+	return change, nil
+}
@@ -0,0 +1,36 @@
+package eventsourcing
+
+import "context"
+
+// Cipher encrypts the marshaled data of the serializer, like the keyring of the envelope package
+type Cipher interface {
+	Encrypt(ctx context.Context, data []byte) ([]byte, error)
+	Decrypt(ctx context.Context, data []byte) ([]byte, error)
+}
+
+type cipherHolder struct {
+	cipher Cipher
+}
+
+// SetCipher encrypts the data marshaled by the serializer and decrypts the data before it's unmarshaled. The event
+// data is validated against the registered schemas before it's encrypted and upcasted and validated after it's
+// decrypted. It applies to the event stores using the serializer, also those opened before the cipher is set.
+func (h *Serializer[T]) SetCipher(c Cipher) {
+	h.cipher.cipher = c
+}
+
+// encrypt encrypts the data if a cipher is set
+func (h *Serializer[T]) encrypt(data []byte) ([]byte, error) {
+	if h.cipher == nil || h.cipher.cipher == nil {
+		return data, nil
+	}
+	return h.cipher.cipher.Encrypt(context.Background(), data)
+}
+
+// decrypt decrypts the data if a cipher is set
+func (h *Serializer[T]) decrypt(data []byte) ([]byte, error) {
+	if h.cipher == nil || h.cipher.cipher == nil {
+		return data, nil
+	}
+	return h.cipher.cipher.Decrypt(context.Background(), data)
+}
@@ -0,0 +1,126 @@
+// Package compose folds the events of other aggregate types into an aggregate, like a CustomerStatistics aggregate
+// counting the orders of the customer from the Order events. The foreign events are applied as events of the
+// aggregate tracked with eventsourcing.TrackForeign, keeping them apart from the events its commands produce.
+package compose
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/projection"
+)
+
+const batchSize = 100
+
+type applier[T, F any, A eventsourcing.Aggregate[T]] struct {
+	target func(event eventsourcing.Event[F]) string
+	apply  func(aggregate A, event eventsourcing.Event[F]) (T, bool)
+}
+
+// Updater applies the foreign events read from the source to the aggregates in the repository. The position is saved
+// in the checkpoint store after each event, a foreign event is applied at least once.
+type Updater[T, F any, A eventsourcing.Aggregate[T]] struct {
+	name         string
+	repo         *eventsourcing.Repository[T]
+	source       eventsourcing.GlobalEventStore[F]
+	checkpoints  projection.CheckpointStore
+	newAggregate func() A
+	appliers     map[string][]applier[T, F, A]
+}
+
+// New constructs an updater, the name is the key of its position in the checkpoint store and newAggregate returns
+// an empty aggregate to load the targeted aggregates into
+func New[T, F any, A eventsourcing.Aggregate[T]](name string, repo *eventsourcing.Repository[T], source eventsourcing.GlobalEventStore[F], checkpoints projection.CheckpointStore, newAggregate func() A) *Updater[T, F, A] {
+	return &Updater[T, F, A]{
+		name:         name,
+		repo:         repo,
+		source:       source,
+		checkpoints:  checkpoints,
+		newAggregate: newAggregate,
+		appliers:     make(map[string][]applier[T, F, A]),
+	}
+}
+
+// ApplyForeign registers how the foreign events with the same reason as the event are applied. target returns the id
+// of the aggregate to apply the event to, an empty id skips the event. apply returns the event data to track on the
+// aggregate or false to skip it, the aggregate is created if it doesn't exist.
+func (u *Updater[T, F, A]) ApplyForeign(event F, target func(event eventsourcing.Event[F]) string, apply func(aggregate A, event eventsourcing.Event[F]) (T, bool)) {
+	reason := eventsourcing.Event[F]{Data: event}.Reason()
+	u.appliers[reason] = append(u.appliers[reason], applier[T, F, A]{target: target, apply: apply})
+}
+
+// Handle applies the foreign event to the aggregates it targets, it can be called from a subscription
+func (u *Updater[T, F, A]) Handle(ctx context.Context, event eventsourcing.Event[F]) error {
+	for _, a := range u.appliers[event.Reason()] {
+		id := a.target(event)
+		if id == "" {
+			continue
+		}
+		aggregate := u.newAggregate()
+		err := u.repo.GetWithContext(ctx, id, aggregate)
+		if errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+			err = aggregate.Root().SetID(id)
+		}
+		if err != nil {
+			return err
+		}
+		data, ok := a.apply(aggregate, event)
+		if !ok {
+			continue
+		}
+		err = eventsourcing.TrackForeign[T, F](aggregate, data, event)
+		if err != nil {
+			return err
+		}
+		err = u.repo.Save(aggregate)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Poll applies the next batch of foreign events and returns the number of events read
+func (u *Updater[T, F, A]) Poll(ctx context.Context) (int, error) {
+	position, err := u.checkpoints.Checkpoint(ctx, u.name)
+	if err != nil {
+		return 0, err
+	}
+	events, err := u.source.GlobalEvents(uint64(position)+1, batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, event := range events {
+		err = u.Handle(ctx, event)
+		if err != nil {
+			return i, err
+		}
+		err = u.checkpoints.SaveCheckpoint(ctx, u.name, event.GlobalVersion)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// Run polls the foreign events until the context is done, waiting interval when there are no new events
+func (u *Updater[T, F, A]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := u.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
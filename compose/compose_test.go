@@ -0,0 +1,123 @@
+package compose_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/compose"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	projmem "github.com/hallgren/eventsourcing/projection/memory"
+)
+
+type OrderEvent interface{ orderEvent() }
+
+type OrderPlaced struct {
+	CustomerID string
+	Amount     int
+}
+
+func (*OrderPlaced) orderEvent() {}
+
+type StatisticsEvent interface{ statisticsEvent() }
+
+type OrderCounted struct {
+	Amount int
+}
+
+func (*OrderCounted) statisticsEvent() {}
+
+type Reset struct{}
+
+func (*Reset) statisticsEvent() {}
+
+// CustomerStatistics folds in the orders of the customer
+type CustomerStatistics struct {
+	eventsourcing.AggregateRoot[StatisticsEvent]
+	Orders  int
+	Total   int
+	Foreign int
+}
+
+func (c *CustomerStatistics) Transition(event eventsourcing.Event[StatisticsEvent]) {
+	if _, ok := eventsourcing.ForeignOf(event); ok {
+		c.Foreign++
+	}
+	switch e := event.Data.(type) {
+	case *OrderCounted:
+		c.Orders++
+		c.Total += e.Amount
+	case *Reset:
+		c.Orders = 0
+		c.Total = 0
+	}
+}
+
+func TestUpdater(t *testing.T) {
+	orders := memory.Create[OrderEvent]()
+	for i, amount := range []int{10, 0, 5} {
+		err := orders.Save([]eventsourcing.Event[OrderEvent]{{AggregateID: string(rune('a' + i)), AggregateType: "Order", Version: 1, Data: &OrderPlaced{CustomerID: "kalle", Amount: amount}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	repo := eventsourcing.NewRepository[StatisticsEvent](memory.Create[StatisticsEvent](), nil)
+	checkpoints := projmem.New()
+
+	updater := compose.New[StatisticsEvent, OrderEvent]("customer-statistics", repo, orders, checkpoints, func() *CustomerStatistics { return &CustomerStatistics{} })
+	updater.ApplyForeign(&OrderPlaced{}, func(event eventsourcing.Event[OrderEvent]) string {
+		return event.Data.(*OrderPlaced).CustomerID
+	}, func(statistics *CustomerStatistics, event eventsourcing.Event[OrderEvent]) (StatisticsEvent, bool) {
+		placed := event.Data.(*OrderPlaced)
+		// empty orders are not counted
+		return &OrderCounted{Amount: placed.Amount}, placed.Amount > 0
+	})
+	n, err := updater.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 events got %d", n)
+	}
+
+	statistics := CustomerStatistics{}
+	err = repo.Get("kalle", &statistics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if statistics.Orders != 2 || statistics.Total != 15 || statistics.Foreign != 2 {
+		t.Fatalf("expected two counted orders got %+v", statistics)
+	}
+
+	// events of the own commands are not foreign
+	statistics.TrackChange(&statistics, &Reset{})
+	if statistics.Foreign != 2 {
+		t.Fatalf("expected the reset not to be foreign got %d", statistics.Foreign)
+	}
+	err = repo.Save(&statistics)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a new updater continues from the checkpoint
+	n, err = compose.New[StatisticsEvent, OrderEvent]("customer-statistics", repo, orders, checkpoints, func() *CustomerStatistics { return &CustomerStatistics{} }).Poll(context.Background())
+	if err != nil || n != 0 {
+		t.Fatalf("expected no events got %d %v", n, err)
+	}
+}
+
+func TestForeignOf(t *testing.T) {
+	statistics := &CustomerStatistics{}
+	foreign := eventsourcing.Event[OrderEvent]{AggregateType: "Order", AggregateID: "a", Version: 3, GlobalVersion: 7, EventID: "e1"}
+	err := eventsourcing.TrackForeign[StatisticsEvent](statistics, &OrderCounted{}, foreign)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ref, ok := eventsourcing.ForeignOf(statistics.Events()[0])
+	if !ok || ref != (eventsourcing.ForeignRef{AggregateType: "Order", AggregateID: "a", Version: 3, GlobalVersion: 7}) {
+		t.Fatalf("unexpected foreign ref %+v %v", ref, ok)
+	}
+	if statistics.Events()[0].Metadata[eventsourcing.MetadataCausationID] != "e1" {
+		t.Fatalf("expected the foreign event id as causation id got %v", statistics.Events()[0].Metadata)
+	}
+}
@@ -0,0 +1,273 @@
+// Package debugger steps through the events of an aggregate to diagnose corrupt state. The aggregate is rebuilt with
+// its own Transition and the state is printed after each event. The events are loaded once, stepping back rebuilds the
+// aggregate from the first event as transitions can't be undone.
+//
+// The aggregate types are only known by the application, Run is meant to be wired into the application's own
+// command line tool.
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/timeline"
+)
+
+// ErrOutOfRange when stepping before the first or after the last event
+var ErrOutOfRange = errors.New("version out of range")
+
+// Debugger holds the events of an aggregate and the aggregate built up to the current version
+type Debugger[T any] struct {
+	events       []eventsourcing.Event[T]
+	newAggregate func() eventsourcing.Aggregate[T]
+	aggregate    eventsourcing.Aggregate[T]
+	previous     eventsourcing.Aggregate[T]
+	position     int
+	err          error
+}
+
+// New loads the events of the aggregate from the event store. The debugger starts before the first event with the
+// aggregate from newAggregate.
+func New[T any](ctx context.Context, store eventsourcing.EventStore[T], aggregateType, id string, newAggregate func() eventsourcing.Aggregate[T]) (*Debugger[T], error) {
+	events, err := timeline.Stream(ctx, store, aggregateType, id)
+	if err != nil && !errors.Is(err, eventsourcing.ErrNoEvents) {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("%w: %s %s", eventsourcing.ErrAggregateNotFound, aggregateType, id)
+	}
+	d := &Debugger[T]{events: events, newAggregate: newAggregate}
+	d.reset()
+	return d, nil
+}
+
+// Version returns the version of the last applied event, 0 before the first event
+func (d *Debugger[T]) Version() eventsourcing.Version {
+	if d.position == 0 {
+		return 0
+	}
+	return d.events[d.position-1].Version
+}
+
+// Last returns the version of the last event of the aggregate
+func (d *Debugger[T]) Last() eventsourcing.Version {
+	return d.events[len(d.events)-1].Version
+}
+
+// Aggregate returns the aggregate built up to the current version
+func (d *Debugger[T]) Aggregate() eventsourcing.Aggregate[T] {
+	return d.aggregate
+}
+
+// Event returns the last applied event, false before the first event
+func (d *Debugger[T]) Event() (eventsourcing.Event[T], bool) {
+	if d.position == 0 {
+		return eventsourcing.Event[T]{}, false
+	}
+	return d.events[d.position-1], true
+}
+
+// Err returns the panic of the last applied transition, nil if it returned normally
+func (d *Debugger[T]) Err() error {
+	return d.err
+}
+
+// Forward applies the next event
+func (d *Debugger[T]) Forward() error {
+	if d.position == len(d.events) {
+		return fmt.Errorf("%w: %d is the last version", ErrOutOfRange, d.Last())
+	}
+	d.apply()
+	return nil
+}
+
+// Back rebuilds the aggregate without the last applied event
+func (d *Debugger[T]) Back() error {
+	if d.position == 0 {
+		return fmt.Errorf("%w: before the first event", ErrOutOfRange)
+	}
+	return d.Goto(d.events[d.position-1].Version - 1)
+}
+
+// Goto builds the aggregate up to and including the event with the version, 0 for the new aggregate
+func (d *Debugger[T]) Goto(version eventsourcing.Version) error {
+	if version > d.Last() {
+		return fmt.Errorf("%w: %d is after the last version %d", ErrOutOfRange, version, d.Last())
+	}
+	if version < d.Version() {
+		d.reset()
+	}
+	for d.position < len(d.events) && d.events[d.position].Version <= version {
+		d.apply()
+	}
+	return nil
+}
+
+// Changes returns the fields of the state changed by the last applied event, the values of sensitive fields are
+// redacted
+func (d *Debugger[T]) Changes() ([]eventsourcing.FieldChange, error) {
+	return eventsourcing.DiffMasked(d.previous, d.aggregate, eventsourcing.MaskRedact)
+}
+
+func (d *Debugger[T]) reset() {
+	d.aggregate = d.newAggregate()
+	d.previous = d.newAggregate()
+	d.position = 0
+	d.err = nil
+}
+
+// apply applies the next event, a panicking transition is kept as the error instead of stopping the debugger
+func (d *Debugger[T]) apply() {
+	event := d.events[d.position]
+	d.position++
+	d.previous = d.copyState()
+	d.err = nil
+	defer func() {
+		if r := recover(); r != nil {
+			d.err = fmt.Errorf("transition of version %d %s panicked: %v", event.Version, event.Reason(), r)
+		}
+	}()
+	d.aggregate.Root().BuildFromHistory(d.aggregate, []eventsourcing.Event[T]{event})
+}
+
+const help = `commands:
+  n, next          apply the next event
+  b, back          step back one event
+  g, goto VERSION  build the aggregate up to the version
+  s, state         print the state
+  d, diff          print the fields changed by the last event
+  l, list          list the events
+  h, help          print the commands
+  q, quit          stop debugging
+`
+
+// Run reads commands from in and writes the results to out until quit or the end of in. The state is printed after
+// each step.
+func (d *Debugger[T]) Run(in io.Reader, out io.Writer) error {
+	fmt.Fprintf(out, "%d events, type help for the commands\n", len(d.events))
+	scanner := bufio.NewScanner(in)
+	for {
+		fmt.Fprintf(out, "(%d/%d) ", d.Version(), d.Last())
+		if !scanner.Scan() {
+			fmt.Fprintln(out)
+			return scanner.Err()
+		}
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var err error
+		switch fields[0] {
+		case "n", "next":
+			err = d.step(out, d.Forward)
+		case "b", "back":
+			err = d.step(out, d.Back)
+		case "g", "goto":
+			if len(fields) != 2 {
+				err = errors.New("goto needs a version")
+				break
+			}
+			var version uint64
+			version, err = strconv.ParseUint(fields[1], 10, 64)
+			if err != nil {
+				err = fmt.Errorf("invalid version %q", fields[1])
+				break
+			}
+			err = d.step(out, func() error { return d.Goto(eventsourcing.Version(version)) })
+		case "s", "state":
+			err = d.printState(out)
+		case "d", "diff":
+			err = d.printChanges(out)
+		case "l", "list":
+			d.printEvents(out)
+		case "h", "help":
+			fmt.Fprint(out, help)
+		case "q", "quit":
+			return nil
+		default:
+			err = fmt.Errorf("unknown command %q, type help for the commands", fields[0])
+		}
+		if err != nil {
+			fmt.Fprintf(out, "error: %v\n", err)
+		}
+	}
+}
+
+func (d *Debugger[T]) step(out io.Writer, f func() error) error {
+	err := f()
+	if err != nil {
+		return err
+	}
+	if event, ok := d.Event(); ok {
+		fmt.Fprintf(out, "version %d %s %s\n", event.Version, event.Reason(), event.Timestamp.Format(time.RFC3339))
+	} else {
+		fmt.Fprintln(out, "version 0 new aggregate")
+	}
+	if d.err != nil {
+		fmt.Fprintf(out, "error: %v\n", d.err)
+	}
+	return d.printState(out)
+}
+
+func (d *Debugger[T]) printState(out io.Writer) error {
+	b, err := d.state()
+	if err != nil {
+		return err
+	}
+	var indented bytes.Buffer
+	err = json.Indent(&indented, b, "", "  ")
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s\n", indented.Bytes())
+	return nil
+}
+
+// copyState returns a copy of the aggregate decoded from the JSON of its state
+func (d *Debugger[T]) copyState() eventsourcing.Aggregate[T] {
+	c := d.newAggregate()
+	b, err := json.Marshal(d.aggregate)
+	if err == nil {
+		_ = json.Unmarshal(b, c)
+	}
+	return c
+}
+
+// state returns the state of the aggregate as JSON with the fields tagged es:"sensitive" redacted, as it's printed to
+// the terminal
+func (d *Debugger[T]) state() ([]byte, error) {
+	return eventsourcing.MarshalMasked(d.aggregate, eventsourcing.MaskRedact)
+}
+
+func (d *Debugger[T]) printChanges(out io.Writer) error {
+	changes, err := d.Changes()
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Fprintln(out, "no changes")
+	}
+	for _, c := range changes {
+		fmt.Fprintf(out, "%s: %v -> %v\n", c.Field, c.From, c.To)
+	}
+	return nil
+}
+
+func (d *Debugger[T]) printEvents(out io.Writer) {
+	for i, event := range d.events {
+		marker := " "
+		if i == d.position-1 {
+			marker = ">"
+		}
+		fmt.Fprintf(out, "%s %d %s\n", marker, event.Version, event.Reason())
+	}
+}
@@ -0,0 +1,135 @@
+package debugger_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/debugger"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Opened struct{}
+
+type Deposited struct {
+	Amount int
+}
+
+type Corrupted struct{}
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+	Balance int
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {
+	switch e := event.Data.(type) {
+	case *Deposited:
+		a.Balance += e.Amount
+	case *Corrupted:
+		panic("corrupt state")
+	}
+}
+
+func newDebugger(t *testing.T) *debugger.Debugger[any] {
+	es := memory.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Account", Version: 1, Data: &Opened{}},
+		{AggregateID: "1", AggregateType: "Account", Version: 2, Data: &Deposited{Amount: 10}},
+		{AggregateID: "1", AggregateType: "Account", Version: 3, Data: &Deposited{Amount: 5}},
+		{AggregateID: "1", AggregateType: "Account", Version: 4, Data: &Corrupted{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := debugger.New[any](context.Background(), es, "Account", "1", func() eventsourcing.Aggregate[any] { return &Account{} })
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func balance(d *debugger.Debugger[any]) int {
+	return d.Aggregate().(*Account).Balance
+}
+
+func TestStepping(t *testing.T) {
+	d := newDebugger(t)
+	if d.Version() != 0 || d.Last() != 4 {
+		t.Fatalf("expected version 0 of 4 got %d of %d", d.Version(), d.Last())
+	}
+	err := d.Back()
+	if !errors.Is(err, debugger.ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange got %v", err)
+	}
+	err = d.Goto(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version() != 3 || balance(d) != 15 {
+		t.Fatalf("expected version 3 with balance 15 got %d %d", d.Version(), balance(d))
+	}
+	changes, err := d.Changes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0].Field != "Balance" || changes[0].From != float64(10) {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	err = d.Back()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Version() != 2 || balance(d) != 10 {
+		t.Fatalf("expected version 2 with balance 10 got %d %d", d.Version(), balance(d))
+	}
+
+	// a panicking transition is kept as the error
+	err = d.Goto(4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Err() == nil || !strings.Contains(d.Err().Error(), "corrupt state") {
+		t.Fatalf("expected the panic as error got %v", d.Err())
+	}
+	err = d.Forward()
+	if !errors.Is(err, debugger.ErrOutOfRange) {
+		t.Fatalf("expected ErrOutOfRange got %v", err)
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	_, err := debugger.New[any](context.Background(), memory.Create[any](), "Account", "1", func() eventsourcing.Aggregate[any] { return &Account{} })
+	if !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected ErrAggregateNotFound got %v", err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	d := newDebugger(t)
+	out := bytes.Buffer{}
+	err := d.Run(strings.NewReader("n\nn\nd\nb\ngoto 7\nl\nfoo\nq\nn\n"), &out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{
+		"version 2 Deposited",
+		`"Balance": 10`,
+		"Balance: 0 -> 10",
+		"version 1 Opened",
+		"error: version out of range: 7 is after the last version 4",
+		"> 1 Opened",
+		`error: unknown command "foo"`,
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Fatalf("expected %q in\n%s", expected, out.String())
+		}
+	}
+	// the commands after quit are not run
+	if d.Version() != 1 {
+		t.Fatalf("expected version 1 got %d", d.Version())
+	}
+}
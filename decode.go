@@ -0,0 +1,138 @@
+package eventsourcing
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeErrorPolicy decides what the event stores do with an event whose data can't be unmarshaled
+type DecodeErrorPolicy int
+
+const (
+	// FailOnDecodeError returns the DecodeError, aborting the read. It's the default policy.
+	FailOnDecodeError DecodeErrorPolicy = iota
+	// SkipOnDecodeError skips the event, reads continue with the next event
+	SkipOnDecodeError
+	// RawOnDecodeError returns the event with the data as a *CorruptData when T can hold it, like when T is any,
+	// otherwise with the zero value as data
+	RawOnDecodeError
+)
+
+// DecodeError is the error of an event whose data can't be unmarshaled
+type DecodeError struct {
+	AggregateType string
+	AggregateID   string
+	Version       Version
+	GlobalVersion Version
+	Reason        string
+	Data          []byte
+	Err           error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("could not deserialize event data %s %s version %d global version %d %s: %v", e.AggregateType, e.AggregateID, e.Version, e.GlobalVersion, e.Reason, e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// CorruptData is the data of an event returned by the RawOnDecodeError policy. It keeps the reason of the stored event
+// returned by Event.Reason.
+type CorruptData struct {
+	EventReason string
+	Data        []byte
+	Err         error
+}
+
+// StoredReason returns the reason of the stored event, see Reasoner
+func (c *CorruptData) StoredReason() string {
+	return c.EventReason
+}
+
+type decodePolicy struct {
+	policy  DecodeErrorPolicy
+	onError func(err *DecodeError)
+}
+
+// SetDecodeErrorPolicy sets what the event stores do with events whose data can't be unmarshaled. onError, if not
+// nil, is called with the error before the event is skipped or returned raw, to alert operators of the offending
+// event. It applies to the event stores using the serializer, also those opened before the policy is set, set it
+// before events are read.
+func (h *Serializer[T]) SetDecodeErrorPolicy(policy DecodeErrorPolicy, onError func(err *DecodeError)) {
+	h.decode.policy = policy
+	h.decode.onError = onError
+}
+
+// UnmarshalEvent unmarshals the data into the data of the event, the other fields of the event are expected to be set
+// to report them in a DecodeError. It returns false if the event is to be skipped, when its reason is not registered
+// on the aggregate type or the data is corrupt and the decode error policy skips it.
+func (h *Serializer[T]) UnmarshalEvent(event *Event[T], reason string, data []byte) (bool, error) {
+	var reuse T
+	return h.unmarshalEvent(event, reason, data, reuse)
+}
+
+// unmarshalEvent unmarshals into reuse instead of a new instance when it's a pointer to the registered type
+func (h *Serializer[T]) unmarshalEvent(event *Event[T], reason string, data []byte, reuse T) (bool, error) {
+	f, ok := h.Type(event.AggregateType, reason)
+	if !ok {
+		return false, nil
+	}
+	upcasted, err := h.decrypt(data)
+	if err == nil {
+		upcasted, err = h.upcast(event.AggregateType, reason, upcasted)
+	}
+	if err == nil && h.validateOnRead() {
+		err = h.validateSchema(event.AggregateType, reason, upcasted)
+	}
+	var eventData T
+	if err == nil {
+		if h.reusable(event.AggregateType, reason, reuse) {
+			eventData = reuse
+			err = h.unmarshal(upcasted, any(eventData))
+		} else {
+			eventData = f()
+			err = h.unmarshal(upcasted, &eventData)
+		}
+	}
+	if err == nil {
+		event.Data = eventData
+		return true, nil
+	}
+	decodeErr := &DecodeError{
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Version:       event.Version,
+		GlobalVersion: event.GlobalVersion,
+		Reason:        reason,
+		Data:          data,
+		Err:           err,
+	}
+	policy := decodePolicy{}
+	if h.decode != nil {
+		policy = *h.decode
+	}
+	if policy.policy == FailOnDecodeError {
+		return false, decodeErr
+	}
+	if policy.onError != nil {
+		policy.onError(decodeErr)
+	}
+	if policy.policy == SkipOnDecodeError {
+		return false, nil
+	}
+	if raw, ok := any(&CorruptData{EventReason: reason, Data: data, Err: err}).(T); ok {
+		event.Data = raw
+	}
+	return true, nil
+}
+
+// reusable resets data to its zero value and returns true if it's a pointer to the type registered for the reason
+func (h *Serializer[T]) reusable(aggregateType, reason string, data T) bool {
+	v := reflect.ValueOf(data)
+	if !v.IsValid() || v.Kind() != reflect.Ptr || v.IsNil() || v.Type() != h.eventTypes[aggregateType+"_"+reason] {
+		return false
+	}
+	v.Elem().Set(reflect.Zero(v.Type().Elem()))
+	return true
+}
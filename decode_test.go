@@ -0,0 +1,91 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Untyped is an aggregate with untyped events
+type Untyped struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (u *Untyped) Transition(event eventsourcing.Event[any]) {}
+
+func TestUnmarshalEvent(t *testing.T) {
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Untyped{}, ser.Events(&Born{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := eventsourcing.Event[any]{AggregateType: "Untyped", AggregateID: "1", Version: 2, GlobalVersion: 7}
+
+	ok, err := ser.UnmarshalEvent(&event, "Born", []byte(`{"Name":"kalle"}`))
+	if err != nil || !ok {
+		t.Fatalf("expected the event to unmarshal got %v %v", ok, err)
+	}
+	if event.Data.(*Born).Name != "kalle" {
+		t.Fatalf("unexpected data %v", event.Data)
+	}
+	ok, err = ser.UnmarshalEvent(&event, "Unknown", []byte(`{}`))
+	if err != nil || ok {
+		t.Fatalf("expected an unregistered reason to be skipped got %v %v", ok, err)
+	}
+
+	// the default policy fails naming the event
+	_, err = ser.UnmarshalEvent(&event, "Born", []byte(`{`))
+	decodeErr := &eventsourcing.DecodeError{}
+	if !errors.As(err, &decodeErr) {
+		t.Fatalf("expected a decode error got %v", err)
+	}
+	if decodeErr.AggregateID != "1" || decodeErr.GlobalVersion != 7 || decodeErr.Reason != "Born" {
+		t.Fatalf("expected the decode error to name the event got %+v", decodeErr)
+	}
+
+	var reported []*eventsourcing.DecodeError
+	ser.SetDecodeErrorPolicy(eventsourcing.SkipOnDecodeError, func(err *eventsourcing.DecodeError) {
+		reported = append(reported, err)
+	})
+	ok, err = ser.UnmarshalEvent(&event, "Born", []byte(`{`))
+	if err != nil || ok {
+		t.Fatalf("expected the corrupt event to be skipped got %v %v", ok, err)
+	}
+
+	ser.SetDecodeErrorPolicy(eventsourcing.RawOnDecodeError, func(err *eventsourcing.DecodeError) {
+		reported = append(reported, err)
+	})
+	ok, err = ser.UnmarshalEvent(&event, "Born", []byte(`{`))
+	if err != nil || !ok {
+		t.Fatalf("expected the corrupt event to be returned got %v %v", ok, err)
+	}
+	raw, isRaw := event.Data.(*eventsourcing.CorruptData)
+	if !isRaw || string(raw.Data) != "{" {
+		t.Fatalf("expected the raw data got %v", event.Data)
+	}
+	if event.Reason() != "Born" {
+		t.Fatalf("expected the reason of the stored event got %q", event.Reason())
+	}
+	if len(reported) != 2 {
+		t.Fatalf("expected both corrupt events to be reported got %d", len(reported))
+	}
+}
+
+func TestUnmarshalEventRawTyped(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Person{}, ser.Events(&Born{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser.SetDecodeErrorPolicy(eventsourcing.RawOnDecodeError, nil)
+	event := eventsourcing.Event[PersonEvent]{AggregateType: "Person", AggregateID: "1", Version: 1}
+	ok, err := ser.UnmarshalEvent(&event, "Born", []byte(`{`))
+	if err != nil || !ok {
+		t.Fatalf("expected the corrupt event to be returned got %v %v", ok, err)
+	}
+	if event.Data != nil {
+		t.Fatalf("expected the zero value as data when T can't hold the raw data got %v", event.Data)
+	}
+}
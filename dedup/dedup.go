@@ -0,0 +1,77 @@
+package dedup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrDuplicate when the command is already processed
+var ErrDuplicate = errors.New("duplicate command")
+
+// Key identifies a command sent to an aggregate
+type Key struct {
+	AggregateType string
+	AggregateID   string
+	CommandID     string
+}
+
+// Store records the processed commands
+type Store interface {
+	// Claim records the command as processed at the time, returns false if it's already recorded
+	Claim(ctx context.Context, key Key, at time.Time) (bool, error)
+	// Release removes the record of a command that failed to make it possible to retry it
+	Release(ctx context.Context, key Key) error
+	// Prune removes the records made before the time and returns the number of removed records
+	Prune(ctx context.Context, before time.Time) (int, error)
+}
+
+// Deduplicator rejects commands that are already processed by an aggregate, making it safe to handle
+// commands arriving via at-least-once transports.
+type Deduplicator[T any] struct {
+	repo  *eventsourcing.Repository[T]
+	store Store
+}
+
+// New constructs a deduplicator updating the aggregates in the repository
+func New[T any](repo *eventsourcing.Repository[T], store Store) *Deduplicator[T] {
+	return &Deduplicator[T]{
+		repo:  repo,
+		store: store,
+	}
+}
+
+// Update runs the command on the aggregate via the repository Update unless a command with the same id is
+// already processed by the aggregate, then ErrDuplicate is returned. The command is claimed before it runs and
+// released if it fails. The claim is kept when the events are saved and a snapshot or after save hook fails.
+func (d *Deduplicator[T]) Update(ctx context.Context, commandID, id string, aggregate eventsourcing.Aggregate[T], command func() error) error {
+	key := Key{
+		AggregateType: reflect.TypeOf(aggregate).Elem().Name(),
+		AggregateID:   id,
+		CommandID:     commandID,
+	}
+	claimed, err := d.store.Claim(ctx, key, time.Now())
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return fmt.Errorf("%w: %s on %s %s", ErrDuplicate, commandID, key.AggregateType, id)
+	}
+	err = d.repo.Update(ctx, id, aggregate, command)
+	if errors.Is(err, eventsourcing.ErrSnapshotAfterSave) || errors.Is(err, eventsourcing.ErrAfterSave) {
+		// the events are saved, the command is processed
+		return err
+	}
+	if err != nil {
+		releaseErr := d.store.Release(ctx, key)
+		if releaseErr != nil {
+			return fmt.Errorf("%v, could not release the command, %v", err, releaseErr)
+		}
+		return err
+	}
+	return nil
+}
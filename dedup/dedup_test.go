@@ -0,0 +1,124 @@
+package dedup_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/dedup"
+	"github.com/hallgren/eventsourcing/dedup/memory"
+	esmem "github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Counter struct {
+	eventsourcing.AggregateRoot[any]
+	Count int
+}
+
+type Incremented struct{}
+
+func (c *Counter) Transition(event eventsourcing.Event[any]) {
+	switch event.Data.(type) {
+	case *Incremented:
+		c.Count++
+	}
+}
+
+func TestDeduplicator(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](esmem.Create[any](), nil)
+	counter := &Counter{}
+	counter.TrackChange(counter, &Incremented{})
+	err := repo.Save(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d := dedup.New(repo, memory.New())
+	increment := func(c *Counter) func() error {
+		return func() error {
+			c.TrackChange(c, &Incremented{})
+			return nil
+		}
+	}
+
+	c := &Counter{}
+	err = d.Update(context.Background(), "cmd-1", counter.ID(), c, increment(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the redelivered command is rejected
+	c = &Counter{}
+	err = d.Update(context.Background(), "cmd-1", counter.ID(), c, increment(c))
+	if !errors.Is(err, dedup.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate got %v", err)
+	}
+
+	// a failed command can be retried
+	failing := errors.New("failing")
+	err = d.Update(context.Background(), "cmd-2", counter.ID(), &Counter{}, func() error { return failing })
+	if !errors.Is(err, failing) {
+		t.Fatalf("expected the command error got %v", err)
+	}
+	c = &Counter{}
+	err = d.Update(context.Background(), "cmd-2", counter.ID(), c, increment(c))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c = &Counter{}
+	err = repo.Get(counter.ID(), c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Count != 3 {
+		t.Fatalf("expected count 3 got %d", c.Count)
+	}
+}
+
+func TestMemoryPrune(t *testing.T) {
+	store := memory.New()
+	old := dedup.Key{AggregateType: "Counter", AggregateID: "1", CommandID: "old"}
+	store.Claim(context.Background(), old, time.Now().Add(-time.Hour))
+	store.Claim(context.Background(), dedup.Key{AggregateType: "Counter", AggregateID: "1", CommandID: "new"}, time.Now())
+	pruned, err := store.Prune(context.Background(), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("expected one pruned record got %d", pruned)
+	}
+	claimed, _ := store.Claim(context.Background(), old, time.Now())
+	if !claimed {
+		t.Fatal("expected the pruned command to be claimable")
+	}
+}
+
+func TestDeduplicatorKeepsClaimWhenSaved(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](esmem.Create[any](), nil)
+	counter := &Counter{}
+	counter.TrackChange(counter, &Incremented{})
+	err := repo.Save(counter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	failing := errors.New("failing")
+	repo.AddAfterSave(eventsourcing.AfterSaveFunc[any](func(events []eventsourcing.Event[any]) error {
+		return failing
+	}))
+	d := dedup.New(repo, memory.New())
+
+	c := &Counter{}
+	err = d.Update(context.Background(), "cmd-1", counter.ID(), c, func() error {
+		c.TrackChange(c, &Incremented{})
+		return nil
+	})
+	if !errors.Is(err, eventsourcing.ErrAfterSave) {
+		t.Fatalf("expected ErrAfterSave got %v", err)
+	}
+	// the events are saved, the redelivered command is rejected
+	err = d.Update(context.Background(), "cmd-1", counter.ID(), &Counter{}, func() error { return nil })
+	if !errors.Is(err, dedup.ErrDuplicate) {
+		t.Fatalf("expected ErrDuplicate got %v", err)
+	}
+}
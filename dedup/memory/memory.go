@@ -0,0 +1,54 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing/dedup"
+)
+
+// Memory is a dedup store keeping the processed commands in memory. The records are lost on restart, commands
+// redelivered after a restart are processed again. Use a durable store like dedup/sql when that matters.
+type Memory struct {
+	lock      sync.Mutex
+	processed map[dedup.Key]time.Time
+}
+
+// New constructs a memory dedup store
+func New() *Memory {
+	return &Memory{processed: make(map[dedup.Key]time.Time)}
+}
+
+// Claim records the command as processed, returns false if it's already recorded
+func (m *Memory) Claim(ctx context.Context, key dedup.Key, at time.Time) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.processed[key]; ok {
+		return false, nil
+	}
+	m.processed[key] = at
+	return true, nil
+}
+
+// Release removes the record of the command
+func (m *Memory) Release(ctx context.Context, key dedup.Key) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.processed, key)
+	return nil
+}
+
+// Prune removes the records made before the time
+func (m *Memory) Prune(ctx context.Context, before time.Time) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pruned := 0
+	for key, at := range m.processed {
+		if at.Before(before) {
+			delete(m.processed, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
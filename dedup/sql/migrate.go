@@ -0,0 +1,23 @@
+package sql
+
+import "context"
+
+// Migrate the database
+func (s *SQL) Migrate() error {
+	sqlStmt := []string{
+		`create table dedup (aggregate_type VARCHAR NOT NULL, aggregate_id VARCHAR NOT NULL, command_id VARCHAR NOT NULL, claimed_at INTEGER NOT NULL, PRIMARY KEY (aggregate_type, aggregate_id, command_id));`,
+		`create index claimed_at on dedup (claimed_at);`,
+	}
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, b := range sqlStmt {
+		_, err := tx.Exec(b)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,58 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hallgren/eventsourcing/dedup"
+)
+
+// SQL is a dedup store keeping the processed commands in a database table, the records survive restarts
+type SQL struct {
+	db *sql.DB
+}
+
+// New returns a SQL dedup store
+func New(db *sql.DB) *SQL {
+	return &SQL{
+		db: db,
+	}
+}
+
+// Close the connection
+func (s *SQL) Close() {
+	s.db.Close()
+}
+
+// Claim records the command as processed, returns false if it's already recorded. The primary key of the table
+// makes the claim atomic between processes sharing the database.
+func (s *SQL) Claim(ctx context.Context, key dedup.Key, at time.Time) (bool, error) {
+	statement := `INSERT INTO dedup (aggregate_type, aggregate_id, command_id, claimed_at) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING`
+	res, err := s.db.ExecContext(ctx, statement, key.AggregateType, key.AggregateID, key.CommandID, at.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return rows == 1, nil
+}
+
+// Release removes the record of the command
+func (s *SQL) Release(ctx context.Context, key dedup.Key) error {
+	statement := `DELETE FROM dedup WHERE aggregate_type=$1 AND aggregate_id=$2 AND command_id=$3`
+	_, err := s.db.ExecContext(ctx, statement, key.AggregateType, key.AggregateID, key.CommandID)
+	return err
+}
+
+// Prune removes the records made before the time
+func (s *SQL) Prune(ctx context.Context, before time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM dedup WHERE claimed_at < $1`, before.UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
@@ -0,0 +1,78 @@
+package sql_test
+
+import (
+	"context"
+	sqldriver "database/sql"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/dedup"
+	"github.com/hallgren/eventsourcing/dedup/sql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func store(t *testing.T) *sql.SQL {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// each in memory database lives as long as its connection
+	db.SetMaxOpenConns(1)
+	s := sql.New(db)
+	t.Cleanup(s.Close)
+	err = s.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestClaim(t *testing.T) {
+	s := store(t)
+	ctx := context.Background()
+	key := dedup.Key{AggregateType: "Counter", AggregateID: "1", CommandID: "cmd-1"}
+	claimed, err := s.Claim(ctx, key, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Fatal("expected the command to be claimed")
+	}
+	claimed, err = s.Claim(ctx, key, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed {
+		t.Fatal("expected the claimed command to be rejected")
+	}
+	err = s.Release(ctx, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claimed, err = s.Claim(ctx, key, time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Fatal("expected the released command to be claimable")
+	}
+}
+
+func TestPrune(t *testing.T) {
+	s := store(t)
+	ctx := context.Background()
+	old := dedup.Key{AggregateType: "Counter", AggregateID: "1", CommandID: "old"}
+	s.Claim(ctx, old, time.Now().Add(-time.Hour))
+	s.Claim(ctx, dedup.Key{AggregateType: "Counter", AggregateID: "1", CommandID: "new"}, time.Now())
+	pruned, err := s.Prune(ctx, time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("expected one pruned record got %d", pruned)
+	}
+	claimed, _ := s.Claim(ctx, old, time.Now())
+	if !claimed {
+		t.Fatal("expected the pruned command to be claimable")
+	}
+}
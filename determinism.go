@@ -0,0 +1,43 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrNonDeterministicTransition is returned when Transition has side effects while the aggregate is built from its
+// events, it tracked new events or panicked
+var ErrNonDeterministicTransition = errors.New("transition is not deterministic")
+
+// SetReplayGuard makes Get verify that Transition has no side effects when the aggregate is built from its events.
+// Business logic in Transition, like tracking new events or panicking on state, makes Get fail with
+// ErrNonDeterministicTransition naming the event it happened on. It's meant to be enabled in tests and development.
+func (r *Repository[T]) SetReplayGuard(enabled bool) {
+	r.replayGuard = enabled
+}
+
+// replay builds the aggregate from the event, checking the transition for side effects if the replay guard is enabled
+func (r *Repository[T]) replay(aggregate Aggregate[T], event Event[T]) error {
+	if !r.replayGuard {
+		aggregate.Root().BuildFromHistory(aggregate, []Event[T]{event})
+		return nil
+	}
+	return guardedReplay(aggregate, event)
+}
+
+// guardedReplay builds the aggregate from the event and returns an error if the transition tracked new events or
+// panicked
+func guardedReplay[T any](aggregate Aggregate[T], event Event[T]) (err error) {
+	root := aggregate.Root()
+	tracked := len(root.aggregateEvents)
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %s %s version %d %s panicked: %v", ErrNonDeterministicTransition, event.AggregateType, event.AggregateID, event.Version, event.Reason(), r)
+		}
+	}()
+	root.BuildFromHistory(aggregate, []Event[T]{event})
+	if n := len(root.aggregateEvents) - tracked; n > 0 {
+		return fmt.Errorf("%w: %s %s version %d %s tracked %d new events", ErrNonDeterministicTransition, event.AggregateType, event.AggregateID, event.Version, event.Reason(), n)
+	}
+	return nil
+}
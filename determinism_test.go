@@ -0,0 +1,66 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+// Impure is a person with business logic in Transition, enabled by the impure field
+type Impure struct {
+	eventsourcing.AggregateRoot[PersonEvent]
+	Age    int
+	impure string
+}
+
+func (p *Impure) Transition(event eventsourcing.Event[PersonEvent]) {
+	switch event.Data.(type) {
+	case *AgedOneYear:
+		p.Age++
+		if p.Age == 2 && p.impure == "track" {
+			p.TrackChange(p, &Born{Name: "reborn"})
+		} else if p.Age == 2 && p.impure == "panic" {
+			panic("too old")
+		}
+	}
+}
+
+func TestReplayGuard(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	person := Impure{}
+	person.TrackChange(&person, &Born{Name: "kalle"})
+	person.TrackChange(&person, &AgedOneYear{})
+	person.TrackChange(&person, &AgedOneYear{})
+	err := repo.Save(&person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// without the guard the side effect goes unnoticed
+	twin := Impure{impure: "track"}
+	err = repo.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !twin.UnsavedEvents() {
+		t.Fatal("expected the transition to track an event")
+	}
+
+	repo.SetReplayGuard(true)
+	err = repo.Get(person.ID(), &Impure{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, impure := range []string{"track", "panic"} {
+		err = repo.Get(person.ID(), &Impure{impure: impure})
+		if !errors.Is(err, eventsourcing.ErrNonDeterministicTransition) {
+			t.Fatalf("expected the %s side effect to be detected got %v", impure, err)
+		}
+		if !strings.Contains(err.Error(), "Impure "+person.ID()+" version 3 AgedOneYear") {
+			t.Fatalf("expected the error to name the event got %v", err)
+		}
+	}
+}
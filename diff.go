@@ -0,0 +1,137 @@
+package eventsourcing
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// FieldChange is a field that has different values, nested fields are joined with a dot like "Address.City". From or
+// To is nil when the field is missing on that side.
+type FieldChange struct {
+	Field string      `json:"field"`
+	From  interface{} `json:"from"`
+	To    interface{} `json:"to"`
+}
+
+// Diff returns the fields of the event payloads that differ between a and b, sorted on field name
+func Diff[T any](a, b Event[T]) ([]FieldChange, error) {
+	return DiffValues(a.Data, b.Data)
+}
+
+// DiffValues returns the fields that differ between a and b, sorted on field name. The values are compared on their
+// JSON encoding, unexported fields are left out and numbers are float64 in the changes. A json.RawMessage is compared
+// as the value it holds.
+func DiffValues(a, b interface{}) ([]FieldChange, error) {
+	from, err := jsonFields(a)
+	if err != nil {
+		return nil, err
+	}
+	to, err := jsonFields(b)
+	if err != nil {
+		return nil, err
+	}
+	return diffFields(from, to), nil
+}
+
+// StateDiff builds the aggregate with the id from its events and returns the fields of its state that changed between
+// versionA and versionB, version 0 being the state of a new aggregate. The aggregate is left at versionB, it's built
+// without snapshots or the cache and should not be saved.
+func (r *Repository[T]) StateDiff(ctx context.Context, id string, aggregate Aggregate[T], versionA, versionB Version) ([]FieldChange, error) {
+	if reflect.ValueOf(aggregate).Kind() != reflect.Ptr {
+		return nil, errors.New("aggregate needs to be a pointer")
+	}
+	if versionA > versionB {
+		return nil, fmt.Errorf("version %d is after version %d", versionA, versionB)
+	}
+	aggregateType := reflect.TypeOf(aggregate).Elem().Name()
+	id, err := r.Resolve(ctx, aggregateType, id)
+	if err != nil {
+		return nil, err
+	}
+	events, err := r.streamEvents(ctx, aggregateType, id, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(events) == 0 {
+		return nil, ErrAggregateNotFound
+	}
+	if last := events[len(events)-1].Version; versionB > last {
+		return nil, fmt.Errorf("version %d of %s %s not found, last version is %d", versionB, aggregateType, id, last)
+	}
+	var before map[string]interface{}
+	for _, event := range events {
+		if event.Version > versionA && before == nil {
+			before, err = jsonFields(aggregate)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if event.Version > versionB {
+			break
+		}
+		aggregate.Root().BuildFromHistory(aggregate, []Event[T]{event})
+	}
+	after, err := jsonFields(aggregate)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		// versionA is the last version
+		return nil, nil
+	}
+	return diffFields(before, after), nil
+}
+
+// jsonFields returns the fields of the JSON encoding of v flattened to dot separated paths
+func jsonFields(v interface{}) (map[string]interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var decoded interface{}
+	err = json.Unmarshal(b, &decoded)
+	if err != nil {
+		return nil, err
+	}
+	fields := make(map[string]interface{})
+	flattenFields("", decoded, fields)
+	return fields, nil
+}
+
+func flattenFields(prefix string, v interface{}, fields map[string]interface{}) {
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		if prefix != "" {
+			fields[prefix] = v
+		}
+		return
+	}
+	for key, value := range m {
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		flattenFields(key, value, fields)
+	}
+}
+
+func diffFields(from, to map[string]interface{}) []FieldChange {
+	var changes []FieldChange
+	for field, value := range to {
+		previous, ok := from[field]
+		if ok && reflect.DeepEqual(previous, value) {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: field, From: previous, To: value})
+	}
+	for field, previous := range from {
+		if _, ok := to[field]; !ok {
+			changes = append(changes, FieldChange{Field: field, From: previous})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
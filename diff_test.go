@@ -0,0 +1,101 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Address struct {
+	Street string
+	City   string
+}
+
+type Registered struct {
+	Name    string
+	Address Address
+	Tags    []string
+}
+
+func TestDiff(t *testing.T) {
+	a := eventsourcing.Event[any]{Data: &Registered{Name: "kalle", Address: Address{Street: "Storgatan", City: "Lund"}, Tags: []string{"a"}}}
+	b := eventsourcing.Event[any]{Data: &Registered{Name: "kalle", Address: Address{Street: "Storgatan", City: "Malmö"}, Tags: []string{"a", "b"}}}
+	changes, err := eventsourcing.Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []eventsourcing.FieldChange{
+		{Field: "Address.City", From: "Lund", To: "Malmö"},
+		{Field: "Tags", From: []interface{}{"a"}, To: []interface{}{"a", "b"}},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %+v got %+v", expected, changes)
+	}
+
+	// fields only present on one side
+	changes, err = eventsourcing.DiffValues(&Born{Name: "kalle"}, &AgedOneYear{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 1 || changes[0] != (eventsourcing.FieldChange{Field: "Name", From: "kalle"}) {
+		t.Fatalf("unexpected changes %+v", changes)
+	}
+	changes, err = eventsourcing.Diff(a, a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected no changes got %+v", changes)
+	}
+}
+
+func TestStateDiff(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		title    string
+		from, to eventsourcing.Version
+		expected []eventsourcing.FieldChange
+	}{
+		{"from a new aggregate", 0, 1, []eventsourcing.FieldChange{{Field: "Name", From: "", To: "kalle"}}},
+		{"between versions", 1, 3, []eventsourcing.FieldChange{{Field: "Age", From: float64(0), To: float64(2)}}},
+		{"same version", 2, 2, nil},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			p := &Person{}
+			changes, err := repo.StateDiff(context.Background(), person.ID(), p, test.from, test.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(changes, test.expected) {
+				t.Fatalf("expected %+v got %+v", test.expected, changes)
+			}
+			if p.Version() != test.to {
+				t.Fatalf("expected the aggregate at version %d got %d", test.to, p.Version())
+			}
+		})
+	}
+
+	_, err = repo.StateDiff(context.Background(), person.ID(), &Person{}, 1, 4)
+	if err == nil {
+		t.Fatal("expected an error on a version after the last event")
+	}
+	_, err = repo.StateDiff(context.Background(), "unknown", &Person{}, 0, 1)
+	if !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected ErrAggregateNotFound got %v", err)
+	}
+}
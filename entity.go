@@ -0,0 +1,80 @@
+package eventsourcing
+
+// EntityEvent is implemented by event data that targets a child entity inside an aggregate
+type EntityEvent interface {
+	EntityID() string
+}
+
+// Entity is a child entity inside an aggregate that builds its state from the events routed to it
+type Entity[T any] interface {
+	Transition(event Event[T])
+}
+
+// Entities holds the child entities of an aggregate (order lines in an order) and routes events
+// to the owning child based on the entity ID on the event data.
+type Entities[T any, E Entity[T]] struct {
+	entities map[string]E
+	order    []string
+	create   func(id string) E
+}
+
+// NewEntities constructs an entity collection, create is used to construct the entity the first time
+// an event is routed to it.
+func NewEntities[T any, E Entity[T]](create func(id string) E) *Entities[T, E] {
+	return &Entities[T, E]{
+		entities: make(map[string]E),
+		create:   create,
+	}
+}
+
+// Route transition the event on the entity it belongs to. Returns false if the event data is not
+// an EntityEvent.
+func (e *Entities[T, E]) Route(event Event[T]) bool {
+	ee, ok := any(event.Data).(EntityEvent)
+	if !ok {
+		return false
+	}
+	id := ee.EntityID()
+	entity, ok := e.entities[id]
+	if !ok {
+		entity = e.create(id)
+		e.entities[id] = entity
+		e.order = append(e.order, id)
+	}
+	entity.Transition(event)
+	return true
+}
+
+// Get returns the entity with id
+func (e *Entities[T, E]) Get(id string) (E, bool) {
+	entity, ok := e.entities[id]
+	return entity, ok
+}
+
+// All returns the entities in the order they were created
+func (e *Entities[T, E]) All() []E {
+	entities := make([]E, 0, len(e.order))
+	for _, id := range e.order {
+		entities = append(entities, e.entities[id])
+	}
+	return entities
+}
+
+// Len returns the number of entities
+func (e *Entities[T, E]) Len() int {
+	return len(e.entities)
+}
+
+// Remove removes the entity with id, used when an event ends the life of the entity
+func (e *Entities[T, E]) Remove(id string) {
+	if _, ok := e.entities[id]; !ok {
+		return
+	}
+	delete(e.entities, id)
+	for i, o := range e.order {
+		if o == id {
+			e.order = append(e.order[:i], e.order[i+1:]...)
+			break
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package eventsourcing_test
+
+import (
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type OrderEvent interface{ orderEvent() }
+
+type OrderPlaced struct{}
+
+func (*OrderPlaced) orderEvent() {}
+
+type LineAdded struct {
+	LineID   string
+	Quantity int
+}
+
+func (*LineAdded) orderEvent() {}
+
+func (l *LineAdded) EntityID() string { return l.LineID }
+
+type LineRemoved struct {
+	LineID string
+}
+
+func (*LineRemoved) orderEvent() {}
+
+func (l *LineRemoved) EntityID() string { return l.LineID }
+
+type OrderLine struct {
+	ID       string
+	Quantity int
+}
+
+func (l *OrderLine) Transition(event eventsourcing.Event[OrderEvent]) {
+	switch e := event.Data.(type) {
+	case *LineAdded:
+		l.Quantity += e.Quantity
+	}
+}
+
+type Order struct {
+	eventsourcing.AggregateRoot[OrderEvent]
+	Placed bool
+	Lines  *eventsourcing.Entities[OrderEvent, *OrderLine]
+}
+
+func NewOrder() *Order {
+	o := Order{
+		Lines: eventsourcing.NewEntities[OrderEvent](func(id string) *OrderLine { return &OrderLine{ID: id} }),
+	}
+	o.TrackChange(&o, &OrderPlaced{})
+	return &o
+}
+
+func (o *Order) Transition(event eventsourcing.Event[OrderEvent]) {
+	switch e := event.Data.(type) {
+	case *OrderPlaced:
+		o.Placed = true
+	case *LineRemoved:
+		o.Lines.Remove(e.LineID)
+	default:
+		o.Lines.Route(event)
+	}
+}
+
+func TestEntities(t *testing.T) {
+	o := NewOrder()
+	o.TrackChange(o, &LineAdded{LineID: "a", Quantity: 1})
+	o.TrackChange(o, &LineAdded{LineID: "b", Quantity: 2})
+	o.TrackChange(o, &LineAdded{LineID: "a", Quantity: 3})
+
+	if o.Lines.Len() != 2 {
+		t.Fatalf("expected 2 lines got %d", o.Lines.Len())
+	}
+	a, ok := o.Lines.Get("a")
+	if !ok {
+		t.Fatal("expected line a")
+	}
+	if a.Quantity != 4 {
+		t.Fatalf("expected quantity 4 got %d", a.Quantity)
+	}
+	all := o.Lines.All()
+	if all[0].ID != "a" || all[1].ID != "b" {
+		t.Fatal("lines should be returned in creation order")
+	}
+
+	o.TrackChange(o, &LineRemoved{LineID: "a"})
+	if _, ok := o.Lines.Get("a"); ok {
+		t.Fatal("line a should be removed")
+	}
+	if o.Lines.Len() != 1 {
+		t.Fatalf("expected 1 line got %d", o.Lines.Len())
+	}
+}
+
+func TestEntitiesRouteNoneEntityEvent(t *testing.T) {
+	lines := eventsourcing.NewEntities[OrderEvent](func(id string) *OrderLine { return &OrderLine{ID: id} })
+	if lines.Route(eventsourcing.Event[OrderEvent]{Data: &OrderPlaced{}}) {
+		t.Fatal("should not route events that are not entity events")
+	}
+}
@@ -0,0 +1,289 @@
+// Package envelope encrypts the data of the events with envelope encryption. The data is encrypted with data keys, the
+// data keys are encrypted, wrapped, with a master key and kept in a key store apart from the events. Rotating the
+// master key re-wraps the data keys under the new master key without touching the encrypted events.
+package envelope
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// format is the first byte of the encrypted data
+const format = 1
+
+const dataKeySize = 32
+
+var (
+	// ErrNotEncrypted when the data was not encrypted by a keyring
+	ErrNotEncrypted = errors.New("data is not encrypted")
+	// ErrUnknownDataKey when the data key of the encrypted data is not in the key store
+	ErrUnknownDataKey = errors.New("unknown data key")
+	// ErrUnknownMasterKey when a data key is wrapped with a master key the keyring does not have
+	ErrUnknownMasterKey = errors.New("unknown master key")
+)
+
+// MasterKey wraps and unwraps the data keys, like a key in a key management service
+type MasterKey interface {
+	// ID identifies the master key, it's stored with the data keys it wraps
+	ID() string
+	Wrap(dataKey []byte) ([]byte, error)
+	Unwrap(wrapped []byte) ([]byte, error)
+}
+
+// DataKey is a data key wrapped by a master key
+type DataKey struct {
+	ID          string
+	MasterKeyID string
+	Wrapped     []byte
+}
+
+// KeyStore keeps the wrapped data keys
+type KeyStore interface {
+	// SaveKey saves the data key, replacing the data key with the same id
+	SaveKey(ctx context.Context, key DataKey) error
+	// Key returns the data key with the id, ErrUnknownDataKey if there is none
+	Key(ctx context.Context, id string) (DataKey, error)
+	// Keys returns all data keys
+	Keys(ctx context.Context) ([]DataKey, error)
+}
+
+type aesKey struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewAESKey returns a master key wrapping the data keys with AES-GCM, the key is 16, 24 or 32 bytes
+func NewAESKey(id string, key []byte) (MasterKey, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	return &aesKey{id: id, aead: aead}, nil
+}
+
+func (k *aesKey) ID() string {
+	return k.id
+}
+
+func (k *aesKey) Wrap(dataKey []byte) ([]byte, error) {
+	return seal(k.aead, dataKey, []byte(k.id))
+}
+
+func (k *aesKey) Unwrap(wrapped []byte) ([]byte, error) {
+	return open(k.aead, wrapped, []byte(k.id))
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal encrypts the plaintext with a random nonce put before the ciphertext
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err := io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additional []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errors.New("envelope: ciphertext too short")
+	}
+	return aead.Open(nil, ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():], additional)
+}
+
+// Keyring encrypts with a data key wrapped by the current master key and decrypts with the data keys wrapped by the
+// current or the retired master keys
+type Keyring struct {
+	store   KeyStore
+	current MasterKey
+	masters map[string]MasterKey
+
+	lock      sync.Mutex
+	active    string
+	unwrapped map[string]cipher.AEAD
+}
+
+// New returns a keyring with the data keys in the key store. The retired master keys decrypt the events until their
+// data keys are rotated to the current master key.
+func New(store KeyStore, current MasterKey, retired ...MasterKey) *Keyring {
+	masters := map[string]MasterKey{current.ID(): current}
+	for _, m := range retired {
+		masters[m.ID()] = m
+	}
+	return &Keyring{store: store, current: current, masters: masters, unwrapped: make(map[string]cipher.AEAD)}
+}
+
+// Encrypt encrypts the data with the active data key. The keyring is an eventsourcing.Cipher, set on the serializer
+// of the event store with SetCipher. It creates a data key wrapped by the current master key the first time it encrypts.
+func (k *Keyring) Encrypt(ctx context.Context, data []byte) ([]byte, error) {
+	id, aead, err := k.activeKey(ctx)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, 1+binary.MaxVarintLen64+len(id))
+	header = append(header, format)
+	var n [binary.MaxVarintLen64]byte
+	header = append(header, n[:binary.PutUvarint(n[:], uint64(len(id)))]...)
+	header = append(header, id...)
+	ciphertext, err := seal(aead, data, header)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, ciphertext...), nil
+}
+
+// Decrypt decrypts data encrypted by Encrypt
+func (k *Keyring) Decrypt(ctx context.Context, data []byte) ([]byte, error) {
+	id, header, err := DataKeyID(data)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := k.dataKey(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := open(aead, data[len(header):], header)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: decrypt with data key %s: %w", id, err)
+	}
+	return plaintext, nil
+}
+
+// DataKeyID returns the id of the data key the data is encrypted with and the header holding it
+func DataKeyID(data []byte) (string, []byte, error) {
+	if len(data) == 0 || data[0] != format {
+		return "", nil, ErrNotEncrypted
+	}
+	size, n := binary.Uvarint(data[1:])
+	if n <= 0 || size > uint64(len(data)-1-n) {
+		return "", nil, ErrNotEncrypted
+	}
+	end := 1 + n + int(size)
+	return string(data[1+n : end]), data[:end], nil
+}
+
+// activeKey returns the data key encrypting new data, created on first use
+func (k *Keyring) activeKey(ctx context.Context) (string, cipher.AEAD, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if k.active != "" {
+		return k.active, k.unwrapped[k.active], nil
+	}
+	key := make([]byte, dataKeySize)
+	_, err := io.ReadFull(rand.Reader, key)
+	if err != nil {
+		return "", nil, err
+	}
+	id := make([]byte, 16)
+	_, err = io.ReadFull(rand.Reader, id)
+	if err != nil {
+		return "", nil, err
+	}
+	wrapped, err := k.current.Wrap(key)
+	if err != nil {
+		return "", nil, err
+	}
+	dataKey := DataKey{ID: hex.EncodeToString(id), MasterKeyID: k.current.ID(), Wrapped: wrapped}
+	err = k.store.SaveKey(ctx, dataKey)
+	if err != nil {
+		return "", nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return "", nil, err
+	}
+	k.active = dataKey.ID
+	k.unwrapped[dataKey.ID] = aead
+	return k.active, aead, nil
+}
+
+// dataKey returns the unwrapped data key, cached after the first use
+func (k *Keyring) dataKey(ctx context.Context, id string) (cipher.AEAD, error) {
+	k.lock.Lock()
+	defer k.lock.Unlock()
+	if aead, ok := k.unwrapped[id]; ok {
+		return aead, nil
+	}
+	dataKey, err := k.store.Key(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	key, err := k.unwrap(dataKey)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	k.unwrapped[id] = aead
+	return aead, nil
+}
+
+func (k *Keyring) unwrap(dataKey DataKey) ([]byte, error) {
+	master, ok := k.masters[dataKey.MasterKeyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q wrapping data key %s", ErrUnknownMasterKey, dataKey.MasterKeyID, dataKey.ID)
+	}
+	key, err := master.Unwrap(dataKey.Wrapped)
+	if err != nil {
+		return nil, fmt.Errorf("envelope: unwrap data key %s: %w", dataKey.ID, err)
+	}
+	return key, nil
+}
+
+// Memory keeps the data keys in memory
+type Memory struct {
+	lock sync.Mutex
+	keys map[string]DataKey
+}
+
+// NewMemory returns an empty in memory key store
+func NewMemory() *Memory {
+	return &Memory{keys: make(map[string]DataKey)}
+}
+
+// SaveKey saves the data key, replacing the data key with the same id
+func (m *Memory) SaveKey(ctx context.Context, key DataKey) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.keys[key.ID] = key
+	return nil
+}
+
+// Key returns the data key with the id
+func (m *Memory) Key(ctx context.Context, id string) (DataKey, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key, ok := m.keys[id]
+	if !ok {
+		return DataKey{}, fmt.Errorf("%w: %s", ErrUnknownDataKey, id)
+	}
+	return key, nil
+}
+
+// Keys returns all data keys
+func (m *Memory) Keys(ctx context.Context) ([]DataKey, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	keys := make([]DataKey, 0, len(m.keys))
+	for _, key := range m.keys {
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
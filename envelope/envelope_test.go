@@ -0,0 +1,107 @@
+package envelope_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/envelope"
+)
+
+type Registered struct {
+	Email string
+}
+
+func masterKey(t *testing.T, id string) envelope.MasterKey {
+	t.Helper()
+	key, err := envelope.NewAESKey(id, bytes.Repeat([]byte(id[:1]), 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+type Person struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (p *Person) Transition(event eventsourcing.Event[any]) {}
+
+func TestCipher(t *testing.T) {
+	keys := envelope.NewMemory()
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	ser.SetCipher(envelope.New(keys, masterKey(t, "m1")))
+	ser.Register(&Person{}, ser.Events(&Registered{}))
+	// the schema only accepts json and fails on encrypted data
+	ser.RegisterSchema("Person", "Registered", eventsourcing.SchemaFunc(func(data []byte) error {
+		if !json.Valid(data) {
+			return errors.New("not json")
+		}
+		return nil
+	}))
+	ser.SetValidateOnRead(true)
+
+	event := eventsourcing.Event[any]{AggregateType: "Person", AggregateID: "1", Version: 1, Data: &Registered{Email: "kalle@example.com"}}
+	data, err := ser.MarshalEvent(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("kalle")) {
+		t.Fatalf("expected the data encrypted got %s", data)
+	}
+	other, err := ser.Marshal(&Registered{Email: "anna@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _, _ := envelope.DataKeyID(data)
+	second, _, _ := envelope.DataKeyID(other)
+	if first != second {
+		t.Fatalf("expected the data encrypted with the same data key got %s and %s", first, second)
+	}
+	stored, err := keys.Keys(context.Background())
+	if err != nil || len(stored) != 1 || stored[0].MasterKeyID != "m1" {
+		t.Fatalf("expected one data key wrapped by m1 got %+v %v", stored, err)
+	}
+
+	// a new keyring unwraps the data key from the key store
+	ser.SetCipher(envelope.New(keys, masterKey(t, "m1")))
+	read := eventsourcing.Event[any]{AggregateType: "Person", AggregateID: "1", Version: 1}
+	ok, err := ser.UnmarshalEvent(&read, "Registered", data)
+	if err != nil || !ok || read.Data.(*Registered).Email != "kalle@example.com" {
+		t.Fatalf("expected the data decrypted got %+v %v", read.Data, err)
+	}
+	r := Registered{}
+	err = ser.Unmarshal(other, &r)
+	if err != nil || r.Email != "anna@example.com" {
+		t.Fatalf("expected the data decrypted got %+v %v", r, err)
+	}
+
+	tampered := append([]byte{}, data...)
+	tampered[len(tampered)-1] ^= 1
+	if err := ser.Unmarshal(tampered, &r); err == nil {
+		t.Fatal("expected tampered data to fail")
+	}
+	if err := ser.Unmarshal([]byte(`{"Email":"x"}`), &r); !errors.Is(err, envelope.ErrNotEncrypted) {
+		t.Fatalf("expected ErrNotEncrypted got %v", err)
+	}
+}
+
+func TestUnknownKeys(t *testing.T) {
+	keys := envelope.NewMemory()
+	data, err := envelope.New(keys, masterKey(t, "m1")).Encrypt(context.Background(), []byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = envelope.New(keys, masterKey(t, "m2")).Decrypt(context.Background(), data)
+	if !errors.Is(err, envelope.ErrUnknownMasterKey) {
+		t.Fatalf("expected ErrUnknownMasterKey got %v", err)
+	}
+	_, err = envelope.New(envelope.NewMemory(), masterKey(t, "m1")).Decrypt(context.Background(), data)
+	if !errors.Is(err, envelope.ErrUnknownDataKey) {
+		t.Fatalf("expected ErrUnknownDataKey got %v", err)
+	}
+}
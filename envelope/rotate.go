@@ -0,0 +1,133 @@
+package envelope
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const defaultBatchSize = 1000
+
+// RotateResult is the number of data keys re-wrapped by Rotate and already wrapped by the current master key
+type RotateResult struct {
+	Rewrapped int
+	Current   int
+}
+
+// Rotate re-wraps the data keys wrapped by retired master keys under the current master key. The events are not
+// re-encrypted, their data keys stay the same. A data key wrapped by a master key the keyring does not have fails
+// with ErrUnknownMasterKey after the other data keys are rotated. Once Report shows no events under a retired master
+// key it can be removed from the keyring and destroyed.
+func (k *Keyring) Rotate(ctx context.Context) (RotateResult, error) {
+	keys, err := k.store.Keys(ctx)
+	if err != nil {
+		return RotateResult{}, err
+	}
+	result := RotateResult{}
+	var unknown error
+	for _, dataKey := range keys {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		if dataKey.MasterKeyID == k.current.ID() {
+			result.Current++
+			continue
+		}
+		key, err := k.unwrap(dataKey)
+		if errors.Is(err, ErrUnknownMasterKey) {
+			unknown = err
+			continue
+		} else if err != nil {
+			return result, err
+		}
+		wrapped, err := k.current.Wrap(key)
+		if err != nil {
+			return result, err
+		}
+		err = k.store.SaveKey(ctx, DataKey{ID: dataKey.ID, MasterKeyID: k.current.ID(), Wrapped: wrapped})
+		if err != nil {
+			return result, err
+		}
+		result.Rewrapped++
+	}
+	return result, unknown
+}
+
+// Usage is the number of events encrypted under a master key
+type Usage struct {
+	MasterKeyID string
+	Events      int
+	// Retired is true if the master key is not the current master key of the keyring
+	Retired bool
+}
+
+// Report is the number of events per master key wrapping their data keys
+type Report struct {
+	Events int
+	// NotEncrypted is the number of events with data not encrypted by a keyring
+	NotEncrypted int
+	// MasterKeys is the usage of the master keys sorted on id
+	MasterKeys []Usage
+}
+
+// Retired returns the number of events still under retired master keys
+func (r Report) Retired() int {
+	n := 0
+	for _, u := range r.MasterKeys {
+		if u.Retired {
+			n += u.Events
+		}
+	}
+	return n
+}
+
+// Report reads the events of the event store in global order and counts the events per master key wrapping the
+// data key they are encrypted with. Only the headers of the event data are read, nothing is decrypted. Events
+// encrypted with data keys missing from the key store fail with ErrUnknownDataKey.
+func (k *Keyring) Report(ctx context.Context, source eventsourcing.RawEventStore) (Report, error) {
+	keys, err := k.store.Keys(ctx)
+	if err != nil {
+		return Report{}, err
+	}
+	masters := make(map[string]string, len(keys))
+	for _, key := range keys {
+		masters[key.ID] = key.MasterKeyID
+	}
+	counts := make(map[string]int)
+	report := Report{}
+	start := uint64(1)
+	for {
+		if ctx.Err() != nil {
+			return report, ctx.Err()
+		}
+		raws, err := source.GlobalEventsRaw(ctx, start, defaultBatchSize)
+		if err != nil {
+			return report, err
+		}
+		if len(raws) == 0 {
+			break
+		}
+		for _, raw := range raws {
+			report.Events++
+			id, _, err := DataKeyID(raw.Data)
+			if errors.Is(err, ErrNotEncrypted) {
+				report.NotEncrypted++
+				continue
+			}
+			master, ok := masters[id]
+			if !ok {
+				return report, fmt.Errorf("%w: %s of %s %s version %d", ErrUnknownDataKey, id, raw.AggregateType, raw.AggregateID, raw.Version)
+			}
+			counts[master]++
+		}
+		start = uint64(raws[len(raws)-1].GlobalVersion) + 1
+	}
+	for master, n := range counts {
+		report.MasterKeys = append(report.MasterKeys, Usage{MasterKeyID: master, Events: n, Retired: master != k.current.ID()})
+	}
+	sort.Slice(report.MasterKeys, func(i, j int) bool { return report.MasterKeys[i].MasterKeyID < report.MasterKeys[j].MasterKeyID })
+	return report, nil
+}
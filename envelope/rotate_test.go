@@ -0,0 +1,113 @@
+package envelope_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/envelope"
+)
+
+// rawStore holds the data of the events as stored
+type rawStore struct {
+	events []eventsourcing.RawEvent
+}
+
+func (s *rawStore) GetRaw(ctx context.Context, id, aggregateType string, afterVersion eventsourcing.Version) ([]eventsourcing.RawEvent, error) {
+	return nil, errors.New("not used")
+}
+
+func (s *rawStore) GlobalEventsRaw(ctx context.Context, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	var events []eventsourcing.RawEvent
+	for _, event := range s.events {
+		if uint64(event.GlobalVersion) >= start && uint64(len(events)) < count {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *rawStore) add(data []byte) {
+	s.events = append(s.events, eventsourcing.RawEvent{AggregateID: "1", AggregateType: "Person", Version: eventsourcing.Version(len(s.events) + 1), GlobalVersion: eventsourcing.Version(len(s.events) + 1), Data: data})
+}
+
+func TestRotate(t *testing.T) {
+	ctx := context.Background()
+	keys := envelope.NewMemory()
+	m1, m2, m3 := masterKey(t, "m1"), masterKey(t, "m2"), masterKey(t, "m3")
+	store := &rawStore{}
+	encrypt := func(keyring *envelope.Keyring, plaintext string) {
+		data, err := keyring.Encrypt(ctx, []byte(plaintext))
+		if err != nil {
+			t.Fatal(err)
+		}
+		store.add(data)
+	}
+	encrypt(envelope.New(keys, m1), "a")
+	encrypt(envelope.New(keys, m1), "b")
+	encrypt(envelope.New(keys, m2, m1), "c")
+	store.add([]byte(`{}`))
+
+	keyring := envelope.New(keys, m3, m1, m2)
+	report, err := keyring.Report(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := envelope.Report{Events: 4, NotEncrypted: 1, MasterKeys: []envelope.Usage{
+		{MasterKeyID: "m1", Events: 2, Retired: true},
+		{MasterKeyID: "m2", Events: 1, Retired: true},
+	}}
+	if !reflect.DeepEqual(report, expected) || report.Retired() != 3 {
+		t.Fatalf("expected %+v got %+v", expected, report)
+	}
+
+	result, err := keyring.Rotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (envelope.RotateResult{Rewrapped: 3}) {
+		t.Fatalf("expected three data keys re-wrapped got %+v", result)
+	}
+	report, err = keyring.Report(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Retired() != 0 || len(report.MasterKeys) != 1 || report.MasterKeys[0].Events != 3 {
+		t.Fatalf("expected all events under m3 got %+v", report)
+	}
+
+	// the retired master keys are no longer needed to decrypt the events
+	current := envelope.New(keys, m3)
+	for i, plaintext := range []string{"a", "b", "c"} {
+		data, err := current.Decrypt(ctx, store.events[i].Data)
+		if err != nil || string(data) != plaintext {
+			t.Fatalf("expected %s got %s %v", plaintext, data, err)
+		}
+	}
+	result, err = current.Rotate(ctx)
+	if err != nil || result != (envelope.RotateResult{Current: 3}) {
+		t.Fatalf("expected nothing to rotate got %+v %v", result, err)
+	}
+}
+
+func TestRotateUnknownMasterKey(t *testing.T) {
+	ctx := context.Background()
+	keys := envelope.NewMemory()
+	_, err := envelope.New(keys, masterKey(t, "m1")).Encrypt(ctx, []byte("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = envelope.New(keys, masterKey(t, "m2")).Encrypt(ctx, []byte("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, err := envelope.New(keys, masterKey(t, "m3"), masterKey(t, "m2")).Rotate(ctx)
+	if !errors.Is(err, envelope.ErrUnknownMasterKey) {
+		t.Fatalf("expected ErrUnknownMasterKey got %v", err)
+	}
+	if result.Rewrapped != 1 {
+		t.Fatalf("expected the data key of m2 re-wrapped got %+v", result)
+	}
+}
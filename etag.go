@@ -0,0 +1,45 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidETag when an ETag was not made from a version
+var ErrInvalidETag = errors.New("invalid etag")
+
+// ErrPreconditionFailed when the saved version of the aggregate differs from the expected version
+var ErrPreconditionFailed = errors.New("precondition failed")
+
+// ETag returns the version, the aggregate Version or GlobalVersion, as an HTTP entity tag including its quotes
+func ETag(version Version) string {
+	return `"` + strconv.FormatUint(uint64(version), 36) + `"`
+}
+
+// ParseETag returns the version of an ETag made by ETag, the tag can be weak as sent in If-None-Match
+func ParseETag(tag string) (Version, error) {
+	s := strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+	if len(s) < 3 || s[0] != '"' || s[len(s)-1] != '"' {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidETag, tag)
+	}
+	v, err := strconv.ParseUint(s[1:len(s)-1], 36, 64)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidETag, tag)
+	}
+	return Version(v), nil
+}
+
+// SaveExpected saves the events of the aggregate if its saved version, the version before the unsaved events, is the
+// expected version. It returns ErrPreconditionFailed otherwise, as an HTTP handler would on an If-Match header not
+// matching the version the client read. Events saved by others after the aggregate was fetched fail in the event
+// store as in Save.
+func (r *Repository[T]) SaveExpected(aggregate Aggregate[T], expected Version) error {
+	root := aggregate.Root()
+	if root.aggregateVersion != expected {
+		return fmt.Errorf("%w: %s %s expected version %d got %d", ErrPreconditionFailed, reflect.TypeOf(aggregate).Elem().Name(), root.ID(), expected, root.aggregateVersion)
+	}
+	return r.Save(aggregate)
+}
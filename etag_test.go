@@ -0,0 +1,65 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestETag(t *testing.T) {
+	for _, version := range []eventsourcing.Version{0, 1, 42, eventsourcing.MaxVersion} {
+		v, err := eventsourcing.ParseETag(eventsourcing.ETag(version))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v != version {
+			t.Fatalf("expected version %d got %d", version, v)
+		}
+	}
+	v, err := eventsourcing.ParseETag("W/" + eventsourcing.ETag(7))
+	if err != nil || v != 7 {
+		t.Fatalf("expected a weak etag to parse to version 7 got %d, %v", v, err)
+	}
+	for _, tag := range []string{"", `""`, "1", `"-1"`, `"x!"`} {
+		_, err = eventsourcing.ParseETag(tag)
+		if !errors.Is(err, eventsourcing.ErrInvalidETag) {
+			t.Fatalf("expected %q to be invalid got %v", tag, err)
+		}
+	}
+}
+
+func TestSaveExpected(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.SaveExpected(person, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tag := eventsourcing.ETag(person.Version())
+
+	// the client sends the etag back in If-Match
+	expected, err := eventsourcing.ParseETag(tag)
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.SaveExpected(person, expected)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a client with the old etag fails
+	person.GrowOlder()
+	err = repo.SaveExpected(person, expected)
+	if !errors.Is(err, eventsourcing.ErrPreconditionFailed) {
+		t.Fatalf("expected precondition failed got %v", err)
+	}
+	if !person.UnsavedEvents() {
+		t.Fatal("expected the events to be unsaved")
+	}
+}
@@ -15,21 +15,46 @@ var ErrNoMoreEvents = errors.New("no more events")
 
 // Event holding meta data and the application specific event in the Data property
 type Event[T any] struct {
+	EventID       string // unique id of the event, set when the event is tracked
 	AggregateID   string
 	Version       Version
 	GlobalVersion Version
 	AggregateType string
 	Timestamp     time.Time
+	ValidTime     time.Time // when the event took effect in the domain, zero if it took effect when recorded
 	Data          T
 	Metadata      map[string]interface{}
 }
 
-// Reason returns the name of the data struct
+// ValidAt returns the time the event took effect, the ValidTime if set otherwise the Timestamp
+func (e Event[T]) ValidAt() time.Time {
+	if e.ValidTime.IsZero() {
+		return e.Timestamp
+	}
+	return e.ValidTime
+}
+
+// Reasoner can be implemented by event data to override the reason derived from the struct name.
+// This makes it possible to rename the struct without breaking the deserialization of stored events. The method has
+// an explicit name to not pick up a Reason method the event data has for other purposes.
+type Reasoner interface {
+	StoredReason() string
+}
+
+// Reason returns the name of the data struct or the value from its StoredReason method
 func (e Event[T]) Reason() string {
-	if any(e.Data) == any(nil) {
+	return reason(e.Data)
+}
+
+// reason returns the event name of the event data
+func reason(data any) string {
+	if data == nil {
 		return ""
 	}
-	return reflect.TypeOf(e.Data).Elem().Name()
+	if r, ok := data.(Reasoner); ok {
+		return r.StoredReason()
+	}
+	return reflect.TypeOf(data).Elem().Name()
 }
 
 // DataAs convert the event.Data to the supplied type.
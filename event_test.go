@@ -37,3 +37,36 @@ func TestDataAs(t *testing.T) {
 		t.Fatal("Age should be int´s zero value")
 	}
 }
+
+type Renamed struct{}
+
+func (*Renamed) personEvent() {}
+
+func (*Renamed) StoredReason() string { return "Born" }
+
+func TestEventReasonOverride(t *testing.T) {
+	e := eventsourcing.Event[PersonEvent]{
+		Data: &Renamed{},
+	}
+	if e.Reason() != "Born" {
+		t.Fatalf("expected Born got %s", e.Reason())
+	}
+}
+
+// Cancelled has a Reason field and method that are part of the domain, not the stored reason
+type Cancelled struct {
+	Why string
+}
+
+func (*Cancelled) personEvent() {}
+
+func (c *Cancelled) Reason() string { return c.Why }
+
+func TestEventReasonMethodNotUsed(t *testing.T) {
+	e := eventsourcing.Event[PersonEvent]{
+		Data: &Cancelled{Why: "moved"},
+	}
+	if e.Reason() != "Cancelled" {
+		t.Fatalf("expected Cancelled got %s", e.Reason())
+	}
+}
@@ -0,0 +1,231 @@
+// Package batcher coalesces the saves of many goroutines into bulk saves, for ingestion workloads where one
+// transaction per save caps the throughput. Each save waits for the bulk save holding its events and gets its own
+// error back.
+package batcher
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrClosed when saving to a closed batcher
+var ErrClosed = errors.New("batcher is closed")
+
+// Store is an event store that can save the events of several aggregates in one operation
+type Store[T any] interface {
+	eventsourcing.EventStore[T]
+	eventsourcing.BulkSaver[T]
+}
+
+// Option configures the batcher
+type Option func(*options)
+
+type options struct {
+	maxEvents int
+	maxDelay  time.Duration
+}
+
+// WithMaxEvents sets the number of events that makes a batch be saved without waiting for more saves, default 500
+func WithMaxEvents(events int) Option {
+	return func(o *options) {
+		if events > 0 {
+			o.maxEvents = events
+		}
+	}
+}
+
+// WithMaxDelay sets how long a batch waits for more saves after the first one, default 5ms. It's the added latency
+// of a save when there are few producers.
+func WithMaxDelay(delay time.Duration) Option {
+	return func(o *options) {
+		if delay > 0 {
+			o.maxDelay = delay
+		}
+	}
+}
+
+// Future is the result of a save
+type Future struct {
+	done chan struct{}
+	err  error
+}
+
+// Done is closed when the save is done
+func (f *Future) Done() <-chan struct{} {
+	return f.done
+}
+
+// Wait waits for the save and returns its error
+func (f *Future) Wait() error {
+	<-f.done
+	return f.err
+}
+
+func (f *Future) resolve(err error) {
+	f.err = err
+	close(f.done)
+}
+
+type call[T any] struct {
+	events []eventsourcing.Event[T]
+	future *Future
+}
+
+// Batcher decorates an event store saving the events of concurrent saves in bulk
+type Batcher[T any] struct {
+	store   Store[T]
+	options options
+	calls   chan call[T]
+	lock    sync.RWMutex
+	closed  bool
+	done    chan struct{}
+}
+
+// New constructs a batcher saving to the store and starts its write loop, Close stops it
+func New[T any](store Store[T], opts ...Option) *Batcher[T] {
+	o := options{
+		maxEvents: 500,
+		maxDelay:  5 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	b := &Batcher[T]{
+		store:   store,
+		options: o,
+		calls:   make(chan call[T]),
+		done:    make(chan struct{}),
+	}
+	go b.loop()
+	return b
+}
+
+// Save saves the events with the next batch and waits for it to be written
+func (b *Batcher[T]) Save(events []eventsourcing.Event[T]) error {
+	return b.SaveAsync(events).Wait()
+}
+
+// SaveAsync adds the events to the next batch without waiting for it to be written. The saves of one aggregate are
+// written in the order they are made, a save following a failed save of the same aggregate gets a concurrency error.
+// The GlobalVersion is set on the events when the future is done.
+func (b *Batcher[T]) SaveAsync(events []eventsourcing.Event[T]) *Future {
+	f := &Future{done: make(chan struct{})}
+	if len(events) == 0 {
+		f.resolve(nil)
+		return f
+	}
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	if b.closed {
+		f.resolve(ErrClosed)
+		return f
+	}
+	b.calls <- call[T]{events: events, future: f}
+	return f
+}
+
+// Get gets the events of the aggregate from the event store
+func (b *Batcher[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	return b.store.Get(ctx, id, aggregateType, afterVersion)
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (b *Batcher[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(b.store)
+}
+
+// Close saves the pending batch and stops the write loop. Saves after close fail with ErrClosed.
+func (b *Batcher[T]) Close() {
+	b.lock.Lock()
+	if !b.closed {
+		b.closed = true
+		close(b.calls)
+	}
+	b.lock.Unlock()
+	<-b.done
+}
+
+func (b *Batcher[T]) loop() {
+	defer close(b.done)
+	for {
+		first, ok := <-b.calls
+		if !ok {
+			return
+		}
+		pending := []call[T]{first}
+		events := len(first.events)
+		timer := time.NewTimer(b.options.maxDelay)
+	collect:
+		for events < b.options.maxEvents {
+			select {
+			case c, ok := <-b.calls:
+				if !ok {
+					break collect
+				}
+				pending = append(pending, c)
+				events += len(c.events)
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		b.flush(pending)
+	}
+}
+
+// flush saves the calls in one bulk save, the saves of an aggregate are merged into one slice in call order. If the
+// bulk save fails the calls are saved one by one to give each call its own error.
+func (b *Batcher[T]) flush(pending []call[T]) {
+	type aggregate struct {
+		events []eventsourcing.Event[T]
+		calls  []call[T]
+	}
+	index := make(map[[2]string]int, len(pending))
+	var aggregates []*aggregate
+	for _, c := range pending {
+		key := [2]string{c.events[0].AggregateType, c.events[0].AggregateID}
+		i, ok := index[key]
+		if !ok {
+			index[key] = len(aggregates)
+			aggregates = append(aggregates, &aggregate{events: c.events, calls: []call[T]{c}})
+			continue
+		}
+		a := aggregates[i]
+		if len(a.calls) == 1 {
+			// copy to not append into the slice of the first call
+			a.events = append([]eventsourcing.Event[T]{}, a.events...)
+		}
+		a.events = append(a.events, c.events...)
+		a.calls = append(a.calls, c)
+	}
+	bulk := make([][]eventsourcing.Event[T], 0, len(aggregates))
+	for _, a := range aggregates {
+		bulk = append(bulk, a.events)
+	}
+	err := b.store.SaveAll(bulk)
+	if err != nil {
+		for _, c := range pending {
+			c.future.resolve(b.store.Save(c.events))
+		}
+		return
+	}
+	for _, a := range aggregates {
+		if len(a.calls) > 1 {
+			// set the global version on the events of the calls from the merged slice
+			i := 0
+			for _, c := range a.calls {
+				for j := range c.events {
+					c.events[j].GlobalVersion = a.events[i].GlobalVersion
+					i++
+				}
+			}
+		}
+		for _, c := range a.calls {
+			c.future.resolve(nil)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package batcher_test
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/batcher"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Deposited struct{}
+
+func event(id string, version eventsourcing.Version) eventsourcing.Event[any] {
+	return eventsourcing.Event[any]{AggregateID: id, AggregateType: "Account", Version: version, Timestamp: time.Now(), Data: &Deposited{}}
+}
+
+// countingStore counts the bulk saves
+type countingStore struct {
+	*memory.Memory[any]
+	lock  sync.Mutex
+	bulks int
+}
+
+func (s *countingStore) SaveAll(events [][]eventsourcing.Event[any]) error {
+	s.lock.Lock()
+	s.bulks++
+	s.lock.Unlock()
+	return s.Memory.SaveAll(events)
+}
+
+func TestCoalesce(t *testing.T) {
+	store := &countingStore{Memory: memory.Create[any]()}
+	b := batcher.New[any](store, batcher.WithMaxDelay(20*time.Millisecond))
+	defer b.Close()
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			err := b.Save([]eventsourcing.Event[any]{event(id, 1), event(id, 2)})
+			if err != nil {
+				t.Error(err)
+			}
+		}(fmt.Sprint(i))
+	}
+	wg.Wait()
+	if store.bulks >= 50 {
+		t.Fatalf("expected the saves to be coalesced got %d bulk saves", store.bulks)
+	}
+	if store.GlobalVersion() != 100 {
+		t.Fatalf("expected 100 events got %d", store.GlobalVersion())
+	}
+}
+
+func TestOrderAndErrors(t *testing.T) {
+	store := memory.Create[any]()
+	b := batcher.New[any](store, batcher.WithMaxDelay(20*time.Millisecond))
+	defer b.Close()
+
+	first := []eventsourcing.Event[any]{event("1", 1)}
+	second := []eventsourcing.Event[any]{event("1", 2), event("1", 3)}
+	f1 := b.SaveAsync(first)
+	f2 := b.SaveAsync(second)
+	if err := f1.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	if err := f2.Wait(); err != nil {
+		t.Fatal(err)
+	}
+	// the saves of the aggregate are merged and the global version is set on the events of each call
+	if first[0].GlobalVersion != 1 || second[1].GlobalVersion != 3 {
+		t.Fatalf("expected global versions 1 and 3 got %d and %d", first[0].GlobalVersion, second[1].GlobalVersion)
+	}
+
+	// a failing save does not fail the other saves of the batch
+	conflict := b.SaveAsync([]eventsourcing.Event[any]{event("1", 2)})
+	ok := b.SaveAsync([]eventsourcing.Event[any]{event("2", 1)})
+	if err := conflict.Wait(); !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected ErrConcurrency got %v", err)
+	}
+	if err := ok.Wait(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRepository(t *testing.T) {
+	b := batcher.New[any](memory.Create[any]())
+	repo := eventsourcing.NewRepository[any](b, nil)
+	account := &Account{}
+	account.TrackChange(account, &Deposited{})
+	err := repo.Save(account)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if account.GlobalVersion() != 1 {
+		t.Fatalf("expected global version 1 got %d", account.GlobalVersion())
+	}
+	b.Close()
+	account.TrackChange(account, &Deposited{})
+	err = repo.Save(account)
+	if !errors.Is(err, batcher.ErrClosed) {
+		t.Fatalf("expected ErrClosed got %v", err)
+	}
+}
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {}
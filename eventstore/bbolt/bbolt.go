@@ -1,10 +1,14 @@
 package bbolt
 
 import (
+	"bytes"
 	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"math"
+	"os"
+	"sort"
 	"time"
 
 	"github.com/hallgren/eventsourcing"
@@ -14,8 +18,37 @@ import (
 
 const (
 	globalEventOrderBucketName = "global_event_order"
+	globalEventTimeBucketName  = "global_event_time"
+	correlationBucketName      = "correlation"
+	aggregatesBucketName       = "aggregates"
+	layoutBucketName           = "layout"
 )
 
+// Layout is how the events of the aggregates are stored in buckets
+type Layout int
+
+const (
+	// LayoutAggregate stores the events of each aggregate in a bucket of its own named type_id
+	LayoutAggregate Layout = iota + 1
+	// LayoutType stores the events of each aggregate in a bucket nested in a bucket per aggregate type. Listing the
+	// aggregates of a type reads only the keys of the type bucket.
+	LayoutType
+)
+
+// String returns the name of the layout
+func (l Layout) String() string {
+	switch l {
+	case LayoutAggregate:
+		return "aggregate"
+	case LayoutType:
+		return "type"
+	}
+	return fmt.Sprintf("Layout(%d)", int(l))
+}
+
+// ErrUnknownLayout is returned when the layout in the database is not known by this version
+var ErrUnknownLayout = errors.New("unknown bucket layout")
+
 // itob returns an 8-byte big endian representation of v.
 func itob(v uint64) []byte {
 	b := make([]byte, 8)
@@ -23,26 +56,84 @@ func itob(v uint64) []byte {
 	return b
 }
 
+// timeKey returns the key of an event in the time index, the timestamp followed by the global sequence. The sign bit of
+// the timestamp is flipped to sort timestamps before 1970 first.
+func timeKey(t time.Time, globalSequence uint64) []byte {
+	b := make([]byte, 16)
+	binary.BigEndian.PutUint64(b, uint64(t.UnixNano())^(1<<63))
+	binary.BigEndian.PutUint64(b[8:], globalSequence)
+	return b
+}
+
+// correlationKey returns the key of an event in the correlation index, the correlation id followed by a zero byte and
+// the global sequence making the events of a correlation id a prefix ordered on the global sequence.
+func correlationKey(correlationID string, globalSequence uint64) []byte {
+	return append(correlationPrefix(correlationID), itob(globalSequence)...)
+}
+
+func correlationPrefix(correlationID string) []byte {
+	return append([]byte(correlationID), 0)
+}
+
+// correlationID returns the correlation id in the metadata, empty if not set
+func correlationID(metadata map[string]interface{}) string {
+	id, _ := metadata[eventsourcing.MetadataCorrelationID].(string)
+	return id
+}
+
 // BBolt is the eventstore handler
 type BBolt[T any] struct {
-	db         *bbolt.DB                   // The bbolt db where we store everything
-	serializer eventsourcing.Serializer[T] // The serializer
+	db           *bbolt.DB                   // The bbolt db where we store everything
+	serializer   eventsourcing.Serializer[T] // The serializer
+	bucketPrefix string                      // Prefix on all buckets
+	layout       Layout                      // The bucket layout of the aggregate events
+	bus          *eventstore.AggregateBus[T] // Delivers the saved events to the aggregate subscribers
+}
+
+// Option configures the bbolt event store
+type Option func(*options)
+
+type options struct {
+	bucketPrefix string
+	layout       Layout
+}
+
+// WithBucketPrefix sets a prefix on the global and aggregate buckets.
+// Makes it possible for multiple bounded contexts to share one database file.
+func WithBucketPrefix(prefix string) Option {
+	return func(o *options) {
+		o.bucketPrefix = prefix
+	}
+}
+
+// WithLayout sets the bucket layout of a new database, defaults to LayoutAggregate. The layout is stored in the
+// database and a database with events saved keeps its layout whatever layout is set.
+func WithLayout(layout Layout) Option {
+	return func(o *options) {
+		o.layout = layout
+	}
 }
 
 type boltEvent struct {
+	EventID       string
 	AggregateID   string
 	Version       uint64
 	GlobalVersion uint64
 	Reason        string
 	AggregateType string
 	Timestamp     time.Time
+	ValidTime     time.Time
 	Data          []byte
 	Metadata      map[string]interface{}
 }
 
 // MustOpenBBolt opens the event stream found in the given file. If the file is not found it will be created and
 // initialized. Will panic if it has problems persisting the changes to the filesystem.
-func MustOpenBBolt[T any](dbFile string, s eventsourcing.Serializer[T]) *BBolt[T] {
+func MustOpenBBolt[T any](dbFile string, s eventsourcing.Serializer[T], opts ...Option) *BBolt[T] {
+	o := options{layout: LayoutAggregate}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	db, err := bbolt.Open(dbFile, 0600, &bbolt.Options{
 		Timeout: 1 * time.Second,
 	})
@@ -52,17 +143,57 @@ func MustOpenBBolt[T any](dbFile string, s eventsourcing.Serializer[T]) *BBolt[T
 
 	// Ensure that we have a bucket to store the global event ordering
 	err = db.Update(func(tx *bbolt.Tx) error {
-		if _, err := tx.CreateBucketIfNotExists([]byte(globalEventOrderBucketName)); err != nil {
+		global, err := tx.CreateBucketIfNotExists([]byte(o.bucketPrefix + globalEventOrderBucketName))
+		if err != nil {
 			return errors.New("could not create global event order bucket")
 		}
-		return nil
+		o.layout, err = detectLayout(tx, o.bucketPrefix, global, o.layout)
+		if err != nil {
+			return err
+		}
+		timeIndex, newTimeIndex, err := createIndex(tx, o.bucketPrefix+globalEventTimeBucketName)
+		if err != nil {
+			return err
+		}
+		correlationIndex, newCorrelationIndex, err := createIndex(tx, o.bucketPrefix+correlationBucketName)
+		if err != nil {
+			return err
+		}
+		if !newTimeIndex && !newCorrelationIndex {
+			return nil
+		}
+		// index the events saved before the index was added
+		return global.ForEach(func(k, v []byte) error {
+			bEvent := struct {
+				Timestamp time.Time
+				Metadata  map[string]interface{}
+			}{}
+			err := s.Unmarshal(v, &bEvent)
+			if err != nil {
+				return err
+			}
+			globalSequence := binary.BigEndian.Uint64(k)
+			if newTimeIndex {
+				err = timeIndex.Put(timeKey(bEvent.Timestamp, globalSequence), []byte{})
+				if err != nil {
+					return err
+				}
+			}
+			if id := correlationID(bEvent.Metadata); newCorrelationIndex && id != "" {
+				return correlationIndex.Put(correlationKey(id, globalSequence), []byte{})
+			}
+			return nil
+		})
 	})
 	if err != nil {
 		panic(err)
 	}
 	return &BBolt[T]{
-		db:         db,
-		serializer: s,
+		db:           db,
+		serializer:   s,
+		bucketPrefix: o.bucketPrefix,
+		layout:       o.layout,
+		bus:          eventstore.NewAggregateBus[T](),
 	}
 }
 
@@ -73,25 +204,146 @@ func (e *BBolt[T]) Save(events []eventsourcing.Event[T]) error {
 		return nil
 	}
 
-	// get bucket name from first event
-	aggregateType := events[0].AggregateType
-	aggregateID := events[0].AggregateID
-	bucketName := aggregateKey(aggregateType, aggregateID)
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	err = e.save(tx, events)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	e.bus.Publish(events)
+	return nil
+}
 
+// SaveAll saves the events of several aggregates in one transaction
+func (e *BBolt[T]) SaveAll(events [][]eventsourcing.Event[T]) error {
+	err := eventstore.ValidateBulk(events)
+	if err != nil {
+		return err
+	}
 	tx, err := e.db.Begin(true)
 	if err != nil {
 		return err
 	}
 	defer tx.Rollback()
 
-	evBucket := tx.Bucket([]byte(bucketName))
-	if evBucket == nil {
-		// Ensure that we have a bucket named events_aggregateType_aggregateID for the given aggregate
-		err = e.createBucket([]byte(bucketName), tx)
+	for _, aggregateEvents := range events {
+		if len(aggregateEvents) == 0 {
+			continue
+		}
+		err = e.save(tx, aggregateEvents)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	for _, aggregateEvents := range events {
+		e.bus.Publish(aggregateEvents)
+	}
+	return nil
+}
+
+// save the events of an aggregate in the transaction
+func (e *BBolt[T]) save(tx *bbolt.Tx, events []eventsourcing.Event[T]) error {
+	aggregateID := events[0].AggregateID
+	evBucket, currentVersion, err := e.aggregate(tx, events[0].AggregateType, aggregateID)
+	if err != nil {
+		return err
+	}
+
+	//Validate events
+	err = eventstore.ValidateEvents(aggregateID, currentVersion, events)
+	if err != nil {
+		return err
+	}
+
+	raws := make([]eventsourcing.RawEvent, len(events))
+	for i, event := range events {
+		// marshal the event.Data separately to be able to handle the type info
+		eventData, err := e.serializer.MarshalEvent(event)
 		if err != nil {
-			return errors.New("could not create aggregate events bucket")
+			return fmt.Errorf("could not serialize event data, %w", err)
+		}
+		raws[i] = eventsourcing.RawEvent{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       event.Version,
+			Reason:        event.Reason(),
+			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
+			Data:          eventData,
+			Metadata:      event.Metadata,
 		}
-		evBucket = tx.Bucket([]byte(bucketName))
+	}
+	err = e.put(tx, evBucket, raws)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		// override the event in the slice exposing the GlobalVersion to the caller
+		events[i].GlobalVersion = raws[i].GlobalVersion
+	}
+	return nil
+}
+
+// SaveRaw saves the events of one aggregate with the data already marshaled. The events are published to the
+// subscribers if their types are registered in the serializer.
+func (e *BBolt[T]) SaveRaw(events []eventsourcing.RawEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := e.db.Begin(true)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	aggregateID := events[0].AggregateID
+	evBucket, currentVersion, err := e.aggregate(tx, events[0].AggregateType, aggregateID)
+	if err != nil {
+		return err
+	}
+	err = eventstore.ValidateRawEvents(aggregateID, currentVersion, events)
+	if err != nil {
+		return err
+	}
+	err = e.put(tx, evBucket, events)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	var decoded []eventsourcing.Event[T]
+	for _, raw := range events {
+		event, ok, err := e.serializer.DecodeRaw(raw)
+		if ok && err == nil {
+			decoded = append(decoded, event)
+		}
+	}
+	if len(decoded) > 0 {
+		e.bus.Publish(decoded)
+	}
+	return nil
+}
+
+// aggregate returns the bucket of the aggregate, created if missing, and the version of its last event
+func (e *BBolt[T]) aggregate(tx *bbolt.Tx, aggregateType, aggregateID string) (*bbolt.Bucket, eventsourcing.Version, error) {
+	evBucket, err := e.createAggregateBucket(tx, aggregateType, aggregateID)
+	if err != nil {
+		return nil, 0, errors.New("could not create aggregate events bucket")
 	}
 
 	currentVersion := eventsourcing.Version(0)
@@ -101,18 +353,17 @@ func (e *BBolt[T]) Save(events []eventsourcing.Event[T]) error {
 		lastEvent := struct{ Version eventsourcing.Version }{}
 		err := e.serializer.Unmarshal(obj, &lastEvent)
 		if err != nil {
-			return errors.New(fmt.Sprintf("could not serialize event, %v", err))
+			return nil, 0, errors.New(fmt.Sprintf("could not serialize event, %v", err))
 		}
 		currentVersion = lastEvent.Version
 	}
+	return evBucket, currentVersion, nil
+}
 
-	//Validate events
-	err = eventstore.ValidateEvents(aggregateID, currentVersion, events)
-	if err != nil {
-		return err
-	}
-
-	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
+// put stores the validated events in the aggregate bucket and the indexes and sets their global version
+func (e *BBolt[T]) put(tx *bbolt.Tx, evBucket *bbolt.Bucket, events []eventsourcing.RawEvent) error {
+	bucketName := e.aggregateKey(events[0].AggregateType, events[0].AggregateID)
+	globalBucket := tx.Bucket(e.globalBucketName())
 	if globalBucket == nil {
 		return errors.New("global bucket not found")
 	}
@@ -132,22 +383,18 @@ func (e *BBolt[T]) Save(events []eventsourcing.Event[T]) error {
 			return errors.New("could not get next sequence for global bucket")
 		}
 
-		// marshal the event.Data separately to be able to handle the type info
-		eventData, err := e.serializer.Marshal(event.Data)
-		if err != nil {
-			return errors.New(fmt.Sprintf("could not serialize event data, %v", err))
-		}
-
 		// build the internal bolt event
 		bEvent := boltEvent{
+			EventID:       event.EventID,
 			AggregateID:   event.AggregateID,
 			AggregateType: event.AggregateType,
 			Version:       uint64(event.Version),
 			GlobalVersion: globalSequence,
-			Reason:        event.Reason(),
+			Reason:        event.Reason,
 			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
 			Metadata:      event.Metadata,
-			Data:          eventData,
+			Data:          event.Data,
 		}
 
 		value, err := e.serializer.Marshal(bEvent)
@@ -163,27 +410,80 @@ func (e *BBolt[T]) Save(events []eventsourcing.Event[T]) error {
 		if err != nil {
 			return errors.New(fmt.Sprintf("could not save global sequence pointer for %#v", bucketName))
 		}
+		err = tx.Bucket(e.timeBucketName()).Put(timeKey(event.Timestamp, globalSequence), []byte{})
+		if err != nil {
+			return errors.New(fmt.Sprintf("could not save time index for %#v", bucketName))
+		}
+		if id := correlationID(event.Metadata); id != "" {
+			err = tx.Bucket(e.correlationBucketName()).Put(correlationKey(id, globalSequence), []byte{})
+			if err != nil {
+				return errors.New(fmt.Sprintf("could not save correlation index for %#v", bucketName))
+			}
+		}
 
-		// override the event in the slice exposing the GlobalVersion to the caller
 		events[i].GlobalVersion = eventsourcing.Version(globalSequence)
 	}
-	return tx.Commit()
+	return nil
 }
 
 // Get aggregate events
 func (e *BBolt[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
-	bucketName := aggregateKey(aggregateType, id)
-
 	tx, err := e.db.Begin(false)
 	if err != nil {
 		return nil, err
 	}
+	if afterVersion >= eventsourcing.MaxVersion {
+		tx.Rollback()
+		return nil, fmt.Errorf("%w: after version %d", eventsourcing.ErrVersionOverflow, afterVersion)
+	}
 	firstEvent := afterVersion + 1
-	i := iterator[T]{tx: tx, bucketName: bucketName, firstEventIndex: uint64(firstEvent), serializer: e.serializer}
+	i := iterator[T]{tx: tx, bucket: e.aggregateBucket(tx, aggregateType, id), firstEventIndex: uint64(firstEvent), serializer: e.serializer}
 	return &i, nil
 
 }
 
+// GetMany returns an iterator per aggregate id over its events after the version in afterVersions, the events are
+// read in one transaction
+func (e *BBolt[T]) GetMany(ctx context.Context, aggregateType string, ids []string, afterVersions map[string]eventsourcing.Version) (map[string]eventsourcing.EventIterator[T], error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	iterators := make(map[string]eventsourcing.EventIterator[T], len(ids))
+	for _, id := range ids {
+		bucket := e.aggregateBucket(tx, aggregateType, id)
+		if bucket == nil {
+			continue
+		}
+		raws, err := e.rawFrom(ctx, bucket.Cursor(), uint64(afterVersions[id])+1, math.MaxUint64)
+		if err != nil {
+			return nil, err
+		}
+		var events []eventsourcing.Event[T]
+		for _, raw := range raws {
+			event, ok, err := e.serializer.DecodeRaw(raw)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 0 {
+			iterators[id] = eventsourcing.NewSliceIterator(events)
+		}
+	}
+	return iterators, nil
+}
+
+// SubscribeAggregate delivers the events of the aggregate after fromVersion as they are saved. Only the events saved
+// via this event store instance are pushed, events saved by other processes are read when the aggregate has a newer
+// event pushed.
+func (e *BBolt[T]) SubscribeAggregate(ctx context.Context, aggregateType, id string, fromVersion eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) error {
+	return e.bus.Subscribe(ctx, e, aggregateType, id, fromVersion, handler)
+}
+
 // GlobalEvents return count events in order globally from the start posistion
 func (e *BBolt[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
 	var events []eventsourcing.Event[T]
@@ -193,7 +493,7 @@ func (e *BBolt[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T],
 	}
 	defer tx.Rollback()
 
-	globalBucket := tx.Bucket([]byte(globalEventOrderBucketName))
+	globalBucket := tx.Bucket(e.globalBucketName())
 	cursor := globalBucket.Cursor()
 	for k, obj := cursor.Seek(itob(start)); k != nil; k, obj = cursor.Next() {
 		bEvent := boltEvent{}
@@ -201,24 +501,11 @@ func (e *BBolt[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T],
 		if err != nil {
 			return nil, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
 		}
-		f, ok := e.serializer.Type(bEvent.AggregateType, bEvent.Reason)
-		if !ok {
-			// if the typ/reason is not register jump over the event
-			continue
-		}
-		eventData := f()
-		err = e.serializer.Unmarshal(bEvent.Data, &eventData)
+		event, ok, err := e.toEvent(bEvent)
 		if err != nil {
-			return nil, errors.New(fmt.Sprintf("could not deserialize event data, %v", err))
-		}
-		event := eventsourcing.Event[T]{
-			AggregateID:   bEvent.AggregateID,
-			AggregateType: bEvent.AggregateType,
-			Version:       eventsourcing.Version(bEvent.Version),
-			GlobalVersion: eventsourcing.Version(bEvent.GlobalVersion),
-			Timestamp:     bEvent.Timestamp,
-			Metadata:      bEvent.Metadata,
-			Data:          eventData,
+			return nil, err
+		} else if !ok {
+			continue
 		}
 		events = append(events, event)
 		count--
@@ -229,11 +516,256 @@ func (e *BBolt[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T],
 	return events, nil
 }
 
+// GlobalEventsBetween returns the events with a timestamp within the time window matching the filter in global order.
+// The events are found via the time index, they are sorted on their global version as the timestamps of events saved
+// concurrently can be out of order.
+func (e *BBolt[T]) GlobalEventsBetween(ctx context.Context, from, to time.Time, filter eventsourcing.EventFilter) ([]eventsourcing.Event[T], error) {
+	var events []eventsourcing.Event[T]
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	globalBucket := tx.Bucket(e.globalBucketName())
+	end := timeKey(to, 0)
+	cursor := tx.Bucket(e.timeBucketName()).Cursor()
+	for k, _ := cursor.Seek(timeKey(from, 0)); k != nil && bytes.Compare(k, end) < 0; k, _ = cursor.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		obj := globalBucket.Get(k[8:])
+		if obj == nil {
+			return nil, errors.New(fmt.Sprintf("global event %d in time index not found", binary.BigEndian.Uint64(k[8:])))
+		}
+		bEvent := boltEvent{}
+		err := e.serializer.Unmarshal(obj, &bEvent)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
+		}
+		if !filter.Match(bEvent.AggregateType, bEvent.Reason) {
+			continue
+		}
+		event, ok, err := e.toEvent(bEvent)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].GlobalVersion < events[j].GlobalVersion })
+	return events, nil
+}
+
+// EventsByCorrelationID returns the events with the correlation id in their metadata in global order
+func (e *BBolt[T]) EventsByCorrelationID(ctx context.Context, correlationID string) ([]eventsourcing.Event[T], error) {
+	var events []eventsourcing.Event[T]
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	globalBucket := tx.Bucket(e.globalBucketName())
+	prefix := correlationPrefix(correlationID)
+	cursor := tx.Bucket(e.correlationBucketName()).Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		globalSequence := k[len(prefix):]
+		obj := globalBucket.Get(globalSequence)
+		if obj == nil {
+			return nil, errors.New(fmt.Sprintf("global event %d in correlation index not found", binary.BigEndian.Uint64(globalSequence)))
+		}
+		bEvent := boltEvent{}
+		err := e.serializer.Unmarshal(obj, &bEvent)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
+		}
+		event, ok, err := e.toEvent(bEvent)
+		if err != nil {
+			return nil, err
+		} else if !ok {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// toEvent deserialize the event data, returns false if the event type is not registered or the event is skipped
+func (e *BBolt[T]) toEvent(bEvent boltEvent) (eventsourcing.Event[T], bool, error) {
+	return e.serializer.DecodeRaw(bEvent.raw())
+}
+
+// raw returns the stored event with the event data as marshaled
+func (b boltEvent) raw() eventsourcing.RawEvent {
+	return eventsourcing.RawEvent{
+		EventID:       b.EventID,
+		AggregateID:   b.AggregateID,
+		AggregateType: b.AggregateType,
+		Version:       eventsourcing.Version(b.Version),
+		GlobalVersion: eventsourcing.Version(b.GlobalVersion),
+		Reason:        b.Reason,
+		Timestamp:     b.Timestamp,
+		ValidTime:     b.ValidTime,
+		Data:          b.Data,
+		Metadata:      b.Metadata,
+	}
+}
+
+// GetRaw returns the events of the aggregate after the version without unmarshaling their data
+func (e *BBolt[T]) GetRaw(ctx context.Context, id, aggregateType string, afterVersion eventsourcing.Version) ([]eventsourcing.RawEvent, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	bucket := e.aggregateBucket(tx, aggregateType, id)
+	if bucket == nil {
+		return nil, nil
+	}
+	return e.rawFrom(ctx, bucket.Cursor(), uint64(afterVersion)+1, math.MaxUint64)
+}
+
+// GlobalEventsRaw returns count events in global order from the start position without unmarshaling their data
+func (e *BBolt[T]) GlobalEventsRaw(ctx context.Context, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	return e.rawFrom(ctx, tx.Bucket(e.globalBucketName()).Cursor(), start, count)
+}
+
+// rawFrom returns count events from the cursor starting on the key
+func (e *BBolt[T]) rawFrom(ctx context.Context, cursor *bbolt.Cursor, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	var events []eventsourcing.RawEvent
+	for k, obj := cursor.Seek(itob(start)); k != nil && uint64(len(events)) < count; k, obj = cursor.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		bEvent := boltEvent{}
+		err := e.serializer.Unmarshal(obj, &bEvent)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
+		}
+		events = append(events, bEvent.raw())
+	}
+	return events, nil
+}
+
+// Stats returns the number of events per aggregate type and the aggregates with the most events
+func (e *BBolt[T]) Stats(ctx context.Context) (eventsourcing.Stats, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return eventsourcing.Stats{}, err
+	}
+	defer tx.Rollback()
+
+	c := eventstore.NewStatsCollector()
+	cursor := tx.Bucket(e.globalBucketName()).Cursor()
+	for k, obj := cursor.First(); k != nil; k, obj = cursor.Next() {
+		if ctx.Err() != nil {
+			return eventsourcing.Stats{}, ctx.Err()
+		}
+		bEvent := boltEvent{}
+		err := e.serializer.Unmarshal(obj, &bEvent)
+		if err != nil {
+			return eventsourcing.Stats{}, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
+		}
+		c.Add(bEvent.AggregateType, bEvent.AggregateID, bEvent.Timestamp)
+	}
+	return c.Stats(), nil
+}
+
+// AggregateIDs returns the ids of the aggregates of the type in key order. With LayoutType only the keys of the type
+// bucket are read, with LayoutAggregate the buckets with the type as prefix are read and the first event of each
+// checked as the type can't be told from the bucket name alone when types contain an underscore.
+func (e *BBolt[T]) AggregateIDs(ctx context.Context, aggregateType string) ([]string, error) {
+	tx, err := e.db.Begin(false)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var ids []string
+	if e.layout == LayoutType {
+		typeBucket := e.typeBucket(tx, aggregateType)
+		if typeBucket == nil {
+			return nil, nil
+		}
+		err = typeBucket.ForEach(func(k, v []byte) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			ids = append(ids, string(k))
+			return nil
+		})
+		return ids, err
+	}
+
+	internal := map[string]bool{}
+	for _, name := range []string{globalEventOrderBucketName, globalEventTimeBucketName, correlationBucketName, aggregatesBucketName, layoutBucketName} {
+		internal[e.bucketPrefix+name] = true
+	}
+	prefix := []byte(e.aggregateKey(aggregateType, ""))
+	cursor := tx.Cursor()
+	for k, _ := cursor.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = cursor.Next() {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if internal[string(k)] {
+			continue
+		}
+		_, obj := tx.Bucket(k).Cursor().First()
+		if obj == nil {
+			continue
+		}
+		bEvent := struct{ AggregateType string }{}
+		err := e.serializer.Unmarshal(obj, &bEvent)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
+		}
+		if bEvent.AggregateType == aggregateType {
+			ids = append(ids, string(k[len(prefix):]))
+		}
+	}
+	return ids, nil
+}
+
+// Layout returns the bucket layout of the aggregate events in the database
+func (e *BBolt[T]) Layout() Layout {
+	return e.layout
+}
+
+// Ordering returns the global order guarantees of the bbolt event store
+func (e *BBolt[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{ContiguousGlobalOrder: true, GlobalVersionOnGet: true}
+}
+
+// Capabilities returns the optional features of the bbolt event store
+func (e *BBolt[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{GlobalOrder: true, Subscriptions: true, MultiAggregateTx: true}
+}
+
 // Close closes the event stream and the underlying database
 func (e *BBolt[T]) Close() error {
 	return e.db.Close()
 }
 
+// CheckHealth returns an error if the database is closed or its file is gone
+func (e *BBolt[T]) CheckHealth(ctx context.Context) error {
+	err := e.db.View(func(tx *bbolt.Tx) error { return nil })
+	if err != nil {
+		return err
+	}
+	_, err = os.Stat(e.db.Path())
+	return err
+}
+
 // CreateBucket creates a bucket
 func (e *BBolt[T]) createBucket(bucketName []byte, tx *bbolt.Tx) error {
 	// Ensure that we have a bucket named event_type for the given type
@@ -245,6 +777,99 @@ func (e *BBolt[T]) createBucket(bucketName []byte, tx *bbolt.Tx) error {
 }
 
 // aggregateKey generate a aggregate key to store events against from aggregateType and aggregateID
-func aggregateKey(aggregateType, aggregateID string) string {
-	return aggregateType + "_" + aggregateID
+func (e *BBolt[T]) aggregateKey(aggregateType, aggregateID string) string {
+	return e.bucketPrefix + aggregateType + "_" + aggregateID
+}
+
+// aggregateBucket returns the bucket holding the events of the aggregate, nil if no events are saved
+func (e *BBolt[T]) aggregateBucket(tx *bbolt.Tx, aggregateType, aggregateID string) *bbolt.Bucket {
+	if e.layout == LayoutType {
+		typeBucket := e.typeBucket(tx, aggregateType)
+		if typeBucket == nil {
+			return nil
+		}
+		return typeBucket.Bucket([]byte(aggregateID))
+	}
+	return tx.Bucket([]byte(e.aggregateKey(aggregateType, aggregateID)))
+}
+
+// createAggregateBucket returns the bucket holding the events of the aggregate, created if it does not exist
+func (e *BBolt[T]) createAggregateBucket(tx *bbolt.Tx, aggregateType, aggregateID string) (*bbolt.Bucket, error) {
+	if e.layout == LayoutType {
+		typeBucket, err := tx.Bucket(e.aggregatesBucketName()).CreateBucketIfNotExists([]byte(aggregateType))
+		if err != nil {
+			return nil, err
+		}
+		return typeBucket.CreateBucketIfNotExists([]byte(aggregateID))
+	}
+	bucketName := []byte(e.aggregateKey(aggregateType, aggregateID))
+	err := e.createBucket(bucketName, tx)
+	if err != nil {
+		return nil, err
+	}
+	return tx.Bucket(bucketName), nil
+}
+
+// typeBucket returns the bucket holding the aggregate buckets of the type in LayoutType, nil if no events are saved
+func (e *BBolt[T]) typeBucket(tx *bbolt.Tx, aggregateType string) *bbolt.Bucket {
+	return tx.Bucket(e.aggregatesBucketName()).Bucket([]byte(aggregateType))
+}
+
+// aggregatesBucketName returns the name of the bucket holding the type buckets in LayoutType
+func (e *BBolt[T]) aggregatesBucketName() []byte {
+	return []byte(e.bucketPrefix + aggregatesBucketName)
+}
+
+// globalBucketName returns the name of the bucket holding the global event order
+func (e *BBolt[T]) globalBucketName() []byte {
+	return []byte(e.bucketPrefix + globalEventOrderBucketName)
+}
+
+// correlationBucketName returns the name of the bucket indexing the events on their correlation id
+func (e *BBolt[T]) correlationBucketName() []byte {
+	return []byte(e.bucketPrefix + correlationBucketName)
+}
+
+// timeBucketName returns the name of the bucket indexing the global event order on the event timestamps
+func (e *BBolt[T]) timeBucketName() []byte {
+	return []byte(e.bucketPrefix + globalEventTimeBucketName)
+}
+
+// createIndex returns the index bucket, created is true if the bucket did not exist and has to be built from the
+// events saved before
+func createIndex(tx *bbolt.Tx, name string) (*bbolt.Bucket, bool, error) {
+	if b := tx.Bucket([]byte(name)); b != nil {
+		return b, false, nil
+	}
+	b, err := tx.CreateBucket([]byte(name))
+	if err != nil {
+		return nil, false, errors.New(fmt.Sprintf("could not create bucket %s: %s", name, err))
+	}
+	return b, true, nil
+}
+
+// detectLayout returns the layout stored in the database. A database without a stored layout gets the requested
+// layout if it has no events, and LayoutAggregate if it has events saved before the layout was stored.
+func detectLayout(tx *bbolt.Tx, prefix string, global *bbolt.Bucket, requested Layout) (Layout, error) {
+	bucket, err := tx.CreateBucketIfNotExists([]byte(prefix + layoutBucketName))
+	if err != nil {
+		return 0, errors.New(fmt.Sprintf("could not create bucket %s: %s", prefix+layoutBucketName, err))
+	}
+	layout := requested
+	if stored := bucket.Get([]byte(layoutBucketName)); stored != nil {
+		layout = Layout(binary.BigEndian.Uint64(stored))
+	} else if k, _ := global.Cursor().First(); k != nil {
+		layout = LayoutAggregate
+	}
+	switch layout {
+	case LayoutAggregate:
+	case LayoutType:
+		_, err = tx.CreateBucketIfNotExists([]byte(prefix + aggregatesBucketName))
+		if err != nil {
+			return 0, errors.New(fmt.Sprintf("could not create bucket %s: %s", prefix+aggregatesBucketName, err))
+		}
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrUnknownLayout, layout)
+	}
+	return layout, bucket.Put([]byte(layoutBucketName), itob(uint64(layout)))
 }
@@ -1,12 +1,19 @@
 package bbolt_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
 	"os"
 	"testing"
+	"time"
 
 	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
 	"github.com/hallgren/eventsourcing/eventstore/bbolt"
 	"github.com/hallgren/eventsourcing/eventstore/suite"
+	bolt "go.etcd.io/bbolt"
 )
 
 func TestSuite(t *testing.T) {
@@ -21,3 +28,274 @@ func TestSuite(t *testing.T) {
 
 	suite.Test[suite.FrequentFlierEvent](t, f)
 }
+
+func TestSuiteWithBucketPrefix(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		dbFile := "bolt_prefix.db"
+		es := bbolt.MustOpenBBolt(dbFile, ser, bbolt.WithBucketPrefix("frequent_flier_"))
+		return es, func() {
+			es.Close()
+			os.Remove(dbFile)
+		}, nil
+	}
+
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestSuiteWithTypeLayout(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		dbFile := "bolt_type_layout.db"
+		es := bbolt.MustOpenBBolt(dbFile, ser, bbolt.WithLayout(bbolt.LayoutType))
+		return es, func() {
+			es.Close()
+			os.Remove(dbFile)
+		}, nil
+	}
+
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestLayoutDetection(t *testing.T) {
+	dbFile := "bolt_layout.db"
+	defer os.Remove(dbFile)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+
+	es := bbolt.MustOpenBBolt(dbFile, *ser, bbolt.WithLayout(bbolt.LayoutType))
+	err := es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "1", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Close()
+
+	// the stored layout is kept when opened without the option
+	es = bbolt.MustOpenBBolt(dbFile, *ser)
+	if es.Layout() != bbolt.LayoutType {
+		t.Fatalf("expected the type layout to be detected got %s", es.Layout())
+	}
+	events, err := es.GetRaw(context.Background(), "1", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the saved event got %d events", len(events))
+	}
+	es.Close()
+
+	// a database with events saved before the layout was stored has the aggregate layout
+	legacyFile := "bolt_layout_legacy.db"
+	defer os.Remove(legacyFile)
+	es = bbolt.MustOpenBBolt(legacyFile, *ser)
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "1", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Close()
+	db, err := bolt.Open(legacyFile, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		return tx.DeleteBucket([]byte("layout"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	es = bbolt.MustOpenBBolt(legacyFile, *ser, bbolt.WithLayout(bbolt.LayoutType))
+	defer es.Close()
+	if es.Layout() != bbolt.LayoutAggregate {
+		t.Fatalf("expected the aggregate layout to be detected got %s", es.Layout())
+	}
+	events, err = es.GetRaw(context.Background(), "1", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the saved event got %d events", len(events))
+	}
+}
+
+func TestAggregateIDs(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	for _, layout := range []bbolt.Layout{bbolt.LayoutAggregate, bbolt.LayoutType} {
+		t.Run(layout.String(), func(t *testing.T) {
+			dbFile := "bolt_ids.db"
+			defer os.Remove(dbFile)
+			es := bbolt.MustOpenBBolt(dbFile, *ser, bbolt.WithLayout(layout))
+			defer es.Close()
+			err := es.SaveAll([][]eventsourcing.Event[suite.FrequentFlierEvent]{
+				{{AggregateID: "b", AggregateType: "Account", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}}},
+				{{AggregateID: "a", AggregateType: "Account", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}}},
+				// the bucket name Account_Owner_c shares the prefix of the Account type
+				{{AggregateID: "c", AggregateType: "Account_Owner", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}}},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids, err := es.AggregateIDs(context.Background(), "Account")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+				t.Fatalf("expected the ids a and b got %v", ids)
+			}
+			ids, err = es.AggregateIDs(context.Background(), "Unknown")
+			if err != nil || len(ids) != 0 {
+				t.Fatalf("expected no ids for an unknown type got %v %v", ids, err)
+			}
+		})
+	}
+}
+
+func TestIndexBackfill(t *testing.T) {
+	dbFile := "bolt_backfill.db"
+	defer os.Remove(dbFile)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FlightTaken{}))
+	timestamp := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	es := bbolt.MustOpenBBolt(dbFile, *ser)
+	err := es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "1", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: timestamp, Data: &suite.FlightTaken{}, Metadata: map[string]interface{}{eventsourcing.MetadataCorrelationID: "order-1"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Close()
+
+	// remove the indexes as if the events were saved before they were added
+	db, err := bolt.Open(dbFile, 0600, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		err := tx.DeleteBucket([]byte("global_event_time"))
+		if err != nil {
+			return err
+		}
+		return tx.DeleteBucket([]byte("correlation"))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	es = bbolt.MustOpenBBolt(dbFile, *ser)
+	defer es.Close()
+	events, err := es.GlobalEventsBetween(context.Background(), timestamp, timestamp.Add(time.Second), eventsourcing.EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the event to be indexed on open got %d events", len(events))
+	}
+	events, err = es.EventsByCorrelationID(context.Background(), "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected the event to be correlated on open got %d events", len(events))
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	dbFile := "bolt_health.db"
+	defer os.Remove(dbFile)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	es := bbolt.MustOpenBBolt(dbFile, *ser)
+	err := es.CheckHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Close()
+	err = es.CheckHealth(context.Background())
+	if err == nil {
+		t.Fatal("expected a closed database to be unhealthy")
+	}
+}
+
+func TestRawEvents(t *testing.T) {
+	dbFile := "bolt_raw.db"
+	defer os.Remove(dbFile)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := bbolt.MustOpenBBolt(dbFile, *ser)
+	defer es.Close()
+	err := es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123"}},
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := es.GetRaw(context.Background(), "123", "FrequentFlierAccount", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Reason != "FlightTaken" || !bytes.Contains(events[0].Data, []byte(`100`)) {
+		t.Fatalf("expected the unregistered event to be returned raw got %+v", events)
+	}
+	events, err = es.GlobalEventsRaw(context.Background(), 1, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].GlobalVersion != 1 || events[0].Reason != "FrequentFlierAccountCreated" {
+		t.Fatalf("expected the first global event got %+v", events)
+	}
+	events, err = es.GetRaw(context.Background(), "unknown", "FrequentFlierAccount", 0)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no events for an unknown aggregate got %v %v", events, err)
+	}
+}
+
+func TestSaveRaw(t *testing.T) {
+	sourceFile, targetFile := "bolt_raw_source.db", "bolt_raw_target.db"
+	defer os.Remove(sourceFile)
+	defer os.Remove(targetFile)
+	// the source serializer has no event types registered
+	source := bbolt.MustOpenBBolt(sourceFile, *eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	defer source.Close()
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	target := bbolt.MustOpenBBolt(targetFile, *ser)
+	defer target.Close()
+
+	err := source.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123"}},
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raws, err := source.GetRaw(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = target.SaveRaw(raws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = target.SaveRaw(raws)
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+
+	events, err := target.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the copied events got %+v", events)
+	}
+	if taken, ok := events[1].Data.(*suite.FlightTaken); !ok || taken.MilesAdded != 100 {
+		t.Fatalf("expected the flight taken event got %+v", events[1].Data)
+	}
+}
@@ -10,7 +10,7 @@ import (
 
 type iterator[T any] struct {
 	tx              *bbolt.Tx
-	bucketName      string
+	bucket          *bbolt.Bucket
 	firstEventIndex uint64
 	cursor          *bbolt.Cursor
 	serializer      eventsourcing.Serializer[T]
@@ -25,11 +25,10 @@ func (i *iterator[T]) Close() {
 func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
 	var k, obj []byte
 	if i.cursor == nil {
-		bucket := i.tx.Bucket([]byte(i.bucketName))
-		if bucket == nil {
+		if i.bucket == nil {
 			return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
 		}
-		i.cursor = bucket.Cursor()
+		i.cursor = i.bucket.Cursor()
 		k, obj = i.cursor.Seek(itob(i.firstEventIndex))
 		if k == nil {
 			return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
@@ -45,24 +44,13 @@ func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
 	if err != nil {
 		return eventsourcing.Event[T]{}, errors.New(fmt.Sprintf("could not deserialize event, %v", err))
 	}
-	f, ok := i.serializer.Type(bEvent.AggregateType, bEvent.Reason)
-	if !ok {
-		// if the typ/reason is not register jump over the event
-		return i.Next()
-	}
-	eventData := f()
-	err = i.serializer.Unmarshal(bEvent.Data, &eventData)
+	event, ok, err := i.serializer.DecodeRaw(bEvent.raw())
 	if err != nil {
-		return eventsourcing.Event[T]{}, errors.New(fmt.Sprintf("could not deserialize event data, %v", err))
+		return eventsourcing.Event[T]{}, err
 	}
-	event := eventsourcing.Event[T]{
-		AggregateID:   bEvent.AggregateID,
-		AggregateType: bEvent.AggregateType,
-		Version:       eventsourcing.Version(bEvent.Version),
-		GlobalVersion: eventsourcing.Version(bEvent.GlobalVersion),
-		Timestamp:     bEvent.Timestamp,
-		Metadata:      bEvent.Metadata,
-		Data:          eventData,
+	if !ok {
+		// if the typ/reason is not register or the event is skipped jump over the event
+		return i.Next()
 	}
 	return event, nil
 }
@@ -0,0 +1,134 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// AggregateBus delivers the events saved in an event store to the subscribers of their aggregate within the process.
+// The event store publishes the events after they are committed and implements SubscribeAggregate with Subscribe.
+type AggregateBus[T any] struct {
+	lock        sync.Mutex
+	subscribers map[string]map[*aggregateSubscriber[T]]struct{}
+}
+
+type aggregateSubscriber[T any] struct {
+	lock    sync.Mutex
+	pending []eventsourcing.Event[T]
+	notify  chan struct{}
+}
+
+// NewAggregateBus returns a bus without subscribers
+func NewAggregateBus[T any]() *AggregateBus[T] {
+	return &AggregateBus[T]{subscribers: make(map[string]map[*aggregateSubscriber[T]]struct{})}
+}
+
+// Publish queues the committed events of an aggregate for its subscribers, it never blocks on slow subscribers
+func (b *AggregateBus[T]) Publish(events []eventsourcing.Event[T]) {
+	if len(events) == 0 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for s := range b.subscribers[busKey(events[0].AggregateType, events[0].AggregateID)] {
+		s.lock.Lock()
+		s.pending = append(s.pending, events...)
+		s.lock.Unlock()
+		select {
+		case s.notify <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe delivers the events of the aggregate after fromVersion, first the events read from the event store then
+// the events published on the bus. If the published events skip a version, like events saved by another process or
+// outside the event store, the missing events are read from the event store. It blocks until the context is done or
+// the handler fails.
+func (b *AggregateBus[T]) Subscribe(ctx context.Context, store eventsourcing.EventStore[T], aggregateType, id string, fromVersion eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) error {
+	s := &aggregateSubscriber[T]{notify: make(chan struct{}, 1)}
+	key := busKey(aggregateType, id)
+	// subscribe before reading the saved events to not miss events saved in between
+	b.lock.Lock()
+	if b.subscribers[key] == nil {
+		b.subscribers[key] = make(map[*aggregateSubscriber[T]]struct{})
+	}
+	b.subscribers[key][s] = struct{}{}
+	b.lock.Unlock()
+	defer func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		delete(b.subscribers[key], s)
+		if len(b.subscribers[key]) == 0 {
+			delete(b.subscribers, key)
+		}
+	}()
+
+	version, err := catchUp(ctx, store, aggregateType, id, fromVersion, handler)
+	if err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-s.notify:
+		}
+		s.lock.Lock()
+		events := s.pending
+		s.pending = nil
+		s.lock.Unlock()
+		for _, event := range events {
+			if event.Version <= version {
+				continue
+			}
+			if event.Version > version+1 {
+				version, err = catchUp(ctx, store, aggregateType, id, version, handler)
+				if err != nil {
+					return err
+				}
+				if event.Version <= version {
+					continue
+				}
+			}
+			err = handler(event)
+			if err != nil {
+				return err
+			}
+			version = event.Version
+		}
+	}
+}
+
+// catchUp delivers the events in the event store after the version and returns the version of the last event, the
+// version is returned unchanged when there are no events after it
+func catchUp[T any](ctx context.Context, store eventsourcing.EventStore[T], aggregateType, id string, version eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) (eventsourcing.Version, error) {
+	iterator, err := store.Get(ctx, id, aggregateType, version)
+	if errors.Is(err, eventsourcing.ErrNoEvents) {
+		// a new aggregate or no events after the version, the subscriber is caught up
+		return version, nil
+	} else if err != nil {
+		return version, err
+	}
+	defer iterator.Close()
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return version, nil
+		} else if err != nil {
+			return version, err
+		}
+		err = handler(event)
+		if err != nil {
+			return version, err
+		}
+		version = event.Version
+	}
+}
+
+func busKey(aggregateType, id string) string {
+	return aggregateType + "\x00" + id
+}
@@ -0,0 +1,111 @@
+package eventstore_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Opened struct{}
+
+func TestAggregateBusCatchUpOnGap(t *testing.T) {
+	store := memory.Create[any]()
+	bus := eventstore.NewAggregateBus[any]()
+	event := func(version eventsourcing.Version) eventsourcing.Event[any] {
+		return eventsourcing.Event[any]{AggregateID: "1", AggregateType: "Account", Version: version, Timestamp: time.Now(), Data: &Opened{}}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan eventsourcing.Version)
+	go bus.Subscribe(ctx, store, "Account", "1", 0, func(e eventsourcing.Event[any]) error {
+		received <- e.Version
+		return nil
+	})
+
+	// the second event is saved without being published, like an event saved by another process
+	for version := eventsourcing.Version(1); version <= 3; version++ {
+		events := []eventsourcing.Event[any]{event(version)}
+		err := store.Save(events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if version != 2 {
+			bus.Publish(events)
+		}
+	}
+	for expected := eventsourcing.Version(1); expected <= 3; expected++ {
+		select {
+		case version := <-received:
+			if version != expected {
+				t.Fatalf("expected version %d got %d", expected, version)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for version %d", expected)
+		}
+	}
+}
+
+func TestAggregateBusSubscribeWithoutEvents(t *testing.T) {
+	event := func(version eventsourcing.Version) eventsourcing.Event[any] {
+		return eventsourcing.Event[any]{AggregateID: "1", AggregateType: "Account", Version: version, Timestamp: time.Now(), Data: &Opened{}}
+	}
+	tests := []struct {
+		name  string
+		saved eventsourcing.Version // events saved before subscribing
+	}{
+		{name: "new aggregate", saved: 0},
+		{name: "at current version", saved: 2},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			store := memory.Create[any]()
+			bus := eventstore.NewAggregateBus[any]()
+			for version := eventsourcing.Version(1); version <= test.saved; version++ {
+				err := store.Save([]eventsourcing.Event[any]{event(version)})
+				if err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			received := make(chan eventsourcing.Version)
+			result := make(chan error, 1)
+			go func() {
+				result <- bus.Subscribe(ctx, store, "Account", "1", test.saved, func(e eventsourcing.Event[any]) error {
+					received <- e.Version
+					return nil
+				})
+			}()
+			// let the subscriber catch up before the next event is saved
+			time.Sleep(10 * time.Millisecond)
+			select {
+			case err := <-result:
+				t.Fatalf("expected the subscription to wait for events got %v", err)
+			default:
+			}
+
+			events := []eventsourcing.Event[any]{event(test.saved + 1)}
+			err := store.Save(events)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bus.Publish(events)
+			select {
+			case version := <-received:
+				if version != test.saved+1 {
+					t.Fatalf("expected version %d got %d", test.saved+1, version)
+				}
+			case err := <-result:
+				t.Fatalf("subscription ended with %v", err)
+			case <-time.After(time.Second):
+				t.Fatal("timeout waiting for the published event")
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package esdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+)
+
+// ConnectionState is the state of the connection to event store db as seen from the operations of the event store
+type ConnectionState int
+
+const (
+	// StateConnected when the last operation reached the server
+	StateConnected ConnectionState = iota
+	// StateDisconnected when the last operation failed on a closed connection or a deadline
+	StateDisconnected
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case StateConnected:
+		return "connected"
+	case StateDisconnected:
+		return "disconnected"
+	}
+	return "unknown"
+}
+
+// ConnectOption configures the event store db client
+type ConnectOption func(*esdb.Configuration)
+
+// WithKeepAlive sets how often the connection is pinged and how long to wait for the reply before the connection
+// is closed. Event store db recommends an interval of at least 10 seconds.
+func WithKeepAlive(interval, timeout time.Duration) ConnectOption {
+	return func(c *esdb.Configuration) {
+		c.KeepAliveInterval = interval
+		c.KeepAliveTimeout = timeout
+	}
+}
+
+// Connect creates an event store db client from the connection string. The client reconnects by itself on the next
+// operation after a lost connection, subscriptions are re-established by the event store with WithResubscribe.
+func Connect(connectionString string, opts ...ConnectOption) (*esdb.Client, error) {
+	config, err := esdb.ParseConnectionString(connectionString)
+	if err != nil {
+		return nil, err
+	}
+	for _, opt := range opts {
+		opt(config)
+	}
+	return esdb.NewClient(config)
+}
+
+// WithConnectionState sets a function called when the connection state changes, with the error causing a disconnect.
+// Services can use it, or the State method, for readiness probes.
+func WithConnectionState(f func(state ConnectionState, err error)) Option {
+	return func(o *options) {
+		o.onStateChange = f
+	}
+}
+
+// WithResubscribe makes subscriptions dropped on transient errors be re-established from the last handled event
+// after the backoff, instead of returning the error.
+func WithResubscribe(backoff time.Duration) Option {
+	return func(o *options) {
+		o.resubscribe = true
+		o.resubscribeBackoff = backoff
+	}
+}
+
+// connection tracks the connection state from the result of the operations
+type connection struct {
+	lock     sync.Mutex
+	state    ConnectionState
+	onChange func(state ConnectionState, err error)
+}
+
+// observe updates the state from the result of an operation
+func (c *connection) observe(err error) {
+	state := StateConnected
+	if isDisconnect(err) {
+		state = StateDisconnected
+	}
+	c.lock.Lock()
+	changed := c.state != state
+	c.state = state
+	onChange := c.onChange
+	c.lock.Unlock()
+	if changed && onChange != nil {
+		onChange(state, err)
+	}
+}
+
+func (c *connection) get() ConnectionState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.state
+}
+
+// isDisconnect returns true if the error means the server could not be reached
+func isDisconnect(err error) bool {
+	if err == nil {
+		return false
+	}
+	if unavailable(err) {
+		return true
+	}
+	var esdbErr *esdb.Error
+	if errors.As(err, &esdbErr) {
+		switch esdbErr.Code() {
+		case esdb.ErrorCodeConnectionClosed, esdb.ErrorCodeDeadlineExceeded:
+			return true
+		}
+		return false
+	}
+	return IsTransient(err)
+}
+
+// State returns the connection state seen by the last operation
+func (es *ESDB[T]) State() ConnectionState {
+	return es.connection.get()
+}
+
+// CheckHealth returns an error if the last operation failed to reach event store db
+func (es *ESDB[T]) CheckHealth(ctx context.Context) error {
+	if state := es.State(); state != StateConnected {
+		return fmt.Errorf("event store db %s", state)
+	}
+	return nil
+}
+
+// permanentError wraps the errors ending a subscription without re-establishing it, like errors from the handler
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string { return e.err.Error() }
+
+// subscribe runs the subscription and re-establish it on transient errors if resubscribe is set
+func (es *ESDB[T]) subscribe(ctx context.Context, run func() error) error {
+	for {
+		err := run()
+		var hErr permanentError
+		if errors.As(err, &hErr) {
+			return hErr.err
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		es.connection.observe(err)
+		if !es.resubscribe || !IsTransient(err) {
+			return err
+		}
+		timer := time.NewTimer(es.resubscribeBackoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package esdb_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	es "github.com/hallgren/eventsourcing/eventstore/esdb"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+func TestConnectionState(t *testing.T) {
+	// nothing listens on the port
+	client, err := es.Connect("esdb://localhost:1?tls=false&maxDiscoverAttempts=1&discoveryInterval=10", es.WithKeepAlive(10*time.Second, 10*time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var states []es.ConnectionState
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	store := es.Open(client, *ser, true, es.WithConnectionState(func(state es.ConnectionState, err error) {
+		states = append(states, state)
+	}))
+	err = store.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "1", Version: 1, AggregateType: "FrequentFlierAccount", Data: &suite.FrequentFlierAccountCreated{}},
+	})
+	if err == nil {
+		t.Fatal("expected the save to fail")
+	}
+	if !es.IsTransient(err) {
+		t.Fatalf("expected the unreachable server to be transient got %v", err)
+	}
+	if store.State() != es.StateDisconnected {
+		t.Fatalf("expected disconnected got %s", store.State())
+	}
+	if len(states) != 1 || states[0] != es.StateDisconnected {
+		t.Fatalf("expected a change to disconnected got %v", states)
+	}
+}
@@ -2,33 +2,125 @@ package esdb
 
 import (
 	"context"
+	"encoding/json"
+	"time"
 
 	"github.com/hallgren/eventsourcing/eventstore"
 
 	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/gofrs/uuid"
 	"github.com/hallgren/eventsourcing"
 )
 
 const streamSeparator = "-"
 
+// validTimeKey is the metadata key the valid time of the event is stored on, event store db has no field for it
+const validTimeKey = "$validTime"
+
+// eventIDKey is the metadata key event ids that are not UUIDs are stored on, UUIDs are stored as the event store db
+// event id
+const eventIDKey = "$eventId"
+
+// aggregateTypeKey and aggregateIDKey are the metadata keys the aggregate type and id are stored on, making the events
+// read from $all and category streams independent of how the stream name is built and parsed
+const (
+	aggregateTypeKey = "$aggregateType"
+	aggregateIDKey   = "$aggregateId"
+)
+
+// timestampKey is the metadata key of the event timestamp, event store db sets its own created date on the recorded
+// events. Events saved before the timestamp was stored are read with the created date.
+const timestampKey = "$timestamp"
+
 // ESDB is the event store handler
 type ESDB[T any] struct {
-	client      *esdb.Client
-	serializer  eventsourcing.Serializer[T]
-	contentType esdb.ContentType
+	client       *esdb.Client
+	serializer   eventsourcing.Serializer[T]
+	contentType  esdb.ContentType
+	streamName   StreamNameFunc
+	streamParser StreamParserFunc
+	connection   *connection
+
+	contentTypeFunc   func(data any) esdb.ContentType
+	marshalMetadata   func(v any) ([]byte, error)
+	unmarshalMetadata func(data []byte, v any) error
+
+	resubscribe        bool
+	resubscribeBackoff time.Duration
+}
+
+// StreamNameFunc builds the stream name from the aggregate type and id
+type StreamNameFunc func(aggregateType, aggregateID string) string
+
+// Option configures the esdb event store
+type Option func(*options)
+
+type options struct {
+	streamName         StreamNameFunc
+	streamParser       StreamParserFunc
+	onStateChange      func(state ConnectionState, err error)
+	resubscribe        bool
+	resubscribeBackoff time.Duration
+	contentType        func(data any) esdb.ContentType
+	jsonMetadata       bool
+}
+
+// WithContentType sets the content type per event from the event data, overriding the content type from Open.
+// Makes it possible to store events serialized as json and binary in the same event store.
+func WithContentType(f func(data any) esdb.ContentType) Option {
+	return func(o *options) {
+		o.contentType = f
+	}
+}
+
+// WithJSONMetadata stores the event metadata as json instead of with the serializer of the event data. Event store
+// db expects the metadata to be json, binary event data can then be combined with metadata readable by projections.
+// Events saved without the option can't be read with it if the serializer is not json.
+func WithJSONMetadata() Option {
+	return func(o *options) {
+		o.jsonMetadata = true
+	}
+}
+
+// WithStreamName sets how the stream names are built, default is aggregateType-aggregateID.
+// Makes it possible for multiple bounded contexts to share one database or to follow existing naming conventions.
+// The aggregate type and id are also stored in the metadata of the events, subscriptions read them from there and
+// only parse the stream name of events saved before they were stored.
+func WithStreamName(f StreamNameFunc) Option {
+	return func(o *options) {
+		o.streamName = f
+	}
 }
 
 // Open binds the event store db client
-func Open[T any](client *esdb.Client, serializer eventsourcing.Serializer[T], jsonSerializer bool) *ESDB[T] {
+func Open[T any](client *esdb.Client, serializer eventsourcing.Serializer[T], jsonSerializer bool, opts ...Option) *ESDB[T] {
+	o := options{streamName: stream, streamParser: parseStream}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	// defaults to binary
 	var contentType esdb.ContentType
 	if jsonSerializer {
 		contentType = esdb.ContentTypeJson
 	}
+	marshalMetadata, unmarshalMetadata := serializer.Marshal, serializer.Unmarshal
+	if o.jsonMetadata {
+		marshalMetadata, unmarshalMetadata = json.Marshal, json.Unmarshal
+	}
 	return &ESDB[T]{
-		client:      client,
-		serializer:  serializer,
-		contentType: contentType,
+		client:       client,
+		serializer:   serializer,
+		contentType:  contentType,
+		streamName:   o.streamName,
+		streamParser: o.streamParser,
+		connection:   &connection{onChange: o.onStateChange},
+
+		contentTypeFunc:   o.contentType,
+		marshalMetadata:   marshalMetadata,
+		unmarshalMetadata: unmarshalMetadata,
+
+		resubscribe:        o.resubscribe,
+		resubscribeBackoff: o.resubscribeBackoff,
 	}
 }
 
@@ -43,7 +135,7 @@ func (es *ESDB[T]) Save(events []eventsourcing.Event[T]) error {
 	aggregateID := events[0].AggregateID
 	aggregateType := events[0].AggregateType
 	version := events[0].Version
-	stream := stream(aggregateType, aggregateID)
+	stream := es.streamName(aggregateType, aggregateID)
 
 	err := eventstore.ValidateEventsNoVersionCheck(aggregateID, events)
 	if err != nil {
@@ -55,18 +147,37 @@ func (es *ESDB[T]) Save(events []eventsourcing.Event[T]) error {
 	for i, event := range events {
 		var e, m []byte
 
-		e, err := es.serializer.Marshal(event.Data)
+		e, err := es.serializer.MarshalEvent(event)
 		if err != nil {
 			return err
 		}
-		if event.Metadata != nil {
-			m, err = es.serializer.Marshal(event.Metadata)
+		metadata := event.Metadata
+		var eventID uuid.UUID
+		if event.EventID != "" {
+			eventID, err = uuid.FromString(event.EventID)
 			if err != nil {
-				return err
+				metadata = withMetadata(metadata, eventIDKey, event.EventID)
 			}
 		}
+		if !event.ValidTime.IsZero() {
+			metadata = withMetadata(metadata, validTimeKey, event.ValidTime.UTC().Format(time.RFC3339Nano))
+		}
+		if !event.Timestamp.IsZero() {
+			metadata = withMetadata(metadata, timestampKey, event.Timestamp.UTC().Format(time.RFC3339Nano))
+		}
+		metadata = withMetadata(metadata, aggregateTypeKey, event.AggregateType)
+		metadata[aggregateIDKey] = event.AggregateID
+		m, err = es.marshalMetadata(metadata)
+		if err != nil {
+			return err
+		}
+		contentType := es.contentType
+		if es.contentTypeFunc != nil {
+			contentType = es.contentTypeFunc(event.Data)
+		}
 		eventData := esdb.EventData{
-			ContentType: es.contentType,
+			EventID:     eventID,
+			ContentType: contentType,
 			EventType:   event.Reason(),
 			Data:        e,
 			Metadata:    m,
@@ -83,6 +194,7 @@ func (es *ESDB[T]) Save(events []eventsourcing.Event[T]) error {
 		streamOptions.ExpectedRevision = esdb.NoStream{}
 	}
 	wr, err := es.client.AppendToStream(context.Background(), stream, streamOptions, esdbEvents...)
+	es.connection.observe(err)
 	if err != nil {
 		return err
 	}
@@ -94,10 +206,11 @@ func (es *ESDB[T]) Save(events []eventsourcing.Event[T]) error {
 }
 
 func (es *ESDB[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
-	streamID := stream(aggregateType, id)
+	streamID := es.streamName(aggregateType, id)
 
 	from := esdb.StreamRevision{Value: uint64(afterVersion)}
 	stream, err := es.client.ReadStream(ctx, streamID, esdb.ReadStreamOptions{From: from}, ^uint64(0))
+	es.connection.observe(err)
 	if err != nil {
 		if err, ok := esdb.FromError(err); !ok {
 			if err.Code() == esdb.ErrorCodeResourceNotFound {
@@ -108,9 +221,32 @@ func (es *ESDB[T]) Get(ctx context.Context, id string, aggregateType string, aft
 	} else if ctx.Err() != nil {
 		return nil, ctx.Err()
 	}
-	return &iterator[T]{stream: stream, serializer: es.serializer}, nil
+	return &iterator[T]{stream: stream, serializer: es.serializer, unmarshalMetadata: es.unmarshalMetadata, aggregateType: aggregateType, aggregateID: id}, nil
+}
+
+// Ordering returns the global order guarantees of the event store db event store. The global version is the
+// commit position of the write, it's shared by all events in the write and increase with the size of the written data.
+// Events read with Get don't have the global version set.
+func (es *ESDB[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{}
+}
+
+// Capabilities returns the optional features of the event store db event store
+func (es *ESDB[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{Subscriptions: true}
 }
 
+// stream is the default stream name
 func stream(aggregateType, aggregateID string) string {
 	return aggregateType + streamSeparator + aggregateID
 }
+
+// withMetadata returns a copy of the metadata with the key set, the metadata of the event is left unchanged
+func withMetadata(metadata map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	m := make(map[string]interface{}, len(metadata)+1)
+	for k, v := range metadata {
+		m[k] = v
+	}
+	m[key] = value
+	return m
+}
@@ -6,11 +6,18 @@
 package esdb_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
 	"github.com/hallgren/eventsourcing"
 	es "github.com/hallgren/eventsourcing/eventstore/esdb"
+	"github.com/hallgren/eventsourcing/eventstore/signing"
 	"github.com/hallgren/eventsourcing/eventstore/suite"
 )
 
@@ -33,3 +40,203 @@ func TestSuite(t *testing.T) {
 	}
 	suite.Test[suite.FrequentFlierEvent](t, f)
 }
+
+// binaryPrefix is put before the json making the event data binary to event store db
+var binaryPrefix = []byte{0xff}
+
+func marshalBinary(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(binaryPrefix, b...), nil
+}
+
+func unmarshalBinary(data []byte, v any) error {
+	if !bytes.HasPrefix(data, binaryPrefix) {
+		return errors.New("event data is not binary")
+	}
+	return json.Unmarshal(data[len(binaryPrefix):], v)
+}
+
+func TestSuiteBinary(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		settings, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+		if err != nil {
+			return nil, nil, err
+		}
+
+		db, err := esdb.NewClient(settings)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		es := es.Open(db, ser, false, es.WithJSONMetadata())
+		return es, func() {
+		}, nil
+	}
+	suite.TestSerializer[suite.FrequentFlierEvent](t, f, marshalBinary, unmarshalBinary)
+}
+
+func TestSubscribeCategory(t *testing.T) {
+	settings, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := esdb.NewClient(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	store := es.Open(db, *ser, true)
+
+	aggregateID := suite.AggregateID()
+	err = store.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: aggregateID, Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: aggregateID}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errFound := errors.New("found")
+	err = store.SubscribeCategory(ctx, "FrequentFlierAccount", nil, func(event eventsourcing.Event[suite.FrequentFlierEvent], revision uint64) error {
+		// the resolved link maps back to the aggregate event
+		if event.AggregateID == aggregateID && event.AggregateType == "FrequentFlierAccount" && event.Version == 1 {
+			return errFound
+		}
+		return nil
+	})
+	if !errors.Is(err, errFound) {
+		t.Fatalf("expected the saved event from the category stream got %v", err)
+	}
+}
+
+func TestSubscribeCustomStreamName(t *testing.T) {
+	settings, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := esdb.NewClient(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	// a stream name the default parser splits wrong, the aggregate type and id are read from the metadata
+	store := es.Open(db, *ser, true, es.WithStreamName(func(aggregateType, aggregateID string) string {
+		return "billing-" + aggregateType + "-" + aggregateID
+	}))
+
+	aggregateID := suite.AggregateID()
+	err = store.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: aggregateID, Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: aggregateID}, Metadata: map[string]interface{}{"test": "hello"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	errFound := errors.New("found")
+	err = store.SubscribeAll(ctx, nil, func(event eventsourcing.Event[suite.FrequentFlierEvent], position esdb.Position) error {
+		if event.AggregateID == aggregateID && event.AggregateType == "FrequentFlierAccount" && len(event.Metadata) == 1 {
+			return errFound
+		}
+		return nil
+	})
+	if !errors.Is(err, errFound) {
+		t.Fatalf("expected the saved event with the aggregate from the metadata got %v", err)
+	}
+}
+
+func TestMigrateStream(t *testing.T) {
+	settings, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := esdb.NewClient(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	old := func(aggregateType, aggregateID string) string {
+		return "legacy_" + aggregateType + "_" + aggregateID
+	}
+	aggregateID := suite.AggregateID()
+	timestamp := time.Now().Add(-time.Hour).UTC()
+	err = es.Open(db, *ser, true, es.WithStreamName(old)).Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: aggregateID, Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: timestamp, Data: &suite.FrequentFlierAccountCreated{AccountId: aggregateID}},
+		{AggregateID: aggregateID, Version: 2, AggregateType: "FrequentFlierAccount", Timestamp: timestamp, Data: &suite.FlightTaken{MilesAdded: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store := es.Open(db, *ser, true)
+	moved, err := store.MigrateStream(context.Background(), "FrequentFlierAccount", aggregateID, old)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved != 2 {
+		t.Fatalf("expected two moved events got %d", moved)
+	}
+	it, err := store.Get(context.Background(), aggregateID, "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	event, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Version != 1 || event.Metadata != nil {
+		t.Fatalf("expected the first event without the migration metadata got %+v", event)
+	}
+	_, err = store.MigrateStream(context.Background(), "FrequentFlierAccount", aggregateID, old)
+	if err == nil {
+		t.Fatal("expected the old stream to be deleted")
+	}
+}
+
+func TestSigned(t *testing.T) {
+	settings, err := esdb.ParseConnectionString("esdb://localhost:2113?tls=false")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db, err := esdb.NewClient(settings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signing.New[suite.FrequentFlierEvent](es.Open(db, *ser, true), "k1", key, nil)
+	aggregateID := suite.AggregateID()
+	timestamp := time.Now().Add(-time.Hour)
+	err = signed.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: aggregateID, AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: timestamp, Data: &suite.FrequentFlierAccountCreated{AccountId: aggregateID, OpeningMiles: 10}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the timestamp is read back as saved and not as the created date of event store db
+	it, err := signed.Get(context.Background(), aggregateID, "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	event, err := it.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.Timestamp.Equal(timestamp) {
+		t.Fatalf("expected the saved timestamp %v got %v", timestamp, event.Timestamp)
+	}
+}
@@ -2,16 +2,21 @@ package esdb
 
 import (
 	"errors"
+	"fmt"
 	"io"
-	"strings"
+	"time"
 
 	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/gofrs/uuid"
 	"github.com/hallgren/eventsourcing"
 )
 
 type iterator[T any] struct {
-	stream     *esdb.ReadStream
-	serializer eventsourcing.Serializer[T]
+	stream            *esdb.ReadStream
+	serializer        eventsourcing.Serializer[T]
+	unmarshalMetadata func(data []byte, v any) error
+	aggregateType     string
+	aggregateID       string
 }
 
 // Close closes the stream
@@ -21,8 +26,6 @@ func (i *iterator[T]) Close() {
 
 // Next returns next event from the stream
 func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
-	var eventMetadata map[string]interface{}
-
 	eventESDB, err := i.stream.Recv()
 	if errors.Is(err, io.EOF) {
 		return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
@@ -36,32 +39,122 @@ func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
 		return eventsourcing.Event[T]{}, err
 	}
 
-	stream := strings.Split(eventESDB.Event.StreamID, streamSeparator)
-	f, ok := i.serializer.Type(stream[0], eventESDB.Event.EventType)
+	// the aggregate type and id is known from the Get call and not parsed from the stream name
+	// as the stream naming is configurable
+	event, ok, err := toEvent(i.serializer, i.unmarshalMetadata, eventESDB.Event, i.aggregateType, i.aggregateID)
+	if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
 	if !ok {
 		// if the typ/reason is not register jump over the event
 		return i.Next()
 	}
-	eventData := f()
-	err = i.serializer.Unmarshal(eventESDB.Event.Data, &eventData)
-	if err != nil {
-		return eventsourcing.Event[T]{}, err
-	}
-	if eventESDB.Event.UserMetadata != nil {
-		err = i.serializer.Unmarshal(eventESDB.Event.UserMetadata, &eventMetadata)
+	return event, nil
+}
+
+// toEvent maps the recorded event to an event, ok is false if the event type is not registered in the serializer or
+// the event is skipped
+func toEvent[T any](serializer eventsourcing.Serializer[T], unmarshalMetadata func(data []byte, v any) error, recorded *esdb.RecordedEvent, aggregateType, aggregateID string) (eventsourcing.Event[T], bool, error) {
+	var eventMetadata map[string]interface{}
+	if recorded.UserMetadata != nil {
+		err := unmarshalMetadata(recorded.UserMetadata, &eventMetadata)
 		if err != nil {
-			return eventsourcing.Event[T]{}, err
+			return eventsourcing.Event[T]{}, false, err
 		}
 	}
+	validTime, eventMetadata, err := extractValidTime(eventMetadata)
+	if err != nil {
+		return eventsourcing.Event[T]{}, false, err
+	}
+	eventID, eventMetadata := extractEventID(eventMetadata, recorded.EventID)
+	aggregateType, aggregateID, eventMetadata = extractAggregate(eventMetadata, aggregateType, aggregateID)
+	timestamp, eventMetadata, err := extractTimestamp(eventMetadata, recorded.CreatedDate)
+	if err != nil {
+		return eventsourcing.Event[T]{}, false, err
+	}
+	if recorded.EventNumber >= uint64(eventsourcing.MaxVersion) {
+		return eventsourcing.Event[T]{}, false, fmt.Errorf("%w: event number %d", eventsourcing.ErrVersionOverflow, recorded.EventNumber)
+	}
 	event := eventsourcing.Event[T]{
-		AggregateID:   stream[1],
-		Version:       eventsourcing.Version(eventESDB.Event.EventNumber) + 1, // +1 as the eventsourcing Version starts on 1 but the esdb event version starts on 0
-		AggregateType: stream[0],
-		Timestamp:     eventESDB.Event.CreatedDate,
-		Data:          eventData,
+		EventID:       eventID,
+		AggregateID:   aggregateID,
+		Version:       eventsourcing.Version(recorded.EventNumber) + 1, // +1 as the eventsourcing Version starts on 1 but the esdb event version starts on 0
+		AggregateType: aggregateType,
+		Timestamp:     timestamp,
+		ValidTime:     validTime,
 		Metadata:      eventMetadata,
 		// Can't get the global version when using the ReadStream method
 		//GlobalVersion: eventsourcing.Version(event.Event.Position.Commit),
 	}
-	return event, nil
+	ok, err := serializer.UnmarshalEvent(&event, recorded.EventType, recorded.Data)
+	return event, ok, err
+}
+
+// extractEventID removes the event id from the metadata and returns it, the event store db event id if it's not set
+func extractEventID(metadata map[string]interface{}, recorded uuid.UUID) (string, map[string]interface{}) {
+	value, ok := metadata[eventIDKey].(string)
+	if !ok {
+		return recorded.String(), metadata
+	}
+	delete(metadata, eventIDKey)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return value, metadata
+}
+
+// extractAggregate removes the aggregate type and id from the metadata and returns them, the type and id from the
+// stream name for events saved before they were stored in the metadata
+func extractAggregate(metadata map[string]interface{}, aggregateType, aggregateID string) (string, string, map[string]interface{}) {
+	typ, okType := metadata[aggregateTypeKey].(string)
+	id, okID := metadata[aggregateIDKey].(string)
+	if !okType || !okID {
+		return aggregateType, aggregateID, metadata
+	}
+	delete(metadata, aggregateTypeKey)
+	delete(metadata, aggregateIDKey)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	return typ, id, metadata
+}
+
+// extractTimestamp removes the timestamp of the event from the metadata and returns it, the created date of events
+// saved without it
+func extractTimestamp(metadata map[string]interface{}, created time.Time) (time.Time, map[string]interface{}, error) {
+	value, ok := metadata[timestampKey]
+	if !ok {
+		return created, metadata, nil
+	}
+	delete(metadata, timestampKey)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, nil, fmt.Errorf("timestamp %v is not a string", value)
+	}
+	t, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return time.Time{}, nil, fmt.Errorf("timestamp: %w", err)
+	}
+	return t, metadata, nil
+}
+
+// extractValidTime removes the valid time from the metadata and returns it
+func extractValidTime(metadata map[string]interface{}) (time.Time, map[string]interface{}, error) {
+	value, ok := metadata[validTimeKey]
+	if !ok {
+		return time.Time{}, metadata, nil
+	}
+	delete(metadata, validTimeKey)
+	if len(metadata) == 0 {
+		metadata = nil
+	}
+	s, ok := value.(string)
+	if !ok {
+		return time.Time{}, metadata, fmt.Errorf("valid time %v is not a string", value)
+	}
+	validTime, err := time.Parse(time.RFC3339Nano, s)
+	return validTime, metadata, err
 }
@@ -0,0 +1,103 @@
+package esdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/hallgren/eventsourcing"
+)
+
+// MigrateStream moves the events of the aggregate from the stream named by from to the stream named by the stream
+// name function of the event store, to change the stream naming of existing aggregates. The events keep their event
+// id, type, data and timestamp and get the aggregate type and id in their metadata. The old stream is deleted after
+// the events are appended, if events are saved to it during the move eventsourcing.ErrStreamChanged is returned and
+// the old stream is kept, delete the new stream and migrate again. It returns the number of moved events.
+//
+// Stop the writers of the aggregate while it's moved and switch them to the new stream names after.
+func (es *ESDB[T]) MigrateStream(ctx context.Context, aggregateType, id string, from StreamNameFunc) (int, error) {
+	source := from(aggregateType, id)
+	target := es.streamName(aggregateType, id)
+	if source == target {
+		return 0, nil
+	}
+	stream, err := es.client.ReadStream(ctx, source, esdb.ReadStreamOptions{From: esdb.Start{}}, ^uint64(0))
+	es.connection.observe(err)
+	if err != nil {
+		return 0, err
+	}
+	defer stream.Close()
+	var events []esdb.EventData
+	var last uint64
+	for {
+		resolved, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if esdbErr, ok := esdb.FromError(err); !ok && esdbErr.Code() == esdb.ErrorCodeResourceNotFound {
+			return 0, eventsourcing.ErrNoEvents
+		}
+		if err != nil {
+			return 0, err
+		}
+		recorded := resolved.Event
+		data, err := es.moved(recorded, aggregateType, id)
+		if err != nil {
+			return 0, fmt.Errorf("migrate %s version %d: %w", source, recorded.EventNumber+1, err)
+		}
+		events = append(events, data)
+		last = recorded.EventNumber
+	}
+	if len(events) == 0 {
+		return 0, eventsourcing.ErrNoEvents
+	}
+	_, err = es.client.AppendToStream(ctx, target, esdb.AppendToStreamOptions{ExpectedRevision: esdb.NoStream{}}, events...)
+	es.connection.observe(err)
+	if err != nil {
+		return 0, err
+	}
+	_, err = es.client.DeleteStream(ctx, source, esdb.DeleteStreamOptions{ExpectedRevision: esdb.Revision(last)})
+	es.connection.observe(err)
+	if esdbErr, ok := esdb.FromError(err); !ok && esdbErr.Code() == esdb.ErrorCodeWrongExpectedVersion {
+		return len(events), fmt.Errorf("%w: %s", eventsourcing.ErrStreamChanged, source)
+	}
+	if err != nil {
+		return len(events), err
+	}
+	return len(events), nil
+}
+
+// moved returns the recorded event to append to the new stream, with the aggregate type, id and original timestamp
+// set in its metadata
+func (es *ESDB[T]) moved(recorded *esdb.RecordedEvent, aggregateType, id string) (esdb.EventData, error) {
+	var metadata map[string]interface{}
+	if len(recorded.UserMetadata) > 0 {
+		err := es.unmarshalMetadata(recorded.UserMetadata, &metadata)
+		if err != nil {
+			return esdb.EventData{}, err
+		}
+	}
+	metadata = withMetadata(metadata, aggregateTypeKey, aggregateType)
+	metadata[aggregateIDKey] = id
+	if _, ok := metadata[timestampKey]; !ok {
+		metadata[timestampKey] = recorded.CreatedDate.UTC().Format(time.RFC3339Nano)
+	}
+	m, err := es.marshalMetadata(metadata)
+	if err != nil {
+		return esdb.EventData{}, err
+	}
+	contentType := esdb.ContentTypeBinary
+	if recorded.ContentType == "application/json" {
+		contentType = esdb.ContentTypeJson
+	}
+	return esdb.EventData{
+		EventID:     recorded.EventID,
+		ContentType: contentType,
+		EventType:   recorded.EventType,
+		Data:        recorded.Data,
+		Metadata:    m,
+	}, nil
+}
@@ -0,0 +1,164 @@
+package esdb
+
+import (
+	"context"
+	"strings"
+
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/hallgren/eventsourcing"
+)
+
+const categoryStreamPrefix = "$ce-"
+
+// StreamParserFunc parses the aggregate type and id from a stream name, ok is false if the stream is not an
+// aggregate stream
+type StreamParserFunc func(stream string) (aggregateType, aggregateID string, ok bool)
+
+// WithStreamParser sets how the aggregate type and id are parsed from stream names in subscriptions, it should be the
+// inverse of the stream name function. Default splits the stream name on the first "-". The parser is only needed for
+// events saved before the aggregate type and id were stored in the event metadata.
+func WithStreamParser(f StreamParserFunc) Option {
+	return func(o *options) {
+		o.streamParser = f
+	}
+}
+
+// SubscribeCategory subscribes to the events of the aggregate type via the category stream $ce-<aggregateType>.
+// The links in the category stream are resolved to the aggregate events. after is the category stream revision of
+// the last handled event, nil starts from the beginning. The handler gets the event and its revision in the category
+// stream to checkpoint on. It blocks until the context is done, the subscription is dropped or the handler fails.
+//
+// The category stream is built by the $by_category system projection, it has to be enabled in event store db.
+func (es *ESDB[T]) SubscribeCategory(ctx context.Context, aggregateType string, after *uint64, handler func(event eventsourcing.Event[T], revision uint64) error) error {
+	return es.subscribe(ctx, func() error {
+		var from esdb.StreamPosition = esdb.Start{}
+		if after != nil {
+			from = esdb.Revision(*after)
+		}
+		sub, err := es.client.SubscribeToStream(ctx, categoryStreamPrefix+aggregateType, esdb.SubscribeToStreamOptions{
+			From:           from,
+			ResolveLinkTos: true,
+		})
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+		return es.receive(ctx, sub, func(resolved *esdb.ResolvedEvent, event eventsourcing.Event[T]) error {
+			revision := resolved.OriginalEvent().EventNumber
+			err := handler(event, revision)
+			if err == nil {
+				// continue after the handled event if the subscription is re-established
+				after = &revision
+			}
+			return err
+		})
+	})
+}
+
+// SubscribeAll subscribes to the aggregate events in $all, system events are filtered out. after is the position of
+// the last handled event, nil starts from the beginning. The handler gets the event, with the global version set to
+// the commit position, and its position in $all to checkpoint on.
+func (es *ESDB[T]) SubscribeAll(ctx context.Context, after *esdb.Position, handler func(event eventsourcing.Event[T], position esdb.Position) error) error {
+	return es.subscribe(ctx, func() error {
+		var from esdb.AllPosition = esdb.Start{}
+		if after != nil {
+			from = *after
+		}
+		sub, err := es.client.SubscribeToAll(ctx, esdb.SubscribeToAllOptions{
+			From:           from,
+			ResolveLinkTos: true,
+			Filter:         esdb.ExcludeSystemEventsFilter(),
+		})
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+		return es.receive(ctx, sub, func(resolved *esdb.ResolvedEvent, event eventsourcing.Event[T]) error {
+			position := resolved.OriginalEvent().Position
+			err := handler(event, position)
+			if err == nil {
+				after = &position
+			}
+			return err
+		})
+	})
+}
+
+// SubscribeAggregate subscribes to the stream of the aggregate, delivering its events after fromVersion and the
+// events appended after the call
+func (es *ESDB[T]) SubscribeAggregate(ctx context.Context, aggregateType, id string, fromVersion eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) error {
+	return es.subscribe(ctx, func() error {
+		var from esdb.StreamPosition = esdb.Start{}
+		if fromVersion > 0 {
+			// the esdb revision starts on 0 and the version on 1
+			from = esdb.Revision(uint64(fromVersion) - 1)
+		}
+		sub, err := es.client.SubscribeToStream(ctx, es.streamName(aggregateType, id), esdb.SubscribeToStreamOptions{From: from})
+		if err != nil {
+			return err
+		}
+		defer sub.Close()
+		return es.receive(ctx, sub, func(resolved *esdb.ResolvedEvent, event eventsourcing.Event[T]) error {
+			err := handler(event)
+			if err == nil {
+				fromVersion = event.Version
+			}
+			return err
+		})
+	})
+}
+
+// receive maps the resolved events of the subscription to aggregate events and pass them to the handler
+func (es *ESDB[T]) receive(ctx context.Context, sub *esdb.Subscription, handler func(resolved *esdb.ResolvedEvent, event eventsourcing.Event[T]) error) error {
+	es.connection.observe(nil)
+	for {
+		msg := sub.Recv()
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if msg.SubscriptionDropped != nil {
+			return msg.SubscriptionDropped.Error
+		}
+		resolved := msg.EventAppeared
+		if resolved == nil || resolved.Event == nil {
+			// checkpoints and links to deleted events
+			continue
+		}
+		// the resolved event is the event in the aggregate stream, the link is the event in the category stream
+		recorded := resolved.Event
+		if strings.HasPrefix(recorded.EventType, "$") {
+			continue
+		}
+		// the aggregate type and id in the metadata take precedence over the ones parsed from the stream name
+		aggregateType, aggregateID, parsed := es.streamParser(recorded.StreamID)
+		event, ok, err := toEvent(es.serializer, es.unmarshalMetadata, recorded, aggregateType, aggregateID)
+		if err != nil {
+			if !parsed {
+				// not an aggregate stream
+				continue
+			}
+			return permanentError{err}
+		}
+		if !ok || event.AggregateType == "" {
+			// if the typ/reason is not register jump over the event
+			continue
+		}
+		event.GlobalVersion = eventsourcing.Version(recorded.Position.Commit)
+		err = handler(resolved, event)
+		if err != nil {
+			return permanentError{err}
+		}
+	}
+}
+
+// parseStream is the default stream parser, the inverse of the default stream name
+func parseStream(stream string) (string, string, bool) {
+	if strings.HasPrefix(stream, "$") {
+		return "", "", false
+	}
+	parts := strings.SplitN(stream, streamSeparator, 2)
+	if len(parts) != 2 {
+		return "", "", false
+	}
+	return parts[0], parts[1], true
+}
@@ -0,0 +1,47 @@
+package esdb
+
+import (
+	"errors"
+
+	"github.com/EventStore/EventStore-Client-Go/v3/esdb"
+	"github.com/hallgren/eventsourcing/eventstore/retry"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// IsTransient classifies errors from the event store db event store for the retry decorator
+func IsTransient(err error) bool {
+	if unavailable(err) {
+		return true
+	}
+	var esdbErr *esdb.Error
+	if errors.As(err, &esdbErr) {
+		switch esdbErr.Code() {
+		case esdb.ErrorCodeDeadlineExceeded, esdb.ErrorCodeConnectionClosed, esdb.ErrorCodeNotLeader:
+			return true
+		}
+		return false
+	}
+	return retry.IsTransient(err)
+}
+
+// IsNotCommitted classifies the errors from the event store db event store where the events are known not to be
+// saved, for the save classifier of the retry decorator. The server was not reached or the write was rejected by a
+// follower node, deadlines and closed connections can happen after the write is committed.
+func IsNotCommitted(err error) bool {
+	if unavailable(err) {
+		return true
+	}
+	var esdbErr *esdb.Error
+	if errors.As(err, &esdbErr) {
+		return esdbErr.Code() == esdb.ErrorCodeNotLeader
+	}
+	return retry.IsNotCommitted(err)
+}
+
+// unavailable returns true if the server could not be reached, the client returns it with an unknown error code
+// when the node discovery fails
+func unavailable(err error) bool {
+	var grpcErr interface{ GRPCStatus() *status.Status }
+	return errors.As(err, &grpcErr) && grpcErr.GRPCStatus().Code() == codes.Unavailable
+}
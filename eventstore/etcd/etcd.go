@@ -0,0 +1,197 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// defaultMaxTxnOps is the default --max-txn-ops of the etcd server
+const defaultMaxTxnOps = 128
+
+// ErrTooManyEvents is returned when the events and the aggregate version don't fit in one etcd transaction
+var ErrTooManyEvents = errors.New("too many events in one save")
+
+// Etcd is the event store handler
+type Etcd[T any] struct {
+	client     *clientv3.Client
+	serializer eventsourcing.Serializer[T]
+	prefix     string
+	maxTxnOps  int
+}
+
+// Option configures the etcd event store
+type Option func(*options)
+
+type options struct {
+	prefix    string
+	maxTxnOps int
+}
+
+// WithPrefix sets a prefix on all keys written by the event store.
+// Makes it possible to share the etcd cluster with the configuration it already holds.
+func WithPrefix(prefix string) Option {
+	return func(o *options) {
+		o.prefix = prefix
+	}
+}
+
+// WithMaxTxnOps sets the max number of operations in one transaction, it has to match the --max-txn-ops of the
+// etcd server. Default is 128.
+func WithMaxTxnOps(ops int) Option {
+	return func(o *options) {
+		o.maxTxnOps = ops
+	}
+}
+
+type etcdEvent struct {
+	EventID       string
+	AggregateID   string
+	Version       uint64
+	Reason        string
+	AggregateType string
+	Timestamp     time.Time
+	ValidTime     time.Time
+	Data          []byte
+	Metadata      map[string]interface{}
+}
+
+// Open binds the etcd client
+func Open[T any](client *clientv3.Client, serializer eventsourcing.Serializer[T], opts ...Option) *Etcd[T] {
+	o := options{maxTxnOps: defaultMaxTxnOps}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Etcd[T]{
+		client:     client,
+		serializer: serializer,
+		prefix:     o.prefix,
+		maxTxnOps:  o.maxTxnOps,
+	}
+}
+
+// Save persists events to etcd. The events and the aggregate version key are written in one transaction comparing
+// the mod revision of the version key with the one read, a concurrent save of the same aggregate makes it fail.
+func (e *Etcd[T]) Save(events []eventsourcing.Event[T]) error {
+	// If no event return no error
+	if len(events) == 0 {
+		return nil
+	}
+	if len(events)+1 > e.maxTxnOps {
+		return fmt.Errorf("%w: %d events, the transaction holds at most %d operations", ErrTooManyEvents, len(events), e.maxTxnOps)
+	}
+	ctx := context.Background()
+	aggregateID := events[0].AggregateID
+	aggregateType := events[0].AggregateType
+	versionKey := e.versionKey(aggregateType, aggregateID)
+
+	res, err := e.client.Get(ctx, versionKey)
+	if err != nil {
+		return err
+	}
+	var currentVersion eventsourcing.Version
+	var modRevision int64
+	if len(res.Kvs) > 0 {
+		v, err := strconv.ParseUint(string(res.Kvs[0].Value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("could not parse aggregate version, %v", err)
+		}
+		currentVersion = eventsourcing.Version(v)
+		modRevision = res.Kvs[0].ModRevision
+	}
+
+	err = eventstore.ValidateEvents(aggregateID, currentVersion, events)
+	if err != nil {
+		return err
+	}
+
+	ops := make([]clientv3.Op, 0, len(events)+1)
+	for _, event := range events {
+		data, err := e.serializer.MarshalEvent(event)
+		if err != nil {
+			return fmt.Errorf("could not serialize event data, %w", err)
+		}
+		value, err := e.serializer.Marshal(etcdEvent{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       uint64(event.Version),
+			Reason:        event.Reason(),
+			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
+			Metadata:      event.Metadata,
+			Data:          data,
+		})
+		if err != nil {
+			return fmt.Errorf("could not serialize event, %v", err)
+		}
+		ops = append(ops, clientv3.OpPut(e.eventKey(aggregateType, aggregateID, event.Version), string(value)))
+	}
+	lastVersion := events[len(events)-1].Version
+	ops = append(ops, clientv3.OpPut(versionKey, strconv.FormatUint(uint64(lastVersion), 10)))
+
+	txn, err := e.client.Txn(ctx).
+		If(clientv3.Compare(clientv3.ModRevision(versionKey), "=", modRevision)).
+		Then(ops...).
+		Commit()
+	if err != nil {
+		return err
+	}
+	if !txn.Succeeded {
+		return eventstore.ErrConcurrency
+	}
+	for i := range events {
+		// Set all events GlobalVersion to the revision of the transaction.
+		events[i].GlobalVersion = eventsourcing.Version(txn.Header.Revision)
+	}
+	return nil
+}
+
+// Get the events from etcd
+func (e *Etcd[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	if afterVersion >= eventsourcing.MaxVersion {
+		return nil, fmt.Errorf("%w: after version %d", eventsourcing.ErrVersionOverflow, afterVersion)
+	}
+	prefix := e.aggregatePrefix(aggregateType, id)
+	res, err := e.client.Get(ctx, e.eventKey(aggregateType, id, afterVersion+1), clientv3.WithRange(clientv3.GetPrefixRangeEnd(prefix)))
+	if err != nil {
+		return nil, err
+	}
+	return &iterator[T]{kvs: res.Kvs, serializer: e.serializer}, nil
+}
+
+// Ordering returns the global order guarantees of the etcd event store. The global version is the etcd revision of
+// the write, it's shared by all events in the write and other keys in the cluster bump it too.
+func (e *Etcd[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{GlobalVersionOnGet: true}
+}
+
+// Capabilities returns the optional features of the etcd event store
+func (e *Etcd[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{Subscriptions: true}
+}
+
+func (e *Etcd[T]) eventsPrefix() string {
+	return e.prefix + "events/"
+}
+
+// aggregatePrefix escapes the aggregate id as it's part of the key path
+func (e *Etcd[T]) aggregatePrefix(aggregateType, aggregateID string) string {
+	return e.eventsPrefix() + aggregateType + "/" + url.PathEscape(aggregateID) + "/"
+}
+
+// eventKey pads the version making the keys sort in version order
+func (e *Etcd[T]) eventKey(aggregateType, aggregateID string, version eventsourcing.Version) string {
+	return fmt.Sprintf("%s%020d", e.aggregatePrefix(aggregateType, aggregateID), version)
+}
+
+func (e *Etcd[T]) versionKey(aggregateType, aggregateID string) string {
+	return e.prefix + "versions/" + aggregateType + "/" + url.PathEscape(aggregateID)
+}
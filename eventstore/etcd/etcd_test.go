@@ -0,0 +1,75 @@
+//go:build manual
+// +build manual
+
+// make these tests manual as they are dependent on a running etcd.
+
+package etcd_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/etcd"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+func client() (*clientv3.Client, error) {
+	return clientv3.New(clientv3.Config{Endpoints: []string{"localhost:2379"}, DialTimeout: 5 * time.Second})
+}
+
+func TestSuite(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		c, err := client()
+		if err != nil {
+			return nil, nil, err
+		}
+		es := etcd.Open(c, ser, etcd.WithPrefix("test/"))
+		return es, func() {
+			c.Close()
+		}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestSubscribe(t *testing.T) {
+	c, err := client()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	store := etcd.Open(c, *ser, etcd.WithPrefix("test/"))
+
+	aggregateID := suite.AggregateID()
+	events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: aggregateID, Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: aggregateID}},
+		{AggregateID: aggregateID, Version: 2, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 10}},
+	}
+	err = store.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	after := int64(events[0].GlobalVersion) - 1
+	err = store.Subscribe(ctx, "FrequentFlierAccount", after, func(handled []eventsourcing.Event[suite.FrequentFlierEvent], revision int64) error {
+		if len(handled) != 2 {
+			t.Fatalf("expected the two events saved together got %d", len(handled))
+		}
+		if revision != int64(events[0].GlobalVersion) {
+			t.Fatalf("expected revision %d got %d", events[0].GlobalVersion, revision)
+		}
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled got %v", err)
+	}
+}
@@ -0,0 +1,58 @@
+package etcd
+
+import (
+	"fmt"
+
+	"github.com/hallgren/eventsourcing"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+)
+
+type iterator[T any] struct {
+	kvs        []*mvccpb.KeyValue
+	serializer eventsourcing.Serializer[T]
+}
+
+// Next return the next event
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	if len(i.kvs) == 0 {
+		return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
+	}
+	kv := i.kvs[0]
+	i.kvs = i.kvs[1:]
+	event, ok, err := toEvent(i.serializer, kv)
+	if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+	if !ok {
+		// if the typ/reason is not register jump over the event
+		return i.Next()
+	}
+	return event, nil
+}
+
+// Close closes the iterator
+func (i *iterator[T]) Close() {
+	i.kvs = nil
+}
+
+// toEvent deserializes the stored event, ok is false when the event type is not registered in the serializer or
+// the event is skipped
+func toEvent[T any](serializer eventsourcing.Serializer[T], kv *mvccpb.KeyValue) (eventsourcing.Event[T], bool, error) {
+	eEvent := etcdEvent{}
+	err := serializer.Unmarshal(kv.Value, &eEvent)
+	if err != nil {
+		return eventsourcing.Event[T]{}, false, fmt.Errorf("could not deserialize event, %v", err)
+	}
+	event := eventsourcing.Event[T]{
+		EventID:       eEvent.EventID,
+		AggregateID:   eEvent.AggregateID,
+		AggregateType: eEvent.AggregateType,
+		Version:       eventsourcing.Version(eEvent.Version),
+		GlobalVersion: eventsourcing.Version(kv.ModRevision),
+		Timestamp:     eEvent.Timestamp,
+		ValidTime:     eEvent.ValidTime,
+		Metadata:      eEvent.Metadata,
+	}
+	ok, err := serializer.UnmarshalEvent(&event, eEvent.Reason, eEvent.Data)
+	return event, ok, err
+}
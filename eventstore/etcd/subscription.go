@@ -0,0 +1,66 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/hallgren/eventsourcing"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+)
+
+// ErrWatchClosed is returned when etcd closes the watch without the context being done
+var ErrWatchClosed = errors.New("watch closed")
+
+// Subscribe watches the events of the aggregate type, an empty aggregate type watches all events. after is the etcd
+// revision of the last handled write, zero starts from the first revision still kept by etcd. The handler gets the
+// events saved together and their revision to checkpoint on. It blocks until the context is done, the watch is closed
+// or the handler fails.
+//
+// Revisions older than the last compaction can't be watched, the subscription then fails with the compacted error
+// from etcd and the projection has to be rebuilt from Get.
+func (e *Etcd[T]) Subscribe(ctx context.Context, aggregateType string, after int64, handler func(events []eventsourcing.Event[T], revision int64) error) error {
+	prefix := e.eventsPrefix()
+	if aggregateType != "" {
+		prefix += aggregateType + "/"
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	watch := e.client.Watch(clientv3.WithRequireLeader(ctx), prefix, clientv3.WithPrefix(), clientv3.WithRev(after+1))
+	for res := range watch {
+		if err := res.Err(); err != nil {
+			return err
+		}
+		// the events of one write share the revision and are never split between watch responses
+		var events []eventsourcing.Event[T]
+		var revision int64
+		for _, ev := range res.Events {
+			if ev.Type != mvccpb.PUT {
+				continue
+			}
+			if len(events) > 0 && ev.Kv.ModRevision != revision {
+				if err := handler(events, revision); err != nil {
+					return err
+				}
+				events = nil
+			}
+			revision = ev.Kv.ModRevision
+			event, ok, err := toEvent(e.serializer, ev.Kv)
+			if err != nil {
+				return err
+			}
+			if ok {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 0 {
+			if err := handler(events, revision); err != nil {
+				return err
+			}
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrWatchClosed
+}
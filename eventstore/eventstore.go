@@ -2,6 +2,7 @@ package eventstore
 
 import (
 	"errors"
+	"fmt"
 
 	"github.com/hallgren/eventsourcing"
 )
@@ -18,6 +19,32 @@ var ErrConcurrency = errors.New("concurrency error")
 // ErrReasonMissing when the reason is not present in the events
 var ErrReasonMissing = errors.New("event holds no reason")
 
+// ErrAggregateSavedTwice when the events of one aggregate are split over several slices in a bulk save
+var ErrAggregateSavedTwice = errors.New("events for the same aggregate in more than one slice")
+
+// aggregate identifies an aggregate by its type and id
+type aggregate struct {
+	typ string
+	id  string
+}
+
+// ValidateBulk make sure each aggregate is only present once in a bulk save, the events of each aggregate are
+// validated with ValidateEvents against its current version
+func ValidateBulk[T any](events [][]eventsourcing.Event[T]) error {
+	seen := make(map[aggregate]struct{}, len(events))
+	for _, e := range events {
+		if len(e) == 0 {
+			continue
+		}
+		key := aggregate{typ: e[0].AggregateType, id: e[0].AggregateID}
+		if _, ok := seen[key]; ok {
+			return fmt.Errorf("%w: %s %s", ErrAggregateSavedTwice, e[0].AggregateType, e[0].AggregateID)
+		}
+		seen[key] = struct{}{}
+	}
+	return nil
+}
+
 // ValidateEvents make sure the incoming events are valid
 func ValidateEvents[T any](aggregateID string, currentVersion eventsourcing.Version, events []eventsourcing.Event[T]) error {
 	aggregateType := events[0].AggregateType
@@ -31,6 +58,10 @@ func ValidateEvents[T any](aggregateID string, currentVersion eventsourcing.Vers
 			return ErrEventMultipleAggregateTypes
 		}
 
+		if event.Version > eventsourcing.MaxVersion {
+			return fmt.Errorf("%w: event version %d", eventsourcing.ErrVersionOverflow, event.Version)
+		}
+
 		if currentVersion+1 != event.Version {
 			return ErrConcurrency
 		}
@@ -39,6 +70,40 @@ func ValidateEvents[T any](aggregateID string, currentVersion eventsourcing.Vers
 			return ErrReasonMissing
 		}
 
+		if err := eventsourcing.ValidateEventData(event.Data); err != nil {
+			return err
+		}
+
+		currentVersion = event.Version
+	}
+	return nil
+}
+
+// ValidateRawEvents make sure the incoming raw events are valid, the data is already marshaled and not validated
+func ValidateRawEvents(aggregateID string, currentVersion eventsourcing.Version, events []eventsourcing.RawEvent) error {
+	aggregateType := events[0].AggregateType
+
+	for _, event := range events {
+		if event.AggregateID != aggregateID {
+			return ErrEventMultipleAggregates
+		}
+
+		if event.AggregateType != aggregateType {
+			return ErrEventMultipleAggregateTypes
+		}
+
+		if event.Version > eventsourcing.MaxVersion {
+			return fmt.Errorf("%w: event version %d", eventsourcing.ErrVersionOverflow, event.Version)
+		}
+
+		if currentVersion+1 != event.Version {
+			return ErrConcurrency
+		}
+
+		if event.Reason == "" {
+			return ErrReasonMissing
+		}
+
 		currentVersion = event.Version
 	}
 	return nil
@@ -47,6 +112,10 @@ func ValidateEvents[T any](aggregateID string, currentVersion eventsourcing.Vers
 // ValidateEventsNoVersionCheck make sure the incoming events are valid
 func ValidateEventsNoVersionCheck[T any](aggregateID string, events []eventsourcing.Event[T]) error {
 	aggregateType := events[0].AggregateType
+	if events[0].Version == 0 {
+		// versions start on 1, the current version below would wrap around
+		return ErrConcurrency
+	}
 	currentVersion := events[0].Version - 1
 
 	for _, event := range events {
@@ -58,12 +127,20 @@ func ValidateEventsNoVersionCheck[T any](aggregateID string, events []eventsourc
 			return ErrEventMultipleAggregateTypes
 		}
 
+		if event.Version > eventsourcing.MaxVersion {
+			return fmt.Errorf("%w: event version %d", eventsourcing.ErrVersionOverflow, event.Version)
+		}
+
 		if currentVersion+1 != event.Version {
 			return ErrConcurrency
 		}
 		if event.Reason() == "" {
 			return ErrReasonMissing
 		}
+
+		if err := eventsourcing.ValidateEventData(event.Data); err != nil {
+			return err
+		}
 		currentVersion = event.Version
 	}
 	return nil
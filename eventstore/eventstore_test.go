@@ -0,0 +1,24 @@
+package eventstore_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+)
+
+func TestValidateBulk(t *testing.T) {
+	event := func(aggregateType, id string) []eventsourcing.Event[any] {
+		return []eventsourcing.Event[any]{{AggregateType: aggregateType, AggregateID: id, Version: 1}}
+	}
+	// the type and id are not joined to a key, "a_b" "c" and "a" "b_c" are different aggregates
+	err := eventstore.ValidateBulk([][]eventsourcing.Event[any]{event("a_b", "c"), event("a", "b_c")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = eventstore.ValidateBulk([][]eventsourcing.Event[any]{event("a", "b"), event("a", "b")})
+	if !errors.Is(err, eventstore.ErrAggregateSavedTwice) {
+		t.Fatalf("expected ErrAggregateSavedTwice got %v", err)
+	}
+}
@@ -0,0 +1,290 @@
+package failover
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+)
+
+// ErrConflict is returned from Reconcile when the primary got events the secondary don't know about, the queued
+// events are kept until the conflict is resolved by hand
+var ErrConflict = errors.New("primary and secondary event store have diverged")
+
+// Hooks are called on events in the decorator, used to collect metrics and alert
+type Hooks struct {
+	// OnFailover is called when a call to the primary failed and the secondary is used instead
+	OnFailover func(op string, err error)
+	// OnReconciled is called when the queued events of an aggregate are written to the primary
+	OnReconciled func(aggregateType, aggregateID string, events int)
+	// OnReconcileError is called when Run fails to reconcile
+	OnReconcileError func(err error)
+}
+
+// Option configures the failover decorator
+type Option func(*options)
+
+type options struct {
+	queueWrites bool
+	hooks       Hooks
+	isFailure   func(err error) bool
+}
+
+// WithQueuedWrites saves to the secondary when the primary fails to save, the events are queued and written to the
+// primary by Reconcile when it recovers. Without it saves fail when the primary fails.
+func WithQueuedWrites() Option {
+	return func(o *options) {
+		o.queueWrites = true
+	}
+}
+
+// WithHooks sets the hooks
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithFailure sets the function deciding if an error from the primary makes the call fail over.
+// By default validation, concurrency and no events errors don't fail over.
+func WithFailure(isFailure func(err error) bool) Option {
+	return func(o *options) {
+		o.isFailure = isFailure
+	}
+}
+
+// Failover decorates a primary event store failing over to a secondary during outages. The secondary is expected to
+// be a replica of the primary kept up to date outside the decorator, queued writes are only possible on aggregates
+// the secondary holds all events of.
+//
+// The queue of writes to reconcile is kept in memory, events queued when the process stops stay in the secondary but
+// have to be copied to the primary by hand.
+type Failover[T any] struct {
+	primary   eventsourcing.EventStore[T]
+	secondary eventsourcing.EventStore[T]
+	options   options
+
+	lock    sync.Mutex
+	pending map[aggregate]queue
+}
+
+// queue holds the versions of an aggregate with queued writes
+type queue struct {
+	version eventsourcing.Version // the version of the aggregate in the primary
+	last    eventsourcing.Version // the version of the last event queued in the secondary
+}
+
+type aggregate struct {
+	typ string
+	id  string
+}
+
+// New decorates the primary event store
+func New[T any](primary, secondary eventsourcing.EventStore[T], opts ...Option) *Failover[T] {
+	o := options{isFailure: isFailure}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Failover[T]{
+		primary:   primary,
+		secondary: secondary,
+		options:   o,
+		pending:   make(map[aggregate]queue),
+	}
+}
+
+// Save saves the events to the primary. Events of aggregates with queued writes are saved to the secondary until
+// they are reconciled, keeping the versions in order.
+func (f *Failover[T]) Save(events []eventsourcing.Event[T]) error {
+	if len(events) == 0 {
+		return nil
+	}
+	a := aggregate{typ: events[0].AggregateType, id: events[0].AggregateID}
+	f.lock.Lock()
+	if q, ok := f.pending[a]; ok {
+		defer f.lock.Unlock()
+		err := f.secondary.Save(events)
+		if err != nil {
+			return err
+		}
+		q.last = events[len(events)-1].Version
+		f.pending[a] = q
+		return nil
+	}
+	f.lock.Unlock()
+
+	err := f.primary.Save(events)
+	if err == nil || !f.options.queueWrites || !f.options.isFailure(err) {
+		return err
+	}
+	f.failover("save", err)
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	err = f.secondary.Save(events)
+	if err != nil {
+		return err
+	}
+	q, ok := f.pending[a]
+	if !ok {
+		q.version = events[0].Version - 1
+	}
+	q.last = events[len(events)-1].Version
+	f.pending[a] = q
+	return nil
+}
+
+// Get gets the events from the primary and from the secondary if the primary fails or the aggregate has queued writes
+func (f *Failover[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	f.lock.Lock()
+	_, ok := f.pending[aggregate{typ: aggregateType, id: id}]
+	f.lock.Unlock()
+	if ok {
+		return f.secondary.Get(ctx, id, aggregateType, afterVersion)
+	}
+	iterator, err := f.primary.Get(ctx, id, aggregateType, afterVersion)
+	if err == nil || !f.options.isFailure(err) {
+		return iterator, err
+	}
+	f.failover("get", err)
+	return f.secondary.Get(ctx, id, aggregateType, afterVersion)
+}
+
+// Pending returns the number of aggregates with queued writes
+func (f *Failover[T]) Pending() int {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return len(f.pending)
+}
+
+// Reconcile writes the queued events from the secondary to the primary. It returns the number of reconciled
+// aggregates and stops on the first error, the aggregates not reconciled stay queued. The lock is only held to read
+// and update the queue, saves are not blocked while the events are copied.
+func (f *Failover[T]) Reconcile(ctx context.Context) (int, error) {
+	f.lock.Lock()
+	pending := make(map[aggregate]eventsourcing.Version, len(f.pending))
+	for a, q := range f.pending {
+		pending[a] = q.version
+	}
+	f.lock.Unlock()
+
+	n := 0
+	for a, version := range pending {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+		events, err := f.reconcile(ctx, a, version)
+		if err != nil {
+			return n, err
+		}
+		n++
+		if f.options.hooks.OnReconciled != nil {
+			f.options.hooks.OnReconciled(a.typ, a.id, events)
+		}
+	}
+	return n, nil
+}
+
+// reconcile copies the queued events of the aggregate to the primary and returns the number of copied events. Events
+// queued while copying are copied in another round until the queue is drained.
+func (f *Failover[T]) reconcile(ctx context.Context, a aggregate, version eventsourcing.Version) (int, error) {
+	n := 0
+	for {
+		events, err := f.queued(ctx, a, version)
+		if err != nil {
+			return n, err
+		}
+		if len(events) > 0 {
+			err = f.primary.Save(events)
+			if errors.Is(err, eventstore.ErrConcurrency) {
+				return n, fmt.Errorf("%w: %s %s after version %d", ErrConflict, a.typ, a.id, version)
+			} else if err != nil {
+				return n, err
+			}
+			n += len(events)
+			version = events[len(events)-1].Version
+		}
+
+		f.lock.Lock()
+		q := f.pending[a]
+		if q.last <= version {
+			delete(f.pending, a)
+			f.lock.Unlock()
+			return n, nil
+		}
+		q.version = version
+		f.pending[a] = q
+		f.lock.Unlock()
+		if len(events) == 0 {
+			return n, fmt.Errorf("queued events of %s %s after version %d missing in the secondary", a.typ, a.id, version)
+		}
+	}
+}
+
+// Run reconciles the queued writes every interval until the context is done. Errors are reported to the
+// OnReconcileError hook and retried on the next interval.
+func (f *Failover[T]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		_, err := f.Reconcile(ctx)
+		if err != nil && ctx.Err() == nil && f.options.hooks.OnReconcileError != nil {
+			f.options.hooks.OnReconcileError(err)
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Ordering returns the global order guarantees of the primary event store. Events read from the secondary during a
+// failover have the global version of the secondary.
+func (f *Failover[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(f.primary)
+}
+
+// queued reads the events of the aggregate saved to the secondary after the version
+func (f *Failover[T]) queued(ctx context.Context, a aggregate, version eventsourcing.Version) ([]eventsourcing.Event[T], error) {
+	iterator, err := f.secondary.Get(ctx, a.id, a.typ, version)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	var events []eventsourcing.Event[T]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		event.GlobalVersion = 0
+		events = append(events, event)
+	}
+}
+
+func (f *Failover[T]) failover(op string, err error) {
+	if f.options.hooks.OnFailover != nil {
+		f.options.hooks.OnFailover(op, err)
+	}
+}
+
+// isFailure returns false for errors caused by the caller and not by the event store
+func isFailure(err error) bool {
+	switch {
+	case errors.Is(err, eventsourcing.ErrNoEvents),
+		errors.Is(err, eventsourcing.ErrVersionOverflow),
+		errors.Is(err, eventstore.ErrConcurrency),
+		errors.Is(err, eventstore.ErrEventMultipleAggregates),
+		errors.Is(err, eventstore.ErrEventMultipleAggregateTypes),
+		errors.Is(err, eventstore.ErrReasonMissing),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
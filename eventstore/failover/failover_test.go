@@ -0,0 +1,268 @@
+package failover_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/failover"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// flakyStore fails all calls while down
+type flakyStore struct {
+	eventsourcing.EventStore[any]
+	lock sync.Mutex
+	down bool
+}
+
+func (f *flakyStore) setDown(down bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.down = down
+}
+
+func (f *flakyStore) err() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.down {
+		return errUnavailable
+	}
+	return nil
+}
+
+func (f *flakyStore) Save(events []eventsourcing.Event[any]) error {
+	if err := f.err(); err != nil {
+		return err
+	}
+	return f.EventStore.Save(events)
+}
+
+func (f *flakyStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	if err := f.err(); err != nil {
+		return nil, err
+	}
+	return f.EventStore.Get(ctx, id, aggregateType, afterVersion)
+}
+
+type Created struct{}
+type Renamed struct{}
+
+func event(version eventsourcing.Version, data any) eventsourcing.Event[any] {
+	return eventsourcing.Event[any]{AggregateID: "1", AggregateType: "Person", Version: version, Data: data}
+}
+
+func versions(t *testing.T, store eventsourcing.EventStore[any]) []eventsourcing.Version {
+	t.Helper()
+	iterator, err := store.Get(context.Background(), "1", "Person", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	var v []eventsourcing.Version
+	for {
+		e, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return v
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		v = append(v, e.Version)
+	}
+}
+
+func TestSuite(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		store := failover.New[suite.FrequentFlierEvent](memory.Create[suite.FrequentFlierEvent](), memory.Create[suite.FrequentFlierEvent](), failover.WithQueuedWrites())
+		return store, func() {}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestGetFailsOver(t *testing.T) {
+	primary := &flakyStore{EventStore: memory.Create[any]()}
+	secondary := memory.Create[any]()
+	// the secondary is a replica of the primary
+	for _, s := range []eventsourcing.EventStore[any]{primary, secondary} {
+		if err := s.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var failovers int
+	store := failover.New[any](primary, secondary, failover.WithHooks(failover.Hooks{
+		OnFailover: func(op string, err error) { failovers++ },
+	}))
+	primary.setDown(true)
+	if v := versions(t, store); len(v) != 1 {
+		t.Fatalf("expected the event from the secondary got %v", v)
+	}
+	if failovers != 1 {
+		t.Fatalf("expected one failover got %d", failovers)
+	}
+
+	// without queued writes saves fail with the primary
+	err := store.Save([]eventsourcing.Event[any]{event(2, &Renamed{})})
+	if !errors.Is(err, errUnavailable) {
+		t.Fatalf("expected the primary error got %v", err)
+	}
+}
+
+func TestQueuedWritesReconcile(t *testing.T) {
+	primary := &flakyStore{EventStore: memory.Create[any]()}
+	secondary := memory.Create[any]()
+	for _, s := range []eventsourcing.EventStore[any]{primary, secondary} {
+		if err := s.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var reconciled int
+	store := failover.New[any](primary, secondary, failover.WithQueuedWrites(), failover.WithHooks(failover.Hooks{
+		OnReconciled: func(aggregateType, aggregateID string, events int) { reconciled += events },
+	}))
+
+	primary.setDown(true)
+	if err := store.Save([]eventsourcing.Event[any]{event(2, &Renamed{})}); err != nil {
+		t.Fatal(err)
+	}
+	primary.setDown(false)
+	// the aggregate is read from the secondary until reconciled, the primary is missing version 2
+	if err := store.Save([]eventsourcing.Event[any]{event(3, &Renamed{})}); err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(t, store); len(v) != 3 {
+		t.Fatalf("expected three events before reconcile got %v", v)
+	}
+	if store.Pending() != 1 {
+		t.Fatalf("expected one pending aggregate got %d", store.Pending())
+	}
+
+	n, err := store.Reconcile(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || reconciled != 2 {
+		t.Fatalf("expected one aggregate with two events reconciled got %d %d", n, reconciled)
+	}
+	if v := versions(t, primary); len(v) != 3 || v[2] != 3 {
+		t.Fatalf("expected the primary to be back filled got %v", v)
+	}
+	if store.Pending() != 0 {
+		t.Fatalf("expected no pending aggregates got %d", store.Pending())
+	}
+}
+
+func TestReconcileConflict(t *testing.T) {
+	primary := &flakyStore{EventStore: memory.Create[any]()}
+	secondary := memory.Create[any]()
+	store := failover.New[any](primary, secondary, failover.WithQueuedWrites())
+
+	primary.setDown(true)
+	if err := store.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+		t.Fatal(err)
+	}
+	primary.setDown(false)
+	// another writer saved to the primary during the outage
+	if err := primary.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := store.Reconcile(context.Background())
+	if !errors.Is(err, failover.ErrConflict) {
+		t.Fatalf("expected conflict got %v", err)
+	}
+	if store.Pending() != 1 {
+		t.Fatal("expected the conflicting aggregate to stay queued")
+	}
+}
+
+func TestConcurrencyErrorDoesNotFailOver(t *testing.T) {
+	primary := memory.Create[any]()
+	secondary := memory.Create[any]()
+	store := failover.New[any](primary, secondary, failover.WithQueuedWrites())
+	if err := store.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+		t.Fatal(err)
+	}
+	err := store.Save([]eventsourcing.Event[any]{event(1, &Created{})})
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+	if store.Pending() != 0 {
+		t.Fatal("expected no queued writes")
+	}
+}
+
+// blockingStore blocks saves until released once the channels are set
+type blockingStore struct {
+	eventsourcing.EventStore[any]
+	saving  chan struct{}
+	release chan struct{}
+}
+
+func (b *blockingStore) Save(events []eventsourcing.Event[any]) error {
+	if b.saving != nil {
+		b.saving <- struct{}{}
+		<-b.release
+	}
+	return b.EventStore.Save(events)
+}
+
+func TestReconcileDoesNotBlockSaves(t *testing.T) {
+	primary := &flakyStore{EventStore: memory.Create[any]()}
+	blocking := &blockingStore{EventStore: primary}
+	secondary := memory.Create[any]()
+	store := failover.New[any](blocking, secondary, failover.WithQueuedWrites())
+
+	primary.setDown(true)
+	if err := store.Save([]eventsourcing.Event[any]{event(1, &Created{})}); err != nil {
+		t.Fatal(err)
+	}
+	primary.setDown(false)
+
+	blocking.saving = make(chan struct{}, 2)
+	blocking.release = make(chan struct{})
+	type result struct {
+		n   int
+		err error
+	}
+	reconciled := make(chan result)
+	go func() {
+		n, err := store.Reconcile(context.Background())
+		reconciled <- result{n, err}
+	}()
+	<-blocking.saving
+
+	// the save is queued while the reconcile writes to the primary
+	saved := make(chan error)
+	go func() {
+		saved <- store.Save([]eventsourcing.Event[any]{event(2, &Renamed{})})
+	}()
+	select {
+	case err := <-saved:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("save blocked by reconcile")
+	}
+	close(blocking.release)
+
+	r := <-reconciled
+	if r.err != nil {
+		t.Fatal(r.err)
+	}
+	if r.n != 1 {
+		t.Fatalf("expected one reconciled aggregate got %d", r.n)
+	}
+	if v := versions(t, primary); len(v) != 2 {
+		t.Fatalf("expected the event saved during reconcile in the primary got %v", v)
+	}
+	if store.Pending() != 0 {
+		t.Fatalf("expected no pending aggregates got %d", store.Pending())
+	}
+}
@@ -0,0 +1,206 @@
+package firestore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultCollection = "aggregates"
+	eventsCollection  = "events"
+
+	// maxWrites is the maximum number of writes firestore accepts in one commit
+	maxWrites = 500
+)
+
+// ErrTooManyEvents is returned when the events and the aggregate document don't fit in one firestore commit.
+// The events are not split over several commits as that would make the save non atomic.
+var ErrTooManyEvents = errors.New("too many events in one save")
+
+// Firestore is the event store handler
+type Firestore[T any] struct {
+	client     *firestore.Client
+	serializer eventsourcing.Serializer[T]
+	collection string
+}
+
+// Option configures the firestore event store
+type Option func(*options)
+
+type options struct {
+	collection string
+}
+
+// WithCollection sets the collection the aggregate documents are stored in, default is aggregates.
+// Makes it possible for multiple bounded contexts to share one database.
+func WithCollection(collection string) Option {
+	return func(o *options) {
+		o.collection = collection
+	}
+}
+
+// aggregateDocument holds the current version of the aggregate, the events are stored in its events sub collection
+type aggregateDocument struct {
+	AggregateID   string `firestore:"id"`
+	AggregateType string `firestore:"type"`
+	Version       int64  `firestore:"version"`
+}
+
+type eventDocument struct {
+	EventID       string    `firestore:"event_id,omitempty"`
+	AggregateID   string    `firestore:"id"`
+	AggregateType string    `firestore:"type"`
+	Version       int64     `firestore:"version"`
+	Reason        string    `firestore:"reason"`
+	Timestamp     string    `firestore:"timestamp"`
+	ValidTime     string    `firestore:"valid_time,omitempty"`
+	Data          []byte    `firestore:"data"`
+	Metadata      []byte    `firestore:"metadata,omitempty"`
+	Commit        time.Time `firestore:"commit,serverTimestamp"`
+}
+
+// Open binds the firestore client
+func Open[T any](client *firestore.Client, serializer eventsourcing.Serializer[T], opts ...Option) *Firestore[T] {
+	o := options{collection: defaultCollection}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Firestore[T]{
+		client:     client,
+		serializer: serializer,
+		collection: o.collection,
+	}
+}
+
+// Save persists events to the database. The aggregate document is written with a precondition on its last update
+// time in the same commit as the events, a concurrent save of the same aggregate makes the commit fail.
+func (f *Firestore[T]) Save(events []eventsourcing.Event[T]) error {
+	// If no event return no error
+	if len(events) == 0 {
+		return nil
+	}
+	if len(events)+1 > maxWrites {
+		return fmt.Errorf("%w: %d events, firestore commits at most %d writes", ErrTooManyEvents, len(events), maxWrites)
+	}
+	ctx := context.Background()
+	aggregateID := events[0].AggregateID
+	aggregateType := events[0].AggregateType
+	ref := f.aggregateRef(aggregateType, aggregateID)
+
+	var currentVersion eventsourcing.Version
+	doc, err := ref.Get(ctx)
+	if err != nil && status.Code(err) != codes.NotFound {
+		return err
+	}
+	if doc.Exists() {
+		var aggregate aggregateDocument
+		if err := doc.DataTo(&aggregate); err != nil {
+			return err
+		}
+		currentVersion, err = eventsourcing.VersionFromInt64(aggregate.Version)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = eventstore.ValidateEvents(aggregateID, currentVersion, events)
+	if err != nil {
+		return err
+	}
+
+	batch := f.client.Batch()
+	lastVersion := int64(events[len(events)-1].Version)
+	if doc.Exists() {
+		batch.Update(ref, []firestore.Update{{Path: "version", Value: lastVersion}}, firestore.LastUpdateTime(doc.UpdateTime))
+	} else {
+		batch.Create(ref, aggregateDocument{AggregateID: aggregateID, AggregateType: aggregateType, Version: lastVersion})
+	}
+	for _, event := range events {
+		var e, m []byte
+
+		e, err := f.serializer.MarshalEvent(event)
+		if err != nil {
+			return err
+		}
+		if event.Metadata != nil {
+			m, err = f.serializer.Marshal(event.Metadata)
+			if err != nil {
+				return err
+			}
+		}
+		var validTime string
+		if !event.ValidTime.IsZero() {
+			validTime = event.ValidTime.UTC().Format(time.RFC3339Nano)
+		}
+		batch.Create(ref.Collection(eventsCollection).Doc(eventDocumentID(event.Version)), eventDocument{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       int64(event.Version),
+			Reason:        event.Reason(),
+			Timestamp:     event.Timestamp.UTC().Format(time.RFC3339Nano),
+			ValidTime:     validTime,
+			Data:          e,
+			Metadata:      m,
+		})
+	}
+	results, err := batch.Commit(ctx)
+	if err != nil {
+		switch status.Code(err) {
+		case codes.AlreadyExists, codes.FailedPrecondition:
+			return eventstore.ErrConcurrency
+		}
+		return err
+	}
+	for i := range events {
+		// Set all events GlobalVersion to the commit time of the write.
+		events[i].GlobalVersion = globalVersion(results[0].UpdateTime)
+	}
+	return nil
+}
+
+// Get the events from database
+func (f *Firestore[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	if afterVersion >= eventsourcing.MaxVersion {
+		return nil, fmt.Errorf("%w: after version %d", eventsourcing.ErrVersionOverflow, afterVersion)
+	}
+	documents := f.aggregateRef(aggregateType, id).Collection(eventsCollection).
+		Where("version", ">", int64(afterVersion)).
+		OrderBy("version", firestore.Asc).
+		Documents(ctx)
+	return &iterator[T]{documents: documents, serializer: f.serializer}, nil
+}
+
+// Ordering returns the global order guarantees of the firestore event store. The global version is the commit time
+// of the write in nanoseconds, it's shared by all events in the write and has gaps between writes.
+func (f *Firestore[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{GlobalVersionOnGet: true}
+}
+
+// Capabilities returns the optional features of the firestore event store
+func (f *Firestore[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{}
+}
+
+// aggregateRef returns the aggregate document, the id is escaped as firestore document ids can't contain a slash
+func (f *Firestore[T]) aggregateRef(aggregateType, aggregateID string) *firestore.DocumentRef {
+	return f.client.Collection(f.collection).Doc(url.PathEscape(aggregateType + "-" + aggregateID))
+}
+
+// eventDocumentID pads the version making the document ids sort in version order
+func eventDocumentID(version eventsourcing.Version) string {
+	return fmt.Sprintf("%020d", version)
+}
+
+func globalVersion(commit time.Time) eventsourcing.Version {
+	return eventsourcing.Version(commit.UnixNano())
+}
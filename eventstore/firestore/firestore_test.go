@@ -0,0 +1,34 @@
+//go:build manual
+// +build manual
+
+// make these tests manual as they are dependent on a running firestore emulator.
+
+package firestore_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"cloud.google.com/go/firestore"
+	"github.com/hallgren/eventsourcing"
+	fs "github.com/hallgren/eventsourcing/eventstore/firestore"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+func TestSuite(t *testing.T) {
+	if os.Getenv("FIRESTORE_EMULATOR_HOST") == "" {
+		os.Setenv("FIRESTORE_EMULATOR_HOST", "localhost:8080")
+	}
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		client, err := firestore.NewClient(context.Background(), "eventsourcing-test")
+		if err != nil {
+			return nil, nil, err
+		}
+		es := fs.Open(client, ser)
+		return es, func() {
+			client.Close()
+		}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
@@ -0,0 +1,73 @@
+package firestore
+
+import (
+	"time"
+
+	"cloud.google.com/go/firestore"
+	"github.com/hallgren/eventsourcing"
+	gi "google.golang.org/api/iterator"
+)
+
+type iterator[T any] struct {
+	documents  *firestore.DocumentIterator
+	serializer eventsourcing.Serializer[T]
+}
+
+// Next return the next event
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	var eventMetadata map[string]interface{}
+	doc, err := i.documents.Next()
+	if err == gi.Done {
+		return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
+	} else if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+	var e eventDocument
+	if err := doc.DataTo(&e); err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, e.Timestamp)
+	if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+	var vt time.Time
+	if e.ValidTime != "" {
+		vt, err = time.Parse(time.RFC3339Nano, e.ValidTime)
+		if err != nil {
+			return eventsourcing.Event[T]{}, err
+		}
+	}
+
+	if len(e.Metadata) > 0 {
+		err = i.serializer.Unmarshal(e.Metadata, &eventMetadata)
+		if err != nil {
+			return eventsourcing.Event[T]{}, err
+		}
+	}
+
+	event := eventsourcing.Event[T]{
+		EventID:       e.EventID,
+		AggregateID:   e.AggregateID,
+		Version:       eventsourcing.Version(e.Version),
+		GlobalVersion: globalVersion(e.Commit),
+		AggregateType: e.AggregateType,
+		Timestamp:     t,
+		ValidTime:     vt,
+		Metadata:      eventMetadata,
+	}
+	ok, err := i.serializer.UnmarshalEvent(&event, e.Reason, e.Data)
+	if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+	if !ok {
+		// if the typ/reason is not register or the event is skipped jump over the event
+		return i.Next()
+	}
+	return event, nil
+}
+
+// Close closes the iterator
+func (i *iterator[T]) Close() {
+	i.documents.Stop()
+}
@@ -0,0 +1,159 @@
+// Package hedge cuts the tail latency of aggregate loads on replicated read paths, like sql read replicas or event
+// store db clusters in several regions. A read not answered by the primary within a threshold is also sent to the
+// secondary and the first successful answer is used.
+package hedge
+
+import (
+	"context"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Hooks are called on events in the decorator, used to collect metrics and tune the threshold
+type Hooks struct {
+	// OnHedge is called when the primary did not answer within the threshold and the read is sent to the secondary
+	OnHedge func()
+	// OnSecondaryWin is called when the answer of the secondary is used
+	OnSecondaryWin func()
+}
+
+// Option configures the hedging decorator
+type Option func(*options)
+
+type options struct {
+	hooks Hooks
+}
+
+// WithHooks sets the hooks
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// Hedge decorates a primary event store hedging reads against a secondary. The secondary is expected to be a replica
+// of the primary, an aggregate read from a replica lagging behind is missing its latest events and saving it fails
+// with a concurrency error. Saves only go to the primary.
+type Hedge[T any] struct {
+	primary   eventsourcing.EventStore[T]
+	secondary eventsourcing.EventStore[T]
+	threshold time.Duration
+	options   options
+}
+
+// New decorates the primary event store. Reads not answered within threshold are also sent to the secondary, set it
+// around the p95 latency of the primary to hedge a few percent of the reads.
+func New[T any](primary, secondary eventsourcing.EventStore[T], threshold time.Duration, opts ...Option) *Hedge[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Hedge[T]{
+		primary:   primary,
+		secondary: secondary,
+		threshold: threshold,
+		options:   o,
+	}
+}
+
+// Save saves the events to the primary
+func (h *Hedge[T]) Save(events []eventsourcing.Event[T]) error {
+	return h.primary.Save(events)
+}
+
+// Ordering returns the global order guarantees of the primary event store
+func (h *Hedge[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(h.primary)
+}
+
+type result[T any] struct {
+	iterator  eventsourcing.EventIterator[T]
+	err       error
+	cancel    context.CancelFunc
+	secondary bool
+}
+
+// Get gets the events of the aggregate from the primary, or from the secondary if it answers first after the
+// threshold. An error from the primary before the threshold is returned as is, after the threshold the answer of the
+// other store is awaited and the error of the primary is returned if both fail. The read that lost is canceled and its
+// iterator closed.
+func (h *Hedge[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	results := make(chan result[T], 2)
+	get := func(store eventsourcing.EventStore[T], secondary bool) {
+		callCtx, cancel := context.WithCancel(ctx)
+		iterator, err := store.Get(callCtx, id, aggregateType, afterVersion)
+		results <- result[T]{iterator: iterator, err: err, cancel: cancel, secondary: secondary}
+	}
+	go get(h.primary, false)
+	inFlight := 1
+	hedged := false
+	timer := time.NewTimer(h.threshold)
+	defer timer.Stop()
+	var primaryErr, secondaryErr error
+	for {
+		select {
+		case r := <-results:
+			inFlight--
+			if r.err == nil {
+				go discard(results, inFlight)
+				if r.secondary && h.options.hooks.OnSecondaryWin != nil {
+					h.options.hooks.OnSecondaryWin()
+				}
+				return &iterator[T]{EventIterator: r.iterator, cancel: r.cancel}, nil
+			}
+			r.cancel()
+			if r.secondary {
+				secondaryErr = r.err
+			} else {
+				primaryErr = r.err
+			}
+			if !hedged || inFlight == 0 {
+				if primaryErr != nil {
+					return nil, primaryErr
+				}
+				return nil, secondaryErr
+			}
+		case <-timer.C:
+			if !hedged {
+				hedged = true
+				inFlight++
+				if h.options.hooks.OnHedge != nil {
+					h.options.hooks.OnHedge()
+				}
+				go get(h.secondary, true)
+			}
+		case <-ctx.Done():
+			go discard(results, inFlight)
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// discard waits for the reads still in flight and releases them
+func discard[T any](results chan result[T], inFlight int) {
+	for i := 0; i < inFlight; i++ {
+		r := <-results
+		r.cancel()
+		if r.err == nil {
+			r.iterator.Close()
+		}
+	}
+}
+
+// iterator cancels the context of the read when closed
+type iterator[T any] struct {
+	eventsourcing.EventIterator[T]
+	cancel context.CancelFunc
+}
+
+// NextInto reads the next event into event, see eventsourcing.ReuseIterator
+func (i *iterator[T]) NextInto(event *eventsourcing.Event[T]) error {
+	return eventsourcing.NextInto(i.EventIterator, event)
+}
+
+// Close closes the iterator of the read
+func (i *iterator[T]) Close() {
+	i.EventIterator.Close()
+	i.cancel()
+}
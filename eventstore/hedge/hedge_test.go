@@ -0,0 +1,135 @@
+package hedge_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/hedge"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Opened struct{}
+
+// delayed answers Get after the delay or fails with err
+type delayed struct {
+	eventsourcing.EventStore[any]
+	delay time.Duration
+	err   error
+
+	lock   sync.Mutex
+	closed int
+}
+
+func (d *delayed) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	time.Sleep(d.delay)
+	if d.err != nil {
+		return nil, d.err
+	}
+	iterator, err := d.EventStore.Get(ctx, id, aggregateType, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &tracked{EventIterator: iterator, store: d}, nil
+}
+
+func (d *delayed) closedIterators() int {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.closed
+}
+
+type tracked struct {
+	eventsourcing.EventIterator[any]
+	store *delayed
+}
+
+func (t *tracked) Close() {
+	t.store.lock.Lock()
+	t.store.closed++
+	t.store.lock.Unlock()
+	t.EventIterator.Close()
+}
+
+func store(t *testing.T) *memory.Memory[any] {
+	es := memory.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Account", Version: 1, Timestamp: time.Now(), Data: &Opened{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return es
+}
+
+func TestHedge(t *testing.T) {
+	errDown := errors.New("primary down")
+	tests := []struct {
+		title        string
+		primary      *delayed
+		secondary    *delayed
+		err          error
+		hedged       bool
+		secondaryWon bool
+	}{
+		{title: "fast primary", primary: &delayed{}, secondary: &delayed{}},
+		{title: "slow primary", primary: &delayed{delay: 50 * time.Millisecond}, secondary: &delayed{}, hedged: true, secondaryWon: true},
+		{title: "fast primary error", primary: &delayed{err: errDown}, secondary: &delayed{}, err: errDown},
+		{title: "slow primary error", primary: &delayed{delay: 20 * time.Millisecond, err: errDown}, secondary: &delayed{delay: 40 * time.Millisecond}, hedged: true, secondaryWon: true},
+		{title: "both fail", primary: &delayed{delay: 20 * time.Millisecond, err: errDown}, secondary: &delayed{err: errors.New("secondary down")}, err: errDown, hedged: true},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			es := store(t)
+			test.primary.EventStore = es
+			test.secondary.EventStore = es
+			hedged, secondaryWon := false, false
+			h := hedge.New[any](test.primary, test.secondary, 10*time.Millisecond, hedge.WithHooks(hedge.Hooks{
+				OnHedge:        func() { hedged = true },
+				OnSecondaryWin: func() { secondaryWon = true },
+			}))
+			iterator, err := h.Get(context.Background(), "1", "Account", 0)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if hedged != test.hedged || secondaryWon != test.secondaryWon {
+				t.Fatalf("expected hedged %v secondary won %v got %v %v", test.hedged, test.secondaryWon, hedged, secondaryWon)
+			}
+			if err != nil {
+				return
+			}
+			event, err := iterator.Next()
+			if err != nil || event.Version != 1 {
+				t.Fatalf("expected the event got %+v %v", event, err)
+			}
+			iterator.Close()
+		})
+	}
+}
+
+func TestLoserClosed(t *testing.T) {
+	es := store(t)
+	primary := &delayed{EventStore: es, delay: 30 * time.Millisecond}
+	h := hedge.New[any](primary, &delayed{EventStore: es}, 5*time.Millisecond)
+	iterator, err := h.Get(context.Background(), "1", "Account", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterator.Close()
+	time.Sleep(50 * time.Millisecond)
+	if primary.closedIterators() != 1 {
+		t.Fatal("expected the iterator of the slow primary to be closed")
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	es := store(t)
+	h := hedge.New[any](&delayed{EventStore: es, delay: 50 * time.Millisecond}, &delayed{EventStore: es, delay: 50 * time.Millisecond}, 5*time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := h.Get(ctx, "1", "Account", 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded got %v", err)
+	}
+}
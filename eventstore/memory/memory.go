@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"sync"
+	"time"
 
 	"github.com/hallgren/eventsourcing"
 	"github.com/hallgren/eventsourcing/eventstore"
@@ -12,9 +13,17 @@ import (
 type Memory[T any] struct {
 	aggregateEvents map[string][]eventsourcing.Event[T] // The memory structure where we store aggregate events
 	eventsInOrder   []eventsourcing.Event[T]            // The global event order
+	globalVersion   eventsourcing.Version               // The last assigned global version
+	observers       []SaveObserver[T]
+	bus             *eventstore.AggregateBus[T]
 	lock            sync.Mutex
 }
 
+// SaveObserver is called with the events of each save in the order the saves are committed. The global versions
+// across the calls are strictly increasing without gaps. It's called while the store is locked and must not call the
+// store.
+type SaveObserver[T any] func(events []eventsourcing.Event[T])
+
 type iterator[T any] struct {
 	events   []eventsourcing.Event[T]
 	position int
@@ -39,6 +48,7 @@ func Create[T any]() *Memory[T] {
 	return &Memory[T]{
 		aggregateEvents: make(map[string][]eventsourcing.Event[T]),
 		eventsInOrder:   make([]eventsourcing.Event[T], 0),
+		bus:             eventstore.NewAggregateBus[T](),
 	}
 }
 
@@ -53,12 +63,65 @@ func (e *Memory[T]) Save(events []eventsourcing.Event[T]) error {
 	e.lock.Lock()
 	defer e.lock.Unlock()
 
+	err := e.validate(events)
+	if err != nil {
+		return err
+	}
+	e.append(events)
+	e.observe(events)
+	return nil
+}
+
+// AddSaveObserver adds an observer called after each committed save
+func (e *Memory[T]) AddSaveObserver(observer SaveObserver[T]) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.observers = append(e.observers, observer)
+}
+
+// GlobalVersion returns the global version of the last saved event
+func (e *Memory[T]) GlobalVersion() eventsourcing.Version {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	return e.globalVersion
+}
+
+// SaveAll saves the events of several aggregates, if the events of one aggregate are invalid no events are saved
+func (e *Memory[T]) SaveAll(events [][]eventsourcing.Event[T]) error {
+	err := eventstore.ValidateBulk(events)
+	if err != nil {
+		return err
+	}
+
+	// make sure its thread safe
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, aggregateEvents := range events {
+		if len(aggregateEvents) == 0 {
+			continue
+		}
+		err := e.validate(aggregateEvents)
+		if err != nil {
+			return err
+		}
+	}
+	for _, aggregateEvents := range events {
+		if len(aggregateEvents) == 0 {
+			continue
+		}
+		e.append(aggregateEvents)
+		e.observe(aggregateEvents)
+	}
+	return nil
+}
+
+// validate the events against the current version of the aggregate
+func (e *Memory[T]) validate(events []eventsourcing.Event[T]) error {
 	// get bucket name from first event
 	aggregateType := events[0].AggregateType
 	aggregateID := events[0].AggregateID
-	bucketName := aggregateKey(aggregateType, aggregateID)
-
-	evBucket := e.aggregateEvents[bucketName]
+	evBucket := e.aggregateEvents[aggregateKey(aggregateType, aggregateID)]
 	currentVersion := eventsourcing.Version(0)
 
 	if len(evBucket) > 0 {
@@ -68,22 +131,35 @@ func (e *Memory[T]) Save(events []eventsourcing.Event[T]) error {
 	}
 
 	//Validate events
-	err := eventstore.ValidateEvents(aggregateID, currentVersion, events)
-	if err != nil {
-		return err
-	}
+	return eventstore.ValidateEvents(aggregateID, currentVersion, events)
+}
 
+// append the validated events to the aggregate and the global event order
+func (e *Memory[T]) append(events []eventsourcing.Event[T]) {
+	bucketName := aggregateKey(events[0].AggregateType, events[0].AggregateID)
+	evBucket := e.aggregateEvents[bucketName]
 	for i, event := range events {
-		// set the global version on the event +1 as if the event was already on the eventsInOrder slice
-		event.GlobalVersion = eventsourcing.Version(len(e.eventsInOrder) + 1)
+		e.globalVersion++
+		event.GlobalVersion = e.globalVersion
 		evBucket = append(evBucket, event)
 		e.eventsInOrder = append(e.eventsInOrder, event)
 		// override the event in the slice exposing the GlobalVersion to the caller
 		events[i].GlobalVersion = event.GlobalVersion
 	}
-
 	e.aggregateEvents[bucketName] = evBucket
-	return nil
+}
+
+// observe calls the save observers with a copy of the saved events
+func (e *Memory[T]) observe(events []eventsourcing.Event[T]) {
+	for _, observer := range e.observers {
+		observer(append([]eventsourcing.Event[T]{}, events...))
+	}
+	e.bus.Publish(events)
+}
+
+// SubscribeAggregate delivers the events of the aggregate after fromVersion as they are saved
+func (e *Memory[T]) SubscribeAggregate(ctx context.Context, aggregateType, id string, fromVersion eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) error {
+	return e.bus.Subscribe(ctx, e, aggregateType, id, fromVersion, handler)
 }
 
 // Get aggregate events
@@ -104,6 +180,29 @@ func (e *Memory[T]) Get(ctx context.Context, id string, aggregateType string, af
 	return &iterator[T]{events: events}, nil
 }
 
+// GetMany returns an iterator per aggregate id over its events after the version in afterVersions
+func (e *Memory[T]) GetMany(ctx context.Context, aggregateType string, ids []string, afterVersions map[string]eventsourcing.Version) (map[string]eventsourcing.EventIterator[T], error) {
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	iterators := make(map[string]eventsourcing.EventIterator[T], len(ids))
+	for _, id := range ids {
+		var events []eventsourcing.Event[T]
+		for _, event := range e.aggregateEvents[aggregateKey(aggregateType, id)] {
+			if event.Version > afterVersions[id] {
+				events = append(events, event)
+			}
+		}
+		if len(events) > 0 {
+			iterators[id] = &iterator[T]{events: events}
+		}
+	}
+	return iterators, nil
+}
+
 // GlobalEvents will return count events in order globally from the start posistion
 func (e *Memory[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
 	var events []eventsourcing.Event[T]
@@ -124,6 +223,73 @@ func (e *Memory[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T],
 	return events, nil
 }
 
+// GlobalEventsBetween returns the events with a timestamp within the time window matching the filter in global order
+func (e *Memory[T]) GlobalEventsBetween(ctx context.Context, from, to time.Time, filter eventsourcing.EventFilter) ([]eventsourcing.Event[T], error) {
+	var events []eventsourcing.Event[T]
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, event := range e.eventsInOrder {
+		if event.Timestamp.Before(from) || !event.Timestamp.Before(to) || !filter.Match(event.AggregateType, event.Reason()) {
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// GlobalEventsPartition returns count events of the partition in global order from the start position
+func (e *Memory[T]) GlobalEventsPartition(ctx context.Context, partition, partitionCount uint32, start, count uint64) ([]eventsourcing.Event[T], error) {
+	var events []eventsourcing.Event[T]
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, event := range e.eventsInOrder {
+		if uint64(len(events)) == count {
+			break
+		}
+		if uint64(event.GlobalVersion) >= start && eventsourcing.PartitionOf(event.AggregateID, partitionCount) == partition {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// EventsByCorrelationID returns the events with the correlation id in their metadata in global order
+func (e *Memory[T]) EventsByCorrelationID(ctx context.Context, correlationID string) ([]eventsourcing.Event[T], error) {
+	var events []eventsourcing.Event[T]
+	e.lock.Lock()
+	defer e.lock.Unlock()
+
+	for _, event := range e.eventsInOrder {
+		if eventsourcing.MetadataCarrierFrom(event).CorrelationID == correlationID {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+// Stats returns the number of events per aggregate type and the aggregates with the most events
+func (e *Memory[T]) Stats(ctx context.Context) (eventsourcing.Stats, error) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	c := eventstore.NewStatsCollector()
+	for _, event := range e.eventsInOrder {
+		c.Add(event.AggregateType, event.AggregateID, event.Timestamp)
+	}
+	return c.Stats(), nil
+}
+
+// Ordering returns the global order guarantees of the memory event store
+func (e *Memory[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{ContiguousGlobalOrder: true, GlobalVersionOnGet: true}
+}
+
+// Capabilities returns the optional features of the memory event store
+func (e *Memory[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{GlobalOrder: true, Subscriptions: true, MultiAggregateTx: true}
+}
+
 // Close does nothing
 func (e *Memory[T]) Close() {}
 
@@ -1,6 +1,8 @@
 package memory_test
 
 import (
+	"fmt"
+	"sync"
 	"testing"
 
 	"github.com/hallgren/eventsourcing"
@@ -16,3 +18,39 @@ func TestSuite(t *testing.T) {
 
 	suite.Test[suite.FrequentFlierEvent](t, f)
 }
+
+func TestSaveObserver(t *testing.T) {
+	es := memory.Create[any]()
+	var observed []eventsourcing.Version
+	es.AddSaveObserver(func(events []eventsourcing.Event[any]) {
+		for _, e := range events {
+			observed = append(observed, e.GlobalVersion)
+		}
+	})
+
+	wg := sync.WaitGroup{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			for v := 1; v <= 10; v++ {
+				err := es.Save([]eventsourcing.Event[any]{
+					{AggregateID: fmt.Sprint(id), AggregateType: "Person", Version: eventsourcing.Version(v), Data: &suite.FlightTaken{}},
+				})
+				if err != nil {
+					t.Error(err)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(observed) != 500 || es.GlobalVersion() != 500 {
+		t.Fatalf("expected 500 observed events got %d at global version %d", len(observed), es.GlobalVersion())
+	}
+	for i, v := range observed {
+		if v != eventsourcing.Version(i+1) {
+			t.Fatalf("expected global version %d at %d got %d", i+1, i, v)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package eventstore
+
+import (
+	"context"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// SaveFunc saves events like EventStore.Save
+type SaveFunc[T any] func(events []eventsourcing.Event[T]) error
+
+// GetFunc gets the events of an aggregate like EventStore.Get
+type GetFunc[T any] func(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error)
+
+// GlobalEventsFunc gets events in the global order like GlobalEventStore.GlobalEvents
+type GlobalEventsFunc[T any] func(start, count uint64) ([]eventsourcing.Event[T], error)
+
+// StoreMiddleware wraps the calls of an event store. Each field gets the next function in the chain and returns the
+// function called in its place, a nil field passes the call through. Tracing, metrics, retries and the like only
+// implement the calls they care about.
+//
+//	timing := eventstore.StoreMiddleware[any]{
+//		Save: func(next eventstore.SaveFunc[any]) eventstore.SaveFunc[any] {
+//			return func(events []eventsourcing.Event[any]) error {
+//				start := time.Now()
+//				defer func() { saveDuration.Observe(time.Since(start).Seconds()) }()
+//				return next(events)
+//			}
+//		},
+//	}
+type StoreMiddleware[T any] struct {
+	Save         func(next SaveFunc[T]) SaveFunc[T]
+	Get          func(next GetFunc[T]) GetFunc[T]
+	GlobalEvents func(next GlobalEventsFunc[T]) GlobalEventsFunc[T]
+}
+
+// Chained is an event store with its calls wrapped by middlewares
+type Chained[T any] struct {
+	store eventsourcing.EventStore[T]
+	save  SaveFunc[T]
+	get   GetFunc[T]
+}
+
+// ChainedGlobal is a chained event store that also returns the events in the global order
+type ChainedGlobal[T any] struct {
+	*Chained[T]
+	globalEvents GlobalEventsFunc[T]
+}
+
+// Chain wraps the calls of the event store with the middlewares, the first middleware is the outermost and sees the
+// calls first. The returned event store implements eventsourcing.GlobalEventStore when the wrapped store does, other
+// optional interfaces of the wrapped store are not exposed.
+func Chain[T any](store eventsourcing.EventStore[T], middlewares ...StoreMiddleware[T]) eventsourcing.EventStore[T] {
+	c := &Chained[T]{
+		store: store,
+		save:  store.Save,
+		get:   store.Get,
+	}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i].Save != nil {
+			c.save = middlewares[i].Save(c.save)
+		}
+		if middlewares[i].Get != nil {
+			c.get = middlewares[i].Get(c.get)
+		}
+	}
+	global, ok := store.(eventsourcing.GlobalEventStore[T])
+	if !ok {
+		return c
+	}
+	g := &ChainedGlobal[T]{Chained: c, globalEvents: global.GlobalEvents}
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		if middlewares[i].GlobalEvents != nil {
+			g.globalEvents = middlewares[i].GlobalEvents(g.globalEvents)
+		}
+	}
+	return g
+}
+
+// Save saves the events through the middlewares
+func (c *Chained[T]) Save(events []eventsourcing.Event[T]) error {
+	return c.save(events)
+}
+
+// Get gets the events of the aggregate through the middlewares
+func (c *Chained[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	return c.get(ctx, id, aggregateType, afterVersion)
+}
+
+// Ordering returns the global order guarantees of the wrapped event store
+func (c *Chained[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(c.store)
+}
+
+// GlobalEvents gets the events in the global order through the middlewares
+func (g *ChainedGlobal[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
+	return g.globalEvents(start, count)
+}
@@ -0,0 +1,86 @@
+package eventstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+// recorder is a middleware appending its name to calls on each wrapped call
+func recorder(name string, calls *[]string) eventstore.StoreMiddleware[any] {
+	return eventstore.StoreMiddleware[any]{
+		Save: func(next eventstore.SaveFunc[any]) eventstore.SaveFunc[any] {
+			return func(events []eventsourcing.Event[any]) error {
+				*calls = append(*calls, name+" save")
+				return next(events)
+			}
+		},
+		GlobalEvents: func(next eventstore.GlobalEventsFunc[any]) eventstore.GlobalEventsFunc[any] {
+			return func(start, count uint64) ([]eventsourcing.Event[any], error) {
+				*calls = append(*calls, name+" global")
+				return next(start, count)
+			}
+		},
+	}
+}
+
+func TestChain(t *testing.T) {
+	var calls []string
+	errGetDenied := errors.New("get denied")
+	deny := eventstore.StoreMiddleware[any]{
+		Get: func(next eventstore.GetFunc[any]) eventstore.GetFunc[any] {
+			return func(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+				return nil, errGetDenied
+			}
+		},
+	}
+	store := eventstore.Chain[any](memory.Create[any](), recorder("outer", &calls), recorder("inner", &calls), deny)
+
+	err := store.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Account", Version: 1, Timestamp: time.Now(), Data: &Opened{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	global, ok := store.(eventsourcing.GlobalEventStore[any])
+	if !ok {
+		t.Fatal("expected the chained memory store to be a GlobalEventStore")
+	}
+	events, err := global.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("expected one event got %d", len(events))
+	}
+	expected := []string{"outer save", "inner save", "outer global", "inner global"}
+	if len(calls) != len(expected) {
+		t.Fatalf("expected calls %v got %v", expected, calls)
+	}
+	for i := range expected {
+		if calls[i] != expected[i] {
+			t.Fatalf("expected calls %v got %v", expected, calls)
+		}
+	}
+	_, err = store.Get(context.Background(), "1", "Account", 0)
+	if !errors.Is(err, errGetDenied) {
+		t.Fatalf("expected the get to be denied got %v", err)
+	}
+	if !eventsourcing.OrderingOf(store).ContiguousGlobalOrder {
+		t.Fatal("expected the ordering of the memory store")
+	}
+}
+
+type getOnly struct {
+	eventsourcing.EventStore[any]
+}
+
+func TestChainWithoutGlobalEvents(t *testing.T) {
+	store := eventstore.Chain[any](getOnly{memory.Create[any]()})
+	if _, ok := store.(eventsourcing.GlobalEventStore[any]); ok {
+		t.Fatal("expected the chained store not to be a GlobalEventStore")
+	}
+}
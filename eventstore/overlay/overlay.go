@@ -0,0 +1,208 @@
+// Package overlay attaches annotations to saved events without changing the event log.
+package overlay
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// MetadataRedacted is the metadata key of the redaction marker, its value is the reason of the redaction
+const MetadataRedacted = "redacted"
+
+// ErrGlobalEventsNotSupported when the decorated event store can't return events in the global order
+var ErrGlobalEventsNotSupported = errors.New("event store does not support global events")
+
+// Annotation is a metadata value attached to the event with the version in the stream of the aggregate
+type Annotation struct {
+	AggregateType string
+	AggregateID   string
+	Version       eventsourcing.Version
+	Key           string
+	Value         interface{}
+}
+
+// AnnotationStore keeps the annotations apart from the events
+type AnnotationStore interface {
+	// Annotate saves the annotation, replacing an annotation with the same key on the event
+	Annotate(ctx context.Context, annotation Annotation) error
+	// Annotations returns the annotations of the events of the aggregate
+	Annotations(ctx context.Context, aggregateType, aggregateID string) ([]Annotation, error)
+}
+
+// Option configures the overlay
+type Option[T any] func(*options[T])
+
+type options[T any] struct {
+	redactor func(event eventsourcing.Event[T]) T
+}
+
+// WithRedactor sets the function returning the masked data of redacted events. Default the data of redacted events
+// is left as is and only the redaction marker is set in the metadata.
+func WithRedactor[T any](f func(event eventsourcing.Event[T]) T) Option[T] {
+	return func(o *options[T]) {
+		o.redactor = f
+	}
+}
+
+// Overlay decorates an event store merging the annotations of the events into their metadata when they are read.
+// The events in the event store are never changed, the annotations only exist in the read path.
+type Overlay[T any] struct {
+	store       eventsourcing.EventStore[T]
+	annotations AnnotationStore
+	options     options[T]
+}
+
+// New decorates the event store with the annotations in the annotation store
+func New[T any](store eventsourcing.EventStore[T], annotations AnnotationStore, opts ...Option[T]) *Overlay[T] {
+	o := options[T]{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Overlay[T]{store: store, annotations: annotations, options: o}
+}
+
+// Save saves the events to the event store
+func (o *Overlay[T]) Save(events []eventsourcing.Event[T]) error {
+	return o.store.Save(events)
+}
+
+// Get returns the events of the aggregate with their annotations
+func (o *Overlay[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	annotations, err := o.annotations.Annotations(ctx, aggregateType, id)
+	if err != nil {
+		return nil, err
+	}
+	it, err := o.store.Get(ctx, id, aggregateType, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator[T]{iterator: it, overlay: o, annotations: byVersion(annotations)}, nil
+}
+
+// GlobalEvents returns the events in the global order with their annotations, ErrGlobalEventsNotSupported is
+// returned if the event store is not a global event store
+func (o *Overlay[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
+	global, ok := o.store.(eventsourcing.GlobalEventStore[T])
+	if !ok {
+		return nil, ErrGlobalEventsNotSupported
+	}
+	events, err := global.GlobalEvents(start, count)
+	if err != nil {
+		return nil, err
+	}
+	// read the annotations once per aggregate in the batch
+	aggregates := make(map[[2]string]map[eventsourcing.Version][]Annotation)
+	for i, event := range events {
+		key := [2]string{event.AggregateType, event.AggregateID}
+		annotations, ok := aggregates[key]
+		if !ok {
+			a, err := o.annotations.Annotations(context.Background(), event.AggregateType, event.AggregateID)
+			if err != nil {
+				return nil, err
+			}
+			annotations = byVersion(a)
+			aggregates[key] = annotations
+		}
+		events[i] = o.merge(event, annotations[event.Version])
+	}
+	return events, nil
+}
+
+// Annotate attaches the key and value to the event of the aggregate
+func (o *Overlay[T]) Annotate(ctx context.Context, aggregateType, id string, version eventsourcing.Version, key string, value interface{}) error {
+	return o.annotations.Annotate(ctx, Annotation{AggregateType: aggregateType, AggregateID: id, Version: version, Key: key, Value: value})
+}
+
+// Redact marks the event of the aggregate as redacted for the reason
+func (o *Overlay[T]) Redact(ctx context.Context, aggregateType, id string, version eventsourcing.Version, reason string) error {
+	return o.Annotate(ctx, aggregateType, id, version, MetadataRedacted, reason)
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (o *Overlay[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(o.store)
+}
+
+// merge returns the event with the annotations set in a copy of its metadata, the event data is masked if the event
+// is redacted and there is a redactor
+func (o *Overlay[T]) merge(event eventsourcing.Event[T], annotations []Annotation) eventsourcing.Event[T] {
+	if len(annotations) == 0 {
+		return event
+	}
+	metadata := make(map[string]interface{}, len(event.Metadata)+len(annotations))
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	for _, a := range annotations {
+		metadata[a.Key] = a.Value
+	}
+	event.Metadata = metadata
+	if _, ok := metadata[MetadataRedacted]; ok && o.options.redactor != nil {
+		event.Data = o.options.redactor(event)
+	}
+	return event
+}
+
+func byVersion(annotations []Annotation) map[eventsourcing.Version][]Annotation {
+	m := make(map[eventsourcing.Version][]Annotation)
+	for _, a := range annotations {
+		m[a.Version] = append(m[a.Version], a)
+	}
+	return m
+}
+
+type iterator[T any] struct {
+	iterator    eventsourcing.EventIterator[T]
+	overlay     *Overlay[T]
+	annotations map[eventsourcing.Version][]Annotation
+}
+
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	event, err := i.iterator.Next()
+	if err != nil {
+		return event, err
+	}
+	return i.overlay.merge(event, i.annotations[event.Version]), nil
+}
+
+func (i *iterator[T]) Close() {
+	i.iterator.Close()
+}
+
+// Memory keeps the annotations in memory
+type Memory struct {
+	lock        sync.Mutex
+	annotations map[[2]string][]Annotation
+}
+
+// NewMemory returns an empty in memory annotation store
+func NewMemory() *Memory {
+	return &Memory{annotations: make(map[[2]string][]Annotation)}
+}
+
+// Annotate saves the annotation, replacing an annotation with the same key on the event
+func (m *Memory) Annotate(ctx context.Context, annotation Annotation) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key := [2]string{annotation.AggregateType, annotation.AggregateID}
+	annotations := m.annotations[key]
+	for i, a := range annotations {
+		if a.Version == annotation.Version && a.Key == annotation.Key {
+			annotations[i] = annotation
+			return nil
+		}
+	}
+	m.annotations[key] = append(annotations, annotation)
+	return nil
+}
+
+// Annotations returns the annotations of the events of the aggregate
+func (m *Memory) Annotations(ctx context.Context, aggregateType, aggregateID string) ([]Annotation, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	annotations := m.annotations[[2]string{aggregateType, aggregateID}]
+	return append([]Annotation{}, annotations...), nil
+}
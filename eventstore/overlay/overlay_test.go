@@ -0,0 +1,90 @@
+package overlay_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/overlay"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+type Registered struct {
+	Email string
+}
+
+func TestSuite(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		return overlay.New[suite.FrequentFlierEvent](memory.Create[suite.FrequentFlierEvent](), overlay.NewMemory()), func() {}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func get(t *testing.T, store eventsourcing.EventStore[any]) []eventsourcing.Event[any] {
+	t.Helper()
+	it, err := store.Get(context.Background(), "1", "Person", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var events []eventsourcing.Event[any]
+	for {
+		event, err := it.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+}
+
+func TestOverlay(t *testing.T) {
+	ctx := context.Background()
+	store := memory.Create[any]()
+	o := overlay.New[any](store, overlay.NewMemory(), overlay.WithRedactor(func(event eventsourcing.Event[any]) any {
+		return &Registered{Email: "***"}
+	}))
+	err := o.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Registered{Email: "kalle@example.com"}, Metadata: map[string]interface{}{"user_id": "admin"}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Data: &Registered{Email: "kalle@example.org"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Annotate(ctx, "Person", "1", 1, "note", "verified by support")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = o.Redact(ctx, "Person", "1", 1, "gdpr")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := get(t, o)
+	if events[0].Metadata["note"] != "verified by support" || events[0].Metadata[overlay.MetadataRedacted] != "gdpr" || events[0].Metadata["user_id"] != "admin" {
+		t.Fatalf("expected the annotations merged with the metadata got %v", events[0].Metadata)
+	}
+	if events[0].Data.(*Registered).Email != "***" {
+		t.Fatalf("expected the redacted event to be masked got %v", events[0].Data)
+	}
+	if events[1].Data.(*Registered).Email != "kalle@example.org" || events[1].Metadata[overlay.MetadataRedacted] != nil {
+		t.Fatalf("expected the event without annotations as saved got %+v", events[1])
+	}
+
+	global, err := o.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if global[0].Metadata["note"] != "verified by support" {
+		t.Fatalf("expected the annotations on the global events got %v", global[0].Metadata)
+	}
+
+	// the event store is not changed
+	events = get(t, store)
+	if _, ok := events[0].Metadata["note"]; ok || events[0].Data.(*Registered).Email != "kalle@example.com" {
+		t.Fatalf("expected the saved event to be unchanged got %+v", events[0])
+	}
+}
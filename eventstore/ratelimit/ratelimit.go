@@ -0,0 +1,216 @@
+// Package ratelimit protects a shared database from bursty writers, like batch jobs replaying aggregates at full
+// speed. Saves are throttled by token buckets per aggregate type and the number of concurrent calls to the event
+// store is capped. Callers over the limit wait instead of failing, pushing the back pressure to the producer.
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+)
+
+// ErrRateLimited when a save would wait longer than the max wait for its tokens
+var ErrRateLimited = errors.New("event store save rate limited")
+
+// Rate is the number of events per second that can be saved, with bursts of up to Burst events
+type Rate struct {
+	EventsPerSecond float64
+	Burst           int
+}
+
+// Option configures the rate limiter
+type Option func(*options)
+
+type options struct {
+	rates       map[string]Rate
+	fallback    *Rate
+	concurrency int
+	maxWait     time.Duration
+}
+
+// WithRate limits the saved events of the aggregate type
+func WithRate(aggregateType string, rate Rate) Option {
+	return func(o *options) {
+		o.rates[aggregateType] = rate
+	}
+}
+
+// WithDefaultRate limits the saved events of the aggregate types without a rate of their own. Each aggregate type
+// gets a bucket of its own.
+func WithDefaultRate(rate Rate) Option {
+	return func(o *options) {
+		o.fallback = &rate
+	}
+}
+
+// WithConcurrency caps the number of Save, Get and GlobalEvents calls in flight at the same time
+func WithConcurrency(calls int) Option {
+	return func(o *options) {
+		o.concurrency = calls
+	}
+}
+
+// WithMaxWait makes a save fail with ErrRateLimited instead of waiting longer than maxWait for its tokens, the
+// default is to wait as long as it takes
+func WithMaxWait(maxWait time.Duration) Option {
+	return func(o *options) {
+		o.maxWait = maxWait
+	}
+}
+
+// New decorates the event store with the limits
+func New[T any](store eventsourcing.EventStore[T], opts ...Option) eventsourcing.EventStore[T] {
+	return eventstore.Chain(store, Middleware[T](opts...))
+}
+
+// Middleware returns the limits as a store middleware, to chain it with other middlewares
+func Middleware[T any](opts ...Option) eventstore.StoreMiddleware[T] {
+	o := options{rates: make(map[string]Rate)}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	l := &limiter{
+		options: o,
+		buckets: make(map[string]*bucket),
+	}
+	if o.concurrency > 0 {
+		l.slots = make(chan struct{}, o.concurrency)
+	}
+	return eventstore.StoreMiddleware[T]{
+		Save: func(next eventstore.SaveFunc[T]) eventstore.SaveFunc[T] {
+			return func(events []eventsourcing.Event[T]) error {
+				if len(events) > 0 {
+					err := l.wait(events[0].AggregateType, len(events))
+					if err != nil {
+						return err
+					}
+				}
+				_ = l.acquire(context.Background())
+				defer l.release()
+				return next(events)
+			}
+		},
+		Get: func(next eventstore.GetFunc[T]) eventstore.GetFunc[T] {
+			return func(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+				err := l.acquire(ctx)
+				if err != nil {
+					return nil, err
+				}
+				defer l.release()
+				return next(ctx, id, aggregateType, afterVersion)
+			}
+		},
+		GlobalEvents: func(next eventstore.GlobalEventsFunc[T]) eventstore.GlobalEventsFunc[T] {
+			return func(start, count uint64) ([]eventsourcing.Event[T], error) {
+				_ = l.acquire(context.Background())
+				defer l.release()
+				return next(start, count)
+			}
+		},
+	}
+}
+
+type limiter struct {
+	options options
+	lock    sync.Mutex
+	buckets map[string]*bucket
+	slots   chan struct{}
+}
+
+// wait blocks until the events can be saved within the rate of the aggregate type
+func (l *limiter) wait(aggregateType string, events int) error {
+	b := l.bucket(aggregateType)
+	if b == nil {
+		return nil
+	}
+	d, ok := b.reserve(float64(events), time.Now(), l.options.maxWait)
+	if !ok {
+		return fmt.Errorf("%w: %s would wait %s", ErrRateLimited, aggregateType, d)
+	}
+	if d > 0 {
+		time.Sleep(d)
+	}
+	return nil
+}
+
+func (l *limiter) bucket(aggregateType string) *bucket {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if b, ok := l.buckets[aggregateType]; ok {
+		return b
+	}
+	rate, ok := l.options.rates[aggregateType]
+	if !ok {
+		if l.options.fallback == nil {
+			return nil
+		}
+		rate = *l.options.fallback
+	}
+	if rate.EventsPerSecond <= 0 {
+		return nil
+	}
+	burst := float64(rate.Burst)
+	if burst < 1 {
+		burst = 1
+	}
+	b := &bucket{rate: rate.EventsPerSecond, burst: burst, tokens: burst, last: time.Now()}
+	l.buckets[aggregateType] = b
+	return b
+}
+
+func (l *limiter) acquire(ctx context.Context) error {
+	if l.slots == nil {
+		return nil
+	}
+	select {
+	case l.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *limiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// bucket is a token bucket refilled at rate tokens per second up to burst tokens
+type bucket struct {
+	lock   sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// reserve takes n tokens and returns how long to wait until they are refilled. The tokens can go negative, the
+// callers after it wait for the debt to be refilled, keeping the waiting callers in order. A reservation waiting longer
+// than maxWait is not made.
+func (b *bucket) reserve(n float64, now time.Time, maxWait time.Duration) (time.Duration, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if elapsed := now.Sub(b.last); elapsed > 0 {
+		b.tokens += elapsed.Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+		b.last = now
+	}
+	tokens := b.tokens - n
+	var wait time.Duration
+	if tokens < 0 {
+		wait = time.Duration(-tokens / b.rate * float64(time.Second))
+	}
+	if maxWait > 0 && wait > maxWait {
+		return wait, false
+	}
+	b.tokens = tokens
+	return wait, true
+}
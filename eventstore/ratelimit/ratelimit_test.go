@@ -0,0 +1,151 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/ratelimit"
+)
+
+type Deposited struct{}
+
+func events(aggregateType, id string, from, count int) []eventsourcing.Event[any] {
+	var events []eventsourcing.Event[any]
+	for i := 0; i < count; i++ {
+		events = append(events, eventsourcing.Event[any]{AggregateID: id, AggregateType: aggregateType, Version: eventsourcing.Version(from + i), Timestamp: time.Now(), Data: &Deposited{}})
+	}
+	return events
+}
+
+func TestRate(t *testing.T) {
+	store := ratelimit.New[any](memory.Create[any](),
+		ratelimit.WithRate("Account", ratelimit.Rate{EventsPerSecond: 100, Burst: 2}),
+	)
+	start := time.Now()
+	// the burst is saved at once, the next four events wait for 40ms of refill
+	for i := 1; i <= 6; i++ {
+		err := store.Save(events("Account", "1", i, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 35*time.Millisecond {
+		t.Fatalf("expected the saves to be throttled, took %s", elapsed)
+	}
+
+	// aggregate types without a rate are not throttled
+	start = time.Now()
+	for i := 1; i <= 50; i++ {
+		err := store.Save(events("Person", "1", i, 1))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > 250*time.Millisecond {
+		t.Fatalf("expected the saves not to be throttled, took %s", elapsed)
+	}
+}
+
+func TestMaxWait(t *testing.T) {
+	store := ratelimit.New[any](memory.Create[any](),
+		ratelimit.WithDefaultRate(ratelimit.Rate{EventsPerSecond: 10, Burst: 5}),
+		ratelimit.WithMaxWait(50*time.Millisecond),
+	)
+	err := store.Save(events("Account", "1", 1, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ten events need a second of refill
+	err = store.Save(events("Account", "1", 6, 10))
+	if !errors.Is(err, ratelimit.ErrRateLimited) {
+		t.Fatalf("expected ErrRateLimited got %v", err)
+	}
+	// the rejected save took no tokens, other aggregate types have their own bucket
+	err = store.Save(events("Person", "1", 1, 5))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type slowStore struct {
+	eventsourcing.EventStore[any]
+	lock     sync.Mutex
+	inFlight int
+	max      int
+}
+
+func (s *slowStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	s.lock.Lock()
+	s.inFlight++
+	if s.inFlight > s.max {
+		s.max = s.inFlight
+	}
+	s.lock.Unlock()
+	time.Sleep(5 * time.Millisecond)
+	s.lock.Lock()
+	s.inFlight--
+	s.lock.Unlock()
+	return s.EventStore.Get(ctx, id, aggregateType, afterVersion)
+}
+
+func TestConcurrency(t *testing.T) {
+	slow := &slowStore{EventStore: memory.Create[any]()}
+	err := slow.Save(events("Account", "1", 1, 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := ratelimit.New[any](slow, ratelimit.WithConcurrency(2))
+	wg := sync.WaitGroup{}
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			iterator, err := store.Get(context.Background(), "1", "Account", 0)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			iterator.Close()
+		}()
+	}
+	wg.Wait()
+	if slow.max != 2 {
+		t.Fatalf("expected two calls in flight got %d", slow.max)
+	}
+
+	// a waiting call stops on the context
+	blocking := &blockingStore{EventStore: slow, started: make(chan struct{}), release: make(chan struct{})}
+	block := ratelimit.New[any](blocking, ratelimit.WithConcurrency(1))
+	done := make(chan struct{})
+	go func() {
+		block.Get(context.Background(), "1", "Account", 0)
+		close(done)
+	}()
+	<-blocking.started
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	_, err = block.Get(ctx, "1", "Account", 0)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the deadline to be exceeded got %v", err)
+	}
+	close(blocking.release)
+	<-done
+}
+
+// blockingStore blocks Get until released
+type blockingStore struct {
+	eventsourcing.EventStore[any]
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	close(s.started)
+	<-s.release
+	return s.EventStore.Get(ctx, id, aggregateType, afterVersion)
+}
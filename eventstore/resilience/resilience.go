@@ -0,0 +1,342 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+)
+
+// ErrTimeout when the event store call did not return within the timeout
+var ErrTimeout = errors.New("event store call timed out")
+
+// ErrCircuitOpen when the circuit breaker is open and the call is not made
+var ErrCircuitOpen = errors.New("event store circuit breaker is open")
+
+// ErrBulkheadFull when the max number of concurrent calls are in flight
+var ErrBulkheadFull = errors.New("event store bulkhead is full")
+
+// State is the state of the circuit breaker
+type State int
+
+const (
+	// Closed lets all calls through
+	Closed State = iota
+	// Open rejects all calls until the open duration has passed
+	Open
+	// HalfOpen lets one trial call through deciding if the circuit closes or opens again
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	}
+	return "closed"
+}
+
+// Hooks are called on events in the decorator, used to collect metrics
+type Hooks struct {
+	// OnCall is called when a call to the event store returns
+	OnCall func(op string, duration time.Duration, err error)
+	// OnReject is called when a call is rejected by the circuit breaker or the bulkhead
+	OnReject func(op string, err error)
+	// OnStateChange is called when the circuit breaker change state
+	OnStateChange func(from, to State)
+}
+
+// Option configures the resilience decorator
+type Option func(*options)
+
+type options struct {
+	timeout     time.Duration
+	failures    int
+	openFor     time.Duration
+	maxInFlight int
+	hooks       Hooks
+	isFailure   func(err error) bool
+}
+
+// WithTimeout sets the max time a call to the event store can take
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+// WithCircuitBreaker opens the circuit after failures consecutive failed calls. The circuit stays open for openFor
+// before a trial call is let through.
+func WithCircuitBreaker(failures int, openFor time.Duration) Option {
+	return func(o *options) {
+		o.failures = failures
+		o.openFor = openFor
+	}
+}
+
+// WithBulkhead limits the number of concurrent calls to the event store, calls over the limit fail directly
+func WithBulkhead(maxInFlight int) Option {
+	return func(o *options) {
+		o.maxInFlight = maxInFlight
+	}
+}
+
+// WithHooks sets the hooks
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = hooks
+	}
+}
+
+// WithFailure sets the function deciding if an error counts as a failure in the circuit breaker.
+// By default validation, concurrency and no events errors are not counted as failures.
+func WithFailure(isFailure func(err error) bool) Option {
+	return func(o *options) {
+		o.isFailure = isFailure
+	}
+}
+
+// Resilience decorates an event store with timeouts, circuit breaking and bulkheading
+type Resilience[T any] struct {
+	store    eventsourcing.EventStore[T]
+	options  options
+	inFlight chan struct{}
+
+	lock     sync.Mutex
+	state    State
+	failures int
+	openedAt time.Time
+	trial    bool // a trial call is in flight in the half open state
+}
+
+// New decorates the event store
+func New[T any](store eventsourcing.EventStore[T], opts ...Option) *Resilience[T] {
+	o := options{isFailure: isFailure}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := &Resilience[T]{
+		store:   store,
+		options: o,
+	}
+	if o.maxInFlight > 0 {
+		r.inFlight = make(chan struct{}, o.maxInFlight)
+	}
+	return r
+}
+
+// State returns the current state of the circuit breaker
+func (r *Resilience[T]) State() State {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.state
+}
+
+// Save saves the events. The event store has no context to cancel the save, it keeps running after a timeout and
+// holds its place in the bulkhead until it returns. ErrTimeout does not mean the events were not saved, the save can
+// still commit after it's returned, reload the aggregate before retrying. The event store saves a copy of the events
+// and the global versions are only set on the events of the caller when the save returns within the timeout.
+func (r *Resilience[T]) Save(events []eventsourcing.Event[T]) error {
+	return r.call("save", func(done func()) error {
+		if r.options.timeout <= 0 {
+			return r.store.Save(events)
+		}
+		saved := make([]eventsourcing.Event[T], len(events))
+		copy(saved, events)
+		result := make(chan error, 1)
+		go func() {
+			result <- r.store.Save(saved)
+			done()
+		}()
+		timer := time.NewTimer(r.options.timeout)
+		defer timer.Stop()
+		select {
+		case err := <-result:
+			for i := range saved {
+				events[i].GlobalVersion = saved[i].GlobalVersion
+			}
+			return err
+		case <-timer.C:
+			return ErrTimeout
+		}
+	})
+}
+
+// Get gets the events of the aggregate, the timeout is set on the context passed to the event store. The context is
+// canceled when the returned iterator is closed, event stores reading lazily read the events within the timeout.
+func (r *Resilience[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	var events eventsourcing.EventIterator[T]
+	err := r.call("get", func(done func()) error {
+		if r.options.timeout <= 0 {
+			var err error
+			events, err = r.store.Get(ctx, id, aggregateType, afterVersion)
+			return err
+		}
+		callCtx, cancel := context.WithTimeout(ctx, r.options.timeout)
+		it, err := r.store.Get(callCtx, id, aggregateType, afterVersion)
+		if err != nil {
+			cancel()
+			if errors.Is(err, context.DeadlineExceeded) && ctx.Err() == nil {
+				return ErrTimeout
+			}
+			return err
+		}
+		events = &iterator[T]{EventIterator: it, cancel: cancel}
+		return nil
+	})
+	return events, err
+}
+
+// iterator cancels the context of the read when closed
+type iterator[T any] struct {
+	eventsourcing.EventIterator[T]
+	cancel context.CancelFunc
+}
+
+// NextInto reads the next event into event, see eventsourcing.ReuseIterator
+func (i *iterator[T]) NextInto(event *eventsourcing.Event[T]) error {
+	return eventsourcing.NextInto(i.EventIterator, event)
+}
+
+// Close closes the iterator of the read
+func (i *iterator[T]) Close() {
+	i.EventIterator.Close()
+	i.cancel()
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (r *Resilience[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(r.store)
+}
+
+// call runs f if the circuit breaker and bulkhead allows it. f gets a function releasing its place in the bulkhead,
+// used when the event store call continues after a timeout.
+func (r *Resilience[T]) call(op string, f func(done func()) error) error {
+	err := r.allow()
+	if err != nil {
+		r.reject(op, err)
+		return err
+	}
+	if r.inFlight != nil {
+		select {
+		case r.inFlight <- struct{}{}:
+		default:
+			r.endTrial()
+			r.reject(op, ErrBulkheadFull)
+			return ErrBulkheadFull
+		}
+	}
+	var once sync.Once
+	done := func() {
+		if r.inFlight != nil {
+			once.Do(func() { <-r.inFlight })
+		}
+	}
+	start := time.Now()
+	err = f(done)
+	if !errors.Is(err, ErrTimeout) {
+		done()
+	}
+	r.release(err)
+	if r.options.hooks.OnCall != nil {
+		r.options.hooks.OnCall(op, time.Since(start), err)
+	}
+	return err
+}
+
+// allow returns ErrCircuitOpen if the circuit breaker rejects the call
+func (r *Resilience[T]) allow() error {
+	if r.options.failures <= 0 {
+		return nil
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch r.state {
+	case Open:
+		if time.Since(r.openedAt) < r.options.openFor {
+			return ErrCircuitOpen
+		}
+		r.setState(HalfOpen)
+		r.trial = true
+	case HalfOpen:
+		if r.trial {
+			return ErrCircuitOpen
+		}
+		r.trial = true
+	}
+	return nil
+}
+
+// endTrial lets a new trial call through when the allowed call was not made
+func (r *Resilience[T]) endTrial() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.trial = false
+}
+
+// release records the result of an allowed call in the circuit breaker
+func (r *Resilience[T]) release(err error) {
+	if r.options.failures <= 0 {
+		return
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	failed := err != nil && r.options.isFailure(err)
+	if r.state == HalfOpen {
+		r.trial = false
+		if failed {
+			r.openedAt = time.Now()
+			r.setState(Open)
+		} else if err == nil {
+			r.failures = 0
+			r.setState(Closed)
+		}
+		return
+	}
+	if !failed {
+		r.failures = 0
+		return
+	}
+	r.failures++
+	if r.failures >= r.options.failures {
+		r.openedAt = time.Now()
+		r.setState(Open)
+	}
+}
+
+func (r *Resilience[T]) setState(state State) {
+	if r.state == state {
+		return
+	}
+	from := r.state
+	r.state = state
+	if r.options.hooks.OnStateChange != nil {
+		r.options.hooks.OnStateChange(from, state)
+	}
+}
+
+func (r *Resilience[T]) reject(op string, err error) {
+	if r.options.hooks.OnReject != nil {
+		r.options.hooks.OnReject(op, err)
+	}
+}
+
+// isFailure returns false for errors caused by the caller and not by the event store
+func isFailure(err error) bool {
+	switch {
+	case errors.Is(err, eventsourcing.ErrNoEvents),
+		errors.Is(err, eventsourcing.ErrVersionOverflow),
+		errors.Is(err, eventstore.ErrConcurrency),
+		errors.Is(err, eventstore.ErrEventMultipleAggregates),
+		errors.Is(err, eventstore.ErrEventMultipleAggregateTypes),
+		errors.Is(err, eventstore.ErrReasonMissing),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package resilience_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/resilience"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+// fakeStore returns err from its calls after waiting on block if set
+type fakeStore struct {
+	lock  sync.Mutex
+	err   error
+	block chan struct{}
+	saved chan struct{} // signaled when a save has set the global versions
+}
+
+func (f *fakeStore) result() error {
+	f.lock.Lock()
+	block, err := f.block, f.err
+	f.lock.Unlock()
+	if block != nil {
+		<-block
+	}
+	return err
+}
+
+func (f *fakeStore) set(err error, block chan struct{}) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.err = err
+	f.block = block
+}
+
+func (f *fakeStore) Save(events []eventsourcing.Event[any]) error {
+	err := f.result()
+	for i := range events {
+		events[i].GlobalVersion = eventsourcing.Version(i + 1)
+	}
+	if f.saved != nil {
+		f.saved <- struct{}{}
+	}
+	return err
+}
+
+func (f *fakeStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	return nil, f.result()
+}
+
+func TestSuite(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		store := resilience.New[suite.FrequentFlierEvent](memory.Create[suite.FrequentFlierEvent](),
+			resilience.WithTimeout(time.Second),
+			resilience.WithCircuitBreaker(3, time.Second),
+			resilience.WithBulkhead(100),
+		)
+		return store, func() {}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	store := &fakeStore{err: errUnavailable}
+	var states []resilience.State
+	r := resilience.New[any](store,
+		resilience.WithCircuitBreaker(2, 20*time.Millisecond),
+		resilience.WithHooks(resilience.Hooks{OnStateChange: func(from, to resilience.State) { states = append(states, to) }}),
+	)
+
+	r.Save(nil)
+	r.Save(nil)
+	if r.State() != resilience.Open {
+		t.Fatalf("expected the circuit to be open got %s", r.State())
+	}
+	err := r.Save(nil)
+	if !errors.Is(err, resilience.ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen got %v", err)
+	}
+
+	// the trial call after the open duration closes the circuit
+	time.Sleep(30 * time.Millisecond)
+	store.set(nil, nil)
+	err = r.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.State() != resilience.Closed {
+		t.Fatalf("expected the circuit to be closed got %s", r.State())
+	}
+	if len(states) != 3 || states[0] != resilience.Open || states[1] != resilience.HalfOpen || states[2] != resilience.Closed {
+		t.Fatalf("wrong state changes %v", states)
+	}
+}
+
+func TestCircuitBreakerIgnoresCallerErrors(t *testing.T) {
+	store := &fakeStore{err: eventstore.ErrConcurrency}
+	r := resilience.New[any](store, resilience.WithCircuitBreaker(1, time.Minute))
+	r.Save(nil)
+	r.Save(nil)
+	if r.State() != resilience.Closed {
+		t.Fatalf("expected concurrency errors to not open the circuit got %s", r.State())
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	store := &fakeStore{block: block}
+	r := resilience.New[any](store, resilience.WithTimeout(10*time.Millisecond))
+	err := r.Save(nil)
+	if !errors.Is(err, resilience.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout got %v", err)
+	}
+}
+
+func TestTimeoutSaveKeepsRunning(t *testing.T) {
+	block := make(chan struct{})
+	saved := make(chan struct{}, 1)
+	store := &fakeStore{block: block, saved: saved}
+	r := resilience.New[any](store, resilience.WithTimeout(10*time.Millisecond))
+	events := []eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{}}}
+	err := r.Save(events)
+	if !errors.Is(err, resilience.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout got %v", err)
+	}
+	// the save committing after the timeout does not write to the events of the caller
+	close(block)
+	<-saved
+	if events[0].GlobalVersion != 0 {
+		t.Fatalf("expected the events of the caller to be untouched got global version %d", events[0].GlobalVersion)
+	}
+
+	store.set(nil, nil)
+	err = r.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-saved
+	if events[0].GlobalVersion != 1 {
+		t.Fatalf("expected the global version to be set got %d", events[0].GlobalVersion)
+	}
+}
+
+type Born struct{}
+
+// lazyStore reads the events when the iterator is used, like the sql event store
+type lazyStore struct {
+	eventsourcing.EventStore[any]
+}
+
+func (l lazyStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	return &lazyIterator{ctx: ctx, store: l.EventStore, id: id, aggregateType: aggregateType, afterVersion: afterVersion}, nil
+}
+
+type lazyIterator struct {
+	ctx           context.Context
+	store         eventsourcing.EventStore[any]
+	id            string
+	aggregateType string
+	afterVersion  eventsourcing.Version
+	events        eventsourcing.EventIterator[any]
+}
+
+func (l *lazyIterator) Next() (eventsourcing.Event[any], error) {
+	if err := l.ctx.Err(); err != nil {
+		return eventsourcing.Event[any]{}, err
+	}
+	if l.events == nil {
+		events, err := l.store.Get(l.ctx, l.id, l.aggregateType, l.afterVersion)
+		if err != nil {
+			return eventsourcing.Event[any]{}, err
+		}
+		l.events = events
+	}
+	return l.events.Next()
+}
+
+func (l *lazyIterator) Close() {
+	if l.events != nil {
+		l.events.Close()
+	}
+}
+
+func TestTimeoutLazyRead(t *testing.T) {
+	store := memory.Create[any]()
+	err := store.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := resilience.New[any](lazyStore{store}, resilience.WithTimeout(time.Second))
+	events, err := r.Get(context.Background(), "1", "Person", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer events.Close()
+	event, err := events.Next()
+	if err != nil {
+		t.Fatalf("expected the event to be read after Get returned got %v", err)
+	}
+	if event.Version != 1 {
+		t.Fatalf("expected version 1 got %d", event.Version)
+	}
+}
+
+func TestBulkhead(t *testing.T) {
+	block := make(chan struct{})
+	store := &fakeStore{block: block}
+	var rejected []error
+	r := resilience.New[any](store,
+		resilience.WithBulkhead(1),
+		resilience.WithTimeout(10*time.Millisecond),
+		resilience.WithHooks(resilience.Hooks{OnReject: func(op string, err error) { rejected = append(rejected, err) }}),
+	)
+
+	// the timed out save keeps its place until the event store returns
+	err := r.Save(nil)
+	if !errors.Is(err, resilience.ErrTimeout) {
+		t.Fatalf("expected ErrTimeout got %v", err)
+	}
+	err = r.Save(nil)
+	if !errors.Is(err, resilience.ErrBulkheadFull) {
+		t.Fatalf("expected ErrBulkheadFull got %v", err)
+	}
+	if len(rejected) != 1 {
+		t.Fatalf("expected one rejected call got %d", len(rejected))
+	}
+
+	close(block)
+	store.set(nil, nil)
+	// wait for the blocked save to release its place
+	deadline := time.Now().Add(time.Second)
+	for {
+		err = r.Save(nil)
+		if err == nil || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,210 @@
+package retry
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+)
+
+// Classifier returns true if the error is transient and the call can be retried
+type Classifier func(err error) bool
+
+// Policy is the retry policy of an operation
+type Policy struct {
+	// MaxAttempts is the max number of calls including the first, one or less makes no retries
+	MaxAttempts int
+	// InitialBackoff is the max wait before the first retry
+	InitialBackoff time.Duration
+	// MaxBackoff caps the backoff
+	MaxBackoff time.Duration
+	// Multiplier grows the backoff after each retry
+	Multiplier float64
+}
+
+// DefaultPolicy makes three attempts with a backoff starting on 50ms
+var DefaultPolicy = Policy{
+	MaxAttempts:    3,
+	InitialBackoff: 50 * time.Millisecond,
+	MaxBackoff:     time.Second,
+	Multiplier:     2,
+}
+
+// Option configures the retry decorator
+type Option func(*options)
+
+type options struct {
+	save           Policy
+	get            Policy
+	classifier     Classifier
+	saveClassifier Classifier
+	onRetry        func(op string, attempt int, err error)
+}
+
+// WithSavePolicy sets the retry policy of Save
+func WithSavePolicy(p Policy) Option {
+	return func(o *options) {
+		o.save = p
+	}
+}
+
+// WithGetPolicy sets the retry policy of Get
+func WithGetPolicy(p Policy) Option {
+	return func(o *options) {
+		o.get = p
+	}
+}
+
+// WithClassifier sets the function deciding which errors of Get are transient, the event store backends provide
+// classifiers for their errors. IsTransient is used by default.
+func WithClassifier(c Classifier) Option {
+	return func(o *options) {
+		o.classifier = c
+	}
+}
+
+// WithSaveClassifier sets the function deciding which errors of Save can be retried. It must only return true for
+// errors where the events are known not to be committed, the event store backends provide classifiers for their
+// errors. IsNotCommitted is used by default.
+func WithSaveClassifier(c Classifier) Option {
+	return func(o *options) {
+		o.saveClassifier = c
+	}
+}
+
+// WithOnRetry sets a function called before each retry
+func WithOnRetry(f func(op string, attempt int, err error)) Option {
+	return func(o *options) {
+		o.onRetry = f
+	}
+}
+
+// Retry decorates an event store retrying calls failing with transient errors
+type Retry[T any] struct {
+	store   eventsourcing.EventStore[T]
+	options options
+	lock    sync.Mutex
+	rand    *rand.Rand
+}
+
+// New decorates the event store
+func New[T any](store eventsourcing.EventStore[T], opts ...Option) *Retry[T] {
+	o := options{
+		save:           DefaultPolicy,
+		get:            DefaultPolicy,
+		classifier:     IsTransient,
+		saveClassifier: IsNotCommitted,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Retry[T]{
+		store:   store,
+		options: o,
+		rand:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Save saves the events, retrying the errors where the events are known not to be committed. Ambiguous errors, like a
+// connection reset or a timeout after the write was sent, are returned as is as the events can be committed. Retrying
+// them could save the events twice or fail on a version conflict with its own events.
+func (r *Retry[T]) Save(events []eventsourcing.Event[T]) error {
+	return r.do(context.Background(), "save", r.options.save, r.options.saveClassifier, func() error {
+		return r.store.Save(events)
+	})
+}
+
+// Get gets the events of the aggregate, retrying transient errors
+func (r *Retry[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	var iterator eventsourcing.EventIterator[T]
+	err := r.do(ctx, "get", r.options.get, r.options.classifier, func() error {
+		var err error
+		iterator, err = r.store.Get(ctx, id, aggregateType, afterVersion)
+		return err
+	})
+	return iterator, err
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (r *Retry[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(r.store)
+}
+
+func (r *Retry[T]) do(ctx context.Context, op string, policy Policy, classifier Classifier, f func() error) error {
+	backoff := policy.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		err := f()
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err, classifier) {
+			return err
+		}
+		if r.options.onRetry != nil {
+			r.options.onRetry(op, attempt, err)
+		}
+		timer := time.NewTimer(r.jitter(backoff))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+		backoff = time.Duration(float64(backoff) * policy.Multiplier)
+		if policy.MaxBackoff > 0 && backoff > policy.MaxBackoff {
+			backoff = policy.MaxBackoff
+		}
+	}
+}
+
+// retryable never retries version conflicts or invalid events, other errors are left to the classifier
+func retryable(err error, classifier Classifier) bool {
+	switch {
+	case errors.Is(err, eventstore.ErrConcurrency),
+		errors.Is(err, eventstore.ErrEventMultipleAggregates),
+		errors.Is(err, eventstore.ErrEventMultipleAggregateTypes),
+		errors.Is(err, eventstore.ErrReasonMissing),
+		errors.Is(err, eventsourcing.ErrVersionOverflow),
+		errors.Is(err, eventsourcing.ErrNoEvents),
+		errors.Is(err, context.Canceled):
+		return false
+	}
+	return classifier(err)
+}
+
+// jitter returns a random duration between zero and backoff
+func (r *Retry[T]) jitter(backoff time.Duration) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return time.Duration(r.rand.Int63n(int64(backoff) + 1))
+}
+
+// IsNotCommitted returns true for the transient errors where the call never reached the database, a bad connection
+// the driver did not use or a refused connection. Saves failing with them can be retried without saving the events
+// twice.
+func IsNotCommitted(err error) bool {
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, syscall.ECONNREFUSED)
+}
+
+// IsTransient returns true for network and connection errors that can succeed on a retry
+func IsTransient(err error) bool {
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout(),
+		errors.Is(err, driver.ErrBadConn),
+		errors.Is(err, io.ErrUnexpectedEOF),
+		errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNREFUSED),
+		errors.Is(err, syscall.EPIPE):
+		return true
+	}
+	return false
+}
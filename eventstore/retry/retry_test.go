@@ -0,0 +1,124 @@
+package retry_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/retry"
+)
+
+// flakyStore fails with errs in order before succeeding
+type flakyStore struct {
+	errs  []error
+	calls int
+}
+
+func (f *flakyStore) next() error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+	return err
+}
+
+func (f *flakyStore) Save(events []eventsourcing.Event[any]) error {
+	return f.next()
+}
+
+func (f *flakyStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	return nil, f.next()
+}
+
+var fast = retry.Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: time.Millisecond, Multiplier: 2}
+
+func TestRetryTransient(t *testing.T) {
+	store := &flakyStore{errs: []error{driver.ErrBadConn, driver.ErrBadConn}}
+	var retries []int
+	r := retry.New[any](store, retry.WithSavePolicy(fast), retry.WithOnRetry(func(op string, attempt int, err error) {
+		retries = append(retries, attempt)
+	}))
+	err := r.Save(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 3 || len(retries) != 2 {
+		t.Fatalf("expected 3 calls and 2 retries got %d and %d", store.calls, len(retries))
+	}
+}
+
+func TestRetryMaxAttempts(t *testing.T) {
+	store := &flakyStore{errs: []error{driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn, driver.ErrBadConn}}
+	r := retry.New[any](store, retry.WithGetPolicy(fast))
+	_, err := r.Get(context.Background(), "1", "Person", 0)
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected the last error got %v", err)
+	}
+	if store.calls != 3 {
+		t.Fatalf("expected 3 calls got %d", store.calls)
+	}
+}
+
+func TestNoRetryOnConcurrencyError(t *testing.T) {
+	store := &flakyStore{errs: []error{eventstore.ErrConcurrency}}
+	// a classifier retrying everything still don't retry version conflicts
+	r := retry.New[any](store, retry.WithSavePolicy(fast), retry.WithSaveClassifier(func(err error) bool { return true }))
+	err := r.Save(nil)
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected ErrConcurrency got %v", err)
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected one call got %d", store.calls)
+	}
+}
+
+func TestNoRetryOnPermanentError(t *testing.T) {
+	store := &flakyStore{errs: []error{errors.New("syntax error")}}
+	r := retry.New[any](store, retry.WithSavePolicy(fast))
+	r.Save(nil)
+	if store.calls != 1 {
+		t.Fatalf("expected one call got %d", store.calls)
+	}
+}
+
+func TestNoRetryOnAmbiguousSaveError(t *testing.T) {
+	// the connection was reset after the write was sent, the events can be committed
+	store := &flakyStore{errs: []error{syscall.ECONNRESET, syscall.ECONNRESET}}
+	r := retry.New[any](store, retry.WithSavePolicy(fast), retry.WithGetPolicy(fast))
+	err := r.Save(nil)
+	if !errors.Is(err, syscall.ECONNRESET) {
+		t.Fatalf("expected the ambiguous error got %v", err)
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected one call got %d", store.calls)
+	}
+	// reads are retried
+	_, err = r.Get(context.Background(), "1", "Person", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.calls != 3 {
+		t.Fatalf("expected the get to be retried got %d calls", store.calls)
+	}
+}
+
+func TestRetryStopsOnContextDone(t *testing.T) {
+	store := &flakyStore{errs: []error{driver.ErrBadConn, driver.ErrBadConn}}
+	r := retry.New[any](store, retry.WithGetPolicy(retry.Policy{MaxAttempts: 3, InitialBackoff: time.Hour, Multiplier: 1}))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := r.Get(ctx, "1", "Person", 0)
+	if !errors.Is(err, driver.ErrBadConn) {
+		t.Fatalf("expected the last error got %v", err)
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected one call got %d", store.calls)
+	}
+}
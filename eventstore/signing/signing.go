@@ -0,0 +1,225 @@
+// Package signing signs the events with Ed25519 when they are saved and verifies the signatures when they are read,
+// letting consumers of events forwarded through brokers or other systems authenticate their origin.
+package signing
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Metadata keys of the signature
+const (
+	MetadataSignature = "signature"
+	MetadataKeyID     = "signature_key_id"
+)
+
+var (
+	// ErrUnsigned when the event has no signature
+	ErrUnsigned = errors.New("event is not signed")
+	// ErrUnknownKey when the event is signed with a key not among the public keys
+	ErrUnknownKey = errors.New("event is signed with an unknown key")
+	// ErrInvalidSignature when the signature does not match the event
+	ErrInvalidSignature = errors.New("invalid event signature")
+	// ErrGlobalEventsNotSupported when the decorated event store can't return events in the global order
+	ErrGlobalEventsNotSupported = errors.New("event store does not support global events")
+)
+
+// Keys are the public keys verifying the signatures by key id, keep retired keys to verify the events they signed
+type Keys map[string]ed25519.PublicKey
+
+// signed is the signed content of an event. The timestamp is signed in nanoseconds as the event stores keep the
+// instant but not the location or monotonic clock, the data and metadata are signed as JSON.
+type signed struct {
+	AggregateType string                 `json:"aggregate_type"`
+	AggregateID   string                 `json:"aggregate_id"`
+	Version       eventsourcing.Version  `json:"version"`
+	Reason        string                 `json:"reason"`
+	Timestamp     int64                  `json:"timestamp"`
+	Data          interface{}            `json:"data"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// message returns the bytes signed for the event, the metadata without the signature
+func message[T any](event eventsourcing.Event[T]) ([]byte, error) {
+	var metadata map[string]interface{}
+	for k, v := range event.Metadata {
+		if k == MetadataSignature || k == MetadataKeyID {
+			continue
+		}
+		if metadata == nil {
+			metadata = make(map[string]interface{}, len(event.Metadata))
+		}
+		metadata[k] = v
+	}
+	return json.Marshal(signed{
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Version:       event.Version,
+		Reason:        event.Reason(),
+		Timestamp:     event.Timestamp.UnixNano(),
+		Data:          event.Data,
+		Metadata:      metadata,
+	})
+}
+
+// Sign returns the event signed with the key, the signature and key id are set in a copy of its metadata
+func Sign[T any](event eventsourcing.Event[T], keyID string, key ed25519.PrivateKey) (eventsourcing.Event[T], error) {
+	m, err := message(event)
+	if err != nil {
+		return event, fmt.Errorf("signing: %w", err)
+	}
+	metadata := make(map[string]interface{}, len(event.Metadata)+2)
+	for k, v := range event.Metadata {
+		metadata[k] = v
+	}
+	metadata[MetadataSignature] = base64.StdEncoding.EncodeToString(ed25519.Sign(key, m))
+	metadata[MetadataKeyID] = keyID
+	event.Metadata = metadata
+	return event, nil
+}
+
+// Verify returns nil if the event is signed by one of the keys
+func Verify[T any](event eventsourcing.Event[T], keys Keys) error {
+	signature, ok := event.Metadata[MetadataSignature].(string)
+	if !ok {
+		return fmt.Errorf("%w: %s %s version %d", ErrUnsigned, event.AggregateType, event.AggregateID, event.Version)
+	}
+	keyID, _ := event.Metadata[MetadataKeyID].(string)
+	key, ok := keys[keyID]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownKey, keyID)
+	}
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return fmt.Errorf("%w: %s %s version %d", ErrInvalidSignature, event.AggregateType, event.AggregateID, event.Version)
+	}
+	m, err := message(event)
+	if err != nil {
+		return fmt.Errorf("signing: %w", err)
+	}
+	if !ed25519.Verify(key, m, sig) {
+		return fmt.Errorf("%w: %s %s version %d", ErrInvalidSignature, event.AggregateType, event.AggregateID, event.Version)
+	}
+	return nil
+}
+
+// Option configures the signing decorator
+type Option func(*options)
+
+type options struct {
+	allowUnsigned bool
+}
+
+// WithAllowUnsigned lets events without signature be read, for event stores with events saved before signing was
+// turned on. Events with a signature are still verified.
+func WithAllowUnsigned() Option {
+	return func(o *options) {
+		o.allowUnsigned = true
+	}
+}
+
+// Signing decorates an event store signing the saved events and verifying the read events. The data is signed as
+// JSON, the event types have to marshal the same after being read as when they were saved.
+type Signing[T any] struct {
+	store   eventsourcing.EventStore[T]
+	keyID   string
+	key     ed25519.PrivateKey
+	keys    Keys
+	options options
+}
+
+// New decorates the event store signing the saved events with the key under the key id. The read events are verified
+// with the public keys, the public key of the signing key is added under its id.
+func New[T any](store eventsourcing.EventStore[T], keyID string, key ed25519.PrivateKey, keys Keys, opts ...Option) *Signing[T] {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	all := make(Keys, len(keys)+1)
+	for id, k := range keys {
+		all[id] = k
+	}
+	all[keyID] = key.Public().(ed25519.PublicKey)
+	return &Signing[T]{store: store, keyID: keyID, key: key, keys: all, options: o}
+}
+
+// Save signs the events and saves them to the event store. The signature is set in the metadata of the events in the
+// slice.
+func (s *Signing[T]) Save(events []eventsourcing.Event[T]) error {
+	for i, event := range events {
+		signed, err := Sign(event, s.keyID, s.key)
+		if err != nil {
+			return err
+		}
+		events[i] = signed
+	}
+	return s.store.Save(events)
+}
+
+// Get returns the events of the aggregate, an event failing verification ends the iteration with the error
+func (s *Signing[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	it, err := s.store.Get(ctx, id, aggregateType, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	return &iterator[T]{iterator: it, signing: s}, nil
+}
+
+// GlobalEvents returns the verified events in the global order, ErrGlobalEventsNotSupported is returned if the event
+// store is not a global event store
+func (s *Signing[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
+	global, ok := s.store.(eventsourcing.GlobalEventStore[T])
+	if !ok {
+		return nil, ErrGlobalEventsNotSupported
+	}
+	events, err := global.GlobalEvents(start, count)
+	if err != nil {
+		return nil, err
+	}
+	for _, event := range events {
+		err = s.verify(event)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return events, nil
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (s *Signing[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(s.store)
+}
+
+func (s *Signing[T]) verify(event eventsourcing.Event[T]) error {
+	if _, ok := event.Metadata[MetadataSignature]; !ok && s.options.allowUnsigned {
+		return nil
+	}
+	return Verify(event, s.keys)
+}
+
+type iterator[T any] struct {
+	iterator eventsourcing.EventIterator[T]
+	signing  *Signing[T]
+}
+
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	event, err := i.iterator.Next()
+	if err != nil {
+		return event, err
+	}
+	err = i.signing.verify(event)
+	if err != nil {
+		return eventsourcing.Event[T]{}, err
+	}
+	return event, nil
+}
+
+func (i *iterator[T]) Close() {
+	i.iterator.Close()
+}
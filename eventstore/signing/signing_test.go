@@ -0,0 +1,147 @@
+package signing_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/signing"
+)
+
+type Registered struct {
+	Email string
+}
+
+func key(t *testing.T) ed25519.PrivateKey {
+	t.Helper()
+	_, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return private
+}
+
+func get(store eventsourcing.EventStore[any]) ([]eventsourcing.Event[any], error) {
+	it, err := store.Get(context.Background(), "1", "Person", 0)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	var events []eventsourcing.Event[any]
+	for {
+		event, err := it.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return events, err
+		}
+		events = append(events, event)
+	}
+}
+
+func TestSigning(t *testing.T) {
+	store := memory.Create[any]()
+	s := signing.New[any](store, "k1", key(t), nil)
+	data := &Registered{Email: "kalle@example.com"}
+	events := []eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: data, Metadata: map[string]interface{}{"user_id": "admin"}}}
+	err := s.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events[0].Metadata[signing.MetadataKeyID] != "k1" || events[0].GlobalVersion != 1 {
+		t.Fatalf("expected the saved event signed with k1 and its global version set got %+v", events[0])
+	}
+
+	read, err := get(s)
+	if err != nil || len(read) != 1 {
+		t.Fatalf("expected the signed event got %v %v", read, err)
+	}
+	global, err := s.GlobalEvents(1, 10)
+	if err != nil || len(global) != 1 {
+		t.Fatalf("expected the signed event in the global order got %v %v", global, err)
+	}
+
+	// the memory store keeps the data pointer, changing it tampers with the stored event
+	data.Email = "mallory@example.com"
+	_, err = get(s)
+	if !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature got %v", err)
+	}
+	_, err = s.GlobalEvents(1, 10)
+	if !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature from global events got %v", err)
+	}
+}
+
+func TestUnsigned(t *testing.T) {
+	store := memory.Create[any]()
+	err := store.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Registered{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	private := key(t)
+	_, err = get(signing.New[any](store, "k1", private, nil))
+	if !errors.Is(err, signing.ErrUnsigned) {
+		t.Fatalf("expected ErrUnsigned got %v", err)
+	}
+	events, err := get(signing.New[any](store, "k1", private, nil, signing.WithAllowUnsigned()))
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the unsigned event allowed got %v %v", events, err)
+	}
+}
+
+func TestRotatedKey(t *testing.T) {
+	store := memory.Create[any]()
+	retired := key(t)
+	err := signing.New[any](store, "k1", retired, nil).Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Registered{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = get(signing.New[any](store, "k2", key(t), nil))
+	if !errors.Is(err, signing.ErrUnknownKey) {
+		t.Fatalf("expected ErrUnknownKey got %v", err)
+	}
+	s := signing.New[any](store, "k2", key(t), signing.Keys{"k1": retired.Public().(ed25519.PublicKey)})
+	err = s.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 2, Timestamp: time.Now(), Data: &Registered{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := get(s)
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected the events signed with both keys got %v %v", events, err)
+	}
+}
+
+// TestVerifyForwarded verifies an event after a JSON round trip, like an event forwarded through a message broker
+func TestVerifyForwarded(t *testing.T) {
+	private := key(t)
+	event, err := signing.Sign(eventsourcing.Event[any]{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Registered{Email: "kalle@example.com"}, Metadata: map[string]interface{}{"attempt": 1}}, "k1", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := json.Marshal(event.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarded := eventsourcing.Event[any]{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: event.Timestamp.UTC(), Data: &Registered{Email: "kalle@example.com"}}
+	err = json.Unmarshal(b, &forwarded.Metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := signing.Keys{"k1": private.Public().(ed25519.PublicKey)}
+	err = signing.Verify(forwarded, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	forwarded.Metadata["attempt"] = 2
+	err = signing.Verify(forwarded, keys)
+	if !errors.Is(err, signing.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature on changed metadata got %v", err)
+	}
+}
@@ -4,7 +4,7 @@ go 1.18
 
 require (
 	github.com/hallgren/eventsourcing v0.0.20
-	github.com/proullon/ramsql v0.0.0-20211120092837-c8d0a408b939
+	github.com/mattn/go-sqlite3 v1.14.16
 )
 
 //replace github.com/hallgren/eventsourcing => ../..
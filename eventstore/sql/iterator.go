@@ -2,7 +2,6 @@ package sql
 
 import (
 	"database/sql"
-	"time"
 
 	"github.com/hallgren/eventsourcing"
 )
@@ -10,61 +9,157 @@ import (
 type iterator[T any] struct {
 	rows       *sql.Rows
 	serializer eventsourcing.Serializer[T]
+	// reused by NextInto
+	row      *row
+	reason   string
+	data     map[string]T
+	metadata map[string]interface{}
+}
+
+// row holds the scanned columns of an event, the scan destinations are allocated once per iterator
+type row struct {
+	seq, version, timestamp                            int64
+	id, reason, aggregateType, data, metadata, eventID sql.RawBytes
+	validTime                                          sql.NullString
+	dest                                               []interface{}
+}
+
+func newRow() *row {
+	r := &row{}
+	r.dest = []interface{}{&r.seq, &r.id, &r.version, &r.reason, &r.aggregateType, &r.timestamp, &r.validTime, &r.data, &r.metadata, &r.eventID}
+	return r
 }
 
 // Next return the next event
 func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
-	var globalVersion eventsourcing.Version
-	var eventMetadata map[string]interface{}
-	var version eventsourcing.Version
-	var id, reason, typ, timestamp string
-	var data, metadata string
 	if !i.rows.Next() {
 		if err := i.rows.Err(); err != nil {
 			return eventsourcing.Event[T]{}, err
 		}
 		return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
 	}
-	if err := i.rows.Scan(&globalVersion, &id, &version, &reason, &typ, &timestamp, &data, &metadata); err != nil {
+	raw, err := scanRaw(i.rows, i.serializer)
+	if err != nil {
 		return eventsourcing.Event[T]{}, err
 	}
-
-	t, err := time.Parse(time.RFC3339, timestamp)
+	event, ok, err := i.serializer.DecodeRaw(raw)
 	if err != nil {
 		return eventsourcing.Event[T]{}, err
 	}
-
-	f, ok := i.serializer.Type(typ, reason)
 	if !ok {
-		// if the typ/reason is not register jump over the event
+		// if the typ/reason is not register or the event is skipped jump over the event
 		return i.Next()
 	}
+	return event, nil
+}
+
+// NextInto reads the next event into event. The strings are reused while they are unchanged, the data is reused per
+// reason and the metadata map is shared by the events read.
+func (i *iterator[T]) NextInto(event *eventsourcing.Event[T]) error {
+	for {
+		if !i.rows.Next() {
+			if err := i.rows.Err(); err != nil {
+				return err
+			}
+			return eventsourcing.ErrNoMoreEvents
+		}
+		raw, err := i.scanInto(event)
+		if err != nil {
+			return err
+		}
+		if i.data == nil {
+			i.data = make(map[string]T)
+		}
+		event.Data = i.data[raw.Reason]
+		ok, err := i.serializer.DecodeRawInto(raw, event)
+		if err != nil {
+			return err
+		}
+		if ok {
+			i.data[raw.Reason] = event.Data
+			return nil
+		}
+	}
+}
 
-	eventData := f()
-	err = i.serializer.Unmarshal([]byte(data), &eventData)
+// scanInto scans the current row without copying the data, the data is only valid until the next row
+func (i *iterator[T]) scanInto(event *eventsourcing.Event[T]) (eventsourcing.RawEvent, error) {
+	if i.row == nil {
+		i.row = newRow()
+	}
+	r := i.row
+	err := i.rows.Scan(r.dest...)
 	if err != nil {
-		return eventsourcing.Event[T]{}, err
+		return eventsourcing.RawEvent{}, err
+	}
+	validTime, err := parseValidTime(r.validTime)
+	if err != nil {
+		return eventsourcing.RawEvent{}, err
+	}
+	i.reason = reuseString(i.reason, r.reason)
+	raw := eventsourcing.RawEvent{
+		AggregateID:   reuseString(event.AggregateID, r.id),
+		AggregateType: reuseString(event.AggregateType, r.aggregateType),
+		Version:       eventsourcing.Version(r.version),
+		GlobalVersion: eventsourcing.Version(r.seq),
+		Reason:        i.reason,
+		Timestamp:     fromUnixNano(r.timestamp),
+		ValidTime:     validTime,
+		Data:          r.data,
+	}
+	if len(r.eventID) > 0 {
+		raw.EventID = string(r.eventID)
 	}
-	if metadata != "" {
-		err = i.serializer.Unmarshal([]byte(metadata), &eventMetadata)
+	if len(r.metadata) > 0 {
+		if i.metadata == nil {
+			i.metadata = make(map[string]interface{})
+		}
+		for key := range i.metadata {
+			delete(i.metadata, key)
+		}
+		err = i.serializer.Unmarshal(r.metadata, &i.metadata)
 		if err != nil {
-			return eventsourcing.Event[T]{}, err
+			return raw, err
 		}
+		raw.Metadata = i.metadata
 	}
+	return raw, nil
+}
 
-	event := eventsourcing.Event[T]{
-		AggregateID:   id,
-		Version:       version,
-		GlobalVersion: globalVersion,
-		AggregateType: typ,
-		Timestamp:     t,
-		Data:          eventData,
-		Metadata:      eventMetadata,
+// reuseString returns s if it holds the bytes, avoiding the allocation of a new string
+func reuseString(s string, b []byte) string {
+	if s == string(b) {
+		return s
 	}
-	return event, nil
+	return string(b)
 }
 
 // Close closes the iterator
 func (i *iterator[T]) Close() {
 	i.rows.Close()
 }
+
+// scanRaw scans the current event row, the metadata is unmarshaled by the serializer
+func scanRaw[T any](rows *sql.Rows, serializer eventsourcing.Serializer[T]) (eventsourcing.RawEvent, error) {
+	raw := eventsourcing.RawEvent{}
+	var timestamp int64
+	var validTime, eventID sql.NullString
+	var metadata []byte
+	err := rows.Scan(&raw.GlobalVersion, &raw.AggregateID, &raw.Version, &raw.Reason, &raw.AggregateType, &timestamp, &validTime, &raw.Data, &metadata, &eventID)
+	if err != nil {
+		return raw, err
+	}
+	raw.ValidTime, err = parseValidTime(validTime)
+	if err != nil {
+		return raw, err
+	}
+	if len(metadata) > 0 {
+		err = serializer.Unmarshal(metadata, &raw.Metadata)
+		if err != nil {
+			return raw, err
+		}
+	}
+	raw.EventID = eventID.String
+	raw.Timestamp = fromUnixNano(timestamp)
+	return raw, nil
+}
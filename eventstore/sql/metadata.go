@@ -0,0 +1,150 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrMetadataKeyNotIndexed when events are searched on a metadata key not set with WithMetadataIndex
+var ErrMetadataKeyNotIndexed = errors.New("metadata key not indexed")
+
+// metadataTable returns the name of the side table holding the indexed metadata values
+func (s *SQL[T]) metadataTable() string {
+	return s.table + "_metadata"
+}
+
+// metadataIndex returns the statements creating the side table of the indexed metadata values
+func (s *SQL[T]) metadataIndex() []string {
+	return []string{
+		fmt.Sprintf(`create table if not exists %s (seq INTEGER NOT NULL, name VARCHAR NOT NULL, value VARCHAR NOT NULL);`, s.metadataTable()),
+		fmt.Sprintf(`create index if not exists %s on %s (name, value, seq);`, s.indexName("metadata_name_value"), s.metadataTable()),
+	}
+}
+
+// MigrateMetadataIndex creates the side table of the metadata keys set with WithMetadataIndex if it doesn't exist and
+// indexes the events saved before the keys were added. It's run again when keys are added to the option.
+func (s *SQL[T]) MigrateMetadataIndex() error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	for _, stmt := range s.metadataIndex() {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	if len(s.metadataKeys) == 0 {
+		return tx.Commit()
+	}
+	// index the keys from scratch to not index an event twice
+	where, args := whereIn(nil, nil, "name", s.metadataKeys)
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(`Delete from %s where %s`, s.metadataTable(), strings.Join(where, " and ")), args...)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`Select seq, metadata from %s where metadata is not null`, s.table))
+	if err != nil {
+		return err
+	}
+	metadata := make(map[int64]map[string]interface{})
+	for rows.Next() {
+		var seq int64
+		var b []byte
+		var eventMetadata map[string]interface{}
+		if err := rows.Scan(&seq, &b); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(b) == 0 {
+			continue
+		}
+		if err := s.serializer.Unmarshal(b, &eventMetadata); err != nil {
+			rows.Close()
+			return fmt.Errorf("event %d: %w", seq, err)
+		}
+		metadata[seq] = eventMetadata
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for seq, eventMetadata := range metadata {
+		err = s.indexMetadata(ctx, tx, seq, eventMetadata)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// indexMetadata inserts the values of the indexed metadata keys of the event in the side table. Only string, number
+// and bool values are indexed.
+func (s *SQL[T]) indexMetadata(ctx context.Context, tx *sql.Tx, seq int64, metadata map[string]interface{}) error {
+	for _, key := range s.metadataKeys {
+		value, ok := metadataValue(metadata[key])
+		if !ok {
+			continue
+		}
+		_, err := tx.ExecContext(ctx, fmt.Sprintf(`Insert into %s (seq, name, value) values (?, ?, ?)`, s.metadataTable()), seq, key, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// metadataValue returns the metadata value as it's indexed, numbers are formatted the same before and after being
+// round tripped as float64 via json, or as json.Number with the UseNumber option
+func metadataValue(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), true
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32), true
+	case bool, int, int32, int64, uint, uint32, uint64:
+		return fmt.Sprint(v), true
+	}
+	return "", false
+}
+
+// EventsByMetadata returns the events with the value on the metadata key in global order, for audit queries like all
+// actions made by a user. The key has to be indexed with WithMetadataIndex.
+func (s *SQL[T]) EventsByMetadata(ctx context.Context, key string, value interface{}) ([]eventsourcing.Event[T], error) {
+	if !s.metadataIndexed(key) {
+		return nil, fmt.Errorf("%w: %s", ErrMetadataKeyNotIndexed, key)
+	}
+	v, ok := metadataValue(value)
+	if !ok {
+		return nil, fmt.Errorf("metadata value of type %T can't be searched", value)
+	}
+	selectStm := fmt.Sprintf(`Select e.seq, e.id, e.version, e.reason, e.type, e.timestamp, e.valid_time, e.data, e.metadata, e.event_id from %s e join %s m on m.seq = e.seq where m.name=? and m.value=? order by e.seq asc`, s.table, s.metadataTable())
+	rows, err := s.db.QueryContext(ctx, selectStm, key, v)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.eventsFromRows(rows)
+}
+
+func (s *SQL[T]) metadataIndexed(key string) bool {
+	for _, k := range s.metadataKeys {
+		if k == key {
+			return true
+		}
+	}
+	return false
+}
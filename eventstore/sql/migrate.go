@@ -1,22 +1,291 @@
 package sql
 
-import "context"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
 
-const createTable = `create table events (seq INTEGER PRIMARY KEY AUTOINCREMENT, id VARCHAR NOT NULL, version INTEGER, reason VARCHAR, type VARCHAR, timestamp VARCHAR, data BLOB, metadata BLOB);`
+	"github.com/hallgren/eventsourcing"
+)
+
+// the timestamp is stored as nanoseconds since the unix epoch making it compare in time order
+const createTable = `create table %s (seq INTEGER PRIMARY KEY AUTOINCREMENT, id VARCHAR NOT NULL, version INTEGER, reason VARCHAR, type VARCHAR, timestamp INTEGER, valid_time VARCHAR, data BLOB, metadata BLOB, event_id VARCHAR, correlation_id VARCHAR);`
 
 // Migrate the database
 func (s *SQL[T]) Migrate() error {
-	sqlStmt := []string{
-		createTable,
-		`create unique index id_type_version on events (id, type, version);`,
-		`create index id_type on events (id, type);`,
+	sqlStmt := append([]string{fmt.Sprintf(createTable, s.table)}, s.indexes()...)
+	if len(s.metadataKeys) > 0 {
+		sqlStmt = append(sqlStmt, s.metadataIndex()...)
 	}
 	return s.migrate(sqlStmt)
 }
 
+// indexes returns the statements creating the indexes of the events table
+func (s *SQL[T]) indexes() []string {
+	return []string{
+		fmt.Sprintf(`create unique index %s on %s (id, type, version);`, s.indexName("id_type_version"), s.table),
+		fmt.Sprintf(`create index %s on %s (id, type);`, s.indexName("id_type"), s.table),
+		fmt.Sprintf(`create index %s on %s (timestamp);`, s.indexName("timestamp"), s.table),
+		fmt.Sprintf(`create unique index %s on %s (event_id);`, s.indexName("event_id"), s.table),
+		fmt.Sprintf(`create index %s on %s (correlation_id);`, s.indexName("correlation_id"), s.table),
+	}
+}
+
+// MigrateTimestampEpoch converts an events table storing the timestamp as RFC3339 strings to the epoch timestamp
+// column. The table is copied to a new table keeping the seq of the events, the old table is dropped and the new
+// one renamed in its place. The valid_time and event_id columns have to be added with MigrateValidTime and
+// MigrateEventID before, the correlation_id column is set from the metadata of the copied events.
+func (s *SQL[T]) MigrateTimestampEpoch() error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// read the timestamps before copying as the rows keep the connection busy
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`Select seq, timestamp from %s order by seq asc`, s.table))
+	if err != nil {
+		return err
+	}
+	var seqs []int64
+	var timestamps []int64
+	for rows.Next() {
+		var seq int64
+		var timestamp string
+		if err := rows.Scan(&seq, &timestamp); err != nil {
+			rows.Close()
+			return err
+		}
+		t, err := time.Parse(time.RFC3339Nano, timestamp)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("event %d: %w", seq, err)
+		}
+		seqs = append(seqs, seq)
+		timestamps = append(timestamps, t.UnixNano())
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	migrated := s.table + "_epoch"
+	_, err = tx.ExecContext(ctx, fmt.Sprintf(createTable, migrated))
+	if err != nil {
+		return err
+	}
+	copyStm := fmt.Sprintf(`Insert into %s (seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id) select seq, id, version, reason, type, ?, valid_time, data, metadata, event_id from %s where seq=?`, migrated, s.table)
+	for i, seq := range seqs {
+		_, err = tx.ExecContext(ctx, copyStm, timestamps[i], seq)
+		if err != nil {
+			return err
+		}
+	}
+	stmts := append([]string{
+		fmt.Sprintf(`drop table %s;`, s.table),
+		fmt.Sprintf(`alter table %s rename to %s;`, migrated, s.table),
+	}, s.indexes()...)
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.backfillCorrelationID(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// MigrateCorrelationID adds the indexed correlation_id column used by EventsByCorrelationID to an events table
+// created before the column was added. The column is set from the metadata of the existing events.
+func (s *SQL[T]) MigrateCorrelationID() error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmts := []string{
+		fmt.Sprintf(`alter table %s add column correlation_id VARCHAR;`, s.table),
+		fmt.Sprintf(`create index %s on %s (correlation_id);`, s.indexName("correlation_id"), s.table),
+	}
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	err = s.backfillCorrelationID(ctx, tx)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backfillCorrelationID sets the correlation_id column from the metadata of the events where it's not set
+func (s *SQL[T]) backfillCorrelationID(ctx context.Context, tx *sql.Tx) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf(`Select seq, metadata from %s where correlation_id is null and metadata is not null`, s.table))
+	if err != nil {
+		return err
+	}
+	correlated := make(map[int64]sql.NullString)
+	for rows.Next() {
+		var seq int64
+		var metadata []byte
+		var eventMetadata map[string]interface{}
+		if err := rows.Scan(&seq, &metadata); err != nil {
+			rows.Close()
+			return err
+		}
+		if len(metadata) == 0 {
+			continue
+		}
+		if err := s.serializer.Unmarshal(metadata, &eventMetadata); err != nil {
+			rows.Close()
+			return fmt.Errorf("event %d: %w", seq, err)
+		}
+		if id := correlationID(eventMetadata); id.Valid {
+			correlated[seq] = id
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	update := fmt.Sprintf(`Update %s set correlation_id=? where seq=?`, s.table)
+	for seq, id := range correlated {
+		_, err = tx.ExecContext(ctx, update, id, seq)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ErrNotReencoded when an event can't be decoded with the serializer the events are migrated from
+var ErrNotReencoded = errors.New("event not reencoded")
+
+const reencodeBatchSize = 1000
+
+// MigrateSerializer rewrites the data and metadata of the stored events from the serializer they were saved with to
+// the serializer of the event store, like from json to protobuf. The rows are updated in place keeping their seq,
+// version, timestamps and indexed columns. The events are rewritten in one transaction, readers see either the old or
+// the new encoding, but the writers and readers have to be switched to the new serializer when it's committed. An
+// event that can't be decoded rolls back the migration with ErrNotReencoded.
+func (s *SQL[T]) MigrateSerializer(from eventsourcing.Serializer[T]) error {
+	ctx := context.Background()
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type reencoded struct {
+		seq            int64
+		data, metadata []byte
+	}
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, data, metadata from %s where seq > ? order by seq asc limit ?`, s.table)
+	update := fmt.Sprintf(`Update %s set data=?, metadata=? where seq=?`, s.table)
+	last := int64(0)
+	for {
+		// read a batch before updating as the rows keep the connection busy
+		rows, err := tx.QueryContext(ctx, selectStm, last, reencodeBatchSize)
+		if err != nil {
+			return err
+		}
+		var batch []reencoded
+		for rows.Next() {
+			var r reencoded
+			var reason string
+			var event eventsourcing.Event[T]
+			if err := rows.Scan(&r.seq, &event.AggregateID, &event.Version, &reason, &event.AggregateType, &r.data, &r.metadata); err != nil {
+				rows.Close()
+				return err
+			}
+			r.data, r.metadata, err = s.reencode(from, event, reason, r.data, r.metadata)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("event %d: %w", r.seq, err)
+			}
+			batch = append(batch, r)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(batch) == 0 {
+			return tx.Commit()
+		}
+		for _, r := range batch {
+			_, err = tx.ExecContext(ctx, update, r.data, r.metadata, r.seq)
+			if err != nil {
+				return err
+			}
+		}
+		last = batch[len(batch)-1].seq
+	}
+}
+
+// reencode unmarshals the data and metadata of the event with the serializer from and marshals them with the
+// serializer of the event store
+func (s *SQL[T]) reencode(from eventsourcing.Serializer[T], event eventsourcing.Event[T], reason string, data, metadata []byte) ([]byte, []byte, error) {
+	ok, err := from.UnmarshalEvent(&event, reason, data)
+	if err != nil {
+		return nil, nil, err
+	}
+	if _, corrupt := any(event.Data).(*eventsourcing.CorruptData); !ok || corrupt {
+		return nil, nil, fmt.Errorf("%w: %s %s version %d reason %s", ErrNotReencoded, event.AggregateType, event.AggregateID, event.Version, reason)
+	}
+	data, err = s.serializer.MarshalEvent(event)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(metadata) == 0 {
+		return data, nil, nil
+	}
+	var eventMetadata map[string]interface{}
+	err = from.Unmarshal(metadata, &eventMetadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	metadata, err = s.serializer.Marshal(eventMetadata)
+	if err != nil {
+		return nil, nil, err
+	}
+	return data, metadata, nil
+}
+
+// MigrateEventID adds the event_id column and its unique index to an events table created before the column was
+// added. Events saved before have no event id.
+func (s *SQL[T]) MigrateEventID() error {
+	return s.migrate([]string{
+		fmt.Sprintf(`alter table %s add column event_id VARCHAR;`, s.table),
+		fmt.Sprintf(`create unique index %s on %s (event_id);`, s.indexName("event_id"), s.table),
+	})
+}
+
+// MigrateValidTime adds the valid_time column to an events table created before the column was added
+func (s *SQL[T]) MigrateValidTime() error {
+	return s.migrate([]string{fmt.Sprintf(`alter table %s add column valid_time VARCHAR;`, s.table)})
+}
+
 // MigrateTest remove the index that the test sql driver does not support
 func (s *SQL[T]) MigrateTest() error {
-	return s.migrate([]string{createTable})
+	return s.migrate([]string{fmt.Sprintf(createTable, s.table)})
+}
+
+// indexName prefix the index with the table name when not using the default table as
+// index names are unique within the database
+func (s *SQL[T]) indexName(name string) string {
+	if s.table == defaultTable {
+		return name
+	}
+	return s.table + "_" + name
 }
 
 func (s *SQL[T]) migrate(stm []string) error {
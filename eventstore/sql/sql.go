@@ -5,23 +5,76 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/hallgren/eventsourcing"
 	"github.com/hallgren/eventsourcing/eventstore"
 )
 
+const defaultTable = "events"
+
+// ErrInvalidTableName when the table name set with WithTableName is not a plain identifier
+var ErrInvalidTableName = errors.New("invalid table name")
+
+// tableName matches the table names that can be put in the statements without quoting
+var tableName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// getManyBatch is the number of aggregates read per query in GetMany, it keeps the query parameters below the limit of
+// sqlite
+const getManyBatch = 400
+
 // SQL event store handler
 type SQL[T any] struct {
-	db         *sql.DB
-	serializer eventsourcing.Serializer[T]
+	db           *sql.DB
+	serializer   eventsourcing.Serializer[T]
+	table        string
+	metadataKeys []string
+	bus          *eventstore.AggregateBus[T]
+}
+
+// Option configures the SQL event store
+type Option func(*options)
+
+type options struct {
+	table        string
+	metadataKeys []string
+}
+
+// WithTableName sets the table the events are stored in, default is events.
+// Makes it possible for multiple bounded contexts to share one database. The name is put in the statements as is and
+// has to be letters, digits and underscores not starting with a digit, it panics with ErrInvalidTableName otherwise.
+func WithTableName(table string) Option {
+	if !tableName.MatchString(table) {
+		panic(fmt.Errorf("%w: %q", ErrInvalidTableName, table))
+	}
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithMetadataIndex indexes the values of the metadata keys, like user_id or tenant_id, in a side table making the
+// events searchable with EventsByMetadata. The side table is created by Migrate, MigrateMetadataIndex creates it for
+// existing tables and indexes the events saved before the keys were added.
+func WithMetadataIndex(keys ...string) Option {
+	return func(o *options) {
+		o.metadataKeys = append(o.metadataKeys, keys...)
+	}
 }
 
 // Open connection to database
-func Open[T any](db *sql.DB, serializer eventsourcing.Serializer[T]) *SQL[T] {
+func Open[T any](db *sql.DB, serializer eventsourcing.Serializer[T], opts ...Option) *SQL[T] {
+	o := options{table: defaultTable}
+	for _, opt := range opts {
+		opt(&o)
+	}
 	return &SQL[T]{
-		db:         db,
-		serializer: serializer,
+		db:           db,
+		serializer:   serializer,
+		table:        o.table,
+		metadataKeys: o.metadataKeys,
+		bus:          eventstore.NewAggregateBus[T](),
 	}
 }
 
@@ -30,33 +83,89 @@ func (s *SQL[T]) Close() {
 	s.db.Close()
 }
 
+// CheckHealth pings the database
+func (s *SQL[T]) CheckHealth(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 // Save persists events to the database
 func (s *SQL[T]) Save(events []eventsourcing.Event[T]) error {
 	// If no event return no error
 	if len(events) == 0 {
 		return nil
 	}
-	aggregateID := events[0].AggregateID
-	aggregateType := events[0].AggregateType
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return errors.New(fmt.Sprintf("could not start a write transaction, %v", err))
+	}
+	defer tx.Rollback()
 
+	err = s.save(tx, events)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	s.bus.Publish(events)
+	return nil
+}
+
+// SaveTx saves the events in a transaction managed by the caller, making it possible to commit the events together
+// with changes to application tables. The events are only persisted if the caller commits the transaction, the
+// GlobalVersion set on the events is not valid if it's rolled back.
+func (s *SQL[T]) SaveTx(tx *sql.Tx, events []eventsourcing.Event[T]) error {
+	// If no event return no error
+	if len(events) == 0 {
+		return nil
+	}
+	return s.save(tx, events)
+}
+
+// Publish pushes the events saved with SaveTx to the aggregate subscribers, call it after the caller committed the
+// transaction. Events of a rolled back transaction must not be published.
+func (s *SQL[T]) Publish(events []eventsourcing.Event[T]) {
+	s.bus.Publish(events)
+}
+
+// SaveAll saves the events of several aggregates in one transaction
+func (s *SQL[T]) SaveAll(events [][]eventsourcing.Event[T]) error {
+	err := eventstore.ValidateBulk(events)
+	if err != nil {
+		return err
+	}
 	tx, err := s.db.BeginTx(context.Background(), nil)
 	if err != nil {
 		return errors.New(fmt.Sprintf("could not start a write transaction, %v", err))
 	}
 	defer tx.Rollback()
 
-	var currentVersion eventsourcing.Version
-	var version int
-	selectStm := `Select version from events where id=? and type=? order by version desc limit 1`
-	err = tx.QueryRow(selectStm, aggregateID, aggregateType).Scan(&version)
-	if err != nil && err != sql.ErrNoRows {
+	for _, aggregateEvents := range events {
+		if len(aggregateEvents) == 0 {
+			continue
+		}
+		err = s.save(tx, aggregateEvents)
+		if err != nil {
+			return err
+		}
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	for _, aggregateEvents := range events {
+		s.bus.Publish(aggregateEvents)
+	}
+	return nil
+}
+
+// save the events of an aggregate in the transaction
+func (s *SQL[T]) save(tx *sql.Tx, events []eventsourcing.Event[T]) error {
+	aggregateID := events[0].AggregateID
+	currentVersion, err := s.currentVersion(tx, aggregateID, events[0].AggregateType)
+	if err != nil {
 		return err
-	} else if err == sql.ErrNoRows {
-		// if no events are saved before set the current version to zero
-		currentVersion = eventsourcing.Version(0)
-	} else {
-		// set the current version to the last event stored
-		currentVersion = eventsourcing.Version(version)
 	}
 
 	//Validate events
@@ -65,38 +174,135 @@ func (s *SQL[T]) Save(events []eventsourcing.Event[T]) error {
 		return err
 	}
 
-	var lastInsertedID int64
-	insert := `Insert into events (id, version, reason, type, timestamp, data, metadata) values ($1, $2, $3, $4, $5, $6, $7)`
+	raws := make([]eventsourcing.RawEvent, len(events))
 	for i, event := range events {
-		var e, m []byte
-
-		e, err := s.serializer.Marshal(event.Data)
+		e, err := s.serializer.MarshalEvent(event)
 		if err != nil {
 			return err
 		}
+		raws[i] = eventsourcing.RawEvent{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       event.Version,
+			Reason:        event.Reason(),
+			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
+			Data:          e,
+			Metadata:      event.Metadata,
+		}
+	}
+	err = s.insert(tx, raws)
+	if err != nil {
+		return err
+	}
+	for i := range events {
+		// override the event in the slice exposing the GlobalVersion to the caller
+		events[i].GlobalVersion = raws[i].GlobalVersion
+	}
+	return nil
+}
+
+// SaveRaw saves the events of one aggregate with the data already marshaled. The events are published to the
+// subscribers if their types are registered in the serializer.
+func (s *SQL[T]) SaveRaw(events []eventsourcing.RawEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	tx, err := s.db.BeginTx(context.Background(), nil)
+	if err != nil {
+		return errors.New(fmt.Sprintf("could not start a write transaction, %v", err))
+	}
+	defer tx.Rollback()
+
+	aggregateID := events[0].AggregateID
+	currentVersion, err := s.currentVersion(tx, aggregateID, events[0].AggregateType)
+	if err != nil {
+		return err
+	}
+	err = eventstore.ValidateRawEvents(aggregateID, currentVersion, events)
+	if err != nil {
+		return err
+	}
+	err = s.insert(tx, events)
+	if err != nil {
+		return err
+	}
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+	var decoded []eventsourcing.Event[T]
+	for _, raw := range events {
+		event, ok, err := s.serializer.DecodeRaw(raw)
+		if ok && err == nil {
+			decoded = append(decoded, event)
+		}
+	}
+	if len(decoded) > 0 {
+		s.bus.Publish(decoded)
+	}
+	return nil
+}
+
+// currentVersion returns the version of the last stored event of the aggregate, zero if it has no events
+func (s *SQL[T]) currentVersion(tx *sql.Tx, aggregateID, aggregateType string) (eventsourcing.Version, error) {
+	var version int64
+	selectStm := fmt.Sprintf(`Select version from %s where id=? and type=? order by version desc limit 1`, s.table)
+	err := tx.QueryRow(selectStm, aggregateID, aggregateType).Scan(&version)
+	if err == sql.ErrNoRows {
+		// if no events are saved before the current version is zero
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return eventsourcing.VersionFromInt64(version)
+}
+
+// insert inserts the validated events and sets their global version
+func (s *SQL[T]) insert(tx *sql.Tx, events []eventsourcing.RawEvent) error {
+	insert := fmt.Sprintf(`Insert into %s (id, version, reason, type, timestamp, valid_time, data, metadata, event_id, correlation_id) values ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`, s.table)
+	for i, event := range events {
+		var m []byte
+		var err error
 		if event.Metadata != nil {
 			m, err = s.serializer.Marshal(event.Metadata)
 			if err != nil {
 				return err
 			}
 		}
-		res, err := tx.Exec(insert, event.AggregateID, event.Version, event.Reason(), event.AggregateType, event.Timestamp.Format(time.RFC3339), string(e), string(m))
+		var validTime sql.NullString
+		if !event.ValidTime.IsZero() {
+			validTime = sql.NullString{String: event.ValidTime.UTC().Format(time.RFC3339Nano), Valid: true}
+		}
+		// events without id are stored as null as the event ids are unique
+		var eventID sql.NullString
+		if event.EventID != "" {
+			eventID = sql.NullString{String: event.EventID, Valid: true}
+		}
+		res, err := tx.Exec(insert, event.AggregateID, event.Version, event.Reason, event.AggregateType, event.Timestamp.UnixNano(), validTime, event.Data, m, eventID, correlationID(event.Metadata))
 		if err != nil {
 			return err
 		}
-		lastInsertedID, err = res.LastInsertId()
+		lastInsertedID, err := res.LastInsertId()
+		if err != nil {
+			return err
+		}
+		events[i].GlobalVersion, err = eventsourcing.VersionFromInt64(lastInsertedID)
+		if err != nil {
+			return err
+		}
+		err = s.indexMetadata(context.Background(), tx, lastInsertedID, event.Metadata)
 		if err != nil {
 			return err
 		}
-		// override the event in the slice exposing the GlobalVersion to the caller
-		events[i].GlobalVersion = eventsourcing.Version(lastInsertedID)
 	}
-	return tx.Commit()
+	return nil
 }
 
 // Get the events from database
 func (s *SQL[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
-	selectStm := `Select seq, id, version, reason, type, timestamp, data, metadata from events where id=? and type=? and version>? order by version asc`
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where id=? and type=? and version>? order by version asc`, s.table)
 	rows, err := s.db.QueryContext(ctx, selectStm, id, aggregateType, afterVersion)
 	if err != nil {
 		return nil, err
@@ -107,9 +313,53 @@ func (s *SQL[T]) Get(ctx context.Context, id string, aggregateType string, after
 	return &i, nil
 }
 
+// GetMany returns an iterator per aggregate id over its events after the version in afterVersions. The events of
+// getManyBatch aggregates are read per query.
+func (s *SQL[T]) GetMany(ctx context.Context, aggregateType string, ids []string, afterVersions map[string]eventsourcing.Version) (map[string]eventsourcing.EventIterator[T], error) {
+	events := make(map[string][]eventsourcing.Event[T], len(ids))
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > getManyBatch {
+			batch = batch[:getManyBatch]
+		}
+		ids = ids[len(batch):]
+		where := make([]string, 0, len(batch))
+		args := []interface{}{aggregateType}
+		for _, id := range batch {
+			where = append(where, "(id=? and version>?)")
+			args = append(args, id, afterVersions[id])
+		}
+		selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where type=? and (%s) order by seq asc`, s.table, strings.Join(where, " or "))
+		rows, err := s.db.QueryContext(ctx, selectStm, args...)
+		if err != nil {
+			return nil, err
+		}
+		batchEvents, err := s.eventsFromRows(rows)
+		rows.Close()
+		if err != nil {
+			return nil, err
+		}
+		for _, event := range batchEvents {
+			events[event.AggregateID] = append(events[event.AggregateID], event)
+		}
+	}
+	iterators := make(map[string]eventsourcing.EventIterator[T], len(events))
+	for id, e := range events {
+		iterators[id] = eventsourcing.NewSliceIterator(e)
+	}
+	return iterators, nil
+}
+
+// SubscribeAggregate delivers the events of the aggregate after fromVersion as they are saved. Only the events saved
+// via Save and SaveAll, or saved with SaveTx and passed to Publish, on this event store instance are pushed. Events
+// saved by other processes are read from the database when the aggregate has a newer event pushed.
+func (s *SQL[T]) SubscribeAggregate(ctx context.Context, aggregateType, id string, fromVersion eventsourcing.Version, handler func(event eventsourcing.Event[T]) error) error {
+	return s.bus.Subscribe(ctx, s, aggregateType, id, fromVersion, handler)
+}
+
 // GlobalEvents return count events in order globally from the start posistion
 func (s *SQL[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], error) {
-	selectStm := `Select seq, id, version, reason, type, timestamp, data, metadata from events where seq >= ? order by seq asc LIMIT ?`
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where seq >= ? order by seq asc LIMIT ?`, s.table)
 	rows, err := s.db.Query(selectStm, start, count)
 	if err != nil {
 		return nil, err
@@ -118,53 +368,190 @@ func (s *SQL[T]) GlobalEvents(start, count uint64) ([]eventsourcing.Event[T], er
 	return s.eventsFromRows(rows)
 }
 
+// GetRaw returns the events of the aggregate after the version without unmarshaling their data
+func (s *SQL[T]) GetRaw(ctx context.Context, id, aggregateType string, afterVersion eventsourcing.Version) ([]eventsourcing.RawEvent, error) {
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where id=? and type=? and version>? order by version asc`, s.table)
+	rows, err := s.db.QueryContext(ctx, selectStm, id, aggregateType, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.rawFromRows(rows)
+}
+
+// GlobalEventsRaw returns count events in global order from the start position without unmarshaling their data
+func (s *SQL[T]) GlobalEventsRaw(ctx context.Context, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where seq >= ? order by seq asc LIMIT ?`, s.table)
+	rows, err := s.db.QueryContext(ctx, selectStm, start, count)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.rawFromRows(rows)
+}
+
+// GlobalEventsBetween returns the events with a timestamp within the time window matching the filter in global order
+func (s *SQL[T]) GlobalEventsBetween(ctx context.Context, from, to time.Time, filter eventsourcing.EventFilter) ([]eventsourcing.Event[T], error) {
+	where := []string{"timestamp >= ?", "timestamp < ?"}
+	args := []interface{}{from.UnixNano(), to.UnixNano()}
+	where, args = whereIn(where, args, "type", filter.AggregateTypes)
+	where, args = whereIn(where, args, "reason", filter.Reasons)
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where %s order by seq asc`, s.table, strings.Join(where, " and "))
+	rows, err := s.db.QueryContext(ctx, selectStm, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.eventsFromRows(rows)
+}
+
+// EventsByCorrelationID returns the events with the correlation id in their metadata in global order
+func (s *SQL[T]) EventsByCorrelationID(ctx context.Context, correlationID string) ([]eventsourcing.Event[T], error) {
+	selectStm := fmt.Sprintf(`Select seq, id, version, reason, type, timestamp, valid_time, data, metadata, event_id from %s where correlation_id=? order by seq asc`, s.table)
+	rows, err := s.db.QueryContext(ctx, selectStm, correlationID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return s.eventsFromRows(rows)
+}
+
+// correlationID returns the correlation id of the metadata for the indexed correlation_id column, null if not set
+func correlationID(metadata map[string]interface{}) sql.NullString {
+	id, _ := metadata[eventsourcing.MetadataCorrelationID].(string)
+	return sql.NullString{String: id, Valid: id != ""}
+}
+
+// whereIn adds a column in condition matching the values, no condition if there are no values
+func whereIn(where []string, args []interface{}, column string, values []string) ([]string, []interface{}) {
+	if len(values) == 0 {
+		return where, args
+	}
+	placeholders := make([]string, len(values))
+	for i, v := range values {
+		placeholders[i] = "?"
+		args = append(args, v)
+	}
+	return append(where, fmt.Sprintf("%s in (%s)", column, strings.Join(placeholders, ", "))), args
+}
+
+// Stats returns the number of events per aggregate type and the aggregates with the most events
+func (s *SQL[T]) Stats(ctx context.Context) (eventsourcing.Stats, error) {
+	stats := eventsourcing.Stats{Types: make(map[string]eventsourcing.AggregateTypeStats)}
+	rows, err := s.db.QueryContext(ctx, fmt.Sprintf(`Select type, count(distinct id), count(*) from %s group by type`, s.table))
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var typ string
+		var typeStats eventsourcing.AggregateTypeStats
+		if err := rows.Scan(&typ, &typeStats.Aggregates, &typeStats.Events); err != nil {
+			return stats, err
+		}
+		stats.Types[typ] = typeStats
+		stats.TotalEvents += typeStats.Events
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	// the version of the last event of an aggregate is its number of events
+	rows, err = s.db.QueryContext(ctx, fmt.Sprintf(`Select type, id, max(version) as events from %s group by type, id order by events desc, type asc, id asc limit ?`, s.table), eventsourcing.StatsLargestAggregates)
+	if err != nil {
+		return stats, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var a eventsourcing.AggregateStats
+		if err := rows.Scan(&a.AggregateType, &a.AggregateID, &a.Events); err != nil {
+			return stats, err
+		}
+		stats.Largest = append(stats.Largest, a)
+	}
+	if err := rows.Err(); err != nil {
+		return stats, err
+	}
+
+	if stats.TotalEvents == 0 {
+		return stats, nil
+	}
+	stats.Oldest, err = s.timestampAt(ctx, "asc")
+	if err != nil {
+		return stats, err
+	}
+	stats.Newest, err = s.timestampAt(ctx, "desc")
+	return stats, err
+}
+
+// timestampAt returns the timestamp of the first event when ordering on seq asc or desc
+func (s *SQL[T]) timestampAt(ctx context.Context, order string) (time.Time, error) {
+	var timestamp int64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`Select timestamp from %s order by seq %s limit 1`, s.table, order)).Scan(&timestamp)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return fromUnixNano(timestamp), nil
+}
+
+// Ordering returns the global order guarantees of the sql event store. The global version is the
+// autoincrement seq column, writes are serialized by the database making the sequence contiguous.
+func (s *SQL[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.Ordering{ContiguousGlobalOrder: true, GlobalVersionOnGet: true}
+}
+
+// Capabilities returns the optional features of the sql event store
+func (s *SQL[T]) Capabilities() eventsourcing.Capabilities {
+	return eventsourcing.Capabilities{GlobalOrder: true, Subscriptions: true, MultiAggregateTx: true}
+}
+
 func (s *SQL[T]) eventsFromRows(rows *sql.Rows) ([]eventsourcing.Event[T], error) {
 	var events []eventsourcing.Event[T]
 	for rows.Next() {
-		var globalVersion eventsourcing.Version
-		var eventMetadata map[string]interface{}
-		var version eventsourcing.Version
-		var id, reason, typ, timestamp string
-		var data, metadata string
-		if err := rows.Scan(&globalVersion, &id, &version, &reason, &typ, &timestamp, &data, &metadata); err != nil {
+		raw, err := scanRaw(rows, s.serializer)
+		if err != nil {
 			return nil, err
 		}
-
-		t, err := time.Parse(time.RFC3339, timestamp)
+		event, ok, err := s.serializer.DecodeRaw(raw)
 		if err != nil {
 			return nil, err
 		}
-
-		f, ok := s.serializer.Type(typ, reason)
 		if !ok {
-			// if the typ/reason is not register jump over the event
+			// if the typ/reason is not register or the event is skipped jump over the event
 			continue
 		}
+		events = append(events, event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return events, nil
+}
 
-		eventData := f()
-		err = s.serializer.Unmarshal([]byte(data), &eventData)
+func (s *SQL[T]) rawFromRows(rows *sql.Rows) ([]eventsourcing.RawEvent, error) {
+	var events []eventsourcing.RawEvent
+	for rows.Next() {
+		raw, err := scanRaw(rows, s.serializer)
 		if err != nil {
 			return nil, err
 		}
-		if metadata != "" {
-			err = s.serializer.Unmarshal([]byte(metadata), &eventMetadata)
-			if err != nil {
-				return nil, err
-			}
-		}
-
-		events = append(events, eventsourcing.Event[T]{
-			AggregateID:   id,
-			Version:       version,
-			GlobalVersion: globalVersion,
-			AggregateType: typ,
-			Timestamp:     t,
-			Data:          eventData,
-			Metadata:      eventMetadata,
-		})
+		events = append(events, raw)
 	}
 	if err := rows.Err(); err != nil {
 		return nil, err
 	}
 	return events, nil
 }
+
+// fromUnixNano returns the time of the timestamp column stored as nanoseconds since the unix epoch
+func fromUnixNano(timestamp int64) time.Time {
+	return time.Unix(0, timestamp).UTC()
+}
+
+// parseValidTime parses the valid time column, null when the event has no valid time
+func parseValidTime(validTime sql.NullString) (time.Time, error) {
+	if !validTime.Valid {
+		return time.Time{}, nil
+	}
+	return time.Parse(time.RFC3339Nano, validTime.String)
+}
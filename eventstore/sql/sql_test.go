@@ -1,36 +1,43 @@
 package sql_test
 
 import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
 	sqldriver "database/sql"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"math/rand"
+	"io"
 	"testing"
 	"time"
 
 	"github.com/hallgren/eventsourcing"
+	aliasmem "github.com/hallgren/eventsourcing/alias/memory"
+	"github.com/hallgren/eventsourcing/envelope"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/signing"
 	"github.com/hallgren/eventsourcing/eventstore/sql"
 	"github.com/hallgren/eventsourcing/eventstore/suite"
-	_ "github.com/proullon/ramsql/driver"
+	"github.com/hallgren/eventsourcing/reencode"
+	_ "github.com/mattn/go-sqlite3"
 )
 
-var seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
-
-func TestSuite(t *testing.T) {
-	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
-		// use random int to get a new db on each test run
-		r := seededRand.Intn(999999999999)
-		db, err := sqldriver.Open("ramsql", fmt.Sprintf("%d", r))
+func storeFunc(opts ...sql.Option) func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+	return func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		// each in memory database lives as long as its connection
+		db, err := sqldriver.Open("sqlite3", ":memory:")
 		if err != nil {
-			return nil, nil, errors.New(fmt.Sprintf("could not open ramsql database %v", err))
+			return nil, nil, errors.New(fmt.Sprintf("could not open sqlite3 database %v", err))
 		}
+		db.SetMaxOpenConns(1)
 		err = db.Ping()
 		if err != nil {
 			return nil, nil, errors.New(fmt.Sprintf("could not ping database %v", err))
 		}
 
-		es := sql.Open(db, ser)
-		err = es.MigrateTest()
+		es := sql.Open(db, ser, opts...)
+		err = es.Migrate()
 		if err != nil {
 			return nil, nil, errors.New(fmt.Sprintf("could not migrate database %v", err))
 		}
@@ -38,5 +45,901 @@ func TestSuite(t *testing.T) {
 			es.Close()
 		}, nil
 	}
-	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestSuite(t *testing.T) {
+	suite.Test[suite.FrequentFlierEvent](t, storeFunc())
+}
+
+func TestSuiteWithTableName(t *testing.T) {
+	suite.Test[suite.FrequentFlierEvent](t, storeFunc(sql.WithTableName("frequent_flier_events")))
+}
+
+func TestInvalidTableName(t *testing.T) {
+	for _, table := range []string{"", "1events", "events; drop table events", "events--", `"events"`} {
+		func() {
+			defer func() {
+				err, _ := recover().(error)
+				if !errors.Is(err, sql.ErrInvalidTableName) {
+					t.Fatalf("expected ErrInvalidTableName for %q got %v", table, err)
+				}
+			}()
+			sql.WithTableName(table)
+		}()
+	}
+}
+
+func openStore(t testing.TB, ser *eventsourcing.Serializer[suite.FrequentFlierEvent]) *sql.SQL[suite.FrequentFlierEvent] {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := sql.Open(db, *ser)
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return es
+}
+
+func TestTimestampPrecision(t *testing.T) {
+	es := openStore(t, eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	defer es.Close()
+
+	timestamp := time.Date(2022, 1, 2, 3, 4, 5, 123456789, time.UTC)
+	events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: timestamp, Data: &suite.FrequentFlierAccountCreated{}},
+	}
+	err := es.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterator, err := es.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	event, err := iterator.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !event.Timestamp.Equal(timestamp) {
+		t.Fatalf("expected timestamp %v got %v", timestamp, event.Timestamp)
+	}
+}
+
+func TestSaveTx(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := sql.Open(db, *ser)
+	defer es.Close()
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`create table accounts (id VARCHAR PRIMARY KEY)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	save := func(id string, commit bool) []eventsourcing.Event[suite.FrequentFlierEvent] {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer tx.Rollback()
+		_, err = tx.Exec(`insert into accounts (id) values (?)`, id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+			{AggregateID: id, Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+		}
+		err = es.SaveTx(tx, events)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if commit {
+			err = tx.Commit()
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		return events
+	}
+	exists := func(id string) (bool, bool) {
+		var accounts int
+		err := db.QueryRow(`select count(*) from accounts where id=?`, id).Scan(&accounts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		iterator, err := es.Get(context.Background(), id, "FrequentFlierAccount", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer iterator.Close()
+		_, err = iterator.Next()
+		return accounts == 1, err == nil
+	}
+
+	save("rolled-back", false)
+	if account, event := exists("rolled-back"); account || event {
+		t.Fatalf("expected nothing saved on rollback got account %v event %v", account, event)
+	}
+	save("committed", true)
+	if account, event := exists("committed"); !account || !event {
+		t.Fatalf("expected account and event saved on commit got account %v event %v", account, event)
+	}
+
+	// the committed events are pushed to the subscribers when published
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan eventsourcing.Event[suite.FrequentFlierEvent], 1)
+	subscribed := make(chan error, 1)
+	go func() {
+		subscribed <- es.SubscribeAggregate(ctx, "FrequentFlierAccount", "published", 0, func(event eventsourcing.Event[suite.FrequentFlierEvent]) error {
+			received <- event
+			return nil
+		})
+	}()
+	// wait for the subscription to read the saved events before the transaction holds the only connection
+	time.Sleep(50 * time.Millisecond)
+	es.Publish(save("published", true))
+	select {
+	case event := <-received:
+		if event.AggregateID != "published" || event.Version != 1 {
+			t.Fatalf("expected version 1 of published got %+v", event)
+		}
+	case err := <-subscribed:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the published event to be pushed")
+	}
+}
+
+// binary marshal prefix the json output with bytes that are not valid in a string
+var binaryPrefix = []byte{0x00, 0xff, 0xfe}
+
+func binaryMarshal(v any) ([]byte, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte{}, binaryPrefix...), b...), nil
+}
+
+func binaryUnmarshal(data []byte, v any) error {
+	if !bytes.HasPrefix(data, binaryPrefix) {
+		return errors.New("binary prefix missing")
+	}
+	return json.Unmarshal(data[len(binaryPrefix):], v)
+}
+
+func TestBinarySerializer(t *testing.T) {
+	es := openStore(t, eventsourcing.NewSerializer[suite.FrequentFlierEvent](binaryMarshal, binaryUnmarshal))
+	defer es.Close()
+
+	events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{OpeningMiles: 10}, Metadata: map[string]interface{}{"foo": "bar"}},
+	}
+	err := es.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterator, err := es.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	event, err := iterator.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Data.(*suite.FrequentFlierAccountCreated).OpeningMiles != 10 {
+		t.Fatalf("wrong event data %v", event.Data)
+	}
+	if event.Metadata["foo"] != "bar" {
+		t.Fatalf("wrong metadata %v", event.Metadata)
+	}
+}
+
+func TestIsTransient(t *testing.T) {
+	if !sql.IsTransient(sqldriver.ErrConnDone) {
+		t.Fatal("expected a closed connection to be transient")
+	}
+	if sql.IsTransient(eventstore.ErrConcurrency) {
+		t.Fatal("expected a concurrency error to not be transient")
+	}
+	if !sql.IsNotCommitted(sqldriver.ErrConnDone) {
+		t.Fatal("expected a closed connection to not commit the events")
+	}
+	if sql.IsNotCommitted(io.ErrUnexpectedEOF) {
+		t.Fatal("expected a connection lost during the save to be ambiguous")
+	}
+}
+
+func TestMigrateTimestampEpoch(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	// the events table as created before the timestamp was stored as epoch with the event_id column migrated
+	_, err = db.Exec(`create table events (seq INTEGER PRIMARY KEY AUTOINCREMENT, id VARCHAR NOT NULL, version INTEGER, reason VARCHAR, type VARCHAR, timestamp VARCHAR, valid_time VARCHAR, data BLOB, metadata BLOB, event_id VARCHAR);
+		create index timestamp on events (timestamp);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`insert into events (id, version, reason, type, timestamp, data, event_id) values ('123', 1, 'FrequentFlierAccountCreated', 'FrequentFlierAccount', '2022-01-02T03:04:05.5Z', '{"OpeningMiles":10}', 'event-1')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := sql.Open(db, *ser)
+	defer es.Close()
+	err = es.MigrateTimestampEpoch()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", Version: 2, AggregateType: "FrequentFlierAccount", Timestamp: time.Date(2022, 1, 2, 3, 4, 6, 0, time.UTC), Data: &suite.FrequentFlierAccountCreated{}},
+	}
+	err = es.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if events[0].GlobalVersion != 2 {
+		t.Fatalf("expected the seq to continue after the migrated event got %d", events[0].GlobalVersion)
+	}
+	migrated, err := es.GlobalEventsBetween(context.Background(), time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC), time.Date(2022, 1, 2, 3, 4, 6, 0, time.UTC), eventsourcing.EventFilter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrated) != 1 || migrated[0].GlobalVersion != 1 || migrated[0].Data.(*suite.FrequentFlierAccountCreated).OpeningMiles != 10 {
+		t.Fatalf("expected the migrated event got %+v", migrated)
+	}
+	if !migrated[0].Timestamp.Equal(time.Date(2022, 1, 2, 3, 4, 5, 500000000, time.UTC)) {
+		t.Fatalf("wrong timestamp %v", migrated[0].Timestamp)
+	}
+	if migrated[0].EventID != "event-1" {
+		t.Fatalf("expected the event id to be kept got %q", migrated[0].EventID)
+	}
+}
+
+func TestRewrite(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	es, closer, err := storeFunc()(*ser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer closer()
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{EventID: "event-1", AggregateID: "123", Version: 1, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+		{EventID: "event-2", AggregateID: "123", Version: 2, AggregateType: "FrequentFlierAccount", Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := eventsourcing.NewRepository[suite.FrequentFlierEvent](es, nil)
+	repo.SetAliasStore(aliasmem.New())
+	err = repo.Rewrite(context.Background(), "FrequentFlierAccount", "123", "123-fixed", func(e eventsourcing.Event[suite.FrequentFlierEvent]) (eventsourcing.Event[suite.FrequentFlierEvent], bool) {
+		return e, true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	iterator, err := es.Get(context.Background(), "123-fixed", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	count := 0
+	for event, err := iterator.Next(); err == nil; event, err = iterator.Next() {
+		if event.EventID == "" || event.EventID == "event-1" || event.EventID == "event-2" {
+			t.Fatalf("expected the rewritten event to get a new event id got %q", event.EventID)
+		}
+		count++
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rewritten events got %d", count)
+	}
+}
+
+func TestMigrateCorrelationID(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	// the events table as created before the correlation id column was added
+	_, err = db.Exec(`create table events (seq INTEGER PRIMARY KEY AUTOINCREMENT, id VARCHAR NOT NULL, version INTEGER, reason VARCHAR, type VARCHAR, timestamp INTEGER, valid_time VARCHAR, data BLOB, metadata BLOB, event_id VARCHAR)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`insert into events (id, version, reason, type, timestamp, data, metadata) values ('123', 1, 'FrequentFlierAccountCreated', 'FrequentFlierAccount', 0, '{}', '{"correlation_id":"order-1"}'), ('123', 2, 'FrequentFlierAccountCreated', 'FrequentFlierAccount', 0, '{}', null)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := sql.Open(db, *ser)
+	defer es.Close()
+	err = es.MigrateCorrelationID()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := es.EventsByCorrelationID(context.Background(), "order-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Version != 1 {
+		t.Fatalf("expected the first event to be correlated got %+v", events)
+	}
+}
+
+func TestMigrateSerializer(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	register := func(ser *eventsourcing.Serializer[suite.FrequentFlierEvent]) *eventsourcing.Serializer[suite.FrequentFlierEvent] {
+		ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+		return ser
+	}
+	from := register(eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	to := register(eventsourcing.NewBinarySerializer[suite.FrequentFlierEvent]())
+	es := sql.Open(db, *from)
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveFlights(t, es, 5)
+	before, err := es.GlobalEventsRaw(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	es = sql.Open(db, *to)
+	err = es.MigrateSerializer(*from)
+	if err != nil {
+		t.Fatal(err)
+	}
+	after, err := es.GlobalEventsRaw(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != 5 {
+		t.Fatalf("expected 5 events got %d", len(after))
+	}
+	for i, raw := range after {
+		if raw.GlobalVersion != before[i].GlobalVersion || raw.Version != before[i].Version || !raw.Timestamp.Equal(before[i].Timestamp) || string(raw.Data) == string(before[i].Data) {
+			t.Fatalf("expected the event to keep its position with new data got %+v", raw)
+		}
+	}
+	events, err := es.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if flight := events[4].Data.(*suite.FlightTaken); flight.MilesAdded != 5 || events[4].Metadata["flight"] != float64(5) {
+		t.Fatalf("expected the event to be read with the new serializer got %+v", events[4])
+	}
+
+	// events the serializer can't decode roll back the migration
+	err = sql.Open(db, *from).MigrateSerializer(*eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	if !errors.Is(err, sql.ErrNotReencoded) {
+		t.Fatalf("expected ErrNotReencoded got %v", err)
+	}
+	events, err = es.GlobalEvents(1, 10)
+	if err != nil || len(events) != 5 {
+		t.Fatalf("expected the events unchanged got %d %v", len(events), err)
+	}
+}
+
+func TestCopySerializer(t *testing.T) {
+	from := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	source := openStore(t, from)
+	defer source.Close()
+	from.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, from.Events(&suite.FlightTaken{}))
+	saveFlights(t, source, 5)
+
+	to := eventsourcing.NewBinarySerializer[suite.FrequentFlierEvent]()
+	target := openStore(t, to)
+	defer target.Close()
+	to.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, to.Events(&suite.FlightTaken{}))
+	result, err := reencode.Copy[suite.FrequentFlierEvent](context.Background(), source, *from, target, reencode.WithBatchSize(2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (reencode.Result{Events: 5, Aggregates: 1}) {
+		t.Fatalf("expected 5 events of one aggregate got %+v", result)
+	}
+	events, err := target.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 5 || events[4].GlobalVersion != 5 || events[4].Data.(*suite.FlightTaken).MilesAdded != 5 {
+		t.Fatalf("expected the events in the target got %+v", events)
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	es := openStore(t, eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	err := es.CheckHealth(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	es.Close()
+	err = es.CheckHealth(context.Background())
+	if err == nil {
+		t.Fatal("expected a closed database to be unhealthy")
+	}
+}
+
+func TestDecodeErrorPolicy(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	es := sql.Open(db, *ser)
+	defer es.Close()
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{}},
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 3, Timestamp: time.Now(), Data: &suite.FlightTaken{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`update events set data='{' where version=2`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = es.GlobalEvents(1, 10)
+	decodeErr := &eventsourcing.DecodeError{}
+	if !errors.As(err, &decodeErr) || decodeErr.Version != 2 || decodeErr.GlobalVersion != 2 {
+		t.Fatalf("expected a decode error on version 2 got %v", err)
+	}
+
+	var skipped []eventsourcing.Version
+	// the policy applies to the store opened before it was set
+	ser.SetDecodeErrorPolicy(eventsourcing.SkipOnDecodeError, func(err *eventsourcing.DecodeError) {
+		skipped = append(skipped, err.GlobalVersion)
+	})
+	events, err := es.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Version != 3 {
+		t.Fatalf("expected the corrupt event to be skipped got %+v", events)
+	}
+	iterator, err := es.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	var versions []eventsourcing.Version
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		versions = append(versions, event.Version)
+	}
+	if len(versions) != 2 || versions[1] != 3 {
+		t.Fatalf("expected the iterator to skip the corrupt event got %v", versions)
+	}
+	if len(skipped) != 2 || skipped[0] != 2 {
+		t.Fatalf("expected the skipped events to be reported got %v", skipped)
+	}
+}
+
+func TestRawEvents(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	es := openStore(t, ser)
+	defer es.Close()
+	err := es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123"}, Metadata: map[string]interface{}{"user": "jane"}},
+		// the flight taken event is not registered in the serializer
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := es.GetRaw(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[1].Reason != "FlightTaken" || events[1].Version != 2 {
+		t.Fatalf("expected the unregistered event to be returned raw got %+v", events)
+	}
+	if !bytes.Contains(events[1].Data, []byte(`100`)) {
+		t.Fatalf("expected the marshaled event data got %s", events[1].Data)
+	}
+	if events[0].Metadata["user"] != "jane" {
+		t.Fatalf("expected the metadata to be unmarshaled got %v", events[0].Metadata)
+	}
+	events, err = es.GlobalEventsRaw(context.Background(), 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].GlobalVersion != 2 {
+		t.Fatalf("expected the second global event got %+v", events)
+	}
+
+	event, ok, err := ser.DecodeRaw(events[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Fatalf("expected the unregistered event not to decode got %+v", event)
+	}
+}
+
+func TestSaveRaw(t *testing.T) {
+	// the source serializer has no event types registered
+	source := openStore(t, eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal))
+	defer source.Close()
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	target := openStore(t, ser)
+	defer target.Close()
+
+	err := source.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{EventID: "e1", AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123"}, Metadata: map[string]interface{}{"user": "jane"}},
+		{EventID: "e2", AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{MilesAdded: 100}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	raws, err := source.GetRaw(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = target.SaveRaw(raws)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if raws[1].GlobalVersion != 2 {
+		t.Fatalf("expected the global version to be set got %d", raws[1].GlobalVersion)
+	}
+	err = target.SaveRaw(raws)
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+
+	iterator, err := target.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	var events []eventsourcing.Event[suite.FrequentFlierEvent]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 2 || events[0].EventID != "e1" || events[0].Metadata["user"] != "jane" {
+		t.Fatalf("expected the copied events got %+v", events)
+	}
+	if taken, ok := events[1].Data.(*suite.FlightTaken); !ok || taken.MilesAdded != 100 {
+		t.Fatalf("expected the flight taken event got %+v", events[1].Data)
+	}
+}
+
+func TestSchemaViolation(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.RegisterSchema("FrequentFlierAccount", "FrequentFlierAccountCreated", eventsourcing.SchemaFunc(func(data []byte) error {
+		if !bytes.Contains(data, []byte(`"AccountId":"`)) || bytes.Contains(data, []byte(`"AccountId":""`)) {
+			return errors.New("missing account id")
+		}
+		return nil
+	}))
+	es := openStore(t, ser)
+	defer es.Close()
+	err := es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}},
+	})
+	if !errors.Is(err, eventsourcing.ErrSchemaViolation) {
+		t.Fatalf("expected a schema violation got %v", err)
+	}
+	events, err := es.GlobalEventsRaw(context.Background(), 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("expected the invalid event not to be saved got %+v", events)
+	}
+}
+
+func TestEventsByMetadata(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	es := sql.Open(db, *ser)
+	defer es.Close()
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}, Metadata: map[string]interface{}{"user_id": "jane", "tenant_id": 1234567}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = es.EventsByMetadata(context.Background(), "user_id", "jane")
+	if !errors.Is(err, sql.ErrMetadataKeyNotIndexed) {
+		t.Fatalf("expected the key not to be indexed got %v", err)
+	}
+
+	// the events saved before the keys were indexed are indexed by the migration
+	es = sql.Open(db, *ser, sql.WithMetadataIndex("user_id", "tenant_id"))
+	err = es.MigrateMetadataIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 2, Timestamp: time.Now(), Data: &suite.FlightTaken{}, Metadata: map[string]interface{}{"user_id": "john", "tenant_id": 1234567}},
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 3, Timestamp: time.Now(), Data: &suite.FlightTaken{}, Metadata: map[string]interface{}{"user_id": "jane"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := es.EventsByMetadata(context.Background(), "user_id", "jane")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Version != 1 || events[1].Version != 3 {
+		t.Fatalf("expected the events of jane got %+v", events)
+	}
+	events, err = es.EventsByMetadata(context.Background(), "tenant_id", 1234567)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected the events of the tenant got %d", len(events))
+	}
+	// running the migration again does not index the events twice
+	err = es.MigrateMetadataIndex()
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err = es.EventsByMetadata(context.Background(), "user_id", "jane")
+	if err != nil || len(events) != 2 {
+		t.Fatalf("expected two events got %d %v", len(events), err)
+	}
+}
+
+func TestEventsByMetadataUseNumber(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	ser := eventsourcing.NewJSONSerializer[suite.FrequentFlierEvent](eventsourcing.WithJSONUseNumber())
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}))
+	es := sql.Open(db, *ser, sql.WithMetadataIndex("tenant_id"))
+	defer es.Close()
+	err = es.Migrate()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{}, Metadata: map[string]interface{}{"tenant_id": int64(9007199254740993)}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := es.EventsByMetadata(context.Background(), "tenant_id", int64(9007199254740993))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].Metadata["tenant_id"] != json.Number("9007199254740993") {
+		t.Fatalf("expected the event with the exact tenant id got %+v", events)
+	}
+	// the metadata read back is indexed the same
+	events, err = es.EventsByMetadata(context.Background(), "tenant_id", events[0].Metadata["tenant_id"])
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the event got %d %v", len(events), err)
+	}
+}
+
+func saveFlights(t testing.TB, es *sql.SQL[suite.FrequentFlierEvent], count int) {
+	events := []eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123", OpeningMiles: 10}},
+	}
+	for i := 2; i <= count; i++ {
+		events = append(events, eventsourcing.Event[suite.FrequentFlierEvent]{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: eventsourcing.Version(i), Timestamp: time.Now(),
+			Data: &suite.FlightTaken{MilesAdded: i, TierPointsAdded: 1}, Metadata: map[string]interface{}{"flight": i}})
+	}
+	err := es.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestNextInto(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	es := openStore(t, ser)
+	defer es.Close()
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FlightTaken{}))
+	saveFlights(t, es, 4)
+
+	iterator, err := es.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	var event eventsourcing.Event[suite.FrequentFlierEvent]
+	var data []suite.FrequentFlierEvent
+	for version := 1; ; version++ {
+		err = eventsourcing.NextInto(iterator, &event)
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			break
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		if event.Version != eventsourcing.Version(version) || event.AggregateID != "123" || event.AggregateType != "FrequentFlierAccount" {
+			t.Fatalf("unexpected event %+v", event)
+		}
+		if version > 1 {
+			flight, ok := event.Data.(*suite.FlightTaken)
+			if !ok || flight.MilesAdded != version {
+				t.Fatalf("unexpected data %+v", event.Data)
+			}
+			if event.Metadata["flight"] != float64(version) {
+				t.Fatalf("unexpected metadata %+v", event.Metadata)
+			}
+		} else if event.Metadata != nil {
+			t.Fatalf("expected no metadata got %+v", event.Metadata)
+		}
+		data = append(data, event.Data)
+	}
+	if len(data) != 4 {
+		t.Fatalf("expected 4 events got %d", len(data))
+	}
+	// the data of the events with the same reason is reused
+	if data[1] != data[3] || data[0] == data[1] {
+		t.Fatal("expected the data to be reused per reason")
+	}
+}
+
+func benchmarkGet(b *testing.B, next func(iterator eventsourcing.EventIterator[suite.FrequentFlierEvent], event *eventsourcing.Event[suite.FrequentFlierEvent]) error) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	es := openStore(b, ser)
+	defer es.Close()
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FlightTaken{}))
+	saveFlights(b, es, 1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		iterator, err := es.Get(context.Background(), "123", "FrequentFlierAccount", 0)
+		if err != nil {
+			b.Fatal(err)
+		}
+		var event eventsourcing.Event[suite.FrequentFlierEvent]
+		for {
+			err = next(iterator, &event)
+			if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+				break
+			} else if err != nil {
+				b.Fatal(err)
+			}
+		}
+		iterator.Close()
+	}
+}
+
+func BenchmarkGetNext(b *testing.B) {
+	benchmarkGet(b, func(iterator eventsourcing.EventIterator[suite.FrequentFlierEvent], event *eventsourcing.Event[suite.FrequentFlierEvent]) (err error) {
+		*event, err = iterator.Next()
+		return err
+	})
+}
+
+func BenchmarkGetNextInto(b *testing.B) {
+	benchmarkGet(b, eventsourcing.NextInto[suite.FrequentFlierEvent])
+}
+
+func TestSigned(t *testing.T) {
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	es := openStore(t, ser)
+	defer es.Close()
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	_, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	signed := signing.New[suite.FrequentFlierEvent](es, "k1", key, nil)
+	err = signed.Save([]eventsourcing.Event[suite.FrequentFlierEvent]{
+		{AggregateID: "123", AggregateType: "FrequentFlierAccount", Version: 1, Timestamp: time.Now(), Data: &suite.FrequentFlierAccountCreated{AccountId: "123", OpeningMiles: 10}, Metadata: map[string]interface{}{"flight": 1}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the data and metadata read from the database verify
+	events, err := signed.GlobalEvents(1, 10)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("expected the signed event got %v %v", events, err)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	ctx := context.Background()
+	keys := envelope.NewMemory()
+	m1, err := envelope.NewAESKey("m1", bytes.Repeat([]byte{1}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser := eventsourcing.NewSerializer[suite.FrequentFlierEvent](json.Marshal, json.Unmarshal)
+	ser.SetCipher(envelope.New(keys, m1))
+	// the schema validates the data before it's encrypted and after it's decrypted
+	ser.RegisterSchema("FrequentFlierAccount", "FlightTaken", eventsourcing.SchemaFunc(func(data []byte) error {
+		if !bytes.Contains(data, []byte("MilesAdded")) {
+			return errors.New("missing MilesAdded")
+		}
+		return nil
+	}))
+	ser.SetValidateOnRead(true)
+	es := openStore(t, ser)
+	defer es.Close()
+	ser.Register(&suite.FrequentFlierAccount[suite.FrequentFlierEvent]{}, ser.Events(&suite.FrequentFlierAccountCreated{}, &suite.FlightTaken{}))
+	saveFlights(t, es, 3)
+
+	m2, err := envelope.NewAESKey("m2", bytes.Repeat([]byte{2}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring := envelope.New(keys, m2, m1)
+	_, err = keyring.Rotate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report, err := keyring.Report(ctx, es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Events != 3 || report.Retired() != 0 {
+		t.Fatalf("expected three events under the current master key got %+v", report)
+	}
+	events, err := es.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 || events[2].Data.(*suite.FlightTaken).MilesAdded != 3 || events[2].Metadata["flight"] != float64(3) {
+		t.Fatalf("expected the events decrypted got %+v", events)
+	}
 }
@@ -0,0 +1,19 @@
+package sql
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/hallgren/eventsourcing/eventstore/retry"
+)
+
+// IsTransient classifies errors from the sql event store for the retry decorator
+func IsTransient(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) || retry.IsTransient(err)
+}
+
+// IsNotCommitted classifies the errors from the sql event store where the events are known not to be saved, for the
+// save classifier of the retry decorator
+func IsNotCommitted(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) || retry.IsNotCommitted(err)
+}
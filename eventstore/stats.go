@@ -0,0 +1,70 @@
+package eventstore
+
+import (
+	"sort"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// StatsCollector builds the event store stats from the events added in the global order
+type StatsCollector struct {
+	stats      eventsourcing.Stats
+	aggregates map[[2]string]uint64
+}
+
+// NewStatsCollector constructs an empty stats collector
+func NewStatsCollector() *StatsCollector {
+	return &StatsCollector{
+		stats:      eventsourcing.Stats{Types: make(map[string]eventsourcing.AggregateTypeStats)},
+		aggregates: make(map[[2]string]uint64),
+	}
+}
+
+// Add counts an event
+func (c *StatsCollector) Add(aggregateType, aggregateID string, timestamp time.Time) {
+	if c.stats.TotalEvents == 0 {
+		c.stats.Oldest = timestamp
+	}
+	c.stats.Newest = timestamp
+	c.stats.TotalEvents++
+
+	key := [2]string{aggregateType, aggregateID}
+	typeStats := c.stats.Types[aggregateType]
+	if c.aggregates[key] == 0 {
+		typeStats.Aggregates++
+	}
+	typeStats.Events++
+	c.stats.Types[aggregateType] = typeStats
+	c.aggregates[key]++
+}
+
+// Stats returns the stats of the added events
+func (c *StatsCollector) Stats() eventsourcing.Stats {
+	largest := make([]eventsourcing.AggregateStats, 0, len(c.aggregates))
+	for key, events := range c.aggregates {
+		largest = append(largest, eventsourcing.AggregateStats{AggregateType: key[0], AggregateID: key[1], Events: events})
+	}
+	sortLargest(largest)
+	if len(largest) > eventsourcing.StatsLargestAggregates {
+		largest = largest[:eventsourcing.StatsLargestAggregates]
+	}
+	stats := c.stats
+	stats.Largest = largest
+	return stats
+}
+
+// sortLargest sorts the aggregates by number of events in descending order, aggregates with the same number of
+// events are sorted on type and id.
+func sortLargest(aggregates []eventsourcing.AggregateStats) {
+	sort.Slice(aggregates, func(i, j int) bool {
+		a, b := aggregates[i], aggregates[j]
+		if a.Events != b.Events {
+			return a.Events > b.Events
+		}
+		if a.AggregateType != b.AggregateType {
+			return a.AggregateType < b.AggregateType
+		}
+		return a.AggregateID < b.AggregateID
+	})
+}
@@ -0,0 +1,229 @@
+package streamcache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// StreamCache decorates an event store with an in-process least recently used cache of aggregate events. Events are
+// immutable, the cached events of an aggregate are served without calling the event store and events saved through the
+// decorator are appended to them.
+//
+// The cache assumes the aggregates are only saved through the decorator. Events saved by other processes are not seen
+// until the aggregate is evicted or a save through the decorator fails with a concurrency error, which invalidates it.
+type StreamCache[T any] struct {
+	store     eventsourcing.EventStore[T]
+	maxEvents int
+
+	lock    sync.Mutex
+	events  int
+	entries map[aggregate]*list.Element
+	order   *list.List // front is the most recently used
+	hits    uint64
+	misses  uint64
+}
+
+// aggregate identifies the aggregate of an entry, a struct as no separator is safe in a joined string of type and id
+type aggregate struct {
+	typ string
+	id  string
+}
+
+type entry[T any] struct {
+	key    aggregate
+	from   eventsourcing.Version // the version of the first event the entry holds
+	events []eventsourcing.Event[T]
+}
+
+// last returns the version the entry holds events up to
+func (e *entry[T]) last() eventsourcing.Version {
+	if len(e.events) == 0 {
+		return e.from - 1
+	}
+	return e.events[len(e.events)-1].Version
+}
+
+// New decorates the event store with a cache holding at most maxEvents events. Aggregates with more events than
+// maxEvents are not cached.
+func New[T any](store eventsourcing.EventStore[T], maxEvents int) *StreamCache[T] {
+	return &StreamCache[T]{
+		store:     store,
+		maxEvents: maxEvents,
+		entries:   make(map[aggregate]*list.Element),
+		order:     list.New(),
+	}
+}
+
+// Save saves the events to the event store and appends them to the cached events of the aggregate. The aggregate is
+// invalidated when the save fails, as the events in the event store are unknown.
+func (c *StreamCache[T]) Save(events []eventsourcing.Event[T]) error {
+	if len(events) == 0 {
+		return nil
+	}
+	key := cacheKey(events[0].AggregateType, events[0].AggregateID)
+	err := c.store.Save(events)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	e, ok := c.entries[key]
+	if !ok {
+		return err
+	}
+	cached := e.Value.(*entry[T])
+	if err != nil || events[0].Version != cached.last()+1 {
+		c.evict(e)
+		return err
+	}
+	cached.events = append(cached.events, events...)
+	c.events += len(events)
+	c.order.MoveToFront(e)
+	c.shrink()
+	return nil
+}
+
+// Get returns the cached events of the aggregate or reads them from the event store and caches them
+func (c *StreamCache[T]) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[T], error) {
+	key := cacheKey(aggregateType, id)
+	c.lock.Lock()
+	if e, ok := c.entries[key]; ok {
+		cached := e.Value.(*entry[T])
+		if cached.from <= afterVersion+1 {
+			c.hits++
+			c.order.MoveToFront(e)
+			events := after(cached.events, afterVersion)
+			c.lock.Unlock()
+			return &iterator[T]{events: events}, nil
+		}
+	}
+	c.misses++
+	c.lock.Unlock()
+
+	it, err := c.store.Get(ctx, id, aggregateType, afterVersion)
+	if err != nil {
+		return nil, err
+	}
+	events, err := drain(it)
+	if err != nil {
+		return nil, err
+	}
+	c.add(&entry[T]{key: key, from: afterVersion + 1, events: events})
+	return &iterator[T]{events: events}, nil
+}
+
+// Invalidate removes the events of the aggregate from the cache
+func (c *StreamCache[T]) Invalidate(id, aggregateType string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[cacheKey(aggregateType, id)]; ok {
+		c.evict(e)
+	}
+}
+
+// Len returns the number of cached aggregates
+func (c *StreamCache[T]) Len() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.order.Len()
+}
+
+// Size returns the number of cached events
+func (c *StreamCache[T]) Size() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.events
+}
+
+// Hits returns the number of Get calls served from the cache and the number of calls made to the event store
+func (c *StreamCache[T]) Hits() (hits, misses uint64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.hits, c.misses
+}
+
+// Ordering returns the global order guarantees of the decorated event store
+func (c *StreamCache[T]) Ordering() eventsourcing.Ordering {
+	return eventsourcing.OrderingOf(c.store)
+}
+
+// add caches the entry, replacing a cached entry of the aggregate
+func (c *StreamCache[T]) add(cached *entry[T]) {
+	if len(cached.events) == 0 || len(cached.events) > c.maxEvents {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if e, ok := c.entries[cached.key]; ok {
+		if e.Value.(*entry[T]).last() > cached.last() {
+			// a save through the cache appended events while the event store was read
+			return
+		}
+		c.evict(e)
+	}
+	c.entries[cached.key] = c.order.PushFront(cached)
+	c.events += len(cached.events)
+	c.shrink()
+}
+
+// shrink evicts the least recently used aggregates until the cache holds at most max events
+func (c *StreamCache[T]) shrink() {
+	for c.events > c.maxEvents && c.order.Len() > 0 {
+		c.evict(c.order.Back())
+	}
+}
+
+func (c *StreamCache[T]) evict(e *list.Element) {
+	cached := c.order.Remove(e).(*entry[T])
+	delete(c.entries, cached.key)
+	c.events -= len(cached.events)
+}
+
+// after returns the events with a version after the version, the slice is capped making appends copy it
+func after[T any](events []eventsourcing.Event[T], version eventsourcing.Version) []eventsourcing.Event[T] {
+	for i, event := range events {
+		if event.Version > version {
+			return events[i:len(events):len(events)]
+		}
+	}
+	return nil
+}
+
+func drain[T any](it eventsourcing.EventIterator[T]) ([]eventsourcing.Event[T], error) {
+	defer it.Close()
+	var events []eventsourcing.Event[T]
+	for {
+		event, err := it.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+func cacheKey(aggregateType, id string) aggregate {
+	return aggregate{typ: aggregateType, id: id}
+}
+
+type iterator[T any] struct {
+	events   []eventsourcing.Event[T]
+	position int
+}
+
+func (i *iterator[T]) Next() (eventsourcing.Event[T], error) {
+	if len(i.events) <= i.position {
+		return eventsourcing.Event[T]{}, eventsourcing.ErrNoMoreEvents
+	}
+	event := i.events[i.position]
+	i.position++
+	return event, nil
+}
+
+func (i *iterator[T]) Close() {
+	i.events = nil
+	i.position = 0
+}
@@ -0,0 +1,190 @@
+package streamcache_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/eventstore/streamcache"
+	"github.com/hallgren/eventsourcing/eventstore/suite"
+)
+
+// countingStore counts the calls to Get
+type countingStore struct {
+	eventsourcing.EventStore[any]
+	gets int
+}
+
+func (c *countingStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	c.gets++
+	return c.EventStore.Get(ctx, id, aggregateType, afterVersion)
+}
+
+type Created struct{}
+type Renamed struct{}
+
+func event(id string, version eventsourcing.Version) eventsourcing.Event[any] {
+	return eventsourcing.Event[any]{AggregateID: id, AggregateType: "Person", Version: version, Data: &Renamed{}}
+}
+
+func versions(t *testing.T, store eventsourcing.EventStore[any], id string, afterVersion eventsourcing.Version) []eventsourcing.Version {
+	t.Helper()
+	iterator, err := store.Get(context.Background(), id, "Person", afterVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	var v []eventsourcing.Version
+	for {
+		e, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return v
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		v = append(v, e.Version)
+	}
+}
+
+func TestSuite(t *testing.T) {
+	f := func(ser eventsourcing.Serializer[suite.FrequentFlierEvent]) (eventsourcing.EventStore[suite.FrequentFlierEvent], func(), error) {
+		return streamcache.New[suite.FrequentFlierEvent](memory.Create[suite.FrequentFlierEvent](), 100), func() {}, nil
+	}
+	suite.Test[suite.FrequentFlierEvent](t, f)
+}
+
+func TestGetFromCache(t *testing.T) {
+	store := &countingStore{EventStore: memory.Create[any]()}
+	c := streamcache.New[any](store, 100)
+	err := c.Save([]eventsourcing.Event[any]{event("1", 1), event("1", 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(t, c, "1", 0); len(v) != 2 {
+		t.Fatalf("expected two events got %v", v)
+	}
+	// the saved events are appended to the cached events
+	err = c.Save([]eventsourcing.Event[any]{event("1", 3)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(t, c, "1", 0); len(v) != 3 {
+		t.Fatalf("expected three events got %v", v)
+	}
+	if v := versions(t, c, "1", 2); len(v) != 1 || v[0] != 3 {
+		t.Fatalf("expected the event after version 2 got %v", v)
+	}
+	if store.gets != 1 {
+		t.Fatalf("expected one get on the event store got %d", store.gets)
+	}
+	hits, misses := c.Hits()
+	if hits != 2 || misses != 1 {
+		t.Fatalf("expected two hits and one miss got %d %d", hits, misses)
+	}
+}
+
+func TestGetBeforeCachedEvents(t *testing.T) {
+	store := &countingStore{EventStore: memory.Create[any]()}
+	c := streamcache.New[any](store, 100)
+	c.Save([]eventsourcing.Event[any]{event("1", 1), event("1", 2), event("1", 3)})
+
+	// loaded after a snapshot at version 2
+	if v := versions(t, c, "1", 2); len(v) != 1 {
+		t.Fatalf("expected one event got %v", v)
+	}
+	// the events before the cached events are read from the event store
+	if v := versions(t, c, "1", 0); len(v) != 3 {
+		t.Fatalf("expected three events got %v", v)
+	}
+	if store.gets != 2 {
+		t.Fatalf("expected two gets on the event store got %d", store.gets)
+	}
+}
+
+func TestInvalidateOnConcurrencyError(t *testing.T) {
+	backend := memory.Create[any]()
+	c := streamcache.New[any](backend, 100)
+	c.Save([]eventsourcing.Event[any]{event("1", 1)})
+	versions(t, c, "1", 0)
+
+	// saved by another process
+	backend.Save([]eventsourcing.Event[any]{event("1", 2)})
+	if v := versions(t, c, "1", 0); len(v) != 1 {
+		t.Fatalf("expected the stale cached event got %v", v)
+	}
+	err := c.Save([]eventsourcing.Event[any]{event("1", 2)})
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+	if c.Len() != 0 {
+		t.Fatal("expected the aggregate to be invalidated")
+	}
+	if v := versions(t, c, "1", 0); len(v) != 2 {
+		t.Fatalf("expected both events got %v", v)
+	}
+}
+
+func TestEvictLeastRecentlyUsed(t *testing.T) {
+	c := streamcache.New[any](memory.Create[any](), 3)
+	c.Save([]eventsourcing.Event[any]{event("1", 1), event("1", 2)})
+	c.Save([]eventsourcing.Event[any]{event("2", 1)})
+	c.Save([]eventsourcing.Event[any]{event("3", 1), event("3", 2), event("3", 3), event("3", 4)})
+	versions(t, c, "1", 0)
+	versions(t, c, "2", 0)
+	// too many events to be cached
+	versions(t, c, "3", 0)
+	if c.Len() != 2 || c.Size() != 3 {
+		t.Fatalf("expected two aggregates with three events got %d %d", c.Len(), c.Size())
+	}
+	versions(t, c, "1", 0)
+	c.Save([]eventsourcing.Event[any]{event("2", 2)})
+	// aggregate 1 is evicted as 2 was saved after 1 was read
+	if c.Len() != 1 || c.Size() != 2 {
+		t.Fatalf("expected one aggregate with two events got %d %d", c.Len(), c.Size())
+	}
+}
+
+// aggregateStore keeps the events per aggregate type and id, unlike the memory event store it doesn't join them to one key
+type aggregateStore struct {
+	events map[[2]string][]eventsourcing.Event[any]
+}
+
+func (s *aggregateStore) Save(events []eventsourcing.Event[any]) error {
+	key := [2]string{events[0].AggregateType, events[0].AggregateID}
+	s.events[key] = append(s.events[key], events...)
+	return nil
+}
+
+func (s *aggregateStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[any], error) {
+	var events []eventsourcing.Event[any]
+	for _, e := range s.events[[2]string{aggregateType, id}] {
+		if e.Version > afterVersion {
+			events = append(events, e)
+		}
+	}
+	return eventsourcing.NewSliceIterator(events), nil
+}
+
+func TestKeysDoNotCollide(t *testing.T) {
+	c := streamcache.New[any](&aggregateStore{events: make(map[[2]string][]eventsourcing.Event[any])}, 100)
+	err := c.Save([]eventsourcing.Event[any]{event("a_b", 1), event("a_b", 2)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := versions(t, c, "a_b", 0); len(v) != 2 {
+		t.Fatalf("expected two events got %v", v)
+	}
+	// type Person_a and id b joined with an underscore is the same string as type Person and id a_b
+	iterator, err := c.Get(context.Background(), "b", "Person_a", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iterator.Close()
+	event, err := iterator.Next()
+	if !errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+		t.Fatalf("expected no events of Person_a b got %+v %v", event, err)
+	}
+}
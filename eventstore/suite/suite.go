@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
 )
 
 var seededRand = rand.New(rand.NewSource(time.Now().UnixNano()))
@@ -23,6 +24,12 @@ func AggregateID() string {
 type eventstoreFunc[T FrequentFlierEvent] func(ser eventsourcing.Serializer[FrequentFlierEvent]) (eventsourcing.EventStore[FrequentFlierEvent], func(), error)
 
 func Test[T FrequentFlierEvent](t *testing.T, esFunc eventstoreFunc[FrequentFlierEvent]) {
+	TestSerializer[T](t, esFunc, json.Marshal, json.Unmarshal)
+}
+
+// TestSerializer runs the suite with events serialized by marshal and unmarshal, to cover event stores storing
+// non json event data
+func TestSerializer[T FrequentFlierEvent](t *testing.T, esFunc eventstoreFunc[FrequentFlierEvent], marshal eventsourcing.MarshalSnapshotFunc, unmarshal eventsourcing.UnmarshalSnapshotFunc) {
 	tests := []struct {
 		title string
 		run   func(es eventsourcing.EventStore[FrequentFlierEvent]) error
@@ -34,11 +41,22 @@ func Test[T FrequentFlierEvent](t *testing.T, esFunc eventstoreFunc[FrequentFlie
 		{"should not save events in wrong order", saveEventsInWrongOrder[T]},
 		{"should not save events in wrong version", saveEventsInWrongVersion[T]},
 		{"should not save event with no reason", saveEventsWithEmptyReason[T]},
+		{"should not save events above max version", saveEventsAboveMaxVersion[T]},
 		{"should save and get event concurrently", saveAndGetEventsConcurrently[T]},
 		{"should return error when no events", getErrWhenNoEvents[T]},
 		{"should get global event order from save", saveReturnGlobalEventOrder[T]},
-	}
-	ser := eventsourcing.NewSerializer[FrequentFlierEvent](json.Marshal, json.Unmarshal)
+		{"should return stats", stats[T]},
+		{"should persist valid time", persistValidTime[T]},
+		{"should persist event id", persistEventID[T]},
+		{"should save several aggregates atomically", saveAll[T]},
+		{"should get global events between times", globalEventsBetween[T]},
+		{"should get events by correlation id", eventsByCorrelationID[T]},
+		{"should subscribe to an aggregate", subscribeAggregate[T]},
+		{"should get global events by partition", globalEventsPartition[T]},
+		{"should get the events of many aggregates", getMany[T]},
+		{"should describe the implemented capabilities", capabilities[T]},
+	}
+	ser := eventsourcing.NewSerializer[FrequentFlierEvent](marshal, unmarshal)
 
 	_ = ser.Register(&FrequentFlierAccount[FrequentFlierEvent]{},
 		ser.Events(
@@ -308,6 +326,17 @@ func saveEventsWithEmptyReason[T FrequentFlierEvent](es eventsourcing.EventStore
 	return nil
 }
 
+func saveEventsAboveMaxVersion[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	aggregateID := AggregateID()
+	events := testEvents[FrequentFlierEvent](aggregateID)[:1]
+	events[0].Version = eventsourcing.MaxVersion + 1
+	err := es.Save(events)
+	if !errors.Is(err, eventsourcing.ErrVersionOverflow) {
+		return fmt.Errorf("should not be able to save events above max version, %v", err)
+	}
+	return nil
+}
+
 func saveAndGetEventsConcurrently[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
 	wg := sync.WaitGroup{}
 	var err error
@@ -400,6 +429,473 @@ func saveReturnGlobalEventOrder[T FrequentFlierEvent](es eventsourcing.EventStor
 	return nil
 }
 
+func persistValidTime[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	aggregateID := AggregateID()
+	validTime := time.Date(2020, 1, 2, 3, 4, 5, 6000, time.UTC)
+	events := testEvents[T](aggregateID)[:2]
+	events[1].ValidTime = validTime
+	err := es.Save(events)
+	if err != nil {
+		return err
+	}
+	iterator, err := es.Get(context.Background(), aggregateID, aggregateType, 0)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	first, err := iterator.Next()
+	if err != nil {
+		return err
+	}
+	if !first.ValidTime.IsZero() {
+		return fmt.Errorf("expected no valid time on the first event got %v", first.ValidTime)
+	}
+	second, err := iterator.Next()
+	if err != nil {
+		return err
+	}
+	if !second.ValidTime.Equal(validTime) {
+		return fmt.Errorf("expected valid time %v got %v", validTime, second.ValidTime)
+	}
+	if second.Metadata["test"] != "hello" || len(second.Metadata) != 1 {
+		return fmt.Errorf("expected the metadata to be unchanged got %v", second.Metadata)
+	}
+	return nil
+}
+
+func persistEventID[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	aggregateID := AggregateID()
+	events := testEvents[T](aggregateID)[:2]
+	eventID := eventsourcing.NewUUIDv7()
+	events[1].EventID = eventID
+	err := es.Save(events)
+	if err != nil {
+		return err
+	}
+	iterator, err := es.Get(context.Background(), aggregateID, aggregateType, 0)
+	if err != nil {
+		return err
+	}
+	defer iterator.Close()
+	_, err = iterator.Next()
+	if err != nil {
+		return err
+	}
+	second, err := iterator.Next()
+	if err != nil {
+		return err
+	}
+	if second.EventID != eventID {
+		return fmt.Errorf("expected event id %s got %s", eventID, second.EventID)
+	}
+	if second.Metadata["test"] != "hello" || len(second.Metadata) != 1 {
+		return fmt.Errorf("expected the metadata to be unchanged got %v", second.Metadata)
+	}
+	return nil
+}
+
+func stats[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	provider, ok := es.(eventsourcing.StatsProvider)
+	if !ok {
+		// the event store does not provide stats
+		return nil
+	}
+	aggregateID := AggregateID()
+	aggregateID2 := AggregateID()
+	err := es.Save(testEvents[T](aggregateID))
+	if err != nil {
+		return err
+	}
+	err = es.Save([]eventsourcing.Event[FrequentFlierEvent]{testEventOtherAggregate[T](aggregateID2)})
+	if err != nil {
+		return err
+	}
+	stats, err := provider.Stats(context.Background())
+	if err != nil {
+		return err
+	}
+	if stats.TotalEvents != 7 {
+		return fmt.Errorf("expected 7 events got %d", stats.TotalEvents)
+	}
+	typeStats := stats.Types[aggregateType]
+	if typeStats.Aggregates != 2 || typeStats.Events != 7 {
+		return fmt.Errorf("expected 2 aggregates and 7 events of the type got %+v", typeStats)
+	}
+	if len(stats.Largest) != 2 {
+		return fmt.Errorf("expected 2 aggregates in largest got %d", len(stats.Largest))
+	}
+	if stats.Largest[0].AggregateID != aggregateID || stats.Largest[0].Events != 6 {
+		return fmt.Errorf("expected the aggregate with 6 events first got %+v", stats.Largest[0])
+	}
+	if !stats.Oldest.Equal(timestamp) || !stats.Newest.Equal(timestamp) {
+		return fmt.Errorf("expected the event timestamps got %v and %v", stats.Oldest, stats.Newest)
+	}
+	return nil
+}
+
+func globalEventsBetween[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	store, ok := es.(eventsourcing.TimeRangeEventStore[FrequentFlierEvent])
+	if !ok {
+		// the event store can't read events within a time window
+		return nil
+	}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	events := testEvents[T](AggregateID())
+	for i := range events {
+		// half a second apart to cover fractions of seconds
+		events[i].Timestamp = start.Add(time.Duration(i) * 500 * time.Millisecond)
+	}
+	err := es.Save(events)
+	if err != nil {
+		return err
+	}
+	other := testEventOtherAggregate[T](AggregateID())
+	other.Timestamp = start.Add(1100 * time.Millisecond)
+	err = es.Save([]eventsourcing.Event[FrequentFlierEvent]{other})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	// versions 2, 3, 4 of the first aggregate and the other aggregate
+	window, err := store.GlobalEventsBetween(ctx, start.Add(500*time.Millisecond), start.Add(2*time.Second), eventsourcing.EventFilter{})
+	if err != nil {
+		return err
+	}
+	if len(window) != 4 {
+		return fmt.Errorf("expected 4 events in the window got %d", len(window))
+	}
+	for i := 1; i < len(window); i++ {
+		if window[i].GlobalVersion <= window[i-1].GlobalVersion {
+			return fmt.Errorf("expected the events in global order")
+		}
+	}
+	if window[0].Version != 2 || window[3].AggregateID != other.AggregateID {
+		return fmt.Errorf("expected version 2 first and the other aggregate last got %d %s", window[0].Version, window[3].AggregateID)
+	}
+
+	window, err = store.GlobalEventsBetween(ctx, start, start.Add(time.Hour), eventsourcing.EventFilter{
+		AggregateTypes: []string{aggregateType},
+		Reasons:        []string{"FlightTaken"},
+	})
+	if err != nil {
+		return err
+	}
+	if len(window) != 4 {
+		return fmt.Errorf("expected 4 flights got %d", len(window))
+	}
+	window, err = store.GlobalEventsBetween(ctx, start.Add(-time.Hour), start, eventsourcing.EventFilter{})
+	if err != nil {
+		return err
+	}
+	if len(window) != 0 {
+		return fmt.Errorf("expected no events before the start got %d", len(window))
+	}
+	return nil
+}
+
+func eventsByCorrelationID[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	store, ok := es.(eventsourcing.CorrelationEventStore[FrequentFlierEvent])
+	if !ok {
+		// the event store can't find events by correlation id
+		return nil
+	}
+	correlationID := AggregateID()
+	correlated := eventsourcing.MetadataCarrier{CorrelationID: correlationID}
+	events := testEvents[T](AggregateID())
+	events[1].Metadata = correlated.Metadata()
+	events[2].Metadata = correlated.Metadata()
+	events[3].Metadata = eventsourcing.MetadataCarrier{CorrelationID: AggregateID()}.Metadata()
+	err := es.Save(events)
+	if err != nil {
+		return err
+	}
+	other := testEventOtherAggregate[T](AggregateID())
+	other.Metadata = correlated.Merge(map[string]interface{}{"test": "hello"})
+	err = es.Save([]eventsourcing.Event[FrequentFlierEvent]{other})
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	found, err := store.EventsByCorrelationID(ctx, correlationID)
+	if err != nil {
+		return err
+	}
+	if len(found) != 3 {
+		return fmt.Errorf("expected 3 correlated events got %d", len(found))
+	}
+	if found[0].Version != 2 || found[1].Version != 3 || found[2].AggregateID != other.AggregateID {
+		return fmt.Errorf("expected version 2 and 3 and the other aggregate last got %d %d %s", found[0].Version, found[1].Version, found[2].AggregateID)
+	}
+	if found[1].GlobalVersion <= found[0].GlobalVersion || found[2].GlobalVersion <= found[1].GlobalVersion {
+		return fmt.Errorf("expected the events in global order")
+	}
+	found, err = store.EventsByCorrelationID(ctx, AggregateID())
+	if err != nil {
+		return err
+	}
+	if len(found) != 0 {
+		return fmt.Errorf("expected no events for an unknown correlation id got %d", len(found))
+	}
+	return nil
+}
+
+func globalEventsPartition[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	store, ok := es.(eventsourcing.GlobalEventStore[FrequentFlierEvent])
+	if !ok {
+		// the event store can't read events in global order
+		return nil
+	}
+	saved := make(map[string]int)
+	var start eventsourcing.Version
+	for i := 0; i < 8; i++ {
+		events := testEvents[T](AggregateID())
+		err := es.Save(events)
+		if err != nil {
+			return err
+		}
+		if i == 0 {
+			start = events[0].GlobalVersion
+		}
+		saved[events[0].AggregateID] = len(events)
+	}
+
+	ctx := context.Background()
+	read := make(map[string]int)
+	for partition := uint32(0); partition < 3; partition++ {
+		events, _, err := eventsourcing.GlobalEventsPartition[FrequentFlierEvent](ctx, store, partition, 3, uint64(start), 1000)
+		if err != nil {
+			return err
+		}
+		last := make(map[string]eventsourcing.Version)
+		for _, event := range events {
+			if _, ok := saved[event.AggregateID]; !ok {
+				continue
+			}
+			if eventsourcing.PartitionOf(event.AggregateID, 3) != partition {
+				return fmt.Errorf("expected the events of partition %d got an event of partition %d", partition, eventsourcing.PartitionOf(event.AggregateID, 3))
+			}
+			if event.Version != last[event.AggregateID]+1 {
+				return fmt.Errorf("expected the events of aggregate %s in order", event.AggregateID)
+			}
+			last[event.AggregateID] = event.Version
+			read[event.AggregateID]++
+		}
+	}
+	for id, count := range saved {
+		if read[id] != count {
+			return fmt.Errorf("expected %d events of aggregate %s in the partitions got %d", count, id, read[id])
+		}
+	}
+	return nil
+}
+
+func getMany[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	first := AggregateID()
+	second := AggregateID()
+	for _, id := range []string{first, second} {
+		err := es.Save(testEvents[T](id))
+		if err != nil {
+			return err
+		}
+	}
+	unknown := AggregateID()
+	iterators, err := eventsourcing.GetMany[FrequentFlierEvent](context.Background(), es, aggregateType, []string{first, second, unknown}, map[string]eventsourcing.Version{second: 4})
+	if err != nil {
+		return err
+	}
+	if _, ok := iterators[unknown]; ok || len(iterators) != 2 {
+		return fmt.Errorf("expected iterators of the two aggregates with events got %d", len(iterators))
+	}
+	expected := map[string][]eventsourcing.Version{first: {1, 2, 3, 4, 5, 6}, second: {5, 6}}
+	for id, iterator := range iterators {
+		var versions []eventsourcing.Version
+		for {
+			event, err := iterator.Next()
+			if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+				break
+			} else if err != nil {
+				return err
+			}
+			if event.AggregateID != id {
+				return fmt.Errorf("expected the events of aggregate %s got %s", id, event.AggregateID)
+			}
+			versions = append(versions, event.Version)
+		}
+		iterator.Close()
+		if fmt.Sprint(versions) != fmt.Sprint(expected[id]) {
+			return fmt.Errorf("expected versions %v of aggregate %s got %v", expected[id], id, versions)
+		}
+	}
+	return nil
+}
+
+func subscribeAggregate[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	subscriber, ok := es.(eventsourcing.AggregateSubscriber[FrequentFlierEvent])
+	if !ok {
+		// the event store can't deliver the events of an aggregate as they are saved
+		return nil
+	}
+	aggregateID := AggregateID()
+	err := es.Save(testEvents[T](aggregateID))
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan eventsourcing.Event[FrequentFlierEvent])
+	done := make(chan error, 1)
+	go func() {
+		done <- subscriber.SubscribeAggregate(ctx, aggregateType, aggregateID, 4, func(event eventsourcing.Event[FrequentFlierEvent]) error {
+			received <- event
+			return nil
+		})
+	}()
+	next := func(version eventsourcing.Version) error {
+		select {
+		case event := <-received:
+			if event.AggregateID != aggregateID || event.Version != version {
+				return fmt.Errorf("expected version %d of %s got version %d of %s", version, aggregateID, event.Version, event.AggregateID)
+			}
+			return nil
+		case err := <-done:
+			return fmt.Errorf("subscription ended before version %d, %v", version, err)
+		case <-time.After(10 * time.Second):
+			return fmt.Errorf("timeout waiting for version %d", version)
+		}
+	}
+	// the saved events after the version
+	for _, version := range []eventsourcing.Version{5, 6} {
+		if err := next(version); err != nil {
+			return err
+		}
+	}
+	// events of other aggregates are not delivered
+	err = es.Save([]eventsourcing.Event[FrequentFlierEvent]{testEventOtherAggregate[T](AggregateID())})
+	if err != nil {
+		return err
+	}
+	err = es.Save(testEventsPartTwo[T](aggregateID))
+	if err != nil {
+		return err
+	}
+	for _, version := range []eventsourcing.Version{7, 8} {
+		if err := next(version); err != nil {
+			return err
+		}
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			return fmt.Errorf("expected the subscription to end with the context got %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		return fmt.Errorf("timeout waiting for the subscription to end")
+	}
+	return nil
+}
+
+// capabilities checks that the event store describes the optional interfaces it implements
+func capabilities[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	c := eventsourcing.CapabilitiesOf(es)
+	if _, ok := es.(eventsourcing.GlobalEventStore[FrequentFlierEvent]); ok && !c.GlobalOrder {
+		return fmt.Errorf("implements GlobalEventStore without the GlobalOrder capability")
+	}
+	if _, ok := es.(eventsourcing.AggregateSubscriber[FrequentFlierEvent]); ok && !c.Subscriptions {
+		return fmt.Errorf("implements AggregateSubscriber without the Subscriptions capability")
+	}
+	if _, ok := es.(eventsourcing.BulkSaver[FrequentFlierEvent]); ok && !c.MultiAggregateTx {
+		return fmt.Errorf("implements BulkSaver without the MultiAggregateTx capability")
+	}
+	return nil
+}
+
+func saveAll[T FrequentFlierEvent](es eventsourcing.EventStore[FrequentFlierEvent]) error {
+	saver, ok := es.(eventsourcing.BulkSaver[FrequentFlierEvent])
+	if !ok {
+		// the event store can't save several aggregates atomically
+		return nil
+	}
+	aggregateID := AggregateID()
+	aggregateID2 := AggregateID()
+	err := es.Save(testEvents[T](aggregateID))
+	if err != nil {
+		return err
+	}
+
+	// the first aggregate is already saved in version 1, no events of the second aggregate can be saved
+	err = saver.SaveAll([][]eventsourcing.Event[FrequentFlierEvent]{
+		{testEventOtherAggregate[T](aggregateID2)},
+		testEvents[T](aggregateID),
+	})
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		return fmt.Errorf("expected concurrency error got %v", err)
+	}
+	n, err := countEvents(es, aggregateID2)
+	if err != nil {
+		return err
+	}
+	if n != 0 {
+		return fmt.Errorf("expected no events saved on the second aggregate got %d", n)
+	}
+
+	err = saver.SaveAll([][]eventsourcing.Event[FrequentFlierEvent]{
+		{testEventOtherAggregate[T](aggregateID2)},
+		{testEventOtherAggregate[T](aggregateID2)},
+	})
+	if !errors.Is(err, eventstore.ErrAggregateSavedTwice) {
+		return fmt.Errorf("expected aggregate saved twice error got %v", err)
+	}
+
+	events := [][]eventsourcing.Event[FrequentFlierEvent]{
+		{testEventOtherAggregate[T](aggregateID2)},
+		testEventsPartTwo[T](aggregateID),
+	}
+	err = saver.SaveAll(events)
+	if err != nil {
+		return err
+	}
+	if events[0][0].GlobalVersion == 0 || events[1][1].GlobalVersion == 0 {
+		return fmt.Errorf("expected the global version to be set on the saved events")
+	}
+	n, err = countEvents(es, aggregateID)
+	if err != nil {
+		return err
+	}
+	n2, err := countEvents(es, aggregateID2)
+	if err != nil {
+		return err
+	}
+	if n != 8 || n2 != 1 {
+		return fmt.Errorf("expected 8 and 1 events got %d and %d", n, n2)
+	}
+	return nil
+}
+
+// countEvents returns the number of saved events of the aggregate
+func countEvents(es eventsourcing.EventStore[FrequentFlierEvent], aggregateID string) (int, error) {
+	iterator, err := es.Get(context.Background(), aggregateID, aggregateType, 0)
+	if errors.Is(err, eventsourcing.ErrNoEvents) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+	n := 0
+	for {
+		_, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return n, nil
+		} else if err != nil {
+			return 0, err
+		}
+		n++
+	}
+}
+
 /* re-activate when esdb eventstore have global event order on each stream
 func setGlobalVersionOnSavedEvents(es eventsourcing.EventStore) error {
 	events := testEvents()
@@ -0,0 +1,47 @@
+package eventsourcing
+
+// Metadata keys set on the events an aggregate applied from the events of another aggregate
+const (
+	MetadataForeignType          = "foreign_type"
+	MetadataForeignID            = "foreign_id"
+	MetadataForeignVersion       = "foreign_version"
+	MetadataForeignGlobalVersion = "foreign_global_version"
+)
+
+// ForeignRef identifies the event of another aggregate an event was applied from
+type ForeignRef struct {
+	AggregateType string
+	AggregateID   string
+	Version       Version
+	GlobalVersion Version
+}
+
+// TrackForeign tracks the data on the aggregate as applied from the event of another aggregate. The event gets the
+// foreign event in its metadata, keeping it apart from the events produced by the commands of the aggregate, and the
+// foreign event id as causation id.
+func TrackForeign[T, F any](a Aggregate[T], data T, foreign Event[F]) error {
+	metadata := map[string]interface{}{
+		MetadataForeignType:          foreign.AggregateType,
+		MetadataForeignID:            foreign.AggregateID,
+		MetadataForeignVersion:       uint64(foreign.Version),
+		MetadataForeignGlobalVersion: uint64(foreign.GlobalVersion),
+	}
+	if foreign.EventID != "" {
+		metadata[MetadataCausationID] = foreign.EventID
+	}
+	return a.Root().TrackChangeWithMetadata(a, data, metadata)
+}
+
+// ForeignOf returns the foreign event the event was applied from, ok is false for events produced by the commands of
+// the aggregate
+func ForeignOf[T any](event Event[T]) (ref ForeignRef, ok bool) {
+	var err error
+	ref.AggregateType, err = MetadataAs[string](event, MetadataForeignType)
+	if err != nil {
+		return ForeignRef{}, false
+	}
+	ref.AggregateID, _ = MetadataAs[string](event, MetadataForeignID)
+	ref.Version, _ = MetadataAs[Version](event, MetadataForeignVersion)
+	ref.GlobalVersion, _ = MetadataAs[Version](event, MetadataForeignGlobalVersion)
+	return ref, true
+}
@@ -0,0 +1,44 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+)
+
+var registerGob sync.Once
+
+// Gob returns marshal functions based on encoding/gob, for systems only sharing events between Go programs. Each value
+// is encoded with its own encoder and carries its type description, the data can be read without registering anything
+// up front. Fields are matched by name so fields can be added to and removed from the event types.
+//
+// Values held in interface fields, like the metadata values, need to be of a type registered with gob.Register, the
+// basic types and the nested maps and slices of metadata are registered.
+func Gob() (MarshalSnapshotFunc, UnmarshalSnapshotFunc) {
+	registerGob.Do(func() {
+		gob.Register(map[string]any{})
+		gob.Register([]any{})
+	})
+	marshal := func(v any) ([]byte, error) {
+		var buf bytes.Buffer
+		err := gob.NewEncoder(&buf).Encode(v)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	unmarshal := func(data []byte, v any) error {
+		target, err := decodeTarget(v)
+		if err != nil {
+			return err
+		}
+		return gob.NewDecoder(bytes.NewReader(data)).DecodeValue(target)
+	}
+	return marshal, unmarshal
+}
+
+// NewGobSerializer returns a serializer using the marshal functions from Gob
+func NewGobSerializer[T any]() *Serializer[T] {
+	marshal, unmarshal := Gob()
+	return NewSerializer[T](marshal, unmarshal)
+}
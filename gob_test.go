@@ -0,0 +1,66 @@
+package eventsourcing_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func TestGobSerializer(t *testing.T) {
+	s := eventsourcing.NewGobSerializer[Data]()
+	err := s.Register(&SomeAggregate{}, s.Events(&SomeData{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Marshal(&SomeData{A: 1, B: "b"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := eventsourcing.Event[Data]{AggregateType: "SomeAggregate"}
+	_, err = s.UnmarshalEvent(&event, "SomeData", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Data.(*SomeData) != (SomeData{A: 1, B: "b"}) {
+		t.Fatalf("expected the event back got %+v", event.Data)
+	}
+
+	data, err = s.Marshal(map[string]any{"tenant": int64(9007199254740993), "nested": map[string]any{"ok": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]any{}
+	err = s.Unmarshal(data, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["tenant"] != int64(9007199254740993) || metadata["nested"].(map[string]any)["ok"] != true {
+		t.Fatalf("expected the metadata back got %v", metadata)
+	}
+}
+
+func FuzzGobRoundTrip(f *testing.F) {
+	f.Add("s1", 1.5, int64(-300), uint64(1<<40), true, []byte{1, 2})
+	f.Add("", math.NaN(), int64(math.MinInt64), uint64(math.MaxUint64), false, []byte(nil))
+	marshal, unmarshal := eventsourcing.Gob()
+	f.Fuzz(func(t *testing.T, sensor string, value float64, count int64, total uint64, valid bool, raw []byte) {
+		reading := readingFrom(sensor, value, count, total, valid, raw)
+		data, err := marshal(reading)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got Reading
+		err = unmarshal(data, &got)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// gob doesn't tell empty from nil slices
+		if len(reading.Raw) == 0 {
+			got.Raw = reading.Raw
+		}
+		if !sameReading(reading, got) {
+			t.Fatalf("expected %+v got %+v", reading, got)
+		}
+	})
+}
@@ -0,0 +1,18 @@
+package eventsourcing
+
+import "context"
+
+// Healther is implemented by components that can check if they are able to serve, like event stores reaching their
+// database. The health package aggregates the checks for readiness and liveness probes.
+type Healther interface {
+	// CheckHealth returns an error describing why the component is unhealthy
+	CheckHealth(ctx context.Context) error
+}
+
+// HealthFunc is a function implementing Healther
+type HealthFunc func(ctx context.Context) error
+
+// CheckHealth calls the function
+func (f HealthFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
@@ -0,0 +1,175 @@
+// Package health aggregates the health checks of the components of a service into reports for readiness and
+// liveness probes.
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const defaultTimeout = 5 * time.Second
+
+// Status of a check or a report
+type Status string
+
+const (
+	// StatusUp when the check passed
+	StatusUp Status = "up"
+	// StatusDown when the check failed
+	StatusDown Status = "down"
+)
+
+// Check is the result of the check of a component
+type Check struct {
+	Name     string        `json:"name"`
+	Status   Status        `json:"status"`
+	Error    string        `json:"error,omitempty"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Report is the result of the checks, it's down if any check is down
+type Report struct {
+	Status Status  `json:"status"`
+	Checks []Check `json:"checks"`
+}
+
+// Option configures the checker
+type Option func(*options)
+
+type options struct {
+	timeout time.Duration
+}
+
+// WithTimeout sets the time each check gets before it's down, default five seconds
+func WithTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.timeout = timeout
+	}
+}
+
+type check struct {
+	name     string
+	healther eventsourcing.Healther
+	liveness bool
+}
+
+// Checker runs the health checks of the components added to it
+type Checker struct {
+	lock    sync.Mutex
+	checks  []check
+	options options
+}
+
+// New constructs a checker without checks
+func New(opts ...Option) *Checker {
+	o := options{timeout: defaultTimeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Checker{options: o}
+}
+
+// Add adds a readiness check, a component the service needs to serve, like a database or a projection read by the
+// service
+func (c *Checker) Add(name string, healther eventsourcing.Healther) {
+	c.add(check{name: name, healther: healther})
+}
+
+// AddLiveness adds a check that is part of both the liveness and the readiness report. Only add components the
+// service can't recover from without a restart, like a runner with failed components.
+func (c *Checker) AddLiveness(name string, healther eventsourcing.Healther) {
+	c.add(check{name: name, healther: healther, liveness: true})
+}
+
+func (c *Checker) add(ch check) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.checks = append(c.checks, ch)
+}
+
+// Readiness runs all checks concurrently and returns the report
+func (c *Checker) Readiness(ctx context.Context) Report {
+	return c.run(ctx, false)
+}
+
+// Liveness runs the liveness checks concurrently and returns the report
+func (c *Checker) Liveness(ctx context.Context) Report {
+	return c.run(ctx, true)
+}
+
+// ReadinessHandler serves the readiness report as JSON, with status 503 when it's down
+func (c *Checker) ReadinessHandler() http.Handler {
+	return c.handler(false)
+}
+
+// LivenessHandler serves the liveness report as JSON, with status 503 when it's down
+func (c *Checker) LivenessHandler() http.Handler {
+	return c.handler(true)
+}
+
+func (c *Checker) handler(liveness bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		report := c.run(r.Context(), liveness)
+		w.Header().Set("Content-Type", "application/json")
+		if report.Status == StatusDown {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(report)
+	})
+}
+
+func (c *Checker) run(ctx context.Context, liveness bool) Report {
+	c.lock.Lock()
+	var checks []check
+	for _, ch := range c.checks {
+		if ch.liveness || !liveness {
+			checks = append(checks, ch)
+		}
+	}
+	c.lock.Unlock()
+
+	report := Report{Status: StatusUp, Checks: make([]Check, len(checks))}
+	wg := sync.WaitGroup{}
+	for i, ch := range checks {
+		wg.Add(1)
+		go func(i int, ch check) {
+			defer wg.Done()
+			report.Checks[i] = c.check(ctx, ch)
+		}(i, ch)
+	}
+	wg.Wait()
+	for _, ch := range report.Checks {
+		if ch.Status == StatusDown {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+// check runs the check within the timeout, a check not returning in time is down
+func (c *Checker) check(ctx context.Context, ch check) Check {
+	ctx, cancel := context.WithTimeout(ctx, c.options.timeout)
+	defer cancel()
+	start := time.Now()
+	result := make(chan error, 1)
+	go func() {
+		result <- ch.healther.CheckHealth(ctx)
+	}()
+	var err error
+	select {
+	case err = <-result:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	res := Check{Name: ch.name, Status: StatusUp, Duration: time.Since(start)}
+	if err != nil {
+		res.Status = StatusDown
+		res.Error = err.Error()
+	}
+	return res
+}
@@ -0,0 +1,89 @@
+package health_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/health"
+)
+
+var up = eventsourcing.HealthFunc(func(ctx context.Context) error { return nil })
+
+func TestReadiness(t *testing.T) {
+	c := health.New()
+	c.AddLiveness("runner", up)
+	c.Add("event store", up)
+	report := c.Readiness(context.Background())
+	if report.Status != health.StatusUp || len(report.Checks) != 2 {
+		t.Fatalf("expected two checks up got %+v", report)
+	}
+
+	c.Add("projection", eventsourcing.HealthFunc(func(ctx context.Context) error {
+		return errors.New("lagging")
+	}))
+	report = c.Readiness(context.Background())
+	if report.Status != health.StatusDown || report.Checks[2].Error != "lagging" {
+		t.Fatalf("expected the projection to be down got %+v", report)
+	}
+	// the readiness checks are not part of the liveness report
+	report = c.Liveness(context.Background())
+	if report.Status != health.StatusUp || len(report.Checks) != 1 {
+		t.Fatalf("expected the liveness check up got %+v", report)
+	}
+}
+
+func TestTimeout(t *testing.T) {
+	c := health.New(health.WithTimeout(10 * time.Millisecond))
+	block := make(chan struct{})
+	defer close(block)
+	c.Add("database", eventsourcing.HealthFunc(func(ctx context.Context) error {
+		<-block
+		return nil
+	}))
+	report := c.Readiness(context.Background())
+	if report.Status != health.StatusDown {
+		t.Fatalf("expected a check not returning in time to be down got %+v", report)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	c := health.New()
+	c.Add("event store", eventsourcing.HealthFunc(func(ctx context.Context) error {
+		return errors.New("database is closed")
+	}))
+	server := httptest.NewServer(c.ReadinessHandler())
+	defer server.Close()
+	res, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("expected service unavailable got %d", res.StatusCode)
+	}
+	report := health.Report{}
+	err = json.NewDecoder(res.Body).Decode(&report)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Checks[0].Name != "event store" || report.Checks[0].Status != health.StatusDown {
+		t.Fatalf("unexpected report %+v", report)
+	}
+
+	live := httptest.NewServer(c.LivenessHandler())
+	defer live.Close()
+	res, err = http.Get(live.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		t.Fatalf("expected the liveness probe to pass got %d", res.StatusCode)
+	}
+}
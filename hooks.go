@@ -0,0 +1,34 @@
+package eventsourcing
+
+// BeforeSaver is an optional interface on the aggregate called by the repository before the events are saved.
+// Returning an error prevents the events from being saved, making it possible to check invariants.
+type BeforeSaver[T any] interface {
+	BeforeSave(events []Event[T]) error
+}
+
+// AfterLoader is an optional interface on the aggregate called by the repository when the aggregate is
+// fetched, making it possible to recompute derived fields.
+type AfterLoader interface {
+	AfterLoad()
+}
+
+// AfterApplier is an optional interface on the aggregate called after each event is applied to the aggregate
+// via Transition, both when tracking new events and when building the aggregate from history.
+type AfterApplier[T any] interface {
+	AfterApply(event Event[T])
+}
+
+// apply transition the event on the aggregate and calls the AfterApply hook
+func apply[T any](a Aggregate[T], event Event[T]) {
+	a.Transition(event)
+	if h, ok := a.(AfterApplier[T]); ok {
+		h.AfterApply(event)
+	}
+}
+
+// afterLoad calls the AfterLoad hook on the aggregate
+func afterLoad[T any](a Aggregate[T]) {
+	if h, ok := a.(AfterLoader); ok {
+		h.AfterLoad()
+	}
+}
@@ -0,0 +1,70 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+var errTooOld = errors.New("too old")
+
+// HookedPerson is a person aggregate implementing all the lifecycle hooks
+type HookedPerson struct {
+	Person
+	applied int
+	loaded  bool
+}
+
+func (p *HookedPerson) BeforeSave(events []eventsourcing.Event[PersonEvent]) error {
+	if p.Age > 2 {
+		return errTooOld
+	}
+	return nil
+}
+
+func (p *HookedPerson) AfterLoad() {
+	p.loaded = true
+}
+
+func (p *HookedPerson) AfterApply(event eventsourcing.Event[PersonEvent]) {
+	p.applied++
+}
+
+func TestHooks(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+
+	person := HookedPerson{}
+	person.TrackChange(&person, &Born{Name: "kalle"})
+	person.TrackChange(&person, &AgedOneYear{})
+	if person.applied != 2 {
+		t.Fatalf("expected AfterApply to be called 2 times got %d", person.applied)
+	}
+	err := repo.Save(&person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	twin := HookedPerson{}
+	err = repo.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !twin.loaded {
+		t.Fatal("expected AfterLoad to be called")
+	}
+	if twin.applied != 2 {
+		t.Fatalf("expected AfterApply to be called 2 times got %d", twin.applied)
+	}
+
+	twin.TrackChange(&twin, &AgedOneYear{})
+	twin.TrackChange(&twin, &AgedOneYear{})
+	err = repo.Save(&twin)
+	if !errors.Is(err, errTooOld) {
+		t.Fatalf("expected BeforeSave to stop the save got %v", err)
+	}
+	if !twin.UnsavedEvents() {
+		t.Fatal("events should still be unsaved")
+	}
+}
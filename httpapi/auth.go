@@ -0,0 +1,162 @@
+package httpapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrUnauthenticated when the request has no valid credentials
+var ErrUnauthenticated = errors.New("unauthenticated")
+
+// AnyType grants a permission on all aggregate types
+const AnyType = "*"
+
+// Permission is the access to the events of an aggregate type
+type Permission int
+
+const (
+	// PermissionRead allows reading the events
+	PermissionRead Permission = 1 << iota
+	// PermissionWrite allows saving events
+	PermissionWrite
+)
+
+// Principal is the authenticated caller of the API
+type Principal struct {
+	Subject string
+	// Tenant scopes the principal to the events with the tenant id in the eventsourcing.MetadataTenantID metadata,
+	// empty for access to all tenants
+	Tenant string
+	// Permissions on aggregate types, AnyType for all types
+	Permissions map[string]Permission
+}
+
+// Can returns true if the principal has the permission on the aggregate type
+func (p Principal) Can(aggregateType string, permission Permission) bool {
+	return p.Permissions[aggregateType]&permission == permission || p.Permissions[AnyType]&permission == permission
+}
+
+// CanAccess returns true if the event belongs to the tenant of the principal
+func (p Principal) CanAccess(metadata map[string]interface{}) bool {
+	if p.Tenant == "" {
+		return true
+	}
+	tenant, _ := metadata[eventsourcing.MetadataTenantID].(string)
+	return tenant == p.Tenant
+}
+
+// readable returns the aggregate types the principal can read, nil if it can read all types
+func (p Principal) readable() []string {
+	if p.Can(AnyType, PermissionRead) {
+		return nil
+	}
+	types := []string{}
+	for aggregateType := range p.Permissions {
+		if p.Can(aggregateType, PermissionRead) {
+			types = append(types, aggregateType)
+		}
+	}
+	return types
+}
+
+// Authenticator returns the principal of the request, ErrUnauthenticated if it has no valid credentials
+type Authenticator interface {
+	Authenticate(r *http.Request) (Principal, error)
+}
+
+// AuthenticatorFunc is a function used as Authenticator
+type AuthenticatorFunc func(r *http.Request) (Principal, error)
+
+// Authenticate calls f(r)
+func (f AuthenticatorFunc) Authenticate(r *http.Request) (Principal, error) {
+	return f(r)
+}
+
+// APIKeys authenticates requests with the key in the X-API-Key header, or as bearer token in the Authorization
+// header, against the keys mapped to their principals
+func APIKeys(keys map[string]Principal) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (Principal, error) {
+		key := r.Header.Get("X-API-Key")
+		if key == "" {
+			key = bearer(r)
+		}
+		if key == "" {
+			return Principal{}, ErrUnauthenticated
+		}
+		// compare all keys in constant time to not leak how much of a key matched
+		var principal Principal
+		found := false
+		for k, p := range keys {
+			if subtle.ConstantTimeCompare([]byte(k), []byte(key)) == 1 {
+				principal, found = p, true
+			}
+		}
+		if !found {
+			return Principal{}, ErrUnauthenticated
+		}
+		return principal, nil
+	})
+}
+
+// TokenVerifier verifies a bearer token and returns the principal from its claims. For OpenID Connect it verifies the
+// signature, issuer, audience and expiry of the ID token, like the IDTokenVerifier of github.com/coreos/go-oidc, and
+// maps the claims to the subject, tenant and permissions.
+type TokenVerifier func(ctx context.Context, token string) (Principal, error)
+
+// BearerToken authenticates requests with the bearer token in the Authorization header verified by verify
+func BearerToken(verify TokenVerifier) Authenticator {
+	return AuthenticatorFunc(func(r *http.Request) (Principal, error) {
+		token := bearer(r)
+		if token == "" {
+			return Principal{}, ErrUnauthenticated
+		}
+		principal, err := verify(r.Context(), token)
+		if err != nil {
+			return Principal{}, fmt.Errorf("%w: %v", ErrUnauthenticated, err)
+		}
+		return principal, nil
+	})
+}
+
+func bearer(r *http.Request) string {
+	const prefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return ""
+	}
+	return strings.TrimSpace(header[len(prefix):])
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx holding the principal
+func WithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFrom returns the principal set by Authenticate
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// Authenticate wraps the handler to only serve authenticated requests, others get 401 Unauthorized. The principal is
+// set on the request context, the feed limits the events to the aggregate types the principal can read and to its
+// tenant. Handlers saving events check the write permission with Principal.Can.
+func Authenticate(auth Authenticator, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := auth.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithPrincipal(r.Context(), principal)))
+	})
+}
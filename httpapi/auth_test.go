@@ -0,0 +1,163 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/httpapi"
+)
+
+func TestAPIKeys(t *testing.T) {
+	auth := httpapi.APIKeys(map[string]httpapi.Principal{"secret": {Subject: "dashboard"}})
+	tests := []struct {
+		title   string
+		header  string
+		value   string
+		subject string
+		err     error
+	}{
+		{title: "api key header", header: "X-API-Key", value: "secret", subject: "dashboard"},
+		{title: "bearer", header: "Authorization", value: "Bearer secret", subject: "dashboard"},
+		{title: "unknown key", header: "X-API-Key", value: "guess", err: httpapi.ErrUnauthenticated},
+		{title: "no key", err: httpapi.ErrUnauthenticated},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/", nil)
+			if test.header != "" {
+				r.Header.Set(test.header, test.value)
+			}
+			principal, err := auth.Authenticate(r)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if principal.Subject != test.subject {
+				t.Fatalf("expected subject %q got %q", test.subject, principal.Subject)
+			}
+		})
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	errExpired := errors.New("token expired")
+	auth := httpapi.BearerToken(func(ctx context.Context, token string) (httpapi.Principal, error) {
+		if token != "valid" {
+			return httpapi.Principal{}, errExpired
+		}
+		return httpapi.Principal{Subject: "jane"}, nil
+	})
+
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.Header.Set("Authorization", "bearer valid")
+	principal, err := auth.Authenticate(r)
+	if err != nil || principal.Subject != "jane" {
+		t.Fatalf("expected jane got %+v %v", principal, err)
+	}
+	r.Header.Set("Authorization", "Bearer old")
+	_, err = auth.Authenticate(r)
+	if !errors.Is(err, httpapi.ErrUnauthenticated) {
+		t.Fatalf("expected ErrUnauthenticated got %v", err)
+	}
+}
+
+func TestPrincipalCan(t *testing.T) {
+	p := httpapi.Principal{Permissions: map[string]httpapi.Permission{
+		"Account":       httpapi.PermissionRead | httpapi.PermissionWrite,
+		"Person":        httpapi.PermissionRead,
+		httpapi.AnyType: 0,
+	}}
+	if !p.Can("Account", httpapi.PermissionWrite) || !p.Can("Person", httpapi.PermissionRead) {
+		t.Fatal("expected the granted permissions")
+	}
+	if p.Can("Person", httpapi.PermissionWrite) || p.Can("Order", httpapi.PermissionRead) {
+		t.Fatal("expected no permissions not granted")
+	}
+	admin := httpapi.Principal{Permissions: map[string]httpapi.Permission{httpapi.AnyType: httpapi.PermissionRead}}
+	if !admin.Can("Order", httpapi.PermissionRead) {
+		t.Fatal("expected read on any type")
+	}
+}
+
+func TestFeedAuthenticated(t *testing.T) {
+	es := memory.Create[any]()
+	for i, tenant := range []string{"a", "b", "a"} {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Account", Version: eventsourcing.Version(i + 1), Timestamp: time.Now(), Data: &Opened{}, Metadata: map[string]interface{}{eventsourcing.MetadataTenantID: tenant}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	save(t, es, "Person", "1", 1, &Opened{})
+
+	auth := httpapi.APIKeys(map[string]httpapi.Principal{
+		"reader":  {Tenant: "a", Permissions: map[string]httpapi.Permission{"Account": httpapi.PermissionRead}},
+		"admin":   {Permissions: map[string]httpapi.Permission{httpapi.AnyType: httpapi.PermissionRead}},
+		"nothing": {Tenant: "a"},
+	})
+	server := httptest.NewServer(httpapi.Authenticate(auth, httpapi.NewFeed[any](es, httpapi.WithPollInterval(10*time.Millisecond))))
+	defer server.Close()
+
+	status := func(key, query string) int {
+		req, err := http.NewRequest(http.MethodGet, server.URL+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		res, err := http.DefaultClient.Do(req.WithContext(ctx))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+	if s := status("", ""); s != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized without key got %d", s)
+	}
+	if s := status("reader", "?type=Person"); s != http.StatusForbidden {
+		t.Fatalf("expected forbidden on a type not readable got %d", s)
+	}
+	if s := status("nothing", ""); s != http.StatusForbidden {
+		t.Fatalf("expected forbidden without read permissions got %d", s)
+	}
+
+	tests := []struct {
+		key string
+		ids []string
+	}{
+		// the events of tenant a on the readable type
+		{key: "reader", ids: []string{"1", "3"}},
+		{key: "admin", ids: []string{"1", "2", "3", "4"}},
+	}
+	for _, test := range tests {
+		t.Run(test.key, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-API-Key", test.key)
+			res, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			messages := read(t, bufio.NewScanner(res.Body), len(test.ids))
+			for i, m := range messages {
+				if m.id != test.ids[i] {
+					t.Fatalf("expected events %v got %+v", test.ids, messages)
+				}
+			}
+		})
+	}
+}
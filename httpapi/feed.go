@@ -0,0 +1,209 @@
+// Package httpapi exposes the event store over HTTP.
+package httpapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	defaultPollInterval = time.Second
+	defaultBatchSize    = 100
+	defaultHeartbeat    = 15 * time.Second
+)
+
+// Option configures the feed
+type Option func(*options)
+
+type options struct {
+	pollInterval time.Duration
+	batchSize    uint64
+	heartbeat    time.Duration
+	maskMode     eventsourcing.MaskMode
+}
+
+// WithPollInterval sets how often the event store is read for new events once the client has caught up, default one
+// second
+func WithPollInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.pollInterval = interval
+	}
+}
+
+// WithBatchSize sets the number of events read from the event store at a time, default 100
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithHeartbeat sets how often a comment is sent to keep idle connections open through proxies, default 15 seconds
+func WithHeartbeat(interval time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = interval
+	}
+}
+
+// WithMaskMode sets how the fields of the event data tagged es:"sensitive" are masked, redacted by default. See
+// eventsourcing.MarshalMasked.
+func WithMaskMode(mode eventsourcing.MaskMode) Option {
+	return func(o *options) {
+		o.maskMode = mode
+	}
+}
+
+// Feed streams the events in global order as Server-Sent Events. The id of each message is the global version of
+// the event and is the resume token, browsers send it back in the Last-Event-ID header when they reconnect and the
+// feed continues after it. The after query parameter sets the resume token on the first connect. The type and reason
+// query parameters, repeated for several values, filter the events. Wrapped with Authenticate the feed only streams
+// the events the principal can read.
+type Feed[T any] struct {
+	store   eventsourcing.GlobalEventStore[T]
+	options options
+}
+
+// Message is the data of a feed message, the event data and metadata as JSON
+type Message struct {
+	GlobalVersion eventsourcing.Version  `json:"global_version"`
+	AggregateID   string                 `json:"aggregate_id"`
+	AggregateType string                 `json:"aggregate_type"`
+	Version       eventsourcing.Version  `json:"version"`
+	Reason        string                 `json:"reason"`
+	Timestamp     time.Time              `json:"timestamp"`
+	Data          json.RawMessage        `json:"data"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// NewFeed returns a feed of the events in the global event store
+func NewFeed[T any](store eventsourcing.GlobalEventStore[T], opts ...Option) *Feed[T] {
+	o := options{pollInterval: defaultPollInterval, batchSize: defaultBatchSize, heartbeat: defaultHeartbeat}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Feed[T]{store: store, options: o}
+}
+
+// ServeHTTP streams the events until the client disconnects
+func (f *Feed[T]) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	token := r.Header.Get("Last-Event-ID")
+	if token == "" {
+		token = r.URL.Query().Get("after")
+	}
+	var after uint64
+	if token != "" {
+		var err error
+		after, err = strconv.ParseUint(token, 10, 64)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid resume token %q", token), http.StatusBadRequest)
+			return
+		}
+	}
+	filter := eventsourcing.EventFilter{
+		AggregateTypes: r.URL.Query()["type"],
+		Reasons:        r.URL.Query()["reason"],
+	}
+	principal, authenticated := PrincipalFrom(r.Context())
+	if authenticated {
+		var ok bool
+		filter.AggregateTypes, ok = scope(principal, filter.AggregateTypes)
+		if !ok {
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	poll := time.NewTicker(f.options.pollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(f.options.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		events, err := f.store.GlobalEvents(after+1, f.options.batchSize)
+		if err != nil {
+			// the status is already sent, the client reconnects with the last event id
+			return
+		}
+		for _, event := range events {
+			after = uint64(event.GlobalVersion)
+			if !filter.Match(event.AggregateType, event.Reason()) || authenticated && !principal.CanAccess(event.Metadata) {
+				continue
+			}
+			err = write(w, event, f.options.maskMode)
+			if err != nil {
+				return
+			}
+		}
+		if len(events) > 0 {
+			flusher.Flush()
+			if uint64(len(events)) == f.options.batchSize {
+				// read the next batch without waiting until the client has caught up
+				continue
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-poll.C:
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+			if err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// scope limits the requested aggregate types to the types the principal can read, false if it can't read any of them
+func scope(principal Principal, requested []string) ([]string, bool) {
+	readable := principal.readable()
+	if len(requested) == 0 {
+		return readable, readable == nil || len(readable) > 0
+	}
+	for _, aggregateType := range requested {
+		if !principal.Can(aggregateType, PermissionRead) {
+			return nil, false
+		}
+	}
+	return requested, true
+}
+
+// write writes the event as a message with the global version as id and the reason as event type, the sensitive fields
+// of the data are masked
+func write[T any](w http.ResponseWriter, event eventsourcing.Event[T], mode eventsourcing.MaskMode) error {
+	data, err := eventsourcing.MarshalMasked(event.Data, mode)
+	if err != nil {
+		return err
+	}
+	b, err := json.Marshal(Message{
+		GlobalVersion: event.GlobalVersion,
+		AggregateID:   event.AggregateID,
+		AggregateType: event.AggregateType,
+		Version:       event.Version,
+		Reason:        event.Reason(),
+		Timestamp:     event.Timestamp,
+		Data:          data,
+		Metadata:      event.Metadata,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.GlobalVersion, event.Reason(), b)
+	return err
+}
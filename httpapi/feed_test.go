@@ -0,0 +1,166 @@
+package httpapi_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/httpapi"
+)
+
+type Opened struct{}
+type Deposited struct {
+	Amount int
+}
+
+func save(t *testing.T, es *memory.Memory[any], aggregateType, id string, version eventsourcing.Version, data any) {
+	err := es.Save([]eventsourcing.Event[any]{{AggregateID: id, AggregateType: aggregateType, Version: version, Timestamp: time.Now(), Data: data}})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+type message struct {
+	id, event string
+	data      httpapi.Message
+}
+
+// read returns the next n messages of the feed
+func read(t *testing.T, scanner *bufio.Scanner, n int) []message {
+	var messages []message
+	m := message{}
+	for len(messages) < n && scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			if m.id != "" {
+				messages = append(messages, m)
+			}
+			m = message{}
+		case strings.HasPrefix(line, "id: "):
+			m.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "event: "):
+			m.event = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &m.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if len(messages) != n {
+		t.Fatalf("expected %d messages got %d, %v", n, len(messages), scanner.Err())
+	}
+	return messages
+}
+
+func open(t *testing.T, ctx context.Context, url, lastEventID string) *bufio.Scanner {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	if res.Header.Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("expected an event stream got %s", res.Header.Get("Content-Type"))
+	}
+	return bufio.NewScanner(res.Body)
+}
+
+func TestFeed(t *testing.T) {
+	es := memory.Create[any]()
+	save(t, es, "Account", "1", 1, &Opened{})
+	save(t, es, "Person", "1", 1, &Opened{})
+	save(t, es, "Account", "1", 2, &Deposited{Amount: 10})
+
+	server := httptest.NewServer(httpapi.NewFeed[any](es, httpapi.WithPollInterval(10*time.Millisecond), httpapi.WithBatchSize(2)))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	scanner := open(t, ctx, server.URL+"?type=Account", "")
+	messages := read(t, scanner, 2)
+	if messages[0].id != "1" || messages[1].id != "3" || messages[1].event != "Deposited" {
+		t.Fatalf("expected the account events 1 and 3 got %+v", messages)
+	}
+	if messages[1].data.Version != 2 || string(messages[1].data.Data) != `{"Amount":10}` {
+		t.Fatalf("unexpected message data %+v", messages[1].data)
+	}
+
+	// events saved after the client caught up are streamed
+	save(t, es, "Account", "1", 3, &Deposited{Amount: 5})
+	messages = read(t, scanner, 1)
+	if messages[0].id != "4" {
+		t.Fatalf("expected event 4 got %s", messages[0].id)
+	}
+}
+
+func TestFeedResume(t *testing.T) {
+	es := memory.Create[any]()
+	save(t, es, "Account", "1", 1, &Opened{})
+	save(t, es, "Account", "1", 2, &Deposited{Amount: 10})
+
+	server := httptest.NewServer(httpapi.NewFeed[any](es))
+	defer server.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	messages := read(t, open(t, ctx, server.URL, "1"), 1)
+	if messages[0].id != "2" {
+		t.Fatalf("expected to resume after event 1 got %s", messages[0].id)
+	}
+
+	res, err := http.Get(server.URL + "?after=x")
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	if res.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected bad request on an invalid resume token got %d", res.StatusCode)
+	}
+}
+
+type Registered struct {
+	Name  string
+	Email string `es:"sensitive"`
+}
+
+func TestFeedMasked(t *testing.T) {
+	es := memory.Create[any]()
+	save(t, es, "Customer", "1", 1, &Registered{Name: "jane", Email: "jane@example.com"})
+
+	tests := []struct {
+		title    string
+		opts     []httpapi.Option
+		expected string
+	}{
+		{title: "redact", expected: `{"Email":"[redacted]","Name":"jane"}`},
+		{title: "drop", opts: []httpapi.Option{httpapi.WithMaskMode(eventsourcing.MaskDrop)}, expected: `{"Name":"jane"}`},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			server := httptest.NewServer(httpapi.NewFeed[any](es, test.opts...))
+			defer server.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			messages := read(t, open(t, ctx, server.URL, ""), 1)
+			if string(messages[0].data.Data) != test.expected {
+				t.Fatalf("expected %s got %s", test.expected, messages[0].data.Data)
+			}
+		})
+	}
+}
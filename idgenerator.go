@@ -2,6 +2,8 @@ package eventsourcing
 
 import (
 	"crypto/rand"
+	"encoding/hex"
+	"time"
 )
 
 // idFunc is a global function that generates aggregate id's.
@@ -14,6 +16,39 @@ func SetIDFunc(f func() string) {
 	idFunc = f
 }
 
+// eventIDFunc generates the id of tracked events.
+// It could be changed from the outside via the SetEventIDFunc function.
+var eventIDFunc = NewUUIDv7
+
+// SetEventIDFunc is used to change how event ID's are generated
+// default is a UUIDv7
+func SetEventIDFunc(f func() string) {
+	eventIDFunc = f
+}
+
+// NewEventID returns an id from the event id function, used by code creating events without tracking them on an
+// aggregate
+func NewEventID() string {
+	return eventIDFunc()
+}
+
+// NewUUIDv7 returns a UUID version 7. The first 48 bits are the unix time in milliseconds making the ids sort in
+// the order they are created, the rest is random.
+func NewUUIDv7() string {
+	b, err := generateRandomBytes(16)
+	if err != nil {
+		return ""
+	}
+	ms := uint64(time.Now().UnixMilli())
+	for i := 0; i < 6; i++ {
+		b[i] = byte(ms >> (40 - 8*i))
+	}
+	b[6] = b[6]&0x0f | 0x70 // version 7
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	h := hex.EncodeToString(b)
+	return h[0:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+}
+
 func randSeq() string {
 	id, err := generateRandomString(20)
 	if err != nil {
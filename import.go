@@ -0,0 +1,38 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// MetadataImported is the metadata key marking the event an aggregate was imported with
+const MetadataImported = "imported"
+
+// ErrAggregateHasHistory when importing an aggregate that already has events
+var ErrAggregateHasHistory = errors.New("aggregate already has events")
+
+// Import creates an aggregate from state kept outside of the event store, like a row in a legacy CRUD system,
+// without reconstructing its history. Set the state on the aggregate and pass the event marking the import, it's
+// tracked as version 1 with the MetadataImported key set. The event is saved and the aggregate state is saved as a
+// snapshot, later loads start from the snapshot.
+//
+// The event stores reject the import of an aggregate that already exists. If the snapshot fails to save it can be
+// retried with SaveSnapshot.
+func (r *Repository[T]) Import(aggregate Aggregate[T], imported T) error {
+	if r.snapshot == nil {
+		return errors.New("no snapshot store has been initialized")
+	}
+	root := aggregate.Root()
+	if root.Version() != 0 {
+		return fmt.Errorf("%w: %s at version %d", ErrAggregateHasHistory, root.ID(), root.Version())
+	}
+	err := root.TrackChangeWithMetadata(aggregate, imported, map[string]interface{}{MetadataImported: true})
+	if err != nil {
+		return err
+	}
+	err = r.Save(aggregate)
+	if err != nil {
+		return err
+	}
+	return r.snapshot.Save(aggregate)
+}
@@ -0,0 +1,71 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+// ImportedFromLegacy event
+type ImportedFromLegacy struct {
+	System string
+}
+
+func (*ImportedFromLegacy) personEvent() {}
+
+func TestImport(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	es := memory.Create[PersonEvent]()
+	repo := eventsourcing.NewRepository[PersonEvent](es, eventsourcing.SnapshotNew(memsnap.New(), *ser))
+
+	// the state as it's kept in the legacy system
+	person := &Person{Name: "kalle", Age: 42}
+	person.SetID("legacy-1")
+	err := repo.Import(person, &ImportedFromLegacy{System: "crm"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if person.Version() != 1 || person.UnsavedEvents() {
+		t.Fatalf("expected the import to be saved at version 1 got %d", person.Version())
+	}
+
+	twin := Person{}
+	err = repo.Get("legacy-1", &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.Name != "kalle" || twin.Age != 42 || twin.Version() != 1 {
+		t.Fatalf("expected the imported state got %+v", twin)
+	}
+	twin.GrowOlder()
+	err = repo.Save(&twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Get("legacy-1", &twin)
+	if err != nil || twin.Age != 43 {
+		t.Fatalf("expected age 43 got %d %v", twin.Age, err)
+	}
+
+	events, _ := es.GlobalEvents(1, 10)
+	if imported, _ := eventsourcing.MetadataAs[bool](events[0], eventsourcing.MetadataImported); !imported {
+		t.Fatal("expected the first event to be marked as imported")
+	}
+
+	// a second import of the same aggregate is rejected
+	again := &Person{Name: "kalle"}
+	again.SetID("legacy-1")
+	err = repo.Import(again, &ImportedFromLegacy{System: "crm"})
+	if !errors.Is(err, eventstore.ErrConcurrency) {
+		t.Fatalf("expected concurrency error got %v", err)
+	}
+	err = repo.Import(&twin, &ImportedFromLegacy{System: "crm"})
+	if !errors.Is(err, eventsourcing.ErrAggregateHasHistory) {
+		t.Fatalf("expected aggregate has history got %v", err)
+	}
+}
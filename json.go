@@ -0,0 +1,304 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONOption configures the marshal functions returned by JSON
+type JSONOption func(*jsonOptions)
+
+type jsonOptions struct {
+	omitEmpty  bool
+	timeFormat string
+	useNumber  bool
+	// mask is set when marshaling for MarshalMasked
+	mask     bool
+	maskMode MaskMode
+}
+
+// WithJSONOmitEmpty leaves out struct fields with empty values, like the omitempty tag on every field. It makes the
+// stored events smaller, a field left out is unmarshaled to its zero value.
+func WithJSONOmitEmpty() JSONOption {
+	return func(o *jsonOptions) {
+		o.omitEmpty = true
+	}
+}
+
+// WithJSONTimeFormat marshals the time.Time fields with the layout instead of RFC 3339, and unmarshals them with it
+func WithJSONTimeFormat(layout string) JSONOption {
+	return func(o *jsonOptions) {
+		o.timeFormat = layout
+	}
+}
+
+// WithJSONUseNumber unmarshals numbers into interface values as json.Number instead of float64, keeping large
+// integers in the metadata from losing precision
+func WithJSONUseNumber() JSONOption {
+	return func(o *jsonOptions) {
+		o.useNumber = true
+	}
+}
+
+// JSON returns marshal functions based on encoding/json with the options. json.RawMessage fields are passed through
+// untouched by the options, for opaque sub payloads. The options apply to the fields known from the type of the
+// value, values held in interface fields are marshaled as by encoding/json.
+func JSON(opts ...JSONOption) (MarshalSnapshotFunc, UnmarshalSnapshotFunc) {
+	o := jsonOptions{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	marshal := func(v any) ([]byte, error) {
+		data, err := json.Marshal(v)
+		if err != nil || !o.rewrites() {
+			return data, err
+		}
+		return o.rewrite(concreteType(v), data, true)
+	}
+	unmarshal := func(data []byte, v any) error {
+		var err error
+		if o.timeFormat != "" {
+			data, err = o.rewrite(concreteType(v), data, false)
+			if err != nil {
+				return err
+			}
+		}
+		if !o.useNumber {
+			return json.Unmarshal(data, v)
+		}
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		return dec.Decode(v)
+	}
+	return marshal, unmarshal
+}
+
+// NewJSONSerializer returns a serializer using the marshal functions from JSON
+func NewJSONSerializer[T any](opts ...JSONOption) *Serializer[T] {
+	marshal, unmarshal := JSON(opts...)
+	return NewSerializer[T](marshal, unmarshal)
+}
+
+var (
+	timeType       = reflect.TypeOf(time.Time{})
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+)
+
+func (o jsonOptions) rewrites() bool {
+	return o.omitEmpty || o.timeFormat != "" || o.mask
+}
+
+// concreteType returns the type of the value behind pointers and interfaces
+func concreteType(v any) reflect.Type {
+	rv := reflect.ValueOf(v)
+	for rv.IsValid() && (rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface) && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	if rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		// a nil pointer to a type is unmarshaled into a new value of the type
+		t := rv.Type()
+		for t.Kind() == reflect.Ptr {
+			t = t.Elem()
+		}
+		return t
+	}
+	return rv.Type()
+}
+
+// rewrite walks the JSON of a value of type t applying the options, on marshal times are formatted with the time
+// format and empty fields are left out, on unmarshal times are turned back into RFC 3339. Only the parts of the JSON
+// that need it are decoded, the rest is kept as is.
+func (o jsonOptions) rewrite(t reflect.Type, data []byte, marshal bool) ([]byte, error) {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == nil || t == rawMessageType || bytes.Equal(data, []byte("null")) {
+		return data, nil
+	}
+	if t == timeType {
+		if o.timeFormat == "" {
+			return data, nil
+		}
+		return o.rewriteTime(data, marshal)
+	}
+	if marshalsItself(t) {
+		return data, nil
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		return o.rewriteStruct(t, data, marshal)
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return data, nil
+		}
+		var elems []json.RawMessage
+		if err := json.Unmarshal(data, &elems); err != nil {
+			return nil, err
+		}
+		for i := range elems {
+			e, err := o.rewrite(t.Elem(), elems[i], marshal)
+			if err != nil {
+				return nil, err
+			}
+			elems[i] = e
+		}
+		return json.Marshal(elems)
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return data, nil
+		}
+		var values map[string]json.RawMessage
+		if err := json.Unmarshal(data, &values); err != nil {
+			return nil, err
+		}
+		for key, value := range values {
+			v, err := o.rewrite(t.Elem(), value, marshal)
+			if err != nil {
+				return nil, err
+			}
+			values[key] = v
+		}
+		return json.Marshal(values)
+	}
+	return data, nil
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// marshalsItself returns true if the type has its own JSON or text encoding
+func marshalsItself(t reflect.Type) bool {
+	for _, m := range []reflect.Type{jsonMarshalerType, textMarshalerType} {
+		if t.Implements(m) || reflect.PtrTo(t).Implements(m) {
+			return true
+		}
+	}
+	return false
+}
+
+func (o jsonOptions) rewriteTime(data []byte, marshal bool) ([]byte, error) {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, err
+	}
+	from, to := time.RFC3339Nano, o.timeFormat
+	if !marshal {
+		from, to = to, from
+	}
+	t, err := time.Parse(from, s)
+	if err != nil {
+		return nil, fmt.Errorf("time %q: %w", s, err)
+	}
+	return json.Marshal(t.Format(to))
+}
+
+func (o jsonOptions) rewriteStruct(t reflect.Type, data []byte, marshal bool) ([]byte, error) {
+	var values map[string]json.RawMessage
+	if err := json.Unmarshal(data, &values); err != nil {
+		return nil, err
+	}
+	for _, f := range jsonFieldsOf(t) {
+		key, ok := lookupKey(values, f.name, marshal)
+		if !ok {
+			continue
+		}
+		if marshal && o.mask && f.sensitive {
+			if o.maskMode == MaskDrop {
+				delete(values, key)
+			} else {
+				values[key] = json.RawMessage(`"` + Redacted + `"`)
+			}
+			continue
+		}
+		if f.quoted {
+			// the ",string" option holds the value in a string
+			continue
+		}
+		v, err := o.rewrite(f.typ, values[key], marshal)
+		if err != nil {
+			return nil, err
+		}
+		// the contents of a json.RawMessage are kept, only a nil one is left out
+		if marshal && o.omitEmpty && (emptyJSON(v) && f.typ != rawMessageType || string(v) == "null") {
+			delete(values, key)
+			continue
+		}
+		values[key] = v
+	}
+	return json.Marshal(values)
+}
+
+// lookupKey finds the key of the field, unmarshal matches keys case insensitively like encoding/json
+func lookupKey(values map[string]json.RawMessage, name string, marshal bool) (string, bool) {
+	if _, ok := values[name]; ok || marshal {
+		return name, ok
+	}
+	for key := range values {
+		if strings.EqualFold(key, name) {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+func emptyJSON(v []byte) bool {
+	switch string(v) {
+	case `""`, `0`, `false`, `null`, `{}`, `[]`:
+		return true
+	}
+	return false
+}
+
+type jsonField struct {
+	name      string
+	typ       reflect.Type
+	quoted    bool
+	sensitive bool
+}
+
+// jsonFieldsOf returns the fields of the struct as named by encoding/json, the fields of embedded structs without a
+// name are promoted and are sensitive if the embedded struct is
+func jsonFieldsOf(t reflect.Type) []jsonField {
+	var fields []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		ft := f.Type
+		sensitive := sensitiveField(f)
+		if f.Anonymous && name == "" {
+			et := ft
+			if et.Kind() == reflect.Ptr {
+				et = et.Elem()
+			}
+			if et.Kind() == reflect.Struct {
+				for _, promoted := range jsonFieldsOf(et) {
+					promoted.sensitive = promoted.sensitive || sensitive
+					fields = append(fields, promoted)
+				}
+				continue
+			}
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, jsonField{name: name, typ: ft, quoted: strings.Contains(opts, "string"), sensitive: sensitive})
+	}
+	return fields
+}
@@ -0,0 +1,133 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type Shipped struct {
+	Carrier
+	Order    string
+	Quantity int
+	Express  bool
+	At       time.Time
+	Stops    []Stop
+	Payload  json.RawMessage
+	Tags     map[string]string
+}
+
+type Carrier struct {
+	CarrierName string `json:"carrier"`
+}
+
+type Stop struct {
+	City string
+	At   *time.Time
+}
+
+func TestJSONOmitEmpty(t *testing.T) {
+	marshal, unmarshal := eventsourcing.JSON(eventsourcing.WithJSONOmitEmpty())
+	data, err := marshal(&Shipped{Order: "1", At: time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), Stops: []Stop{{City: "Lund"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"At":"2023-01-02T00:00:00Z","Order":"1","Stops":[{"City":"Lund"}]}`
+	if string(data) != expected {
+		t.Fatalf("expected %s got %s", expected, data)
+	}
+	var shipped Shipped
+	err = unmarshal(data, &shipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shipped.Order != "1" || shipped.Quantity != 0 || len(shipped.Stops) != 1 {
+		t.Fatalf("expected the event back got %+v", shipped)
+	}
+}
+
+func TestJSONTimeFormat(t *testing.T) {
+	marshal, unmarshal := eventsourcing.JSON(eventsourcing.WithJSONTimeFormat("2006-01-02 15:04"))
+	at := time.Date(2023, 1, 2, 3, 4, 0, 0, time.UTC)
+	data, err := marshal(Shipped{Carrier: Carrier{CarrierName: "post"}, At: at, Stops: []Stop{{City: "Lund", At: &at}, {City: "Malmö"}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var values struct {
+		At    string
+		Stops []struct{ At *string }
+	}
+	err = json.Unmarshal(data, &values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if values.At != "2023-01-02 03:04" || *values.Stops[0].At != "2023-01-02 03:04" || values.Stops[1].At != nil {
+		t.Fatalf("expected the times to be formatted got %s", data)
+	}
+	var shipped Shipped
+	err = unmarshal(data, &shipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !shipped.At.Equal(at) || !shipped.Stops[0].At.Equal(at) || shipped.CarrierName != "post" {
+		t.Fatalf("expected the event back got %+v", shipped)
+	}
+}
+
+func TestJSONUseNumber(t *testing.T) {
+	marshal, unmarshal := eventsourcing.JSON(eventsourcing.WithJSONUseNumber())
+	data, err := marshal(map[string]interface{}{"tenant_id": int64(9007199254740993)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]interface{}{}
+	err = unmarshal(data, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if metadata["tenant_id"] != json.Number("9007199254740993") {
+		t.Fatalf("expected the number to keep its precision got %v", metadata["tenant_id"])
+	}
+}
+
+func TestJSONRawMessagePassthrough(t *testing.T) {
+	marshal, unmarshal := eventsourcing.JSON(eventsourcing.WithJSONOmitEmpty(), eventsourcing.WithJSONTimeFormat(time.Kitchen))
+	payload := json.RawMessage(`{"z":1,"a":{},"at":"2023-01-02T00:00:00Z","empty":""}`)
+	data, err := marshal(Shipped{Payload: payload})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var shipped Shipped
+	err = unmarshal(data, &shipped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(shipped.Payload) != string(payload) {
+		t.Fatalf("expected the payload untouched got %s", shipped.Payload)
+	}
+}
+
+func TestJSONSerializer(t *testing.T) {
+	s := eventsourcing.NewJSONSerializer[Data](eventsourcing.WithJSONOmitEmpty())
+	err := s.Register(&SomeAggregate{}, s.Events(&SomeData{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := s.Marshal(&SomeData{A: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"A":1}` {
+		t.Fatalf("expected the empty field to be left out got %s", data)
+	}
+	event := eventsourcing.Event[Data]{AggregateType: "SomeAggregate"}
+	_, err = s.UnmarshalEvent(&event, "SomeData", data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *event.Data.(*SomeData) != (SomeData{A: 1}) {
+		t.Fatalf("expected the event back got %+v", event.Data)
+	}
+}
@@ -0,0 +1,262 @@
+// Package jsonschema validates json event data against a JSON Schema. It supports the subset of the specification
+// describing event payloads: type, properties, required, additionalProperties, items, enum and the length and range
+// keywords. Unsupported keywords are ignored. The compiled schema is registered on the serializer with
+// RegisterSchema.
+package jsonschema
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrInvalidSchema is returned when the schema document can't be compiled
+var ErrInvalidSchema = errors.New("invalid json schema")
+
+// Schema is a compiled JSON Schema
+type Schema struct {
+	types                []string
+	properties           map[string]*Schema
+	required             []string
+	additionalProperties *Schema
+	noAdditional         bool
+	items                *Schema
+	enum                 []interface{}
+	minimum, maximum     *float64
+	minLength, maxLength *int
+	minItems, maxItems   *int
+}
+
+type document struct {
+	Type                 json.RawMessage            `json:"type"`
+	Properties           map[string]json.RawMessage `json:"properties"`
+	Required             []string                   `json:"required"`
+	AdditionalProperties json.RawMessage            `json:"additionalProperties"`
+	Items                json.RawMessage            `json:"items"`
+	Enum                 []interface{}              `json:"enum"`
+	Minimum              *float64                   `json:"minimum"`
+	Maximum              *float64                   `json:"maximum"`
+	MinLength            *int                       `json:"minLength"`
+	MaxLength            *int                       `json:"maxLength"`
+	MinItems             *int                       `json:"minItems"`
+	MaxItems             *int                       `json:"maxItems"`
+}
+
+// Compile compiles the JSON Schema document
+func Compile(doc []byte) (*Schema, error) {
+	s, err := compile(doc)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSchema, err)
+	}
+	return s, nil
+}
+
+// MustCompile compiles the JSON Schema document and panics if it's invalid
+func MustCompile(doc []byte) *Schema {
+	s, err := Compile(doc)
+	if err != nil {
+		panic(err)
+	}
+	return s
+}
+
+func compile(raw []byte) (*Schema, error) {
+	d := document{}
+	err := json.Unmarshal(raw, &d)
+	if err != nil {
+		return nil, err
+	}
+	s := &Schema{
+		required:  d.Required,
+		enum:      d.Enum,
+		minimum:   d.Minimum,
+		maximum:   d.Maximum,
+		minLength: d.MinLength,
+		maxLength: d.MaxLength,
+		minItems:  d.MinItems,
+		maxItems:  d.MaxItems,
+	}
+	if len(d.Type) > 0 {
+		var typ string
+		if json.Unmarshal(d.Type, &typ) == nil {
+			s.types = []string{typ}
+		} else if err := json.Unmarshal(d.Type, &s.types); err != nil {
+			return nil, fmt.Errorf("type must be a string or an array of strings")
+		}
+	}
+	if len(d.Properties) > 0 {
+		s.properties = make(map[string]*Schema, len(d.Properties))
+		for name, p := range d.Properties {
+			s.properties[name], err = compile(p)
+			if err != nil {
+				return nil, fmt.Errorf("property %s: %v", name, err)
+			}
+		}
+	}
+	if len(d.AdditionalProperties) > 0 {
+		var allowed bool
+		if json.Unmarshal(d.AdditionalProperties, &allowed) == nil {
+			s.noAdditional = !allowed
+		} else if s.additionalProperties, err = compile(d.AdditionalProperties); err != nil {
+			return nil, fmt.Errorf("additionalProperties: %v", err)
+		}
+	}
+	if len(d.Items) > 0 {
+		s.items, err = compile(d.Items)
+		if err != nil {
+			return nil, fmt.Errorf("items: %v", err)
+		}
+	}
+	return s, nil
+}
+
+// Validate validates the json data against the schema, the error names the path of the first violation
+func (s *Schema) Validate(data []byte) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	// numbers are kept as json.Number to tell integers from other numbers
+	decoder.UseNumber()
+	var v interface{}
+	err := decoder.Decode(&v)
+	if err != nil {
+		return err
+	}
+	return s.validate("", v)
+}
+
+func (s *Schema) validate(path string, v interface{}) error {
+	if len(s.types) > 0 && !s.hasType(v) {
+		return violation(path, "expected %s got %s", strings.Join(s.types, " or "), typeOf(v))
+	}
+	if len(s.enum) > 0 && !s.inEnum(v) {
+		return violation(path, "value not in enum")
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return s.validateObject(path, value)
+	case []interface{}:
+		if s.minItems != nil && len(value) < *s.minItems {
+			return violation(path, "expected at least %d items", *s.minItems)
+		}
+		if s.maxItems != nil && len(value) > *s.maxItems {
+			return violation(path, "expected at most %d items", *s.maxItems)
+		}
+		if s.items != nil {
+			for i, item := range value {
+				err := s.items.validate(fmt.Sprintf("%s/%d", path, i), item)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	case string:
+		length := len([]rune(value))
+		if s.minLength != nil && length < *s.minLength {
+			return violation(path, "expected at least %d characters", *s.minLength)
+		}
+		if s.maxLength != nil && length > *s.maxLength {
+			return violation(path, "expected at most %d characters", *s.maxLength)
+		}
+	case json.Number:
+		f, err := value.Float64()
+		if err != nil {
+			return violation(path, "%v", err)
+		}
+		if s.minimum != nil && f < *s.minimum {
+			return violation(path, "expected minimum %v", *s.minimum)
+		}
+		if s.maximum != nil && f > *s.maximum {
+			return violation(path, "expected maximum %v", *s.maximum)
+		}
+	}
+	return nil
+}
+
+func (s *Schema) validateObject(path string, object map[string]interface{}) error {
+	for _, name := range s.required {
+		if _, ok := object[name]; !ok {
+			return violation(path, "missing required property %s", name)
+		}
+	}
+	// validate the properties in order to make the reported violation deterministic
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		property, ok := s.properties[name]
+		if !ok {
+			if s.noAdditional {
+				return violation(path, "additional property %s not allowed", name)
+			}
+			property = s.additionalProperties
+		}
+		if property == nil {
+			continue
+		}
+		err := property.validate(path+"/"+name, object[name])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Schema) hasType(v interface{}) bool {
+	actual := typeOf(v)
+	for _, t := range s.types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) inEnum(v interface{}) bool {
+	for _, e := range s.enum {
+		// the enum values are decoded as float64 and the data as json.Number
+		if n, ok := v.(json.Number); ok {
+			f, err := n.Float64()
+			if err == nil && reflect.DeepEqual(e, f) {
+				return true
+			}
+			continue
+		}
+		if reflect.DeepEqual(e, v) {
+			return true
+		}
+	}
+	return false
+}
+
+func typeOf(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		return "number"
+	case []interface{}:
+		return "array"
+	case map[string]interface{}:
+		return "object"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+func violation(path, format string, args ...interface{}) error {
+	if path == "" {
+		path = "/"
+	}
+	return fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...))
+}
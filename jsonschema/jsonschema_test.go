@@ -0,0 +1,65 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing/jsonschema"
+)
+
+var orderPlaced = []byte(`{
+	"type": "object",
+	"required": ["OrderID", "Lines"],
+	"additionalProperties": false,
+	"properties": {
+		"OrderID": {"type": "string", "minLength": 1},
+		"Currency": {"enum": ["SEK", "EUR"]},
+		"Lines": {
+			"type": "array",
+			"minItems": 1,
+			"items": {
+				"type": "object",
+				"required": ["Quantity"],
+				"properties": {"Quantity": {"type": "integer", "minimum": 1}}
+			}
+		}
+	}
+}`)
+
+func TestValidate(t *testing.T) {
+	schema, err := jsonschema.Compile(orderPlaced)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{data: `{"OrderID":"1","Currency":"SEK","Lines":[{"Quantity":2}]}`},
+		{data: `{"OrderID":"1","Lines":[]}`, err: "/Lines: expected at least 1 items"},
+		{data: `{"Lines":[{"Quantity":1}]}`, err: "/: missing required property OrderID"},
+		{data: `{"OrderID":"","Lines":[{"Quantity":1}]}`, err: "/OrderID: expected at least 1 characters"},
+		{data: `{"OrderID":"1","Lines":[{"Quantity":1.5}]}`, err: "/Lines/0/Quantity: expected integer got number"},
+		{data: `{"OrderID":"1","Lines":[{"Quantity":0}]}`, err: "/Lines/0/Quantity: expected minimum 1"},
+		{data: `{"OrderID":"1","Currency":"USD","Lines":[{"Quantity":1}]}`, err: "/Currency: value not in enum"},
+		{data: `{"OrderID":"1","Lines":[{"Quantity":1}],"Note":"x"}`, err: "/: additional property Note not allowed"},
+		{data: `[]`, err: "/: expected object got array"},
+	}
+	for _, test := range tests {
+		err := schema.Validate([]byte(test.data))
+		if test.err == "" && err != nil {
+			t.Fatalf("expected %s to be valid got %v", test.data, err)
+		}
+		if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Fatalf("expected %s to fail with %q got %v", test.data, test.err, err)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	_, err := jsonschema.Compile([]byte(`{"type": 1}`))
+	if !errors.Is(err, jsonschema.ErrInvalidSchema) {
+		t.Fatalf("expected invalid schema got %v", err)
+	}
+}
@@ -0,0 +1,10 @@
+package eventsourcing
+
+import "context"
+
+// CorrelationEventStore is implemented by event stores that can find the events sharing a correlation id, tracing
+// a business transaction across aggregates
+type CorrelationEventStore[T any] interface {
+	// EventsByCorrelationID returns the events with the correlation id in their metadata in global order
+	EventsByCorrelationID(ctx context.Context, correlationID string) ([]Event[T], error)
+}
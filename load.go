@@ -0,0 +1,32 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// Load constructs a new aggregate of type A and builds it from the events of the aggregate with the id in the event
+// store. It's for reading aggregates straight from an event store without a repository, snapshots or the cache.
+// ErrAggregateNotFound is returned when the aggregate has no events.
+//
+//	person, err := eventsourcing.Load[any, *Person](ctx, eventStore, id)
+func Load[T any, A Aggregate[T]](ctx context.Context, eventStore EventStore[T], id string) (A, error) {
+	var zero A
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return zero, errors.New("aggregate needs to be a pointer")
+	}
+	aggregate := reflect.New(typ.Elem()).Interface().(A)
+	aggregateType := typ.Elem().Name()
+	_, err := applyEventsAfter[T](ctx, eventStore, id, aggregateType, aggregate)
+	if err != nil {
+		return zero, err
+	}
+	if aggregate.Root().Version() == 0 {
+		return zero, fmt.Errorf("%w: %s %s", ErrAggregateNotFound, aggregateType, id)
+	}
+	afterLoad[T](aggregate)
+	return aggregate, nil
+}
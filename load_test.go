@@ -0,0 +1,46 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestLoad(t *testing.T) {
+	es := memory.Create[PersonEvent]()
+	repo := eventsourcing.NewRepository[PersonEvent](es, nil)
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := eventsourcing.Load[PersonEvent, *Person](context.Background(), es, person.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Name != "kalle" || loaded.Age != 1 {
+		t.Fatalf("expected kalle aged 1 got %s %d", loaded.Name, loaded.Age)
+	}
+	if loaded.ID() != person.ID() || loaded.Version() != 2 || loaded.GlobalVersion() != 2 {
+		t.Fatalf("expected id %s version 2 got %s %d %d", person.ID(), loaded.ID(), loaded.Version(), loaded.GlobalVersion())
+	}
+}
+
+func TestLoadNotFound(t *testing.T) {
+	es := memory.Create[PersonEvent]()
+	loaded, err := eventsourcing.Load[PersonEvent, *Person](context.Background(), es, "missing")
+	if !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected aggregate not found got %v", err)
+	}
+	if loaded != nil {
+		t.Fatal("expected no aggregate")
+	}
+}
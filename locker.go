@@ -0,0 +1,53 @@
+package eventsourcing
+
+import (
+	"context"
+	"reflect"
+)
+
+// Unlock releases an aggregate lock
+type Unlock func() error
+
+// Locker serializes the writers of an aggregate, across service instances when backed by a shared service
+type Locker interface {
+	// Lock blocks until the lock of the aggregate is held or the context is done
+	Lock(ctx context.Context, aggregateType, aggregateID string) (Unlock, error)
+}
+
+// SetLocker sets the locker used by Update to hold the aggregate lock while the aggregate is loaded, changed and saved
+func (r *Repository[T]) SetLocker(locker Locker) {
+	r.locker = locker
+}
+
+// Update loads the aggregate, runs the command and saves the aggregate. If a locker is set the aggregate lock is held
+// during the update, serializing the writers of the aggregate instead of failing them on concurrency errors. The lock
+// is taken on the id the alias store resolves the id to, writers using different aliases of the aggregate share it.
+func (r *Repository[T]) Update(ctx context.Context, id string, aggregate Aggregate[T], command func() error) (err error) {
+	if r.locker != nil {
+		aggregateType := reflect.TypeOf(aggregate).Elem().Name()
+		id, err = r.Resolve(ctx, aggregateType, id)
+		if err != nil {
+			return err
+		}
+		var unlock Unlock
+		unlock, err = r.locker.Lock(ctx, aggregateType, id)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			unlockErr := unlock()
+			if err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+	err = r.GetWithContext(ctx, id, aggregate)
+	if err != nil {
+		return err
+	}
+	err = command()
+	if err != nil {
+		return err
+	}
+	return r.Save(aggregate)
+}
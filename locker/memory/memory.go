@@ -0,0 +1,62 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type lock struct {
+	held    chan struct{}
+	waiters int
+}
+
+// Memory is a locker serializing the writers of an aggregate within the process
+type Memory struct {
+	lock  sync.Mutex
+	locks map[string]*lock
+}
+
+// New constructs a memory locker
+func New() *Memory {
+	return &Memory{locks: make(map[string]*lock)}
+}
+
+// Lock blocks until the lock of the aggregate is held or the context is done
+func (m *Memory) Lock(ctx context.Context, aggregateType, aggregateID string) (eventsourcing.Unlock, error) {
+	key := aggregateType + "_" + aggregateID
+	m.lock.Lock()
+	l, ok := m.locks[key]
+	if !ok {
+		l = &lock{held: make(chan struct{}, 1)}
+		m.locks[key] = l
+	}
+	l.waiters++
+	m.lock.Unlock()
+
+	select {
+	case l.held <- struct{}{}:
+	case <-ctx.Done():
+		m.release(key, l)
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() error {
+		once.Do(func() {
+			<-l.held
+			m.release(key, l)
+		})
+		return nil
+	}, nil
+}
+
+// release removes the lock when no one holds or waits for it
+func (m *Memory) release(key string, l *lock) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	l.waiters--
+	if l.waiters == 0 {
+		delete(m.locks, key)
+	}
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultTTL           = 30 * time.Second
+	defaultRetryInterval = 50 * time.Millisecond
+)
+
+// ErrLockLost when the lock expired and was taken by someone else before it was released
+var ErrLockLost = errors.New("lock lost before it was released")
+
+// unlockScript deletes the lock only if it's still held with the token
+var unlockScript = redis.NewScript(`
+if redis.call("get", KEYS[1]) == ARGV[1] then
+	return redis.call("del", KEYS[1])
+end
+return 0`)
+
+// Redis is a locker holding the aggregate locks in a redis instance. The lock expires after the ttl to not be held
+// forever by a crashed writer, a writer holding the lock longer than the ttl can lose it to another writer.
+type Redis struct {
+	client        redis.Cmdable
+	prefix        string
+	ttl           time.Duration
+	retryInterval time.Duration
+}
+
+// Option configures the redis locker
+type Option func(*Redis)
+
+// WithPrefix sets a prefix on the lock keys
+func WithPrefix(prefix string) Option {
+	return func(r *Redis) {
+		r.prefix = prefix
+	}
+}
+
+// WithTTL sets the time a lock is held before it expires
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Redis) {
+		r.ttl = ttl
+	}
+}
+
+// WithRetryInterval sets the time between attempts to take a held lock
+func WithRetryInterval(interval time.Duration) Option {
+	return func(r *Redis) {
+		r.retryInterval = interval
+	}
+}
+
+// New returns a redis locker
+func New(client redis.Cmdable, opts ...Option) *Redis {
+	r := &Redis{
+		client:        client,
+		prefix:        "lock:",
+		ttl:           defaultTTL,
+		retryInterval: defaultRetryInterval,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Lock blocks until the lock of the aggregate is held or the context is done
+func (r *Redis) Lock(ctx context.Context, aggregateType, aggregateID string) (eventsourcing.Unlock, error) {
+	key := r.prefix + aggregateType + ":" + aggregateID
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		ok, err := r.client.SetNX(ctx, key, token, r.ttl).Result()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			break
+		}
+		timer := time.NewTimer(r.retryInterval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+	return func() error {
+		deleted, err := unlockScript.Run(context.Background(), r.client, []string{key}, token).Int()
+		if err != nil {
+			return err
+		}
+		if deleted == 0 {
+			return ErrLockLost
+		}
+		return nil
+	}, nil
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,82 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hallgren/eventsourcing/locker/redis"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+func TestLock(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+	locker := redis.New(client, redis.WithRetryInterval(time.Millisecond))
+
+	unlock, err := locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !server.Exists("lock:Person:1") {
+		t.Fatal("expected the lock key")
+	}
+
+	// the lock is held
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err = locker.Lock(ctx, "Person", "1")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded got %v", err)
+	}
+
+	// another aggregate is not blocked
+	unlockOther, err := locker.Lock(context.Background(), "Person", "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlockOther()
+
+	err = unlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
+
+func TestLockLost(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+	locker := redis.New(client, redis.WithTTL(time.Second))
+
+	unlock, err := locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the lock expires and is taken by another writer
+	server.FastForward(2 * time.Second)
+	unlockOther, err := locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = unlock()
+	if !errors.Is(err, redis.ErrLockLost) {
+		t.Fatalf("expected ErrLockLost got %v", err)
+	}
+	// the other writer still holds the lock
+	if !server.Exists("lock:Person:1") {
+		t.Fatal("expected the other writers lock to be kept")
+	}
+	err = unlockOther()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
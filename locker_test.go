@@ -0,0 +1,121 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	aliasmem "github.com/hallgren/eventsourcing/alias/memory"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	lockmem "github.com/hallgren/eventsourcing/locker/memory"
+)
+
+func TestUpdateWithLocker(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	repo.SetLocker(lockmem.New())
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// concurrent writers are serialized instead of failing on concurrency errors
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p := Person{}
+			errs <- repo.Update(context.Background(), person.ID(), &p, func() error {
+				p.GrowOlder()
+				return nil
+			})
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	p := Person{}
+	err = repo.Get(person.ID(), &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Age != 10 {
+		t.Fatalf("expected age 10 got %d", p.Age)
+	}
+}
+
+// recordingLocker records the aggregate ids it locks
+type recordingLocker struct {
+	eventsourcing.Locker
+	locked []string
+}
+
+func (l *recordingLocker) Lock(ctx context.Context, aggregateType, aggregateID string) (eventsourcing.Unlock, error) {
+	l.locked = append(l.locked, aggregateID)
+	return l.Locker.Lock(ctx, aggregateType, aggregateID)
+}
+
+func TestUpdateLocksResolvedID(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	locker := &recordingLocker{Locker: lockmem.New()}
+	repo.SetLocker(locker)
+	aliases := aliasmem.New()
+	repo.SetAliasStore(aliases)
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = aliases.SetAlias(context.Background(), "Person", "old", person.ID())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := Person{}
+	err = repo.Update(context.Background(), "old", &p, func() error {
+		p.GrowOlder()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locker.locked) != 1 || locker.locked[0] != person.ID() {
+		t.Fatalf("expected the lock on %s got %v", person.ID(), locker.locked)
+	}
+}
+
+func TestMemoryLockerContextDone(t *testing.T) {
+	locker := lockmem.New()
+	unlock, err := locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = locker.Lock(ctx, "Person", "1")
+	if err != context.Canceled {
+		t.Fatalf("expected context canceled got %v", err)
+	}
+	unlock()
+	unlock, err = locker.Lock(context.Background(), "Person", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	unlock()
+}
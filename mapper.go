@@ -0,0 +1,87 @@
+package eventsourcing
+
+import (
+	"context"
+)
+
+// EventMapper decodes the raw events of several aggregate types, each with its own event type and serializer, into
+// untyped events. It lets one projection consume the events of aggregates that don't share an event type, like Order,
+// Payment and Shipment.
+type EventMapper struct {
+	decoders map[string]func(raw RawEvent) (Event[any], bool, error)
+}
+
+// NewEventMapper returns an event mapper without mappings
+func NewEventMapper() *EventMapper {
+	return &EventMapper{decoders: make(map[string]func(raw RawEvent) (Event[any], bool, error))}
+}
+
+// AddMapping decodes the events of the aggregate type with the serializer where the event types of the aggregate are
+// registered. It's a function as methods can't have type parameters.
+func AddMapping[T any](m *EventMapper, aggregateType string, serializer *Serializer[T]) {
+	m.decoders[aggregateType] = func(raw RawEvent) (Event[any], bool, error) {
+		event, ok, err := serializer.DecodeRaw(raw)
+		if err != nil || !ok {
+			return Event[any]{}, ok, err
+		}
+		return Event[any]{
+			EventID:       event.EventID,
+			AggregateID:   event.AggregateID,
+			AggregateType: event.AggregateType,
+			Version:       event.Version,
+			GlobalVersion: event.GlobalVersion,
+			Timestamp:     event.Timestamp,
+			ValidTime:     event.ValidTime,
+			Data:          event.Data,
+			Metadata:      event.Metadata,
+		}, true, nil
+	}
+}
+
+// Map decodes the raw event, ok is false if its aggregate type is not mapped or its reason is not registered
+func (m *EventMapper) Map(raw RawEvent) (event Event[any], ok bool, err error) {
+	decode, found := m.decoders[raw.AggregateType]
+	if !found {
+		return Event[any]{}, false, nil
+	}
+	return decode(raw)
+}
+
+// GlobalEvents returns a global event store of the mapped events read from the raw event store
+func (m *EventMapper) GlobalEvents(store RawEventStore) GlobalEventStore[any] {
+	return &mappedEvents{mapper: m, store: store}
+}
+
+type mappedEvents struct {
+	mapper *EventMapper
+	store  RawEventStore
+}
+
+// GlobalEvents returns the mapped events from the start position. Events that are not mapped are skipped, the raw
+// events are read until a mapped event is found to not report the end of the events on a batch of skipped events.
+func (e *mappedEvents) GlobalEvents(start, count uint64) ([]Event[any], error) {
+	events := make([]Event[any], 0)
+	if count == 0 {
+		return events, nil
+	}
+	for len(events) == 0 {
+		raws, err := e.store.GlobalEventsRaw(context.Background(), start, count)
+		if err != nil {
+			return nil, err
+		}
+		for _, raw := range raws {
+			event, ok, err := e.mapper.Map(raw)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				events = append(events, event)
+			}
+		}
+		if uint64(len(raws)) < count {
+			break
+		}
+		start = uint64(raws[len(raws)-1].GlobalVersion) + 1
+	}
+	return events, nil
+}
@@ -0,0 +1,70 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// rawStore holds the raw events in global order
+type rawStore []eventsourcing.RawEvent
+
+func (s rawStore) GetRaw(ctx context.Context, id, aggregateType string, afterVersion eventsourcing.Version) ([]eventsourcing.RawEvent, error) {
+	return nil, nil
+}
+
+func (s rawStore) GlobalEventsRaw(ctx context.Context, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	var events []eventsourcing.RawEvent
+	for _, e := range s {
+		if uint64(e.GlobalVersion) >= start && uint64(len(events)) < count {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+func TestEventMapper(t *testing.T) {
+	persons := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	persons.Register(&Person{}, persons.Events(&Born{}))
+	orders := eventsourcing.NewSerializer[OrderEvent](json.Marshal, json.Unmarshal)
+	orders.Register(&Order{}, orders.Events(&OrderPlaced{}, &LineAdded{}))
+
+	mapper := eventsourcing.NewEventMapper()
+	eventsourcing.AddMapping(mapper, "Person", persons)
+	eventsourcing.AddMapping(mapper, "Order", orders)
+
+	store := rawStore{
+		{AggregateType: "Person", AggregateID: "p", Version: 1, GlobalVersion: 1, Reason: "Born", Data: []byte(`{"Name":"kalle"}`)},
+		{AggregateType: "Order", AggregateID: "o", Version: 1, GlobalVersion: 2, Reason: "OrderPlaced", Data: []byte(`{}`)},
+		// not mapped aggregate types are skipped
+		{AggregateType: "Shipment", AggregateID: "s", Version: 1, GlobalVersion: 3, Reason: "Shipped", Data: []byte(`{}`)},
+		{AggregateType: "Shipment", AggregateID: "s", Version: 2, GlobalVersion: 4, Reason: "Delivered", Data: []byte(`{}`)},
+		{AggregateType: "Order", AggregateID: "o", Version: 2, GlobalVersion: 5, Reason: "LineAdded", Data: []byte(`{"LineID":"a","Quantity":2}`)},
+	}
+	source := mapper.GlobalEvents(store)
+
+	events, err := source.GlobalEvents(1, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 || events[0].Data.(*Born).Name != "kalle" {
+		t.Fatalf("expected the person and order events got %+v", events)
+	}
+	if _, ok := events[1].Data.(*OrderPlaced); !ok {
+		t.Fatalf("expected the order placed event got %T", events[1].Data)
+	}
+	// a batch of skipped events does not end the read
+	events, err = source.GlobalEvents(3, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].GlobalVersion != 5 || events[0].Data.(*LineAdded).Quantity != 2 {
+		t.Fatalf("expected the line added event got %+v", events)
+	}
+	events, err = source.GlobalEvents(6, 2)
+	if err != nil || len(events) != 0 {
+		t.Fatalf("expected no more events got %v %v", events, err)
+	}
+}
@@ -0,0 +1,78 @@
+package eventsourcing
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// Metadata keys used by the MetadataCarrier
+const (
+	MetadataCorrelationID = "correlation_id"
+	MetadataCausationID   = "causation_id"
+	MetadataUserID        = "user_id"
+)
+
+// MetadataTenantID is the metadata key of the tenant the event belongs to
+const MetadataTenantID = "tenant_id"
+
+// ErrMetadataKeyMissing when the metadata key is not present on the event
+var ErrMetadataKeyMissing = errors.New("metadata key missing")
+
+// MetadataAs convert the metadata value on key to the supplied type. Numbers that has been
+// round tripped via JSON as float64 can be fetched as int, uint64 etc.
+func MetadataAs[V any, T any](e Event[T], key string) (V, error) {
+	var v V
+	value, ok := e.Metadata[key]
+	if !ok {
+		return v, ErrMetadataKeyMissing
+	}
+	// fast path if the value already has the correct type
+	if typed, ok := value.(V); ok {
+		return typed, nil
+	}
+	b, err := json.Marshal(value)
+	if err != nil {
+		return v, err
+	}
+	err = json.Unmarshal(b, &v)
+	return v, err
+}
+
+// MetadataCarrier holds the well known metadata properties of an event
+type MetadataCarrier struct {
+	CorrelationID string
+	CausationID   string
+	UserID        string
+}
+
+// MetadataCarrierFrom extracts the well known metadata properties from the event
+func MetadataCarrierFrom[T any](e Event[T]) MetadataCarrier {
+	m := MetadataCarrier{}
+	m.CorrelationID, _ = MetadataAs[string](e, MetadataCorrelationID)
+	m.CausationID, _ = MetadataAs[string](e, MetadataCausationID)
+	m.UserID, _ = MetadataAs[string](e, MetadataUserID)
+	return m
+}
+
+// Metadata returns the carrier as a metadata map. Properties with empty values are left out.
+func (m MetadataCarrier) Metadata() map[string]interface{} {
+	return m.Merge(make(map[string]interface{}))
+}
+
+// Merge sets the carrier properties on the metadata map and returns it.
+// A nil map is allocated.
+func (m MetadataCarrier) Merge(metadata map[string]interface{}) map[string]interface{} {
+	if metadata == nil {
+		metadata = make(map[string]interface{})
+	}
+	if m.CorrelationID != "" {
+		metadata[MetadataCorrelationID] = m.CorrelationID
+	}
+	if m.CausationID != "" {
+		metadata[MetadataCausationID] = m.CausationID
+	}
+	if m.UserID != "" {
+		metadata[MetadataUserID] = m.UserID
+	}
+	return metadata
+}
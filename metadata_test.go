@@ -0,0 +1,61 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func TestMetadataAsAfterJSONRoundTrip(t *testing.T) {
+	b, err := json.Marshal(map[string]interface{}{"count": 42, "name": "kalle"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	metadata := map[string]interface{}{}
+	err = json.Unmarshal(b, &metadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e := eventsourcing.Event[PersonEvent]{Data: &Born{}, Metadata: metadata}
+
+	count, err := eventsourcing.MetadataAs[int](e, "count")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 42 {
+		t.Fatalf("expected 42 got %d", count)
+	}
+	name, err := eventsourcing.MetadataAs[string](e, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "kalle" {
+		t.Fatalf("expected kalle got %s", name)
+	}
+	_, err = eventsourcing.MetadataAs[int](e, "name")
+	if err == nil {
+		t.Fatal("expected error when converting string to int")
+	}
+	_, err = eventsourcing.MetadataAs[int](e, "missing")
+	if !errors.Is(err, eventsourcing.ErrMetadataKeyMissing) {
+		t.Fatalf("expected ErrMetadataKeyMissing got %v", err)
+	}
+}
+
+func TestMetadataCarrier(t *testing.T) {
+	c := eventsourcing.MetadataCarrier{CorrelationID: "corr", UserID: "user"}
+	metadata := c.Merge(map[string]interface{}{"foo": "bar"})
+	if _, ok := metadata[eventsourcing.MetadataCausationID]; ok {
+		t.Fatal("empty causation id should not be set")
+	}
+	e := eventsourcing.Event[PersonEvent]{Data: &Born{}, Metadata: metadata}
+	c2 := eventsourcing.MetadataCarrierFrom(e)
+	if c != c2 {
+		t.Fatalf("expected %v got %v", c, c2)
+	}
+	if e.Metadata["foo"] != "bar" {
+		t.Fatal("existing metadata should be kept")
+	}
+}
@@ -0,0 +1,61 @@
+package eventsourcing
+
+// OrderingKey is the position of an event in the global event order, taken from the event GlobalVersion.
+// What the key means differ between event stores, in the sql, bbolt and memory event stores it's a sequence
+// increasing by one per event while in event store db it's the commit position of the write.
+type OrderingKey uint64
+
+// Compare returns -1 if k is before o, 0 if they are equal and 1 if k is after o
+func (k OrderingKey) Compare(o OrderingKey) int {
+	switch {
+	case k < o:
+		return -1
+	case k > o:
+		return 1
+	}
+	return 0
+}
+
+// Before returns true if k is before o in the global order
+func (k OrderingKey) Before(o OrderingKey) bool {
+	return k < o
+}
+
+// After returns true if k is after o in the global order
+func (k OrderingKey) After(o OrderingKey) bool {
+	return k > o
+}
+
+// Follows returns true if k is the key directly after prev. Only meaningful on event stores with a contiguous
+// global order, on other stores a gap between two keys does not mean an event is missing.
+func (k OrderingKey) Follows(prev OrderingKey) bool {
+	return k == prev+1
+}
+
+// OrderingKey returns the position of the event in the global event order
+func (e Event[T]) OrderingKey() OrderingKey {
+	return OrderingKey(e.GlobalVersion)
+}
+
+// Ordering describes the global order guarantees of an event store
+type Ordering struct {
+	// ContiguousGlobalOrder is true when the global version increase by exactly one per saved event,
+	// a projection can then detect missed events from gaps in the global version.
+	ContiguousGlobalOrder bool
+	// GlobalVersionOnGet is true when the events returned from Get has the GlobalVersion set
+	GlobalVersionOnGet bool
+}
+
+// OrderingDescriber is implemented by event stores describing their global order guarantees
+type OrderingDescriber interface {
+	Ordering() Ordering
+}
+
+// OrderingOf returns the global order guarantees of the event store. Event stores not implementing
+// OrderingDescriber are assumed to give no guarantees.
+func OrderingOf(eventStore any) Ordering {
+	if d, ok := eventStore.(OrderingDescriber); ok {
+		return d.Ordering()
+	}
+	return Ordering{}
+}
@@ -0,0 +1,36 @@
+package eventsourcing_test
+
+import (
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestOrderingKey(t *testing.T) {
+	first := eventsourcing.Event[PersonEvent]{GlobalVersion: 1}.OrderingKey()
+	second := eventsourcing.Event[PersonEvent]{GlobalVersion: 2}.OrderingKey()
+
+	if first.Compare(second) != -1 || second.Compare(first) != 1 || first.Compare(first) != 0 {
+		t.Fatal("wrong compare result")
+	}
+	if !first.Before(second) || !second.After(first) {
+		t.Fatal("expected first before second")
+	}
+	if !second.Follows(first) {
+		t.Fatal("expected second to follow first")
+	}
+	if eventsourcing.OrderingKey(3).Follows(first) {
+		t.Fatal("did not expect 3 to follow 1")
+	}
+}
+
+func TestOrderingOf(t *testing.T) {
+	ordering := eventsourcing.OrderingOf(memory.Create[PersonEvent]())
+	if !ordering.ContiguousGlobalOrder || !ordering.GlobalVersionOnGet {
+		t.Fatalf("expected the memory event store to have a contiguous global order %+v", ordering)
+	}
+	if eventsourcing.OrderingOf(struct{}{}) != (eventsourcing.Ordering{}) {
+		t.Fatal("expected no guarantees from a store not describing its ordering")
+	}
+}
@@ -0,0 +1,75 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"hash/fnv"
+)
+
+// ErrInvalidPartition when the partition is not below the partition count
+var ErrInvalidPartition = errors.New("invalid partition")
+
+// ErrNoPartitions when the global stream is split in zero partitions
+var ErrNoPartitions = errors.New("partition count must be above zero")
+
+// partitionScanBatch is the number of global events read at a time when an event store can't read a partition
+const partitionScanBatch = 500
+
+// PartitionedEventStore is implemented by event stores that can read the events of a partition of the global stream
+type PartitionedEventStore[T any] interface {
+	// GlobalEventsPartition returns count events of the partition in global order from the start position
+	GlobalEventsPartition(ctx context.Context, partition, partitionCount uint32, start, count uint64) ([]Event[T], error)
+}
+
+// PartitionOf returns the partition of the aggregate id, all events of an aggregate are in the same partition. The
+// partition count has to be above zero.
+func PartitionOf(aggregateID string, partitionCount uint32) uint32 {
+	h := fnv.New32a()
+	h.Write([]byte(aggregateID))
+	return h.Sum32() % partitionCount
+}
+
+// GlobalEventsPartition returns count events of the partition in global order from the start position. The events are
+// assigned to partitions on the hash of the aggregate id, consumers each reading a partition read disjoint events
+// with the events of an aggregate in order. Event stores not implementing PartitionedEventStore are read in batches
+// of global events, skipping the events of other partitions. It also returns the position to start the next read
+// from, past the global events scanned for the partition, so a partition with few events is not scanned from its last
+// event again.
+func GlobalEventsPartition[T any](ctx context.Context, store GlobalEventStore[T], partition, partitionCount uint32, start, count uint64) ([]Event[T], uint64, error) {
+	if partitionCount == 0 {
+		return nil, start, ErrNoPartitions
+	}
+	if partition >= partitionCount {
+		return nil, start, ErrInvalidPartition
+	}
+	if s, ok := store.(PartitionedEventStore[T]); ok {
+		events, err := s.GlobalEventsPartition(ctx, partition, partitionCount, start, count)
+		if err != nil || len(events) == 0 {
+			return events, start, err
+		}
+		return events, uint64(events[len(events)-1].GlobalVersion) + 1, nil
+	}
+	var events []Event[T]
+	for uint64(len(events)) < count {
+		if ctx.Err() != nil {
+			return nil, start, ctx.Err()
+		}
+		batch, err := store.GlobalEvents(start, partitionScanBatch)
+		if err != nil {
+			return nil, start, err
+		}
+		for _, event := range batch {
+			start = uint64(event.GlobalVersion) + 1
+			if PartitionOf(event.AggregateID, partitionCount) == partition {
+				events = append(events, event)
+				if uint64(len(events)) == count {
+					break
+				}
+			}
+		}
+		if len(batch) < partitionScanBatch {
+			break
+		}
+	}
+	return events, start, nil
+}
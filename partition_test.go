@@ -0,0 +1,87 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// scanStore is a global event store without native partition support
+type scanStore struct {
+	events []eventsourcing.Event[any]
+}
+
+func (g *scanStore) GlobalEvents(start, count uint64) ([]eventsourcing.Event[any], error) {
+	var events []eventsourcing.Event[any]
+	for _, event := range g.events {
+		if uint64(event.GlobalVersion) >= start && uint64(len(events)) < count {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func TestGlobalEventsPartition(t *testing.T) {
+	store := &scanStore{}
+	for i := 1; i <= 1200; i++ {
+		id := string(rune('a' + i%7))
+		store.events = append(store.events, eventsourcing.Event[any]{AggregateID: id, GlobalVersion: eventsourcing.Version(i)})
+	}
+	ctx := context.Background()
+	total := 0
+	for partition := uint32(0); partition < 4; partition++ {
+		events, next, err := eventsourcing.GlobalEventsPartition[any](ctx, store, partition, 4, 1, 10000)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, event := range events {
+			if eventsourcing.PartitionOf(event.AggregateID, 4) != partition {
+				t.Fatalf("event of aggregate %s in partition %d", event.AggregateID, partition)
+			}
+		}
+		if next != 1201 {
+			t.Fatalf("expected the next read from 1201 got %d", next)
+		}
+		total += len(events)
+	}
+	if total != len(store.events) {
+		t.Fatalf("expected the partitions to hold all %d events got %d", len(store.events), total)
+	}
+
+	// the scan stops when count events of the partition are found
+	partition := eventsourcing.PartitionOf("a", 4)
+	events, next, err := eventsourcing.GlobalEventsPartition[any](ctx, store, partition, 4, 1, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 3 {
+		t.Fatalf("expected 3 events got %d", len(events))
+	}
+	if next != uint64(events[2].GlobalVersion)+1 {
+		t.Fatalf("expected the next read after the last event %d got %d", events[2].GlobalVersion, next)
+	}
+
+	// a partition without events is scanned to the end of the global stream
+	empty := &scanStore{}
+	for i := 1; i <= 1200; i++ {
+		empty.events = append(empty.events, eventsourcing.Event[any]{AggregateID: "a", GlobalVersion: eventsourcing.Version(i)})
+	}
+	events, next, err = eventsourcing.GlobalEventsPartition[any](ctx, empty, (partition+1)%4, 4, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 || next != 1201 {
+		t.Fatalf("expected no events and the next read from 1201 got %d events and %d", len(events), next)
+	}
+
+	_, _, err = eventsourcing.GlobalEventsPartition[any](ctx, store, 4, 4, 1, 10)
+	if !errors.Is(err, eventsourcing.ErrInvalidPartition) {
+		t.Fatalf("expected invalid partition got %v", err)
+	}
+	_, _, err = eventsourcing.GlobalEventsPartition[any](ctx, store, 0, 0, 1, 10)
+	if !errors.Is(err, eventsourcing.ErrNoPartitions) {
+		t.Fatalf("expected no partitions got %v", err)
+	}
+}
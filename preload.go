@@ -0,0 +1,142 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+)
+
+// DefaultPreloadParallelism is the number of aggregates Preload loads at the same time when the parallelism is not set
+const DefaultPreloadParallelism = 8
+
+// preloadBatchSize is the number of aggregates read per GetMany call when the events are read in batches
+const preloadBatchSize = 100
+
+// Preload loads the aggregates of type A with the ids via the repository, parallelism of them at the same time. It's
+// for request handlers needing many aggregates at once. Aggregates without events are left out of the returned map,
+// the first other error stops the loading and is returned. When the repository has no snapshot store, cache or alias
+// store and the event store is a BatchEventStore the events are read in batches of aggregates instead.
+//
+//	people, err := eventsourcing.Preload[any, *Person](ctx, repo, 0, ids...)
+func Preload[T any, A Aggregate[T]](ctx context.Context, repo *Repository[T], parallelism int, ids ...string) (map[string]A, error) {
+	var zero A
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return nil, errors.New("aggregate needs to be a pointer")
+	}
+	if parallelism <= 0 {
+		parallelism = DefaultPreloadParallelism
+	}
+	if _, ok := repo.eventStore.(BatchEventStore[T]); ok && repo.snapshot == nil && repo.cache == nil && repo.aliases == nil {
+		return preloadBatched[T, A](ctx, repo, typ.Elem(), ids)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		firstErr error
+	)
+	aggregates := make(map[string]A, len(ids))
+	seen := make(map[string]struct{}, len(ids))
+	slots := make(chan struct{}, parallelism)
+	for _, id := range ids {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(id string) {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			aggregate := reflect.New(typ.Elem()).Interface().(A)
+			err := repo.GetWithContext(ctx, id, aggregate)
+			lock.Lock()
+			defer lock.Unlock()
+			if errors.Is(err, ErrAggregateNotFound) {
+				return
+			} else if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			aggregates[id] = aggregate
+		}(id)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+	return aggregates, nil
+}
+
+// preloadBatched builds the aggregates from the events read in batches with GetMany
+func preloadBatched[T any, A Aggregate[T]](ctx context.Context, repo *Repository[T], typ reflect.Type, ids []string) (map[string]A, error) {
+	aggregates := make(map[string]A, len(ids))
+	for len(ids) > 0 {
+		batch := ids
+		if len(batch) > preloadBatchSize {
+			batch = batch[:preloadBatchSize]
+		}
+		ids = ids[len(batch):]
+		iterators, err := GetMany[T](ctx, repo.eventStore, typ.Name(), batch, nil)
+		if err != nil {
+			return nil, err
+		}
+		for id, iterator := range iterators {
+			aggregate := reflect.New(typ).Interface().(A)
+			err = repo.replayAll(ctx, aggregate, iterator)
+			if err != nil {
+				closeAll(iterators)
+				return nil, err
+			}
+			if aggregate.Root().Version() > 0 {
+				afterLoad[T](aggregate)
+				aggregates[id] = aggregate
+			}
+		}
+	}
+	return aggregates, nil
+}
+
+// replayAll builds the aggregate from the events of the iterator and closes it
+func (r *Repository[T]) replayAll(ctx context.Context, aggregate Aggregate[T], iterator EventIterator[T]) error {
+	defer iterator.Close()
+	for {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		event, err := iterator.Next()
+		if errors.Is(err, ErrNoMoreEvents) {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		err = r.replay(aggregate, event)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func closeAll[T any](iterators map[string]EventIterator[T]) {
+	for _, iterator := range iterators {
+		iterator.Close()
+	}
+}
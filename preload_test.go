@@ -0,0 +1,62 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestPreload(t *testing.T) {
+	eventStore := memory.Create[PersonEvent]()
+	repo := eventsourcing.NewRepository[PersonEvent](eventStore, nil)
+	var ids []string
+	for i := 0; i < 20; i++ {
+		person, err := CreatePerson("kalle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < i; j++ {
+			person.GrowOlder()
+		}
+		err = repo.Save(person)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, person.ID())
+	}
+
+	people, err := eventsourcing.Preload[PersonEvent, *Person](context.Background(), repo, 3, append(ids, "unknown", ids[0])...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 20 {
+		t.Fatalf("expected 20 people got %d", len(people))
+	}
+	for i, id := range ids {
+		if people[id].Age != i {
+			t.Fatalf("expected age %d got %d", i, people[id].Age)
+		}
+	}
+
+	// with a snapshot store the aggregates are loaded one by one via the repository
+	snapshotRepo := eventsourcing.NewRepository[PersonEvent](eventStore, eventsourcing.SnapshotNew(memsnap.New(), *eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)))
+	people, err = eventsourcing.Preload[PersonEvent, *Person](context.Background(), snapshotRepo, 3, ids...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(people) != 20 || people[ids[5]].Age != 5 {
+		t.Fatalf("expected 20 people loaded via the repository got %d", len(people))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = eventsourcing.Preload[PersonEvent, *Person](ctx, repo, 0, ids...)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context canceled got %v", err)
+	}
+}
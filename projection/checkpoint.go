@@ -0,0 +1,30 @@
+package projection
+
+import (
+	"context"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// InboxKey identifies an event handled by a projection
+type InboxKey struct {
+	Projection    string
+	AggregateType string
+	AggregateID   string
+	Version       eventsourcing.Version
+}
+
+// CheckpointStore persists how far projections have come and the events they have processed
+type CheckpointStore interface {
+	// Checkpoint returns the last saved position of the projection, zero if no position is saved
+	Checkpoint(ctx context.Context, projection string) (eventsourcing.Version, error)
+	// SaveCheckpoint saves the position of the projection
+	SaveCheckpoint(ctx context.Context, projection string, position eventsourcing.Version) error
+	// Processed returns true if the event is marked as processed
+	Processed(ctx context.Context, key InboxKey) (bool, error)
+	// MarkProcessed marks the event as processed at the time
+	MarkProcessed(ctx context.Context, key InboxKey, at time.Time) error
+	// Prune removes the processed marks of the projection made before the time and returns the number of removed marks
+	Prune(ctx context.Context, projection string, before time.Time) (int, error)
+}
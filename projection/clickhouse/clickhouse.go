@@ -0,0 +1,189 @@
+// Package clickhouse streams the global event stream into a ClickHouse table for analytical queries over the
+// event history. The sink works on a *sql.DB opened with the clickhouse-go driver.
+package clickhouse
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	defaultTable     = "events"
+	defaultBatchSize = 10000
+)
+
+// Option configures the sink
+type Option func(*options)
+
+type options struct {
+	table     string
+	batchSize uint64
+}
+
+// WithTable sets the table the events are inserted into
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithBatchSize sets the max number of events inserted per poll
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// Sink inserts the events from the global event stream in batches. The position is read from the highest global
+// version in the table, the table is a ReplacingMergeTree sorted on the global version, aggregate and version so a
+// batch inserted twice after a failure is merged to one copy of each event. The aggregate and version are part of the
+// key as event stores like esdb can give events of different streams the same global version.
+type Sink[T any] struct {
+	db       *sql.DB
+	source   eventsourcing.GlobalEventStore[T]
+	options  options
+	lock     sync.Mutex
+	position uint64
+	loaded   bool
+}
+
+// New constructs a ClickHouse sink reading events from source
+func New[T any](db *sql.DB, source eventsourcing.GlobalEventStore[T], opts ...Option) *Sink[T] {
+	o := options{
+		table:     defaultTable,
+		batchSize: defaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Sink[T]{
+		db:      db,
+		source:  source,
+		options: o,
+	}
+}
+
+// Migrate creates the events table if it does not exist. Tables created when the sorting key was only the global
+// version merge events sharing a global version, they have to be recreated as the sorting key can't be changed.
+func (s *Sink[T]) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, fmt.Sprintf(`create table if not exists %s (
+		global_version UInt64,
+		aggregate_type LowCardinality(String),
+		aggregate_id String,
+		version UInt64,
+		reason LowCardinality(String),
+		timestamp DateTime64(6, 'UTC'),
+		data String,
+		metadata String
+	) engine = ReplacingMergeTree
+	partition by toYYYYMM(timestamp)
+	order by (global_version, aggregate_type, aggregate_id, version)`, s.options.table))
+	return err
+}
+
+// Position returns the global version of the last inserted event
+func (s *Sink[T]) Position(ctx context.Context) (uint64, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.loadPosition(ctx)
+}
+
+func (s *Sink[T]) loadPosition(ctx context.Context) (uint64, error) {
+	if s.loaded {
+		return s.position, nil
+	}
+	var position uint64
+	err := s.db.QueryRowContext(ctx, fmt.Sprintf(`select max(global_version) from %s`, s.options.table)).Scan(&position)
+	if err != nil {
+		return 0, err
+	}
+	s.position = position
+	s.loaded = true
+	return position, nil
+}
+
+// Poll inserts the next batch of events and returns the number of events inserted
+func (s *Sink[T]) Poll(ctx context.Context) (int, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	position, err := s.loadPosition(ctx)
+	if err != nil {
+		return 0, err
+	}
+	events, err := s.source.GlobalEvents(position+1, s.options.batchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	err = s.insert(ctx, events)
+	if err != nil {
+		// the batch could be partly inserted, read the position from the table again
+		s.loaded = false
+		return 0, err
+	}
+	s.position = uint64(events[len(events)-1].GlobalVersion)
+	return len(events), nil
+}
+
+// insert sends the events as one batch
+func (s *Sink[T]) insert(ctx context.Context, events []eventsourcing.Event[T]) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmt, err := tx.PrepareContext(ctx, fmt.Sprintf(`insert into %s (global_version, aggregate_type, aggregate_id, version, reason, timestamp, data, metadata)`, s.options.table))
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, event := range events {
+		data, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		metadata, err := json.Marshal(event.Metadata)
+		if err != nil {
+			return err
+		}
+		_, err = stmt.ExecContext(ctx,
+			uint64(event.GlobalVersion),
+			event.AggregateType,
+			event.AggregateID,
+			uint64(event.Version),
+			event.Reason(),
+			event.Timestamp.UTC(),
+			string(data),
+			string(metadata),
+		)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events
+func (s *Sink[T]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := s.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
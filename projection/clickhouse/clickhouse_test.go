@@ -0,0 +1,150 @@
+package clickhouse_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection/clickhouse"
+)
+
+// recorder is a database/sql driver keeping the inserted rows in place of ClickHouse
+type recorder struct {
+	lock    sync.Mutex
+	rows    [][]driver.Value
+	queries []string
+}
+
+func (r *recorder) Open(name string) (driver.Conn, error) { return &conn{r}, nil }
+
+type conn struct{ r *recorder }
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) { return &stmt{c.r, query}, nil }
+func (c *conn) Close() error                              { return nil }
+func (c *conn) Begin() (driver.Tx, error)                 { return tx{}, nil }
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type stmt struct {
+	r     *recorder
+	query string
+}
+
+func (s *stmt) Close() error  { return nil }
+func (s *stmt) NumInput() int { return -1 }
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.r.lock.Lock()
+	defer s.r.lock.Unlock()
+	if strings.HasPrefix(s.query, "insert") {
+		s.r.rows = append(s.r.rows, args)
+	} else {
+		s.r.queries = append(s.r.queries, s.query)
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	s.r.lock.Lock()
+	defer s.r.lock.Unlock()
+	var max int64
+	for _, row := range s.r.rows {
+		if v := row[0].(int64); v > max {
+			max = v
+		}
+	}
+	return &maxRows{value: max}, nil
+}
+
+type maxRows struct {
+	value int64
+	done  bool
+}
+
+func (r *maxRows) Columns() []string { return []string{"max"} }
+func (r *maxRows) Close() error      { return nil }
+func (r *maxRows) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = r.value
+	return nil
+}
+
+type Born struct{ Name string }
+
+func TestSink(t *testing.T) {
+	rec := &recorder{}
+	sql.Register("recorder", rec)
+	db, err := sql.Open("recorder", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	es := memory.Create[any]()
+	for _, id := range []string{"1", "2", "3"} {
+		err = es.Save([]eventsourcing.Event[any]{{AggregateID: id, AggregateType: "Person", Version: 1, Data: &Born{Name: "kalle"}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	sink := clickhouse.New[any](db, es, clickhouse.WithBatchSize(2))
+	err = sink.Migrate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// events of different esdb streams can share a global version
+	if len(rec.queries) != 1 || !strings.Contains(rec.queries[0], "order by (global_version, aggregate_type, aggregate_id, version)") {
+		t.Fatalf("expected the aggregate and version in the sorting key got %v", rec.queries)
+	}
+	n, err := sink.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected a batch of 2 events got %d", n)
+	}
+	n, err = sink.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the last event got %d", n)
+	}
+	if len(rec.rows) != 3 {
+		t.Fatalf("expected 3 inserted rows got %d", len(rec.rows))
+	}
+	row := rec.rows[0]
+	if row[4] != "Born" || row[6] != `{"Name":"kalle"}` {
+		t.Fatalf("unexpected row %v", row)
+	}
+
+	// a new sink continues from the position in the table
+	sink = clickhouse.New[any](db, es)
+	position, err := sink.Position(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 3 {
+		t.Fatalf("expected position 3 got %d", position)
+	}
+	n, err = sink.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Fatalf("expected no events got %d", n)
+	}
+}
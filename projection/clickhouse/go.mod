@@ -0,0 +1,7 @@
+module github.com/hallgren/eventsourcing/projection/clickhouse
+
+go 1.18
+
+require github.com/hallgren/eventsourcing v0.0.20
+
+//replace github.com/hallgren/eventsourcing => ../..
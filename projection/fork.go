@@ -0,0 +1,170 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const forkBatchSize = 100
+
+// ErrPositionsDiffer is returned from Compare when the forked projections have not handled the same events
+var ErrPositionsDiffer = errors.New("forked projections are at different positions")
+
+// ForkedProjection is a projection version run in a Fork, implemented by Materializer
+type ForkedProjection[T, V any] interface {
+	Handle(ctx context.Context, event eventsourcing.Event[T]) error
+	List(ctx context.Context) (map[string]V, error)
+}
+
+// Fork runs two versions of a projection side by side from the same event source, used to validate a rewritten
+// projection before switching the reads to it. Each version has its own position in the checkpoint store, the new
+// version can start from the beginning while the current version continues from its position.
+type Fork[T, V any] struct {
+	source      eventsourcing.GlobalEventStore[T]
+	checkpoints CheckpointStore
+	names       [2]string
+	projections [2]ForkedProjection[T, V]
+	equal       func(a, b V) bool
+}
+
+// ForkDiff holds the keys whose values differ between the forked projections
+type ForkDiff struct {
+	Position eventsourcing.Version
+	// OnlyCurrent holds the keys only present in the current projection
+	OnlyCurrent []string
+	// OnlyNext holds the keys only present in the next projection
+	OnlyNext []string
+	// Changed holds the keys present in both projections with different values
+	Changed []string
+}
+
+// Equal returns true when the projections hold the same values
+func (d ForkDiff) Equal() bool {
+	return len(d.OnlyCurrent) == 0 && len(d.OnlyNext) == 0 && len(d.Changed) == 0
+}
+
+// NewFork constructs a fork of the current and the next version of a projection, the names are the keys of their
+// positions in the checkpoint store
+func NewFork[T, V any](source eventsourcing.GlobalEventStore[T], checkpoints CheckpointStore, currentName string, current ForkedProjection[T, V], nextName string, next ForkedProjection[T, V]) *Fork[T, V] {
+	return &Fork[T, V]{
+		source:      source,
+		checkpoints: checkpoints,
+		names:       [2]string{currentName, nextName},
+		projections: [2]ForkedProjection[T, V]{current, next},
+		equal:       func(a, b V) bool { return reflect.DeepEqual(a, b) },
+	}
+}
+
+// SetEqual sets the function comparing the values of the projections, default is reflect.DeepEqual
+func (f *Fork[T, V]) SetEqual(equal func(a, b V) bool) {
+	f.equal = equal
+}
+
+// Positions returns the positions of the current and the next projection
+func (f *Fork[T, V]) Positions(ctx context.Context) (current, next eventsourcing.Version, err error) {
+	current, err = f.checkpoints.Checkpoint(ctx, f.names[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	next, err = f.checkpoints.Checkpoint(ctx, f.names[1])
+	return current, next, err
+}
+
+// Poll reads the next batch of events from the position of the projection furthest behind and passes each event to
+// the projections that have not handled it. Returns the number of events read.
+func (f *Fork[T, V]) Poll(ctx context.Context) (int, error) {
+	current, next, err := f.Positions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	positions := [2]eventsourcing.Version{current, next}
+	start := current
+	if next < start {
+		start = next
+	}
+	events, err := f.source.GlobalEvents(uint64(start)+1, forkBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, event := range events {
+		for p, projection := range f.projections {
+			if event.GlobalVersion <= positions[p] {
+				continue
+			}
+			err = projection.Handle(ctx, event)
+			if err != nil {
+				return i, fmt.Errorf("projection %s: %w", f.names[p], err)
+			}
+			err = f.checkpoints.SaveCheckpoint(ctx, f.names[p], event.GlobalVersion)
+			if err != nil {
+				return i, err
+			}
+			positions[p] = event.GlobalVersion
+		}
+	}
+	return len(events), nil
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events
+func (f *Fork[T, V]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := f.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Compare diffs the values of the projections. The projections have to be at the same position, Poll until the next
+// projection has caught up and compare before new events are handled.
+func (f *Fork[T, V]) Compare(ctx context.Context) (ForkDiff, error) {
+	current, next, err := f.Positions(ctx)
+	if err != nil {
+		return ForkDiff{}, err
+	}
+	if current != next {
+		return ForkDiff{}, fmt.Errorf("%w: %s at %d %s at %d", ErrPositionsDiffer, f.names[0], current, f.names[1], next)
+	}
+	currentValues, err := f.projections[0].List(ctx)
+	if err != nil {
+		return ForkDiff{}, err
+	}
+	nextValues, err := f.projections[1].List(ctx)
+	if err != nil {
+		return ForkDiff{}, err
+	}
+	diff := ForkDiff{Position: current}
+	for key, value := range currentValues {
+		nextValue, ok := nextValues[key]
+		if !ok {
+			diff.OnlyCurrent = append(diff.OnlyCurrent, key)
+		} else if !f.equal(value, nextValue) {
+			diff.Changed = append(diff.Changed, key)
+		}
+	}
+	for key := range nextValues {
+		if _, ok := currentValues[key]; !ok {
+			diff.OnlyNext = append(diff.OnlyNext, key)
+		}
+	}
+	sort.Strings(diff.OnlyCurrent)
+	sort.Strings(diff.OnlyNext)
+	sort.Strings(diff.Changed)
+	return diff, nil
+}
@@ -0,0 +1,97 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection"
+	checkpoints "github.com/hallgren/eventsourcing/projection/memory"
+)
+
+func TestFork(t *testing.T) {
+	es := memory.Create[any]()
+	es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Data: &Renamed{Name: "kalle"}},
+	})
+	es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "2", AggregateType: "Person", Version: 1, Data: &Renamed{Name: "anka"}},
+	})
+	cp := checkpoints.New()
+	ctx := context.Background()
+
+	current := projection.NewMaterializer[any, person](projection.NewMapStore[person](), applyPerson)
+	// the current projection is in production and has handled the events
+	err := current.Rebuild(ctx, es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cp.SaveCheckpoint(ctx, "people", 3)
+
+	// the rewrite upper cases the names
+	next := projection.NewMaterializer[any, person](projection.NewMapStore[person](), func(p *person, event eventsourcing.Event[any]) bool {
+		if e, ok := event.Data.(*Renamed); ok {
+			p.Name = strings.ToUpper(e.Name)
+			return true
+		}
+		return applyPerson(p, event)
+	})
+	fork := projection.NewFork[any, person](es, cp, "people", current, "people-v2", next)
+
+	_, err = fork.Compare(ctx)
+	if !errors.Is(err, projection.ErrPositionsDiffer) {
+		t.Fatalf("expected positions differ got %v", err)
+	}
+	n, err := fork.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected the next projection to catch up on three events got %d", n)
+	}
+	currentPosition, nextPosition, err := fork.Positions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if currentPosition != 3 || nextPosition != 3 {
+		t.Fatalf("expected both projections at position 3 got %d %d", currentPosition, nextPosition)
+	}
+
+	diff, err := fork.Compare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff.Equal() || len(diff.Changed) != 2 {
+		t.Fatalf("expected the two renamed persons to differ got %+v", diff)
+	}
+
+	// compare on the fields the rewrite is expected to keep
+	fork.SetEqual(func(a, b person) bool { return strings.EqualFold(a.Name, b.Name) && a.Age == b.Age })
+	diff, err = fork.Compare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Equal() {
+		t.Fatalf("expected no diff got %+v", diff)
+	}
+
+	// new events are handled by both projections
+	es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "2", AggregateType: "Person", Version: 2, Data: &Removed{}},
+	})
+	n, err = fork.Poll(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one event got %d %v", n, err)
+	}
+	diff, err = fork.Compare(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !diff.Equal() || diff.Position != 4 {
+		t.Fatalf("expected no diff at position 4 got %+v", diff)
+	}
+}
@@ -0,0 +1,230 @@
+package projection
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	groupBatchSize  = 100
+	defaultLeaseTTL = 30 * time.Second
+)
+
+// LeaseStore grants time limited exclusive leases on keys to owners
+type LeaseStore interface {
+	// Acquire takes the lease on the key for the owner, or renews it if the owner holds it, until the ttl has passed.
+	// It returns false if another owner holds an unexpired lease on the key.
+	Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error)
+	// Release gives up the lease on the key if the owner holds it
+	Release(ctx context.Context, key, owner string) error
+	// Holders returns the owners of the unexpired leases on the keys starting with the prefix
+	Holders(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+// Group coordinates the instances, the members, of a projection reading the global stream in partitions. Each member
+// holds a lease on its membership and the partitions are spread over the members alive. A member only handles the
+// partitions it holds the lease on, the lease is renewed before each batch is handled and before its checkpoint, so two
+// members never process the same partition at the same time as long as a batch is handled within the lease ttl. A
+// crashed member's leases expire and its partitions are taken over by the other members. Events are handled at least
+// once, a member losing its lease during a batch leaves the events after the checkpoint to be handled again by the
+// next owner.
+type Group[T any] struct {
+	name        string
+	member      string
+	partitions  uint32
+	source      eventsourcing.GlobalEventStore[T]
+	checkpoints CheckpointStore
+	leases      LeaseStore
+	handler     func(ctx context.Context, event eventsourcing.Event[T]) error
+	ttl         time.Duration
+
+	lock  sync.Mutex
+	owned []uint32
+}
+
+// NewGroup constructs the member of the group handling the events of its partitions. The checkpoint of each partition
+// is saved under the name of the group and the partition. Returns eventsourcing.ErrNoPartitions if partitions is zero.
+func NewGroup[T any](name, member string, partitions uint32, source eventsourcing.GlobalEventStore[T], checkpoints CheckpointStore, leases LeaseStore, handler func(ctx context.Context, event eventsourcing.Event[T]) error) (*Group[T], error) {
+	if partitions == 0 {
+		return nil, eventsourcing.ErrNoPartitions
+	}
+	return &Group[T]{
+		name:        name,
+		member:      member,
+		partitions:  partitions,
+		source:      source,
+		checkpoints: checkpoints,
+		leases:      leases,
+		handler:     handler,
+		ttl:         defaultLeaseTTL,
+	}, nil
+}
+
+// SetLeaseTTL sets how long the leases are held without being renewed, default 30 seconds. It has to be longer than
+// handling a batch of events and the time between polls.
+func (g *Group[T]) SetLeaseTTL(ttl time.Duration) {
+	g.ttl = ttl
+}
+
+// Partitions returns the partitions held by the member since the last rebalance
+func (g *Group[T]) Partitions() []uint32 {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return append([]uint32{}, g.owned...)
+}
+
+// Rebalance renews the membership and takes the leases of the partitions assigned to the member. Partitions assigned
+// to other members are released, partitions still held by a previous owner are taken once their lease is released or
+// expired. It returns the partitions the member holds.
+func (g *Group[T]) Rebalance(ctx context.Context) ([]uint32, error) {
+	_, err := g.leases.Acquire(ctx, g.memberKey(g.member), g.member, g.ttl)
+	if err != nil {
+		return nil, err
+	}
+	holders, err := g.leases.Holders(ctx, g.memberKey(""))
+	if err != nil {
+		return nil, err
+	}
+	members := make([]string, 0, len(holders)+1)
+	for _, member := range holders {
+		members = append(members, member)
+	}
+	sort.Strings(members)
+	index := sort.SearchStrings(members, g.member)
+	if index == len(members) || members[index] != g.member {
+		// the membership lease already expired, like with a ttl shorter than the rebalance, the member is alive
+		members = append(members[:index], append([]string{g.member}, members[index:]...)...)
+	}
+
+	var owned []uint32
+	for partition := uint32(0); partition < g.partitions; partition++ {
+		key := g.partitionKey(partition)
+		if int(partition)%len(members) != index {
+			err = g.leases.Release(ctx, key, g.member)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+		ok, err := g.leases.Acquire(ctx, key, g.member, g.ttl)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			owned = append(owned, partition)
+		}
+	}
+	g.lock.Lock()
+	g.owned = owned
+	g.lock.Unlock()
+	return owned, nil
+}
+
+// Poll rebalances and handles the next batch of events of each partition held by the member, returns the number of
+// events handled
+func (g *Group[T]) Poll(ctx context.Context) (int, error) {
+	owned, err := g.Rebalance(ctx)
+	if err != nil {
+		return 0, err
+	}
+	handled := 0
+	for _, partition := range owned {
+		n, err := g.poll(ctx, partition)
+		handled += n
+		if err != nil {
+			return handled, err
+		}
+	}
+	return handled, nil
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events. The leases are
+// released when it returns, handing the partitions over to the other members without waiting for them to expire.
+func (g *Group[T]) Run(ctx context.Context, interval time.Duration) error {
+	defer g.release()
+	for {
+		n, err := g.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// poll handles the next batch of events of the partition and checkpoints them while the lease is held. The lease is
+// renewed before the events are handled, it can have expired since the rebalance while other partitions were polled.
+// The checkpoint is the last global version scanned for the partition, also when it holds no new events, to not scan
+// the same part of the global stream on the next poll.
+func (g *Group[T]) poll(ctx context.Context, partition uint32) (int, error) {
+	ok, err := g.leases.Acquire(ctx, g.partitionKey(partition), g.member, g.ttl)
+	if err != nil || !ok {
+		// the partition was taken over, its events are handled by the new owner
+		return 0, err
+	}
+	name := g.checkpointName(partition)
+	position, err := g.checkpoints.Checkpoint(ctx, name)
+	if err != nil {
+		return 0, err
+	}
+	events, next, err := eventsourcing.GlobalEventsPartition(ctx, g.source, partition, g.partitions, uint64(position)+1, groupBatchSize)
+	if err != nil {
+		return 0, err
+	}
+	scanned := eventsourcing.Version(next - 1)
+	if scanned <= position {
+		return 0, nil
+	}
+	for _, event := range events {
+		err = g.handler(ctx, event)
+		if err != nil {
+			return 0, fmt.Errorf("group %s partition %d: %w", g.name, partition, err)
+		}
+	}
+	ok, err = g.leases.Acquire(ctx, g.partitionKey(partition), g.member, g.ttl)
+	if err != nil {
+		return 0, err
+	} else if !ok {
+		// the partition was taken over, leave the checkpoint to the new owner
+		return 0, nil
+	}
+	return len(events), g.checkpoints.SaveCheckpoint(ctx, name, scanned)
+}
+
+// release gives up the leases of the member, it uses a new context as the run context is done
+func (g *Group[T]) release() {
+	ctx := context.Background()
+	for _, partition := range g.Partitions() {
+		g.leases.Release(ctx, g.partitionKey(partition), g.member)
+	}
+	g.leases.Release(ctx, g.memberKey(g.member), g.member)
+	g.lock.Lock()
+	g.owned = nil
+	g.lock.Unlock()
+}
+
+func (g *Group[T]) memberKey(member string) string {
+	return g.name + "/member/" + member
+}
+
+func (g *Group[T]) partitionKey(partition uint32) string {
+	return g.name + "/partition/" + strconv.FormatUint(uint64(partition), 10)
+}
+
+func (g *Group[T]) checkpointName(partition uint32) string {
+	return g.name + "-" + strconv.FormatUint(uint64(partition), 10)
+}
@@ -0,0 +1,263 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	eventstore "github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection"
+	"github.com/hallgren/eventsourcing/projection/memory"
+)
+
+// handled records the events handled by the members of a group
+type handled struct {
+	lock   sync.Mutex
+	events map[string][]eventsourcing.Version
+}
+
+func (h *handled) handler(member string) func(ctx context.Context, event eventsourcing.Event[any]) error {
+	return func(ctx context.Context, event eventsourcing.Event[any]) error {
+		h.lock.Lock()
+		defer h.lock.Unlock()
+		h.events[member] = append(h.events[member], event.GlobalVersion)
+		return nil
+	}
+}
+
+func (h *handled) count() int {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	n := 0
+	for _, events := range h.events {
+		n += len(events)
+	}
+	return n
+}
+
+func TestGroup(t *testing.T) {
+	ctx := context.Background()
+	es := eventstore.Create[any]()
+	for i := 0; i < 20; i++ {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: fmt.Sprint(i), AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Born{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := memory.New()
+	h := &handled{events: make(map[string][]eventsourcing.Version)}
+	a, err := projection.NewGroup[any]("people", "a", 4, es, store, store, h.handler("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := projection.NewGroup[any]("people", "b", 4, es, store, store, h.handler("b"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a joins first and holds all partitions
+	partitions, err := a.Rebalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 4 {
+		t.Fatalf("expected the single member to hold all partitions got %v", partitions)
+	}
+
+	// b joins, a hands over half of the partitions on its next rebalance
+	partitions, err = b.Rebalance(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) != 0 {
+		t.Fatalf("expected the partitions to still be held by a got %v", partitions)
+	}
+	_, err = a.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = b.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(a.Partitions()) != 2 || len(b.Partitions()) != 2 {
+		t.Fatalf("expected the partitions to be spread got %v and %v", a.Partitions(), b.Partitions())
+	}
+	for _, partition := range a.Partitions() {
+		for _, other := range b.Partitions() {
+			if partition == other {
+				t.Fatalf("expected disjoint partitions got %v and %v", a.Partitions(), b.Partitions())
+			}
+		}
+	}
+	if h.count() != 20 {
+		t.Fatalf("expected all 20 events to be handled once got %d", h.count())
+	}
+
+	// b stops, a takes over its partitions from their checkpoints
+	runCtx, cancel := context.WithCancel(ctx)
+	cancel()
+	b.Run(runCtx, time.Millisecond)
+	err = es.Save([]eventsourcing.Event[any]{{AggregateID: "0", AggregateType: "Person", Version: 2, Timestamp: time.Now(), Data: &Renamed{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := a.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 || len(a.Partitions()) != 4 {
+		t.Fatalf("expected a to take over all partitions and handle the new event got %d events in %v", n, a.Partitions())
+	}
+}
+
+func TestGroupLeaseExpiry(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	ok, err := store.Acquire(ctx, "lease", "a", 10*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the lease got %v %v", ok, err)
+	}
+	ok, err = store.Acquire(ctx, "lease", "b", time.Second)
+	if err != nil || ok {
+		t.Fatalf("expected the lease to be held by a got %v %v", ok, err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	ok, err = store.Acquire(ctx, "lease", "b", time.Second)
+	if err != nil || !ok {
+		t.Fatalf("expected to take over the expired lease got %v %v", ok, err)
+	}
+	holders, err := store.Holders(ctx, "lea")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holders["lease"] != "b" {
+		t.Fatalf("expected b to hold the lease got %v", holders)
+	}
+}
+
+func TestGroupZeroTTL(t *testing.T) {
+	store := memory.New()
+	g, err := projection.NewGroup[any]("people", "a", 4, eventstore.Create[any](), store, store, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.SetLeaseTTL(0)
+	_, err = g.Rebalance(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// takenLeases denies the partition leases once taken is set, as if another member took over the partitions
+type takenLeases struct {
+	projection.LeaseStore
+	lock  sync.Mutex
+	taken bool
+}
+
+func (l *takenLeases) take() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.taken = true
+}
+
+func (l *takenLeases) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	l.lock.Lock()
+	taken := l.taken
+	l.lock.Unlock()
+	if taken && key != "people/member/"+owner {
+		return false, nil
+	}
+	return l.LeaseStore.Acquire(ctx, key, owner, ttl)
+}
+
+func TestGroupLeaseCheckedBeforeHandling(t *testing.T) {
+	ctx := context.Background()
+	es := eventstore.Create[any]()
+	for i := 0; i < 20; i++ {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: fmt.Sprint(i), AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Born{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := memory.New()
+	leases := &takenLeases{LeaseStore: store}
+	var partitions []uint32
+	g, err := projection.NewGroup[any]("people", "a", 2, es, store, leases, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		partitions = append(partitions, eventsourcing.PartitionOf(event.AggregateID, 2))
+		// the partitions are taken over while the first batch is handled
+		leases.take()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = g.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(partitions) == 0 {
+		t.Fatal("expected the first partition to be handled")
+	}
+	for _, partition := range partitions {
+		if partition != partitions[0] {
+			t.Fatalf("expected only the partition handled before the take over got %v", partitions)
+		}
+	}
+}
+
+func TestGroupWithoutPartitions(t *testing.T) {
+	store := memory.New()
+	_, err := projection.NewGroup[any]("people", "a", 0, eventstore.Create[any](), store, store, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		return nil
+	})
+	if !errors.Is(err, eventsourcing.ErrNoPartitions) {
+		t.Fatalf("expected ErrNoPartitions got %v", err)
+	}
+}
+
+// globalStore hides the native partition reads of the memory event store
+type globalStore struct {
+	eventsourcing.GlobalEventStore[any]
+}
+
+func TestGroupCheckpointsEmptyPartition(t *testing.T) {
+	ctx := context.Background()
+	es := eventstore.Create[any]()
+	for i := 1; i <= 3; i++ {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: eventsourcing.Version(i), Timestamp: time.Now(), Data: &Born{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	store := memory.New()
+	h := &handled{events: make(map[string][]eventsourcing.Version)}
+	g, err := projection.NewGroup[any]("people", "a", 2, globalStore{es}, store, store, h.handler("a"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, err := g.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 events handled got %d", n)
+	}
+	// the partition without events is checkpointed at the end of the scan
+	for partition := 0; partition < 2; partition++ {
+		position, err := store.Checkpoint(ctx, fmt.Sprintf("people-%d", partition))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if position != 3 {
+			t.Fatalf("expected partition %d checkpointed at 3 got %d", partition, position)
+		}
+	}
+}
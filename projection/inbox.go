@@ -0,0 +1,59 @@
+package projection
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Inbox skips events already handled by a projection, used when events are consumed from a transport that
+// delivers at least once. An event is marked as processed after the handler returns without error, an event is
+// handled again if the process stops in between. The sql projection package has an inbox marking the event in the
+// transaction of the handler.
+type Inbox[T any] struct {
+	projection string
+	store      CheckpointStore
+	handler    func(ctx context.Context, event eventsourcing.Event[T]) error
+	lock       sync.Mutex
+}
+
+// NewInbox constructs an inbox in front of the projection handler
+func NewInbox[T any](projection string, store CheckpointStore, handler func(ctx context.Context, event eventsourcing.Event[T]) error) *Inbox[T] {
+	return &Inbox[T]{
+		projection: projection,
+		store:      store,
+		handler:    handler,
+	}
+}
+
+// Handle calls the handler if the event is not already processed. Returns true if the handler was called.
+func (i *Inbox[T]) Handle(ctx context.Context, event eventsourcing.Event[T]) (bool, error) {
+	key := InboxKey{
+		Projection:    i.projection,
+		AggregateType: event.AggregateType,
+		AggregateID:   event.AggregateID,
+		Version:       event.Version,
+	}
+	// the check and mark is not atomic in the store, serialize the events to not handle a duplicate delivered in parallel
+	i.lock.Lock()
+	defer i.lock.Unlock()
+	processed, err := i.store.Processed(ctx, key)
+	if err != nil {
+		return false, err
+	}
+	if processed {
+		return false, nil
+	}
+	err = i.handler(ctx, event)
+	if err != nil {
+		return true, err
+	}
+	return true, i.store.MarkProcessed(ctx, key, time.Now())
+}
+
+// Prune removes the processed marks older than maxAge. Duplicates delivered after their mark is pruned are handled again.
+func (i *Inbox[T]) Prune(ctx context.Context, maxAge time.Duration) (int, error) {
+	return i.store.Prune(ctx, i.projection, time.Now().Add(-maxAge))
+}
@@ -0,0 +1,119 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/projection"
+	"github.com/hallgren/eventsourcing/projection/memory"
+)
+
+type Born struct{}
+
+func TestInboxSkipsDuplicates(t *testing.T) {
+	store := memory.New()
+	handled := 0
+	inbox := projection.NewInbox("people", store, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		handled++
+		return nil
+	})
+	event := eventsourcing.Event[any]{AggregateID: "123", AggregateType: "Person", Version: 1, Data: &Born{}}
+
+	for i := 0; i < 3; i++ {
+		_, err := inbox.Handle(context.Background(), event)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if handled != 1 {
+		t.Fatalf("expected the event to be handled once got %d", handled)
+	}
+
+	// the same event is handled by another projection
+	other := projection.NewInbox("other", store, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		handled++
+		return nil
+	})
+	called, err := other.Handle(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called || handled != 2 {
+		t.Fatal("expected the other projection to handle the event")
+	}
+}
+
+func TestInboxHandlerError(t *testing.T) {
+	fail := true
+	handled := 0
+	inbox := projection.NewInbox("people", memory.New(), func(ctx context.Context, event eventsourcing.Event[any]) error {
+		handled++
+		if fail {
+			return errors.New("handler error")
+		}
+		return nil
+	})
+	event := eventsourcing.Event[any]{AggregateID: "123", AggregateType: "Person", Version: 1}
+
+	_, err := inbox.Handle(context.Background(), event)
+	if err == nil {
+		t.Fatal("expected handler error")
+	}
+	// the event is not marked as processed when the handler fails
+	fail = false
+	called, err := inbox.Handle(context.Background(), event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !called || handled != 2 {
+		t.Fatalf("expected the event to be handled again got %d", handled)
+	}
+}
+
+func TestInboxPrune(t *testing.T) {
+	store := memory.New()
+	inbox := projection.NewInbox("people", store, func(ctx context.Context, event eventsourcing.Event[any]) error { return nil })
+	ctx := context.Background()
+
+	key := projection.InboxKey{Projection: "people", AggregateType: "Person", AggregateID: "1", Version: 1}
+	store.MarkProcessed(ctx, key, time.Now().Add(-2*time.Hour))
+	_, err := inbox.Handle(ctx, eventsourcing.Event[any]{AggregateID: "2", AggregateType: "Person", Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pruned, err := inbox.Prune(ctx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pruned != 1 {
+		t.Fatalf("expected one pruned mark got %d", pruned)
+	}
+	processed, _ := store.Processed(ctx, key)
+	if processed {
+		t.Fatal("expected the old mark to be pruned")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	store := memory.New()
+	ctx := context.Background()
+	position, err := store.Checkpoint(ctx, "people")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 0 {
+		t.Fatalf("expected no checkpoint got %d", position)
+	}
+	err = store.SaveCheckpoint(ctx, "people", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	position, _ = store.Checkpoint(ctx, "people")
+	if position != 10 {
+		t.Fatalf("expected checkpoint 10 got %d", position)
+	}
+}
@@ -0,0 +1,166 @@
+package projection
+
+import (
+	"context"
+	"sync"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const rebuildBatchSize = 100
+
+// KVStore stores the values of a key value read model
+type KVStore[V any] interface {
+	Get(ctx context.Context, key string) (V, bool, error)
+	Put(ctx context.Context, key string, value V) error
+	Delete(ctx context.Context, key string) error
+	List(ctx context.Context) (map[string]V, error)
+	// Clear removes all values, used before the read model is rebuilt
+	Clear(ctx context.Context) error
+}
+
+// MapStore is a KVStore keeping the values in a map
+type MapStore[V any] struct {
+	lock   sync.RWMutex
+	values map[string]V
+}
+
+// NewMapStore constructs a map store
+func NewMapStore[V any]() *MapStore[V] {
+	return &MapStore[V]{values: make(map[string]V)}
+}
+
+// Get returns the value of the key
+func (m *MapStore[V]) Get(ctx context.Context, key string) (V, bool, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	v, ok := m.values[key]
+	return v, ok, nil
+}
+
+// Put sets the value of the key
+func (m *MapStore[V]) Put(ctx context.Context, key string, value V) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values[key] = value
+	return nil
+}
+
+// Delete removes the key
+func (m *MapStore[V]) Delete(ctx context.Context, key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.values, key)
+	return nil
+}
+
+// List returns a copy of all values
+func (m *MapStore[V]) List(ctx context.Context) (map[string]V, error) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	values := make(map[string]V, len(m.values))
+	for k, v := range m.values {
+		values[k] = v
+	}
+	return values, nil
+}
+
+// Clear removes all values
+func (m *MapStore[V]) Clear(ctx context.Context) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.values = make(map[string]V)
+	return nil
+}
+
+// Materializer maintains a key value read model from events. Each event updates the value of its key, the
+// aggregate id by default. It implements eventsourcing.AfterSaver to be updated by the repository on save.
+type Materializer[T, V any] struct {
+	store KVStore[V]
+	apply func(value *V, event eventsourcing.Event[T]) bool
+	key   func(event eventsourcing.Event[T]) string
+	lock  sync.Mutex
+}
+
+// NewMaterializer constructs a materializer. apply updates the value from the event and returns false
+// if the key should be removed from the read model.
+func NewMaterializer[T, V any](store KVStore[V], apply func(value *V, event eventsourcing.Event[T]) bool) *Materializer[T, V] {
+	return &Materializer[T, V]{
+		store: store,
+		apply: apply,
+		key:   func(event eventsourcing.Event[T]) string { return event.AggregateID },
+	}
+}
+
+// SetKey sets the function returning the key of the event, an empty key skips the event
+func (m *Materializer[T, V]) SetKey(key func(event eventsourcing.Event[T]) string) {
+	m.key = key
+}
+
+// Handle updates the read model from the event
+func (m *Materializer[T, V]) Handle(ctx context.Context, event eventsourcing.Event[T]) error {
+	key := m.key(event)
+	if key == "" {
+		return nil
+	}
+	// serialize the read-modify-write of the values
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	value, _, err := m.store.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !m.apply(&value, event) {
+		return m.store.Delete(ctx, key)
+	}
+	return m.store.Put(ctx, key, value)
+}
+
+// AfterSave updates the read model from the saved events
+func (m *Materializer[T, V]) AfterSave(events []eventsourcing.Event[T]) error {
+	for _, event := range events {
+		err := m.Handle(context.Background(), event)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the value of the key
+func (m *Materializer[T, V]) Get(ctx context.Context, key string) (V, bool, error) {
+	return m.store.Get(ctx, key)
+}
+
+// List returns all values in the read model
+func (m *Materializer[T, V]) List(ctx context.Context) (map[string]V, error) {
+	return m.store.List(ctx)
+}
+
+// Rebuild clears the read model and builds it from all events in the event store
+func (m *Materializer[T, V]) Rebuild(ctx context.Context, eventStore eventsourcing.GlobalEventStore[T]) error {
+	err := m.store.Clear(ctx)
+	if err != nil {
+		return err
+	}
+	start := uint64(0)
+	for {
+		events, err := eventStore.GlobalEvents(start, rebuildBatchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, event := range events {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			err = m.Handle(ctx, event)
+			if err != nil {
+				return err
+			}
+			start = uint64(event.GlobalVersion) + 1
+		}
+	}
+}
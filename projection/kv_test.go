@@ -0,0 +1,84 @@
+package projection_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection"
+)
+
+type Renamed struct{ Name string }
+type Removed struct{}
+
+type person struct {
+	Name string
+	Age  int
+}
+
+func applyPerson(p *person, event eventsourcing.Event[any]) bool {
+	switch e := event.Data.(type) {
+	case *Renamed:
+		p.Name = e.Name
+	case *Born:
+		p.Age = 0
+	case *Removed:
+		return false
+	}
+	return true
+}
+
+func TestMaterializer(t *testing.T) {
+	m := projection.NewMaterializer[any, person](projection.NewMapStore[person](), applyPerson)
+	err := m.AfterSave([]eventsourcing.Event[any]{
+		{AggregateID: "1", Data: &Born{}},
+		{AggregateID: "1", Data: &Renamed{Name: "kalle"}},
+		{AggregateID: "2", Data: &Renamed{Name: "anka"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, ok, err := m.Get(context.Background(), "1")
+	if err != nil || !ok {
+		t.Fatalf("expected person 1 %v", err)
+	}
+	if p.Name != "kalle" {
+		t.Fatalf("expected name kalle got %q", p.Name)
+	}
+
+	err = m.Handle(context.Background(), eventsourcing.Event[any]{AggregateID: "2", Data: &Removed{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, err := m.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("expected one person got %d", len(all))
+	}
+}
+
+func TestMaterializerRebuild(t *testing.T) {
+	es := memory.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Data: &Renamed{Name: "kalle"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := projection.NewMapStore[person]()
+	store.Put(context.Background(), "stale", person{Name: "stale"})
+
+	m := projection.NewMaterializer[any, person](store, applyPerson)
+	err = m.Rebuild(context.Background(), es)
+	if err != nil {
+		t.Fatal(err)
+	}
+	all, _ := m.List(context.Background())
+	if len(all) != 1 || all["1"].Name != "kalle" {
+		t.Fatalf("wrong read model after rebuild %v", all)
+	}
+}
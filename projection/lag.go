@@ -0,0 +1,43 @@
+package projection
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrLagging when a projection is more events behind the global stream than allowed
+var ErrLagging = errors.New("projection is lagging")
+
+// LagCheck checks that a projection keeps up with the global stream, it implements eventsourcing.Healther
+type LagCheck[T any] struct {
+	name        string
+	source      eventsourcing.GlobalEventStore[T]
+	checkpoints CheckpointStore
+	maxLag      uint64
+}
+
+// NewLagCheck constructs a check failing when the projection with the name has more than maxLag events after its
+// checkpoint
+func NewLagCheck[T any](name string, source eventsourcing.GlobalEventStore[T], checkpoints CheckpointStore, maxLag uint64) *LagCheck[T] {
+	return &LagCheck[T]{name: name, source: source, checkpoints: checkpoints, maxLag: maxLag}
+}
+
+// CheckHealth returns ErrLagging if the projection is more than the max lag behind. Only up to max lag plus one
+// events after the checkpoint are read.
+func (l *LagCheck[T]) CheckHealth(ctx context.Context) error {
+	position, err := l.checkpoints.Checkpoint(ctx, l.name)
+	if err != nil {
+		return err
+	}
+	events, err := l.source.GlobalEvents(uint64(position)+1, l.maxLag+1)
+	if err != nil {
+		return err
+	}
+	if uint64(len(events)) > l.maxLag {
+		return fmt.Errorf("%w: %s at %d is more than %d events behind", ErrLagging, l.name, position, l.maxLag)
+	}
+	return nil
+}
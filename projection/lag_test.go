@@ -0,0 +1,38 @@
+package projection_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	eventstore "github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection"
+	"github.com/hallgren/eventsourcing/projection/memory"
+)
+
+func TestLagCheck(t *testing.T) {
+	ctx := context.Background()
+	es := eventstore.Create[any]()
+	for i := 1; i <= 3; i++ {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: eventsourcing.Version(i), Timestamp: time.Now(), Data: &Born{}}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkpoints := memory.New()
+	check := projection.NewLagCheck[any]("people", es, checkpoints, 2)
+	err := check.CheckHealth(ctx)
+	if !errors.Is(err, projection.ErrLagging) {
+		t.Fatalf("expected the projection three events behind to lag got %v", err)
+	}
+	err = checkpoints.SaveCheckpoint(ctx, "people", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = check.CheckHealth(ctx)
+	if err != nil {
+		t.Fatalf("expected the projection two events behind to be healthy got %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/projection"
+)
+
+// Memory is a checkpoint store keeping the checkpoints and processed events in memory, it's also a lease store
+type Memory struct {
+	lock        sync.Mutex
+	checkpoints map[string]eventsourcing.Version
+	processed   map[projection.InboxKey]time.Time
+	states      map[string][]byte
+	leases      map[string]lease
+}
+
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+// New constructs a memory checkpoint store
+func New() *Memory {
+	return &Memory{
+		checkpoints: make(map[string]eventsourcing.Version),
+		processed:   make(map[projection.InboxKey]time.Time),
+		states:      make(map[string][]byte),
+		leases:      make(map[string]lease),
+	}
+}
+
+// Checkpoint returns the last saved position of the projection
+func (m *Memory) Checkpoint(ctx context.Context, name string) (eventsourcing.Version, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.checkpoints[name], nil
+}
+
+// SaveCheckpoint saves the position of the projection
+func (m *Memory) SaveCheckpoint(ctx context.Context, name string, position eventsourcing.Version) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.checkpoints[name] = position
+	return nil
+}
+
+// CheckpointState returns the state and position of the projection
+func (m *Memory) CheckpointState(ctx context.Context, name string) ([]byte, eventsourcing.Version, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.states[name], m.checkpoints[name], nil
+}
+
+// SaveCheckpointState saves the state and position of the projection
+func (m *Memory) SaveCheckpointState(ctx context.Context, name string, state []byte, position eventsourcing.Version) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.states[name] = append([]byte{}, state...)
+	m.checkpoints[name] = position
+	return nil
+}
+
+// Processed returns true if the event is marked as processed
+func (m *Memory) Processed(ctx context.Context, key projection.InboxKey) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	_, ok := m.processed[key]
+	return ok, nil
+}
+
+// MarkProcessed marks the event as processed
+func (m *Memory) MarkProcessed(ctx context.Context, key projection.InboxKey, at time.Time) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.processed[key] = at
+	return nil
+}
+
+// Prune removes the processed marks of the projection made before the time
+func (m *Memory) Prune(ctx context.Context, name string, before time.Time) (int, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	pruned := 0
+	for key, at := range m.processed {
+		if key.Projection == name && at.Before(before) {
+			delete(m.processed, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+// Acquire takes or renews the lease on the key for the owner, false if another owner holds it
+func (m *Memory) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	if l, ok := m.leases[key]; ok && l.owner != owner && now.Before(l.expires) {
+		return false, nil
+	}
+	m.leases[key] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+// Release gives up the lease on the key if the owner holds it
+func (m *Memory) Release(ctx context.Context, key, owner string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if l, ok := m.leases[key]; ok && l.owner == owner {
+		delete(m.leases, key)
+	}
+	return nil
+}
+
+// Holders returns the owners of the unexpired leases on the keys starting with the prefix
+func (m *Memory) Holders(ctx context.Context, prefix string) (map[string]string, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	now := time.Now()
+	holders := make(map[string]string)
+	for key, l := range m.leases {
+		if strings.HasPrefix(key, prefix) && now.Before(l.expires) {
+			holders[key] = l.owner
+		}
+	}
+	return holders, nil
+}
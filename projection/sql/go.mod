@@ -0,0 +1,10 @@
+module github.com/hallgren/eventsourcing/projection/sql
+
+go 1.18
+
+require (
+	github.com/hallgren/eventsourcing v0.0.20
+	github.com/mattn/go-sqlite3 v1.14.16
+)
+
+//replace github.com/hallgren/eventsourcing => ../..
@@ -0,0 +1,76 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// Inbox skips events already handled by a projection writing its read model to the same database. The processed
+// mark is inserted in the transaction passed to the handler, the mark and the handler changes are committed or
+// rolled back together giving exactly once handling of events delivered at least once.
+type Inbox[T any] struct {
+	db         *sql.DB
+	projection string
+	table      string
+	handler    func(ctx context.Context, tx *sql.Tx, event eventsourcing.Event[T]) error
+}
+
+// NewInbox constructs an inbox in front of the projection handler, the table is set with WithInboxTable
+func NewInbox[T any](db *sql.DB, projection string, handler func(ctx context.Context, tx *sql.Tx, event eventsourcing.Event[T]) error, opts ...Option) *Inbox[T] {
+	o := options{inboxTable: defaultInboxTable}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Inbox[T]{
+		db:         db,
+		projection: projection,
+		table:      o.inboxTable,
+		handler:    handler,
+	}
+}
+
+// Migrate creates the inbox table if it doesn't exist
+func (i *Inbox[T]) Migrate(ctx context.Context) error {
+	_, err := i.db.ExecContext(ctx, `create table if not exists `+i.table+` (projection VARCHAR NOT NULL, type VARCHAR NOT NULL, id VARCHAR NOT NULL, version INTEGER NOT NULL, processed INTEGER NOT NULL, PRIMARY KEY (projection, type, id, version));`)
+	return err
+}
+
+// Handle calls the handler in a transaction if the event is not already processed. Returns true if the handler was
+// called. The mark is inserted before the handler runs, a duplicate delivered in parallel waits on the row until the
+// first transaction is done.
+func (i *Inbox[T]) Handle(ctx context.Context, event eventsourcing.Event[T]) (bool, error) {
+	tx, err := i.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+	res, err := tx.ExecContext(ctx, `insert into `+i.table+` (projection, type, id, version, processed) values ($1, $2, $3, $4, $5) on conflict do nothing`, i.projection, event.AggregateType, event.AggregateID, uint64(event.Version), time.Now().UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	if n == 0 {
+		return false, nil
+	}
+	err = i.handler(ctx, tx, event)
+	if err != nil {
+		return true, err
+	}
+	return true, tx.Commit()
+}
+
+// Prune removes the processed marks older than maxAge. Duplicates delivered after their mark is pruned are handled again.
+func (i *Inbox[T]) Prune(ctx context.Context, maxAge time.Duration) (int, error) {
+	res, err := i.db.ExecContext(ctx, `delete from `+i.table+` where projection=$1 and processed<$2`, i.projection, time.Now().Add(-maxAge).UnixNano())
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	return int(n), err
+}
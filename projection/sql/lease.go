@@ -0,0 +1,66 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// LeaseStore keeps the leases of projection groups in a row per key, it implements projection.LeaseStore. The
+// expiry is set from the clock of the instance acquiring the lease, the clocks of the instances have to be in sync
+// within a fraction of the lease ttl.
+type LeaseStore struct {
+	db    *sql.DB
+	table string
+}
+
+// NewLeaseStore constructs a lease store, the table is set with WithLeaseTable
+func NewLeaseStore(db *sql.DB, opts ...Option) *LeaseStore {
+	o := options{leaseTable: defaultLeaseTable}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &LeaseStore{db: db, table: o.leaseTable}
+}
+
+// Migrate creates the lease table if it doesn't exist
+func (l *LeaseStore) Migrate(ctx context.Context) error {
+	_, err := l.db.ExecContext(ctx, `create table if not exists `+l.table+` (key VARCHAR NOT NULL PRIMARY KEY, owner VARCHAR NOT NULL, expires INTEGER NOT NULL);`)
+	return err
+}
+
+// Acquire takes or renews the lease on the key for the owner, false if another owner holds it
+func (l *LeaseStore) Acquire(ctx context.Context, key, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	res, err := l.db.ExecContext(ctx, `insert into `+l.table+` (key, owner, expires) values ($1, $2, $3) on conflict (key) do update set owner=excluded.owner, expires=excluded.expires where `+l.table+`.owner=excluded.owner or `+l.table+`.expires<$4`, key, owner, now.Add(ttl).UnixNano(), now.UnixNano())
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Release gives up the lease on the key if the owner holds it
+func (l *LeaseStore) Release(ctx context.Context, key, owner string) error {
+	_, err := l.db.ExecContext(ctx, `delete from `+l.table+` where key=$1 and owner=$2`, key, owner)
+	return err
+}
+
+// Holders returns the owners of the unexpired leases on the keys starting with the prefix
+func (l *LeaseStore) Holders(ctx context.Context, prefix string) (map[string]string, error) {
+	rows, err := l.db.QueryContext(ctx, `select key, owner from `+l.table+` where substr(key, 1, length($1))=$1 and expires>=$2`, prefix, time.Now().UnixNano())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	holders := make(map[string]string)
+	for rows.Next() {
+		var key, owner string
+		err = rows.Scan(&key, &owner)
+		if err != nil {
+			return nil, err
+		}
+		holders[key] = owner
+	}
+	return holders, rows.Err()
+}
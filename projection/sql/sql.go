@@ -0,0 +1,214 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	defaultBatchSize       = 500
+	defaultCheckpointTable = "projection_checkpoints"
+	defaultStateTable      = "projection_states"
+	defaultLeaseTable      = "projection_leases"
+	defaultInboxTable      = "projection_inbox"
+)
+
+// Option configures the sql projection
+type Option func(*options)
+
+type options struct {
+	batchSize       uint64
+	checkpointTable string
+	stateTable      string
+	leaseTable      string
+	inboxTable      string
+}
+
+// WithBatchSize sets the max number of events read and written per poll
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithCheckpointTable sets the table the projection position is stored in
+func WithCheckpointTable(table string) Option {
+	return func(o *options) {
+		o.checkpointTable = table
+	}
+}
+
+// WithStateTable sets the table the state store keeps the projection states in
+func WithStateTable(table string) Option {
+	return func(o *options) {
+		o.stateTable = table
+	}
+}
+
+// WithLeaseTable sets the table the lease store keeps the leases in
+func WithLeaseTable(table string) Option {
+	return func(o *options) {
+		o.leaseTable = table
+	}
+}
+
+// WithInboxTable sets the table the inbox keeps the processed marks in
+func WithInboxTable(table string) Option {
+	return func(o *options) {
+		o.inboxTable = table
+	}
+}
+
+// Projection materializes events into a sql table. Each poll reads a batch of events after the projection position,
+// maps them to rows and upserts the rows together with the new position in one transaction.
+type Projection[T, V any] struct {
+	db      *sql.DB
+	name    string
+	table   *Table[V]
+	source  eventsourcing.GlobalEventStore[T]
+	row     func(event eventsourcing.Event[T]) (V, []string, bool)
+	options options
+}
+
+// New constructs a sql projection. row maps the event to the row to upsert, returning false skips the event. All
+// columns of an existing row are updated.
+func New[T, V any](db *sql.DB, name string, table *Table[V], source eventsourcing.GlobalEventStore[T], row func(event eventsourcing.Event[T]) (V, bool), opts ...Option) *Projection[T, V] {
+	return NewPartial(db, name, table, source, func(event eventsourcing.Event[T]) (V, []string, bool) {
+		v, ok := row(event)
+		return v, nil, ok
+	}, opts...)
+}
+
+// NewPartial constructs a sql projection updating only the columns named by row. row maps the event to the row and the
+// none key columns set by the event, the other columns of an existing row are kept and left empty on a new row. nil
+// columns updates all columns, returning false skips the event.
+func NewPartial[T, V any](db *sql.DB, name string, table *Table[V], source eventsourcing.GlobalEventStore[T], row func(event eventsourcing.Event[T]) (V, []string, bool), opts ...Option) *Projection[T, V] {
+	o := options{
+		batchSize:       defaultBatchSize,
+		checkpointTable: defaultCheckpointTable,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Projection[T, V]{
+		db:      db,
+		name:    name,
+		table:   table,
+		source:  source,
+		row:     row,
+		options: o,
+	}
+}
+
+// Migrate creates the read model table and the checkpoint table if they don't exist
+func (p *Projection[T, V]) Migrate(ctx context.Context) error {
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	stmts := []string{
+		p.table.createTable(),
+		`create table if not exists ` + p.options.checkpointTable + ` (name VARCHAR NOT NULL PRIMARY KEY, position INTEGER NOT NULL);`,
+	}
+	for _, stmt := range stmts {
+		_, err = tx.ExecContext(ctx, stmt)
+		if err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// Position returns the global version of the last event handled by the projection
+func (p *Projection[T, V]) Position(ctx context.Context) (eventsourcing.Version, error) {
+	var position int64
+	err := p.db.QueryRowContext(ctx, `select position from `+p.options.checkpointTable+` where name=$1`, p.name).Scan(&position)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	return eventsourcing.VersionFromInt64(position)
+}
+
+// Poll handles the next batch of events and returns the number of events read
+func (p *Projection[T, V]) Poll(ctx context.Context) (int, error) {
+	position, err := p.Position(ctx)
+	if err != nil {
+		return 0, err
+	}
+	events, err := p.source.GlobalEvents(uint64(position)+1, p.options.batchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	// a statement per set of columns updated by the events
+	upserts := make(map[string]*sql.Stmt)
+	defer func() {
+		for _, stmt := range upserts {
+			stmt.Close()
+		}
+	}()
+	for _, event := range events {
+		row, names, ok := p.row(event)
+		if !ok {
+			continue
+		}
+		columns, err := p.table.selected(names)
+		if err != nil {
+			return 0, err
+		}
+		key := "*"
+		if names != nil {
+			key = strings.Join(names, ",")
+		}
+		upsert, ok := upserts[key]
+		if !ok {
+			upsert, err = tx.PrepareContext(ctx, p.table.upsert(columns))
+			if err != nil {
+				return 0, err
+			}
+			upserts[key] = upsert
+		}
+		_, err = upsert.ExecContext(ctx, p.table.values(row, columns)...)
+		if err != nil {
+			return 0, err
+		}
+	}
+	last := events[len(events)-1].GlobalVersion
+	_, err = tx.ExecContext(ctx, `insert into `+p.options.checkpointTable+` (name, position) values ($1, $2) on conflict (name) do update set position=excluded.position`, p.name, uint64(last))
+	if err != nil {
+		return 0, err
+	}
+	return len(events), tx.Commit()
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events
+func (p *Projection[T, V]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := p.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
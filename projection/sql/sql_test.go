@@ -0,0 +1,334 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	projection "github.com/hallgren/eventsourcing/projection/sql"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type Born struct{ Name string }
+type AgedOneYear struct{}
+
+type personView struct {
+	ID    string `db:"id,key"`
+	Name  string `db:"name"`
+	Event string `db:"last_event"`
+	Skip  int
+}
+
+func row(event eventsourcing.Event[any]) (personView, bool) {
+	switch e := event.Data.(type) {
+	case *Born:
+		return personView{ID: event.AggregateID, Name: e.Name, Event: event.Reason()}, true
+	}
+	return personView{}, false
+}
+
+func TestProjection(t *testing.T) {
+	db, err := sql.Open("sqlite3", "file::memory:?locked.sqlite?cache=shared")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	es := memory.Create[any]()
+	err = es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{Name: "kalle"}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Data: &AgedOneYear{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "2", AggregateType: "Person", Version: 1, Data: &Born{Name: "anka"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := projection.NewTable[personView]("person_view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := projection.New[any, personView](db, "persons", table, es, row, projection.WithBatchSize(2))
+	err = p.Migrate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	n, err := p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Fatalf("expected a batch of 2 events got %d", n)
+	}
+	n, err = p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the last event got %d", n)
+	}
+	position, err := p.Position(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 3 {
+		t.Fatalf("expected position 3 got %d", position)
+	}
+
+	// a renamed person updates the existing row
+	err = es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 3, Data: &Born{Name: "kalle anka"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var count int
+	err = db.QueryRow(`select count(*) from person_view`).Scan(&count)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rows got %d", count)
+	}
+	var name string
+	err = db.QueryRow(`select name from person_view where id='1'`).Scan(&name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "kalle anka" {
+		t.Fatalf("expected the row to be updated got %q", name)
+	}
+}
+
+func TestPartialProjection(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+
+	es := memory.Create[any]()
+	err = es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Data: &Born{Name: "kalle"}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Data: &AgedOneYear{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	table, err := projection.NewTable[personView]("person_view")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the aged event only sets the last event column
+	p := projection.NewPartial[any, personView](db, "persons", table, es, func(event eventsourcing.Event[any]) (personView, []string, bool) {
+		switch e := event.Data.(type) {
+		case *Born:
+			return personView{ID: event.AggregateID, Name: e.Name, Event: event.Reason()}, nil, true
+		case *AgedOneYear:
+			return personView{ID: event.AggregateID, Event: event.Reason()}, []string{"last_event"}, true
+		}
+		return personView{}, nil, false
+	})
+	err = p.Migrate(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = p.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	var name, event string
+	err = db.QueryRow(`select name, last_event from person_view where id='1'`).Scan(&name, &event)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "kalle" || event != "AgedOneYear" {
+		t.Fatalf("expected the name kept and the last event updated got %q %q", name, event)
+	}
+
+	unknown := projection.NewPartial[any, personView](db, "unknown", table, es, func(event eventsourcing.Event[any]) (personView, []string, bool) {
+		return personView{ID: event.AggregateID}, []string{"age"}, true
+	})
+	_, err = unknown.Poll(context.Background())
+	if !errors.Is(err, projection.ErrUnknownColumn) {
+		t.Fatalf("expected ErrUnknownColumn got %v", err)
+	}
+}
+
+func TestTableWithoutKey(t *testing.T) {
+	type noKey struct {
+		Name string `db:"name"`
+	}
+	_, err := projection.NewTable[noKey]("no_key")
+	if err != projection.ErrNoKey {
+		t.Fatalf("expected ErrNoKey got %v", err)
+	}
+}
+
+func TestStateStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+
+	store := projection.NewStateStore[map[string]int](db, projection.WithStateTable("states"))
+	err = store.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	state, position, err := store.LoadState(ctx, "reasons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil || position != 0 {
+		t.Fatalf("expected no state got %v at %d", state, position)
+	}
+	for i := 1; i <= 2; i++ {
+		err = store.SaveState(ctx, "reasons", map[string]int{"Born": i}, eventsourcing.Version(i))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	state, position, err = store.LoadState(ctx, "reasons")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state["Born"] != 2 || position != 2 {
+		t.Fatalf("expected the last saved state got %v at %d", state, position)
+	}
+}
+
+func TestLeaseStore(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+
+	store := projection.NewLeaseStore(db, projection.WithLeaseTable("leases"))
+	err = store.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ok, err := store.Acquire(ctx, "people/partition/0", "a", 50*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("expected to acquire the lease got %v %v", ok, err)
+	}
+	ok, err = store.Acquire(ctx, "people/partition/0", "a", 50*time.Millisecond)
+	if err != nil || !ok {
+		t.Fatalf("expected to renew the lease got %v %v", ok, err)
+	}
+	ok, err = store.Acquire(ctx, "people/partition/0", "b", time.Second)
+	if err != nil || ok {
+		t.Fatalf("expected the lease to be held by a got %v %v", ok, err)
+	}
+	holders, err := store.Holders(ctx, "people/partition/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(holders) != 1 || holders["people/partition/0"] != "a" {
+		t.Fatalf("expected a to hold the lease got %v", holders)
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	ok, err = store.Acquire(ctx, "people/partition/0", "b", time.Second)
+	if err != nil || !ok {
+		t.Fatalf("expected to take over the expired lease got %v %v", ok, err)
+	}
+	err = store.Release(ctx, "people/partition/0", "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	holders, err = store.Holders(ctx, "people/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if holders["people/partition/0"] != "b" {
+		t.Fatalf("expected the release by a former owner to be ignored got %v", holders)
+	}
+}
+
+func TestInbox(t *testing.T) {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	defer db.Close()
+	ctx := context.Background()
+	_, err = db.Exec(`create table people (id VARCHAR NOT NULL PRIMARY KEY, births INTEGER NOT NULL)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fail := true
+	inbox := projection.NewInbox(db, "people", func(ctx context.Context, tx *sql.Tx, event eventsourcing.Event[any]) error {
+		_, err := tx.ExecContext(ctx, `insert into people (id, births) values ($1, 1) on conflict (id) do update set births=births+1`, event.AggregateID)
+		if err != nil {
+			return err
+		}
+		if fail {
+			return errors.New("crash")
+		}
+		return nil
+	}, projection.WithInboxTable("inbox"))
+	err = inbox.Migrate(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := eventsourcing.Event[any]{AggregateID: "123", AggregateType: "Person", Version: 1, Data: &Born{}}
+
+	// the failed handler rolls back the mark with its changes
+	_, err = inbox.Handle(ctx, event)
+	if err == nil {
+		t.Fatal("expected the handler error")
+	}
+	fail = false
+	for i := 0; i < 3; i++ {
+		handled, err := inbox.Handle(ctx, event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if handled != (i == 0) {
+			t.Fatalf("expected only the first delivery to be handled, delivery %d handled %v", i, handled)
+		}
+	}
+	var births int
+	err = db.QueryRow(`select births from people where id='123'`).Scan(&births)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if births != 1 {
+		t.Fatalf("expected the event to be applied once got %d", births)
+	}
+
+	n, err := inbox.Prune(ctx, -time.Second)
+	if err != nil || n != 1 {
+		t.Fatalf("expected the mark to be pruned got %d %v", n, err)
+	}
+	handled, err := inbox.Handle(ctx, event)
+	if err != nil || !handled {
+		t.Fatalf("expected the event to be handled after its mark was pruned got %v %v", handled, err)
+	}
+}
@@ -0,0 +1,60 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// StateStore keeps the state of typed projections as JSON in a row per projection, it implements
+// projection.StateStore
+type StateStore[S any] struct {
+	db    *sql.DB
+	table string
+}
+
+// NewStateStore constructs a state store, the table is set with WithStateTable
+func NewStateStore[S any](db *sql.DB, opts ...Option) *StateStore[S] {
+	o := options{stateTable: defaultStateTable}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &StateStore[S]{db: db, table: o.stateTable}
+}
+
+// Migrate creates the state table if it doesn't exist
+func (s *StateStore[S]) Migrate(ctx context.Context) error {
+	_, err := s.db.ExecContext(ctx, `create table if not exists `+s.table+` (name VARCHAR NOT NULL PRIMARY KEY, state BLOB NOT NULL, position INTEGER NOT NULL);`)
+	return err
+}
+
+// LoadState returns the state and position of the projection, the zero state and position if none is saved
+func (s *StateStore[S]) LoadState(ctx context.Context, projection string) (S, eventsourcing.Version, error) {
+	var state S
+	var b []byte
+	var position int64
+	err := s.db.QueryRowContext(ctx, `select state, position from `+s.table+` where name=$1`, projection).Scan(&b, &position)
+	if err == sql.ErrNoRows {
+		return state, 0, nil
+	} else if err != nil {
+		return state, 0, err
+	}
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return state, 0, err
+	}
+	version, err := eventsourcing.VersionFromInt64(position)
+	return state, version, err
+}
+
+// SaveState saves the state and position of the projection
+func (s *StateStore[S]) SaveState(ctx context.Context, projection string, state S, position eventsourcing.Version) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.ExecContext(ctx, `insert into `+s.table+` (name, state, position) values ($1, $2, $3) on conflict (name) do update set state=excluded.state, position=excluded.position`, projection, b, uint64(position))
+	return err
+}
@@ -0,0 +1,158 @@
+package sql
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// ErrNoKey when the read model struct has no key column
+var ErrNoKey = errors.New("no key column tagged")
+
+// ErrUnknownColumn when a row names a column that is not tagged on the read model struct
+var ErrUnknownColumn = errors.New("unknown column")
+
+type column struct {
+	name  string
+	index int
+	typ   string
+	key   bool
+}
+
+// Table maps a read model struct to a table. The columns are the struct fields tagged with `db:"name"`,
+// the primary key columns are tagged `db:"name,key"`.
+//
+//	type PersonView struct {
+//		ID   string `db:"id,key"`
+//		Name string `db:"name"`
+//		Age  int    `db:"age"`
+//	}
+type Table[V any] struct {
+	name    string
+	columns []column
+}
+
+// NewTable parses the struct tags of V
+func NewTable[V any](name string) (*Table[V], error) {
+	var v V
+	typ := reflect.TypeOf(v)
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("read model %v is not a struct", typ)
+	}
+	t := &Table[V]{name: name}
+	hasKey := false
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag, ok := field.Tag.Lookup("db")
+		if !ok || tag == "-" {
+			continue
+		}
+		parts := strings.Split(tag, ",")
+		c := column{name: parts[0], index: i, typ: columnType(field.Type)}
+		for _, p := range parts[1:] {
+			if p == "key" {
+				c.key = true
+				hasKey = true
+			}
+		}
+		if c.typ == "" {
+			return nil, fmt.Errorf("unsupported column type %s of %s", field.Type, field.Name)
+		}
+		t.columns = append(t.columns, c)
+	}
+	if !hasKey {
+		return nil, ErrNoKey
+	}
+	return t, nil
+}
+
+// createTable returns the statement creating the table if it not exists
+func (t *Table[V]) createTable() string {
+	var defs, keys []string
+	for _, c := range t.columns {
+		def := c.name + " " + c.typ
+		if c.key {
+			def += " NOT NULL"
+			keys = append(keys, c.name)
+		}
+		defs = append(defs, def)
+	}
+	defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(keys, ", ")))
+	return fmt.Sprintf("create table if not exists %s (%s);", t.name, strings.Join(defs, ", "))
+}
+
+// selected returns the key columns followed by the named columns in column order, all columns if names is nil
+func (t *Table[V]) selected(names []string) ([]column, error) {
+	if names == nil {
+		return t.columns, nil
+	}
+	named := make(map[string]bool, len(names))
+	for _, name := range names {
+		named[name] = true
+	}
+	var columns []column
+	for _, c := range t.columns {
+		if c.key || named[c.name] {
+			columns = append(columns, c)
+			delete(named, c.name)
+		}
+	}
+	for name := range named {
+		return nil, fmt.Errorf("%w %s in table %s", ErrUnknownColumn, name, t.name)
+	}
+	return columns, nil
+}
+
+// upsert returns the statement inserting a row or updating the none key columns of an existing row
+func (t *Table[V]) upsert(columns []column) string {
+	var names, params, keys, updates []string
+	for i, c := range columns {
+		names = append(names, c.name)
+		params = append(params, fmt.Sprintf("$%d", i+1))
+		if c.key {
+			keys = append(keys, c.name)
+		} else {
+			updates = append(updates, fmt.Sprintf("%s=excluded.%s", c.name, c.name))
+		}
+	}
+	conflict := "do nothing"
+	if len(updates) > 0 {
+		conflict = "do update set " + strings.Join(updates, ", ")
+	}
+	return fmt.Sprintf("insert into %s (%s) values (%s) on conflict (%s) %s;",
+		t.name, strings.Join(names, ", "), strings.Join(params, ", "), strings.Join(keys, ", "), conflict)
+}
+
+// values returns the values of the columns of the row
+func (t *Table[V]) values(row V, columns []column) []interface{} {
+	v := reflect.ValueOf(row)
+	values := make([]interface{}, len(columns))
+	for i, c := range columns {
+		values[i] = v.Field(c.index).Interface()
+	}
+	return values
+}
+
+func columnType(typ reflect.Type) string {
+	if typ == reflect.TypeOf(time.Time{}) {
+		return "TIMESTAMP"
+	}
+	switch typ.Kind() {
+	case reflect.String:
+		return "TEXT"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "REAL"
+	case reflect.Bool:
+		return "BOOLEAN"
+	case reflect.Slice:
+		if typ.Elem().Kind() == reflect.Uint8 {
+			return "BLOB"
+		}
+	}
+	return ""
+}
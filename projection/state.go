@@ -0,0 +1,210 @@
+package projection
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const stateBatchSize = 100
+
+// StateStore persists the state of a projection together with the global version of the last event applied to it
+type StateStore[S any] interface {
+	// LoadState returns the state and position of the projection, the zero state and position if none is saved
+	LoadState(ctx context.Context, projection string) (S, eventsourcing.Version, error)
+	// SaveState saves the state and position of the projection
+	SaveState(ctx context.Context, projection string, state S, position eventsourcing.Version) error
+}
+
+// StateCheckpointStore is implemented by checkpoint stores that can keep a serialized projection state beside the
+// checkpoint
+type StateCheckpointStore interface {
+	// CheckpointState returns the state and position of the projection, nil and zero if no state is saved
+	CheckpointState(ctx context.Context, projection string) ([]byte, eventsourcing.Version, error)
+	// SaveCheckpointState saves the state and sets the checkpoint of the projection to the position
+	SaveCheckpointState(ctx context.Context, projection string, state []byte, position eventsourcing.Version) error
+}
+
+// Projection keeps a typed state S built from the events in the global order. The apply function mutates the state
+// for each event and the state is saved with the position in the state store after each batch, making small
+// projections possible without a storage integration of their own.
+type Projection[T, S any] struct {
+	name   string
+	source eventsourcing.GlobalEventStore[T]
+	store  StateStore[S]
+	apply  func(state *S, event eventsourcing.Event[T])
+
+	lock     sync.Mutex
+	loaded   bool
+	state    S
+	position eventsourcing.Version
+}
+
+// NewProjection constructs a projection, the name is the key of its state in the store
+func NewProjection[T, S any](name string, source eventsourcing.GlobalEventStore[T], store StateStore[S], apply func(state *S, event eventsourcing.Event[T])) *Projection[T, S] {
+	return &Projection[T, S]{
+		name:   name,
+		source: source,
+		store:  store,
+		apply:  apply,
+	}
+}
+
+// State returns the state and the global version of the last event applied to it. The state is shared with the
+// projection and must not be modified.
+func (p *Projection[T, S]) State(ctx context.Context) (S, eventsourcing.Version, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	err := p.load(ctx)
+	return p.state, p.position, err
+}
+
+// Poll applies the next batch of events to the state and saves it, returns the number of events read
+func (p *Projection[T, S]) Poll(ctx context.Context) (int, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	err := p.load(ctx)
+	if err != nil {
+		return 0, err
+	}
+	events, err := p.source.GlobalEvents(uint64(p.position)+1, stateBatchSize)
+	if err != nil || len(events) == 0 {
+		return 0, err
+	}
+	for _, event := range events {
+		p.apply(&p.state, event)
+	}
+	position := events[len(events)-1].GlobalVersion
+	err = p.store.SaveState(ctx, p.name, p.state, position)
+	if err != nil {
+		// the state holds events not saved, load the saved state on the next poll
+		p.loaded = false
+		return 0, err
+	}
+	p.position = position
+	return len(events), nil
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events
+func (p *Projection[T, S]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := p.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// load reads the state from the store the first time and after a failed save
+func (p *Projection[T, S]) load(ctx context.Context) error {
+	if p.loaded {
+		return nil
+	}
+	state, position, err := p.store.LoadState(ctx, p.name)
+	if err != nil {
+		return err
+	}
+	p.state, p.position, p.loaded = state, position, true
+	return nil
+}
+
+type checkpointStateStore[S any] struct {
+	store StateCheckpointStore
+}
+
+// NewCheckpointStateStore returns a state store keeping the state as JSON in the checkpoint store
+func NewCheckpointStateStore[S any](store StateCheckpointStore) StateStore[S] {
+	return &checkpointStateStore[S]{store: store}
+}
+
+func (c *checkpointStateStore[S]) LoadState(ctx context.Context, projection string) (S, eventsourcing.Version, error) {
+	var state S
+	b, position, err := c.store.CheckpointState(ctx, projection)
+	if err != nil || b == nil {
+		return state, position, err
+	}
+	err = json.Unmarshal(b, &state)
+	return state, position, err
+}
+
+func (c *checkpointStateStore[S]) SaveState(ctx context.Context, projection string, state S, position eventsourcing.Version) error {
+	b, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	return c.store.SaveCheckpointState(ctx, projection, b, position)
+}
+
+type fileStateStore[S any] struct {
+	dir string
+}
+
+type fileState[S any] struct {
+	Position eventsourcing.Version `json:"position"`
+	State    S                     `json:"state"`
+}
+
+// NewFileStateStore returns a state store keeping the state of each projection as JSON in a file named after the
+// projection in the directory. The file is replaced on save, a crash leaves either the old or the new state.
+func NewFileStateStore[S any](dir string) StateStore[S] {
+	return &fileStateStore[S]{dir: dir}
+}
+
+func (f *fileStateStore[S]) LoadState(ctx context.Context, projection string) (S, eventsourcing.Version, error) {
+	state := fileState[S]{}
+	b, err := os.ReadFile(f.path(projection))
+	if errors.Is(err, os.ErrNotExist) {
+		return state.State, 0, nil
+	} else if err != nil {
+		return state.State, 0, err
+	}
+	err = json.Unmarshal(b, &state)
+	if err != nil {
+		return state.State, 0, fmt.Errorf("projection state %s: %w", projection, err)
+	}
+	return state.State, state.Position, nil
+}
+
+func (f *fileStateStore[S]) SaveState(ctx context.Context, projection string, state S, position eventsourcing.Version) error {
+	b, err := json.Marshal(fileState[S]{Position: position, State: state})
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(f.dir, projection+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = tmp.Write(b)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path(projection))
+}
+
+func (f *fileStateStore[S]) path(projection string) string {
+	return filepath.Join(f.dir, projection+".json")
+}
@@ -0,0 +1,79 @@
+package projection_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	eventstore "github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/projection"
+	"github.com/hallgren/eventsourcing/projection/memory"
+)
+
+// counts is the state of a projection counting the events per reason
+type counts struct {
+	Reasons map[string]int
+}
+
+func countReasons(state *counts, event eventsourcing.Event[any]) {
+	if state.Reasons == nil {
+		state.Reasons = make(map[string]int)
+	}
+	state.Reasons[event.Reason()]++
+}
+
+func testStateStore(t *testing.T, store projection.StateStore[counts]) {
+	ctx := context.Background()
+	es := eventstore.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Born{}},
+		{AggregateID: "1", AggregateType: "Person", Version: 2, Timestamp: time.Now(), Data: &Renamed{}},
+		{AggregateID: "1", AggregateType: "Person", Version: 3, Timestamp: time.Now(), Data: &Renamed{}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := projection.NewProjection[any, counts]("counts", es, store, countReasons)
+	n, err := p.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 events got %d", n)
+	}
+
+	// a new projection continues from the saved state
+	err = es.Save([]eventsourcing.Event[any]{{AggregateID: "2", AggregateType: "Person", Version: 1, Timestamp: time.Now(), Data: &Born{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p = projection.NewProjection[any, counts]("counts", es, store, countReasons)
+	n, err = p.Poll(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Fatalf("expected the new event only got %d", n)
+	}
+	state, position, err := p.State(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if position != 4 || state.Reasons["Born"] != 2 || state.Reasons["Renamed"] != 2 {
+		t.Fatalf("unexpected state %v at %d", state.Reasons, position)
+	}
+}
+
+func TestProjectionCheckpointState(t *testing.T) {
+	checkpoints := memory.New()
+	testStateStore(t, projection.NewCheckpointStateStore[counts](checkpoints))
+	position, _ := checkpoints.Checkpoint(context.Background(), "counts")
+	if position != 4 {
+		t.Fatalf("expected the checkpoint to follow the state got %d", position)
+	}
+}
+
+func TestProjectionFileState(t *testing.T) {
+	testStateStore(t, projection.NewFileStateStore[counts](t.TempDir()))
+}
@@ -0,0 +1,153 @@
+// Package proptest checks the invariants of an aggregate against random command sequences. The failing sequences are
+// shrunk to the fewest commands still breaking an invariant before they are reported.
+package proptest
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	defaultRuns  = 100
+	defaultSteps = 50
+)
+
+// Option to configure the check
+type Option func(*options)
+
+type options struct {
+	runs  int
+	steps int
+	seed  int64
+}
+
+// WithRuns sets the number of random sequences, default 100
+func WithRuns(runs int) Option {
+	return func(o *options) {
+		o.runs = runs
+	}
+}
+
+// WithSteps sets the number of commands in each sequence, default 50
+func WithSteps(steps int) Option {
+	return func(o *options) {
+		o.steps = steps
+	}
+}
+
+// WithSeed sets the seed of the random generator, use the seed of a reported failure to reproduce it
+func WithSeed(seed int64) Option {
+	return func(o *options) {
+		o.seed = seed
+	}
+}
+
+// Property describes how to build and exercise an aggregate of type A with commands of type C
+type Property[A, C any] struct {
+	// New returns a new aggregate
+	New func() A
+	// Generate returns a random command, the aggregate is passed to generate commands valid in its current state
+	Generate func(r *rand.Rand, aggregate A) C
+	// Run runs the command on the aggregate, commands returning an error are rejected and not part of the sequence
+	Run func(aggregate A, command C) error
+	// Invariant returns an error if the aggregate is in an invalid state
+	Invariant func(aggregate A) error
+}
+
+// Failure is a command sequence breaking an invariant
+type Failure[C any] struct {
+	Seed int64
+	// Commands is the shrunk sequence of commands
+	Commands []C
+	// Step is the index of the command after which the invariant broke, len(Commands) if it broke when the
+	// aggregate was built from the events of the sequence
+	Step int
+	Err  error
+}
+
+func (f *Failure[C]) Error() string {
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "invariant broken after step %d (seed %d): %v\n", f.Step, f.Seed, f.Err)
+	for i, c := range f.Commands {
+		fmt.Fprintf(&b, "  %d: %+v\n", i, c)
+	}
+	return b.String()
+}
+
+// Check runs the property and fails the test with the shrunk sequence if an invariant breaks
+func Check[T any, A eventsourcing.Aggregate[T], C any](t testing.TB, p Property[A, C], opts ...Option) {
+	t.Helper()
+	if f := Run[T](p, opts...); f != nil {
+		t.Fatal(f.Error())
+	}
+}
+
+// Run runs random command sequences on new aggregates and checks the invariant after each command. After each
+// sequence a new aggregate is built from the tracked events and the invariant checked again, making sure the state
+// built from history is valid too. Returns nil if no invariant broke.
+func Run[T any, A eventsourcing.Aggregate[T], C any](p Property[A, C], opts ...Option) *Failure[C] {
+	o := options{runs: defaultRuns, steps: defaultSteps, seed: time.Now().UnixNano()}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	r := rand.New(rand.NewSource(o.seed))
+	for run := 0; run < o.runs; run++ {
+		aggregate := p.New()
+		commands := make([]C, 0, o.steps)
+		for step := 0; step < o.steps; step++ {
+			command := p.Generate(r, aggregate)
+			if p.Run(aggregate, command) != nil {
+				continue
+			}
+			commands = append(commands, command)
+			if p.Invariant(aggregate) != nil {
+				break
+			}
+		}
+		step, err := replay[T](p, commands)
+		if err != nil {
+			commands = shrink[T](p, commands)
+			step, err = replay[T](p, commands)
+			return &Failure[C]{Seed: o.seed, Commands: commands, Step: step, Err: err}
+		}
+	}
+	return nil
+}
+
+// replay runs the commands on a new aggregate and returns the step and error of the first broken invariant
+func replay[T any, A eventsourcing.Aggregate[T], C any](p Property[A, C], commands []C) (int, error) {
+	aggregate := p.New()
+	for i, command := range commands {
+		if p.Run(aggregate, command) != nil {
+			continue
+		}
+		err := p.Invariant(aggregate)
+		if err != nil {
+			return i, err
+		}
+	}
+	rebuilt := p.New()
+	rebuilt.Root().BuildFromHistory(rebuilt, aggregate.Root().Events())
+	return len(commands), p.Invariant(rebuilt)
+}
+
+// shrink removes commands from the sequence as long as an invariant still breaks
+func shrink[T any, A eventsourcing.Aggregate[T], C any](p Property[A, C], commands []C) []C {
+	// try to remove larger blocks first to shrink long sequences fast
+	for size := len(commands) / 2; size >= 1; size /= 2 {
+		for i := 0; i+size <= len(commands); {
+			candidate := append(append([]C{}, commands[:i]...), commands[i+size:]...)
+			if _, err := replay[T](p, candidate); err != nil {
+				commands = candidate
+				continue
+			}
+			i++
+		}
+	}
+	return commands
+}
@@ -0,0 +1,85 @@
+package proptest_test
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/proptest"
+)
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+	Balance int
+	// overdraft makes Withdraw check the balance off by one
+	overdraft bool
+}
+
+type Deposited struct{ Amount int }
+type Withdrawn struct{ Amount int }
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {
+	switch e := event.Data.(type) {
+	case *Deposited:
+		a.Balance += e.Amount
+	case *Withdrawn:
+		a.Balance -= e.Amount
+	}
+}
+
+func (a *Account) Withdraw(amount int) error {
+	limit := a.Balance
+	if a.overdraft {
+		limit++
+	}
+	if amount > limit {
+		return errors.New("insufficient funds")
+	}
+	return a.TrackChange(a, &Withdrawn{Amount: amount})
+}
+
+type command struct {
+	Deposit bool
+	Amount  int
+}
+
+func property(overdraft bool) proptest.Property[*Account, command] {
+	return proptest.Property[*Account, command]{
+		New: func() *Account { return &Account{overdraft: overdraft} },
+		Generate: func(r *rand.Rand, a *Account) command {
+			return command{Deposit: r.Intn(2) == 0, Amount: r.Intn(10) + 1}
+		},
+		Run: func(a *Account, c command) error {
+			if c.Deposit {
+				return a.TrackChange(a, &Deposited{Amount: c.Amount})
+			}
+			return a.Withdraw(c.Amount)
+		},
+		Invariant: func(a *Account) error {
+			if a.Balance < 0 {
+				return fmt.Errorf("negative balance %d", a.Balance)
+			}
+			return nil
+		},
+	}
+}
+
+func TestCheck(t *testing.T) {
+	proptest.Check[any](t, property(false), proptest.WithSeed(1))
+}
+
+func TestRunShrinks(t *testing.T) {
+	f := proptest.Run[any](property(true), proptest.WithSeed(1), proptest.WithSteps(100))
+	if f == nil {
+		t.Fatal("expected the overdraft to break the invariant")
+	}
+	// withdrawing one more than the balance is enough
+	if len(f.Commands) != 1 || f.Commands[0].Deposit || f.Commands[0].Amount != 1 || f.Step != 0 {
+		t.Fatalf("expected a single withdraw of 1 got %s", f.Error())
+	}
+	if f.Seed != 1 {
+		t.Fatalf("expected seed 1 got %d", f.Seed)
+	}
+}
@@ -0,0 +1,72 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+)
+
+// RawEvent is an event as stored, the data is not unmarshaled making it possible to read events without registering
+// their types in a serializer
+type RawEvent struct {
+	EventID       string
+	AggregateID   string
+	AggregateType string
+	Version       Version
+	GlobalVersion Version
+	Reason        string
+	Timestamp     time.Time
+	ValidTime     time.Time
+	// Data is the event data as marshaled by the serializer of the event store
+	Data     []byte
+	Metadata map[string]interface{}
+}
+
+// RawEventStore is implemented by event stores that can return the events without unmarshaling their data
+type RawEventStore interface {
+	// GetRaw returns the events of the aggregate after the version
+	GetRaw(ctx context.Context, id, aggregateType string, afterVersion Version) ([]RawEvent, error)
+	// GlobalEventsRaw returns count events in global order from the start position
+	GlobalEventsRaw(ctx context.Context, start, count uint64) ([]RawEvent, error)
+}
+
+// RawEventSaver is implemented by event stores that can save events with the data already marshaled. The data has to
+// be marshaled like the serializer of the event store would, tools copying events between stores sharing the
+// serialization format don't need the event types registered.
+type RawEventSaver interface {
+	// SaveRaw saves the events of one aggregate, the global versions are set on the events
+	SaveRaw(events []RawEvent) error
+}
+
+// DecodeRaw unmarshals the data of the raw event, ok is false if the event is not registered or skipped by the decode
+// error policy
+func (h *Serializer[T]) DecodeRaw(raw RawEvent) (event Event[T], ok bool, err error) {
+	event = Event[T]{
+		EventID:       raw.EventID,
+		AggregateID:   raw.AggregateID,
+		AggregateType: raw.AggregateType,
+		Version:       raw.Version,
+		GlobalVersion: raw.GlobalVersion,
+		Timestamp:     raw.Timestamp,
+		ValidTime:     raw.ValidTime,
+		Metadata:      raw.Metadata,
+	}
+	ok, err = h.UnmarshalEvent(&event, raw.Reason, raw.Data)
+	return event, ok, err
+}
+
+// DecodeRawInto is DecodeRaw setting the fields of event. When the data of event is a pointer to the type the raw
+// event unmarshals to, it's reset and unmarshaled into instead of allocating a new instance.
+func (h *Serializer[T]) DecodeRawInto(raw RawEvent, event *Event[T]) (ok bool, err error) {
+	reuse := event.Data
+	*event = Event[T]{
+		EventID:       raw.EventID,
+		AggregateID:   raw.AggregateID,
+		AggregateType: raw.AggregateType,
+		Version:       raw.Version,
+		GlobalVersion: raw.GlobalVersion,
+		Timestamp:     raw.Timestamp,
+		ValidTime:     raw.ValidTime,
+		Metadata:      raw.Metadata,
+	}
+	return h.unmarshalEvent(event, raw.Reason, raw.Data, reuse)
+}
@@ -0,0 +1,162 @@
+package reactor
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/projection"
+)
+
+const defaultBatchSize = 100
+
+// Handler reacts to an event, typically by running a command on another aggregate
+type Handler[T any] func(ctx context.Context, event eventsourcing.Event[T]) error
+
+// Option configures the reactor
+type Option func(*options)
+
+type options struct {
+	batchSize uint64
+	attempts  int
+	backoff   time.Duration
+}
+
+// WithBatchSize sets the max number of events read per poll
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithRetry sets the number of attempts a handler gets and the time to wait between them
+func WithRetry(attempts int, backoff time.Duration) Option {
+	return func(o *options) {
+		o.attempts = attempts
+		o.backoff = backoff
+	}
+}
+
+// Reactor runs handlers on the events they are registered for, in the global event order. The position is saved
+// in the checkpoint store after each event, making the handlers called at least once per event. Handlers running
+// commands can use CommandID as the id of the command to deduplicate it.
+type Reactor[T any] struct {
+	name        string
+	source      eventsourcing.GlobalEventStore[T]
+	checkpoints projection.CheckpointStore
+	handlers    map[string][]Handler[T]
+	onFailure   func(event eventsourcing.Event[T], err error)
+	options     options
+}
+
+// New constructs a reactor, the name is the key of its position in the checkpoint store
+func New[T any](name string, source eventsourcing.GlobalEventStore[T], checkpoints projection.CheckpointStore, opts ...Option) *Reactor[T] {
+	o := options{
+		batchSize: defaultBatchSize,
+		attempts:  1,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Reactor[T]{
+		name:        name,
+		source:      source,
+		checkpoints: checkpoints,
+		handlers:    make(map[string][]Handler[T]),
+		options:     o,
+	}
+}
+
+// When registers the handler to run on the events with the same reason as the event data
+func (r *Reactor[T]) When(event T, handler Handler[T]) {
+	reason := eventsourcing.Event[T]{Data: event}.Reason()
+	r.handlers[reason] = append(r.handlers[reason], handler)
+}
+
+// OnFailure sets a function called with events whose handler failed all attempts. The event is skipped after the
+// function is called, without it the reactor stops on the event and tries it again on the next poll.
+func (r *Reactor[T]) OnFailure(f func(event eventsourcing.Event[T], err error)) {
+	r.onFailure = f
+}
+
+// Poll handles the next batch of events and returns the number of events read
+func (r *Reactor[T]) Poll(ctx context.Context) (int, error) {
+	position, err := r.checkpoints.Checkpoint(ctx, r.name)
+	if err != nil {
+		return 0, err
+	}
+	events, err := r.source.GlobalEvents(uint64(position)+1, r.options.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	for i, event := range events {
+		err = r.handle(ctx, event)
+		if err != nil {
+			return i, err
+		}
+		err = r.checkpoints.SaveCheckpoint(ctx, r.name, event.GlobalVersion)
+		if err != nil {
+			return i, err
+		}
+	}
+	return len(events), nil
+}
+
+// handle runs the handlers of the event
+func (r *Reactor[T]) handle(ctx context.Context, event eventsourcing.Event[T]) error {
+	for _, handler := range r.handlers[event.Reason()] {
+		err := r.attempt(ctx, handler, event)
+		if err == nil {
+			continue
+		}
+		if ctx.Err() != nil || r.onFailure == nil {
+			return err
+		}
+		r.onFailure(event, err)
+	}
+	return nil
+}
+
+// attempt runs the handler until it succeeds or is out of attempts
+func (r *Reactor[T]) attempt(ctx context.Context, handler Handler[T], event eventsourcing.Event[T]) error {
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = handler(ctx, event)
+		if err == nil || attempt >= r.options.attempts {
+			return err
+		}
+		timer := time.NewTimer(r.options.backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// Run polls the events until the context is done, waiting interval when there are no new events
+func (r *Reactor[T]) Run(ctx context.Context, interval time.Duration) error {
+	for {
+		n, err := r.Poll(ctx)
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// CommandID returns an id unique to the event, a command issued once per event can use it to be deduplicated
+func CommandID[T any](reactor string, event eventsourcing.Event[T]) string {
+	return fmt.Sprintf("%s:%s:%s:%d", reactor, event.AggregateType, event.AggregateID, event.Version)
+}
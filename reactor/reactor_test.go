@@ -0,0 +1,97 @@
+package reactor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	projmem "github.com/hallgren/eventsourcing/projection/memory"
+	"github.com/hallgren/eventsourcing/reactor"
+)
+
+type OrderPlaced struct{}
+type OrderShipped struct{}
+
+func saveEvents(t *testing.T, es *memory.Memory[any], data ...any) {
+	for i, d := range data {
+		err := es.Save([]eventsourcing.Event[any]{{AggregateID: "order", AggregateType: "Order", Version: eventsourcing.Version(i + 1), Data: d}})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestReactor(t *testing.T) {
+	es := memory.Create[any]()
+	saveEvents(t, es, &OrderPlaced{}, &OrderShipped{}, &OrderPlaced{})
+	checkpoints := projmem.New()
+
+	var commands []string
+	r := reactor.New[any]("reserve-stock", es, checkpoints)
+	r.When(&OrderPlaced{}, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		commands = append(commands, reactor.CommandID("reserve-stock", event))
+		return nil
+	})
+	n, err := r.Poll(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 events got %d", n)
+	}
+	if len(commands) != 2 || commands[0] != "reserve-stock:Order:order:1" {
+		t.Fatalf("expected a command per placed order got %v", commands)
+	}
+	position, _ := checkpoints.Checkpoint(context.Background(), "reserve-stock")
+	if position != 3 {
+		t.Fatalf("expected position 3 got %d", position)
+	}
+
+	// a new reactor continues from the checkpoint
+	n, err = reactor.New[any]("reserve-stock", es, checkpoints).Poll(context.Background())
+	if err != nil || n != 0 {
+		t.Fatalf("expected no events got %d %v", n, err)
+	}
+}
+
+func TestReactorRetry(t *testing.T) {
+	es := memory.Create[any]()
+	saveEvents(t, es, &OrderPlaced{}, &OrderShipped{})
+	checkpoints := projmem.New()
+
+	failing := errors.New("failing")
+	calls := 0
+	r := reactor.New[any]("ship", es, checkpoints, reactor.WithRetry(3, 0))
+	r.When(&OrderPlaced{}, func(ctx context.Context, event eventsourcing.Event[any]) error {
+		calls++
+		return failing
+	})
+
+	// the reactor stops on the failing event
+	n, err := r.Poll(context.Background())
+	if !errors.Is(err, failing) || n != 0 {
+		t.Fatalf("expected the handler error on the first event got %d %v", n, err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts got %d", calls)
+	}
+	position, _ := checkpoints.Checkpoint(context.Background(), "ship")
+	if position != 0 {
+		t.Fatalf("expected no checkpoint got %d", position)
+	}
+
+	// with a failure function the event is skipped
+	var failed []eventsourcing.Event[any]
+	r.OnFailure(func(event eventsourcing.Event[any], err error) {
+		failed = append(failed, event)
+	})
+	n, err = r.Poll(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expected the events to be handled got %d %v", n, err)
+	}
+	if len(failed) != 1 {
+		t.Fatalf("expected one failed event got %d", len(failed))
+	}
+}
@@ -0,0 +1,130 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// RebuildProgress is the number of aggregates handled by Rebuild so far
+type RebuildProgress struct {
+	Rebuilt int
+	Failed  int
+}
+
+// RebuildResult is the outcome of Rebuild
+type RebuildResult struct {
+	Rebuilt int
+	// Failed holds the error of each aggregate that could not be loaded or handled
+	Failed map[string]error
+}
+
+// RebuildOption configures Rebuild
+type RebuildOption func(*rebuildOptions)
+
+type rebuildOptions struct {
+	parallelism int
+	progress    func(RebuildProgress)
+}
+
+// WithRebuildParallelism sets the number of aggregates loaded and handled at the same time, DefaultPreloadParallelism
+// by default. It bounds the number of aggregates held in memory.
+func WithRebuildParallelism(workers int) RebuildOption {
+	return func(o *rebuildOptions) {
+		if workers > 0 {
+			o.parallelism = workers
+		}
+	}
+}
+
+// WithRebuildProgress sets a function called after each aggregate with the progress so far. The calls are not made
+// concurrently.
+func WithRebuildProgress(f func(RebuildProgress)) RebuildOption {
+	return func(o *rebuildOptions) {
+		o.progress = f
+	}
+}
+
+// Rebuild loads the aggregates of type A with the ids read from the channel via the repository and calls handle with
+// each of them, to rebuild caches or migrate aggregates. The ids are read until the channel is closed, a few
+// aggregates at a time, so the ids of millions of aggregates can be streamed from a listing without holding them all.
+//
+// An aggregate that fails to load, or whose handle returns an error or panics, is recorded in the result and the
+// rebuild goes on with the other aggregates. An error is only returned if the context is done.
+func Rebuild[T any, A Aggregate[T]](ctx context.Context, repo *Repository[T], ids <-chan string, handle func(ctx context.Context, aggregate A) error, opts ...RebuildOption) (RebuildResult, error) {
+	var zero A
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return RebuildResult{}, errors.New("aggregate needs to be a pointer")
+	}
+	o := rebuildOptions{parallelism: DefaultPreloadParallelism}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	var (
+		lock     sync.Mutex
+		wg       sync.WaitGroup
+		progress RebuildProgress
+	)
+	result := RebuildResult{Failed: make(map[string]error)}
+	done := func(id string, err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		if err != nil {
+			result.Failed[id] = err
+			progress.Failed++
+		} else {
+			result.Rebuilt++
+			progress.Rebuilt++
+		}
+		if o.progress != nil {
+			o.progress(progress)
+		}
+	}
+	work := make(chan string)
+	for i := 0; i < o.parallelism; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for id := range work {
+				done(id, rebuildOne(ctx, repo, typ.Elem(), id, handle))
+			}
+		}()
+	}
+feed:
+	for {
+		select {
+		case id, ok := <-ids:
+			if !ok {
+				break feed
+			}
+			select {
+			case work <- id:
+			case <-ctx.Done():
+				break feed
+			}
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(work)
+	wg.Wait()
+	return result, ctx.Err()
+}
+
+// rebuildOne loads and handles one aggregate, a panic is returned as an error to not stop the other aggregates
+func rebuildOne[T any, A Aggregate[T]](ctx context.Context, repo *Repository[T], typ reflect.Type, id string, handle func(ctx context.Context, aggregate A) error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rebuild of %s %s panicked: %v", typ.Name(), id, r)
+		}
+	}()
+	aggregate := reflect.New(typ).Interface().(A)
+	err = repo.GetWithContext(ctx, id, aggregate)
+	if err != nil {
+		return err
+	}
+	return handle(ctx, aggregate)
+}
@@ -0,0 +1,83 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestRebuild(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	ids := make(chan string)
+	var saved []string
+	for i := 0; i < 20; i++ {
+		person, err := CreatePerson(fmt.Sprintf("person %d", i))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = repo.Save(person)
+		if err != nil {
+			t.Fatal(err)
+		}
+		saved = append(saved, person.ID())
+	}
+	go func() {
+		for _, id := range saved {
+			ids <- id
+		}
+		ids <- "unknown"
+		close(ids)
+	}()
+
+	var lock sync.Mutex
+	names := make(map[string]struct{})
+	var last eventsourcing.RebuildProgress
+	calls := 0
+	result, err := eventsourcing.Rebuild(context.Background(), repo, ids, func(ctx context.Context, person *Person) error {
+		switch person.Name {
+		case "person 3":
+			return errors.New("handle failed")
+		case "person 7":
+			panic("transition bug")
+		}
+		lock.Lock()
+		defer lock.Unlock()
+		names[person.Name] = struct{}{}
+		return nil
+	}, eventsourcing.WithRebuildParallelism(4), eventsourcing.WithRebuildProgress(func(p eventsourcing.RebuildProgress) {
+		calls++
+		last = p
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Rebuilt != 18 || len(names) != 18 {
+		t.Fatalf("expected 18 rebuilt aggregates got %d", result.Rebuilt)
+	}
+	if len(result.Failed) != 3 || !errors.Is(result.Failed["unknown"], eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected three failures got %v", result.Failed)
+	}
+	if calls != 21 || last != (eventsourcing.RebuildProgress{Rebuilt: 18, Failed: 3}) {
+		t.Fatalf("expected 21 progress calls got %d %+v", calls, last)
+	}
+}
+
+func TestRebuildCanceled(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	// the ids channel is never closed, the rebuild stops on the context
+	ids := make(chan string)
+	go func() {
+		ids <- "1"
+		cancel()
+	}()
+	_, err := eventsourcing.Rebuild(ctx, repo, ids, func(ctx context.Context, person *Person) error { return nil })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled got %v", err)
+	}
+}
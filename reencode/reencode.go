@@ -0,0 +1,139 @@
+// Package reencode moves stored events to another serializer, like from json to protobuf. Copy reads all events of an
+// event store and saves them to a fresh event store opened with the new serializer. Event stores that can rewrite
+// their events in place have their own migration, like MigrateSerializer of the sql event store.
+package reencode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+const defaultBatchSize = 1000
+
+var (
+	// ErrNotDecoded when an event can't be decoded with the current serializer, its type is not registered or its data
+	// is corrupt. Copying stops instead of leaving the event out of the new store.
+	ErrNotDecoded = errors.New("event not decoded")
+
+	// ErrOrderChanged when the target event store does not keep the global order of the copied events, it was not
+	// empty or events were saved to it during the copy
+	ErrOrderChanged = errors.New("global order changed")
+)
+
+// Result is the number of events and aggregates copied
+type Result struct {
+	Events     int
+	Aggregates int
+}
+
+// Option configures Copy
+type Option func(*options)
+
+type options struct {
+	batchSize uint64
+	progress  func(Result)
+}
+
+// WithBatchSize sets the number of events read from the source event store at a time
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		if size > 0 {
+			o.batchSize = size
+		}
+	}
+}
+
+// WithProgress sets a function called after each batch of events with the result so far
+func WithProgress(f func(Result)) Option {
+	return func(o *options) {
+		o.progress = f
+	}
+}
+
+type aggregateKey struct {
+	aggregateType string
+	id            string
+}
+
+// Copy reads all events of the source event store in global order, decodes them with the current serializer from
+// and saves them to the target event store, opened with the new serializer. The source is opened with the current
+// serializer as well, it unmarshals the metadata. The versions, timestamps, valid times,
+// event ids and metadata of the events are kept. The target is expected to be empty, the events are saved in the
+// global order of the source so the target keeps it, the global versions can differ where the source has gaps.
+//
+// The source is read as raw events to not skip events the serializer can't decode, an event that can't be decoded
+// stops the copy with ErrNotDecoded. Events saved to the source during the copy are copied until the last read, stop
+// the writers before switching them to the target.
+func Copy[T any](ctx context.Context, source eventsourcing.RawEventStore, from eventsourcing.Serializer[T], target eventsourcing.EventStore[T], opts ...Option) (Result, error) {
+	o := options{batchSize: defaultBatchSize}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	result := Result{}
+	aggregates := make(map[aggregateKey]struct{})
+	var lastTarget eventsourcing.Version
+	var batch []eventsourcing.Event[T]
+	save := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := target.Save(batch)
+		if err != nil {
+			return fmt.Errorf("copy %s %s: %w", batch[0].AggregateType, batch[0].AggregateID, err)
+		}
+		for _, event := range batch {
+			// event stores not exposing the global version leave it at zero
+			if event.GlobalVersion != 0 && event.GlobalVersion <= lastTarget {
+				return fmt.Errorf("%w: %s %s version %d saved at global version %d after %d", ErrOrderChanged, event.AggregateType, event.AggregateID, event.Version, event.GlobalVersion, lastTarget)
+			}
+			lastTarget = event.GlobalVersion
+		}
+		result.Events += len(batch)
+		batch = nil
+		return nil
+	}
+
+	start := uint64(1)
+	for {
+		if ctx.Err() != nil {
+			return result, ctx.Err()
+		}
+		raws, err := source.GlobalEventsRaw(ctx, start, o.batchSize)
+		if err != nil {
+			return result, err
+		}
+		if len(raws) == 0 {
+			break
+		}
+		for _, raw := range raws {
+			event, ok, err := from.DecodeRaw(raw)
+			if err != nil {
+				return result, err
+			}
+			if _, corrupt := any(event.Data).(*eventsourcing.CorruptData); !ok || corrupt {
+				return result, fmt.Errorf("%w: %s %s version %d reason %s", ErrNotDecoded, raw.AggregateType, raw.AggregateID, raw.Version, raw.Reason)
+			}
+			// keep the global order by saving the consecutive events of an aggregate together
+			if len(batch) > 0 && (batch[0].AggregateType != event.AggregateType || batch[0].AggregateID != event.AggregateID) {
+				if err := save(); err != nil {
+					return result, err
+				}
+			}
+			aggregates[aggregateKey{event.AggregateType, event.AggregateID}] = struct{}{}
+			event.GlobalVersion = 0
+			batch = append(batch, event)
+		}
+		if err := save(); err != nil {
+			return result, err
+		}
+		result.Aggregates = len(aggregates)
+		if o.progress != nil {
+			o.progress(result)
+		}
+		start = uint64(raws[len(raws)-1].GlobalVersion) + 1
+	}
+	return result, nil
+}
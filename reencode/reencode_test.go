@@ -0,0 +1,138 @@
+package reencode_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/reencode"
+)
+
+type Account struct {
+	eventsourcing.AggregateRoot[any]
+}
+
+func (a *Account) Transition(event eventsourcing.Event[any]) {}
+
+type Opened struct {
+	Owner string
+}
+
+type Deposited struct {
+	Amount int
+}
+
+// rawStore holds events marshaled with json
+type rawStore struct {
+	events []eventsourcing.RawEvent
+}
+
+func (s *rawStore) GetRaw(ctx context.Context, id, aggregateType string, afterVersion eventsourcing.Version) ([]eventsourcing.RawEvent, error) {
+	return nil, errors.New("not used")
+}
+
+func (s *rawStore) GlobalEventsRaw(ctx context.Context, start, count uint64) ([]eventsourcing.RawEvent, error) {
+	var events []eventsourcing.RawEvent
+	for _, event := range s.events {
+		if uint64(event.GlobalVersion) >= start && uint64(len(events)) < count {
+			events = append(events, event)
+		}
+	}
+	return events, nil
+}
+
+func (s *rawStore) add(id string, version eventsourcing.Version, data any) {
+	b, _ := json.Marshal(data)
+	s.events = append(s.events, eventsourcing.RawEvent{
+		AggregateID:   id,
+		AggregateType: "Account",
+		Version:       version,
+		// the global versions have a gap
+		GlobalVersion: eventsourcing.Version(len(s.events)*2 + 1),
+		Reason:        eventsourcing.Event[any]{Data: data}.Reason(),
+		Timestamp:     time.Date(2023, 1, 1, 0, 0, len(s.events), 0, time.UTC),
+		Data:          b,
+		Metadata:      map[string]interface{}{"user": "jane"},
+	})
+}
+
+func serializer(t *testing.T) eventsourcing.Serializer[any] {
+	s := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := s.Register(&Account{}, s.Events(&Opened{}, &Deposited{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *s
+}
+
+func TestCopy(t *testing.T) {
+	source := &rawStore{}
+	source.add("1", 1, &Opened{Owner: "jane"})
+	source.add("2", 1, &Opened{Owner: "john"})
+	source.add("1", 2, &Deposited{Amount: 10})
+	source.add("1", 3, &Deposited{Amount: 20})
+	target := memory.Create[any]()
+	var progress []reencode.Result
+	result, err := reencode.Copy[any](context.Background(), source, serializer(t), target, reencode.WithBatchSize(3), reencode.WithProgress(func(r reencode.Result) {
+		progress = append(progress, r)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result != (reencode.Result{Events: 4, Aggregates: 2}) || len(progress) != 2 || progress[0].Events != 3 {
+		t.Fatalf("unexpected result %+v progress %+v", result, progress)
+	}
+	events, err := target.GlobalEvents(1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 4 {
+		t.Fatalf("expected 4 events got %d", len(events))
+	}
+	for i, event := range events {
+		raw := source.events[i]
+		if event.AggregateID != raw.AggregateID || event.Version != raw.Version || !event.Timestamp.Equal(raw.Timestamp) || event.Metadata["user"] != "jane" {
+			t.Fatalf("expected event %d to be copied in global order got %+v", i, event)
+		}
+	}
+	if events[3].Data.(*Deposited).Amount != 20 {
+		t.Fatalf("expected the event data got %+v", events[3].Data)
+	}
+}
+
+func TestCopyNotDecoded(t *testing.T) {
+	source := &rawStore{}
+	source.add("1", 1, &Opened{Owner: "jane"})
+	source.add("1", 2, &struct{ Closed bool }{})
+	source.events[1].Reason = "Closed"
+	_, err := reencode.Copy[any](context.Background(), source, serializer(t), memory.Create[any]())
+	if !errors.Is(err, reencode.ErrNotDecoded) {
+		t.Fatalf("expected ErrNotDecoded got %v", err)
+	}
+}
+
+// unordered sets the same global version on all saved events, a target not keeping the order of the events
+type unordered struct {
+	eventsourcing.EventStore[any]
+}
+
+func (u *unordered) Save(events []eventsourcing.Event[any]) error {
+	for i := range events {
+		events[i].GlobalVersion = 1
+	}
+	return nil
+}
+
+func TestCopyOrderChanged(t *testing.T) {
+	source := &rawStore{}
+	source.add("1", 1, &Opened{Owner: "jane"})
+	source.add("1", 2, &Deposited{Amount: 10})
+	_, err := reencode.Copy[any](context.Background(), source, serializer(t), &unordered{})
+	if !errors.Is(err, reencode.ErrOrderChanged) {
+		t.Fatalf("expected ErrOrderChanged got %v", err)
+	}
+}
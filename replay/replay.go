@@ -0,0 +1,230 @@
+package replay
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type mode int
+
+const (
+	asFastAsPossible mode = iota
+	realTime
+	stepped
+)
+
+const defaultBatchSize = 100
+
+// Option configures the replayer
+type Option func(*options)
+
+type options struct {
+	mode           mode
+	scale          float64
+	batchSize      uint64
+	reasons        map[string]struct{}
+	aggregateTypes map[string]struct{}
+	from, to       time.Time
+}
+
+// WithRealTime replays the events with the time between them scaled by scale, a scale of 2 replays twice as fast
+// as the events happened
+func WithRealTime(scale float64) Option {
+	return func(o *options) {
+		o.mode = realTime
+		o.scale = scale
+	}
+}
+
+// WithStepped replays one event for each call to Step
+func WithStepped() Option {
+	return func(o *options) {
+		o.mode = stepped
+	}
+}
+
+// WithBatchSize sets the number of events read from the event store at a time
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithReasons only replays events with one of the reasons
+func WithReasons(reasons ...string) Option {
+	return func(o *options) {
+		for _, r := range reasons {
+			o.reasons[r] = struct{}{}
+		}
+	}
+}
+
+// WithAggregateTypes only replays events from the aggregate types
+func WithAggregateTypes(types ...string) Option {
+	return func(o *options) {
+		for _, t := range types {
+			o.aggregateTypes[t] = struct{}{}
+		}
+	}
+}
+
+// WithTimeRange only replays events with a timestamp from and including from to and excluding to,
+// a zero time leaves that side open
+func WithTimeRange(from, to time.Time) Option {
+	return func(o *options) {
+		o.from = from
+		o.to = to
+	}
+}
+
+// Replayer re-feeds historical events in global order into the registered handlers
+type Replayer[T any] struct {
+	source   eventsourcing.GlobalEventStore[T]
+	options  options
+	handlers []func(event eventsourcing.Event[T])
+	lock     sync.Mutex
+	paused   chan struct{} // closed on resume, nil when not paused
+	steps    chan struct{}
+}
+
+// New constructs a replayer reading events from source
+func New[T any](source eventsourcing.GlobalEventStore[T], opts ...Option) *Replayer[T] {
+	o := options{
+		batchSize:      defaultBatchSize,
+		scale:          1,
+		reasons:        make(map[string]struct{}),
+		aggregateTypes: make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return &Replayer[T]{
+		source:  source,
+		options: o,
+		steps:   make(chan struct{}, 1),
+	}
+}
+
+// Handle registers a handler receiving the replayed events
+func (r *Replayer[T]) Handle(f func(event eventsourcing.Event[T])) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.handlers = append(r.handlers, f)
+}
+
+// Pause stops the replay before the next event until Resume is called
+func (r *Replayer[T]) Pause() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.paused == nil {
+		r.paused = make(chan struct{})
+	}
+}
+
+// Resume continues a paused replay
+func (r *Replayer[T]) Resume() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.paused != nil {
+		close(r.paused)
+		r.paused = nil
+	}
+}
+
+// Step lets the next event through in a stepped replay. A step is not queued if there already is one waiting.
+func (r *Replayer[T]) Step() {
+	select {
+	case r.steps <- struct{}{}:
+	default:
+	}
+}
+
+// Run replays the events from the global version start until there are no more events or the context is done
+func (r *Replayer[T]) Run(ctx context.Context, start uint64) error {
+	var previous time.Time
+	for {
+		events, err := r.source.GlobalEvents(start, r.options.batchSize)
+		if err != nil {
+			return err
+		}
+		if len(events) == 0 {
+			return nil
+		}
+		for _, event := range events {
+			start = uint64(event.GlobalVersion) + 1
+			if !r.match(event) {
+				continue
+			}
+			err = r.wait(ctx, previous, event.Timestamp)
+			if err != nil {
+				return err
+			}
+			previous = event.Timestamp
+			r.lock.Lock()
+			handlers := r.handlers
+			r.lock.Unlock()
+			for _, h := range handlers {
+				h(event)
+			}
+		}
+	}
+}
+
+// match returns true if the event passes the filters
+func (r *Replayer[T]) match(event eventsourcing.Event[T]) bool {
+	if len(r.options.reasons) > 0 {
+		if _, ok := r.options.reasons[event.Reason()]; !ok {
+			return false
+		}
+	}
+	if len(r.options.aggregateTypes) > 0 {
+		if _, ok := r.options.aggregateTypes[event.AggregateType]; !ok {
+			return false
+		}
+	}
+	if !r.options.from.IsZero() && event.Timestamp.Before(r.options.from) {
+		return false
+	}
+	if !r.options.to.IsZero() && !event.Timestamp.Before(r.options.to) {
+		return false
+	}
+	return true
+}
+
+// wait blocks while the replay is paused and until the event is due in the replay mode
+func (r *Replayer[T]) wait(ctx context.Context, previous, current time.Time) error {
+	r.lock.Lock()
+	paused := r.paused
+	r.lock.Unlock()
+	if paused != nil {
+		select {
+		case <-paused:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+
+	switch r.options.mode {
+	case stepped:
+		select {
+		case <-r.steps:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case realTime:
+		if previous.IsZero() || !current.After(previous) || r.options.scale <= 0 {
+			break
+		}
+		timer := time.NewTimer(time.Duration(float64(current.Sub(previous)) / r.options.scale))
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return ctx.Err()
+}
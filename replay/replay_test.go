@@ -0,0 +1,146 @@
+package replay_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/replay"
+)
+
+type Born struct{}
+type AgedOneYear struct{}
+
+var start = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// store saves a born event followed by aged one year events, one minute apart
+func store(t *testing.T, aged int) *memory.Memory[any] {
+	es := memory.Create[any]()
+	events := []eventsourcing.Event[any]{{AggregateID: "1", AggregateType: "Person", Version: 1, Timestamp: start, Data: &Born{}}}
+	for i := 1; i <= aged; i++ {
+		events = append(events, eventsourcing.Event[any]{
+			AggregateID:   "1",
+			AggregateType: "Person",
+			Version:       eventsourcing.Version(i + 1),
+			Timestamp:     start.Add(time.Duration(i) * time.Minute),
+			Data:          &AgedOneYear{},
+		})
+	}
+	err := es.Save(events)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = es.Save([]eventsourcing.Event[any]{{AggregateID: "2", AggregateType: "Car", Version: 1, Timestamp: start, Data: &Born{}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return es
+}
+
+func TestReplay(t *testing.T) {
+	r := replay.New[any](store(t, 3), replay.WithBatchSize(2))
+	var events []eventsourcing.Event[any]
+	r.Handle(func(e eventsourcing.Event[any]) { events = append(events, e) })
+	err := r.Run(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 5 {
+		t.Fatalf("expected 5 events got %d", len(events))
+	}
+	for i, e := range events {
+		if e.GlobalVersion != eventsourcing.Version(i+1) {
+			t.Fatalf("expected events in global order got %d at %d", e.GlobalVersion, i)
+		}
+	}
+}
+
+func TestReplayFilters(t *testing.T) {
+	r := replay.New[any](store(t, 3),
+		replay.WithAggregateTypes("Person"),
+		replay.WithReasons("AgedOneYear"),
+		replay.WithTimeRange(start.Add(time.Minute), start.Add(3*time.Minute)),
+	)
+	var events []eventsourcing.Event[any]
+	r.Handle(func(e eventsourcing.Event[any]) { events = append(events, e) })
+	err := r.Run(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events got %d", len(events))
+	}
+	if events[0].Version != 2 || events[1].Version != 3 {
+		t.Fatalf("wrong events replayed %d %d", events[0].Version, events[1].Version)
+	}
+}
+
+func TestReplayStepped(t *testing.T) {
+	r := replay.New[any](store(t, 1), replay.WithStepped())
+	received := make(chan eventsourcing.Event[any])
+	r.Handle(func(e eventsourcing.Event[any]) { received <- e })
+	done := make(chan error)
+	go func() { done <- r.Run(context.Background(), 0) }()
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-received:
+			t.Fatal("no event should be replayed before a step")
+		case <-time.After(10 * time.Millisecond):
+		}
+		r.Step()
+		<-received
+	}
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReplayPauseResume(t *testing.T) {
+	r := replay.New[any](store(t, 1))
+	r.Pause()
+	received := make(chan eventsourcing.Event[any], 3)
+	r.Handle(func(e eventsourcing.Event[any]) { received <- e })
+	done := make(chan error)
+	go func() { done <- r.Run(context.Background(), 0) }()
+
+	select {
+	case <-received:
+		t.Fatal("no event should be replayed while paused")
+	case <-time.After(10 * time.Millisecond):
+	}
+	r.Resume()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if len(received) != 3 {
+		t.Fatalf("expected 3 events got %d", len(received))
+	}
+}
+
+func TestReplayRealTime(t *testing.T) {
+	// one minute between the events replayed 6000 times faster is 10ms
+	r := replay.New[any](store(t, 2), replay.WithRealTime(6000), replay.WithAggregateTypes("Person"))
+	r.Handle(func(e eventsourcing.Event[any]) {})
+	begin := time.Now()
+	err := r.Run(context.Background(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(begin); elapsed < 20*time.Millisecond {
+		t.Fatalf("expected the replay to take at least 20ms took %v", elapsed)
+	}
+}
+
+func TestReplayCancel(t *testing.T) {
+	r := replay.New[any](store(t, 1), replay.WithStepped())
+	r.Handle(func(e eventsourcing.Event[any]) {})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := r.Run(ctx, 0)
+	if err != context.Canceled {
+		t.Fatalf("expected context canceled got %v", err)
+	}
+}
@@ -18,6 +18,11 @@ type EventStore[T any] interface {
 	Get(ctx context.Context, id string, aggregateType string, afterVersion Version) (EventIterator[T], error)
 }
 
+// GlobalEventStore is implemented by event stores that can return events in the global order
+type GlobalEventStore[T any] interface {
+	GlobalEvents(start, count uint64) ([]Event[T], error)
+}
+
 // SnapshotStore interface expose the methods an snapshot store must uphold
 type SnapshotStore interface {
 	Save(s Snapshot) error
@@ -44,11 +49,41 @@ var ErrSnapshotNotFound = errors.New("snapshot not found")
 // ErrAggregateNotFound returns if snapshot or event not found for aggregate
 var ErrAggregateNotFound = errors.New("aggregate not found")
 
+// ErrSnapshotAfterSave when the snapshot triggered by the snapshot policy fails, the events are saved in the event store
+var ErrSnapshotAfterSave = errors.New("snapshot after save failed")
+
+// SnapshotAfterSaveError is returned from Save when the events are saved but the snapshot of the aggregate failed.
+// It matches ErrSnapshotAfterSave and unwraps to the snapshot error, like ErrSnapshotQueueFull.
+type SnapshotAfterSaveError struct {
+	Err error
+}
+
+func (e *SnapshotAfterSaveError) Error() string {
+	return ErrSnapshotAfterSave.Error() + ": " + e.Err.Error()
+}
+
+func (e *SnapshotAfterSaveError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes the error match ErrSnapshotAfterSave
+func (e *SnapshotAfterSaveError) Is(target error) bool {
+	return target == ErrSnapshotAfterSave
+}
+
 // Repository is the returned instance from the factory function
 type Repository[T any] struct {
-	eventStream *EventStream[T]
-	eventStore  EventStore[T]
-	snapshot    *SnapshotHandler[T]
+	eventStream    *EventStream[T]
+	eventStore     EventStore[T]
+	snapshot       *SnapshotHandler[T]
+	snapshotPolicy SnapshotPolicy[T]
+	snapshotWorker *SnapshotWorker[T]
+	cache          Cache
+	cacheHandler   *SnapshotHandler[T]
+	locker         Locker
+	afterSave      []AfterSaver[T]
+	aliases        AliasStore
+	replayGuard    bool
 }
 
 // NewRepository factory function
@@ -65,19 +100,107 @@ func (r *Repository[T]) Subscribers() EventSubscribers[T] {
 	return r.eventStream
 }
 
+// SetSnapshotPolicy sets the policy deciding when the repository saves a snapshot of the aggregate after
+// its events are saved. The snapshot is saved via the snapshot worker if set otherwise directly in Save. A failing
+// snapshot is returned from Save as a SnapshotAfterSaveError, the events are saved and the command must not be retried.
+func (r *Repository[T]) SetSnapshotPolicy(policy SnapshotPolicy[T]) {
+	r.snapshotPolicy = policy
+}
+
+// SetSnapshotWorker makes the snapshots triggered by the snapshot policy to be saved in the background
+func (r *Repository[T]) SetSnapshotWorker(worker *SnapshotWorker[T]) {
+	r.snapshotWorker = worker
+}
+
+// SetCache sets an in-process cache of aggregates. Get reads the aggregate from the cache before the snapshot store and
+// fetch only the events after the cached version, Save updates the cached aggregate after the events are saved.
+// The aggregates are serialized in the cache as in a snapshot, to not share aggregate instances between callers.
+func (r *Repository[T]) SetCache(cache Cache, serializer Serializer[T]) {
+	r.cache = cache
+	r.cacheHandler = SnapshotNew(cache, serializer)
+}
+
 // Save an aggregates events
 func (r *Repository[T]) Save(aggregate Aggregate[T]) error {
+	err := beforeSave(aggregate)
+	if err != nil {
+		return err
+	}
 	root := aggregate.Root()
 	// use under laying event slice to set GlobalVersion
-	err := r.eventStore.Save(root.aggregateEvents)
+	err = r.eventStore.Save(root.aggregateEvents)
 	if err != nil {
+		// the cached aggregate could be behind the event store
+		r.invalidateCache(aggregate)
 		return err
 	}
+	return r.saved(aggregate)
+}
+
+// beforeSave calls the BeforeSave hook on the aggregate if it has unsaved events
+func beforeSave[T any](aggregate Aggregate[T]) error {
+	root := aggregate.Root()
+	if h, ok := aggregate.(BeforeSaver[T]); ok && root.UnsavedEvents() {
+		return h.BeforeSave(root.Events())
+	}
+	return nil
+}
+
+// saved publish the saved events of the aggregate, updates its state and runs the after save hooks and snapshot policy
+func (r *Repository[T]) saved(aggregate Aggregate[T]) error {
+	root := aggregate.Root()
+	events := root.Events()
 	// publish the saved events to subscribers
-	r.eventStream.Publish(*root, root.Events())
+	r.eventStream.Publish(*root, events)
 
 	// update the internal aggregate state
 	root.update()
+	if len(events) == 0 {
+		return nil
+	}
+	r.cacheAggregate(aggregate)
+	hookErr := r.afterSaveHooks(events)
+	err := r.policySnapshot(aggregate, events)
+	if hookErr != nil {
+		return hookErr
+	}
+	return err
+}
+
+// cacheAggregate puts the current aggregate state in the cache
+func (r *Repository[T]) cacheAggregate(aggregate Aggregate[T]) {
+	if r.cache == nil {
+		return
+	}
+	err := r.cacheHandler.Save(aggregate)
+	if err != nil {
+		// the cache is best effort, make sure an old state is not left behind
+		r.invalidateCache(aggregate)
+	}
+}
+
+func (r *Repository[T]) invalidateCache(aggregate Aggregate[T]) {
+	if r.cache == nil {
+		return
+	}
+	r.cache.Invalidate(aggregate.Root().ID(), reflect.TypeOf(aggregate).Elem().Name())
+}
+
+// policySnapshot saves a snapshot of the aggregate if the snapshot policy says so, an error is wrapped in
+// SnapshotAfterSaveError as the events are already saved
+func (r *Repository[T]) policySnapshot(aggregate Aggregate[T], events []Event[T]) error {
+	if r.snapshot == nil || r.snapshotPolicy == nil || !r.snapshotPolicy(aggregate, events) {
+		return nil
+	}
+	var err error
+	if r.snapshotWorker != nil {
+		err = r.snapshotWorker.Add(aggregate)
+	} else {
+		err = r.snapshot.Save(aggregate)
+	}
+	if err != nil {
+		return &SnapshotAfterSaveError{Err: err}
+	}
 	return nil
 }
 
@@ -97,17 +220,30 @@ func (r *Repository[T]) GetWithContext(ctx context.Context, id string, aggregate
 	if reflect.ValueOf(aggregate).Kind() != reflect.Ptr {
 		return errors.New("aggregate needs to be a pointer")
 	}
+	aggregateType := reflect.TypeOf(aggregate).Elem().Name()
+	id, err := r.Resolve(ctx, aggregateType, id)
+	if err != nil {
+		return err
+	}
+	// try the cache before the snapshot store
+	cached := false
+	if r.cache != nil {
+		var err error
+		cached, err = loadSnapshot(ctx, r.cacheHandler, id, aggregate)
+		if err != nil {
+			return err
+		}
+	}
+	// the version of the cached aggregate, used to only update the cache when newer events are applied
+	cachedVersion := aggregate.Root().Version()
 	// if there is a snapshot store try fetch aggregate snapshot
-	if r.snapshot != nil {
-		err := r.snapshot.Get(ctx, id, aggregate)
-		if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+	if !cached && r.snapshot != nil {
+		_, err := loadSnapshot(ctx, r.snapshot, id, aggregate)
+		if err != nil {
 			return err
-		} else if ctx.Err() != nil {
-			return ctx.Err()
 		}
 	}
 	root := aggregate.Root()
-	aggregateType := reflect.TypeOf(aggregate).Elem().Name()
 	// fetch events after the current version of the aggregate that could be fetched from the snapshot store
 	eventIterator, err := r.eventStore.Get(ctx, id, aggregateType, root.Version())
 	if err != nil && !errors.Is(err, ErrNoEvents) {
@@ -117,6 +253,11 @@ func (r *Repository[T]) GetWithContext(ctx context.Context, id string, aggregate
 		return ErrAggregateNotFound
 	} else if ctx.Err() != nil {
 		return ctx.Err()
+	} else if errors.Is(err, ErrNoEvents) {
+		// no events after the snapshot
+		afterLoad(aggregate)
+		r.cacheLoaded(cachedVersion, aggregate)
+		return nil
 	}
 	defer eventIterator.Close()
 	for {
@@ -131,14 +272,47 @@ func (r *Repository[T]) GetWithContext(ctx context.Context, id string, aggregate
 				// no events and no snapshot (some eventstore will not return the error ErrNoEvent on Get())
 				return ErrAggregateNotFound
 			} else if errors.Is(err, ErrNoMoreEvents) {
+				afterLoad(aggregate)
+				r.cacheLoaded(cachedVersion, aggregate)
 				return nil
 			}
 			// apply the event on the aggregate
-			root.BuildFromHistory(aggregate, []Event[T]{event})
+			err = r.replay(aggregate, event)
+			if err != nil {
+				return err
+			}
 		}
 	}
 }
 
+// cacheLoaded caches the loaded aggregate if it's newer than the cached version
+func (r *Repository[T]) cacheLoaded(cachedVersion Version, aggregate Aggregate[T]) {
+	if aggregate.Root().Version() == cachedVersion {
+		return
+	}
+	r.cacheAggregate(aggregate)
+}
+
+// loadSnapshot sets the aggregate state from the snapshot handler and returns true if a snapshot was found.
+// A corrupt snapshot is ignored to rebuild the aggregate from its events.
+func loadSnapshot[T any](ctx context.Context, handler *SnapshotHandler[T], id string, aggregate Aggregate[T]) (bool, error) {
+	// keep a copy of the aggregate to fall back to if the snapshot is corrupt
+	value := reflect.ValueOf(aggregate).Elem()
+	initial := reflect.New(value.Type()).Elem()
+	initial.Set(value)
+	err := handler.Get(ctx, id, aggregate)
+	if errors.Is(err, ErrSnapshotCorrupt) {
+		// rebuild the aggregate from all its events instead
+		value.Set(initial)
+		return false, nil
+	} else if errors.Is(err, ErrSnapshotNotFound) {
+		return false, ctx.Err()
+	} else if err != nil {
+		return false, err
+	}
+	return true, ctx.Err()
+}
+
 // Get fetches the aggregates event and build up the aggregate
 // If there is a snapshot store try fetch a snapshot of the aggregate and fetch event after the
 // version of the aggregate if any
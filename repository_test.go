@@ -190,6 +190,63 @@ func TestSaveAndGetAggregateSnapshotAndEventsWithContext(t *testing.T) {
 	}
 }
 
+func TestGetAggregateWithNoEventsAfterSnapshot(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), eventsourcing.SnapshotNew(memsnap.New(), *ser))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.SaveSnapshot(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	twin := Person{}
+	err = repo.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.Version() != person.Version() {
+		t.Fatalf("Wrong version org %q copy %q", person.Version(), twin.Version())
+	}
+}
+
+func TestGetAggregateWithCorruptSnapshot(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	snapshotStore := memsnap.New()
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), eventsourcing.SnapshotNew(snapshotStore, *ser))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = snapshotStore.Save(eventsourcing.Snapshot{ID: person.ID(), Type: "Person", Version: person.Version(), State: []byte("{corrupt")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the aggregate is rebuilt from the events when the snapshot can't be deserialized
+	twin := Person{}
+	err = repo.Get(person.ID(), &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.Version() != person.Version() || twin.Age != person.Age {
+		t.Fatalf("expected the aggregate to be rebuilt from events got version %d age %d", twin.Version(), twin.Age)
+	}
+}
+
 func TestSaveSnapshotWithUnsavedEvents(t *testing.T) {
 	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
 	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), eventsourcing.SnapshotNew(memsnap.New(), *ser))
@@ -0,0 +1,32 @@
+package eventsourcing
+
+// ReuseIterator is implemented by event iterators that can read the next event into an event owned by the caller,
+// reusing its data and metadata instead of allocating them for each event. It cuts the garbage of high volume reads
+// like projection rebuilds. The event, its data and metadata are only valid until the next call, copy what is kept.
+type ReuseIterator[T any] interface {
+	// NextInto reads the next event into event, ErrNoMoreEvents when there are no more events
+	NextInto(event *Event[T]) error
+}
+
+// NextInto reads the next event of the iterator into event, reusing it when the iterator implements ReuseIterator.
+// Other iterators return a new event from Next.
+//
+//	var event eventsourcing.Event[any]
+//	for {
+//		err := eventsourcing.NextInto(iterator, &event)
+//		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+//			break
+//		}
+//		...
+//	}
+func NextInto[T any](iterator EventIterator[T], event *Event[T]) error {
+	if r, ok := iterator.(ReuseIterator[T]); ok {
+		return r.NextInto(event)
+	}
+	e, err := iterator.Next()
+	if err != nil {
+		return err
+	}
+	*event = e
+	return nil
+}
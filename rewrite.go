@@ -0,0 +1,113 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrStreamChanged when events are saved to the stream being rewritten before the alias is swapped
+var ErrStreamChanged = errors.New("stream changed during rewrite")
+
+// ErrRewriteEmpty when the transform drops all events of the stream
+var ErrRewriteEmpty = errors.New("rewrite drops all events")
+
+// Transform rewrites an event copied to the new stream, returning false drops the event
+type Transform[T any] func(event Event[T]) (Event[T], bool)
+
+// Rewrite copies the stream of the aggregate to a new stream with the newID, passing each event through the
+// transform, and points the current stream id to the new stream in the alias store. The original stream is kept
+// unchanged for audit. It's the remedy for events with bad data that can't be fixed by new events.
+//
+// The copied events are renumbered from version one and keep their timestamp and metadata, they get new event ids as
+// the event id is unique in the event store. If a locker is set the
+// aggregate lock is held during the rewrite, if events are still saved to the original stream during the copy
+// ErrStreamChanged is returned without the alias being set.
+func (r *Repository[T]) Rewrite(ctx context.Context, aggregateType, id, newID string, transform Transform[T]) (err error) {
+	if r.aliases == nil {
+		return errors.New("no alias store has been set")
+	}
+	current, err := r.Resolve(ctx, aggregateType, id)
+	if err != nil {
+		return err
+	}
+	if r.locker != nil {
+		unlock, err := r.locker.Lock(ctx, aggregateType, current)
+		if err != nil {
+			return err
+		}
+		defer func() {
+			unlockErr := unlock()
+			if err == nil {
+				err = unlockErr
+			}
+		}()
+	}
+
+	events, err := r.streamEvents(ctx, aggregateType, current, 0)
+	if err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return ErrAggregateNotFound
+	}
+	lastVersion := events[len(events)-1].Version
+
+	var copied []Event[T]
+	for _, event := range events {
+		event, ok := transform(event)
+		if !ok {
+			continue
+		}
+		event.EventID = NewEventID()
+		event.AggregateID = newID
+		event.AggregateType = aggregateType
+		event.Version = Version(len(copied) + 1)
+		event.GlobalVersion = 0
+		copied = append(copied, event)
+	}
+	if len(copied) == 0 {
+		return ErrRewriteEmpty
+	}
+	err = r.eventStore.Save(copied)
+	if err != nil {
+		return err
+	}
+
+	after, err := r.streamEvents(ctx, aggregateType, current, lastVersion)
+	if err != nil {
+		return err
+	}
+	if len(after) > 0 {
+		return fmt.Errorf("%w: %d events saved to %s %s", ErrStreamChanged, len(after), aggregateType, current)
+	}
+	err = r.aliases.SetAlias(ctx, aggregateType, current, newID)
+	if err != nil {
+		return err
+	}
+	if r.cache != nil {
+		r.cache.Invalidate(current, aggregateType)
+	}
+	return nil
+}
+
+// streamEvents returns the events of the stream after the version
+func (r *Repository[T]) streamEvents(ctx context.Context, aggregateType, id string, afterVersion Version) ([]Event[T], error) {
+	iterator, err := r.eventStore.Get(ctx, id, aggregateType, afterVersion)
+	if errors.Is(err, ErrNoEvents) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	var events []Event[T]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
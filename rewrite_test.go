@@ -0,0 +1,118 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	aliasmem "github.com/hallgren/eventsourcing/alias/memory"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestRewrite(t *testing.T) {
+	es := memory.Create[any]()
+	repo := eventsourcing.NewRepository[any](es, nil)
+	repo.SetAliasStore(aliasmem.New())
+
+	ledger := &Ledger{}
+	ledger.SetID("ledger")
+	ledger.TrackChange(ledger, &Opened{Balance: 10})
+	ledger.TrackChange(ledger, &Deposited{Amount: 1000000})
+	ledger.TrackChange(ledger, &Deposited{Amount: 5})
+	err := repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// drop the deposit with bad data and fix the opening balance
+	err = repo.Rewrite(context.Background(), "Ledger", "ledger", "ledger-fixed", func(e eventsourcing.Event[any]) (eventsourcing.Event[any], bool) {
+		switch d := e.Data.(type) {
+		case *Deposited:
+			return e, d.Amount < 1000
+		case *Opened:
+			e.Data = &Opened{Balance: 20}
+		}
+		return e, true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	l := Ledger{}
+	err = repo.Get("ledger", &l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l.ID() != "ledger-fixed" || l.Balance != 25 || l.Version() != 2 {
+		t.Fatalf("expected the rewritten ledger got id %s balance %d version %d", l.ID(), l.Balance, l.Version())
+	}
+
+	// the original stream is kept
+	iter, err := es.Get(context.Background(), "ledger", "Ledger", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	count := 0
+	for _, err = iter.Next(); err == nil; _, err = iter.Next() {
+		count++
+	}
+	if count != 3 {
+		t.Fatalf("expected the original 3 events got %d", count)
+	}
+}
+
+func TestRewriteDropAll(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](memory.Create[any](), nil)
+	repo.SetAliasStore(aliasmem.New())
+	ledger := &Ledger{}
+	ledger.TrackChange(ledger, &Opened{Balance: 10})
+	err := repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Rewrite(context.Background(), "Ledger", ledger.ID(), "new", func(e eventsourcing.Event[any]) (eventsourcing.Event[any], bool) {
+		return e, false
+	})
+	if !errors.Is(err, eventsourcing.ErrRewriteEmpty) {
+		t.Fatalf("expected ErrRewriteEmpty got %v", err)
+	}
+}
+
+// writerDuringCopy saves an event to the original stream when the copy is saved
+type writerDuringCopy struct {
+	*memory.Memory[any]
+}
+
+func (s writerDuringCopy) Save(events []eventsourcing.Event[any]) error {
+	if events[0].AggregateID == "new" {
+		err := s.Memory.Save([]eventsourcing.Event[any]{{AggregateID: "ledger", AggregateType: "Ledger", Version: 2, Data: &Deposited{Amount: 1}}})
+		if err != nil {
+			return err
+		}
+	}
+	return s.Memory.Save(events)
+}
+
+func TestRewriteStreamChanged(t *testing.T) {
+	aliases := aliasmem.New()
+	repo := eventsourcing.NewRepository[any](writerDuringCopy{memory.Create[any]()}, nil)
+	repo.SetAliasStore(aliases)
+	ledger := &Ledger{}
+	ledger.SetID("ledger")
+	ledger.TrackChange(ledger, &Opened{Balance: 10})
+	err := repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Rewrite(context.Background(), "Ledger", "ledger", "new", func(e eventsourcing.Event[any]) (eventsourcing.Event[any], bool) {
+		return e, true
+	})
+	if !errors.Is(err, eventsourcing.ErrStreamChanged) {
+		t.Fatalf("expected ErrStreamChanged got %v", err)
+	}
+	if _, ok, _ := aliases.Alias(context.Background(), "Ledger", "ledger"); ok {
+		t.Fatal("expected no alias to be set")
+	}
+}
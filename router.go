@@ -0,0 +1,81 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrApplyMethodMissing when the aggregate has no Apply<Reason> method for a registered event
+var ErrApplyMethodMissing = errors.New("apply method missing")
+
+// ErrApplyMethodSignature when the Apply<Reason> method does not take the event data or the event as its only parameter
+var ErrApplyMethodSignature = errors.New("apply method has the wrong signature")
+
+const applyMethodPrefix = "Apply"
+
+// Router routes events to methods on the aggregate named Apply followed by the event reason.
+// The method takes either the event data or the event as its only parameter.
+//
+//	func (a *Account) ApplyAccountOpened(e *AccountOpened)
+//	func (a *Account) ApplyMoneyDeposited(e eventsourcing.Event[AccountEvent])
+//
+// The methods are looked up once when the router is created and validated against the events,
+// making missing methods an error at startup instead of a silently ignored event.
+type Router[T any] struct {
+	routes map[string]route
+}
+
+type route struct {
+	method     reflect.Value
+	wholeEvent bool
+}
+
+// NewRouter builds the router from the aggregate and the events it should handle
+func NewRouter[T any](aggregate Aggregate[T], events []eventFunc[T]) (*Router[T], error) {
+	typ := reflect.TypeOf(aggregate)
+	eventType := reflect.TypeOf(Event[T]{})
+	routes := make(map[string]route)
+	for _, f := range events {
+		data := f()
+		name := reason(data)
+		if name == "" {
+			return nil, ErrEventNameMissing
+		}
+		method, ok := typ.MethodByName(applyMethodPrefix + name)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s.%s%s", ErrApplyMethodMissing, typ.Elem().Name(), applyMethodPrefix, name)
+		}
+		// the first in parameter is the receiver
+		if method.Type.NumIn() != 2 || method.Type.NumOut() != 0 {
+			return nil, fmt.Errorf("%w: %s.%s", ErrApplyMethodSignature, typ.Elem().Name(), method.Name)
+		}
+		in := method.Type.In(1)
+		switch {
+		case in == eventType:
+			routes[name] = route{method: method.Func, wholeEvent: true}
+		case in == reflect.TypeOf(data):
+			routes[name] = route{method: method.Func}
+		default:
+			return nil, fmt.Errorf("%w: %s.%s", ErrApplyMethodSignature, typ.Elem().Name(), method.Name)
+		}
+	}
+	return &Router[T]{routes: routes}, nil
+}
+
+// Route calls the Apply method of the event on the aggregate.
+// Returns false if there is no method registered for the event.
+func (r *Router[T]) Route(aggregate Aggregate[T], event Event[T]) bool {
+	rt, ok := r.routes[event.Reason()]
+	if !ok {
+		return false
+	}
+	var in reflect.Value
+	if rt.wholeEvent {
+		in = reflect.ValueOf(event)
+	} else {
+		in = reflect.ValueOf(event.Data)
+	}
+	rt.method.Call([]reflect.Value{reflect.ValueOf(aggregate), in})
+	return true
+}
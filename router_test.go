@@ -0,0 +1,71 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+var personRouter *eventsourcing.Router[PersonEvent]
+
+// RoutedPerson builds its state via Apply methods instead of a switch in Transition
+type RoutedPerson struct {
+	eventsourcing.AggregateRoot[PersonEvent]
+	Name string
+	Age  int
+}
+
+func (p *RoutedPerson) Transition(event eventsourcing.Event[PersonEvent]) {
+	personRouter.Route(p, event)
+}
+
+func (p *RoutedPerson) ApplyBorn(e *Born) {
+	p.Name = e.Name
+}
+
+func (p *RoutedPerson) ApplyAgedOneYear(e eventsourcing.Event[PersonEvent]) {
+	p.Age++
+}
+
+// BadPerson miss the ApplyAgedOneYear method and has the wrong signature on ApplyBorn
+type BadPerson struct {
+	eventsourcing.AggregateRoot[PersonEvent]
+}
+
+func (p *BadPerson) Transition(event eventsourcing.Event[PersonEvent]) {}
+
+func (p *BadPerson) ApplyBorn(e Born) {}
+
+func TestRouter(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	var err error
+	personRouter, err = eventsourcing.NewRouter[PersonEvent](&RoutedPerson{}, ser.Events(&Born{}, &AgedOneYear{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := RoutedPerson{}
+	p.TrackChange(&p, &Born{Name: "kalle"})
+	p.TrackChange(&p, &AgedOneYear{})
+	p.TrackChange(&p, &AgedOneYear{})
+
+	if p.Name != "kalle" {
+		t.Fatalf("expected name kalle got %s", p.Name)
+	}
+	if p.Age != 2 {
+		t.Fatalf("expected age 2 got %d", p.Age)
+	}
+}
+
+func TestRouterValidation(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	_, err := eventsourcing.NewRouter[PersonEvent](&BadPerson{}, ser.Events(&AgedOneYear{}))
+	if !errors.Is(err, eventsourcing.ErrApplyMethodMissing) {
+		t.Fatalf("expected ErrApplyMethodMissing got %v", err)
+	}
+	_, err = eventsourcing.NewRouter[PersonEvent](&BadPerson{}, ser.Events(&Born{}))
+	if !errors.Is(err, eventsourcing.ErrApplyMethodSignature) {
+		t.Fatalf("expected ErrApplyMethodSignature got %v", err)
+	}
+}
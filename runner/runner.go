@@ -0,0 +1,220 @@
+// Package runner starts and stops the long running components of a service in order.
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrStarted when the runner is started more than once
+var ErrStarted = errors.New("runner already started")
+
+// ErrFailed when components of the runner have failed
+var ErrFailed = errors.New("components failed")
+
+// State is the state of a component
+type State string
+
+const (
+	// Pending components are added but not started
+	Pending State = "pending"
+	// Running components are started and have not returned
+	Running State = "running"
+	// Stopping components are canceled and not yet returned
+	Stopping State = "stopping"
+	// Stopped components returned after they were canceled, or their stop function was called
+	Stopped State = "stopped"
+	// Failed components returned an error, or returned before they were canceled
+	Failed State = "failed"
+)
+
+// Status is the state of a component at a point in time
+type Status struct {
+	Name  string
+	State State
+	// Since is the time the component entered the state
+	Since time.Time
+	// Err is the error of a failed component
+	Err error
+}
+
+type component struct {
+	name   string
+	run    func(ctx context.Context) error
+	stop   func(ctx context.Context) error
+	cancel context.CancelFunc
+	done   chan struct{}
+	status Status
+}
+
+// Runner owns the components of a service. Components running until their context is done, like projections,
+// reactors and subscriptions, are added with Add. Components to close when all of them have returned, like the event
+// store, snapshot workers and dispatchers, are added with OnStop.
+//
+// Stop shuts the components down in order. The running components are canceled one at a time in the reverse order
+// they were added, waiting for each to return, so components taking in work added last are stopped before the
+// components they hand work to. The stop functions are then called in reverse order.
+type Runner struct {
+	lock       sync.Mutex
+	started    bool
+	components []*component
+}
+
+// New constructs a runner without components
+func New() *Runner {
+	return &Runner{}
+}
+
+// Add adds a component running until its context is done. Returning nil or the context error after the context is
+// done stops it, any other return fails it.
+func (r *Runner) Add(name string, run func(ctx context.Context) error) {
+	r.add(&component{name: name, run: run})
+}
+
+// OnStop adds a function called when Stop has stopped the running components, to flush and close the component
+func (r *Runner) OnStop(name string, stop func(ctx context.Context) error) {
+	r.add(&component{name: name, stop: stop})
+}
+
+func (r *Runner) add(c *component) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	c.status = Status{Name: c.name, State: Pending, Since: time.Now()}
+	r.components = append(r.components, c)
+}
+
+// Start starts the running components in the order they were added, they run until Stop is called or the context
+// is done
+func (r *Runner) Start(ctx context.Context) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if r.started {
+		return ErrStarted
+	}
+	r.started = true
+	for _, c := range r.components {
+		if c.run == nil {
+			continue
+		}
+		runCtx, cancel := context.WithCancel(ctx)
+		c.cancel = cancel
+		c.done = make(chan struct{})
+		c.status = Status{Name: c.name, State: Running, Since: time.Now()}
+		go r.run(runCtx, c)
+	}
+	return nil
+}
+
+// run runs the component and sets its state when it returns
+func (r *Runner) run(ctx context.Context, c *component) {
+	defer close(c.done)
+	err := c.run(ctx)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if ctx.Err() != nil && (err == nil || errors.Is(err, ctx.Err())) {
+		c.status = Status{Name: c.name, State: Stopped, Since: time.Now()}
+		return
+	}
+	if err == nil {
+		err = errors.New("returned before it was stopped")
+	}
+	c.status = Status{Name: c.name, State: Failed, Since: time.Now(), Err: err}
+}
+
+// Stop cancels the running components in reverse order waiting for each to return and then calls the stop functions
+// in reverse order. The context bounds the time to wait, the components not stopped when it's done are left behind.
+// It returns the first error of a failed component or stop function.
+func (r *Runner) Stop(ctx context.Context) error {
+	r.lock.Lock()
+	components := append([]*component{}, r.components...)
+	r.lock.Unlock()
+
+	var first error
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if c.run == nil || c.done == nil {
+			continue
+		}
+		r.setState(c, Stopping, nil)
+		c.cancel()
+		select {
+		case <-c.done:
+		case <-ctx.Done():
+			return fmt.Errorf("stopping %s: %w", c.name, ctx.Err())
+		}
+		if status := r.status(c); status.State == Failed && first == nil {
+			first = fmt.Errorf("%s: %w", c.name, status.Err)
+		}
+	}
+	for i := len(components) - 1; i >= 0; i-- {
+		c := components[i]
+		if c.stop == nil {
+			continue
+		}
+		err := c.stop(ctx)
+		if err != nil {
+			r.setState(c, Failed, err)
+			if first == nil {
+				first = fmt.Errorf("%s: %w", c.name, err)
+			}
+			continue
+		}
+		r.setState(c, Stopped, nil)
+	}
+	return first
+}
+
+// Health returns the status of the components in the order they were added
+func (r *Runner) Health() []Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	statuses := make([]Status, len(r.components))
+	for i, c := range r.components {
+		statuses[i] = c.status
+	}
+	return statuses
+}
+
+// Healthy returns true if no component has failed
+func (r *Runner) Healthy() bool {
+	for _, status := range r.Health() {
+		if status.State == Failed {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckHealth returns an error naming the failed components
+func (r *Runner) CheckHealth(ctx context.Context) error {
+	var failed []string
+	for _, status := range r.Health() {
+		if status.State == Failed {
+			failed = append(failed, fmt.Sprintf("%s: %v", status.Name, status.Err))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%w: %s", ErrFailed, strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+func (r *Runner) setState(c *component, state State, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if state == Stopping && c.status.State != Running {
+		// the component already returned on its own
+		return
+	}
+	c.status = Status{Name: c.name, State: state, Since: time.Now(), Err: err}
+}
+
+func (r *Runner) status(c *component) Status {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return c.status
+}
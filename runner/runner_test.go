@@ -0,0 +1,146 @@
+package runner_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing/runner"
+)
+
+// order records the order the components are stopped in
+type order struct {
+	lock  sync.Mutex
+	names []string
+}
+
+func (o *order) add(name string) {
+	o.lock.Lock()
+	defer o.lock.Unlock()
+	o.names = append(o.names, name)
+}
+
+func (o *order) running(name string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		<-ctx.Done()
+		o.add(name)
+		return ctx.Err()
+	}
+}
+
+func TestRunner(t *testing.T) {
+	o := &order{}
+	r := runner.New()
+	r.OnStop("event store", func(ctx context.Context) error {
+		o.add("event store")
+		return nil
+	})
+	r.OnStop("snapshot worker", func(ctx context.Context) error {
+		o.add("snapshot worker")
+		return nil
+	})
+	r.Add("projection", o.running("projection"))
+	r.Add("http", o.running("http"))
+
+	ctx := context.Background()
+	err := r.Start(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !errors.Is(r.Start(ctx), runner.ErrStarted) {
+		t.Fatal("expected the second start to fail")
+	}
+	for _, status := range r.Health() {
+		if status.Name == "http" && status.State != runner.Running {
+			t.Fatalf("expected http to be running got %s", status.State)
+		}
+	}
+
+	err = r.Stop(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"http", "projection", "snapshot worker", "event store"}
+	for i, name := range expected {
+		if o.names[i] != name {
+			t.Fatalf("expected the stop order %v got %v", expected, o.names)
+		}
+	}
+	for _, status := range r.Health() {
+		if status.State != runner.Stopped {
+			t.Fatalf("expected %s to be stopped got %s", status.Name, status.State)
+		}
+	}
+}
+
+func TestRunnerFailure(t *testing.T) {
+	failure := errors.New("database gone")
+	r := runner.New()
+	r.Add("reactor", func(ctx context.Context) error {
+		return failure
+	})
+	r.Add("projection", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+	err := r.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.Healthy() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	status := r.Health()[0]
+	if status.State != runner.Failed || !errors.Is(status.Err, failure) {
+		t.Fatalf("expected the reactor to fail got %+v", status)
+	}
+	err = r.Stop(context.Background())
+	if !errors.Is(err, failure) {
+		t.Fatalf("expected the failure from stop got %v", err)
+	}
+}
+
+func TestRunnerStopTimeout(t *testing.T) {
+	r := runner.New()
+	block := make(chan struct{})
+	defer close(block)
+	r.Add("stuck", func(ctx context.Context) error {
+		<-block
+		return nil
+	})
+	err := r.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err = r.Stop(ctx)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the stop to time out got %v", err)
+	}
+	if r.Health()[0].State != runner.Stopping {
+		t.Fatalf("expected the component to be stopping got %s", r.Health()[0].State)
+	}
+}
+
+func TestRunnerCheckHealth(t *testing.T) {
+	r := runner.New()
+	r.Add("reactor", func(ctx context.Context) error {
+		return errors.New("database gone")
+	})
+	err := r.Start(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for r.Healthy() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	err = r.CheckHealth(context.Background())
+	if !errors.Is(err, runner.ErrFailed) {
+		t.Fatalf("expected the failed reactor to be reported got %v", err)
+	}
+}
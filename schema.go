@@ -0,0 +1,73 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrSchemaViolation is returned when the marshaled event data doesn't match the schema registered for its reason
+var ErrSchemaViolation = errors.New("schema violation")
+
+// Schema validates the marshaled data of an event
+type Schema interface {
+	Validate(data []byte) error
+}
+
+// SchemaFunc is a function validating the marshaled data of an event
+type SchemaFunc func(data []byte) error
+
+// Validate calls the function
+func (f SchemaFunc) Validate(data []byte) error {
+	return f(data)
+}
+
+type schemaRegistry struct {
+	schemas map[string]Schema
+	onRead  bool
+}
+
+// RegisterSchema registers the schema of the event data of the reason on the aggregate type. The event stores validate
+// the data against it when events are saved, catching producers that drift from the agreed event format before the
+// events are stored.
+func (h *Serializer[T]) RegisterSchema(aggregateType, reason string, schema Schema) {
+	h.schemas.schemas[aggregateType+"_"+reason] = schema
+}
+
+// SetValidateOnRead sets if the event data is validated against the registered schemas also when events are read. A
+// violation on read is handled as a decode error by the decode error policy.
+func (h *Serializer[T]) SetValidateOnRead(enabled bool) {
+	h.schemas.onRead = enabled
+}
+
+// MarshalEvent marshals the event data and validates it against the schema registered for the reason of the event,
+// before the data is encrypted if a cipher is set
+func (h *Serializer[T]) MarshalEvent(event Event[T]) ([]byte, error) {
+	data, err := h.marshal(event.Data)
+	if err != nil {
+		return nil, err
+	}
+	err = h.validateSchema(event.AggregateType, event.Reason(), data)
+	if err != nil {
+		return nil, err
+	}
+	return h.encrypt(data)
+}
+
+func (h *Serializer[T]) validateSchema(aggregateType, reason string, data []byte) error {
+	if h.schemas == nil {
+		return nil
+	}
+	schema, ok := h.schemas.schemas[aggregateType+"_"+reason]
+	if !ok {
+		return nil
+	}
+	err := schema.Validate(data)
+	if err != nil {
+		return fmt.Errorf("%w %s %s: %v", ErrSchemaViolation, aggregateType, reason, err)
+	}
+	return nil
+}
+
+func (h *Serializer[T]) validateOnRead() bool {
+	return h.schemas != nil && h.schemas.onRead
+}
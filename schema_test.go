@@ -0,0 +1,60 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func TestSchema(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Person{}, ser.Events(&Born{}, &AgedOneYear{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ser.RegisterSchema("Person", "Born", eventsourcing.SchemaFunc(func(data []byte) error {
+		born := map[string]interface{}{}
+		err := json.Unmarshal(data, &born)
+		if err != nil {
+			return err
+		}
+		if born["Name"] == "" {
+			return fmt.Errorf("missing name")
+		}
+		return nil
+	}))
+
+	_, err = ser.MarshalEvent(eventsourcing.Event[PersonEvent]{AggregateType: "Person", Data: &Born{}})
+	if !errors.Is(err, eventsourcing.ErrSchemaViolation) {
+		t.Fatalf("expected a schema violation got %v", err)
+	}
+	data, err := ser.MarshalEvent(eventsourcing.Event[PersonEvent]{AggregateType: "Person", Data: &Born{Name: "kalle"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// events without a schema are not validated
+	_, err = ser.MarshalEvent(eventsourcing.Event[PersonEvent]{AggregateType: "Person", Data: &AgedOneYear{}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the data is validated on read only when enabled
+	event := eventsourcing.Event[PersonEvent]{AggregateType: "Person", AggregateID: "1", Version: 1}
+	ok, err := ser.UnmarshalEvent(&event, "Born", []byte(`{"Name":""}`))
+	if err != nil || !ok {
+		t.Fatalf("expected the event to unmarshal got %v %v", ok, err)
+	}
+	ser.SetValidateOnRead(true)
+	_, err = ser.UnmarshalEvent(&event, "Born", []byte(`{"Name":""}`))
+	decodeErr := &eventsourcing.DecodeError{}
+	if !errors.As(err, &decodeErr) || !errors.Is(err, eventsourcing.ErrSchemaViolation) {
+		t.Fatalf("expected a decode error with the schema violation got %v", err)
+	}
+	ok, err = ser.UnmarshalEvent(&event, "Born", data)
+	if err != nil || !ok {
+		t.Fatalf("expected the valid event to unmarshal got %v %v", ok, err)
+	}
+}
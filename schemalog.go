@@ -0,0 +1,215 @@
+package eventsourcing
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ErrIncompatibleSchema when a registered event type changed incompatibly since its last fingerprint in the schema
+// changelog without a new upcaster
+var ErrIncompatibleSchema = errors.New("incompatible event schema change")
+
+// EventFingerprint is the shape of a registered event type, the paths of its fields as named in the marshaled data
+// mapped to their types. Fields of nested structs are joined with a dot, [] marks the elements of slices and {} the
+// values of maps.
+type EventFingerprint struct {
+	AggregateType string            `json:"aggregate_type"`
+	Reason        string            `json:"reason"`
+	Fields        map[string]string `json:"fields"`
+	// Upcasters is the number of upcasters registered for the reason when the fingerprint was recorded
+	Upcasters int `json:"upcasters"`
+}
+
+// SchemaChangelog is the history of the fingerprints of the event types, oldest first. A fingerprint is recorded when
+// an event type is registered for the first time and each time it changes. Keep the changelog with the code, like a
+// JSON file next to the event types.
+type SchemaChangelog struct {
+	Events []EventFingerprint `json:"events"`
+}
+
+// ReadSchemaChangelog reads a changelog written by SchemaChangelog.Write
+func ReadSchemaChangelog(r io.Reader) (SchemaChangelog, error) {
+	c := SchemaChangelog{}
+	err := json.NewDecoder(r).Decode(&c)
+	if err != nil {
+		return SchemaChangelog{}, fmt.Errorf("schema changelog: %w", err)
+	}
+	return c, nil
+}
+
+// Write writes the changelog as indented JSON
+func (c SchemaChangelog) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(c)
+}
+
+// latest returns the last fingerprint of each event type
+func (c SchemaChangelog) latest() map[eventName]EventFingerprint {
+	latest := make(map[eventName]EventFingerprint)
+	for _, f := range c.Events {
+		latest[eventName{aggregateType: f.AggregateType, reason: f.Reason}] = f
+	}
+	return latest
+}
+
+// SchemaChange is an incompatible change of an event type, fields removed or changed type. Old events keep the
+// removed fields and the old types in their data, they are silently lost or fail to unmarshal without an upcaster.
+type SchemaChange struct {
+	AggregateType string
+	Reason        string
+	Removed       []string
+	// Changed are the fields that changed type, as "field: old type -> new type"
+	Changed []string
+}
+
+// SchemaError lists the incompatible changes found by CheckSchema
+type SchemaError struct {
+	Changes []SchemaChange
+}
+
+func (e *SchemaError) Error() string {
+	var b strings.Builder
+	b.WriteString(ErrIncompatibleSchema.Error())
+	for _, c := range e.Changes {
+		fmt.Fprintf(&b, "; %s %s", c.AggregateType, c.Reason)
+		if len(c.Removed) > 0 {
+			fmt.Fprintf(&b, " removed %s", strings.Join(c.Removed, ", "))
+		}
+		if len(c.Changed) > 0 {
+			fmt.Fprintf(&b, " changed %s", strings.Join(c.Changed, ", "))
+		}
+	}
+	return b.String()
+}
+
+func (e *SchemaError) Unwrap() error {
+	return ErrIncompatibleSchema
+}
+
+// Fingerprints returns the fingerprints of the registered event types sorted on aggregate type and reason. The fields
+// are found like encoding/json names them, types marshaling themselves like time.Time are not looked into.
+func (h *Serializer[T]) Fingerprints() []EventFingerprint {
+	fingerprints := make([]EventFingerprint, 0, len(h.eventNames))
+	for key, name := range h.eventNames {
+		fields := make(map[string]string)
+		fingerprintFields(h.eventTypes[key], "", fields, make(map[reflect.Type]bool))
+		fingerprints = append(fingerprints, EventFingerprint{
+			AggregateType: name.aggregateType,
+			Reason:        name.reason,
+			Fields:        fields,
+			Upcasters:     len(h.upcasters[key]),
+		})
+	}
+	sort.Slice(fingerprints, func(i, j int) bool {
+		if fingerprints[i].AggregateType != fingerprints[j].AggregateType {
+			return fingerprints[i].AggregateType < fingerprints[j].AggregateType
+		}
+		return fingerprints[i].Reason < fingerprints[j].Reason
+	})
+	return fingerprints
+}
+
+// CheckSchema compares the registered event types to their last fingerprint in the changelog and returns a
+// *SchemaError, matching ErrIncompatibleSchema, if fields were removed or changed type without an upcaster registered
+// for the reason since the fingerprint was recorded. Added fields and event types not in the changelog are compatible.
+// Call it at startup or in a test to stop incompatible changes before they reach production.
+func (h *Serializer[T]) CheckSchema(changelog SchemaChangelog) error {
+	latest := changelog.latest()
+	var changes []SchemaChange
+	for _, current := range h.Fingerprints() {
+		recorded, ok := latest[eventName{aggregateType: current.AggregateType, reason: current.Reason}]
+		if !ok || current.Upcasters > recorded.Upcasters {
+			continue
+		}
+		change := SchemaChange{AggregateType: current.AggregateType, Reason: current.Reason}
+		for _, field := range sortedKeys(recorded.Fields) {
+			typ, ok := current.Fields[field]
+			if !ok {
+				change.Removed = append(change.Removed, field)
+			} else if typ != recorded.Fields[field] {
+				change.Changed = append(change.Changed, fmt.Sprintf("%s: %s -> %s", field, recorded.Fields[field], typ))
+			}
+		}
+		if len(change.Removed) > 0 || len(change.Changed) > 0 {
+			changes = append(changes, change)
+		}
+	}
+	if len(changes) > 0 {
+		return &SchemaError{Changes: changes}
+	}
+	return nil
+}
+
+// RecordSchema checks the schema like CheckSchema and returns the changelog with the fingerprints of the new and
+// changed event types appended
+func (h *Serializer[T]) RecordSchema(changelog SchemaChangelog) (SchemaChangelog, error) {
+	err := h.CheckSchema(changelog)
+	if err != nil {
+		return changelog, err
+	}
+	latest := changelog.latest()
+	events := append([]EventFingerprint{}, changelog.Events...)
+	for _, current := range h.Fingerprints() {
+		recorded, ok := latest[eventName{aggregateType: current.AggregateType, reason: current.Reason}]
+		if ok && recorded.Upcasters == current.Upcasters && reflect.DeepEqual(recorded.Fields, current.Fields) {
+			continue
+		}
+		events = append(events, current)
+	}
+	return SchemaChangelog{Events: events}, nil
+}
+
+// fingerprintFields adds the paths and types of the fields of t to fields
+func fingerprintFields(t reflect.Type, path string, fields map[string]string, seen map[reflect.Type]bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch {
+	case marshalsItself(t):
+		fields[path] = t.String()
+	case t.Kind() == reflect.Struct:
+		if seen[t] {
+			// a recursive type
+			fields[path] = t.String()
+			return
+		}
+		seen[t] = true
+		defer delete(seen, t)
+		for _, f := range jsonFieldsOf(t) {
+			name := f.name
+			if path != "" {
+				name = path + "." + f.name
+			}
+			if f.quoted {
+				fields[name] = "string"
+				continue
+			}
+			fingerprintFields(f.typ, name, fields, seen)
+		}
+	case (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) && t.Elem().Kind() == reflect.Uint8:
+		fields[path] = "bytes"
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		fingerprintFields(t.Elem(), path+"[]", fields, seen)
+	case t.Kind() == reflect.Map:
+		fingerprintFields(t.Elem(), path+"{}", fields, seen)
+	case t.Kind() == reflect.Interface:
+		fields[path] = "any"
+	default:
+		fields[path] = t.Kind().String()
+	}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,137 @@
+package eventsourcing_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func serializerOf(t *testing.T, events ...any) *eventsourcing.Serializer[any] {
+	t.Helper()
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Untyped{}, ser.Events(events...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ser
+}
+
+type Location struct {
+	Street string
+	Zip    int `json:"zip,string"`
+}
+
+type Moved struct {
+	To      Location
+	Before  []Location
+	At      time.Time
+	Tags    map[string]int
+	Note    *string `json:"note,omitempty"`
+	Payload []byte
+	Extra   any
+	ignored int
+}
+
+func TestFingerprints(t *testing.T) {
+	fingerprints := serializerOf(t, &Moved{}, &Born{}).Fingerprints()
+	expected := []eventsourcing.EventFingerprint{
+		{AggregateType: "Untyped", Reason: "Born", Fields: map[string]string{"Name": "string"}},
+		{AggregateType: "Untyped", Reason: "Moved", Fields: map[string]string{
+			"To.Street":       "string",
+			"To.zip":          "string",
+			"Before[].Street": "string",
+			"Before[].zip":    "string",
+			"At":              "time.Time",
+			"Tags{}":          "int",
+			"note":            "string",
+			"Payload":         "bytes",
+			"Extra":           "any",
+		}},
+	}
+	if !reflect.DeepEqual(fingerprints, expected) {
+		t.Fatalf("expected %+v got %+v", expected, fingerprints)
+	}
+}
+
+func TestSchemaChangelog(t *testing.T) {
+	type Registered struct {
+		Name string
+		Age  int
+	}
+	changelog, err := serializerOf(t, &Registered{}).RecordSchema(eventsourcing.SchemaChangelog{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changelog.Events) != 1 {
+		t.Fatalf("expected the fingerprint of Registered got %+v", changelog)
+	}
+
+	// write and read the changelog like a file kept with the code
+	var buf bytes.Buffer
+	err = changelog.Write(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changelog, err = eventsourcing.ReadSchemaChangelog(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("added field", func(t *testing.T) {
+		type Registered struct {
+			Name  string
+			Age   int
+			Email string
+		}
+		ser := serializerOf(t, &Registered{})
+		recorded, err := ser.RecordSchema(changelog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(recorded.Events) != 2 {
+			t.Fatalf("expected the changed fingerprint appended got %+v", recorded)
+		}
+		again, err := ser.RecordSchema(recorded)
+		if err != nil || len(again.Events) != 2 {
+			t.Fatalf("expected nothing recorded without changes got %+v %v", again, err)
+		}
+	})
+
+	t.Run("removed and changed fields", func(t *testing.T) {
+		type Registered struct {
+			Age string
+		}
+		ser := serializerOf(t, &Registered{})
+		err := ser.CheckSchema(changelog)
+		if !errors.Is(err, eventsourcing.ErrIncompatibleSchema) {
+			t.Fatalf("expected ErrIncompatibleSchema got %v", err)
+		}
+		schemaErr := &eventsourcing.SchemaError{}
+		if !errors.As(err, &schemaErr) {
+			t.Fatalf("expected a schema error got %v", err)
+		}
+		expected := []eventsourcing.SchemaChange{{AggregateType: "Untyped", Reason: "Registered", Removed: []string{"Name"}, Changed: []string{"Age: int -> string"}}}
+		if !reflect.DeepEqual(schemaErr.Changes, expected) {
+			t.Fatalf("expected %+v got %+v", expected, schemaErr.Changes)
+		}
+		_, err = ser.RecordSchema(changelog)
+		if !errors.Is(err, eventsourcing.ErrIncompatibleSchema) {
+			t.Fatalf("expected the incompatible change not to be recorded got %v", err)
+		}
+
+		// an upcaster registered for the change makes it pass and be recorded
+		ser.RegisterUpcaster("Untyped", "Registered", func(data []byte) ([]byte, error) { return data, nil })
+		recorded, err := ser.RecordSchema(changelog)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := ser.CheckSchema(recorded); err != nil {
+			t.Fatalf("expected the recorded change to pass got %v", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package eventsourcing
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Redacted replaces the value of a sensitive field masked with MaskRedact
+const Redacted = "[redacted]"
+
+// MaskMode is how MarshalMasked masks the sensitive fields
+type MaskMode int
+
+const (
+	// MaskRedact replaces the value of the sensitive fields with Redacted, keeping that the field is set visible
+	MaskRedact MaskMode = iota
+	// MaskDrop leaves the sensitive fields out
+	MaskDrop
+)
+
+// MarshalMasked marshals v to JSON with the fields tagged es:"sensitive" masked, for the paths exporting events and
+// aggregates out of the system like feeds, audit trails and debugging output. The serializers of the event stores
+// don't look at the tag, the persisted events keep the fields.
+//
+//	type Registered struct {
+//		Name  string
+//		Email string `es:"sensitive"`
+//	}
+//
+// The tag on an embedded struct masks all of its fields. Like the options of JSON, the fields are found from the type
+// of the value, values held in interface fields are not masked.
+func MarshalMasked(v any, mode MaskMode) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	t := concreteType(v)
+	if !hasSensitive(t) {
+		return data, nil
+	}
+	o := jsonOptions{mask: true, maskMode: mode}
+	return o.rewrite(t, data, true)
+}
+
+// sensitiveTypes caches if the types have sensitive fields
+var sensitiveTypes sync.Map
+
+// hasSensitive returns true if the type or the types of its fields have fields tagged sensitive, types without are
+// marshaled as is
+func hasSensitive(t reflect.Type) bool {
+	if t == nil {
+		return false
+	}
+	if cached, ok := sensitiveTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	found := findSensitive(t, make(map[reflect.Type]bool))
+	sensitiveTypes.Store(t, found)
+	return found
+}
+
+func findSensitive(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+	switch t.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Array, reflect.Map:
+		return findSensitive(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if sensitiveField(f) || findSensitive(f.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sensitiveField returns true if the field is tagged es:"sensitive"
+func sensitiveField(f reflect.StructField) bool {
+	for _, opt := range strings.Split(f.Tag.Get("es"), ",") {
+		if opt == "sensitive" {
+			return true
+		}
+	}
+	return false
+}
+
+// DiffMasked returns the changes between a and b like DiffValues with the fields tagged es:"sensitive" masked. The
+// values are compared unmasked, a changed sensitive field is reported with redacted values, or left out with
+// MaskDrop.
+func DiffMasked(a, b interface{}, mode MaskMode) ([]FieldChange, error) {
+	changes, err := DiffValues(a, b)
+	if err != nil || !hasSensitive(concreteType(a)) && !hasSensitive(concreteType(b)) {
+		return changes, err
+	}
+	maskedA, err := maskedFields(a, mode)
+	if err != nil {
+		return nil, err
+	}
+	maskedB, err := maskedFields(b, mode)
+	if err != nil {
+		return nil, err
+	}
+	masked := make([]FieldChange, 0, len(changes))
+	for _, c := range changes {
+		from, okA := maskedValue(maskedA, c.Field)
+		to, okB := maskedValue(maskedB, c.Field)
+		if !okA && !okB {
+			// a dropped sensitive field
+			continue
+		}
+		masked = append(masked, FieldChange{Field: c.Field, From: from, To: to})
+	}
+	return masked, nil
+}
+
+func maskedFields(v interface{}, mode MaskMode) (map[string]interface{}, error) {
+	data, err := MarshalMasked(v, mode)
+	if err != nil {
+		return nil, err
+	}
+	return jsonFields(json.RawMessage(data))
+}
+
+// maskedValue returns the value of the field, or the redacted value of the sensitive struct holding it
+func maskedValue(fields map[string]interface{}, field string) (interface{}, bool) {
+	for path := field; ; {
+		if v, ok := fields[path]; ok {
+			if path != field && v != Redacted {
+				return nil, false
+			}
+			return v, true
+		}
+		i := strings.LastIndex(path, ".")
+		if i < 0 {
+			return nil, false
+		}
+		path = path[:i]
+	}
+}
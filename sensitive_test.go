@@ -0,0 +1,102 @@
+package eventsourcing_test
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+type Contact struct {
+	Email string `es:"sensitive"`
+	Phone string `json:"phone" es:"sensitive"`
+}
+
+type Customer struct {
+	Name     string
+	Contact  Contact
+	Previous []Contact
+	Card     `es:"sensitive"`
+}
+
+type Card struct {
+	Number string
+}
+
+func TestMarshalMasked(t *testing.T) {
+	customer := &Customer{
+		Name:     "jane",
+		Contact:  Contact{Email: "jane@example.com", Phone: "123"},
+		Previous: []Contact{{Email: "old@example.com"}},
+		Card:     Card{Number: "4111"},
+	}
+	tests := []struct {
+		title    string
+		mode     eventsourcing.MaskMode
+		expected string
+	}{
+		{title: "redact", mode: eventsourcing.MaskRedact, expected: `{"Contact":{"Email":"[redacted]","phone":"[redacted]"},"Name":"jane","Number":"[redacted]","Previous":[{"Email":"[redacted]","phone":"[redacted]"}]}`},
+		{title: "drop", mode: eventsourcing.MaskDrop, expected: `{"Contact":{},"Name":"jane","Previous":[{}]}`},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			data, err := eventsourcing.MarshalMasked(customer, test.mode)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.expected {
+				t.Fatalf("expected %s got %s", test.expected, data)
+			}
+		})
+	}
+
+	// the serializers keep the fields
+	s := eventsourcing.NewJSONSerializer[any]()
+	data, err := s.Marshal(customer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var stored Customer
+	err = json.Unmarshal(data, &stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Contact.Email != "jane@example.com" || stored.Number != "4111" {
+		t.Fatalf("expected the sensitive fields to be stored got %s", data)
+	}
+}
+
+func TestMarshalMaskedNoSensitive(t *testing.T) {
+	data, err := eventsourcing.MarshalMasked(&Person{Name: "jane", Age: 1}, eventsourcing.MaskRedact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected, _ := json.Marshal(&Person{Name: "jane", Age: 1})
+	if string(data) != string(expected) {
+		t.Fatalf("expected %s got %s", expected, data)
+	}
+}
+
+func TestDiffMasked(t *testing.T) {
+	before := &Customer{Name: "jane", Contact: Contact{Email: "jane@example.com"}}
+	after := &Customer{Name: "jane", Contact: Contact{Email: "jane@example.org"}, Card: Card{Number: "4111"}}
+	changes, err := eventsourcing.DiffMasked(before, after, eventsourcing.MaskRedact)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []eventsourcing.FieldChange{
+		{Field: "Contact.Email", From: eventsourcing.Redacted, To: eventsourcing.Redacted},
+		{Field: "Number", From: eventsourcing.Redacted, To: eventsourcing.Redacted},
+	}
+	if !reflect.DeepEqual(changes, expected) {
+		t.Fatalf("expected %+v got %+v", expected, changes)
+	}
+	changes, err = eventsourcing.DiffMasked(before, after, eventsourcing.MaskDrop)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("expected the changes to be dropped got %+v", changes)
+	}
+}
@@ -2,6 +2,7 @@ package eventsourcing
 
 import (
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 )
@@ -13,16 +14,30 @@ type UnmarshalSnapshotFunc func(data []byte, v any) error
 // Serializer for json serializes
 type Serializer[T any] struct {
 	eventRegister map[string]eventFunc[T]
+	eventTypes    map[string]reflect.Type
 	marshal       MarshalSnapshotFunc
 	unmarshal     UnmarshalSnapshotFunc
+	// the policy is shared by the copies of the serializer held by the event stores
+	decode    *decodePolicy
+	schemas   *schemaRegistry
+	cipher    *cipherHolder
+	upcasters map[string][]Upcaster
+	// the aggregate type and reason of the registered events by their key in eventRegister
+	eventNames map[string]eventName
 }
 
 // NewSerializer returns a json Handle
 func NewSerializer[T any](marshalF MarshalSnapshotFunc, unmarshalF UnmarshalSnapshotFunc) *Serializer[T] {
 	return &Serializer[T]{
 		eventRegister: make(map[string]eventFunc[T]),
+		eventTypes:    make(map[string]reflect.Type),
 		marshal:       marshalF,
 		unmarshal:     unmarshalF,
+		decode:        &decodePolicy{},
+		schemas:       &schemaRegistry{schemas: make(map[string]Schema)},
+		cipher:        &cipherHolder{},
+		upcasters:     make(map[string][]Upcaster),
+		eventNames:    make(map[string]eventName),
 	}
 }
 
@@ -35,10 +50,18 @@ var (
 
 	// ErrEventNameMissing return if Event name is missing
 	ErrEventNameMissing = errors.New("missing event name")
+
+	// ErrEventNameAlreadyRegistered return if the event name is already registered by another type
+	ErrEventNameAlreadyRegistered = errors.New("event name already registered by another type")
 )
 
+// event returns a func making a new instance of the event type for each unmarshaled event
 func event[T any](event T) eventFunc[T] {
-	return func() T { return event }
+	typ := reflect.TypeOf(event)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return func() T { return event }
+	}
+	return func() T { return reflect.New(typ.Elem()).Interface().(T) }
 }
 
 // Events is a helper function to make the event type registration simpler
@@ -67,11 +90,18 @@ func (h *Serializer[T]) Register(aggregate Aggregate[T], events []eventFunc[T])
 
 	for _, f := range events {
 		event := f()
-		reason := reflect.TypeOf(event).Elem().Name()
-		if reason == "" {
+		name := reason(event)
+		if name == "" {
 			return ErrEventNameMissing
 		}
-		h.eventRegister[typ+"_"+reason] = f
+		// make sure the reason is produced by one type only as it would be ambiguous which type to
+		// unmarshal the event data into
+		if registered, ok := h.eventRegister[typ+"_"+name]; ok && reflect.TypeOf(registered()) != reflect.TypeOf(event) {
+			return fmt.Errorf("%w: %s %s", ErrEventNameAlreadyRegistered, typ, name)
+		}
+		h.eventRegister[typ+"_"+name] = f
+		h.eventTypes[typ+"_"+name] = reflect.TypeOf(event)
+		h.eventNames[typ+"_"+name] = eventName{aggregateType: typ, reason: name}
 	}
 	return nil
 }
@@ -87,12 +117,20 @@ func (h *Serializer[T]) Type(typ, reason string) (eventFunc[T], bool) {
 	return d, ok
 }
 
-// Marshal pass the request to the under laying Marshal method
+// Marshal pass the request to the under laying Marshal method and encrypts the result if a cipher is set
 func (h *Serializer[T]) Marshal(v any) ([]byte, error) {
-	return h.marshal(v)
+	data, err := h.marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return h.encrypt(data)
 }
 
-// Unmarshal pass the request to the under laying Unmarshal method
+// Unmarshal decrypts the data if a cipher is set and pass the request to the under laying Unmarshal method
 func (h *Serializer[T]) Unmarshal(data []byte, v any) error {
+	data, err := h.decrypt(data)
+	if err != nil {
+		return err
+	}
 	return h.unmarshal(data, v)
 }
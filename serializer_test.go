@@ -2,6 +2,7 @@ package eventsourcing_test
 
 import (
 	"encoding/json"
+	"errors"
 	"reflect"
 	"sync"
 	"testing"
@@ -122,3 +123,87 @@ func TestConcurrentUnmarshal(t *testing.T) {
 		wg.Wait()
 	}
 }
+
+type ReasonOverride struct {
+	A int
+}
+
+func (*ReasonOverride) data() {}
+
+func (*ReasonOverride) StoredReason() string { return "Override" }
+
+type ReasonClash struct{}
+
+func (*ReasonClash) data() {}
+
+func (*ReasonClash) StoredReason() string { return "SomeData" }
+
+func TestRegisterReasonOverride(t *testing.T) {
+	s := eventsourcing.NewSerializer[Data](json.Marshal, json.Unmarshal)
+	err := s.Register(&SomeAggregate{}, s.Events(&ReasonOverride{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.Type("SomeAggregate", "Override"); !ok {
+		t.Fatal("expected the event to be registered on its overridden reason")
+	}
+	if _, ok := s.Type("SomeAggregate", "ReasonOverride"); ok {
+		t.Fatal("the struct name should not be registered when the reason is overridden")
+	}
+}
+
+func TestRegisterDuplicateReason(t *testing.T) {
+	s := eventsourcing.NewSerializer[Data](json.Marshal, json.Unmarshal)
+	err := s.Register(&SomeAggregate{}, s.Events(&SomeData{}, &ReasonClash{}))
+	if !errors.Is(err, eventsourcing.ErrEventNameAlreadyRegistered) {
+		t.Fatalf("expected ErrEventNameAlreadyRegistered got %v", err)
+	}
+	// registering the same type twice is allowed
+	err = s.Register(&SomeAggregate{}, s.Events(&SomeData2{}, &SomeData2{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestUnmarshalEventNewInstance(t *testing.T) {
+	s := initSerializers(t)[0]
+	a := eventsourcing.Event[Data]{AggregateType: "SomeAggregate"}
+	b := eventsourcing.Event[Data]{AggregateType: "SomeAggregate"}
+	_, err := s.UnmarshalEvent(&a, "SomeData", []byte(`{"A":1}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = s.UnmarshalEvent(&b, "SomeData", []byte(`{"A":2}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Data == b.Data || a.Data.(*SomeData).A != 1 {
+		t.Fatalf("expected the events to get their own data got %+v and %+v", a.Data, b.Data)
+	}
+}
+
+func TestDecodeRawInto(t *testing.T) {
+	s := initSerializers(t)[0]
+	event := eventsourcing.Event[Data]{}
+	ok, err := s.DecodeRawInto(eventsourcing.RawEvent{AggregateType: "SomeAggregate", AggregateID: "1", Version: 1, Reason: "SomeData", Data: []byte(`{"A":1,"B":"b"}`)}, &event)
+	if err != nil || !ok {
+		t.Fatalf("expected the event to be decoded got %v %v", ok, err)
+	}
+	first := event.Data
+	// the data of the same type is reset and reused
+	ok, err = s.DecodeRawInto(eventsourcing.RawEvent{AggregateType: "SomeAggregate", AggregateID: "1", Version: 2, Reason: "SomeData", Data: []byte(`{"A":2}`)}, &event)
+	if err != nil || !ok {
+		t.Fatalf("expected the event to be decoded got %v %v", ok, err)
+	}
+	if event.Data != first || *event.Data.(*SomeData) != (SomeData{A: 2}) || event.Version != 2 {
+		t.Fatalf("expected the data to be reused got %+v", event)
+	}
+	// data of another type is not reused
+	ok, err = s.DecodeRawInto(eventsourcing.RawEvent{AggregateType: "SomeAggregate", AggregateID: "1", Version: 3, Reason: "SomeData2", Data: []byte(`{"A":3}`)}, &event)
+	if err != nil || !ok {
+		t.Fatalf("expected the event to be decoded got %v %v", ok, err)
+	}
+	if _, ok := event.Data.(*SomeData2); !ok || *first.(*SomeData) != (SomeData{A: 2}) {
+		t.Fatalf("expected new data got %+v", event.Data)
+	}
+}
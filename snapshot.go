@@ -1,9 +1,14 @@
 package eventsourcing
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"errors"
+	"fmt"
+	"io"
 	"reflect"
+	"time"
 )
 
 // ErrEmptyID indicates that the aggregate ID was empty
@@ -12,6 +17,21 @@ var ErrEmptyID = errors.New("aggregate id is empty")
 // ErrUnsavedEvents aggregate events must be saved before creating snapshot
 var ErrUnsavedEvents = errors.New("aggregate holds unsaved events")
 
+// ErrSnapshotCorrupt when the snapshot state could not be deserialized into the aggregate
+var ErrSnapshotCorrupt = errors.New("snapshot could not be deserialized")
+
+// ErrSnapshotTooLarge when the snapshot state is larger than the max size set on the snapshot handler
+var ErrSnapshotTooLarge = errors.New("snapshot is too large")
+
+// ErrEventVersionGap when the events read after a snapshot do not continue from the aggregate version
+var ErrEventVersionGap = errors.New("event version gap")
+
+// compressedMarker prefixes compressed snapshot states, the zero byte can't start a text encoded state and the second
+// byte is the compression format
+var compressedMarker = []byte{0x00, formatGzip}
+
+const formatGzip = 0x01
+
 // Snapshot holds current state of an aggregate
 type Snapshot struct {
 	ID            string
@@ -19,6 +39,8 @@ type Snapshot struct {
 	State         []byte
 	Version       Version
 	GlobalVersion Version
+	// Timestamp is the timestamp of the last event in the snapshot, zero in snapshots saved before it was added
+	Timestamp time.Time
 }
 
 // SnapshotAggregate is an Aggregate plus extra methods to help serialize into a snapshot
@@ -30,8 +52,12 @@ type SnapshotAggregate[T any] interface {
 
 // SnapshotHandler gets and saves snapshots
 type SnapshotHandler[T any] struct {
-	snapshotStore SnapshotStore
-	serializer    Serializer[T]
+	snapshotStore    SnapshotStore
+	serializer       Serializer[T]
+	compress         bool
+	compressionLevel int
+	maxSize          int
+	onTooLarge       func(snap Snapshot)
 }
 
 // SnapshotNew constructs a SnapshotHandler
@@ -42,59 +68,141 @@ func SnapshotNew[T any](ss SnapshotStore, ser Serializer[T]) *SnapshotHandler[T]
 	}
 }
 
+// SetCompression gzip compress the snapshot state with the compression level from the compress/gzip package.
+// Compressed states are prefixed with a marker and detected when read, snapshots saved before compression was turned on
+// or off are still readable.
+func (s *SnapshotHandler[T]) SetCompression(level int) {
+	s.compress = true
+	s.compressionLevel = level
+}
+
+// SetMaxSize sets the max size in bytes of the stored snapshot state. Larger snapshots are not saved, if onTooLarge
+// is nil ErrSnapshotTooLarge is returned otherwise onTooLarge is called and the snapshot is skipped.
+func (s *SnapshotHandler[T]) SetMaxSize(size int, onTooLarge func(snap Snapshot)) {
+	s.maxSize = size
+	s.onTooLarge = onTooLarge
+}
+
 // Save transform an aggregate to a snapshot
 func (s *SnapshotHandler[T]) Save(i interface{}) error {
-	sa, ok := i.(SnapshotAggregate[T])
-	if ok {
-		return s.saveSnapshotAggregate(sa)
+	snap, err := s.snapshot(i)
+	if err != nil {
+		return err
+	}
+	return s.save(snap)
+}
+
+// save stores the snapshot if it's within the max size
+func (s *SnapshotHandler[T]) save(snap Snapshot) error {
+	if s.maxSize > 0 && len(snap.State) > s.maxSize {
+		if s.onTooLarge == nil {
+			return fmt.Errorf("%w: %s %s is %d bytes", ErrSnapshotTooLarge, snap.Type, snap.ID, len(snap.State))
+		}
+		s.onTooLarge(snap)
+		return nil
+	}
+	return s.snapshotStore.Save(snap)
+}
+
+// snapshot transform an aggregate to a snapshot without saving it
+func (s *SnapshotHandler[T]) snapshot(i interface{}) (Snapshot, error) {
+	var snap Snapshot
+	var err error
+	if sa, ok := i.(SnapshotAggregate[T]); ok {
+		snap, err = s.snapshotSnapshotAggregate(sa)
+	} else if a, ok := i.(Aggregate[T]); ok {
+		snap, err = s.snapshotAggregate(a)
+	} else {
+		return Snapshot{}, errors.New("not an aggregate")
+	}
+	if err != nil || !s.compress {
+		return snap, err
+	}
+	snap.State, err = s.compressState(snap.State)
+	return snap, err
+}
+
+// marshalState returns the state of the aggregate as saved in a snapshot before compression
+func (s *SnapshotHandler[T]) marshalState(aggregate Aggregate[T]) ([]byte, error) {
+	if sa, ok := aggregate.(SnapshotAggregate[T]); ok {
+		return sa.Marshal(s.serializer.Marshal)
+	}
+	return s.serializer.Marshal(aggregate)
+}
+
+func (s *SnapshotHandler[T]) compressState(state []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write(compressedMarker)
+	w, err := gzip.NewWriterLevel(&buf, s.compressionLevel)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(state)
+	if err != nil {
+		return nil, err
 	}
-	a, ok := i.(Aggregate[T])
-	if ok {
-		return s.saveAggregate(a)
+	err = w.Close()
+	if err != nil {
+		return nil, err
 	}
-	return errors.New("not an aggregate")
+	return buf.Bytes(), nil
 }
 
-func (s *SnapshotHandler[T]) saveSnapshotAggregate(sa SnapshotAggregate[T]) error {
+// decompressState returns the state as is if it's not prefixed with the compressed marker
+func decompressState(state []byte) ([]byte, error) {
+	if !bytes.HasPrefix(state, compressedMarker) {
+		return state, nil
+	}
+	r, err := gzip.NewReader(bytes.NewReader(state[len(compressedMarker):]))
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+func (s *SnapshotHandler[T]) snapshotSnapshotAggregate(sa SnapshotAggregate[T]) (Snapshot, error) {
 	root := sa.Root()
 	err := validate(*root)
 	if err != nil {
-		return err
+		return Snapshot{}, err
 	}
 	typ := reflect.TypeOf(sa).Elem().Name()
 	b, err := sa.Marshal(s.serializer.Marshal)
 	if err != nil {
-		return err
+		return Snapshot{}, err
 	}
 	snap := Snapshot{
 		ID:            root.ID(),
 		Type:          typ,
 		Version:       root.Version(),
 		GlobalVersion: root.GlobalVersion(),
+		Timestamp:     root.aggregateTimestamp,
 		State:         b,
 	}
-	return s.snapshotStore.Save(snap)
+	return snap, nil
 }
 
-func (s *SnapshotHandler[T]) saveAggregate(sa Aggregate[T]) error {
+func (s *SnapshotHandler[T]) snapshotAggregate(sa Aggregate[T]) (Snapshot, error) {
 	root := sa.Root()
 	err := validate(*root)
 	if err != nil {
-		return err
+		return Snapshot{}, err
 	}
 	typ := reflect.TypeOf(sa).Elem().Name()
 	b, err := s.serializer.Marshal(sa)
 	if err != nil {
-		return err
+		return Snapshot{}, err
 	}
 	snap := Snapshot{
 		ID:            root.ID(),
 		Type:          typ,
 		Version:       root.Version(),
 		GlobalVersion: root.GlobalVersion(),
+		Timestamp:     root.aggregateTimestamp,
 		State:         b,
 	}
-	return s.snapshotStore.Save(snap)
+	return snap, nil
 }
 
 // Get fetch a snapshot and reconstruct an aggregate
@@ -104,27 +212,88 @@ func (s *SnapshotHandler[T]) Get(ctx context.Context, id string, i interface{})
 	if err != nil {
 		return err
 	}
+	snap.State, err = decompressState(snap.State)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
+	}
 	switch a := i.(type) {
 	case SnapshotAggregate[T]:
 		err := a.Unmarshal(s.serializer.Unmarshal, snap.State)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
 		}
 		root := a.Root()
-		root.setInternals(snap.ID, snap.Version, snap.GlobalVersion)
+		root.setInternals(snap.ID, snap.Version, snap.GlobalVersion, snap.Timestamp)
 	case Aggregate[T]:
 		err = s.serializer.Unmarshal(snap.State, a)
 		if err != nil {
-			return err
+			return fmt.Errorf("%w: %v", ErrSnapshotCorrupt, err)
 		}
 		root := a.Root()
-		root.setInternals(snap.ID, snap.Version, snap.GlobalVersion)
+		root.setInternals(snap.ID, snap.Version, snap.GlobalVersion, snap.Timestamp)
 	default:
 		return errors.New("not an aggregate")
 	}
 	return nil
 }
 
+// GetWithEvents fetch the snapshot and apply the events saved after it. Events saved while the events are read
+// are picked up by reading again from the new aggregate version until the event store returns no newer events.
+// If there is no snapshot the aggregate is built from all its events.
+func (s *SnapshotHandler[T]) GetWithEvents(ctx context.Context, id string, aggregate Aggregate[T], eventStore EventStore[T]) error {
+	err := s.Get(ctx, id, aggregate)
+	if err != nil && !errors.Is(err, ErrSnapshotNotFound) {
+		return err
+	}
+	typ := reflect.TypeOf(aggregate).Elem().Name()
+	for {
+		applied, err := applyEventsAfter(ctx, eventStore, id, typ, aggregate)
+		if err != nil {
+			return err
+		}
+		if applied == 0 {
+			break
+		}
+	}
+	if aggregate.Root().Version() == 0 {
+		return ErrAggregateNotFound
+	}
+	afterLoad(aggregate)
+	return nil
+}
+
+// applyEventsAfter applies the events after the current aggregate version and returns the number of applied events
+func applyEventsAfter[T any](ctx context.Context, eventStore EventStore[T], id, typ string, aggregate Aggregate[T]) (int, error) {
+	root := aggregate.Root()
+	iterator, err := eventStore.Get(ctx, id, typ, root.Version())
+	if errors.Is(err, ErrNoEvents) {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	defer iterator.Close()
+	applied := 0
+	for {
+		if ctx.Err() != nil {
+			return applied, ctx.Err()
+		}
+		event, err := iterator.Next()
+		if errors.Is(err, ErrNoMoreEvents) {
+			return applied, nil
+		} else if err != nil {
+			return applied, err
+		}
+		if event.Version <= root.Version() {
+			// already part of the aggregate state
+			continue
+		} else if event.Version != root.Version()+1 {
+			return applied, fmt.Errorf("%w: expected version %d got %d", ErrEventVersionGap, root.Version()+1, event.Version)
+		}
+		root.BuildFromHistory(aggregate, []Event[T]{event})
+		applied++
+	}
+}
+
 // validate make sure the aggregate is valid to be saved
 func validate[T any](root AggregateRoot[T]) error {
 	if root.ID() == "" {
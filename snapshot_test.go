@@ -1,9 +1,13 @@
 package eventsourcing_test
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/xml"
+	"errors"
 	"testing"
+	"time"
 
 	memory2 "github.com/hallgren/eventsourcing/eventstore/memory"
 
@@ -174,3 +178,217 @@ func TestSaveEmptySnapshotID(t *testing.T) {
 		t.Fatalf("could save blank snapshot id %v", err)
 	}
 }
+
+func TestSnapshotCompression(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](xml.Marshal, xml.Unmarshal)
+	store := memsnap.New()
+	s := eventsourcing.SnapshotNew(store, *ser)
+	repo := eventsourcing.NewRepository[PersonEvent](memory2.Create[PersonEvent](), s)
+
+	person, err := CreatePersonWithID("123", "kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Save(person)
+
+	// a snapshot saved before compression was turned on
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetCompression(gzip.BestCompression)
+	p := Person{}
+	err = s.Get(context.Background(), "123", &p)
+	if err != nil {
+		t.Fatalf("could not get uncompressed snapshot %v", err)
+	}
+
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Get(context.Background(), "123", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(snap.State, []byte{0x00, 0x01, 0x1f, 0x8b}) {
+		t.Fatal("expected the snapshot state to be marked and gzip compressed")
+	}
+	p = Person{}
+	err = s.Get(context.Background(), "123", &p)
+	if err != nil {
+		t.Fatalf("could not get compressed snapshot %v", err)
+	}
+	if p.Name != person.Name || p.Version() != person.Version() {
+		t.Fatalf("wrong person from compressed snapshot %q version %d", p.Name, p.Version())
+	}
+}
+
+func TestSnapshotTimestamp(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](xml.Marshal, xml.Unmarshal)
+	store := memsnap.New()
+	s := eventsourcing.SnapshotNew(store, *ser)
+	repo := eventsourcing.NewRepository[PersonEvent](memory2.Create[PersonEvent](), s)
+
+	person, err := CreatePersonWithID("123", "kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	created := person.Events()[0].Timestamp
+	repo.Save(person)
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Get(context.Background(), "123", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snap.Timestamp.Equal(created) {
+		t.Fatalf("expected the timestamp of the last event %v got %v", created, snap.Timestamp)
+	}
+
+	// a snapshot taken on a clock ahead, the events tracked after it don't go back in time
+	future := time.Now().Add(time.Hour).UTC()
+	snap.Timestamp = future
+	err = store.Save(snap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Person{}
+	err = s.Get(context.Background(), "123", &p)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p.GrowOlder()
+	if p.Events()[0].Timestamp.Before(future) {
+		t.Fatalf("expected the event timestamp not before the snapshot timestamp %v got %v", future, p.Events()[0].Timestamp)
+	}
+}
+
+func TestSnapshotUncompressedGzipHeader(t *testing.T) {
+	// a serializer writing states that start with the gzip magic number
+	header := []byte{0x1f, 0x8b}
+	marshal := func(v interface{}) ([]byte, error) {
+		b, err := xml.Marshal(v)
+		return append(header, b...), err
+	}
+	unmarshal := func(data []byte, v interface{}) error {
+		return xml.Unmarshal(bytes.TrimPrefix(data, header), v)
+	}
+	ser := eventsourcing.NewSerializer[PersonEvent](marshal, unmarshal)
+	s := eventsourcing.SnapshotNew(memsnap.New(), *ser)
+	repo := eventsourcing.NewRepository[PersonEvent](memory2.Create[PersonEvent](), s)
+
+	person, err := CreatePersonWithID("123", "kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Save(person)
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := Person{}
+	err = s.Get(context.Background(), "123", &p)
+	if err != nil {
+		t.Fatalf("could not get uncompressed snapshot %v", err)
+	}
+	if p.Name != person.Name {
+		t.Fatalf("wrong person from uncompressed snapshot %q", p.Name)
+	}
+}
+
+func TestSnapshotMaxSize(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](xml.Marshal, xml.Unmarshal)
+	store := memsnap.New()
+	s := eventsourcing.SnapshotNew(store, *ser)
+	repo := eventsourcing.NewRepository[PersonEvent](memory2.Create[PersonEvent](), s)
+
+	person, err := CreatePersonWithID("123", "kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Save(person)
+
+	s.SetMaxSize(10, nil)
+	err = s.Save(person)
+	if !errors.Is(err, eventsourcing.ErrSnapshotTooLarge) {
+		t.Fatalf("expected ErrSnapshotTooLarge got %v", err)
+	}
+
+	var skipped []eventsourcing.Snapshot
+	s.SetMaxSize(10, func(snap eventsourcing.Snapshot) { skipped = append(skipped, snap) })
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(skipped) != 1 {
+		t.Fatalf("expected one skipped snapshot got %d", len(skipped))
+	}
+	_, err = store.Get(context.Background(), "123", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected the snapshot to not be saved got %v", err)
+	}
+}
+
+// racingEventStore saves an event after the first read to simulate an event landing between the reads
+type racingEventStore struct {
+	eventsourcing.EventStore[PersonEvent]
+	race func()
+}
+
+func (r *racingEventStore) Get(ctx context.Context, id string, aggregateType string, afterVersion eventsourcing.Version) (eventsourcing.EventIterator[PersonEvent], error) {
+	iterator, err := r.EventStore.Get(ctx, id, aggregateType, afterVersion)
+	if r.race != nil {
+		race := r.race
+		r.race = nil
+		race()
+	}
+	return iterator, err
+}
+
+func TestGetWithEvents(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](xml.Marshal, xml.Unmarshal)
+	s := eventsourcing.SnapshotNew(memsnap.New(), *ser)
+	eventStore := &racingEventStore{EventStore: memory2.Create[PersonEvent]()}
+	repo := eventsourcing.NewRepository[PersonEvent](eventStore, s)
+
+	person, err := CreatePersonWithID("123", "kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Save(person)
+	err = s.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	repo.Save(person)
+
+	eventStore.race = func() {
+		person.GrowOlder()
+		repo.Save(person)
+	}
+	p := Person{}
+	err = s.GetWithEvents(context.Background(), "123", &p, eventStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Version() != person.Version() {
+		t.Fatalf("expected version %d got %d", person.Version(), p.Version())
+	}
+	if p.Age != person.Age {
+		t.Fatalf("expected age %d got %d", person.Age, p.Age)
+	}
+}
+
+func TestGetWithEventsNoneExisting(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](xml.Marshal, xml.Unmarshal)
+	s := eventsourcing.SnapshotNew(memsnap.New(), *ser)
+	p := Person{}
+	err := s.GetWithEvents(context.Background(), "123", &p, memory2.Create[PersonEvent]())
+	if !errors.Is(err, eventsourcing.ErrAggregateNotFound) {
+		t.Fatalf("expected ErrAggregateNotFound got %v", err)
+	}
+}
@@ -0,0 +1,75 @@
+package eventsourcing
+
+import (
+	"reflect"
+	"sync"
+)
+
+// SnapshotPolicies holds the snapshot policy per aggregate type, aggregates of a type without a policy use the
+// default policy. Set it on the repository with SetSnapshotPolicy(policies.Snapshot).
+type SnapshotPolicies[T any] struct {
+	lock     sync.RWMutex
+	policies map[string]SnapshotPolicy[T]
+	fallback SnapshotPolicy[T]
+}
+
+// NewSnapshotPolicies returns a registry using the default policy for the aggregate types without a policy, a nil
+// default policy takes no snapshots of them
+func NewSnapshotPolicies[T any](fallback SnapshotPolicy[T]) *SnapshotPolicies[T] {
+	return &SnapshotPolicies[T]{
+		policies: make(map[string]SnapshotPolicy[T]),
+		fallback: fallback,
+	}
+}
+
+// Set sets the policy of the aggregate type, the type is the struct name of the aggregate
+func (p *SnapshotPolicies[T]) Set(aggregateType string, policy SnapshotPolicy[T]) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.policies[aggregateType] = policy
+}
+
+// Snapshot decides with the policy of the aggregate type if a snapshot should be taken
+func (p *SnapshotPolicies[T]) Snapshot(aggregate Aggregate[T], events []Event[T]) bool {
+	p.lock.RLock()
+	policy, ok := p.policies[reflect.TypeOf(aggregate).Elem().Name()]
+	p.lock.RUnlock()
+	if !ok {
+		policy = p.fallback
+	}
+	return policy != nil && policy(aggregate, events)
+}
+
+// AnyPolicy takes a snapshot when one of the policies says so. All policies are asked to let the stateful ones keep
+// their count.
+func AnyPolicy[T any](policies ...SnapshotPolicy[T]) SnapshotPolicy[T] {
+	return func(aggregate Aggregate[T], events []Event[T]) bool {
+		snapshot := false
+		for _, policy := range policies {
+			if policy(aggregate, events) {
+				snapshot = true
+			}
+		}
+		return snapshot
+	}
+}
+
+// StateOverNBytes is a snapshot policy that takes a snapshot when the marshaled state of the aggregate is at least
+// n bytes, large states are the costly ones to rebuild from the events. The state is marshaled each time the policy
+// is asked, aggregates implementing SnapshotAggregate with their own Marshal method. Once the state is over n bytes
+// every save is followed by a snapshot, combine it with EveryNEvents in AnyPolicy to also snapshot the small ones.
+func StateOverNBytes[T any](n int, marshal MarshalSnapshotFunc) SnapshotPolicy[T] {
+	return func(aggregate Aggregate[T], events []Event[T]) bool {
+		if len(events) == 0 {
+			return false
+		}
+		var state []byte
+		var err error
+		if sa, ok := aggregate.(SnapshotAggregate[T]); ok {
+			state, err = sa.Marshal(marshal)
+		} else {
+			state, err = marshal(aggregate)
+		}
+		return err == nil && len(state) >= n
+	}
+}
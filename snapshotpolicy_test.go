@@ -0,0 +1,72 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestSnapshotPolicies(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	snapshotStore := memsnap.New()
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), eventsourcing.SnapshotNew(snapshotStore, *ser))
+	policies := eventsourcing.NewSnapshotPolicies[PersonEvent](eventsourcing.EveryNEvents[PersonEvent](1))
+	policies.Set("Person", eventsourcing.EveryNEvents[PersonEvent](3))
+	repo.SetSnapshotPolicy(policies.Snapshot)
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = snapshotStore.Get(context.Background(), person.ID(), "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected the person policy to skip the snapshot got %v", err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := snapshotStore.Get(context.Background(), person.ID(), "Person")
+	if err != nil || snap.Version != 3 {
+		t.Fatalf("expected a snapshot on version 3 got %v %v", snap.Version, err)
+	}
+
+	// aggregate types without a policy use the default
+	if !policies.Snapshot(&RoutedPerson{}, []eventsourcing.Event[PersonEvent]{{Version: 1}}) {
+		t.Fatal("expected the default policy to take a snapshot")
+	}
+	if eventsourcing.NewSnapshotPolicies[PersonEvent](nil).Snapshot(&RoutedPerson{}, []eventsourcing.Event[PersonEvent]{{Version: 1}}) {
+		t.Fatal("expected no snapshot without a default policy")
+	}
+}
+
+func TestStateOverNBytes(t *testing.T) {
+	policy := eventsourcing.StateOverNBytes[PersonEvent](100, json.Marshal)
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := person.Events()
+	if policy(person, events) {
+		t.Fatal("expected no snapshot of a small state")
+	}
+	person.Name = strings.Repeat("a", 100)
+	if !policy(person, events) {
+		t.Fatal("expected a snapshot of a state over 100 bytes")
+	}
+	if policy(person, nil) {
+		t.Fatal("expected no snapshot without saved events")
+	}
+}
@@ -3,6 +3,7 @@ package memory
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	"github.com/hallgren/eventsourcing"
 )
@@ -10,6 +11,7 @@ import (
 // Handler of snapshot store
 type Handler struct {
 	store map[string]eventsourcing.Snapshot
+	lock  sync.Mutex
 }
 
 // New handler for the snapshot service
@@ -21,6 +23,8 @@ func New() *Handler {
 
 // Get returns the deserialize snapshot
 func (h *Handler) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot, error) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
 	v, ok := h.store[fmt.Sprintf("%s_%s", id, typ)]
 	if !ok {
 		return eventsourcing.Snapshot{}, eventsourcing.ErrSnapshotNotFound
@@ -30,6 +34,8 @@ func (h *Handler) Get(ctx context.Context, id, typ string) (eventsourcing.Snapsh
 
 // Save persists the snapshot
 func (h *Handler) Save(s eventsourcing.Snapshot) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
 	h.store[fmt.Sprintf("%s_%s", s.ID, s.Type)] = s
 	return nil
 }
@@ -0,0 +1,141 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	fieldState         = "state"
+	fieldVersion       = "version"
+	fieldGlobalVersion = "global_version"
+	fieldTimestamp     = "timestamp"
+)
+
+// save replaces the snapshot unless a newer version is stored. The old snapshot is removed to not keep fields from it.
+var save = redis.NewScript(`
+local current = redis.call('HGET', KEYS[1], 'version')
+if current and tonumber(current) > tonumber(ARGV[2]) then
+	return 0
+end
+redis.call('DEL', KEYS[1])
+redis.call('HSET', KEYS[1], 'state', ARGV[1], 'version', ARGV[2], 'global_version', ARGV[3], 'timestamp', ARGV[5])
+if tonumber(ARGV[4]) > 0 then
+	redis.call('PEXPIRE', KEYS[1], ARGV[4])
+end
+return 1
+`)
+
+// Redis is a snapshot store keeping snapshots as hashes with an expiry time. It's meant as a warm cache in front
+// of the event store, when a snapshot is missing or expired the repository rebuilds the aggregate from its events.
+type Redis struct {
+	client   redis.Cmdable
+	prefix   string
+	ttl      time.Duration
+	typeTTLs map[string]time.Duration
+}
+
+// Option configures the redis snapshot store
+type Option func(*Redis)
+
+// WithPrefix sets a prefix on the snapshot keys
+func WithPrefix(prefix string) Option {
+	return func(r *Redis) {
+		r.prefix = prefix
+	}
+}
+
+// WithTTL sets the expiry time of all snapshots, zero keeps them until they are evicted by redis
+func WithTTL(ttl time.Duration) Option {
+	return func(r *Redis) {
+		r.ttl = ttl
+	}
+}
+
+// WithTypeTTL sets the expiry time of the snapshots of an aggregate type, overrides the ttl from WithTTL
+func WithTypeTTL(typ string, ttl time.Duration) Option {
+	return func(r *Redis) {
+		r.typeTTLs[typ] = ttl
+	}
+}
+
+// New returns a redis snapshot store
+func New(client redis.Cmdable, opts ...Option) *Redis {
+	r := &Redis{
+		client:   client,
+		typeTTLs: make(map[string]time.Duration),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Get retrieves the persisted snapshot
+func (r *Redis) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot, error) {
+	fields, err := r.client.HGetAll(ctx, r.key(id, typ)).Result()
+	if err != nil {
+		return eventsourcing.Snapshot{}, err
+	}
+	if len(fields) == 0 {
+		return eventsourcing.Snapshot{}, eventsourcing.ErrSnapshotNotFound
+	}
+	version, err := strconv.ParseUint(fields[fieldVersion], 10, 64)
+	if err != nil {
+		return eventsourcing.Snapshot{}, fmt.Errorf("%w: could not parse snapshot version, %v", eventsourcing.ErrSnapshotCorrupt, err)
+	}
+	globalVersion, err := strconv.ParseUint(fields[fieldGlobalVersion], 10, 64)
+	if err != nil {
+		return eventsourcing.Snapshot{}, fmt.Errorf("%w: could not parse snapshot global version, %v", eventsourcing.ErrSnapshotCorrupt, err)
+	}
+	snap := eventsourcing.Snapshot{
+		ID:            id,
+		Type:          typ,
+		State:         []byte(fields[fieldState]),
+		Version:       eventsourcing.Version(version),
+		GlobalVersion: eventsourcing.Version(globalVersion),
+	}
+	// snapshots saved before the timestamp was added have no timestamp field
+	if ts, ok := fields[fieldTimestamp]; ok && ts != "" {
+		snap.Timestamp, err = time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return eventsourcing.Snapshot{}, fmt.Errorf("%w: could not parse snapshot timestamp, %v", eventsourcing.ErrSnapshotCorrupt, err)
+		}
+	}
+	return snap, nil
+}
+
+// Save persists the snapshot and sets its expiry time. The write is a compare and set on the version in a script,
+// a snapshot older than the stored one is not saved.
+func (r *Redis) Save(snap eventsourcing.Snapshot) error {
+	ctx := context.Background()
+	ttl := r.ttl
+	if typeTTL, ok := r.typeTTLs[snap.Type]; ok {
+		ttl = typeTTL
+	}
+	return save.Run(ctx, r.client, []string{r.key(snap.ID, snap.Type)},
+		snap.State,
+		uint64(snap.Version),
+		uint64(snap.GlobalVersion),
+		ttl.Milliseconds(),
+		formatTimestamp(snap.Timestamp),
+	).Err()
+}
+
+// key builds the snapshot key from the aggregate type and id
+func (r *Redis) key(id, typ string) string {
+	return r.prefix + typ + ":" + id
+}
+
+// formatTimestamp formats the snapshot timestamp, empty when it's not set
+func formatTimestamp(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339Nano)
+}
@@ -0,0 +1,109 @@
+package redis_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/snapshotstore/redis"
+	"github.com/hallgren/eventsourcing/snapshotstore/suite"
+	goredis "github.com/redis/go-redis/v9"
+)
+
+type provider struct {
+	server *miniredis.Miniredis
+	client *goredis.Client
+}
+
+func (p *provider) Setup() (eventsourcing.SnapshotStore, error) {
+	server, err := miniredis.Run()
+	if err != nil {
+		return nil, err
+	}
+	p.server = server
+	p.client = goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	return redis.New(p.client, redis.WithTTL(time.Hour)), nil
+}
+
+func (p *provider) Cleanup() { p.server.FlushAll() }
+
+func (p *provider) Teardown() {
+	p.client.Close()
+	p.server.Close()
+}
+
+func TestRedisSnapshotStore(t *testing.T) {
+	suite.Test(t, new(provider))
+}
+
+func TestTTL(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := redis.New(client, redis.WithPrefix("snap:"), redis.WithTTL(time.Hour), redis.WithTypeTTL("Person", time.Minute))
+
+	err := store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save(eventsourcing.Snapshot{ID: "123", Type: "Car", Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ttl := server.TTL("snap:Person:123"); ttl != time.Minute {
+		t.Fatalf("expected the type ttl got %v", ttl)
+	}
+	if ttl := server.TTL("snap:Car:123"); ttl != time.Hour {
+		t.Fatalf("expected the default ttl got %v", ttl)
+	}
+
+	server.FastForward(2 * time.Minute)
+	_, err = store.Get(context.Background(), "123", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected the snapshot to be expired got %v", err)
+	}
+	_, err = store.Get(context.Background(), "123", "Car")
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOlderSnapshotNotSaved(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := redis.New(client)
+
+	err := store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 5, State: []byte("five")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a writer that loaded the aggregate before version 5 saves its snapshot late
+	err = store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 3, State: []byte("three")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Get(context.Background(), "123", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != 5 || string(snap.State) != "five" {
+		t.Fatalf("expected the newer snapshot to be kept got version %d %q", snap.Version, snap.State)
+	}
+}
+
+func TestCorruptSnapshot(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+	store := redis.New(client)
+
+	server.HSet("Person:123", "state", "{}", "version", "five", "global_version", "0")
+	_, err := store.Get(context.Background(), "123", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotCorrupt) {
+		t.Fatalf("expected ErrSnapshotCorrupt got %v", err)
+	}
+}
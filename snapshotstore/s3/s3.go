@@ -0,0 +1,188 @@
+package s3
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/hallgren/eventsourcing"
+)
+
+const (
+	metadataVersion       = "version"
+	metadataGlobalVersion = "global-version"
+	metadataTimestamp     = "timestamp"
+)
+
+// ErrConcurrentWrite when the snapshot object was changed by someone else during the save
+var ErrConcurrentWrite = errors.New("snapshot object changed during save")
+
+// API is the part of the s3 client used by the snapshot store, it's implemented by *s3.Client
+type API interface {
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+}
+
+// S3 is the snapshot store writing snapshots as objects keyed by prefix/type/id. If versioning is enabled on
+// the bucket every snapshot is kept as an object version.
+type S3 struct {
+	client               API
+	bucket               string
+	prefix               string
+	serverSideEncryption types.ServerSideEncryption
+	kmsKeyID             string
+}
+
+// Option configures the s3 snapshot store
+type Option func(*S3)
+
+// WithPrefix sets a prefix on the object keys
+func WithPrefix(prefix string) Option {
+	return func(s *S3) {
+		s.prefix = prefix
+	}
+}
+
+// WithServerSideEncryption sets the server side encryption on the snapshot objects, kmsKeyID is only used
+// with the aws:kms encryption types.
+func WithServerSideEncryption(sse types.ServerSideEncryption, kmsKeyID string) Option {
+	return func(s *S3) {
+		s.serverSideEncryption = sse
+		s.kmsKeyID = kmsKeyID
+	}
+}
+
+// New returns a S3 snapshot store
+func New(client API, bucket string, opts ...Option) *S3 {
+	s := &S3{
+		client: client,
+		bucket: bucket,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Get retrieves the persisted snapshot
+func (s *S3) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(s.key(id, typ)),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return eventsourcing.Snapshot{}, eventsourcing.ErrSnapshotNotFound
+		}
+		return eventsourcing.Snapshot{}, err
+	}
+	defer out.Body.Close()
+
+	state, err := io.ReadAll(out.Body)
+	if err != nil {
+		return eventsourcing.Snapshot{}, err
+	}
+	version, globalVersion, err := versions(out.Metadata)
+	if err != nil {
+		return eventsourcing.Snapshot{}, err
+	}
+	snap := eventsourcing.Snapshot{
+		ID:            id,
+		Type:          typ,
+		State:         state,
+		Version:       version,
+		GlobalVersion: globalVersion,
+	}
+	// objects saved before the timestamp was added have no timestamp metadata
+	if ts, ok := out.Metadata[metadataTimestamp]; ok {
+		snap.Timestamp, err = time.Parse(time.RFC3339Nano, ts)
+		if err != nil {
+			return eventsourcing.Snapshot{}, fmt.Errorf("could not parse snapshot timestamp, %v", err)
+		}
+	}
+	return snap, nil
+}
+
+// Save persists the snapshot. The object is conditionally put based on the ETag of the current object,
+// a snapshot older than the stored one is not saved.
+func (s *S3) Save(snap eventsourcing.Snapshot) error {
+	ctx := context.Background()
+	key := s.key(snap.ID, snap.Type)
+
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+		Body:   bytes.NewReader(snap.State),
+		Metadata: map[string]string{
+			metadataVersion:       strconv.FormatUint(uint64(snap.Version), 10),
+			metadataGlobalVersion: strconv.FormatUint(uint64(snap.GlobalVersion), 10),
+		},
+	}
+	if !snap.Timestamp.IsZero() {
+		input.Metadata[metadataTimestamp] = snap.Timestamp.UTC().Format(time.RFC3339Nano)
+	}
+	if s.serverSideEncryption != "" {
+		input.ServerSideEncryption = s.serverSideEncryption
+		if s.kmsKeyID != "" {
+			input.SSEKMSKeyId = aws.String(s.kmsKeyID)
+		}
+	}
+
+	head, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		// only create the object if it's still missing
+		input.IfNoneMatch = aws.String("*")
+	} else if err != nil {
+		return err
+	} else {
+		version, _, err := versions(head.Metadata)
+		if err != nil {
+			return err
+		}
+		if version > snap.Version {
+			// a newer snapshot is already stored
+			return nil
+		}
+		// only replace the object we compared the version against
+		input.IfMatch = head.ETag
+	}
+
+	_, err = s.client.PutObject(ctx, input)
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) && apiErr.ErrorCode() == "PreconditionFailed" {
+		return ErrConcurrentWrite
+	}
+	return err
+}
+
+// key builds the object key from the aggregate type and id
+func (s *S3) key(id, typ string) string {
+	return s.prefix + typ + "/" + id
+}
+
+// versions parse the version and global version from the object metadata
+func versions(metadata map[string]string) (eventsourcing.Version, eventsourcing.Version, error) {
+	version, err := strconv.ParseUint(metadata[metadataVersion], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not parse snapshot version, %v", err)
+	}
+	globalVersion, err := strconv.ParseUint(metadata[metadataGlobalVersion], 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("could not parse snapshot global version, %v", err)
+	}
+	return eventsourcing.Version(version), eventsourcing.Version(globalVersion), nil
+}
@@ -0,0 +1,179 @@
+package s3_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awss3 "github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/snapshotstore/s3"
+	"github.com/hallgren/eventsourcing/snapshotstore/suite"
+)
+
+type object struct {
+	body     []byte
+	metadata map[string]string
+	etag     string
+}
+
+// fakeS3 is an in memory bucket supporting the conditional puts used by the store
+type fakeS3 struct {
+	lock      sync.Mutex
+	objects   map[string]object
+	etag      int
+	lastPut   *awss3.PutObjectInput
+	beforePut func()
+}
+
+func newFakeS3() *fakeS3 {
+	return &fakeS3{objects: make(map[string]object)}
+}
+
+func (f *fakeS3) HeadObject(ctx context.Context, params *awss3.HeadObjectInput, optFns ...func(*awss3.Options)) (*awss3.HeadObjectOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	o, ok := f.objects[*params.Key]
+	if !ok {
+		return nil, &types.NotFound{}
+	}
+	return &awss3.HeadObjectOutput{ETag: aws.String(o.etag), Metadata: o.metadata}, nil
+}
+
+func (f *fakeS3) GetObject(ctx context.Context, params *awss3.GetObjectInput, optFns ...func(*awss3.Options)) (*awss3.GetObjectOutput, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	o, ok := f.objects[*params.Key]
+	if !ok {
+		return nil, &types.NoSuchKey{}
+	}
+	return &awss3.GetObjectOutput{
+		Body:     io.NopCloser(bytes.NewReader(o.body)),
+		ETag:     aws.String(o.etag),
+		Metadata: o.metadata,
+	}, nil
+}
+
+func (f *fakeS3) PutObject(ctx context.Context, params *awss3.PutObjectInput, optFns ...func(*awss3.Options)) (*awss3.PutObjectOutput, error) {
+	if f.beforePut != nil {
+		f.beforePut()
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.lastPut = params
+	current, exists := f.objects[*params.Key]
+	if params.IfNoneMatch != nil && exists {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	if params.IfMatch != nil && (!exists || current.etag != *params.IfMatch) {
+		return nil, &smithy.GenericAPIError{Code: "PreconditionFailed"}
+	}
+	body, err := io.ReadAll(params.Body)
+	if err != nil {
+		return nil, err
+	}
+	f.etag++
+	etag := strconv.Itoa(f.etag)
+	f.objects[*params.Key] = object{body: body, metadata: params.Metadata, etag: etag}
+	return &awss3.PutObjectOutput{ETag: aws.String(etag)}, nil
+}
+
+// put writes an object directly in the fake bucket
+func (f *fakeS3) put(key string, version int) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.etag++
+	f.objects[key] = object{
+		metadata: map[string]string{"version": strconv.Itoa(version), "global-version": "0"},
+		etag:     strconv.Itoa(f.etag),
+	}
+}
+
+type provider struct {
+	fake *fakeS3
+}
+
+func (p *provider) Setup() (eventsourcing.SnapshotStore, error) {
+	p.fake = newFakeS3()
+	return s3.New(p.fake, "snapshots"), nil
+}
+
+func (p *provider) Cleanup() { p.fake.objects = make(map[string]object) }
+
+func (p *provider) Teardown() {}
+
+func TestS3SnapshotStore(t *testing.T) {
+	suite.Test(t, new(provider))
+}
+
+func TestGetMissingSnapshot(t *testing.T) {
+	store := s3.New(newFakeS3(), "snapshots")
+	_, err := store.Get(context.Background(), "123", "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected ErrSnapshotNotFound got %v", err)
+	}
+}
+
+func TestSaveKeepsNewerSnapshot(t *testing.T) {
+	fake := newFakeS3()
+	store := s3.New(fake, "snapshots", s3.WithPrefix("snap/"))
+	err := store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 10, State: []byte("new")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 5, State: []byte("old")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := fake.objects["snap/Person/123"]; !ok {
+		t.Fatal("expected the object key to be prefixed")
+	}
+	snap, err := store.Get(context.Background(), "123", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != 10 || string(snap.State) != "new" {
+		t.Fatalf("expected the newer snapshot to be kept got version %d", snap.Version)
+	}
+}
+
+func TestSaveConcurrentWrite(t *testing.T) {
+	fake := newFakeS3()
+	store := s3.New(fake, "snapshots")
+
+	// the object is created between the head and put request
+	fake.beforePut = func() { fake.put("Person/123", 1) }
+	err := store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 2})
+	if !errors.Is(err, s3.ErrConcurrentWrite) {
+		t.Fatalf("expected ErrConcurrentWrite got %v", err)
+	}
+
+	// the object is replaced between the head and put request
+	fake.beforePut = func() { fake.put("Person/123", 3) }
+	err = store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 4})
+	if !errors.Is(err, s3.ErrConcurrentWrite) {
+		t.Fatalf("expected ErrConcurrentWrite got %v", err)
+	}
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	fake := newFakeS3()
+	store := s3.New(fake, "snapshots", s3.WithServerSideEncryption(types.ServerSideEncryptionAwsKms, "key-id"))
+	err := store.Save(eventsourcing.Snapshot{ID: "123", Type: "Person", Version: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fake.lastPut.ServerSideEncryption != types.ServerSideEncryptionAwsKms {
+		t.Fatalf("expected aws:kms encryption got %q", fake.lastPut.ServerSideEncryption)
+	}
+	if aws.ToString(fake.lastPut.SSEKMSKeyId) != "key-id" {
+		t.Fatalf("expected kms key id got %q", aws.ToString(fake.lastPut.SSEKMSKeyId))
+	}
+}
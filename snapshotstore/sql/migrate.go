@@ -2,7 +2,8 @@ package sql
 
 import "context"
 
-const createTable = `create table snapshots (id VARCHAR NOT NULL, type VARCHAR, version INTEGER, global_version INTEGER, state BLOB);`
+// the timestamp is stored as nanoseconds since the unix epoch
+const createTable = `create table snapshots (id VARCHAR NOT NULL, type VARCHAR, version INTEGER, global_version INTEGER, state BLOB, timestamp INTEGER);`
 
 // Migrate the database
 func (s *SQL) Migrate() error {
@@ -13,6 +14,12 @@ func (s *SQL) Migrate() error {
 	return s.migrate(sqlStmt)
 }
 
+// MigrateTimestamp adds the timestamp column to a snapshots table created before the column was added. Snapshots
+// saved before have no timestamp.
+func (s *SQL) MigrateTimestamp() error {
+	return s.migrate([]string{`alter table snapshots add column timestamp INTEGER;`})
+}
+
 // MigrateTest remove the index that the test sql driver does not support
 func (s *SQL) MigrateTest() error {
 	return s.migrate([]string{createTable})
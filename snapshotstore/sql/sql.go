@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"time"
 
 	"github.com/hallgren/eventsourcing"
 )
@@ -26,6 +27,11 @@ func (s *SQL) Close() {
 	s.db.Close()
 }
 
+// CheckHealth pings the database
+func (s *SQL) CheckHealth(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 // Get retrieves the persisted snapshot
 func (s *SQL) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot, error) {
 	tx, err := s.db.BeginTx(ctx, nil)
@@ -36,11 +42,12 @@ func (s *SQL) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot,
 	}
 	defer tx.Rollback()
 
-	statement := `SELECT state, version, global_version from snapshots where id=$1 AND type=$2 LIMIT 1`
+	statement := `SELECT state, version, global_version, timestamp from snapshots where id=$1 AND type=$2 LIMIT 1`
 	var state []byte
 	var version uint64
 	var globalVersion uint64
-	err = tx.QueryRowContext(ctx, statement, id, typ).Scan(&state, &version, &globalVersion)
+	var timestamp sql.NullInt64
+	err = tx.QueryRowContext(ctx, statement, id, typ).Scan(&state, &version, &globalVersion, &timestamp)
 	if err != nil && err != sql.ErrNoRows {
 		return eventsourcing.Snapshot{}, err
 	} else if err == sql.ErrNoRows {
@@ -55,6 +62,9 @@ func (s *SQL) Get(ctx context.Context, id, typ string) (eventsourcing.Snapshot,
 		Version:       eventsourcing.Version(version),
 		GlobalVersion: eventsourcing.Version(globalVersion),
 	}
+	if timestamp.Valid {
+		snap.Timestamp = time.Unix(0, timestamp.Int64).UTC()
+	}
 	return snap, nil
 }
 
@@ -74,18 +84,26 @@ func (s *SQL) Save(snap eventsourcing.Snapshot) error {
 	}
 	if err == sql.ErrNoRows {
 		// insert
-		statement = `INSERT INTO snapshots (state, id, type, version, global_version) VALUES ($1, $2, $3, $4, $5)`
-		_, err = tx.Exec(statement, string(snap.State), snap.ID, snap.Type, snap.Version, snap.GlobalVersion)
+		statement = `INSERT INTO snapshots (state, id, type, version, global_version, timestamp) VALUES ($1, $2, $3, $4, $5, $6)`
+		_, err = tx.Exec(statement, snap.State, snap.ID, snap.Type, snap.Version, snap.GlobalVersion, timestamp(snap))
 		if err != nil {
 			return err
 		}
 	} else {
 		// update
-		statement = `UPDATE snapshots set state=$1, version=$2, global_version=$3 where id=$4 AND type=$5`
-		_, err = tx.Exec(statement, string(snap.State), snap.Version, snap.GlobalVersion, snap.ID, snap.Type)
+		statement = `UPDATE snapshots set state=$1, version=$2, global_version=$3, timestamp=$4 where id=$5 AND type=$6`
+		_, err = tx.Exec(statement, snap.State, snap.Version, snap.GlobalVersion, timestamp(snap), snap.ID, snap.Type)
 		if err != nil {
 			return err
 		}
 	}
 	return tx.Commit()
 }
+
+// timestamp returns the snapshot timestamp as nanoseconds since the unix epoch, null when it's not set
+func timestamp(snap eventsourcing.Snapshot) sql.NullInt64 {
+	if snap.Timestamp.IsZero() {
+		return sql.NullInt64{}
+	}
+	return sql.NullInt64{Int64: snap.Timestamp.UnixNano(), Valid: true}
+}
@@ -1,16 +1,14 @@
 package sql_test
 
 import (
+	"context"
 	sqldriver "database/sql"
-	"fmt"
-	"math/rand"
 	"testing"
-	"time"
 
 	"github.com/hallgren/eventsourcing"
 	"github.com/hallgren/eventsourcing/snapshotstore/sql"
 	"github.com/hallgren/eventsourcing/snapshotstore/suite"
-	_ "github.com/proullon/ramsql/driver"
+	_ "github.com/mattn/go-sqlite3"
 )
 
 type provider struct {
@@ -18,11 +16,12 @@ type provider struct {
 }
 
 func (p *provider) Setup() (eventsourcing.SnapshotStore, error) {
-	seededRand := rand.New(rand.NewSource(time.Now().UnixNano()))
-	db, err := sqldriver.Open("ramsql", fmt.Sprint(seededRand.Intn(99999999)))
+	db, err := sqldriver.Open("sqlite3", ":memory:")
 	if err != nil {
 		return nil, err
 	}
+	// each in memory database lives as long as its connection
+	db.SetMaxOpenConns(1)
 	p.db = db
 
 	err = db.Ping()
@@ -31,7 +30,7 @@ func (p *provider) Setup() (eventsourcing.SnapshotStore, error) {
 	}
 
 	store := sql.New(db)
-	err = store.MigrateTest()
+	err = store.Migrate()
 	return store, err
 }
 
@@ -42,3 +41,33 @@ func (p *provider) Teardown() { p.db.Close() }
 func TestSQLSnapshotStore(t *testing.T) {
 	suite.Test(t, new(provider))
 }
+
+func TestMigrateTimestamp(t *testing.T) {
+	db, err := sqldriver.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	store := sql.New(db)
+	defer store.Close()
+	// a table created before the timestamp column was added
+	_, err = db.Exec(`create table snapshots (id VARCHAR NOT NULL, type VARCHAR, version INTEGER, global_version INTEGER, state BLOB);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = db.Exec(`insert into snapshots (id, type, version, global_version, state) values ('1', 'Person', 1, 1, '{}')`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = store.MigrateTimestamp()
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := store.Get(context.Background(), "1", "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !snap.Timestamp.IsZero() {
+		t.Fatalf("expected no timestamp on a snapshot saved before the migration got %v", snap.Timestamp)
+	}
+}
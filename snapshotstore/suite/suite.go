@@ -3,6 +3,7 @@ package suite
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/hallgren/eventsourcing"
 )
@@ -43,6 +44,7 @@ func TestSnapshot(t *testing.T, snapshot eventsourcing.SnapshotStore) {
 		ID:            "123",
 		Type:          "Person",
 		State:         []byte{},
+		Timestamp:     time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC),
 	}
 
 	err := snapshot.Save(snap)
@@ -66,6 +68,9 @@ func TestSnapshot(t *testing.T, snapshot eventsourcing.SnapshotStore) {
 	if snap.Version != snap2.Version {
 		t.Fatalf("wrong Version in snapshot %q expected: %q", snap.Version, snap2.Version)
 	}
+	if !snap.Timestamp.Equal(snap2.Timestamp) {
+		t.Fatalf("wrong Timestamp in snapshot %v expected: %v", snap2.Timestamp, snap.Timestamp)
+	}
 	if string(snap.State) != string(snap2.State) {
 		t.Fatalf("wrong State in snapshot %q expected: %q", snap.State, snap2.State)
 	}
@@ -0,0 +1,134 @@
+package eventsourcing
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// SnapshotDrift is a snapshot whose state differs from the state built from the events up to the snapshot version,
+// like after a change in the Transition logic
+type SnapshotDrift struct {
+	ID      string
+	Version Version
+	// Snapshot and Replayed are the marshaled states of the aggregate from the snapshot and from the events
+	Snapshot []byte
+	Replayed []byte
+	// Changes are the exported fields that differ, from the snapshot to the replayed value
+	Changes []FieldChange
+}
+
+// SnapshotVerification is the outcome of VerifySnapshots
+type SnapshotVerification struct {
+	// Verified is the number of snapshots matching their events
+	Verified int
+	// Missing is the number of aggregates without snapshot
+	Missing int
+	Drifted []SnapshotDrift
+	// Failed holds the error of each aggregate that could not be verified
+	Failed map[string]error
+}
+
+// VerifySnapshots checks the snapshots of the aggregates of type A with the ids read from the channel against their
+// events. For each snapshot the aggregate is built from its events up to the snapshot version and both are marshaled
+// like when a snapshot is saved, a snapshot with a different state is reported as drifted. Drifted snapshots are
+// left as is, delete or save them again to have the aggregates built from the current Transition logic.
+//
+// An aggregate that can't be verified, like when its events end before the snapshot version or its Transition panics,
+// is recorded in the result and the verification goes on. An error is only returned if the context is done or the
+// repository has no snapshot store.
+func VerifySnapshots[T any, A Aggregate[T]](ctx context.Context, repo *Repository[T], ids <-chan string) (SnapshotVerification, error) {
+	var zero A
+	typ := reflect.TypeOf(zero)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return SnapshotVerification{}, errors.New("aggregate needs to be a pointer")
+	}
+	if repo.snapshot == nil {
+		return SnapshotVerification{}, errors.New("no snapshot store has been initialized")
+	}
+	result := SnapshotVerification{Failed: make(map[string]error)}
+	for {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		case id, ok := <-ids:
+			if !ok {
+				return result, nil
+			}
+			drift, err := verifySnapshot[T](ctx, repo, typ.Elem(), id)
+			switch {
+			case errors.Is(err, ErrSnapshotNotFound):
+				result.Missing++
+			case err != nil:
+				result.Failed[id] = err
+			case drift != nil:
+				result.Drifted = append(result.Drifted, *drift)
+			default:
+				result.Verified++
+			}
+		}
+	}
+}
+
+// verifySnapshot returns the drift of the snapshot of the aggregate, nil if it matches its events
+func verifySnapshot[T any](ctx context.Context, repo *Repository[T], typ reflect.Type, id string) (drift *SnapshotDrift, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("verify of %s %s panicked: %v", typ.Name(), id, r)
+		}
+	}()
+	snapshotted := reflect.New(typ).Interface().(Aggregate[T])
+	err = repo.snapshot.Get(ctx, id, snapshotted)
+	if err != nil {
+		return nil, err
+	}
+	version := snapshotted.Root().Version()
+	replayed := reflect.New(typ).Interface().(Aggregate[T])
+	err = replayTo(ctx, repo.eventStore, id, typ.Name(), replayed, version)
+	if err != nil {
+		return nil, err
+	}
+	snapshotState, err := repo.snapshot.marshalState(snapshotted)
+	if err != nil {
+		return nil, err
+	}
+	replayedState, err := repo.snapshot.marshalState(replayed)
+	if err != nil {
+		return nil, err
+	}
+	if bytes.Equal(snapshotState, replayedState) {
+		return nil, nil
+	}
+	changes, err := DiffValues(snapshotted, replayed)
+	if err != nil {
+		return nil, err
+	}
+	return &SnapshotDrift{ID: id, Version: version, Snapshot: snapshotState, Replayed: replayedState, Changes: changes}, nil
+}
+
+// replayTo builds the aggregate from its events up to the version
+func replayTo[T any](ctx context.Context, eventStore EventStore[T], id, typ string, aggregate Aggregate[T], version Version) error {
+	root := aggregate.Root()
+	iterator, err := eventStore.Get(ctx, id, typ, 0)
+	if err != nil && !errors.Is(err, ErrNoEvents) {
+		return err
+	}
+	if err == nil {
+		defer iterator.Close()
+		for root.Version() < version {
+			event, err := iterator.Next()
+			if errors.Is(err, ErrNoMoreEvents) {
+				break
+			} else if err != nil {
+				return err
+			}
+			root.BuildFromHistory(aggregate, []Event[T]{event})
+		}
+	}
+	if root.Version() != version {
+		return fmt.Errorf("%w: snapshot at version %d but the events end at version %d", ErrEventVersionGap, version, root.Version())
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestVerifySnapshots(t *testing.T) {
+	snapshots := memsnap.New()
+	handler := eventsourcing.SnapshotNew(snapshots, *eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal))
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), handler)
+	save := func(id string, grow int) *Person {
+		person, err := CreatePersonWithID(id, "kalle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i := 0; i < grow; i++ {
+			person.GrowOlder()
+		}
+		err = repo.Save(person)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return person
+	}
+
+	// a snapshot matching its events followed by events saved after it
+	person := save("1", 1)
+	err := handler.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a snapshot saved with state the events don't build, like before a change in Transition
+	person = save("2", 2)
+	person.Age = 10
+	err = handler.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// a snapshot ahead of the events
+	err = snapshots.Save(eventsourcing.Snapshot{ID: "3", Type: "Person", Version: 5, State: []byte(`{}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	save("4", 0)
+
+	ids := make(chan string, 4)
+	for _, id := range []string{"1", "2", "3", "4"} {
+		ids <- id
+	}
+	close(ids)
+	result, err := eventsourcing.VerifySnapshots[PersonEvent, *Person](context.Background(), repo, ids)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Verified != 1 || result.Missing != 1 {
+		t.Fatalf("expected one verified and one missing snapshot got %+v", result)
+	}
+	if len(result.Drifted) != 1 || result.Drifted[0].ID != "2" || result.Drifted[0].Version != 3 {
+		t.Fatalf("expected person 2 drifted at version 3 got %+v", result.Drifted)
+	}
+	changes := result.Drifted[0].Changes
+	if len(changes) != 1 || changes[0].Field != "Age" || changes[0].From != float64(10) || changes[0].To != float64(2) {
+		t.Fatalf("expected the age to drift from 10 to 2 got %+v", changes)
+	}
+	if len(result.Failed) != 1 || !errors.Is(result.Failed["3"], eventsourcing.ErrEventVersionGap) {
+		t.Fatalf("expected person 3 to fail on the missing events got %v", result.Failed)
+	}
+}
+
+func TestVerifySnapshotsNoSnapshotStore(t *testing.T) {
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil)
+	ids := make(chan string)
+	close(ids)
+	_, err := eventsourcing.VerifySnapshots[PersonEvent, *Person](context.Background(), repo, ids)
+	if err == nil {
+		t.Fatal("expected an error without snapshot store")
+	}
+}
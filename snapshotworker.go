@@ -0,0 +1,180 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrSnapshotQueueFull when the snapshot worker queue has no room for more snapshots
+var ErrSnapshotQueueFull = errors.New("snapshot queue is full")
+
+// ErrSnapshotWorkerClosed when snapshots are added to a closed snapshot worker
+var ErrSnapshotWorkerClosed = errors.New("snapshot worker is closed")
+
+// SnapshotPolicy decides if a snapshot should be taken of the aggregate after the events are saved
+type SnapshotPolicy[T any] func(aggregate Aggregate[T], events []Event[T]) bool
+
+// EveryNEvents is a snapshot policy that takes a snapshot each time the aggregate version passes a multiple of n
+func EveryNEvents[T any](n Version) SnapshotPolicy[T] {
+	return func(aggregate Aggregate[T], events []Event[T]) bool {
+		if len(events) == 0 || n == 0 {
+			return false
+		}
+		first := events[0].Version
+		last := events[len(events)-1].Version
+		return last/n > (first-1)/n
+	}
+}
+
+// SnapshotWorkerStats holds metrics from the snapshot worker
+type SnapshotWorkerStats struct {
+	QueueDepth    int
+	InFlight      int
+	Completed     uint64
+	Failed        uint64
+	LastDuration  time.Duration
+	TotalDuration time.Duration
+}
+
+// SnapshotWorker saves snapshots in the background with a bounded number of workers.
+// The aggregate state is serialized when the snapshot is added, only the write to the snapshot store is made
+// asynchronously. There is at most one snapshot in flight per aggregate, if a newer snapshot of the same
+// aggregate is added while one is waiting the waiting snapshot is replaced.
+type SnapshotWorker[T any] struct {
+	handler  *SnapshotHandler[T]
+	lock     sync.Mutex
+	cond     *sync.Cond
+	queue    []string            // aggregate keys waiting to be saved
+	pending  map[string]Snapshot // the latest snapshot per aggregate key
+	inFlight map[string]struct{} // aggregate keys currently being saved
+	maxQueue int
+	closed   bool
+	onError  func(snap Snapshot, err error)
+	stats    SnapshotWorkerStats
+	wg       sync.WaitGroup
+}
+
+// NewSnapshotWorker starts workers number of goroutines saving snapshots, queueSize limits the number
+// of aggregates waiting to be saved. Returns ErrNoWorkers if workers is below one.
+func NewSnapshotWorker[T any](handler *SnapshotHandler[T], workers, queueSize int) (*SnapshotWorker[T], error) {
+	if workers < 1 {
+		return nil, fmt.Errorf("%w: %d workers", ErrNoWorkers, workers)
+	}
+	w := &SnapshotWorker[T]{
+		handler:  handler,
+		pending:  make(map[string]Snapshot),
+		inFlight: make(map[string]struct{}),
+		maxQueue: queueSize,
+	}
+	w.cond = sync.NewCond(&w.lock)
+	w.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go w.work()
+	}
+	return w, nil
+}
+
+// OnError sets a function that is called when a snapshot could not be saved
+func (w *SnapshotWorker[T]) OnError(f func(snap Snapshot, err error)) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.onError = f
+}
+
+// Add serialize the aggregate and queue the snapshot to be saved
+func (w *SnapshotWorker[T]) Add(aggregate Aggregate[T]) error {
+	snap, err := w.handler.snapshot(aggregate)
+	if err != nil {
+		return err
+	}
+	key := snap.Type + "_" + snap.ID
+
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	if w.closed {
+		return ErrSnapshotWorkerClosed
+	}
+	if current, ok := w.pending[key]; ok {
+		// the aggregate is already waiting, replace it if this snapshot is newer
+		if snap.Version > current.Version {
+			w.pending[key] = snap
+		}
+		return nil
+	}
+	if len(w.queue) >= w.maxQueue {
+		return ErrSnapshotQueueFull
+	}
+	w.pending[key] = snap
+	// if the aggregate is in flight it's queued when the current save is done
+	if _, ok := w.inFlight[key]; !ok {
+		w.queue = append(w.queue, key)
+		w.cond.Signal()
+	}
+	return nil
+}
+
+// Stats returns the current metrics of the worker
+func (w *SnapshotWorker[T]) Stats() SnapshotWorkerStats {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	stats := w.stats
+	stats.QueueDepth = len(w.queue)
+	stats.InFlight = len(w.inFlight)
+	return stats
+}
+
+// Close stops accepting snapshots and waits until the queued snapshots are saved
+func (w *SnapshotWorker[T]) Close() {
+	w.lock.Lock()
+	w.closed = true
+	w.cond.Broadcast()
+	w.lock.Unlock()
+	w.wg.Wait()
+}
+
+func (w *SnapshotWorker[T]) work() {
+	defer w.wg.Done()
+	for {
+		w.lock.Lock()
+		for len(w.queue) == 0 && !w.closed {
+			w.cond.Wait()
+		}
+		if len(w.queue) == 0 {
+			w.lock.Unlock()
+			return
+		}
+		key := w.queue[0]
+		w.queue = w.queue[1:]
+		snap := w.pending[key]
+		delete(w.pending, key)
+		w.inFlight[key] = struct{}{}
+		w.lock.Unlock()
+
+		start := time.Now()
+		err := w.handler.save(snap)
+		duration := time.Since(start)
+
+		w.lock.Lock()
+		delete(w.inFlight, key)
+		w.stats.LastDuration = duration
+		w.stats.TotalDuration += duration
+		if err != nil {
+			w.stats.Failed++
+		} else {
+			w.stats.Completed++
+		}
+		// a newer snapshot was added while this one was saved
+		if _, ok := w.pending[key]; ok {
+			w.queue = append(w.queue, key)
+			w.cond.Signal()
+		}
+		onError := w.onError
+		w.lock.Unlock()
+
+		if err != nil && onError != nil {
+			onError(snap, err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	memsnap "github.com/hallgren/eventsourcing/snapshotstore/memory"
+)
+
+func TestEveryNEvents(t *testing.T) {
+	policy := eventsourcing.EveryNEvents[PersonEvent](3)
+	events := func(from, to eventsourcing.Version) []eventsourcing.Event[PersonEvent] {
+		var e []eventsourcing.Event[PersonEvent]
+		for v := from; v <= to; v++ {
+			e = append(e, eventsourcing.Event[PersonEvent]{Version: v})
+		}
+		return e
+	}
+	if policy(nil, events(1, 2)) {
+		t.Fatal("should not snapshot before version 3")
+	}
+	if !policy(nil, events(1, 3)) {
+		t.Fatal("should snapshot on version 3")
+	}
+	if !policy(nil, events(2, 7)) {
+		t.Fatal("should snapshot when passing version 3 and 6")
+	}
+	if policy(nil, events(4, 5)) {
+		t.Fatal("should not snapshot between multiples")
+	}
+}
+
+func TestSnapshotPolicySync(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	snapshotStore := memsnap.New()
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), eventsourcing.SnapshotNew(snapshotStore, *ser))
+	repo.SetSnapshotPolicy(eventsourcing.EveryNEvents[PersonEvent](2))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = snapshotStore.Get(context.Background(), person.ID(), "Person")
+	if !errors.Is(err, eventsourcing.ErrSnapshotNotFound) {
+		t.Fatalf("expected no snapshot got %v", err)
+	}
+	person.GrowOlder()
+	err = repo.Save(person)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snap, err := snapshotStore.Get(context.Background(), person.ID(), "Person")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snap.Version != 2 {
+		t.Fatalf("expected snapshot version 2 got %d", snap.Version)
+	}
+}
+
+func TestSnapshotPolicyFailure(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	es := memory.Create[PersonEvent]()
+	repo := eventsourcing.NewRepository[PersonEvent](es, eventsourcing.SnapshotNew(failingSnapshotStore{}, *ser))
+	repo.SetSnapshotPolicy(eventsourcing.EveryNEvents[PersonEvent](1))
+
+	person, err := CreatePerson("kalle")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = repo.Save(person)
+	if !errors.Is(err, eventsourcing.ErrSnapshotAfterSave) {
+		t.Fatalf("expected ErrSnapshotAfterSave got %v", err)
+	}
+	var snapshotErr *eventsourcing.SnapshotAfterSaveError
+	if !errors.As(err, &snapshotErr) || snapshotErr.Err.Error() != "unavailable" {
+		t.Fatalf("expected the snapshot store error got %v", err)
+	}
+	if person.UnsavedEvents() {
+		t.Fatal("expected the events to be saved")
+	}
+	loaded := Person{}
+	err = eventsourcing.NewRepository[PersonEvent](es, nil).Get(person.ID(), &loaded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.Version() != person.Version() {
+		t.Fatalf("expected version %d in the event store got %d", person.Version(), loaded.Version())
+	}
+}
+
+func TestSnapshotWorker(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	snapshotStore := memsnap.New()
+	handler := eventsourcing.SnapshotNew(snapshotStore, *ser)
+	repo := eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), handler)
+	worker, err := eventsourcing.NewSnapshotWorker(handler, 2, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.SetSnapshotPolicy(eventsourcing.EveryNEvents[PersonEvent](1))
+	repo.SetSnapshotWorker(worker)
+
+	var people []*Person
+	for i := 0; i < 5; i++ {
+		person, err := CreatePerson("kalle")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for j := 0; j < 3; j++ {
+			person.GrowOlder()
+			err = repo.Save(person)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		people = append(people, person)
+	}
+	worker.Close()
+
+	for _, person := range people {
+		snap, err := snapshotStore.Get(context.Background(), person.ID(), "Person")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if snap.Version != person.Version() {
+			t.Fatalf("expected the latest snapshot version %d got %d", person.Version(), snap.Version)
+		}
+	}
+	stats := worker.Stats()
+	if stats.QueueDepth != 0 || stats.InFlight != 0 {
+		t.Fatalf("expected empty queue after close got %+v", stats)
+	}
+	if stats.Completed == 0 || stats.Failed != 0 {
+		t.Fatalf("unexpected stats %+v", stats)
+	}
+
+	person, _ := CreatePerson("kalle")
+	repo.Save(person)
+	err = worker.Add(person)
+	if !errors.Is(err, eventsourcing.ErrSnapshotWorkerClosed) {
+		t.Fatalf("expected ErrSnapshotWorkerClosed got %v", err)
+	}
+}
+
+func TestSnapshotWorkerWithoutWorkers(t *testing.T) {
+	ser := eventsourcing.NewSerializer[PersonEvent](json.Marshal, json.Unmarshal)
+	handler := eventsourcing.SnapshotNew(memsnap.New(), *ser)
+	_, err := eventsourcing.NewSnapshotWorker(handler, 0, 10)
+	if !errors.Is(err, eventsourcing.ErrNoWorkers) {
+		t.Fatalf("expected ErrNoWorkers got %v", err)
+	}
+}
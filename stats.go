@@ -0,0 +1,39 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+)
+
+// StatsLargestAggregates is the number of aggregates returned in Stats.Largest
+const StatsLargestAggregates = 10
+
+// AggregateTypeStats holds the counts of an aggregate type
+type AggregateTypeStats struct {
+	Aggregates uint64
+	Events     uint64
+}
+
+// AggregateStats holds the number of events of an aggregate
+type AggregateStats struct {
+	AggregateType string
+	AggregateID   string
+	Events        uint64
+}
+
+// Stats gives an overview of the content of an event store. Aggregates with a large number of events are slow
+// to build without snapshots and are listed in Largest to be spotted before they become a problem.
+type Stats struct {
+	TotalEvents uint64
+	Types       map[string]AggregateTypeStats
+	// Largest holds the aggregates with the most events, in descending order
+	Largest []AggregateStats
+	// Oldest and Newest are the timestamps of the first and last event in the global order
+	Oldest time.Time
+	Newest time.Time
+}
+
+// StatsProvider is implemented by event stores that can summarize their content
+type StatsProvider interface {
+	Stats(ctx context.Context) (Stats, error)
+}
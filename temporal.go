@@ -0,0 +1,45 @@
+package eventsourcing
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"time"
+)
+
+// GetAsOf builds the aggregate as it was known at knownAt about the state at validAt. Only the events recorded
+// until knownAt that took effect until validAt are applied, in the order they took effect.
+//
+// The aggregate is built from all its events without snapshots or the cache. It's meant to be read, the version is
+// the version of the last applied event and it should not be saved.
+func (r *Repository[T]) GetAsOf(ctx context.Context, id string, aggregate Aggregate[T], knownAt, validAt time.Time) error {
+	if reflect.ValueOf(aggregate).Kind() != reflect.Ptr {
+		return errors.New("aggregate needs to be a pointer")
+	}
+	aggregateType := reflect.TypeOf(aggregate).Elem().Name()
+	id, err := r.Resolve(ctx, aggregateType, id)
+	if err != nil {
+		return err
+	}
+	events, err := r.streamEvents(ctx, aggregateType, id, 0)
+	if err != nil {
+		return err
+	}
+	var known []Event[T]
+	for _, event := range events {
+		if event.Timestamp.After(knownAt) || event.ValidAt().After(validAt) {
+			continue
+		}
+		known = append(known, event)
+	}
+	if len(known) == 0 {
+		return ErrAggregateNotFound
+	}
+	sort.SliceStable(known, func(i, j int) bool {
+		return known[i].ValidAt().Before(known[j].ValidAt())
+	})
+	aggregate.Root().BuildFromHistory(aggregate, known)
+	afterLoad(aggregate)
+	return nil
+}
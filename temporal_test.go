@@ -0,0 +1,59 @@
+package eventsourcing_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+func TestGetAsOf(t *testing.T) {
+	repo := eventsourcing.NewRepository[any](memory.Create[any](), nil)
+	twoYearsAgo := time.Now().AddDate(-2, 0, 0)
+	ledger := &Ledger{}
+	ledger.TrackChangeWithValidTime(ledger, &Opened{Balance: 10}, twoYearsAgo, nil)
+	err := repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	beforeCorrection := time.Now()
+	time.Sleep(time.Millisecond)
+
+	// a deposit discovered later that took effect a year ago
+	yearAgo := time.Now().AddDate(-1, 0, 0)
+	ledger.TrackChangeWithValidTime(ledger, &Deposited{Amount: 5}, yearAgo, nil)
+	err = repo.Save(ledger)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		title   string
+		knownAt time.Time
+		validAt time.Time
+		balance int
+		err     error
+	}{
+		{"known now about now", time.Now(), time.Now(), 15, nil},
+		{"known before the correction about now", beforeCorrection, time.Now(), 10, nil},
+		{"known now about a year ago", time.Now(), yearAgo, 15, nil},
+		{"known now about before the deposit", time.Now(), yearAgo.Add(-time.Hour), 10, nil},
+		{"known now about before the ledger was opened", time.Now(), twoYearsAgo.Add(-time.Hour), 0, eventsourcing.ErrAggregateNotFound},
+	}
+	for _, test := range tests {
+		t.Run(test.title, func(t *testing.T) {
+			l := Ledger{}
+			err := repo.GetAsOf(context.Background(), ledger.ID(), &l, test.knownAt, test.validAt)
+			if !errors.Is(err, test.err) {
+				t.Fatalf("expected error %v got %v", test.err, err)
+			}
+			if l.Balance != test.balance {
+				t.Fatalf("expected balance %d got %d", test.balance, l.Balance)
+			}
+		})
+	}
+}
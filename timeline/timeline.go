@@ -0,0 +1,160 @@
+// Package timeline renders the events of an aggregate stream as a DOT or Mermaid graph for debugging and
+// documentation. Each event is a node with its version, reason and timestamp, consecutive events of an aggregate are
+// linked and events caused by another event in the stream get a dashed causation link.
+package timeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+// ErrUnknownFormat when there is no renderer for the format
+var ErrUnknownFormat = errors.New("unknown timeline format")
+
+// Renderer writes the timeline of the events to w
+type Renderer[T any] func(w io.Writer, events []eventsourcing.Event[T]) error
+
+// Lookup returns the renderer of the format, dot or mermaid. Used by tools picking the output format by name.
+func Lookup[T any](format string) (Renderer[T], error) {
+	switch strings.ToLower(format) {
+	case "dot":
+		return DOT[T], nil
+	case "mermaid":
+		return Mermaid[T], nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownFormat, format)
+}
+
+// Stream returns the events of the aggregate in version order
+func Stream[T any](ctx context.Context, store eventsourcing.EventStore[T], aggregateType, id string) ([]eventsourcing.Event[T], error) {
+	iterator, err := store.Get(ctx, id, aggregateType, 0)
+	if err != nil {
+		return nil, err
+	}
+	defer iterator.Close()
+	var events []eventsourcing.Event[T]
+	for {
+		event, err := iterator.Next()
+		if errors.Is(err, eventsourcing.ErrNoMoreEvents) {
+			return events, nil
+		} else if err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+}
+
+// DOT writes the timeline as a graphviz digraph
+func DOT[T any](w io.Writer, events []eventsourcing.Event[T]) error {
+	g := build(events)
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "digraph %s {\n", dotQuote(g.title))
+	b.WriteString("\trankdir=LR;\n\tnode [shape=box];\n")
+	for _, n := range g.nodes {
+		fmt.Fprintf(&b, "\t%s [label=%s];\n", n.id, dotQuote(strings.Join(n.lines, "\n")))
+	}
+	for _, e := range g.edges {
+		if e.causation {
+			fmt.Fprintf(&b, "\t%s -> %s [style=dashed, label=\"caused\"];\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(&b, "\t%s -> %s;\n", e.from, e.to)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// Mermaid writes the timeline as a mermaid flowchart
+func Mermaid[T any](w io.Writer, events []eventsourcing.Event[T]) error {
+	g := build(events)
+	b := strings.Builder{}
+	fmt.Fprintf(&b, "---\ntitle: %s\n---\nflowchart LR\n", g.title)
+	for _, n := range g.nodes {
+		fmt.Fprintf(&b, "\t%s[\"%s\"]\n", n.id, mermaidEscape(strings.Join(n.lines, "<br/>")))
+	}
+	for _, e := range g.edges {
+		if e.causation {
+			fmt.Fprintf(&b, "\t%s -. caused .-> %s\n", e.from, e.to)
+			continue
+		}
+		fmt.Fprintf(&b, "\t%s --> %s\n", e.from, e.to)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type node struct {
+	id    string
+	lines []string
+}
+
+type edge struct {
+	from, to  string
+	causation bool
+}
+
+type graph struct {
+	title string
+	nodes []node
+	edges []edge
+}
+
+// build lays out the events as nodes linked in stream order per aggregate and by causation
+func build[T any](events []eventsourcing.Event[T]) graph {
+	g := graph{title: "timeline"}
+	if len(events) == 0 {
+		return g
+	}
+	multiple := false
+	for _, event := range events {
+		if event.AggregateID != events[0].AggregateID || event.AggregateType != events[0].AggregateType {
+			multiple = true
+			break
+		}
+	}
+	if !multiple {
+		g.title = events[0].AggregateType + " " + events[0].AggregateID
+	}
+
+	last := make(map[string]string) // the node of the last event of each aggregate
+	byEventID := make(map[string]string)
+	for i, event := range events {
+		id := fmt.Sprintf("e%d", i)
+		n := node{id: id}
+		if multiple {
+			n.lines = append(n.lines, event.AggregateType+" "+event.AggregateID)
+		}
+		n.lines = append(n.lines, fmt.Sprintf("%d %s", event.Version, event.Reason()), event.Timestamp.UTC().Format(time.RFC3339Nano))
+		g.nodes = append(g.nodes, n)
+
+		aggregate := event.AggregateType + "\x00" + event.AggregateID
+		if prev, ok := last[aggregate]; ok {
+			g.edges = append(g.edges, edge{from: prev, to: id})
+		}
+		last[aggregate] = id
+		causationID := eventsourcing.MetadataCarrierFrom(event).CausationID
+		if cause, ok := byEventID[causationID]; ok {
+			g.edges = append(g.edges, edge{from: cause, to: id, causation: true})
+		}
+		if event.EventID != "" {
+			byEventID[event.EventID] = id
+		}
+	}
+	return g
+}
+
+func dotQuote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+	return `"` + r.Replace(s) + `"`
+}
+
+func mermaidEscape(s string) string {
+	return strings.ReplaceAll(s, `"`, "#quot;")
+}
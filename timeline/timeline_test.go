@@ -0,0 +1,100 @@
+package timeline_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+	"github.com/hallgren/eventsourcing/timeline"
+)
+
+type Opened struct{}
+type Deposited struct{}
+
+var timestamp = time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func events() []eventsourcing.Event[any] {
+	return []eventsourcing.Event[any]{
+		{EventID: "a", AggregateID: "1", AggregateType: "Account", Version: 1, Timestamp: timestamp, Data: &Opened{}},
+		{EventID: "b", AggregateID: "1", AggregateType: "Account", Version: 2, Timestamp: timestamp.Add(time.Second), Data: &Deposited{}},
+		{EventID: "c", AggregateID: "1", AggregateType: "Account", Version: 3, Timestamp: timestamp.Add(2 * time.Second), Data: &Deposited{},
+			Metadata: eventsourcing.MetadataCarrier{CausationID: "a"}.Metadata()},
+	}
+}
+
+func TestDOT(t *testing.T) {
+	b := bytes.Buffer{}
+	err := timeline.DOT(&b, events())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `digraph "Account 1" {
+	rankdir=LR;
+	node [shape=box];
+	e0 [label="1 Opened\n2024-03-01T12:00:00Z"];
+	e1 [label="2 Deposited\n2024-03-01T12:00:01Z"];
+	e2 [label="3 Deposited\n2024-03-01T12:00:02Z"];
+	e0 -> e1;
+	e1 -> e2;
+	e0 -> e2 [style=dashed, label="caused"];
+}
+`
+	if b.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestMermaid(t *testing.T) {
+	b := bytes.Buffer{}
+	render, err := timeline.Lookup[any]("mermaid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// events of several aggregates are labeled with their aggregate
+	e := append(events(), eventsourcing.Event[any]{AggregateID: "2", AggregateType: "Account", Version: 1, Timestamp: timestamp, Data: &Opened{}})
+	err = render(&b, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `---
+title: timeline
+---
+flowchart LR
+	e0["Account 1<br/>1 Opened<br/>2024-03-01T12:00:00Z"]
+	e1["Account 1<br/>2 Deposited<br/>2024-03-01T12:00:01Z"]
+	e2["Account 1<br/>3 Deposited<br/>2024-03-01T12:00:02Z"]
+	e3["Account 2<br/>1 Opened<br/>2024-03-01T12:00:00Z"]
+	e0 --> e1
+	e1 --> e2
+	e0 -. caused .-> e2
+`
+	if b.String() != expected {
+		t.Fatalf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
+
+func TestLookupUnknown(t *testing.T) {
+	_, err := timeline.Lookup[any]("svg")
+	if !errors.Is(err, timeline.ErrUnknownFormat) {
+		t.Fatalf("expected unknown format got %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	es := memory.Create[any]()
+	err := es.Save(events())
+	if err != nil {
+		t.Fatal(err)
+	}
+	stream, err := timeline.Stream[any](context.Background(), es, "Account", "1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stream) != 3 || stream[2].Version != 3 {
+		t.Fatalf("expected the three events of the stream got %+v", stream)
+	}
+}
@@ -0,0 +1,36 @@
+package eventsourcing
+
+import (
+	"context"
+	"time"
+)
+
+// EventFilter selects the events returned from a query, an empty field matches all events
+type EventFilter struct {
+	AggregateTypes []string
+	Reasons        []string
+}
+
+// Match returns true if the aggregate type and reason pass the filter
+func (f EventFilter) Match(aggregateType, reason string) bool {
+	return matchAny(f.AggregateTypes, aggregateType) && matchAny(f.Reasons, reason)
+}
+
+func matchAny(values []string, value string) bool {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// TimeRangeEventStore is implemented by event stores that can read the events saved within a time window
+type TimeRangeEventStore[T any] interface {
+	// GlobalEventsBetween returns the events with a timestamp from, inclusive, to, exclusive, matching the filter
+	// in global order
+	GlobalEventsBetween(ctx context.Context, from, to time.Time, filter EventFilter) ([]Event[T], error)
+}
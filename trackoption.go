@@ -0,0 +1,55 @@
+package eventsourcing
+
+import "time"
+
+// TrackOption sets properties of the event tracked with TrackChangeWithOptions
+type TrackOption func(*trackOptions)
+
+type trackOptions struct {
+	metadata  map[string]interface{}
+	timestamp time.Time
+	validTime time.Time
+}
+
+// WithMetadata adds the metadata to the event, keys already set by earlier options are overwritten
+func WithMetadata(metadata map[string]interface{}) TrackOption {
+	return func(o *trackOptions) {
+		for k, v := range metadata {
+			o.set(k, v)
+		}
+	}
+}
+
+// WithCorrelation sets the correlation and causation id in the event metadata, empty ids are left out
+func WithCorrelation(correlationID, causationID string) TrackOption {
+	return func(o *trackOptions) {
+		if correlationID != "" {
+			o.set(MetadataCorrelationID, correlationID)
+		}
+		if causationID != "" {
+			o.set(MetadataCausationID, causationID)
+		}
+	}
+}
+
+// WithTimestamp sets the timestamp of the event instead of the current time, used to make events deterministic in
+// tests and imports. The timestamp is used as is, it's not kept from going before the last event of the aggregate.
+func WithTimestamp(timestamp time.Time) TrackOption {
+	return func(o *trackOptions) {
+		o.timestamp = timestamp.UTC()
+	}
+}
+
+// WithValidTime sets the time the state change took effect, see TrackChangeWithValidTime
+func WithValidTime(validTime time.Time) TrackOption {
+	return func(o *trackOptions) {
+		o.validTime = validTime.UTC()
+	}
+}
+
+func (o *trackOptions) set(key string, value interface{}) {
+	if o.metadata == nil {
+		o.metadata = make(map[string]interface{})
+	}
+	o.metadata[key] = value
+}
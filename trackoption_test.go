@@ -0,0 +1,46 @@
+package eventsourcing_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func TestTrackChangeWithOptions(t *testing.T) {
+	timestamp := time.Date(2022, 1, 2, 3, 4, 5, 0, time.UTC)
+	validTime := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC)
+	person := Person{}
+	person.TrackChangeWithOptions(&person, &Born{Name: "kalle"},
+		eventsourcing.WithMetadata(map[string]interface{}{"foo": "bar", eventsourcing.MetadataCorrelationID: "overwritten"}),
+		eventsourcing.WithCorrelation("correlation", "causation"),
+		eventsourcing.WithTimestamp(timestamp),
+		eventsourcing.WithValidTime(validTime),
+	)
+	event := person.Events()[0]
+	if !event.Timestamp.Equal(timestamp) {
+		t.Fatalf("expected timestamp %v got %v", timestamp, event.Timestamp)
+	}
+	if !event.ValidTime.Equal(validTime) {
+		t.Fatalf("expected valid time %v got %v", validTime, event.ValidTime)
+	}
+	m := eventsourcing.MetadataCarrierFrom(event)
+	if m.CorrelationID != "correlation" || m.CausationID != "causation" {
+		t.Fatalf("expected correlation and causation id got %+v", m)
+	}
+	if event.Metadata["foo"] != "bar" {
+		t.Fatalf("expected metadata foo got %v", event.Metadata)
+	}
+	if person.Name != "kalle" {
+		t.Fatal("expected the event to be applied")
+	}
+}
+
+func TestTrackChangeWithoutOptions(t *testing.T) {
+	person := Person{}
+	person.TrackChangeWithOptions(&person, &Born{Name: "kalle"})
+	event := person.Events()[0]
+	if event.Metadata != nil || event.Timestamp.IsZero() || !event.ValidTime.IsZero() {
+		t.Fatalf("expected no metadata, a timestamp and no valid time got %+v", event)
+	}
+}
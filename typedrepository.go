@@ -0,0 +1,46 @@
+package eventsourcing
+
+import (
+	"context"
+	"fmt"
+)
+
+// TypedRepository binds a repository to an aggregate type A and its domain specific ID type (OrderID, AccountID).
+// An aggregate can only be fetched and saved with the ID type it's bound to, passing the ID or the aggregate of
+// another aggregate type does not compile. The ID is stored as its String() representation in the event stores.
+// Only the repository calls are typed, Event.AggregateID and AggregateRoot.ID() stay strings.
+type TypedRepository[T any, ID fmt.Stringer, A Aggregate[T]] struct {
+	repository *Repository[T]
+}
+
+// NewTypedRepository factory function
+func NewTypedRepository[T any, ID fmt.Stringer, A Aggregate[T]](repository *Repository[T]) *TypedRepository[T, ID, A] {
+	return &TypedRepository[T, ID, A]{
+		repository: repository,
+	}
+}
+
+// Repository returns the untyped repository, for the features not bound to the aggregate type
+func (r *TypedRepository[T, ID, A]) Repository() *Repository[T] {
+	return r.repository
+}
+
+// SetID sets the typed aggregate ID on an aggregate that has no ID
+func (r *TypedRepository[T, ID, A]) SetID(aggregate A, id ID) error {
+	return aggregate.Root().SetID(id.String())
+}
+
+// Save saves the events of the aggregate
+func (r *TypedRepository[T, ID, A]) Save(aggregate A) error {
+	return r.repository.Save(aggregate)
+}
+
+// GetWithContext fetches the aggregate based on its typed identifier
+func (r *TypedRepository[T, ID, A]) GetWithContext(ctx context.Context, id ID, aggregate A) error {
+	return r.repository.GetWithContext(ctx, id.String(), aggregate)
+}
+
+// Get fetches the aggregate based on its typed identifier
+func (r *TypedRepository[T, ID, A]) Get(id ID, aggregate A) error {
+	return r.GetWithContext(context.Background(), id, aggregate)
+}
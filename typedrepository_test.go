@@ -0,0 +1,52 @@
+package eventsourcing_test
+
+import (
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type PersonID string
+
+func (id PersonID) String() string {
+	return string(id)
+}
+
+func TestTypedRepositorySaveAndGet(t *testing.T) {
+	repo := eventsourcing.NewTypedRepository[PersonEvent, PersonID, *Person](eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil))
+
+	id := PersonID("person-1")
+	person := Person{}
+	err := repo.SetID(&person, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	person.TrackChange(&person, &Born{Name: "kalle"})
+	err = repo.Save(&person)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	twin := Person{}
+	err = repo.Get(id, &twin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if twin.ID() != id.String() {
+		t.Fatalf("wrong id exp: %s got: %s", id, twin.ID())
+	}
+	if twin.Name != "kalle" {
+		t.Fatalf("wrong name exp: kalle got: %s", twin.Name)
+	}
+}
+
+func TestTypedRepositoryGetNoneExisting(t *testing.T) {
+	repo := eventsourcing.NewTypedRepository[PersonEvent, PersonID, *Person](eventsourcing.NewRepository[PersonEvent](memory.Create[PersonEvent](), nil))
+
+	p := Person{}
+	err := repo.Get(PersonID("none_existing"), &p)
+	if err != eventsourcing.ErrAggregateNotFound {
+		t.Fatalf("expected ErrAggregateNotFound got %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package eventsourcing
+
+import "fmt"
+
+// Upcaster rewrites the marshaled data of an event stored in an older format of its type to the current format. It's
+// called with the data of all events of the reason when they are read, data already in the current format is returned
+// as is.
+//
+//	// Born had the name in the field FullName
+//	ser.RegisterUpcaster("Person", "Born", func(data []byte) ([]byte, error) {
+//		return bytes.Replace(data, []byte(`"FullName":`), []byte(`"Name":`), 1), nil
+//	})
+type Upcaster func(data []byte) ([]byte, error)
+
+type eventName struct {
+	aggregateType string
+	reason        string
+}
+
+// RegisterUpcaster registers an upcaster of the event data of the reason on the aggregate type. The upcasters of a
+// reason are called in the order they are registered before the data is unmarshaled, an upcaster failing is handled
+// as a decode error by the decode error policy.
+func (h *Serializer[T]) RegisterUpcaster(aggregateType, reason string, upcaster Upcaster) {
+	key := aggregateType + "_" + reason
+	h.upcasters[key] = append(h.upcasters[key], upcaster)
+}
+
+func (h *Serializer[T]) upcast(aggregateType, reason string, data []byte) ([]byte, error) {
+	for i, upcaster := range h.upcasters[aggregateType+"_"+reason] {
+		var err error
+		data, err = upcaster(data)
+		if err != nil {
+			return nil, fmt.Errorf("upcaster %d of %s %s: %w", i+1, aggregateType, reason, err)
+		}
+	}
+	return data, nil
+}
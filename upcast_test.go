@@ -0,0 +1,47 @@
+package eventsourcing_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+)
+
+func TestUpcaster(t *testing.T) {
+	ser := eventsourcing.NewSerializer[any](json.Marshal, json.Unmarshal)
+	err := ser.Register(&Untyped{}, ser.Events(&Born{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Born had the name in FullName, and later in lower case
+	ser.RegisterUpcaster("Untyped", "Born", func(data []byte) ([]byte, error) {
+		return bytes.Replace(data, []byte(`"FullName":`), []byte(`"name":`), 1), nil
+	})
+	ser.RegisterUpcaster("Untyped", "Born", func(data []byte) ([]byte, error) {
+		return bytes.Replace(data, []byte(`"name":`), []byte(`"Name":`), 1), nil
+	})
+
+	for _, data := range []string{`{"FullName":"kalle"}`, `{"name":"kalle"}`, `{"Name":"kalle"}`} {
+		event := eventsourcing.Event[any]{AggregateType: "Untyped", AggregateID: "1"}
+		ok, err := ser.UnmarshalEvent(&event, "Born", []byte(data))
+		if err != nil || !ok {
+			t.Fatalf("expected %s to unmarshal got %v %v", data, ok, err)
+		}
+		if event.Data.(*Born).Name != "kalle" {
+			t.Fatalf("expected %s upcasted got %+v", data, event.Data)
+		}
+	}
+
+	errUpcast := errors.New("unknown format")
+	ser.RegisterUpcaster("Untyped", "Born", func(data []byte) ([]byte, error) {
+		return nil, errUpcast
+	})
+	event := eventsourcing.Event[any]{AggregateType: "Untyped", AggregateID: "1"}
+	_, err = ser.UnmarshalEvent(&event, "Born", []byte(`{"Name":"kalle"}`))
+	decodeErr := &eventsourcing.DecodeError{}
+	if !errors.As(err, &decodeErr) || !errors.Is(err, errUpcast) || string(decodeErr.Data) != `{"Name":"kalle"}` {
+		t.Fatalf("expected a decode error with the stored data got %v", err)
+	}
+}
@@ -0,0 +1,28 @@
+package eventsourcing
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrInvalidEvent is returned when the event data fails its validation
+var ErrInvalidEvent = errors.New("invalid event")
+
+// EventValidator is an optional interface on event data. Invalid events are rejected when tracked on the aggregate
+// and when saved to the event stores, before they become part of the event log.
+type EventValidator interface {
+	Validate() error
+}
+
+// ValidateEventData validates the event data if it implements EventValidator
+func ValidateEventData(data any) error {
+	v, ok := data.(EventValidator)
+	if !ok {
+		return nil
+	}
+	err := v.Validate()
+	if err != nil {
+		return fmt.Errorf("%w %s: %v", ErrInvalidEvent, reason(data), err)
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package eventsourcing_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hallgren/eventsourcing"
+	"github.com/hallgren/eventsourcing/eventstore/memory"
+)
+
+type Withdrawn struct {
+	Amount int
+}
+
+func (w *Withdrawn) Validate() error {
+	if w.Amount <= 0 {
+		return errors.New("amount must be positive")
+	}
+	return nil
+}
+
+func TestTrackChangeInvalidEvent(t *testing.T) {
+	l := &Ledger{}
+	err := l.TrackChange(l, &Opened{Balance: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = l.TrackChange(l, &Withdrawn{Amount: -5})
+	if !errors.Is(err, eventsourcing.ErrInvalidEvent) {
+		t.Fatalf("expected invalid event got %v", err)
+	}
+	if l.UnsavedEventCount() != 1 || l.Version() != 1 {
+		t.Fatalf("expected the invalid event not to be tracked got %d events", l.UnsavedEventCount())
+	}
+	err = l.TrackChange(l, &Withdrawn{Amount: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSaveInvalidEvent(t *testing.T) {
+	es := memory.Create[any]()
+	err := es.Save([]eventsourcing.Event[any]{
+		{AggregateID: "1", AggregateType: "Ledger", Version: 1, Data: &Withdrawn{}},
+	})
+	if !errors.Is(err, eventsourcing.ErrInvalidEvent) {
+		t.Fatalf("expected invalid event got %v", err)
+	}
+}